@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+	"code.cloudfoundry.org/quarks-utils/pkg/logger"
+)
+
+const boshDNSServerFailedMessage = "bosh-dns-server command failed."
+
+// boshDNSServerCmd answers BOSH DNS queries for the coredns of a deployment
+var boshDNSServerCmd = &cobra.Command{
+	Use:   "bosh-dns-server [flags]",
+	Short: "Answers BOSH DNS queries from kubernetes endpoints",
+	Long: `Answers BOSH DNS queries from kubernetes endpoints.
+
+Decodes queries like 'q-a1i2s0.<instance-group>.<network>.<deployment>.bosh'
+and 'q-s0.q-g<group>.bosh' and returns the addresses of the matching
+instances. Ready endpoints are healthy instances.
+
+The config file lists the instance groups of the deployment in manifest order.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("config", cmd.Flags().Lookup("config"))
+		viper.BindPFlag("namespace", cmd.Flags().Lookup("namespace"))
+		viper.BindPFlag("address", cmd.Flags().Lookup("address"))
+	},
+	RunE: func(_ *cobra.Command, args []string) (err error) {
+		log = logger.New(cmd.LogLevel())
+		defer func() {
+			_ = log.Sync()
+			if err != nil {
+				time.Sleep(debugGracePeriod)
+			}
+		}()
+
+		namespace := viper.GetString("namespace")
+		configPath := viper.GetString("config")
+		contents, err := ioutil.ReadFile(configPath)
+		if err != nil {
+			return errors.Wrapf(err, "%s Reading config file '%s' failed.", boshDNSServerFailedMessage, configPath)
+		}
+		config := boshdns.QueryConfig{}
+		if err := json.Unmarshal(contents, &config); err != nil {
+			return errors.Wrapf(err, "%s Loading config file '%s' failed.", boshDNSServerFailedMessage, configPath)
+		}
+
+		restConfig, err := cmd.KubeConfig(log)
+		if err != nil {
+			return errors.Wrap(err, boshDNSServerFailedMessage)
+		}
+
+		ctx := context.Background()
+		informers, err := cache.New(restConfig, cache.Options{Namespace: namespace})
+		if err != nil {
+			return errors.Wrap(err, boshDNSServerFailedMessage)
+		}
+		for _, obj := range []client.Object{&corev1.Endpoints{}, &corev1.Pod{}} {
+			if _, err := informers.GetInformer(ctx, obj); err != nil {
+				return errors.Wrap(err, boshDNSServerFailedMessage)
+			}
+		}
+		go func() {
+			if err := informers.Start(ctx); err != nil {
+				log.Errorf("Informers stopped: %v", err)
+			}
+		}()
+		if !informers.WaitForCacheSync(ctx) {
+			return errors.New(boshDNSServerFailedMessage + " Syncing endpoints and pods failed.")
+		}
+
+		resolver := boshdns.NewResolver(config, namespace, informers)
+		server := boshdns.NewServer(log, resolver, viper.GetString("address"))
+		if err := server.ListenAndServe(ctx); err != nil {
+			return errors.Wrap(err, boshDNSServerFailedMessage)
+		}
+		return nil
+	},
+}
+
+func init() {
+	pf := boshDNSServerCmd.Flags()
+	utilCmd.AddCommand(boshDNSServerCmd)
+	pf.StringP("config", "", "/etc/coredns/queries.json", "path to the config file with the instance groups")
+	pf.StringP("namespace", "", "default", "namespace of the deployment")
+	pf.StringP("address", "", boshdns.QueryServerAddress, "address to listen on for udp and tcp")
+
+	argToEnv := map[string]string{
+		"config":    "CONFIG",
+		"namespace": "NAMESPACE",
+		"address":   "ADDRESS",
+	}
+	cmd.AddEnvToUsage(boshDNSServerCmd, argToEnv)
+}
@@ -17,6 +17,7 @@ import (
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/qjobs"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/withops"
 	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+	"code.cloudfoundry.org/quarks-utils/pkg/logger"
 )
 
 const igFailedMessage = "instance-group command failed."
@@ -42,7 +43,9 @@ Also calculates and prints the BPM configurations for all BOSH jobs of that inst
 	},
 
 	RunE: func(_ *cobra.Command, args []string) (err error) {
+		log = logger.New(cmd.LogLevel())
 		defer func() {
+			_ = log.Sync()
 			if err != nil {
 				time.Sleep(debugGracePeriod)
 			}
@@ -88,7 +91,7 @@ Also calculates and prints the BPM configurations for all BOSH jobs of that inst
 			return errors.Wrapf(err, "%s Loading BOSH manifest file failed. Please check the file contents and try again.", igFailedMessage)
 		}
 
-		igr, err := manifest.NewInstanceGroupResolver(afero.NewOsFs(), baseDir, deploymentName, *m, instanceGroupName)
+		igr, err := manifest.NewInstanceGroupResolver(log, afero.NewOsFs(), baseDir, deploymentName, *m, instanceGroupName)
 		if err != nil {
 			return errors.Wrap(err, igFailedMessage)
 		}
@@ -126,22 +129,9 @@ Also calculates and prints the BPM configurations for all BOSH jobs of that inst
 		}
 
 		opsBytes := propertiesBytes
-		if (ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps != nil) && (len(ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps.InstanceGroup) != 0) {
-			// Apply ops file on the BPM yaml
-			// Interpolate manifest with ops
-			interpolator := withops.NewInterpolator()
-
-			opsData, err := ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps.InstanceGroup.Bytes()
-			if err != nil {
-				return errors.Wrapf(err, "failed to get bytes for pre render ops in instance group '%s'", instanceGroupName)
-			}
-
-			err = interpolator.AddOps([]byte(opsData))
-			if err != nil {
-				return errors.Wrapf(err, "interpolation failed for pre-render ops in instance group '%s'", instanceGroupName)
-			}
-
-			opsBytes, err = interpolator.Interpolate([]byte(propertiesBytes))
+		if ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps != nil {
+			// Apply ops file on the instance group manifest
+			opsBytes, err = withops.InterpolateOps(ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps.InstanceGroup, propertiesBytes)
 			if err != nil {
 				return errors.Wrapf(err, "failed to interpolate pre render ops for instance group '%s'", instanceGroupName)
 			}
@@ -171,21 +161,9 @@ Also calculates and prints the BPM configurations for all BOSH jobs of that inst
 		}
 
 		opsBytes = bpmBytes
-		if (ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps != nil) && (len(ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps.BPM) != 0) {
+		if ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps != nil {
 			// Apply ops for the BPM file
-			interpolator := withops.NewInterpolator()
-
-			opsData, err := ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps.BPM.Bytes()
-			if err != nil {
-				return errors.Wrapf(err, "failed to get bytes for bpm pre render ops in instance group '%s'", instanceGroupName)
-			}
-
-			err = interpolator.AddOps([]byte(opsData))
-			if err != nil {
-				return errors.Wrapf(err, "interpolation failed for bpm pre-render ops in instance group '%s'", instanceGroupName)
-			}
-
-			opsBytes, err = interpolator.Interpolate([]byte(bpmBytes))
+			opsBytes, err = withops.InterpolateOps(ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps.BPM, bpmBytes)
 			if err != nil {
 				return errors.Wrapf(err, "failed to interpolate bpm pre render ops for instance group '%s'", instanceGroupName)
 			}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/linktemplate"
+	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+	"code.cloudfoundry.org/quarks-utils/pkg/logger"
+)
+
+const linkRenderFailedMessage = "link-render command failed."
+
+// linkRenderCmd renders the link templates of an entangled pod
+var linkRenderCmd = &cobra.Command{
+	Use:   "link-render [flags]",
+	Short: "Renders templates with the properties of quarks links",
+	Long: `Renders templates with the properties of quarks links.
+
+Each file of the templates dir is rendered as a Go template into a file of
+the same name in the rendered dir. The links are read from the links dir,
+where the link secrets are mounted on entangled pods.
+
+With the watch flag, the templates are rendered again whenever the
+mounted link secrets change.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("links-dir", cmd.Flags().Lookup("links-dir"))
+		viper.BindPFlag("templates-dir", cmd.Flags().Lookup("templates-dir"))
+		viper.BindPFlag("rendered-dir", cmd.Flags().Lookup("rendered-dir"))
+		viper.BindPFlag("watch", cmd.Flags().Lookup("watch"))
+	},
+	RunE: func(_ *cobra.Command, args []string) (err error) {
+		log = logger.New(cmd.LogLevel())
+		defer func() {
+			_ = log.Sync()
+			if err != nil {
+				time.Sleep(debugGracePeriod)
+			}
+		}()
+
+		renderer := linktemplate.NewRenderer(
+			log,
+			viper.GetString("links-dir"),
+			viper.GetString("templates-dir"),
+			viper.GetString("rendered-dir"),
+		)
+
+		if err := renderer.Render(); err != nil {
+			return errors.Wrap(err, linkRenderFailedMessage)
+		}
+
+		if viper.GetBool("watch") {
+			if err := renderer.Watch(context.Background()); err != nil {
+				return errors.Wrap(err, linkRenderFailedMessage)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	pf := linkRenderCmd.Flags()
+	utilCmd.AddCommand(linkRenderCmd)
+	pf.StringP("links-dir", "", linktemplate.LinksDir, "path to the dir with the mounted link secrets")
+	pf.StringP("templates-dir", "", linktemplate.TemplatesDir, "path to the dir with the templates")
+	pf.StringP("rendered-dir", "", linktemplate.RenderedDir, "path to the dir for the rendered files")
+	pf.BoolP("watch", "", false, "render again, when the link secrets change")
+
+	argToEnv := map[string]string{
+		"links-dir":     "LINKS_DIR",
+		"templates-dir": "TEMPLATES_DIR",
+		"rendered-dir":  "RENDERED_DIR",
+		"watch":         "WATCH",
+	}
+	cmd.AddEnvToUsage(linkRenderCmd, argToEnv)
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/render"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+	"code.cloudfoundry.org/quarks-utils/pkg/logger"
+)
+
+const renderFailedMessage = "render command failed."
+
+// renderCmd prints the k8s resources the operator creates for a BOSH manifest
+var renderCmd = &cobra.Command{
+	Use:   "render [flags]",
+	Short: "Renders the Kubernetes resources of a BOSH manifest",
+	Long: `Renders the Kubernetes resources of a BOSH manifest.
+
+This applies the ops files and vars files to the manifest and prints all
+QuarksSecrets, QuarksJobs, QuarksStatefulSets, Services, instance group
+secrets and BOSH DNS resources, which the operator would create for the
+BOSHDeployment, as YAML. BOSHRuntimeConfig and BOSHCloudConfig resources are
+applied like in the cluster.
+
+The base dir has to contain the unpacked release jobs, like the release
+images do, e.g. '<base-dir>/jobs-src/<release>/<job>/job.MF'.
+
+Implicit variables have to be set in a vars file. Explicit variables, which
+are not set in a vars file, are not resolved.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		boshManifestFlagViperBind(cmd.Flags())
+		baseDirFlagViperBind(cmd.Flags())
+		deploymentNameFlagViperBind(cmd.Flags())
+		for _, name := range []string{"namespace", "dns-service-ip", "coredns-service-account"} {
+			viper.BindPFlag(name, cmd.Flags().Lookup(name))
+		}
+	},
+	RunE: func(c *cobra.Command, args []string) error {
+		log = logger.New(cmd.LogLevel())
+		defer func() {
+			_ = log.Sync()
+		}()
+
+		boshManifestPath, err := boshManifestFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, renderFailedMessage)
+		}
+
+		baseDir, err := baseDirFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, renderFailedMessage)
+		}
+
+		deploymentName, err := deploymentNameFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, renderFailedMessage)
+		}
+
+		opts := render.Options{
+			DeploymentName:        deploymentName,
+			Namespace:             viper.GetString("namespace"),
+			BaseDir:               baseDir,
+			DNSServiceIP:          viper.GetString("dns-service-ip"),
+			CorednsServiceAccount: viper.GetString("coredns-service-account"),
+		}
+
+		opts.Manifest, err = ioutil.ReadFile(boshManifestPath)
+		if err != nil {
+			return errors.Wrapf(err, "%s Reading file specified in the bosh-manifest-path flag failed.", renderFailedMessage)
+		}
+
+		opsFiles, _ := c.Flags().GetStringArray("ops-file")
+		for _, path := range opsFiles {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "%s Reading ops file '%s' failed.", renderFailedMessage, path)
+			}
+			opts.Ops = append(opts.Ops, data)
+		}
+
+		varsFiles, _ := c.Flags().GetStringArray("vars-file")
+		for _, path := range varsFiles {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "%s Reading vars file '%s' failed.", renderFailedMessage, path)
+			}
+			opts.Vars = append(opts.Vars, data)
+		}
+
+		runtimeConfigs, _ := c.Flags().GetStringArray("runtime-config")
+		for _, path := range runtimeConfigs {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "%s Reading runtime config '%s' failed.", renderFailedMessage, path)
+			}
+			opts.RuntimeConfigs = append(opts.RuntimeConfigs, data)
+		}
+
+		if path, _ := c.Flags().GetString("cloud-config"); path != "" {
+			opts.CloudConfig, err = ioutil.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "%s Reading cloud config '%s' failed.", renderFailedMessage, path)
+			}
+		}
+
+		resources, err := render.Render(log, opts)
+		if err != nil {
+			return errors.Wrap(err, renderFailedMessage)
+		}
+
+		err = controllers.AddToScheme(scheme.Scheme)
+		if err != nil {
+			return errors.Wrap(err, renderFailedMessage)
+		}
+
+		objects := []runtime.Object{}
+		for i := range resources.QuarksSecrets {
+			objects = append(objects, &resources.QuarksSecrets[i])
+		}
+		for i := range resources.Secrets {
+			objects = append(objects, &resources.Secrets[i])
+		}
+		for i := range resources.QuarksJobs {
+			objects = append(objects, &resources.QuarksJobs[i])
+		}
+		for i := range resources.QuarksStatefulSets {
+			objects = append(objects, &resources.QuarksStatefulSets[i])
+		}
+		for i := range resources.Services {
+			objects = append(objects, &resources.Services[i])
+		}
+		for i := range resources.ConfigMaps {
+			objects = append(objects, &resources.ConfigMaps[i])
+		}
+		for i := range resources.Deployments {
+			objects = append(objects, &resources.Deployments[i])
+		}
+
+		for _, obj := range objects {
+			gvk, err := apiutil.GVKForObject(obj, scheme.Scheme)
+			if err != nil {
+				return errors.Wrap(err, renderFailedMessage)
+			}
+			obj.GetObjectKind().SetGroupVersionKind(gvk)
+
+			data, err := yaml.Marshal(obj)
+			if err != nil {
+				return errors.Wrapf(err, "%s YAML marshalling %s failed.", renderFailedMessage, gvk.Kind)
+			}
+			fmt.Printf("---\n%s", data)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	pf := renderCmd.Flags()
+	argToEnv := map[string]string{}
+
+	boshManifestFlagCobraSet(pf, argToEnv)
+	baseDirFlagCobraSet(pf, argToEnv)
+	deploymentNameFlagCobraSet(pf, argToEnv)
+	pf.StringArrayP("ops-file", "o", []string{}, "path to an ops file, can be repeated")
+	pf.StringArrayP("vars-file", "l", []string{}, "path to a YAML file with variable values, can be repeated")
+	pf.StringArray("runtime-config", []string{}, "path to a BOSHRuntimeConfig resource, can be repeated")
+	pf.String("cloud-config", "", "path to a BOSHCloudConfig resource")
+	pf.String("namespace", "default", "namespace of the bdpl resource")
+	pf.String("dns-service-ip", "0.0.0.0", "nameserver for the pods, if the BOSH DNS addon is used")
+	pf.String("coredns-service-account", "coredns-quarks", "service account of the BOSH DNS deployment")
+	argToEnv["namespace"] = "NAMESPACE"
+
+	cmd.AddEnvToUsage(renderCmd, argToEnv)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/client/clientset/versioned"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/desiredmanifest"
+	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+	"code.cloudfoundry.org/quarks-utils/pkg/logger"
+	"code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+const rollbackFailedMessage = "rollback command failed."
+
+// rollbackCmd requests the rollback of a BOSHDeployment to an earlier desired
+// manifest version, by setting the rollback annotation
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [flags]",
+	Short: "Rolls back a BOSHDeployment to an earlier desired manifest version",
+	Long: `Rolls back a BOSHDeployment to an earlier desired manifest version.
+
+Without the version flag, this lists the available desired manifest versions.
+With the version flag, the operator creates a new desired manifest version
+from the chosen one, which is then deployed like any other update.
+
+The next change to the BOSHDeployment or its manifest and ops files renders
+the desired manifest from the BOSHDeployment again.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		deploymentNameFlagViperBind(cmd.Flags())
+		viper.BindPFlag("namespace", cmd.Flags().Lookup("namespace"))
+		viper.BindPFlag("version", cmd.Flags().Lookup("version"))
+	},
+	RunE: func(_ *cobra.Command, args []string) error {
+		log = logger.New(cmd.LogLevel())
+		defer func() {
+			_ = log.Sync()
+		}()
+
+		deploymentName, err := deploymentNameFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, rollbackFailedMessage)
+		}
+		namespace := viper.GetString("namespace")
+		version := viper.GetInt("version")
+
+		restConfig, err := cmd.KubeConfig(log)
+		if err != nil {
+			return errors.Wrap(err, rollbackFailedMessage)
+		}
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return errors.Wrap(err, rollbackFailedMessage)
+		}
+
+		ctx := context.Background()
+		store := versionedsecretstore.NewClientsetVersionedSecretStore(clientset)
+		secrets, err := store.List(ctx, namespace, desiredmanifest.SecretName(deploymentName))
+		if err != nil {
+			return errors.Wrapf(err, "%s Listing desired manifest versions of '%s/%s' failed.", rollbackFailedMessage, namespace, deploymentName)
+		}
+
+		versions := map[int]string{}
+		for _, s := range secrets {
+			v, err := strconv.Atoi(s.Labels[versionedsecretstore.LabelVersion])
+			if err != nil {
+				continue
+			}
+			versions[v] = fmt.Sprintf("%d\t%s\t%s", v, s.CreationTimestamp.Format("2006-01-02T15:04:05Z07:00"), s.Annotations[versionedsecretstore.AnnotationSourceDescription])
+		}
+
+		if version == 0 {
+			keys := make([]int, 0, len(versions))
+			for v := range versions {
+				keys = append(keys, v)
+			}
+			sort.Ints(keys)
+			fmt.Printf("Desired manifest versions of BOSHDeployment '%s/%s':\n", namespace, deploymentName)
+			for _, v := range keys {
+				fmt.Println(versions[v])
+			}
+			return nil
+		}
+
+		if _, ok := versions[version]; !ok {
+			return errors.Errorf("%s Desired manifest version %d of '%s/%s' does not exist.", rollbackFailedMessage, version, namespace, deploymentName)
+		}
+
+		bdplClient, err := versioned.NewForConfig(restConfig)
+		if err != nil {
+			return errors.Wrap(err, rollbackFailedMessage)
+		}
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{"%s":"%d"}}}`, bdv1.AnnotationRollbackTo, version)
+		_, err = bdplClient.BoshdeploymentV1alpha1().BOSHDeployments(namespace).Patch(ctx, deploymentName, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "%s Annotating BOSHDeployment '%s/%s' failed.", rollbackFailedMessage, namespace, deploymentName)
+		}
+
+		fmt.Printf("Requested rollback of BOSHDeployment '%s/%s' to desired manifest version %d\n", namespace, deploymentName, version)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	pf := rollbackCmd.Flags()
+	argToEnv := map[string]string{}
+
+	deploymentNameFlagCobraSet(pf, argToEnv)
+	pf.String("namespace", "default", "namespace of the bdpl resource")
+	pf.Int("version", 0, "desired manifest version to roll back to, lists the versions if not set")
+	argToEnv["namespace"] = "NAMESPACE"
+
+	cmd.AddEnvToUsage(rollbackCmd, argToEnv)
+}
@@ -14,6 +14,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc" // from https://github.com/kubernetes/client-go/issues/345
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
@@ -102,12 +103,17 @@ var rootCmd = &cobra.Command{
 			return wrapError(err, "Couldn't apply CRDs.")
 		}
 
-		mgr, err := operator.NewManager(ctx, cfg, restConfig, manager.Options{
-			MetricsBindAddress: "0",
-			LeaderElection:     false,
+		options := manager.Options{
+			MetricsBindAddress: viper.GetString("metrics-bind-address"),
 			Port:               managerPort,
 			Host:               "0.0.0.0",
-		})
+		}
+		err = leaderElection(&options, cfg)
+		if err != nil {
+			return wrapError(err, "")
+		}
+
+		mgr, err := operator.NewManager(ctx, cfg, restConfig, options)
 		if err != nil {
 			return wrapError(err, "Failed to create new manager.")
 		}
@@ -123,6 +129,42 @@ var rootCmd = &cobra.Command{
 	TraverseChildren: true,
 }
 
+// leaderElection configures the leader election of the manager. Only the
+// leader runs the controllers, while every replica serves the webhooks.
+func leaderElection(options *manager.Options, cfg *config.Config) error {
+	if !viper.GetBool("leader-election") {
+		return nil
+	}
+
+	leaseDuration := viper.GetDuration("leader-election-lease-duration")
+	renewDeadline := viper.GetDuration("leader-election-renew-deadline")
+	retryPeriod := viper.GetDuration("leader-election-retry-period")
+	if renewDeadline >= leaseDuration {
+		return errors.Errorf("leader-election-renew-deadline (%s) must be less than leader-election-lease-duration (%s)", renewDeadline, leaseDuration)
+	}
+	if retryPeriod >= renewDeadline {
+		return errors.Errorf("leader-election-retry-period (%s) must be less than leader-election-renew-deadline (%s)", retryPeriod, renewDeadline)
+	}
+
+	namespace := viper.GetString("leader-election-namespace")
+	if namespace == "" {
+		namespace = cfg.OperatorNamespace
+	}
+
+	options.LeaderElection = true
+	options.LeaderElectionResourceLock = resourcelock.LeasesResourceLock
+	options.LeaderElectionNamespace = namespace
+	options.LeaderElectionID = viper.GetString("leader-election-id")
+	// Hand over the lease on shutdown, so the next replica doesn't wait for it to expire
+	options.LeaderElectionReleaseOnCancel = true
+	options.LeaseDuration = &leaseDuration
+	options.RenewDeadline = &renewDeadline
+	options.RetryPeriod = &retryPeriod
+
+	log.Infof("Leader election enabled, using lease '%s/%s'", namespace, options.LeaderElectionID)
+	return nil
+}
+
 // NewCFOperatorCommand returns the `quarks-operator` command.
 func NewCFOperatorCommand() *cobra.Command {
 	return rootCmd
@@ -152,8 +194,15 @@ func init() {
 
 	pf.StringP("bosh-dns-docker-image", "", "coredns/coredns:1.6.3", "The docker image used for emulating bosh DNS (a CoreDNS image)")
 	pf.String("cluster-domain", "cluster.local", "The Kubernetes cluster domain")
+	pf.Bool("leader-election", false, "Enable leader election, so only one of several operator replicas runs the controllers")
+	pf.String("leader-election-id", "quarks-operator-leader", "Name of the lease used for leader election")
+	pf.String("leader-election-namespace", "", "Namespace of the lease used for leader election, defaults to the operator namespace")
+	pf.Duration("leader-election-lease-duration", 15*time.Second, "Duration non-leader replicas wait before taking over an unrenewed lease")
+	pf.Duration("leader-election-renew-deadline", 10*time.Second, "Duration the leader retries renewing the lease before giving up leadership")
+	pf.Duration("leader-election-retry-period", 2*time.Second, "Duration between attempts to acquire or renew the lease")
 	pf.IntP("logrotate-interval", "i", 24*60, "Interval between logrotate calls for instance groups in minutes")
 	pf.Int("max-boshdeployment-workers", 1, "Maximum number of workers concurrently running BOSHDeployment controller")
+	pf.String("metrics-bind-address", "0", "Address the prometheus metrics endpoint binds to, e.g. ':60000'. '0' disables the metrics endpoint")
 	pf.StringP("operator-webhook-service-host", "w", "", "Hostname/IP under which the webhook server can be reached from the cluster")
 	pf.StringP("operator-webhook-service-port", "p", "2999", "Port the webhook server listens on")
 	pf.BoolP("operator-webhook-use-service-reference", "x", false, "If true the webhook service is targeted using a service reference instead of a URL")
@@ -161,8 +210,15 @@ func init() {
 	for _, name := range []string{
 		"bosh-dns-docker-image",
 		"cluster-domain",
+		"leader-election",
+		"leader-election-id",
+		"leader-election-namespace",
+		"leader-election-lease-duration",
+		"leader-election-renew-deadline",
+		"leader-election-retry-period",
 		"logrotate-interval",
 		"max-boshdeployment-workers",
+		"metrics-bind-address",
 		"operator-webhook-service-host",
 		"operator-webhook-service-port",
 		"operator-webhook-use-service-reference",
@@ -172,8 +228,15 @@ func init() {
 
 	argToEnv["bosh-dns-docker-image"] = "BOSH_DNS_DOCKER_IMAGE"
 	argToEnv["cluster-domain"] = "CLUSTER_DOMAIN"
+	argToEnv["leader-election"] = "LEADER_ELECTION"
+	argToEnv["leader-election-id"] = "LEADER_ELECTION_ID"
+	argToEnv["leader-election-namespace"] = "LEADER_ELECTION_NAMESPACE"
+	argToEnv["leader-election-lease-duration"] = "LEADER_ELECTION_LEASE_DURATION"
+	argToEnv["leader-election-renew-deadline"] = "LEADER_ELECTION_RENEW_DEADLINE"
+	argToEnv["leader-election-retry-period"] = "LEADER_ELECTION_RETRY_PERIOD"
 	argToEnv["logrotate-interval"] = "LOGROTATE_INTERVAL"
 	argToEnv["max-boshdeployment-workers"] = "MAX_BOSHDEPLOYMENT_WORKERS"
+	argToEnv["metrics-bind-address"] = "METRICS_BIND_ADDRESS"
 	argToEnv["operator-webhook-service-host"] = "CF_OPERATOR_WEBHOOK_SERVICE_HOST"
 	argToEnv["operator-webhook-service-port"] = "CF_OPERATOR_WEBHOOK_SERVICE_PORT"
 	argToEnv["operator-webhook-use-service-reference"] = "CF_OPERATOR_WEBHOOK_USE_SERVICE_REFERENCE"
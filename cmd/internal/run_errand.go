@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/client/clientset/versioned"
+	"code.cloudfoundry.org/quarks-utils/pkg/cmd"
+	"code.cloudfoundry.org/quarks-utils/pkg/logger"
+)
+
+const runErrandFailedMessage = "run-errand command failed."
+
+// runErrandCmd requests a single run of an errand of a BOSHDeployment, by
+// setting the run-errand annotation, and optionally waits for its result
+var runErrandCmd = &cobra.Command{
+	Use:   "run-errand [flags]",
+	Short: "Runs an errand of a BOSHDeployment once",
+	Long: `Runs an errand of a BOSHDeployment once.
+
+The operator triggers the QuarksJob of the errand instance group and records
+the exit code, start and end time of the errand pod in the BOSHDeployment
+status. The tail of the container logs is stored in a config map, which is
+referenced by the status.
+
+With the wait flag, this waits for the errand to finish, prints its result
+and logs and fails, if the errand failed.
+
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		deploymentNameFlagViperBind(cmd.Flags())
+		viper.BindPFlag("namespace", cmd.Flags().Lookup("namespace"))
+		viper.BindPFlag("errand", cmd.Flags().Lookup("errand"))
+		viper.BindPFlag("wait", cmd.Flags().Lookup("wait"))
+		viper.BindPFlag("timeout", cmd.Flags().Lookup("timeout"))
+	},
+	RunE: func(_ *cobra.Command, args []string) error {
+		log = logger.New(cmd.LogLevel())
+		defer func() {
+			_ = log.Sync()
+		}()
+
+		deploymentName, err := deploymentNameFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, runErrandFailedMessage)
+		}
+		namespace := viper.GetString("namespace")
+		errand := viper.GetString("errand")
+		if errand == "" {
+			return errors.Errorf("%s errand flag is empty.", runErrandFailedMessage)
+		}
+
+		restConfig, err := cmd.KubeConfig(log)
+		if err != nil {
+			return errors.Wrap(err, runErrandFailedMessage)
+		}
+		bdplClient, err := versioned.NewForConfig(restConfig)
+		if err != nil {
+			return errors.Wrap(err, runErrandFailedMessage)
+		}
+		bdpls := bdplClient.BoshdeploymentV1alpha1().BOSHDeployments(namespace)
+
+		ctx := context.Background()
+		bdpl, err := bdpls.Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "%s Getting BOSHDeployment '%s/%s' failed.", runErrandFailedMessage, namespace, deploymentName)
+		}
+		previous := errandRequestTime(bdpl, errand)
+
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{"%s":"%s"}}}`, bdv1.AnnotationRunErrand, errand)
+		_, err = bdpls.Patch(ctx, deploymentName, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "%s Annotating BOSHDeployment '%s/%s' failed.", runErrandFailedMessage, namespace, deploymentName)
+		}
+		fmt.Printf("Requested run of errand '%s' of BOSHDeployment '%s/%s'\n", errand, namespace, deploymentName)
+
+		if !viper.GetBool("wait") {
+			return nil
+		}
+
+		var status *bdv1.ErrandStatus
+		err = wait.PollImmediate(2*time.Second, viper.GetDuration("timeout"), func() (bool, error) {
+			bdpl, err := bdpls.Get(ctx, deploymentName, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			for _, s := range bdpl.Status.Errands {
+				if s.Name != errand || s.RequestTime == nil || s.RequestTime.Equal(previous) {
+					continue
+				}
+				if s.State != bdv1.ErrandStateRequested {
+					status = s.DeepCopy()
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "%s Waiting for errand '%s' of BOSHDeployment '%s/%s' failed.", runErrandFailedMessage, errand, namespace, deploymentName)
+		}
+
+		if status.ExitCode == nil {
+			return errors.Errorf("%s Errand '%s' of BOSHDeployment '%s/%s' did not run: %s", runErrandFailedMessage, errand, namespace, deploymentName, status.Message)
+		}
+
+		fmt.Printf("Pod:\t\t%s\nStart time:\t%s\nEnd time:\t%s\nExit code:\t%d\n", status.Pod, formatTime(status.StartTime), formatTime(status.EndTime), *status.ExitCode)
+
+		if status.LogsConfigMap != "" {
+			clientset, err := kubernetes.NewForConfig(restConfig)
+			if err != nil {
+				return errors.Wrap(err, runErrandFailedMessage)
+			}
+			configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, status.LogsConfigMap, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrapf(err, "%s Getting logs of errand '%s' from config map '%s/%s' failed.", runErrandFailedMessage, errand, namespace, status.LogsConfigMap)
+			}
+
+			keys := make([]string, 0, len(configMap.Data))
+			for k := range configMap.Data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Printf("\n==> %s <==\n%s\n", k, configMap.Data[k])
+			}
+		}
+
+		if status.State != bdv1.ErrandStateSucceeded {
+			return errors.Errorf("%s Errand '%s' of BOSHDeployment '%s/%s' exited with code %d.", runErrandFailedMessage, errand, namespace, deploymentName, *status.ExitCode)
+		}
+		return nil
+	},
+}
+
+// errandRequestTime returns the request time of the errand's last run
+func errandRequestTime(bdpl *bdv1.BOSHDeployment, errand string) *metav1.Time {
+	for _, s := range bdpl.Status.Errands {
+		if s.Name == errand {
+			return s.RequestTime
+		}
+	}
+	return nil
+}
+
+func formatTime(t *metav1.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02T15:04:05Z07:00")
+}
+
+func init() {
+	rootCmd.AddCommand(runErrandCmd)
+
+	pf := runErrandCmd.Flags()
+	argToEnv := map[string]string{}
+
+	deploymentNameFlagCobraSet(pf, argToEnv)
+	pf.String("namespace", "default", "namespace of the bdpl resource")
+	pf.String("errand", "", "name of the errand instance group to run")
+	pf.Bool("wait", false, "wait for the errand to finish and print its result and logs")
+	pf.Duration("timeout", 10*time.Minute, "how long to wait for the errand to finish")
+	argToEnv["namespace"] = "NAMESPACE"
+	argToEnv["errand"] = "ERRAND"
+
+	cmd.AddEnvToUsage(runErrandCmd, argToEnv)
+}
@@ -32,6 +32,7 @@ This will render a provided manifest instance-group
 `,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		boshManifestFlagViperBind(cmd.Flags())
+		deploymentNameFlagViperBind(cmd.Flags())
 		instanceGroupFlagViperBind(cmd.Flags())
 		initialRolloutFlagViperBind(cmd.Flags())
 	},
@@ -48,6 +49,11 @@ This will render a provided manifest instance-group
 			return errors.Wrap(err, tRenderFailedMessage)
 		}
 
+		deploymentName, err := deploymentNameFlagValidation()
+		if err != nil {
+			return errors.Wrap(err, tRenderFailedMessage)
+		}
+
 		jobsDir := viper.GetString("jobs-dir")
 		outputDir := viper.GetString("output-dir")
 
@@ -90,7 +96,7 @@ This will render a provided manifest instance-group
 		initialRollout := viper.GetBool("initial-rollout")
 		podIP := net.ParseIP(viper.GetString("pod-ip"))
 
-		return manifest.RenderJobTemplates(boshManifestPath, jobsDir, outputDir, instanceGroupName, podIP,
+		return manifest.RenderJobTemplates(deploymentName, boshManifestPath, jobsDir, outputDir, instanceGroupName, podIP,
 			azIndex, podOrdinal, replicas, initialRollout)
 	},
 }
@@ -123,6 +129,7 @@ func init() {
 	}
 
 	boshManifestFlagCobraSet(pf, argToEnv)
+	deploymentNameFlagCobraSet(pf, argToEnv)
 	instanceGroupFlagCobraSet(pf, argToEnv)
 	initialRolloutFlagCobraSet(pf, argToEnv)
 	cmd.AddEnvToUsage(templateRenderCmd, argToEnv)
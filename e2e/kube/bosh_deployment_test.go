@@ -39,16 +39,16 @@ var _ = Describe("BOSHDeployment", func() {
 			Expect(status.StartTime).To(Equal(startTime), "error pod must not be restarted")
 
 			By("Checking for secrets not created")
-			exist, err := kubectl.SecretExists(namespace, "bpm.nats-v2")
-			Expect(err).ToNot(HaveOccurred(), "error getting secret/bpm.nats-v2")
+			exist, err := kubectl.SecretExists(namespace, "nats-deployment.bpm.nats-v2")
+			Expect(err).ToNot(HaveOccurred(), "error getting secret/nats-deployment.bpm.nats-v2")
 			Expect(exist).To(BeFalse(), "error unexpected bpm info secret is created")
 
-			exist, err = kubectl.SecretExists(namespace, "desired-manifest-v2")
-			Expect(err).ToNot(HaveOccurred(), "error getting secret/desired-manifest-v2")
+			exist, err = kubectl.SecretExists(namespace, "nats-deployment.desired-manifest-v2")
+			Expect(err).ToNot(HaveOccurred(), "error getting secret/nats-deployment.desired-manifest-v2")
 			Expect(exist).To(BeFalse(), "error unexpected desire manifest is created")
 
-			exist, err = kubectl.SecretExists(namespace, "ig-resolved.nats-v2")
-			Expect(err).ToNot(HaveOccurred(), "error getting secret/ig-resolved.nats-v2")
+			exist, err = kubectl.SecretExists(namespace, "nats-deployment.ig-resolved.nats-v2")
+			Expect(err).ToNot(HaveOccurred(), "error getting secret/nats-deployment.ig-resolved.nats-v2")
 			Expect(exist).To(BeFalse(), "error unexpected properties secret is created")
 		})
 	})
@@ -134,13 +134,13 @@ var _ = Describe("Examples Directory", func() {
 			waitReady("pod/nats-0")
 
 			// Check that we didn't create new secrets
-			sd, err := cmdHelper.GetData(namespace, "secret", "var-nats-password", "go-template={{.data}}")
+			sd, err := cmdHelper.GetData(namespace, "secret", "nats-deployment.var-nats-password", "go-template={{.data}}")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(sd).To(BeEmpty())
-			sd, err = cmdHelper.GetData(namespace, "secret", "var-nats-ca", "go-template={{.data}}")
+			sd, err = cmdHelper.GetData(namespace, "secret", "nats-deployment.var-nats-ca", "go-template={{.data}}")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(sd).To(BeEmpty())
-			sd, err = cmdHelper.GetData(namespace, "secret", "var-nats-cert", "go-template={{.data}}")
+			sd, err = cmdHelper.GetData(namespace, "secret", "nats-deployment.var-nats-cert", "go-template={{.data}}")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(sd).To(BeEmpty())
 
@@ -232,7 +232,7 @@ var _ = Describe("Examples Directory", func() {
 			By("Getting expected IP")
 			podName := "nats-0"
 			waitReady(fmt.Sprintf("pod/%s", podName))
-			serviceName := "nats-0"
+			serviceName := "nats-deployment-nats-0"
 			service, err := kubectl.Service(namespace, serviceName)
 			Expect(err).ToNot(HaveOccurred())
 
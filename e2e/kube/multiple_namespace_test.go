@@ -26,17 +26,17 @@ var _ = Describe("Deploying in multiple namespace", func() {
 			applyNamespace(namespace, "bosh-deployment/quarks-gora.yaml")
 			waitReadyNamespace(namespace, "pod/quarks-gora-0")
 			waitReadyNamespace(namespace, "pod/quarks-gora-1")
-			err := kubectl.WaitForService(namespace, "quarks-gora-0")
+			err := kubectl.WaitForService(namespace, "quarks-gora-deployment-quarks-gora-0")
 			Expect(err).ToNot(HaveOccurred())
-			err = kubectl.WaitForService(namespace, "quarks-gora-1")
+			err = kubectl.WaitForService(namespace, "quarks-gora-deployment-quarks-gora-1")
 			Expect(err).ToNot(HaveOccurred())
 
 			applyNamespace(newNamespace, "bosh-deployment/quarks-gora.yaml")
 			waitReadyNamespace(newNamespace, "pod/quarks-gora-0")
 			waitReadyNamespace(newNamespace, "pod/quarks-gora-1")
-			err = kubectl.WaitForService(newNamespace, "quarks-gora-0")
+			err = kubectl.WaitForService(newNamespace, "quarks-gora-deployment-quarks-gora-0")
 			Expect(err).ToNot(HaveOccurred())
-			err = kubectl.WaitForService(newNamespace, "quarks-gora-1")
+			err = kubectl.WaitForService(newNamespace, "quarks-gora-deployment-quarks-gora-1")
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
@@ -60,30 +60,30 @@ var _ = Describe("Deploying in multiple namespace", func() {
 			applyNamespace(namespace, "bosh-deployment/quarks-gora.yaml")
 			waitReadyNamespace(namespace, "pod/quarks-gora-0")
 			waitReadyNamespace(namespace, "pod/quarks-gora-1")
-			err := kubectl.WaitForService(namespace, "quarks-gora-0")
+			err := kubectl.WaitForService(namespace, "quarks-gora-deployment-quarks-gora-0")
 			Expect(err).ToNot(HaveOccurred())
-			err = kubectl.WaitForService(namespace, "quarks-gora-1")
+			err = kubectl.WaitForService(namespace, "quarks-gora-deployment-quarks-gora-1")
 			Expect(err).ToNot(HaveOccurred())
 
 			applyNamespace(newNamespace, "bosh-deployment/quarks-gora.yaml")
 			waitReadyNamespace(newNamespace, "pod/quarks-gora-0")
 			waitReadyNamespace(newNamespace, "pod/quarks-gora-1")
-			err = kubectl.WaitForService(newNamespace, "quarks-gora-0")
+			err = kubectl.WaitForService(newNamespace, "quarks-gora-deployment-quarks-gora-0")
 			Expect(err).ToNot(HaveOccurred())
-			err = kubectl.WaitForService(newNamespace, "quarks-gora-1")
+			err = kubectl.WaitForService(newNamespace, "quarks-gora-deployment-quarks-gora-1")
 			Expect(err).ToNot(HaveOccurred())
 
 			scale(namespace, "3")
 			waitReadyNamespace(namespace, "pod/quarks-gora-2")
-			err = kubectl.WaitForService(namespace, "quarks-gora-2")
+			err = kubectl.WaitForService(namespace, "quarks-gora-deployment-quarks-gora-2")
 			Expect(err).ToNot(HaveOccurred())
 
 			scale(newNamespace, "4")
 			waitReadyNamespace(newNamespace, "pod/quarks-gora-3")
-			err = kubectl.WaitForService(newNamespace, "quarks-gora-3")
+			err = kubectl.WaitForService(newNamespace, "quarks-gora-deployment-quarks-gora-3")
 			Expect(err).ToNot(HaveOccurred())
 
-			e, err := kubectl.ServiceExists(namespace, "quarks-gora-3")
+			e, err := kubectl.ServiceExists(namespace, "quarks-gora-deployment-quarks-gora-3")
 			Expect(err).To(HaveOccurred())
 			Expect(e).ToNot(BeTrue())
 		})
@@ -29,7 +29,7 @@ var _ = Describe("BOSH deployment provides links to native k8s resources", func(
 
 	Context("when creating a bosh deployment", func() {
 		It("creates secrets for a all BOSH links", func() {
-			exist, err := kubectl.SecretExists(namespace, "link-nats-nats")
+			exist, err := kubectl.SecretExists(namespace, "link-nats-deployment-nats-nats")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(exist).To(BeTrue())
 		})
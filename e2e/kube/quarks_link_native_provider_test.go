@@ -14,7 +14,7 @@ import (
 
 var _ = Describe("K8s native resources provide BOSH links to a BOSH deployment", func() {
 	jobLink := func(name string) manifest.JobLink {
-		enc, err := cmdHelper.GetData(namespace, "secret", "ig-resolved.quarks-gora-v1", `go-template={{index .data "properties.yaml"}}`)
+		enc, err := cmdHelper.GetData(namespace, "secret", "cfo-test-deployment.ig-resolved.quarks-gora-v1", `go-template={{index .data "properties.yaml"}}`)
 		Expect(err).ToNot(HaveOccurred())
 		decoded, _ := base64.StdEncoding.DecodeString(string(enc))
 
@@ -29,7 +29,7 @@ var _ = Describe("K8s native resources provide BOSH links to a BOSH deployment",
 	Context("when the link has an underscore in its name", func() {
 		BeforeEach(func() {
 			apply("quarks-link/native-to-bosh/underscore.yaml")
-			err := kubectl.WaitForSecret(namespace, "ig-resolved.quarks-gora-v1")
+			err := kubectl.WaitForSecret(namespace, "cfo-test-deployment.ig-resolved.quarks-gora-v1")
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -68,7 +68,7 @@ var _ = Describe("K8s native resources provide BOSH links to a BOSH deployment",
 		JustBeforeEach(func() {
 			// after creating the service, create a deployment to assert against
 			apply("quarks-link/native-to-bosh/boshdeployment.yaml")
-			err := kubectl.WaitForSecret(namespace, "ig-resolved.quarks-gora-v1")
+			err := kubectl.WaitForSecret(namespace, "cfo-test-deployment.ig-resolved.quarks-gora-v1")
 			Expect(err).ToNot(HaveOccurred())
 
 		})
@@ -29,7 +29,7 @@ var _ = Describe("Examples", func() {
 				literalValues := map[string]string{
 					"value": class,
 				}
-				err := cmdHelper.CreateSecretFromLiteral(namespace, "var-operator-test-storage-class", literalValues)
+				err := cmdHelper.CreateSecretFromLiteral(namespace, "nats-deployment.var-operator-test-storage-class", literalValues)
 				Expect(err).ToNot(HaveOccurred())
 
 				By("Creating bosh deployment")
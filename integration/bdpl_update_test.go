@@ -83,7 +83,7 @@ var _ = Describe("BDPL updates", func() {
 			})
 
 			It("should create a new secret for the variable", func() {
-				err := env.WaitForSecret(env.Namespace, "var-nats-password")
+				err := env.WaitForSecret(env.Namespace, deploymentName+".var-nats-password")
 				Expect(err).NotTo(HaveOccurred(), "error waiting for new generated variable secret")
 			})
 		})
@@ -146,13 +146,13 @@ var _ = Describe("BDPL updates", func() {
 			})
 
 			It("should update the service with new port", func() {
-				err := env.WaitForSecret(env.Namespace, "bpm.nats-v2")
+				err := env.WaitForSecret(env.Namespace, deploymentName+".bpm.nats-v2")
 				Expect(err).NotTo(HaveOccurred(), "error waiting for new bpm config")
 
-				err = env.WaitForServiceVersion(env.Namespace, "nats", "2")
+				err = env.WaitForServiceVersion(env.Namespace, deploymentName+"-nats", "2")
 				Expect(err).NotTo(HaveOccurred(), "error waiting for service from deployment")
 
-				svc, err := env.GetService(env.Namespace, "nats")
+				svc, err := env.GetService(env.Namespace, deploymentName+"-nats")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(svc.Spec.Ports).To(ContainElement(corev1.ServicePort{
 					Name:       "fake-port",
@@ -172,7 +172,7 @@ var _ = Describe("BDPL updates", func() {
 			// We define "downtime" as more than 5 errors, while checking every 200ms during the update
 			It("should be zero", func() {
 				By("Setting up a NodePort service")
-				svc, err := env.GetService(env.Namespace, "nats-0")
+				svc, err := env.GetService(env.Namespace, deploymentName+"-nats-0")
 				Expect(err).NotTo(HaveOccurred(), "error retrieving clusterIP service")
 
 				tearDown, err := env.CreateService(env.Namespace, env.NodePortService("nats-service", "nats", svc.Spec.Ports[0].Port))
@@ -288,7 +288,7 @@ var _ = Describe("BDPL updates", func() {
 			})
 
 			It("should update the dns pods", func() {
-				deployment, err := env.CollectDeployment(env.Namespace, boshdns.AppName, 1)
+				deployment, err := env.CollectDeployment(env.Namespace, boshdns.ResourceName(deploymentName), 1)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(deployment.Spec.Template.GetAnnotations()).To(HaveKey(quarksrestart.RestartKey))
 			})
@@ -394,8 +394,8 @@ var _ = Describe("BDPL updates", func() {
 
 		Context("unnecessary secret updates should not happen", func() {
 			It("update the instance group", func() {
-				secret, err := env.GetSecret(env.Namespace, "var-nats-password")
-				Expect(err).NotTo(HaveOccurred(), "error getting var-nats-password secret")
+				secret, err := env.GetSecret(env.Namespace, deploymentName+".var-nats-password")
+				Expect(err).NotTo(HaveOccurred(), "error getting nats-password variable secret")
 				passwordv1 := string(secret.Data["password"])
 
 				By("updating the deployment")
@@ -413,8 +413,8 @@ var _ = Describe("BDPL updates", func() {
 				err = env.WaitForInstanceGroupVersions(env.Namespace, deploymentName, "nats", 2, "2")
 				Expect(err).NotTo(HaveOccurred(), "error waiting for instance group pods from deployment")
 
-				secret, err = env.GetSecret(env.Namespace, "var-nats-password")
-				Expect(err).NotTo(HaveOccurred(), "error getting var-nats-password secret")
+				secret, err = env.GetSecret(env.Namespace, deploymentName+".var-nats-password")
+				Expect(err).NotTo(HaveOccurred(), "error getting nats-password variable secret")
 				passwordv2 := string(secret.Data["password"])
 				Expect(passwordv1).To(Equal(passwordv2))
 			})
@@ -434,10 +434,10 @@ var _ = Describe("BDPL updates", func() {
 			})
 
 			It("should use the value from the user's secret", func() {
-				err := env.WaitForSecret(env.Namespace, "desired-manifest-v2")
+				err := env.WaitForSecret(env.Namespace, deploymentName+".desired-manifest-v2")
 				Expect(err).NotTo(HaveOccurred(), "error waiting for new desired manifest")
 
-				secret, err := env.GetSecret(env.Namespace, "desired-manifest-v2")
+				secret, err := env.GetSecret(env.Namespace, deploymentName+".desired-manifest-v2")
 				Expect(err).NotTo(HaveOccurred(), "error getting new desired manifest")
 
 				manifest := string(secret.Data["manifest.yaml"])
@@ -446,17 +446,17 @@ var _ = Describe("BDPL updates", func() {
 			})
 
 			It("should update when the user's secret changes", func() {
-				err := env.WaitForSecret(env.Namespace, "desired-manifest-v2")
+				err := env.WaitForSecret(env.Namespace, deploymentName+".desired-manifest-v2")
 				Expect(err).NotTo(HaveOccurred(), "error waiting for new desired manifest")
 
 				_, tearDown, err := env.UpdateSecret(env.Namespace, env.UserExplicitPassword("my-var", "anothersupersecret"))
 				Expect(err).NotTo(HaveOccurred(), "error updating user var")
 				tearDowns = append(tearDowns, tearDown)
 
-				err = env.WaitForSecret(env.Namespace, "desired-manifest-v3")
+				err = env.WaitForSecret(env.Namespace, deploymentName+".desired-manifest-v3")
 				Expect(err).NotTo(HaveOccurred(), "error waiting for new desired manifest")
 
-				secret, err := env.GetSecret(env.Namespace, "desired-manifest-v3")
+				secret, err := env.GetSecret(env.Namespace, deploymentName+".desired-manifest-v3")
 				Expect(err).NotTo(HaveOccurred(), "error getting new desired manifest")
 
 				manifest := string(secret.Data["manifest.yaml"])
@@ -490,7 +490,7 @@ var _ = Describe("BDPL updates", func() {
 				Expect(err).NotTo(HaveOccurred())
 				tearDowns = append(tearDowns, tearDown)
 
-				_, err = env.GetService(env.Namespace, "nats")
+				_, err = env.GetService(env.Namespace, deploymentName+"-nats")
 				Expect(err).To(BeNil())
 				bdpl, err := env.GetBOSHDeployment(env.Namespace, deploymentName)
 				Expect(err).NotTo(HaveOccurred())
@@ -504,11 +504,11 @@ var _ = Describe("BDPL updates", func() {
 				err := env.WaitForQuarksStatefulSetDelete(env.Namespace, "nats")
 				Expect(err).NotTo(HaveOccurred())
 
-				_, err = env.GetService(env.Namespace, "nats")
+				_, err = env.GetService(env.Namespace, deploymentName+"-nats")
 				Expect(err).To(HaveOccurred())
-				_, err = env.GetService(env.Namespace, "nats-0")
+				_, err = env.GetService(env.Namespace, deploymentName+"-nats-0")
 				Expect(err).To(HaveOccurred())
-				_, err = env.GetService(env.Namespace, "nats-1")
+				_, err = env.GetService(env.Namespace, deploymentName+"-nats-1")
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -532,7 +532,7 @@ var _ = Describe("BDPL updates", func() {
 		Context("by rotating the explicit secret", func() {
 			BeforeEach(func() {
 				qsecCatalog := qsecm.Catalog{}
-				rotationConfig := qsecCatalog.RotationConfig("var-nats-password")
+				rotationConfig := qsecCatalog.RotationConfig(deploymentName + ".var-nats-password")
 				tearDown, err := env.CreateConfigMap(env.Namespace, rotationConfig)
 				Expect(err).NotTo(HaveOccurred())
 				tearDowns = append(tearDowns, tearDown)
@@ -581,7 +581,7 @@ var _ = Describe("BDPL updates", func() {
 				if err != nil {
 					return err
 				}
-				if pod.Spec.Volumes[4].Secret.SecretName != "ig-resolved.nats-v2" {
+				if pod.Spec.Volumes[4].Secret.SecretName != deploymentName+".ig-resolved.nats-v2" {
 					return fmt.Errorf("wrong ig resolved secret version")
 				}
 				Expect(pod.Spec.InitContainers[2].VolumeMounts[2].Name).To(Equal("ig-resolved"))
@@ -590,7 +590,7 @@ var _ = Describe("BDPL updates", func() {
 				if err != nil {
 					return err
 				}
-				if pod.Spec.Volumes[4].Secret.SecretName != "ig-resolved.route-registrar-v2" {
+				if pod.Spec.Volumes[4].Secret.SecretName != deploymentName+".ig-resolved.route-registrar-v2" {
 					return fmt.Errorf("wrong ig resolved secret version")
 				}
 				Expect(pod.Spec.InitContainers[2].VolumeMounts[2].Name).To(Equal("ig-resolved"))
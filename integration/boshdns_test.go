@@ -85,7 +85,7 @@ var _ = Describe("BOSH DNS coredns", func() {
 			boshdns.SetBoshDNSDockerImage("coredns/coredns:1.7.0")
 			boshdns.SetClusterDomain("cluster.local")
 
-			dns := boshdns.NewBoshDomainNameService(bdm.InstanceGroups{})
+			dns := boshdns.NewBoshDomainNameService("test", bdm.InstanceGroups{})
 
 			err := dns.Add(loadAddOn(handlerAddon))
 			Expect(err).NotTo(HaveOccurred())
@@ -106,7 +106,7 @@ var _ = Describe("BOSH DNS coredns", func() {
 		})
 
 		It("config is valid and deployment starts", func() {
-			err := env.WaitForDeployment(env.Namespace, boshdns.AppName, 0)
+			err := env.WaitForDeployment(env.Namespace, boshdns.ResourceName("test"), 0)
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
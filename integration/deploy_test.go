@@ -63,8 +63,8 @@ var _ = Describe("Deploy", func() {
 
 	Context("when using the default configuration", func() {
 		const (
-			headlessSvcName  = "quarks-gora"
-			clusterIPSvcName = "quarks-gora-0"
+			headlessSvcName  = "test-quarks-gora"
+			clusterIPSvcName = "test-quarks-gora-0"
 		)
 
 		It("should deploy a pod and create services", func() {
@@ -419,10 +419,17 @@ var _ = Describe("Deploy", func() {
 			tearDowns = append(tearDowns, tearDown)
 		})
 
-		It("fails to create the resource", func() {
-			_, _, err := env.CreateBOSHDeployment(env.Namespace, env.DefaultBOSHDeployment("second-bdpl", manifestName))
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring(`denied the request: Only one deployment allowed`))
+		It("creates a second deployment next to it", func() {
+			tearDown, err := env.CreateConfigMap(env.Namespace, env.BOSHManifestConfigMap("second-manifest", bm.NatsSmall))
+			Expect(err).NotTo(HaveOccurred())
+			tearDowns = append(tearDowns, tearDown)
+
+			_, tearDown, err = env.CreateBOSHDeployment(env.Namespace, env.DefaultBOSHDeployment("second-bdpl", "second-manifest"))
+			Expect(err).NotTo(HaveOccurred())
+			tearDowns = append(tearDowns, tearDown)
+
+			err = env.WaitForInstanceGroup(env.Namespace, "second-bdpl", "nats", 2)
+			Expect(err).NotTo(HaveOccurred(), "error waiting for instance group pods from second deployment")
 		})
 	})
 
@@ -43,7 +43,7 @@ var _ = Describe("QuarksLink from BOSH to native", func() {
 		})
 
 		It("creates a secret for each link found in jobs", func() {
-			secretName := names.QuarksLinkSecretName("nats", "nats")
+			secretName := names.QuarksLinkSecretName(deploymentName, "nats", "nats")
 
 			By("waiting for secrets", func() {
 				err := env.WaitForSecret(env.Namespace, secretName)
@@ -66,7 +66,7 @@ var _ = Describe("QuarksLink from BOSH to native", func() {
 		})
 
 		It("creates a secret for each link found in jobs", func() {
-			secretName := names.QuarksLinkSecretName("nats", "nutty-nuts")
+			secretName := names.QuarksLinkSecretName(deploymentName, "nats", "nutty-nuts")
 
 			By("waiting for secrets", func() {
 				err := env.WaitForSecret(env.Namespace, secretName)
@@ -81,7 +81,7 @@ var _ = Describe("QuarksLink from native provider to explicit links in BOSH", fu
 			Expect(err).NotTo(HaveOccurred())
 
 			By("checking the ig manifest", func() {
-				ig, err := env.GetSecret(env.Namespace, "ig-resolved.nats-smoke-tests-v1")
+				ig, err := env.GetSecret(env.Namespace, deploymentName+".ig-resolved.nats-smoke-tests-v1")
 				Expect(err).NotTo(HaveOccurred())
 				igm := string(ig.Data["properties.yaml"])
 
@@ -105,7 +105,7 @@ var _ = Describe("QuarksLink from native provider to explicit links in BOSH", fu
 				_, _, err = env.UpdateSecret(env.Namespace, env.NatsOtherSecret(deploymentName))
 				Expect(err).NotTo(HaveOccurred())
 
-				ig, err := env.CollectSecret(env.Namespace, "ig-resolved.nats-smoke-tests-v2")
+				ig, err := env.CollectSecret(env.Namespace, deploymentName+".ig-resolved.nats-smoke-tests-v2")
 				Expect(err).NotTo(HaveOccurred())
 				igm := string(ig.Data["properties.yaml"])
 				Expect(igm).To(ContainSubstring(`user: nats_user`))
@@ -120,7 +120,7 @@ var _ = Describe("QuarksLink from native provider to explicit links in BOSH", fu
 				_, _, err = env.UpdateService(env.Namespace, *svc)
 				Expect(err).NotTo(HaveOccurred())
 
-				ig, err := env.CollectSecret(env.Namespace, "ig-resolved.nats-smoke-tests-v3")
+				ig, err := env.CollectSecret(env.Namespace, deploymentName+".ig-resolved.nats-smoke-tests-v3")
 				Expect(err).NotTo(HaveOccurred())
 				igm := string(ig.Data["properties.yaml"])
 				Expect(igm).NotTo(ContainSubstring("address: " + nats.Status.PodIP))
@@ -146,7 +146,7 @@ var _ = Describe("QuarksLink from native provider to explicit links in BOSH", fu
 
 		It("uses the values provided by the native resources", func() {
 			By("checking the ig manifest", func() {
-				ig, err := env.CollectSecret(env.Namespace, "ig-resolved.nats-smoke-tests-v1")
+				ig, err := env.CollectSecret(env.Namespace, deploymentName+".ig-resolved.nats-smoke-tests-v1")
 				Expect(err).NotTo(HaveOccurred())
 
 				igm := string(ig.Data["properties.yaml"])
@@ -159,7 +159,7 @@ var _ = Describe("QuarksLink from native provider to explicit links in BOSH", fu
 				_, _, err := env.UpdateEndpoints(env.Namespace, ep)
 				Expect(err).NotTo(HaveOccurred())
 
-				ig, err := env.CollectSecret(env.Namespace, "ig-resolved.nats-smoke-tests-v2")
+				ig, err := env.CollectSecret(env.Namespace, deploymentName+".ig-resolved.nats-smoke-tests-v2")
 				Expect(err).NotTo(HaveOccurred())
 
 				igm := string(ig.Data["properties.yaml"])
@@ -179,7 +179,7 @@ var _ = Describe("QuarksLink from native provider to explicit links in BOSH", fu
 
 		It("uses the values provided by the native resources", func() {
 			By("checking the ig manifest", func() {
-				ig, err := env.CollectSecret(env.Namespace, "ig-resolved.nats-smoke-tests-v1")
+				ig, err := env.CollectSecret(env.Namespace, deploymentName+".ig-resolved.nats-smoke-tests-v1")
 				Expect(err).NotTo(HaveOccurred())
 
 				igm := string(ig.Data["properties.yaml"])
@@ -223,7 +223,7 @@ var _ = Describe("QuarksLink from native provider to explicit links in BOSH", fu
 
 		It("uses the values provided by the k8s secret", func() {
 			By("checking the ig manifest", func() {
-				ig, err := env.CollectSecret(env.Namespace, "ig-resolved.nats-smoke-tests-v1")
+				ig, err := env.CollectSecret(env.Namespace, deploymentName+".ig-resolved.nats-smoke-tests-v1")
 				Expect(err).NotTo(HaveOccurred())
 
 				igm := string(ig.Data["properties.yaml"])
@@ -26,7 +26,7 @@ var _ = Describe("DeployWithStorage", func() {
 			storageClass, ok := os.LookupEnv("OPERATOR_TEST_STORAGE_CLASS")
 			Expect(ok).To(Equal(true))
 
-			tearDown, err := env.CreateSecret(env.Namespace, env.StorageClassSecret("var-operator-test-storage-class", storageClass))
+			tearDown, err := env.CreateSecret(env.Namespace, env.StorageClassSecret("test-bdpl.var-operator-test-storage-class", storageClass))
 			Expect(err).NotTo(HaveOccurred())
 			tearDowns = append(tearDowns, tearDown)
 
@@ -46,7 +46,7 @@ var _ = Describe("DeployWithStorage", func() {
 			Expect(err).NotTo(HaveOccurred(), "error waiting for instance group pods from deployment")
 
 			By("checking for services")
-			svc, err := env.GetService(env.Namespace, "bpm")
+			svc, err := env.GetService(env.Namespace, "test-bdpl-bpm")
 			Expect(err).NotTo(HaveOccurred(), "error getting service")
 			Expect(svc.Spec.Selector).To(Equal(map[string]string{
 				bdv1.LabelInstanceGroupName: "bpm",
@@ -81,7 +81,7 @@ var _ = Describe("DeployWithStorage", func() {
 			storageClass, ok := os.LookupEnv("OPERATOR_TEST_STORAGE_CLASS")
 			Expect(ok).To(Equal(true))
 
-			tearDown, err := env.CreateSecret(env.Namespace, env.StorageClassSecret("var-operator-test-storage-class", storageClass))
+			tearDown, err := env.CreateSecret(env.Namespace, env.StorageClassSecret("bpm-affinity.var-operator-test-storage-class", storageClass))
 			Expect(err).NotTo(HaveOccurred())
 			tearDowns = append(tearDowns, tearDown)
 
@@ -181,6 +181,35 @@ func (c Config) MergeProcesses(presetProcesses []Process) ([]Process, error) {
 	return renderedProcesses, nil
 }
 
+// MergeHealthChecks adds the default health checks of the config's
+// processes to the run config and returns a new map. Probes which are
+// already set take precedence over the defaults.
+func (c Config) MergeHealthChecks(defaults map[string]HealthCheck) map[string]HealthCheck {
+	healthChecks := make(map[string]HealthCheck, len(c.Run.HealthCheck))
+	for name, healthCheck := range c.Run.HealthCheck {
+		healthChecks[name] = healthCheck
+	}
+
+	for name, defaultCheck := range defaults {
+		if _, exist := indexOfBPMProcess(c.Processes, name); !exist {
+			continue
+		}
+		healthCheck := healthChecks[name]
+		if healthCheck.ReadinessProbe == nil {
+			healthCheck.ReadinessProbe = defaultCheck.ReadinessProbe
+		}
+		if healthCheck.LivenessProbe == nil {
+			healthCheck.LivenessProbe = defaultCheck.LivenessProbe
+		}
+		healthChecks[name] = healthCheck
+	}
+
+	if len(healthChecks) == 0 {
+		return c.Run.HealthCheck
+	}
+	return healthChecks
+}
+
 // indexOfBPMProcess will return the first index at which a given process name can be found in the []bpm.Process.
 // Return -1 if not find valid version
 func indexOfBPMProcess(processes []Process, processName string) (int, bool) {
@@ -0,0 +1,224 @@
+package bpm
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// monitPeriodSeconds is the monit daemon interval of a BOSH VM, which is
+	// the duration of a monit cycle
+	monitPeriodSeconds = 10
+	// monitStartTimeoutSeconds is the default timeout of a monit start program
+	monitStartTimeoutSeconds = 30
+	// bpmPidDir is where BPM writes the pid files of a job's processes
+	bpmPidDir = "/var/vcap/sys/run/bpm"
+)
+
+// monitService is a 'check process' entry of a monit file
+type monitService struct {
+	name         string
+	pidFile      string
+	startTimeout int32
+	readiness    *corev1.Probe
+	liveness     *corev1.Probe
+}
+
+// MonitHealthChecks derives liveness and readiness probes from the
+// 'if failed' checks of the process entries in a job's monit file. Port
+// checks result in TCP or HTTP probes, unix socket checks in exec probes.
+// Readiness probes are derived from all checks, liveness probes only from
+// checks, which restart the process. The health checks are returned by the
+// BPM process name, which is taken from the BPM pid file.
+func MonitHealthChecks(monit []byte, jobName string) (map[string]HealthCheck, error) {
+	tokens, err := monitTokens(string(monit))
+	if err != nil {
+		return nil, err
+	}
+
+	services := []*monitService{}
+	var service *monitService
+	for i := 0; i < len(tokens); i++ {
+		switch keyword(tokens[i]) {
+		case "check":
+			service = nil
+			if i+2 < len(tokens) && keyword(tokens[i+1]) == "process" {
+				service = &monitService{name: tokens[i+2], startTimeout: monitStartTimeoutSeconds}
+				services = append(services, service)
+				i += 2
+			}
+		case "pidfile":
+			if service != nil && i+1 < len(tokens) {
+				service.pidFile = tokens[i+1]
+				i++
+			}
+		case "start":
+			if service == nil {
+				continue
+			}
+			// start program "<cmd>" [as uid ... gid ...] [with timeout N seconds]
+			for j := i + 1; j < len(tokens) && j < i+12; j++ {
+				if keyword(tokens[j]) == "timeout" && j+1 < len(tokens) {
+					if timeout, err := strconv.Atoi(tokens[j+1]); err == nil {
+						service.startTimeout = int32(timeout)
+					}
+					break
+				}
+				if k := keyword(tokens[j]); k == "stop" || k == "if" || k == "check" {
+					break
+				}
+			}
+		case "if":
+			end := i + 1
+			for end < len(tokens) && keyword(tokens[end]) != "then" {
+				end++
+			}
+			if end+1 >= len(tokens) {
+				return nil, errors.Errorf("monit check of job '%s' has an 'if' without 'then'", jobName)
+			}
+			if service != nil && keyword(tokens[i+1]) == "failed" {
+				probe := monitProbe(tokens[i+2 : end])
+				if probe != nil {
+					if service.readiness == nil {
+						service.readiness = probe.DeepCopy()
+					}
+					if service.liveness == nil && keyword(tokens[end+1]) == "restart" {
+						service.liveness = probe.DeepCopy()
+						service.liveness.InitialDelaySeconds = service.startTimeout
+					}
+				}
+			}
+			i = end + 1
+		}
+	}
+
+	healthChecks := map[string]HealthCheck{}
+	for _, s := range services {
+		if s.readiness == nil && s.liveness == nil {
+			continue
+		}
+		healthChecks[s.processName(jobName)] = HealthCheck{
+			ReadinessProbe: s.readiness,
+			LivenessProbe:  s.liveness,
+		}
+	}
+	return healthChecks, nil
+}
+
+// processName returns the name of the BPM process, which is monitored by
+// the service. BPM writes the pid file of the process to
+// /var/vcap/sys/run/bpm/<job>/<process>.pid.
+func (s *monitService) processName(jobName string) string {
+	if filepath.Dir(s.pidFile) == filepath.Join(bpmPidDir, jobName) {
+		return strings.TrimSuffix(filepath.Base(s.pidFile), ".pid")
+	}
+	return s.name
+}
+
+// monitProbe creates a probe from the tokens of a 'failed' condition, e.g.
+// 'port 8080 protocol http request "/health" with timeout 5 seconds for 3 cycles'
+func monitProbe(condition []string) *corev1.Probe {
+	var port int
+	var socket, protocol, socketType string
+	path := "/"
+	probe := &corev1.Probe{PeriodSeconds: monitPeriodSeconds}
+
+	for i := 0; i < len(condition); i++ {
+		if i+1 >= len(condition) {
+			break
+		}
+		value := condition[i+1]
+		switch keyword(condition[i]) {
+		case "port":
+			port, _ = strconv.Atoi(value)
+		case "unixsocket":
+			socket = value
+		case "protocol":
+			protocol = strings.ToLower(value)
+		case "type":
+			socketType = strings.ToLower(value)
+		case "request":
+			path = value
+		case "timeout":
+			if timeout, err := strconv.Atoi(value); err == nil {
+				probe.TimeoutSeconds = int32(timeout)
+			}
+		case "for":
+			if cycles, err := strconv.Atoi(value); err == nil {
+				probe.FailureThreshold = int32(cycles)
+			}
+		default:
+			continue
+		}
+		i++
+	}
+
+	switch {
+	case socket != "":
+		probe.Exec = &corev1.ExecAction{Command: []string{"test", "-S", socket}}
+	case port < 1 || socketType == "udp":
+		return nil
+	case protocol == "http" || protocol == "https":
+		scheme := corev1.URISchemeHTTP
+		if protocol == "https" || socketType == "tcpssl" {
+			scheme = corev1.URISchemeHTTPS
+		}
+		probe.HTTPGet = &corev1.HTTPGetAction{
+			Path:   path,
+			Port:   intstr.FromInt(port),
+			Scheme: scheme,
+		}
+	default:
+		probe.TCPSocket = &corev1.TCPSocketAction{Port: intstr.FromInt(port)}
+	}
+
+	return probe
+}
+
+// monitTokens splits a monit control file into words and quoted strings,
+// ignoring comments and the noise keywords of the monit grammar
+func monitTokens(monit string) ([]string, error) {
+	noise := map[string]bool{"with": true, "and": true, "has": true, "using": true, "on": true}
+
+	tokens := []string{}
+	for _, line := range strings.Split(monit, "\n") {
+		for len(line) > 0 {
+			line = strings.TrimLeft(line, " \t\r")
+			if line == "" || line[0] == '#' {
+				break
+			}
+
+			if line[0] == '"' || line[0] == '\'' {
+				end := strings.IndexByte(line[1:], line[0])
+				if end < 0 {
+					return nil, errors.Errorf("unterminated string in monit file: %s", line)
+				}
+				tokens = append(tokens, line[1:end+1])
+				line = line[end+2:]
+				continue
+			}
+
+			end := strings.IndexAny(line, " \t\r")
+			if end < 0 {
+				end = len(line)
+			}
+			word := line[:end]
+			if !noise[keyword(word)] {
+				tokens = append(tokens, word)
+			}
+			line = line[end:]
+		}
+	}
+	return tokens, nil
+}
+
+// keyword returns the token for comparison with the case insensitive
+// keywords of the monit grammar
+func keyword(token string) string {
+	return strings.ToLower(token)
+}
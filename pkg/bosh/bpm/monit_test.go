@@ -0,0 +1,116 @@
+package bpm_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	bpm "code.cloudfoundry.org/quarks-operator/pkg/bosh/bpm"
+)
+
+var _ = Describe("Monit", func() {
+	Describe("MonitHealthChecks", func() {
+		var monit []byte
+
+		BeforeEach(func() {
+			monit = []byte(`# the API server
+check process api
+  with pidfile /var/vcap/sys/run/bpm/cloud_controller/api.pid
+  start program "/var/vcap/jobs/bpm/bin/bpm start cloud_controller -p api" with timeout 120 seconds
+  stop program "/var/vcap/jobs/bpm/bin/bpm stop cloud_controller -p api"
+  if failed port 9022 protocol http request "/healthz" with timeout 5 seconds for 3 cycles then restart
+  group vcap
+
+check process worker
+  with pidfile /var/vcap/sys/run/bpm/cloud_controller/worker.pid
+  start program "/var/vcap/jobs/bpm/bin/bpm start cloud_controller -p worker"
+  if failed unixsocket /var/vcap/data/cloud_controller/worker.sock
+    then alert
+  group vcap
+
+check process nginx
+  with pidfile /var/vcap/sys/run/nginx.pid
+  if failed host 127.0.0.1 port 443 type tcpssl then restart
+  if failed port 80 then alert
+
+check process idle
+  with pidfile /var/vcap/sys/run/bpm/cloud_controller/idle.pid
+  if 5 restarts within 5 cycles then timeout
+`)
+		})
+
+		It("derives probes from the checks", func() {
+			healthChecks, err := bpm.MonitHealthChecks(monit, "cloud_controller")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(healthChecks).To(HaveLen(3))
+
+			By("using http probes for the http protocol")
+			api := healthChecks["api"]
+			Expect(api.ReadinessProbe.HTTPGet).To(Equal(&corev1.HTTPGetAction{
+				Path:   "/healthz",
+				Port:   intstr.FromInt(9022),
+				Scheme: corev1.URISchemeHTTP,
+			}))
+			Expect(api.ReadinessProbe.TimeoutSeconds).To(Equal(int32(5)))
+			Expect(api.ReadinessProbe.FailureThreshold).To(Equal(int32(3)))
+			Expect(api.ReadinessProbe.PeriodSeconds).To(Equal(int32(10)))
+			Expect(api.LivenessProbe.HTTPGet).To(Equal(api.ReadinessProbe.HTTPGet))
+			Expect(api.LivenessProbe.InitialDelaySeconds).To(Equal(int32(120)))
+
+			By("using exec probes for unix sockets and no liveness probe for alerts")
+			worker := healthChecks["worker"]
+			Expect(worker.ReadinessProbe.Exec.Command).To(Equal([]string{"test", "-S", "/var/vcap/data/cloud_controller/worker.sock"}))
+			Expect(worker.LivenessProbe).To(BeNil())
+
+			By("using the check name, if the pid file is not written by BPM")
+			nginx := healthChecks["nginx"]
+			Expect(nginx.ReadinessProbe.TCPSocket.Port).To(Equal(intstr.FromInt(443)))
+			Expect(nginx.LivenessProbe.TCPSocket.Port).To(Equal(intstr.FromInt(443)))
+			Expect(nginx.LivenessProbe.InitialDelaySeconds).To(Equal(int32(30)))
+		})
+
+		It("fails for incomplete checks", func() {
+			_, err := bpm.MonitHealthChecks([]byte("check process api\n  if failed port 80"), "api")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("'if' without 'then'"))
+		})
+	})
+
+	Describe("MergeHealthChecks", func() {
+		var (
+			config   bpm.Config
+			defaults map[string]bpm.HealthCheck
+			explicit *corev1.Probe
+			derived  *corev1.Probe
+		)
+
+		BeforeEach(func() {
+			explicit = &corev1.Probe{Handler: corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"true"}}}}
+			derived = &corev1.Probe{Handler: corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(80)}}}
+			config = bpm.Config{
+				Processes: []bpm.Process{{Name: "api"}, {Name: "worker"}},
+				Run: bpm.RunConfig{
+					HealthCheck: map[string]bpm.HealthCheck{"api": {ReadinessProbe: explicit}},
+				},
+			}
+			defaults = map[string]bpm.HealthCheck{
+				"api":     {ReadinessProbe: derived, LivenessProbe: derived},
+				"worker":  {ReadinessProbe: derived},
+				"unknown": {ReadinessProbe: derived},
+			}
+		})
+
+		It("keeps explicit probes and adds the defaults of known processes", func() {
+			healthChecks := config.MergeHealthChecks(defaults)
+			Expect(healthChecks).To(HaveLen(2))
+			Expect(healthChecks["api"].ReadinessProbe).To(Equal(explicit))
+			Expect(healthChecks["api"].LivenessProbe).To(Equal(derived))
+			Expect(healthChecks["worker"].ReadinessProbe).To(Equal(derived))
+
+			By("not modifying the config's health checks")
+			Expect(config.Run.HealthCheck["api"].LivenessProbe).To(BeNil())
+		})
+	})
+})
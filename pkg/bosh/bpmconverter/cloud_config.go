@@ -0,0 +1,138 @@
+package bpmconverter
+
+import (
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+)
+
+// ApplyCloudConfigDisks resolves the persistent_disk_type of the instance
+// group to the storage class and size of the cloud config's disk type. It
+// needs to run before the conversion, which creates the PVCs.
+func ApplyCloudConfigDisks(cloudConfig *bdv1.BOSHCloudConfigSpec, instanceGroup *bdm.InstanceGroup) error {
+	if instanceGroup.PersistentDiskType == "" {
+		return nil
+	}
+
+	diskType, ok := cloudConfig.DiskType(instanceGroup.PersistentDiskType)
+	if !ok {
+		return errors.Errorf("disk type '%s' of instance group '%s' not found in cloud config", instanceGroup.PersistentDiskType, instanceGroup.Name)
+	}
+
+	if instanceGroup.PersistentDisk == nil && diskType.DiskSize > 0 {
+		instanceGroup.PersistentDisk = pointers.Int(diskType.DiskSize)
+	}
+	instanceGroup.PersistentDiskType = diskType.StorageClassName
+
+	return nil
+}
+
+// ApplyCloudConfig maps the vm_type, vm_extensions and azs of the instance
+// group to the pod templates of the converted QuarksStatefulSets and QuarksJobs
+func ApplyCloudConfig(cloudConfig *bdv1.BOSHCloudConfigSpec, instanceGroup *bdm.InstanceGroup, resources *Resources) error {
+	var vmType *bdv1.VMType
+	if instanceGroup.VMType != "" {
+		t, ok := cloudConfig.VMType(instanceGroup.VMType)
+		if !ok {
+			return errors.Errorf("vm type '%s' of instance group '%s' not found in cloud config", instanceGroup.VMType, instanceGroup.Name)
+		}
+		vmType = t
+	}
+
+	extensions := make([]*bdv1.VMExtension, 0, len(instanceGroup.VMExtensions))
+	for _, name := range instanceGroup.VMExtensions {
+		e, ok := cloudConfig.VMExtension(name)
+		if !ok {
+			return errors.Errorf("vm extension '%s' of instance group '%s' not found in cloud config", name, instanceGroup.Name)
+		}
+		extensions = append(extensions, e)
+	}
+
+	// Without azs in the cloud config, the az names are used as values of the zone node label
+	zones := instanceGroup.AZs
+	if len(cloudConfig.AZs) > 0 && len(instanceGroup.AZs) > 0 {
+		zones = make([]string, 0, len(instanceGroup.AZs))
+		for _, name := range instanceGroup.AZs {
+			az, ok := cloudConfig.AZ(name)
+			if !ok {
+				return errors.Errorf("az '%s' of instance group '%s' not found in cloud config", name, instanceGroup.Name)
+			}
+			zone := az.Zone
+			if zone == "" {
+				zone = az.Name
+			}
+			zones = append(zones, zone)
+		}
+	}
+
+	for i := range resources.InstanceGroups {
+		qsts := &resources.InstanceGroups[i]
+		applyToPodTemplate(&qsts.Spec.Template.Spec.Template, vmType, extensions)
+		qsts.Spec.Zones = zones
+		if cloudConfig.ZoneNodeLabel != "" {
+			qsts.Spec.ZoneNodeLabel = cloudConfig.ZoneNodeLabel
+		}
+	}
+
+	for i := range resources.Errands {
+		applyToPodTemplate(&resources.Errands[i].Spec.Template.Spec.Template, vmType, extensions)
+	}
+
+	return nil
+}
+
+// applyToPodTemplate adds the settings of the vm type and extensions to the
+// pod template. Settings from the instance group's agent env take precedence.
+func applyToPodTemplate(template *corev1.PodTemplateSpec, vmType *bdv1.VMType, extensions []*bdv1.VMExtension) {
+	spec := &template.Spec
+
+	if vmType != nil {
+		if len(vmType.NodeSelector) > 0 {
+			spec.NodeSelector = labels.Merge(vmType.NodeSelector, spec.NodeSelector)
+		}
+
+		for i := range spec.Containers {
+			resources := &spec.Containers[i].Resources
+			resources.Requests = defaultResources(resources.Requests, vmType.Resources.Requests)
+			resources.Limits = defaultResources(resources.Limits, vmType.Resources.Limits)
+		}
+	}
+
+	for _, e := range extensions {
+		spec.Tolerations = append(spec.Tolerations, e.Tolerations...)
+
+		// The maps are shared with the selector and the metadata of the
+		// parent resource, so they are replaced instead of modified
+		if len(e.Labels) > 0 {
+			template.Labels = labels.Merge(e.Labels, template.Labels)
+		}
+		if len(e.Annotations) > 0 {
+			template.Annotations = labels.Merge(e.Annotations, template.Annotations)
+		}
+
+		if spec.Affinity == nil && e.Affinity != nil {
+			spec.Affinity = e.Affinity.DeepCopy()
+		}
+	}
+}
+
+// defaultResources adds the resources of the vm type, which the container doesn't set itself
+func defaultResources(resources corev1.ResourceList, defaults corev1.ResourceList) corev1.ResourceList {
+	if len(defaults) == 0 {
+		return resources
+	}
+
+	result := corev1.ResourceList{}
+	for name, quantity := range defaults {
+		result[name] = quantity.DeepCopy()
+	}
+	for name, quantity := range resources {
+		result[name] = quantity
+	}
+	return result
+}
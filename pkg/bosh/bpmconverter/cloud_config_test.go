@@ -0,0 +1,162 @@
+package bpmconverter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+)
+
+var _ = Describe("Cloud config", func() {
+	var (
+		cloudConfig   *bdv1.BOSHCloudConfigSpec
+		instanceGroup *manifest.InstanceGroup
+	)
+
+	BeforeEach(func() {
+		cloudConfig = &bdv1.BOSHCloudConfigSpec{
+			VMTypes: []bdv1.VMType{
+				{
+					Name: "small",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("500m"),
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						},
+					},
+					NodeSelector: map[string]string{"pool": "small"},
+				},
+			},
+			VMExtensions: []bdv1.VMExtension{
+				{
+					Name:        "spot",
+					Tolerations: []corev1.Toleration{{Key: "spot", Operator: corev1.TolerationOpExists}},
+					Labels:      map[string]string{"spot": "true"},
+					Annotations: map[string]string{"cost-center": "ci"},
+				},
+			},
+			DiskTypes: []bdv1.DiskType{
+				{Name: "5GB", StorageClassName: "fast-ssd", DiskSize: 5120},
+			},
+			AZs: []bdv1.AZ{
+				{Name: "z1", Zone: "us-east-1a"},
+				{Name: "z2"},
+			},
+			ZoneNodeLabel: "topology.kubernetes.io/zone",
+		}
+
+		instanceGroup = &manifest.InstanceGroup{
+			Name:               "diego-cell",
+			AZs:                []string{"z1", "z2"},
+			VMType:             "small",
+			VMExtensions:       []string{"spot"},
+			PersistentDiskType: "5GB",
+		}
+	})
+
+	Context("ApplyCloudConfigDisks", func() {
+		It("uses the storage class and size of the disk type", func() {
+			err := bpmconverter.ApplyCloudConfigDisks(cloudConfig, instanceGroup)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceGroup.PersistentDiskType).To(Equal("fast-ssd"))
+			Expect(*instanceGroup.PersistentDisk).To(Equal(5120))
+		})
+
+		It("keeps the persistent disk size of the instance group", func() {
+			instanceGroup.PersistentDisk = pointers.Int(1024)
+			err := bpmconverter.ApplyCloudConfigDisks(cloudConfig, instanceGroup)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*instanceGroup.PersistentDisk).To(Equal(1024))
+		})
+
+		It("fails for an unknown disk type", func() {
+			instanceGroup.PersistentDiskType = "10GB"
+			err := bpmconverter.ApplyCloudConfigDisks(cloudConfig, instanceGroup)
+			Expect(err).To(MatchError(ContainSubstring("disk type '10GB' of instance group 'diego-cell' not found")))
+		})
+	})
+
+	Context("ApplyCloudConfig", func() {
+		var (
+			resources *bpmconverter.Resources
+			selector  map[string]string
+		)
+
+		BeforeEach(func() {
+			selector = map[string]string{"app": "diego-cell"}
+			qsts := qstsv1a1.QuarksStatefulSet{}
+			qsts.Spec.Zones = instanceGroup.AZs
+			qsts.Spec.Template.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+			qsts.Spec.Template.Spec.Template.Labels = selector
+			qsts.Spec.Template.Spec.Template.Spec.Containers = []corev1.Container{
+				{
+					Name: "rep",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+					},
+				},
+			}
+
+			resources = &bpmconverter.Resources{
+				InstanceGroups: []qstsv1a1.QuarksStatefulSet{qsts},
+				Errands:        []qjv1a1.QuarksJob{{}},
+			}
+		})
+
+		It("maps the vm type to resources and a node selector", func() {
+			err := bpmconverter.ApplyCloudConfig(cloudConfig, instanceGroup, resources)
+			Expect(err).ToNot(HaveOccurred())
+
+			spec := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec
+			Expect(spec.NodeSelector).To(Equal(map[string]string{"pool": "small"}))
+			requests := spec.Containers[0].Resources.Requests
+			Expect(requests.Cpu().String()).To(Equal("500m"))
+			Expect(requests.Memory().String()).To(Equal("4Gi"))
+		})
+
+		It("maps the vm extensions to tolerations, labels and annotations", func() {
+			err := bpmconverter.ApplyCloudConfig(cloudConfig, instanceGroup, resources)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, template := range []corev1.PodTemplateSpec{
+				resources.InstanceGroups[0].Spec.Template.Spec.Template,
+				resources.Errands[0].Spec.Template.Spec.Template,
+			} {
+				Expect(template.Spec.Tolerations).To(ContainElement(corev1.Toleration{Key: "spot", Operator: corev1.TolerationOpExists}))
+				Expect(template.Labels).To(HaveKeyWithValue("spot", "true"))
+				Expect(template.Annotations).To(HaveKeyWithValue("cost-center", "ci"))
+			}
+			Expect(selector).To(Equal(map[string]string{"app": "diego-cell"}))
+		})
+
+		It("maps the azs to the values of the zone node label", func() {
+			err := bpmconverter.ApplyCloudConfig(cloudConfig, instanceGroup, resources)
+			Expect(err).ToNot(HaveOccurred())
+
+			qsts := resources.InstanceGroups[0]
+			Expect(qsts.Spec.Zones).To(Equal([]string{"us-east-1a", "z2"}))
+			Expect(qsts.Spec.ZoneNodeLabel).To(Equal("topology.kubernetes.io/zone"))
+		})
+
+		It("fails for an unknown vm type", func() {
+			instanceGroup.VMType = "large"
+			err := bpmconverter.ApplyCloudConfig(cloudConfig, instanceGroup, resources)
+			Expect(err).To(MatchError(ContainSubstring("vm type 'large' of instance group 'diego-cell' not found")))
+		})
+
+		It("fails for an unknown az", func() {
+			instanceGroup.AZs = []string{"z3"}
+			err := bpmconverter.ApplyCloudConfig(cloudConfig, instanceGroup, resources)
+			Expect(err).To(MatchError(ContainSubstring("az 'z3' of instance group 'diego-cell' not found")))
+		})
+	})
+})
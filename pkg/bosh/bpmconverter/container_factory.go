@@ -25,6 +25,15 @@ var (
 		},
 	}
 
+	deploymentNameEnv = corev1.EnvVar{
+		Name: EnvDeploymentName,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				FieldPath: "metadata.labels['quarks.cloudfoundry.org/deployment-name']",
+			},
+		},
+	}
+
 	replicasEnv = corev1.EnvVar{
 		Name:  EnvReplicas,
 		Value: "1",
@@ -157,6 +157,7 @@ func templateRenderingContainer(instanceGroupName string, initialRollout bool) c
 				Name:  qjv1a1.RemoteIDKey,
 				Value: instanceGroupName,
 			},
+			deploymentNameEnv,
 			{
 				Name:  EnvBOSHManifestPath,
 				Value: fmt.Sprintf(resolvedPropertiesFormat+"/properties.yaml", instanceGroupName),
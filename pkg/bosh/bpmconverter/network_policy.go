@@ -0,0 +1,103 @@
+package bpmconverter
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpm"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+)
+
+// namespaceNameLabel is set by Kubernetes on each namespace to its name.
+// Kubernetes adds it since 1.21, older clusters need it to be added manually.
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// NetworkPolicy creates a network policy, which denies all ingress to the
+// pods of the instance group, except from its own pods and on the declared
+// quarks ports from the instance groups, entangled pods and the pods of the
+// link consumer deployments, which consume a link provided by the instance group
+func NetworkPolicy(namespace string, deploymentName string, instanceGroup bdm.BPMInstanceGroup, bpmConfigs bpm.Configs, linkConsumers []bdv1.LinkConsumer) networkingv1.NetworkPolicy {
+	podSelector := metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			bdv1.LabelDeploymentName:    deploymentName,
+			bdv1.LabelInstanceGroupName: instanceGroup.Name,
+		},
+	}
+	policy := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.NetworkPolicyName(deploymentName, instanceGroup.Name),
+			Namespace: namespace,
+			Labels: map[string]string{
+				bdv1.LabelDeploymentName:    deploymentName,
+				bdv1.LabelInstanceGroupName: instanceGroup.Name,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: podSelector,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				// the instances of a cluster talk to each other on any port
+				{From: []networkingv1.NetworkPolicyPeer{{PodSelector: podSelector.DeepCopy()}}},
+			},
+		},
+	}
+
+	servicePorts := bpmConfigs.ServicePorts()
+	peers := make([]networkingv1.NetworkPolicyPeer, 0, len(instanceGroup.LinkConsumers)+len(instanceGroup.ProvidedLinks)+len(linkConsumers))
+	for _, consumer := range instanceGroup.LinkConsumers {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					bdv1.LabelDeploymentName:    deploymentName,
+					bdv1.LabelInstanceGroupName: consumer,
+				},
+			},
+		})
+	}
+	for _, link := range instanceGroup.ProvidedLinks {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{names.QuarksLinkConsumerLabel(link.Key()): deploymentName},
+			},
+		})
+	}
+	if len(instanceGroup.ProvidedLinks) > 0 {
+		for _, consumer := range linkConsumers {
+			peer := networkingv1.NetworkPolicyPeer{
+				PodSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{bdv1.LabelDeploymentName: consumer.Deployment},
+				},
+			}
+			if consumer.Namespace != "" && consumer.Namespace != namespace {
+				peer.NamespaceSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{namespaceNameLabel: consumer.Namespace},
+				}
+			}
+			peers = append(peers, peer)
+		}
+	}
+	if len(servicePorts) == 0 || len(peers) == 0 {
+		return policy
+	}
+
+	ports := make([]networkingv1.NetworkPolicyPort, 0, len(servicePorts))
+	for _, servicePort := range servicePorts {
+		port := intstr.FromInt(int(servicePort.Port))
+		policyPort := networkingv1.NetworkPolicyPort{Port: &port}
+		if servicePort.Protocol != "" {
+			protocol := corev1.Protocol(servicePort.Protocol)
+			policyPort.Protocol = &protocol
+		}
+		ports = append(ports, policyPort)
+	}
+
+	policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+		Ports: ports,
+		From:  peers,
+	})
+	return policy
+}
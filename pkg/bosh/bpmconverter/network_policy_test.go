@@ -0,0 +1,128 @@
+package bpmconverter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpm"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+var _ = Describe("NetworkPolicy", func() {
+	var (
+		instanceGroup manifest.BPMInstanceGroup
+		bpmConfigs    bpm.Configs
+		linkConsumers []bdv1.LinkConsumer
+	)
+
+	BeforeEach(func() {
+		instanceGroup = manifest.BPMInstanceGroup{
+			Name:          "nats",
+			LinkConsumers: []string{"doppler", "router"},
+			ProvidedLinks: []manifest.ProvidedLink{{Name: "nats", Type: "nats"}},
+		}
+		bpmConfigs = bpm.Configs{
+			"nats": bpm.Config{Ports: []bpm.Port{
+				{Name: "nats", Protocol: "TCP", Internal: 4222},
+				{Name: "nats-routes", Internal: 4223},
+			}},
+		}
+		linkConsumers = nil
+	})
+
+	It("selects the pods of the instance group", func() {
+		policy := bpmconverter.NetworkPolicy("default", "cf", instanceGroup, bpmConfigs, linkConsumers)
+		Expect(policy.Name).To(Equal("cf.nats"))
+		Expect(policy.Labels).To(HaveKeyWithValue(bdv1.LabelInstanceGroupName, "nats"))
+		Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{
+			bdv1.LabelDeploymentName:    "cf",
+			bdv1.LabelInstanceGroupName: "nats",
+		}))
+		Expect(policy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress))
+	})
+
+	It("allows ingress on all ports from the pods of the instance group", func() {
+		policy := bpmconverter.NetworkPolicy("default", "cf", instanceGroup, bpmConfigs, linkConsumers)
+
+		rule := policy.Spec.Ingress[0]
+		Expect(rule.Ports).To(BeEmpty())
+		Expect(rule.From).To(HaveLen(1))
+		Expect(rule.From[0].PodSelector.MatchLabels).To(Equal(policy.Spec.PodSelector.MatchLabels))
+		Expect(rule.From[0].NamespaceSelector).To(BeNil())
+	})
+
+	It("allows ingress on the quarks ports from link consumers", func() {
+		policy := bpmconverter.NetworkPolicy("default", "cf", instanceGroup, bpmConfigs, linkConsumers)
+		Expect(policy.Spec.Ingress).To(HaveLen(2))
+
+		rule := policy.Spec.Ingress[1]
+		Expect(rule.Ports).To(HaveLen(2))
+		Expect(*rule.Ports[0].Port).To(Equal(intstr.FromInt(4222)))
+		Expect(*rule.Ports[0].Protocol).To(Equal(corev1.ProtocolTCP))
+		Expect(rule.Ports[1].Protocol).To(BeNil())
+
+		Expect(rule.From).To(HaveLen(3))
+		Expect(rule.From[0].PodSelector.MatchLabels).To(HaveKeyWithValue(bdv1.LabelInstanceGroupName, "doppler"))
+		Expect(rule.From[1].PodSelector.MatchLabels).To(HaveKeyWithValue(bdv1.LabelInstanceGroupName, "router"))
+		Expect(rule.From[2].PodSelector.MatchLabels).To(Equal(map[string]string{"link.quarks.cloudfoundry.org/nats-nats": "cf"}))
+	})
+
+	Context("when other deployments consume the links", func() {
+		BeforeEach(func() {
+			linkConsumers = []bdv1.LinkConsumer{
+				{Deployment: "app"},
+				{Namespace: "staging", Deployment: "app"},
+			}
+		})
+
+		It("allows ingress from the pods of the consumer deployments", func() {
+			policy := bpmconverter.NetworkPolicy("default", "cf", instanceGroup, bpmConfigs, linkConsumers)
+			Expect(policy.Spec.Ingress).To(HaveLen(2))
+
+			from := policy.Spec.Ingress[1].From
+			Expect(from).To(HaveLen(5))
+			Expect(from[3].PodSelector.MatchLabels).To(Equal(map[string]string{bdv1.LabelDeploymentName: "app"}))
+			Expect(from[3].NamespaceSelector).To(BeNil())
+			Expect(from[4].PodSelector.MatchLabels).To(Equal(map[string]string{bdv1.LabelDeploymentName: "app"}))
+			Expect(from[4].NamespaceSelector.MatchLabels).To(Equal(map[string]string{"kubernetes.io/metadata.name": "staging"}))
+		})
+
+		It("doesn't allow ingress from them, if the instance group provides no links", func() {
+			instanceGroup.ProvidedLinks = nil
+
+			policy := bpmconverter.NetworkPolicy("default", "cf", instanceGroup, bpmConfigs, linkConsumers)
+			Expect(policy.Spec.Ingress[1].From).To(HaveLen(2))
+		})
+	})
+
+	Context("when no instance group consumes a link", func() {
+		BeforeEach(func() {
+			instanceGroup.LinkConsumers = nil
+			instanceGroup.ProvidedLinks = nil
+		})
+
+		It("only allows ingress from the pods of the instance group", func() {
+			policy := bpmconverter.NetworkPolicy("default", "cf", instanceGroup, bpmConfigs, linkConsumers)
+			Expect(policy.Spec.Ingress).To(HaveLen(1))
+			Expect(policy.Spec.Ingress[0].From[0].PodSelector.MatchLabels).To(HaveKeyWithValue(bdv1.LabelInstanceGroupName, "nats"))
+			Expect(policy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress))
+		})
+	})
+
+	Context("when the instance group has no quarks ports", func() {
+		BeforeEach(func() {
+			bpmConfigs = bpm.Configs{"nats": bpm.Config{}}
+		})
+
+		It("only allows ingress from the pods of the instance group", func() {
+			policy := bpmconverter.NetworkPolicy("default", "cf", instanceGroup, bpmConfigs, linkConsumers)
+			Expect(policy.Spec.Ingress).To(HaveLen(1))
+		})
+	})
+})
@@ -10,6 +10,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1b1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 
@@ -45,7 +46,7 @@ type NewContainerFactoryFunc func(instanceGroupName string, errand bool, version
 
 // VolumeFactory builds Kubernetes containers from BOSH jobs.
 type VolumeFactory interface {
-	GenerateDefaultDisks(instanceGroupName *bdm.InstanceGroup, igResolvedSecretVersion string, namespace string) bdm.Disks
+	GenerateDefaultDisks(deploymentName string, instanceGroupName *bdm.InstanceGroup, igResolvedSecretVersion string, namespace string) bdm.Disks
 	GenerateBPMDisks(instanceGroup *bdm.InstanceGroup, bpmConfigs bpm.Configs, namespace string) (bdm.Disks, error)
 }
 
@@ -69,6 +70,7 @@ type Resources struct {
 	Errands                []qjv1a1.QuarksJob
 	Services               []corev1.Service
 	PersistentVolumeClaims []corev1.PersistentVolumeClaim
+	NetworkPolicies        []networkingv1.NetworkPolicy
 }
 
 // FilterLabels filters out labels, that are not suitable for StatefulSet updates
@@ -84,14 +86,14 @@ func FilterLabels(labels map[string]string) map[string]string {
 
 // Resources uses BOSH Process Manager information to create k8s container specs from single BOSH instance group.
 // It returns quarks stateful sets, services and quarks jobs.
-func (kc *BPMConverter) Resources(manifest bdm.Manifest, namespace string, deploymentName string, serviceIP string, qStsVersion string, instanceGroup *bdm.InstanceGroup, bpmConfigs bpm.Configs, igResolvedSecretVersion string) (*Resources, error) {
+func (kc *BPMConverter) Resources(manifest bdm.Manifest, namespace string, deploymentName string, dns boshdns.PodDNS, qStsVersion string, instanceGroup *bdm.InstanceGroup, bpmConfigs bpm.Configs, igResolvedSecretVersion string) (*Resources, error) {
 	if len(instanceGroup.Jobs) == 0 {
 		return nil, errors.Errorf("instance group '%s' has no jobs defined", instanceGroup.Name)
 	}
 
 	instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Set(deploymentName, instanceGroup.Name, qStsVersion)
 
-	defaultDisks := kc.volumeFactory.GenerateDefaultDisks(instanceGroup, igResolvedSecretVersion, namespace)
+	defaultDisks := kc.volumeFactory.GenerateDefaultDisks(deploymentName, instanceGroup, igResolvedSecretVersion, namespace)
 	bpmDisks, err := kc.volumeFactory.GenerateBPMDisks(instanceGroup, bpmConfigs, namespace)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Generate of BPM disks failed for manifest name %s, instance group %s.", deploymentName, instanceGroup.Name)
@@ -116,7 +118,7 @@ func (kc *BPMConverter) Resources(manifest bdm.Manifest, namespace string, deplo
 	)
 
 	if instanceGroup.IsErrand() {
-		j, err := kc.quarksJob(manifest, namespace, cfac, serviceIP, instanceGroup, defaultDisks, bpmDisks)
+		j, err := kc.quarksJob(manifest, namespace, deploymentName, cfac, dns, instanceGroup, defaultDisks, bpmDisks)
 		if err != nil {
 			return nil, err
 		}
@@ -125,12 +127,12 @@ func (kc *BPMConverter) Resources(manifest bdm.Manifest, namespace string, deplo
 		return res, nil
 	}
 
-	qsts, err := kc.quarksStatefulset(manifest, namespace, cfac, serviceIP, instanceGroup, defaultDisks, bpmDisks, bpmConfigs.ActivePassiveProbes())
+	qsts, err := kc.quarksStatefulset(manifest, namespace, deploymentName, cfac, dns, instanceGroup, defaultDisks, bpmDisks, bpmConfigs.ActivePassiveProbes())
 	if err != nil {
 		return nil, err
 	}
 
-	services := kc.service(namespace, deploymentName, instanceGroup, &qsts, bpmConfigs)
+	services := kc.service(namespace, deploymentName, instanceGroup, &qsts, bpmConfigs, dns.Native)
 	if len(services) != 0 {
 		res.Services = append(res.Services, services...)
 	}
@@ -144,8 +146,9 @@ func (kc *BPMConverter) Resources(manifest bdm.Manifest, namespace string, deplo
 func (kc *BPMConverter) quarksStatefulset(
 	manifest bdm.Manifest,
 	namespace string,
+	deploymentName string,
 	cfac ContainerFactory,
-	serviceIP string,
+	dns boshdns.PodDNS,
 	instanceGroup *bdm.InstanceGroup,
 	defaultDisks bdm.Disks,
 	bpmDisks bdm.Disks,
@@ -179,9 +182,10 @@ func (kc *BPMConverter) quarksStatefulset(
 	if err != nil {
 		return qstsv1a1.QuarksStatefulSet{}, errors.Wrapf(err, "computing annotations failed for instance group %s", instanceGroup.Name)
 	}
+	qstsName := names.QuarksStatefulSetName(deploymentName, instanceGroup.Name)
 	extSts := qstsv1a1.QuarksStatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        instanceGroup.NameSanitized(),
+			Name:        qstsName,
 			Namespace:   namespace,
 			Labels:      instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Labels,
 			Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
@@ -193,7 +197,7 @@ func (kc *BPMConverter) quarksStatefulset(
 			InjectReplicasEnv:    instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.InjectReplicasEnv,
 			Template: appsv1.StatefulSet{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:        instanceGroup.NameSanitized(),
+					Name:        qstsName,
 					Labels:      statefulSetLabels,
 					Annotations: statefulSetAnnotations,
 				},
@@ -205,7 +209,7 @@ func (kc *BPMConverter) quarksStatefulset(
 					Template: corev1.PodTemplateSpec{
 						ObjectMeta: metav1.ObjectMeta{
 							Labels:      statefulSetLabels,
-							Name:        instanceGroup.NameSanitized(),
+							Name:        qstsName,
 							Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
 						},
 						Spec: corev1.PodSpec{
@@ -217,7 +221,7 @@ func (kc *BPMConverter) quarksStatefulset(
 							SecurityContext: &corev1.PodSecurityContext{
 								FSGroup: &admGroupID,
 							},
-							Subdomain:        names.ServiceName(instanceGroup.Name),
+							Subdomain:        names.ServiceName(deploymentName, instanceGroup.Name),
 							ImagePullSecrets: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.ImagePullSecrets,
 						},
 					},
@@ -235,7 +239,7 @@ func (kc *BPMConverter) quarksStatefulset(
 			namespace,
 		)
 	} else {
-		spec.DNSPolicy, spec.DNSConfig, err = boshdns.DNSSetting(manifest, serviceIP, namespace)
+		err = dns.SetPodSpec(manifest, namespace, spec)
 		if err != nil {
 			return qstsv1a1.QuarksStatefulSet{}, err
 		}
@@ -257,7 +261,7 @@ func (kc *BPMConverter) quarksStatefulset(
 }
 
 // service creates a k8s services, which exposes the BOSH InstanceGroup's jobs
-func (kc *BPMConverter) service(namespace string, deploymentName string, instanceGroup *bdm.InstanceGroup, qSts *qstsv1a1.QuarksStatefulSet, bpmConfigs bpm.Configs) []corev1.Service {
+func (kc *BPMConverter) service(namespace string, deploymentName string, instanceGroup *bdm.InstanceGroup, qSts *qstsv1a1.QuarksStatefulSet, bpmConfigs bpm.Configs, nativeDNS bool) []corev1.Service {
 	var services []corev1.Service
 	// Collect ports from bpm configs
 	ports := bpmConfigs.ServicePorts()
@@ -289,7 +293,7 @@ func (kc *BPMConverter) service(namespace string, deploymentName string, instanc
 			ports)
 	}
 
-	headlessServiceName := names.ServiceName(instanceGroup.Name)
+	headlessServiceName := names.ServiceName(deploymentName, instanceGroup.Name)
 	headlessServiceSelector := map[string]string{
 		bdv1.LabelDeploymentName:    deploymentName,
 		bdv1.LabelInstanceGroupName: instanceGroup.Name,
@@ -321,6 +325,22 @@ func (kc *BPMConverter) service(namespace string, deploymentName string, instanc
 
 	services = append(services, headlessService)
 
+	// Native DNS resolves the aliases of the instance group with a stable ClusterIP
+	if nativeDNS {
+		services = append(services, corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        boshdns.AliasServiceName(deploymentName, instanceGroup.Name),
+				Namespace:   namespace,
+				Labels:      labels,
+				Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
+			},
+			Spec: corev1.ServiceSpec{
+				Ports:    ports,
+				Selector: headlessServiceSelector,
+			},
+		})
+	}
+
 	return services
 }
 
@@ -328,8 +348,9 @@ func (kc *BPMConverter) service(namespace string, deploymentName string, instanc
 func (kc *BPMConverter) quarksJob(
 	manifest bdm.Manifest,
 	namespace string,
+	deploymentName string,
 	cfac ContainerFactory,
-	serviceIP string,
+	dns boshdns.PodDNS,
 	instanceGroup *bdm.InstanceGroup,
 	defaultDisks bdm.Disks,
 	bpmDisks bdm.Disks,
@@ -365,12 +386,18 @@ func (kc *BPMConverter) quarksJob(
 		restartPolicy = corev1.RestartPolicyNever
 	}
 
+	jobAnnotations, err := scheduleAnnotations(instanceGroup)
+	if err != nil {
+		return qjv1a1.QuarksJob{}, errors.Wrapf(err, "invalid schedule for instance group %s", instanceGroup.Name)
+	}
+
+	qJobName := names.ErrandName(deploymentName, instanceGroup.Name)
 	qJob := qjv1a1.QuarksJob{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        instanceGroup.Name,
+			Name:        qJobName,
 			Namespace:   namespace,
 			Labels:      instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Labels,
-			Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
+			Annotations: jobAnnotations,
 		},
 		Spec: qjv1a1.QuarksJobSpec{
 			Trigger: qjv1a1.Trigger{
@@ -381,7 +408,7 @@ func (kc *BPMConverter) quarksJob(
 					BackoffLimit: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.JobBackoffLimit,
 					Template: corev1.PodTemplateSpec{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:        instanceGroup.Name,
+							Name:        qJobName,
 							Labels:      podLabels,
 							Annotations: instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings.Annotations,
 						},
@@ -409,7 +436,7 @@ func (kc *BPMConverter) quarksJob(
 			namespace,
 		)
 	} else {
-		spec.DNSPolicy, spec.DNSConfig, err = boshdns.DNSSetting(manifest, serviceIP, namespace)
+		err = dns.SetPodSpec(manifest, namespace, spec)
 		if err != nil {
 			return qjv1a1.QuarksJob{}, err
 		}
@@ -434,6 +461,40 @@ func (kc *BPMConverter) quarksJob(
 	return qJob, nil
 }
 
+// scheduleAnnotations adds the schedule of an errand to the annotations of
+// its QuarksJob
+func scheduleAnnotations(instanceGroup *bdm.InstanceGroup) (map[string]string, error) {
+	settings := instanceGroup.Env.AgentEnvBoshConfig.Agent.Settings
+	schedule := settings.Schedule
+	if schedule == nil {
+		return settings.Annotations, nil
+	}
+
+	if instanceGroup.LifeCycle != bdm.IGTypeErrand {
+		return nil, errors.Errorf("schedule is only supported for instance groups with lifecycle '%s'", bdm.IGTypeErrand)
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	annotations := make(map[string]string, len(settings.Annotations)+4)
+	for k, v := range settings.Annotations {
+		annotations[k] = v
+	}
+	annotations[bdv1.AnnotationErrandSchedule] = schedule.Cron
+	if schedule.ConcurrencyPolicy != "" {
+		annotations[bdv1.AnnotationErrandConcurrencyPolicy] = string(schedule.ConcurrencyPolicy)
+	}
+	if schedule.StartingDeadlineSeconds != nil {
+		annotations[bdv1.AnnotationErrandStartingDeadline] = strconv.FormatInt(*schedule.StartingDeadlineSeconds, 10)
+	}
+	if schedule.HistoryLimit != nil {
+		annotations[bdv1.AnnotationErrandHistoryLimit] = strconv.Itoa(int(*schedule.HistoryLimit))
+	}
+
+	return annotations, nil
+}
+
 func (kc *BPMConverter) generateServices(
 	services []corev1.Service,
 	namespace string,
@@ -461,7 +522,7 @@ func (kc *BPMConverter) generateServices(
 	for i := 0; i < instanceGroup.Instances; i++ {
 		services = append(services, corev1.Service{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      instanceGroup.IndexedServiceName(i, azIndex),
+				Name:      instanceGroup.IndexedServiceName(deploymentName, i, azIndex),
 				Namespace: namespace,
 				Labels:    serviceLabels(azIndex, i, false),
 			},
@@ -502,5 +563,15 @@ func computeAnnotations(ig *bdm.InstanceGroup) (map[string]string, error) {
 		statefulSetAnnotations[statefulset.AnnotationUpdateWatchTime] = updateWatchTime
 	}
 
+	if ig.Update.Canaries > 0 {
+		statefulSetAnnotations[bdv1.AnnotationCanaries] = strconv.Itoa(ig.Update.Canaries)
+	}
+
+	maxInFlight, err := bdm.ExtractMaxInFlight(ig.Update.MaxInFlight, ig.Instances)
+	if err != nil {
+		return nil, errors.Wrap(err, "update block has invalid max_in_flight")
+	}
+	statefulSetAnnotations[bdv1.AnnotationMaxInFlight] = strconv.Itoa(maxInFlight)
+
 	return statefulSetAnnotations, nil
 }
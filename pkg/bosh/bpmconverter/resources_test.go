@@ -17,6 +17,7 @@ import (
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter/fakes"
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
 	"code.cloudfoundry.org/quarks-operator/testing"
 	"code.cloudfoundry.org/quarks-operator/testing/boshreleases"
 	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
@@ -28,6 +29,7 @@ var _ = Describe("BPM Converter", func() {
 	var (
 		m                *manifest.Manifest
 		deploymentName   string
+		dns              boshdns.PodDNS
 		volumeFactory    *fakes.FakeVolumeFactory
 		containerFactory *fakes.FakeContainerFactory
 		env              testing.Catalog
@@ -41,12 +43,13 @@ var _ = Describe("BPM Converter", func() {
 				func(igName string, errand bool, version string, disableLogSidecar bool, releaseImageProvider manifest.ReleaseImageProvider, bpmConfigs bpm.Configs) bpmconverter.ContainerFactory {
 					return containerFactory
 				})
-			resources, err := c.Resources(*m, "foo", deploymentName, "1.2.3.4", "1", instanceGroup, bpmConfigs, "1")
+			resources, err := c.Resources(*m, "foo", deploymentName, dns, "1", instanceGroup, bpmConfigs, "1")
 			return resources, err
 		}
 
 		BeforeEach(func() {
 			deploymentName = "fake-deployment"
+			dns = boshdns.PodDNS{ServiceIP: "1.2.3.4"}
 
 			m, err = env.DefaultBOSHManifest()
 			Expect(err).NotTo(HaveOccurred())
@@ -98,7 +101,7 @@ var _ = Describe("BPM Converter", func() {
 
 					// Test labels and annotations in the quarks job
 					qJob := resources.Errands[0]
-					Expect(qJob.Name).To(Equal("redis-slave"))
+					Expect(qJob.Name).To(Equal(deploymentName + "-redis-slave"))
 					Expect(qJob.GetLabels()).To(HaveKeyWithValue(bdv1.LabelDeploymentName, deploymentName))
 					Expect(qJob.GetLabels()).To(HaveKeyWithValue(bdv1.LabelInstanceGroupName, m.InstanceGroups[0].Name))
 					Expect(qJob.GetLabels()).To(HaveKeyWithValue(bdv1.LabelDeploymentVersion, "1"))
@@ -125,6 +128,39 @@ var _ = Describe("BPM Converter", func() {
 					Expect(qJob.Spec.Template.Spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyOnFailure))
 				})
 
+				It("adds the schedule of the errand to the quarksJob", func() {
+					m.InstanceGroups[0].Env.AgentEnvBoshConfig.Agent.Settings.Schedule = &manifest.ErrandSchedule{
+						Cron:                    "0 3 * * *",
+						ConcurrencyPolicy:       manifest.ErrandConcurrencyReplace,
+						StartingDeadlineSeconds: pointers.Int64(600),
+						HistoryLimit:            pointers.Int32(5),
+					}
+					resources, err := act(bpmConfigs[0], m.InstanceGroups[0])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					qJob := resources.Errands[0]
+					Expect(qJob.Spec.Trigger.Strategy).To(Equal(qjv1a1.TriggerManual))
+					Expect(qJob.GetAnnotations()).To(HaveKeyWithValue("custom-annotation", "bar"))
+					Expect(qJob.GetAnnotations()).To(HaveKeyWithValue(bdv1.AnnotationErrandSchedule, "0 3 * * *"))
+					Expect(qJob.GetAnnotations()).To(HaveKeyWithValue(bdv1.AnnotationErrandConcurrencyPolicy, "Replace"))
+					Expect(qJob.GetAnnotations()).To(HaveKeyWithValue(bdv1.AnnotationErrandStartingDeadline, "600"))
+					Expect(qJob.GetAnnotations()).To(HaveKeyWithValue(bdv1.AnnotationErrandHistoryLimit, "5"))
+					Expect(qJob.Spec.Template.Spec.Template.GetAnnotations()).NotTo(HaveKey(bdv1.AnnotationErrandSchedule))
+				})
+
+				It("rejects an invalid schedule", func() {
+					m.InstanceGroups[0].Env.AgentEnvBoshConfig.Agent.Settings.Schedule = &manifest.ErrandSchedule{Cron: "every night"}
+					_, err := act(bpmConfigs[0], m.InstanceGroups[0])
+					Expect(err).To(MatchError(ContainSubstring("invalid schedule for instance group redis-slave: invalid cron schedule 'every night'")))
+				})
+
+				It("rejects a schedule for auto-errands", func() {
+					m.InstanceGroups[0].LifeCycle = manifest.IGTypeAutoErrand
+					m.InstanceGroups[0].Env.AgentEnvBoshConfig.Agent.Settings.Schedule = &manifest.ErrandSchedule{Cron: "@daily"}
+					_, err := act(bpmConfigs[0], m.InstanceGroups[0])
+					Expect(err).To(MatchError(ContainSubstring("schedule is only supported for instance groups with lifecycle 'errand'")))
+				})
+
 				It("converts the AgentEnvBoshConfig information", func() {
 					affinityCase := corev1.Affinity{
 						NodeAffinity: &corev1.NodeAffinity{
@@ -229,7 +265,7 @@ var _ = Describe("BPM Converter", func() {
 					service0 := resources.Services[0]
 					Expect(service0.Spec.Selector).To(Equal(map[string]string{
 						bdv1.LabelDeploymentName:    deploymentName,
-						bdv1.LabelInstanceGroupName: stS.Name,
+						bdv1.LabelInstanceGroupName: m.InstanceGroups[1].Name,
 						qstsv1a1.LabelAZIndex:       "0",
 						qstsv1a1.LabelPodOrdinal:    "0",
 						qstsv1a1.LabelActivePod:     "active",
@@ -253,7 +289,7 @@ var _ = Describe("BPM Converter", func() {
 
 					// Test labels and annotation in the quarks statefulSet
 					qSts := resources.InstanceGroups[0]
-					Expect(qSts.Name).To(Equal("diego-cell"))
+					Expect(qSts.Name).To(Equal(deploymentName + "-diego-cell"))
 					Expect(qSts.GetLabels()).To(HaveKeyWithValue(bdv1.LabelDeploymentName, deploymentName))
 					Expect(qSts.GetLabels()).To(HaveKeyWithValue(bdv1.LabelInstanceGroupName, "diego-cell"))
 					Expect(qSts.GetLabels()).To(HaveKeyWithValue(bdv1.LabelDeploymentVersion, "1"))
@@ -262,14 +298,14 @@ var _ = Describe("BPM Converter", func() {
 					Expect(qSts.Spec.Zones).To(Equal(m.InstanceGroups[1].AZs))
 
 					stS := qSts.Spec.Template.Spec.Template
-					Expect(stS.Name).To(Equal("diego-cell"))
+					Expect(stS.Name).To(Equal(deploymentName + "-diego-cell"))
 
 					// Test services for the quarks statefulSet
 					service0 := resources.Services[0]
-					Expect(service0.Name).To(Equal(fmt.Sprintf("%s-z%d-0", stS.Name, 0)))
+					Expect(service0.Name).To(Equal(fmt.Sprintf("%s-%s-z%d-0", deploymentName, m.InstanceGroups[1].Name, 0)))
 					Expect(service0.Spec.Selector).To(Equal(map[string]string{
 						bdv1.LabelDeploymentName:    deploymentName,
-						bdv1.LabelInstanceGroupName: stS.Name,
+						bdv1.LabelInstanceGroupName: m.InstanceGroups[1].Name,
 						qstsv1a1.LabelAZIndex:       "0",
 						qstsv1a1.LabelPodOrdinal:    "0",
 					}))
@@ -282,10 +318,10 @@ var _ = Describe("BPM Converter", func() {
 					}))
 
 					service1 := resources.Services[1]
-					Expect(service1.Name).To(Equal(fmt.Sprintf("%s-z%d-1", stS.Name, 0)))
+					Expect(service1.Name).To(Equal(fmt.Sprintf("%s-%s-z%d-1", deploymentName, m.InstanceGroups[1].Name, 0)))
 					Expect(service1.Spec.Selector).To(Equal(map[string]string{
 						bdv1.LabelDeploymentName:    deploymentName,
-						bdv1.LabelInstanceGroupName: stS.Name,
+						bdv1.LabelInstanceGroupName: m.InstanceGroups[1].Name,
 						qstsv1a1.LabelAZIndex:       "0",
 						qstsv1a1.LabelPodOrdinal:    "1",
 					}))
@@ -298,10 +334,10 @@ var _ = Describe("BPM Converter", func() {
 					}))
 
 					service2 := resources.Services[2]
-					Expect(service2.Name).To(Equal(fmt.Sprintf("%s-z%d-0", stS.Name, 1)))
+					Expect(service2.Name).To(Equal(fmt.Sprintf("%s-%s-z%d-0", deploymentName, m.InstanceGroups[1].Name, 1)))
 					Expect(service2.Spec.Selector).To(Equal(map[string]string{
 						bdv1.LabelDeploymentName:    deploymentName,
-						bdv1.LabelInstanceGroupName: stS.Name,
+						bdv1.LabelInstanceGroupName: m.InstanceGroups[1].Name,
 						qstsv1a1.LabelAZIndex:       "1",
 						qstsv1a1.LabelPodOrdinal:    "0",
 					}))
@@ -314,10 +350,10 @@ var _ = Describe("BPM Converter", func() {
 					}))
 
 					service3 := resources.Services[3]
-					Expect(service3.Name).To(Equal(fmt.Sprintf("%s-z%d-1", stS.Name, 1)))
+					Expect(service3.Name).To(Equal(fmt.Sprintf("%s-%s-z%d-1", deploymentName, m.InstanceGroups[1].Name, 1)))
 					Expect(service3.Spec.Selector).To(Equal(map[string]string{
 						bdv1.LabelDeploymentName:    deploymentName,
-						bdv1.LabelInstanceGroupName: stS.Name,
+						bdv1.LabelInstanceGroupName: m.InstanceGroups[1].Name,
 						qstsv1a1.LabelAZIndex:       "1",
 						qstsv1a1.LabelPodOrdinal:    "1",
 					}))
@@ -330,10 +366,10 @@ var _ = Describe("BPM Converter", func() {
 					}))
 
 					headlessService := resources.Services[4]
-					Expect(headlessService.Name).To(Equal(stS.Name))
+					Expect(headlessService.Name).To(Equal(deploymentName + "-" + m.InstanceGroups[1].Name))
 					Expect(headlessService.Spec.Selector).To(Equal(map[string]string{
 						bdv1.LabelDeploymentName:    deploymentName,
-						bdv1.LabelInstanceGroupName: stS.Name,
+						bdv1.LabelInstanceGroupName: m.InstanceGroups[1].Name,
 					}))
 					Expect(headlessService.Spec.Ports).To(Equal([]corev1.ServicePort{
 						{
@@ -348,6 +384,26 @@ var _ = Describe("BPM Converter", func() {
 					Expect(stS.Spec.Affinity).To(BeNil())
 					Expect(stS.Spec.Tolerations).To(Equal(tolerations))
 				})
+
+				It("uses host aliases and adds an alias service for native DNS", func() {
+					hostAliases := []corev1.HostAlias{{IP: "10.0.0.10", Hostnames: []string{"uaa.service.cf.internal"}}}
+					dns = boshdns.PodDNS{Native: true, HostAliases: hostAliases}
+
+					resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+					Expect(err).ShouldNot(HaveOccurred())
+
+					podSpec := resources.InstanceGroups[0].Spec.Template.Spec.Template.Spec
+					Expect(podSpec.DNSPolicy).To(Equal(corev1.DNSClusterFirst))
+					Expect(podSpec.DNSConfig).To(BeNil())
+					Expect(podSpec.HostAliases).To(Equal(hostAliases))
+
+					Expect(resources.Services).To(HaveLen(6))
+					aliasService := resources.Services[5]
+					Expect(aliasService.Name).To(Equal(deploymentName + "-diego-cell-alias"))
+					Expect(aliasService.Spec.ClusterIP).To(BeEmpty())
+					Expect(aliasService.Spec.Selector).To(Equal(resources.Services[4].Spec.Selector))
+					Expect(aliasService.Spec.Ports).To(Equal(resources.Services[4].Spec.Ports))
+				})
 			})
 
 			It("adds the canaryWatchTime of an instance group to an QuarksStatefulSet", func() {
@@ -358,6 +414,25 @@ var _ = Describe("BPM Converter", func() {
 				Expect(extStS.Spec.Template.Annotations).To(HaveKeyWithValue(statefulset.AnnotationCanaryWatchTime, "1200000"))
 			})
 
+			It("adds canaries and max_in_flight of an instance group to an QuarksStatefulSet", func() {
+				m.InstanceGroups[1].Instances = 4
+				m.InstanceGroups[1].Update.Canaries = 2
+				m.InstanceGroups[1].Update.MaxInFlight = "50%"
+				resources, err := act(bpmConfigs[1], m.InstanceGroups[1])
+				Expect(err).ShouldNot(HaveOccurred())
+
+				extStS := resources.InstanceGroups[0]
+				Expect(extStS.Spec.Template.Annotations).To(HaveKeyWithValue(bdv1.AnnotationCanaries, "2"))
+				Expect(extStS.Spec.Template.Annotations).To(HaveKeyWithValue(bdv1.AnnotationMaxInFlight, "2"))
+			})
+
+			It("fails for an invalid max_in_flight", func() {
+				m.InstanceGroups[1].Update.MaxInFlight = "all"
+				_, err := act(bpmConfigs[1], m.InstanceGroups[1])
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid max_in_flight"))
+			})
+
 			It("combines the canaryWatchTime and custom annotations and adds them to QuarksStatefulSet", func() {
 				m.InstanceGroups[1].Env.AgentEnvBoshConfig.Agent.Settings.Annotations = make(map[string]string)
 				m.InstanceGroups[1].Env.AgentEnvBoshConfig.Agent.Settings.Annotations["custom-annotation"] = "bar"
@@ -625,7 +700,7 @@ var _ = Describe("BPM Converter", func() {
 								releaseImageProvider,
 								bpmConfigs)
 						})
-					resources, err := c.Resources(*m, "foo", deploymentName, "1.2.3.4", "1", m.InstanceGroups[1], bpmConfigs[1], "1")
+					resources, err := c.Resources(*m, "foo", deploymentName, boshdns.PodDNS{ServiceIP: "1.2.3.4"}, "1", m.InstanceGroups[1], bpmConfigs[1], "1")
 
 					Expect(err).ShouldNot(HaveOccurred())
 					Expect(resources.InstanceGroups).To(HaveLen(1))
@@ -91,8 +91,9 @@ func NewVolumeFactory() *VolumeFactoryImpl {
 // - the "not interpolated" manifest volume
 // - resolved properties data volume
 // - shared empty dir for drain-stamps files
-func (f *VolumeFactoryImpl) GenerateDefaultDisks(instanceGroup *bdm.InstanceGroup, igResolvedSecretVersion string, namespace string) bdm.Disks {
+func (f *VolumeFactoryImpl) GenerateDefaultDisks(deploymentName string, instanceGroup *bdm.InstanceGroup, igResolvedSecretVersion string, namespace string) bdm.Disks {
 	resolvedPropertiesSecretName := boshnames.InstanceGroupSecretName(
+		deploymentName,
 		instanceGroup.Name,
 		igResolvedSecretVersion,
 	)
@@ -224,7 +225,7 @@ func (f *VolumeFactoryImpl) GenerateBPMDisks(instanceGroup *bdm.InstanceGroup, b
 					subPath, err = filepath.Rel(VolumeDataDirMountPath, additionalVolume.Path)
 				}
 				if strings.HasPrefix(additionalVolume.Path, VolumeStoreDirMountPath) {
-					volumeName = generatePersistentVolumeClaimName(instanceGroup.Name)
+					volumeName = boshnames.PersistentVolumeClaimName(instanceGroup.Name)
 					subPath, err = filepath.Rel(VolumeStoreDirMountPath, additionalVolume.Path)
 				}
 				if strings.HasPrefix(additionalVolume.Path, VolumeSysDirMountPath) {
@@ -426,7 +427,7 @@ func generatePersistentVolumeClaim(instanceGroup *bdm.InstanceGroup, namespace s
 	// Spec of a persistentVolumeClaim
 	persistentVolumeClaim := corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      generatePersistentVolumeClaimName(instanceGroup.Name),
+			Name:      boshnames.PersistentVolumeClaimName(instanceGroup.Name),
 			Namespace: namespace,
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
@@ -451,10 +452,6 @@ func ephemeralPVCName(instanceGroupName string) string {
 	return names.Sanitize(fmt.Sprintf("%s-%s", instanceGroupName, "ephemeral"))
 }
 
-func generatePersistentVolumeClaimName(instanceGroupName string) string {
-	return names.Sanitize(fmt.Sprintf("%s-%s", instanceGroupName, "pvc"))
-}
-
 func renderingVolume() *corev1.Volume {
 	return &corev1.Volume{
 		Name:         VolumeRenderingDataName,
@@ -45,7 +45,7 @@ var _ = Describe("VolumeFactory", func() {
 
 	Describe("GenerateDefaultDisks", func() {
 		It("creates default disks", func() {
-			disks := factory.GenerateDefaultDisks(instanceGroup, version, namespace)
+			disks := factory.GenerateDefaultDisks("foo-deployment", instanceGroup, version, namespace)
 
 			Expect(disks).Should(HaveLen(6))
 			Expect(disks).Should(ContainElement(bdm.Disk{
@@ -93,7 +93,7 @@ var _ = Describe("VolumeFactory", func() {
 					Name: "ig-resolved",
 					VolumeSource: corev1.VolumeSource{
 						Secret: &corev1.SecretVolumeSource{
-							SecretName: fmt.Sprintf("ig-resolved.%s-v%s", instanceGroup.Name, version),
+							SecretName: fmt.Sprintf("foo-deployment.ig-resolved.%s-v%s", instanceGroup.Name, version),
 						},
 					},
 				},
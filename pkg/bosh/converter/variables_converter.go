@@ -26,7 +26,7 @@ func (vc *VariablesConverter) Variables(namespace string, manifestName string, v
 	secrets := []qsv1a1.QuarksSecret{}
 
 	for _, v := range variables {
-		secretName := names.SecretVariableName(v.Name)
+		secretName := names.SecretVariableName(manifestName, v.Name)
 		s := qsv1a1.QuarksSecret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      secretName,
@@ -89,11 +89,11 @@ func (vc *VariablesConverter) Variables(namespace string, manifestName string, v
 			}
 			if v.Options.CA != "" {
 				certRequest.CARef = qsv1a1.SecretReference{
-					Name: names.SecretVariableName(v.Options.CA),
+					Name: names.SecretVariableName(manifestName, v.Options.CA),
 					Key:  "certificate",
 				}
 				certRequest.CAKeyRef = qsv1a1.SecretReference{
-					Name: names.SecretVariableName(v.Options.CA),
+					Name: names.SecretVariableName(manifestName, v.Options.CA),
 					Key:  "private_key",
 				}
 			}
@@ -7,10 +7,19 @@ import (
 	"go.uber.org/zap"
 )
 
-type matcher func(*InstanceGroup, *AddOnPlacementRules) (bool, error)
+// AddOnDeployment describes the deployment, whose instance groups addons are
+// applied to. It's matched by the deployments and teams placement rules.
+type AddOnDeployment struct {
+	// Name of the BOSHDeployment
+	Name string
+	// Teams owning the deployment. The team of a BOSHDeployment is its namespace.
+	Teams []string
+}
+
+type matcher func(AddOnDeployment, *InstanceGroup, *AddOnPlacementRules) (bool, error)
 
-// jobMatch matches stemcell rules for addon placement
-func (m *Manifest) stemcellMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+// stemcellMatch matches stemcell rules for addon placement
+func (m *Manifest) stemcellMatch(_ AddOnDeployment, instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
 	if instanceGroup == nil || rules == nil {
 		return false, nil
 	}
@@ -36,7 +45,7 @@ func (m *Manifest) stemcellMatch(instanceGroup *InstanceGroup, rules *AddOnPlace
 }
 
 // jobMatch matches job rules for addon placement
-func (m *Manifest) jobMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+func (m *Manifest) jobMatch(_ AddOnDeployment, instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
 	if instanceGroup == nil || rules == nil {
 		return false, nil
 	}
@@ -59,7 +68,7 @@ func (m *Manifest) jobMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementR
 }
 
 // instanceGroupMatch matches instance group rules for addon placement
-func (m *Manifest) instanceGroupMatch(instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+func (m *Manifest) instanceGroupMatch(_ AddOnDeployment, instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
 	if instanceGroup == nil || rules == nil {
 		return false, nil
 	}
@@ -73,8 +82,62 @@ func (m *Manifest) instanceGroupMatch(instanceGroup *InstanceGroup, rules *AddOn
 	return false, nil
 }
 
-// addOnPlacementMatch returns true if any placement rule of the addon matches the instance group
-func (m *Manifest) addOnPlacementMatch(log *zap.SugaredLogger, placementType string, instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+// deploymentMatch matches deployment rules for addon placement
+func (m *Manifest) deploymentMatch(deployment AddOnDeployment, instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+	if instanceGroup == nil || rules == nil {
+		return false, nil
+	}
+
+	for _, name := range rules.Deployments {
+		if name == deployment.Name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// networkMatch matches network rules for addon placement
+func (m *Manifest) networkMatch(_ AddOnDeployment, instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+	if instanceGroup == nil || rules == nil {
+		return false, nil
+	}
+
+	networkList := map[string]struct{}{}
+
+	for _, network := range instanceGroup.Networks {
+		networkList[network.Name] = struct{}{}
+	}
+
+	for _, network := range rules.Networks {
+		if _, networkPresent := networkList[network]; networkPresent {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// teamMatch matches team rules for addon placement
+func (m *Manifest) teamMatch(deployment AddOnDeployment, instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
+	if instanceGroup == nil || rules == nil {
+		return false, nil
+	}
+
+	for _, team := range rules.Teams {
+		for _, t := range deployment.Teams {
+			if team == t {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// addOnPlacementMatch returns true if the placement rules of the addon match
+// the instance group. Every rule type, which lists values, has to match.
+func (m *Manifest) addOnPlacementMatch(log *zap.SugaredLogger, placementType string, deployment AddOnDeployment, instanceGroup *InstanceGroup, rules *AddOnPlacementRules) (bool, error) {
 	// This check is special, not a matcher. Lifecycle always needs to match
 	if (instanceGroup.LifeCycle == IGTypeErrand ||
 		instanceGroup.LifeCycle == IGTypeAutoErrand) &&
@@ -90,21 +153,40 @@ func (m *Manifest) addOnPlacementMatch(log *zap.SugaredLogger, placementType str
 		return false, nil
 	}
 
-	matchers := []matcher{
-		m.stemcellMatch,
-		m.jobMatch,
-		m.instanceGroupMatch,
+	if rules == nil {
+		log.Debugf("Instance group '%s' did not match the %s placement rules", instanceGroup.Name, placementType)
+		return false, nil
+	}
+
+	matchers := []struct {
+		set   bool
+		match matcher
+	}{
+		{len(rules.Stemcell) > 0, m.stemcellMatch},
+		{len(rules.Jobs) > 0, m.jobMatch},
+		{len(rules.InstanceGroup) > 0, m.instanceGroupMatch},
+		{len(rules.Deployments) > 0, m.deploymentMatch},
+		{len(rules.Networks) > 0, m.networkMatch},
+		{len(rules.Teams) > 0, m.teamMatch},
 	}
 
 	matchResult := false
 
 	for _, matcher := range matchers {
-		matched, err := matcher(instanceGroup, rules)
+		if !matcher.set {
+			continue
+		}
+
+		matched, err := matcher.match(deployment, instanceGroup, rules)
 		if err != nil {
 			return false, errors.Wrapf(err, "failed to process match for instance group %s", instanceGroup.Name)
 		}
 
-		matchResult = matchResult || matched
+		if !matched {
+			matchResult = false
+			break
+		}
+		matchResult = true
 	}
 
 	if !matchResult {
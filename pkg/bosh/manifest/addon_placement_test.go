@@ -34,7 +34,7 @@ var _ = Describe("Addons", func() {
 	})
 
 	It("should add addon jobs to instance groups", func() {
-		err := manifest.ApplyAddons(log)
+		err := manifest.ApplyAddons(log, AddOnDeployment{Name: "foo-deployment", Teams: []string{"default"}})
 		Expect(err).NotTo(HaveOccurred())
 		Expect(logs.All()).To(HaveLen(0))
 
@@ -51,13 +51,93 @@ var _ = Describe("Addons", func() {
 		Expect(manifest.InstanceGroups[2].Jobs[1].Name).To(Equal("addon-job3"))
 	})
 
+	Context("when using deployment, network and team placement rules", func() {
+		var deployment AddOnDeployment
+
+		addon := func(name string, include *AddOnPlacementRules) *AddOn {
+			return &AddOn{
+				Name:    name,
+				Jobs:    []AddOnJob{{Name: name + "-job", Release: "redis"}},
+				Include: include,
+			}
+		}
+
+		jobNames := func(ig *InstanceGroup) []string {
+			names := []string{}
+			for _, job := range ig.Jobs {
+				names = append(names, job.Name)
+			}
+			return names
+		}
+
+		BeforeEach(func() {
+			deployment = AddOnDeployment{Name: "foo-deployment", Teams: []string{"default"}}
+		})
+
+		JustBeforeEach(func() {
+			manifest.InstanceGroups[1].Networks = []*Network{{Name: "private"}}
+			manifest.AddOns = []*AddOn{
+				addon("by-deployment", &AddOnPlacementRules{Deployments: []string{"foo-deployment"}}),
+				addon("by-other-deployment", &AddOnPlacementRules{Deployments: []string{"bar-deployment"}}),
+				addon("by-network", &AddOnPlacementRules{Networks: []string{"private"}}),
+				addon("by-team", &AddOnPlacementRules{Teams: []string{"default"}}),
+			}
+		})
+
+		It("matches the deployment name, the networks of the instance group and the teams", func() {
+			err := manifest.ApplyAddons(log, deployment)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(jobNames(manifest.InstanceGroups[0])).To(Equal([]string{"redis-server", "by-deployment-job", "by-team-job"}))
+			Expect(jobNames(manifest.InstanceGroups[1])).To(Equal([]string{"cflinuxfs3-rootfs-setup", "by-deployment-job", "by-network-job", "by-team-job"}))
+			// errands need a lifecycle rule
+			Expect(jobNames(manifest.InstanceGroups[2])).To(Equal([]string{"redis-server"}))
+		})
+
+		Context("when the deployment belongs to another team", func() {
+			BeforeEach(func() {
+				deployment.Teams = []string{"other"}
+			})
+
+			It("doesn't apply addons with team rules", func() {
+				err := manifest.ApplyAddons(log, deployment)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(jobNames(manifest.InstanceGroups[0])).To(Equal([]string{"redis-server", "by-deployment-job"}))
+			})
+		})
+
+		Context("when an addon combines several rule types", func() {
+			JustBeforeEach(func() {
+				manifest.AddOns = []*AddOn{
+					addon("by-deployment-and-network", &AddOnPlacementRules{
+						Deployments: []string{"foo-deployment"},
+						Networks:    []string{"private"},
+					}),
+					addon("by-job-and-other-team", &AddOnPlacementRules{
+						Jobs:  []*AddOnPlacementJob{{Name: "redis-server", Release: "redis"}},
+						Teams: []string{"other"},
+					}),
+				}
+			})
+
+			It("requires all of them to match", func() {
+				err := manifest.ApplyAddons(log, deployment)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(jobNames(manifest.InstanceGroups[0])).To(Equal([]string{"redis-server"}))
+				Expect(jobNames(manifest.InstanceGroups[1])).To(Equal([]string{"cflinuxfs3-rootfs-setup", "by-deployment-and-network-job"}))
+			})
+		})
+	})
+
 	Context("when using trace logger", func() {
 		BeforeEach(func() {
 			logger.Trace = true
 		})
 
 		It("should log", func() {
-			err := manifest.ApplyAddons(log)
+			err := manifest.ApplyAddons(log, AddOnDeployment{Name: "foo-deployment", Teams: []string{"default"}})
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(logs.FilterMessageSnippet("'redis-slave-errand' is an errand, but the exclusion placement rules don't match").Len()).To(Equal(3))
@@ -3,24 +3,21 @@ package manifest
 import "code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
 
 // ApplyUpdateBlock interprets and propagates information of the 'update'-blocks
-func (m *Manifest) ApplyUpdateBlock() {
+func (m *Manifest) ApplyUpdateBlock(deploymentName string) {
 	m.PropagateGlobalUpdateBlockToIGs()
-	m.calculateRequiredServices()
+	m.calculateRequiredServices(deploymentName)
 }
 
 // calculateRequiredServices calculates the required services using the update.serial property
 // It follows the algorithm from BOSH:
 // * it will use the last service as a dependency that had update.serial set
 // * if there are no service ports, it will use the last value
-func (m *Manifest) calculateRequiredServices() {
+func (m *Manifest) calculateRequiredServices(deploymentName string) {
 	var requiredService *string
 	var lastUsedService *string
 
 	for _, ig := range m.InstanceGroups {
-		serial := true
-		if ig.Update != nil && ig.Update.Serial != nil {
-			serial = *ig.Update.Serial
-		}
+		serial := ig.IsSerial()
 
 		if serial {
 			ig.Properties.Quarks.RequiredService = requiredService
@@ -30,7 +27,7 @@ func (m *Manifest) calculateRequiredServices() {
 
 		ports := ig.ServicePorts()
 		if len(ports) > 0 {
-			serviceName := names.ServiceName(ig.Name)
+			serviceName := names.ServiceName(deploymentName, ig.Name)
 			requiredService = &serviceName
 		}
 
@@ -39,3 +36,27 @@ func (m *Manifest) calculateRequiredServices() {
 		}
 	}
 }
+
+// RolloutStages groups the instance groups in the order BOSH updates them:
+// * a serial instance group is updated after all instance groups before it
+// * consecutive non-serial instance groups are updated in parallel
+// Errands are not part of the rollout.
+func (m *Manifest) RolloutStages() []InstanceGroups {
+	stages := []InstanceGroups{}
+	parallel := false
+
+	for _, ig := range m.InstanceGroups {
+		if ig.IsErrand() {
+			continue
+		}
+
+		serial := ig.IsSerial()
+		if serial || !parallel {
+			stages = append(stages, InstanceGroups{})
+		}
+		stages[len(stages)-1] = append(stages[len(stages)-1], ig)
+		parallel = !serial
+	}
+
+	return stages
+}
@@ -2,6 +2,7 @@ package manifest
 
 import (
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpm"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
 )
 
 // BPMInfo contains custom information about
@@ -21,4 +22,19 @@ type BPMInstanceGroup struct {
 	Instances int      `json:"instances"`
 	AZs       []string `json:"azs"`
 	Env       AgentEnv `json:"env,omitempty"`
+	// LinkConsumers are the instance groups, which consume a link provided by this instance group
+	LinkConsumers []string `json:"link_consumers,omitempty"`
+	// ProvidedLinks are the links provided by this instance group
+	ProvidedLinks []ProvidedLink `json:"provided_links,omitempty"`
+}
+
+// ProvidedLink is the name and type of a link provided by an instance group
+type ProvidedLink struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Key returns the '<type>-<name>' key of the link secret
+func (l ProvidedLink) Key() string {
+	return names.QuarksLinkSecretKey(l.Type, l.Name)
 }
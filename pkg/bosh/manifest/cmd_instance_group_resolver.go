@@ -7,11 +7,13 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
 	btg "github.com/viovanov/bosh-template-go"
+	"go.uber.org/zap"
 	yaml "gopkg.in/yaml.v2"
 
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpm"
@@ -27,6 +29,7 @@ const LinkFile = "link"
 // InstanceGroupResolver gathers data for jobs in the manifest, it handles links and returns a deployment manifest
 // that only has information pertinent to an instance group.
 type InstanceGroupResolver struct {
+	log              *zap.SugaredLogger
 	baseDir          string
 	deploymentName   string
 	manifest         Manifest
@@ -37,13 +40,14 @@ type InstanceGroupResolver struct {
 }
 
 // NewInstanceGroupResolver returns a data gatherer with logging for a given input manifest and instance group
-func NewInstanceGroupResolver(fs afero.Fs, basedir string, deploymentName string, manifest Manifest, instanceGroupName string) (*InstanceGroupResolver, error) {
+func NewInstanceGroupResolver(log *zap.SugaredLogger, fs afero.Fs, basedir string, deploymentName string, manifest Manifest, instanceGroupName string) (*InstanceGroupResolver, error) {
 	ig, found := manifest.InstanceGroups.InstanceGroupByName(instanceGroupName)
 	if !found {
 		return nil, errors.Errorf("instance group '%s' not found", instanceGroupName)
 	}
 
 	return &InstanceGroupResolver{
+		log:              log,
 		baseDir:          basedir,
 		deploymentName:   deploymentName,
 		manifest:         manifest,
@@ -99,11 +103,35 @@ func (igr *InstanceGroupResolver) BPMInfo() (BPMInfo, error) {
 	bpmInfo.InstanceGroup.Name = igr.instanceGroup.Name
 	bpmInfo.InstanceGroup.Instances = igr.instanceGroup.Instances
 	bpmInfo.InstanceGroup.Env = igr.instanceGroup.Env
+	bpmInfo.InstanceGroup.LinkConsumers = igr.linkConsumers()
+	bpmInfo.InstanceGroup.ProvidedLinks = append(bpmInfo.InstanceGroup.ProvidedLinks, igr.jobProviderLinks.provided[igr.instanceGroup.Name]...)
+	sort.Slice(bpmInfo.InstanceGroup.ProvidedLinks, func(i, j int) bool {
+		return bpmInfo.InstanceGroup.ProvidedLinks[i].Key() < bpmInfo.InstanceGroup.ProvidedLinks[j].Key()
+	})
 	bpmInfo.Variables = igr.manifest.Variables
 
 	return bpmInfo, nil
 }
 
+// linkConsumers returns the names of the instance groups, which consume a
+// link provided by the current instance group
+func (igr *InstanceGroupResolver) linkConsumers() []string {
+	consumers := []string{}
+	for _, instanceGroup := range igr.manifest.InstanceGroups {
+	jobs:
+		for _, job := range instanceGroup.Jobs {
+			for _, provider := range igr.jobReleaseSpecs[job.Release][job.Name].Consumes {
+				provider := provider
+				if igr.jobProviderLinks.providedBy(igr.instanceGroup.Name, &provider) {
+					consumers = append(consumers, instanceGroup.Name)
+					break jobs
+				}
+			}
+		}
+	}
+	return consumers
+}
+
 // Manifest returns a manifest for a specific instance group only.
 // That manifest includes the gathered data from BPM and links.
 // The output will be persisted by QuarksJob as 'properties.yaml' in the
@@ -239,7 +267,7 @@ func (igr *InstanceGroupResolver) CollectQuarksLinks(linksPath string) error {
 // collectReleaseSpecsAndProviderLinks will collect all release specs and generate bosh links for provider jobs
 func (igr *InstanceGroupResolver) collectReleaseSpecsAndProviderLinks(initialRollout bool) error {
 	for _, instanceGroup := range igr.manifest.InstanceGroups {
-		serviceName := names.ServiceName(instanceGroup.Name)
+		serviceName := names.ServiceName(igr.deploymentName, instanceGroup.Name)
 
 		for jobIdx, job := range instanceGroup.Jobs {
 			// make sure a map entry exists for the current job release
@@ -263,7 +291,7 @@ func (igr *InstanceGroupResolver) collectReleaseSpecsAndProviderLinks(initialRol
 			// This will be stored inside the current job under
 			// job.properties.quarks.
 			// However igr.Manifest() will remove all Quarks.Instances before marshalling the ig manifest
-			jobInstances := instanceGroup.newJobInstances(job.Name, initialRollout)
+			jobInstances := instanceGroup.newJobInstances(igr.deploymentName, job.Name, initialRollout)
 			// set jobs.properties.quarks.instances with the ig instances
 			instanceGroup.Jobs[jobIdx].Properties.Quarks.Instances = jobInstances
 
@@ -369,40 +397,26 @@ func (igr *InstanceGroupResolver) renderJobBPM(currentJob *Job, jobSpecFile stri
 		var renderedBPM = bpm.Config{}
 
 		properties := currentJob.Properties.ToMap()
+		renderPointer := btg.NewERBRenderer(
+			&btg.EvaluationContext{
+				Properties: properties,
+			},
+			&btg.InstanceInfo{
+				Address:    jobInstance.Address,
+				AZ:         jobInstance.AZ,
+				Bootstrap:  jobInstance.Bootstrap,
+				ID:         jobInstance.ID,
+				Index:      jobInstance.Index,
+				Deployment: igr.deploymentName,
+				Name:       jobInstance.Name,
+			},
+			jobSpecFile,
+		)
 
 		if erbFilePath != "" {
-			renderPointer := btg.NewERBRenderer(
-				&btg.EvaluationContext{
-					Properties: properties,
-				},
-				&btg.InstanceInfo{
-					Address:    jobInstance.Address,
-					AZ:         jobInstance.AZ,
-					Bootstrap:  jobInstance.Bootstrap,
-					ID:         jobInstance.ID,
-					Index:      jobInstance.Index,
-					Deployment: igr.deploymentName,
-					Name:       jobInstance.Name,
-				},
-				jobSpecFile,
-			)
-
-			// Write to a tmp, this is following the conventions on how the
-			// https://github.com/viovanov/bosh-template-go/ processes the params
-			// when we calling the *.Render().
-			tmpfile, err := ioutil.TempFile("", "rendered.*.yml")
-			if err != nil {
-				return errors.Wrapf(err, "Creation of tmp file %s failed", tmpfile.Name())
-			}
-			defer os.Remove(tmpfile.Name())
-
-			if err := renderPointer.Render(erbFilePath, tmpfile.Name()); err != nil {
-				return errors.Wrapf(err, "Rendering file %s failed", erbFilePath)
-			}
-
-			bpmBytes, err := ioutil.ReadFile(tmpfile.Name())
+			bpmBytes, err := renderERB(renderPointer, erbFilePath)
 			if err != nil {
-				return errors.Wrapf(err, "Reading of tmp file %s failed", tmpfile.Name())
+				return err
 			}
 
 			// Parse a rendered bpm.yml into the bpm Config struct.
@@ -435,6 +449,9 @@ func (igr *InstanceGroupResolver) renderJobBPM(currentJob *Job, jobSpecFile stri
 
 		// Add these to reflect quarks properties update
 		renderedBPM.Run = currentJob.Properties.Quarks.Run
+
+		// Add default probes from the monit file for processes without health checks
+		renderedBPM.Run.HealthCheck = renderedBPM.MergeHealthChecks(igr.monitHealthChecks(currentJob, renderPointer))
 		renderedBPM.PostStart = currentJob.Properties.Quarks.PostStart
 		renderedBPM.Debug = currentJob.Properties.Quarks.Debug
 		renderedBPM.ActivePassiveProbes = currentJob.Properties.Quarks.ActivePassiveProbes
@@ -456,6 +473,58 @@ func (igr *InstanceGroupResolver) renderJobBPM(currentJob *Job, jobSpecFile stri
 	return nil
 }
 
+// monitHealthChecks returns the probes derived from the job's monit file.
+// Monit files, which contain ERB, are rendered for the job instance first.
+// The probes are only defaults, so a job whose monit file can't be read,
+// rendered or parsed is deployed without them.
+func (igr *InstanceGroupResolver) monitHealthChecks(currentJob *Job, renderer *btg.ERBRenderer) map[string]bpm.HealthCheck {
+	monitFilePath := filepath.Join(currentJob.specDir(igr.baseDir), "monit")
+	monit, err := ioutil.ReadFile(monitFilePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			igr.log.Warnf("Skipping probes of job '%s', reading monit file '%s' failed: %v", currentJob.Name, monitFilePath, err)
+		}
+		return nil
+	}
+
+	if strings.Contains(string(monit), "<%") {
+		monit, err = renderERB(renderer, monitFilePath)
+		if err != nil {
+			igr.log.Warnf("Skipping probes of job '%s', rendering monit file '%s' failed: %v", currentJob.Name, monitFilePath, err)
+			return nil
+		}
+	}
+
+	healthChecks, err := bpm.MonitHealthChecks(monit, currentJob.Name)
+	if err != nil {
+		igr.log.Warnf("Skipping probes of job '%s', parsing monit file '%s' failed: %v", currentJob.Name, monitFilePath, err)
+		return nil
+	}
+	return healthChecks
+}
+
+// renderERB renders the ERB template for a job instance and returns the result
+func renderERB(renderer *btg.ERBRenderer, erbFilePath string) ([]byte, error) {
+	// Write to a tmp, this is following the conventions on how the
+	// https://github.com/viovanov/bosh-template-go/ processes the params
+	// when we calling the *.Render().
+	tmpfile, err := ioutil.TempFile("", "rendered.*.yml")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Creation of tmp file for %s failed", erbFilePath)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if err := renderer.Render(erbFilePath, tmpfile.Name()); err != nil {
+		return nil, errors.Wrapf(err, "Rendering file %s failed", erbFilePath)
+	}
+
+	rendered, err := ioutil.ReadFile(tmpfile.Name())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Reading of tmp file %s failed", tmpfile.Name())
+	}
+	return rendered, nil
+}
+
 // generateJobConsumersData will populate a job with its corresponding provider links
 // under properties.quarks.consumes
 func generateJobConsumersData(currentJob *Job, jobReleaseSpecs map[string]map[string]JobSpec, jobProviderLinks jobProviderLinks) error {
@@ -8,12 +8,14 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/spf13/afero"
+	"go.uber.org/zap"
 
 	bpmConfig "code.cloudfoundry.org/quarks-operator/pkg/bosh/bpm"
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/converter"
 	. "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	"code.cloudfoundry.org/quarks-operator/testing"
 	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
 )
 
 var _ = Describe("InstanceGroupResolver", func() {
@@ -24,8 +26,13 @@ var _ = Describe("InstanceGroupResolver", func() {
 		igr *InstanceGroupResolver
 		ig  string
 		err error
+		log *zap.SugaredLogger
 	)
 
+	BeforeEach(func() {
+		_, log = helper.NewTestLogger()
+	})
+
 	resolve := func() {
 		err := igr.Resolve(true)
 		Expect(err).ToNot(HaveOccurred())
@@ -79,7 +86,7 @@ var _ = Describe("InstanceGroupResolver", func() {
 			m, err = env.BOSHManifestFromKubeCF641()
 			Expect(err).NotTo(HaveOccurred())
 
-			igr, err = NewInstanceGroupResolver(fs, assetPath, deploymentName, *m, ig)
+			igr, err = NewInstanceGroupResolver(log, fs, assetPath, deploymentName, *m, ig)
 			Expect(err).ToNot(HaveOccurred())
 
 			resolve()
@@ -100,7 +107,7 @@ var _ = Describe("InstanceGroupResolver", func() {
 		var deploymentName string
 
 		JustBeforeEach(func() {
-			igr, err = NewInstanceGroupResolver(fs, assetPath, deploymentName, *m, ig)
+			igr, err = NewInstanceGroupResolver(log, fs, assetPath, deploymentName, *m, ig)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -33,6 +33,7 @@ const (
 // qsts controller overwrites replicas, if InjectReplicasEnv is true, otherwise replicas is 1.
 // qsts controller overwrites azIndex (1..n), or to 0 if ig.AZs is null
 func RenderJobTemplates(
+	deploymentName string,
 	boshManifestPath string,
 	jobsDir string,
 	jobsOutputDir string,
@@ -93,7 +94,7 @@ func RenderJobTemplates(
 	for jobIdx, job := range ig.Jobs {
 		// Generate instance spec for each ig instance, so templates
 		// can access all instance specs.
-		jobInstances := ig.newJobInstances(job.Name, initialRollout)
+		jobInstances := ig.newJobInstances(deploymentName, job.Name, initialRollout)
 		ig.Jobs[jobIdx].Properties.Quarks.Instances = jobInstances
 	}
 
@@ -152,13 +153,14 @@ func RenderJobTemplates(
 						},
 
 						&btg.InstanceInfo{
-							Address:   currentJobInstance.Address,
-							AZ:        currentJobInstance.AZ,
-							Bootstrap: currentJobInstance.Bootstrap,
-							ID:        currentJobInstance.ID,
-							Index:     currentJobInstance.Index,
-							IP:        podIP.String(),
-							Name:      currentJobInstance.Name,
+							Address:    currentJobInstance.Address,
+							AZ:         currentJobInstance.AZ,
+							Bootstrap:  currentJobInstance.Bootstrap,
+							Deployment: deploymentName,
+							ID:         currentJobInstance.ID,
+							Index:      currentJobInstance.Index,
+							IP:         podIP.String(),
+							Name:       currentJobInstance.Name,
 						},
 
 						filepath.Join(jobSrcDir, JobSpecFilename),
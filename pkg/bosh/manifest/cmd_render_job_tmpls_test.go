@@ -29,7 +29,7 @@ var _ = Describe("RenderJobTemplates", func() {
 	)
 
 	act := func() error {
-		return manifest.RenderJobTemplates(deploymentManifest, assetPath, tmpDir, instanceGroupName, podIP, azIndex, index, replicas, true)
+		return manifest.RenderJobTemplates("foo-deployment", deploymentManifest, assetPath, tmpDir, instanceGroupName, podIP, azIndex, index, replicas, true)
 	}
 
 	readBPM := func(f string) bpm.Process {
@@ -109,8 +109,8 @@ var _ = Describe("RenderJobTemplates", func() {
 			Expect(values.Env["FOOBARWITHSPECINDEX"]).To(Equal("0"))
 			Expect(values.Env["FOOBARWITHSPECNAME"]).To(Equal("log-api-loggregator_trafficcontroller"))
 			Expect(values.Env["FOOBARWITHSPECNETWORKS"]).To(Equal(""))
-			Expect(values.Env["FOOBARWITHSPECADDRESS"]).To(Equal("log-api-z0-0"))
-			Expect(values.Env["FOOBARWITHSPECDEPLOYMENT"]).To(Equal(""))
+			Expect(values.Env["FOOBARWITHSPECADDRESS"]).To(Equal("foo-deployment-log-api-z0-0"))
+			Expect(values.Env["FOOBARWITHSPECDEPLOYMENT"]).To(Equal("foo-deployment"))
 			Expect(values.Env["FOOBARWITHSPECIP"]).To(Equal("172.17.0.13"))
 		})
 	})
@@ -217,26 +217,26 @@ var _ = Describe("RenderJobTemplates", func() {
 			It("renders the job erb files correctly", func() {
 				tests := cases{
 					// two az
-					{0, 0, 1, true, "log-api-z0-0", "z1", "0", "true"},
-					{1, 1, 1, true, "log-api-z0-1", "z1", "1", "false"},
-					{0, 0, 2, true, "log-api-z1-0", "z2", "2", "false"},
-					{1, 1, 2, true, "log-api-z1-1", "z2", "3", "false"},
+					{0, 0, 1, true, "foo-deployment-log-api-z0-0", "z1", "0", "true"},
+					{1, 1, 1, true, "foo-deployment-log-api-z0-1", "z1", "1", "false"},
+					{0, 0, 2, true, "foo-deployment-log-api-z1-0", "z2", "2", "false"},
+					{1, 1, 2, true, "foo-deployment-log-api-z1-1", "z2", "3", "false"},
 
 					// // two az, updated
-					{0, 1, 1, false, "log-api-z0-0", "z1", "0", "false"},
-					{1, 0, 1, false, "log-api-z0-1", "z1", "1", "false"}, // TODO would have expected this to be bootstrap
-					{0, 1, 2, false, "log-api-z1-0", "z2", "2", "false"},
-					{1, 0, 2, false, "log-api-z1-1", "z2", "3", "true"},
+					{0, 1, 1, false, "foo-deployment-log-api-z0-0", "z1", "0", "false"},
+					{1, 0, 1, false, "foo-deployment-log-api-z0-1", "z1", "1", "false"}, // TODO would have expected this to be bootstrap
+					{0, 1, 2, false, "foo-deployment-log-api-z1-0", "z2", "2", "false"},
+					{1, 0, 2, false, "foo-deployment-log-api-z1-1", "z2", "3", "true"},
 
 					// TODO two az, happily generates out of bounds - nothing we can do, replicas is automatically increased
-					// {20, 20, 1, true, "log-api-z0-20", "z1", "20", "false"},
+					// {20, 20, 1, true, "foo-deployment-log-api-z0-20", "z1", "20", "false"},
 					// FIXME this will fail because of invalid AZ
-					// {20, 20, 3, true, "log-api-z1-20", "z2", "5", "false"},
+					// {20, 20, 3, true, "foo-deployment-log-api-z1-20", "z2", "5", "false"},
 
 				}
 
 				for i, t := range tests {
-					err := manifest.RenderJobTemplates(deploymentManifest, assetPath, tmpDir, instanceGroupName, podIP, t.azIndex, t.podOrdinal, replicas, t.initial)
+					err := manifest.RenderJobTemplates("foo-deployment", deploymentManifest, assetPath, tmpDir, instanceGroupName, podIP, t.azIndex, t.podOrdinal, replicas, t.initial)
 					Expect(err).ToNot(HaveOccurred())
 					values := readBPM(filepath.Join(tmpDir, "loggregator_trafficcontroller", "config/spec.yml"))
 					errstr := fmt.Sprintf("test case %d", i+1)
@@ -256,16 +256,16 @@ var _ = Describe("RenderJobTemplates", func() {
 			It("renders the job erb files correctly", func() {
 				tests := cases{
 					// single az
-					{0, 0, 1, true, "log-api-z0-0", "z1", "0", "true"},
-					{1, 1, 1, true, "log-api-z0-1", "z1", "1", "false"},
+					{0, 0, 1, true, "foo-deployment-log-api-z0-0", "z1", "0", "true"},
+					{1, 1, 1, true, "foo-deployment-log-api-z0-1", "z1", "1", "false"},
 
 					// single az, updated
-					{0, 1, 1, false, "log-api-z0-0", "z1", "0", "false"},
-					{1, 0, 1, false, "log-api-z0-1", "z1", "1", "true"},
+					{0, 1, 1, false, "foo-deployment-log-api-z0-0", "z1", "0", "false"},
+					{1, 0, 1, false, "foo-deployment-log-api-z0-1", "z1", "1", "true"},
 				}
 
 				for i, t := range tests {
-					err := manifest.RenderJobTemplates(deploymentManifest, assetPath, tmpDir, instanceGroupName, podIP, t.azIndex, t.podOrdinal, replicas, t.initial)
+					err := manifest.RenderJobTemplates("foo-deployment", deploymentManifest, assetPath, tmpDir, instanceGroupName, podIP, t.azIndex, t.podOrdinal, replicas, t.initial)
 					Expect(err).ToNot(HaveOccurred())
 					values := readBPM(filepath.Join(tmpDir, "loggregator_trafficcontroller", "config/spec.yml"))
 					errstr := fmt.Sprintf("test case %d", i+1)
@@ -285,16 +285,16 @@ var _ = Describe("RenderJobTemplates", func() {
 			It("renders the job erb files correctly", func() {
 				tests := cases{
 					// no az
-					{0, 0, 0, true, "log-api-0", "", "0", "true"},
-					{1, 1, 0, true, "log-api-1", "", "1", "false"},
+					{0, 0, 0, true, "foo-deployment-log-api-0", "", "0", "true"},
+					{1, 1, 0, true, "foo-deployment-log-api-1", "", "1", "false"},
 
 					// no az, updated
-					{0, 1, 0, false, "log-api-0", "", "0", "false"},
-					{1, 0, 0, false, "log-api-1", "", "1", "true"},
+					{0, 1, 0, false, "foo-deployment-log-api-0", "", "0", "false"},
+					{1, 0, 0, false, "foo-deployment-log-api-1", "", "1", "true"},
 				}
 
 				for i, t := range tests {
-					err := manifest.RenderJobTemplates(deploymentManifest, assetPath, tmpDir, instanceGroupName, podIP, t.azIndex, t.podOrdinal, replicas, t.initial)
+					err := manifest.RenderJobTemplates("foo-deployment", deploymentManifest, assetPath, tmpDir, instanceGroupName, podIP, t.azIndex, t.podOrdinal, replicas, t.initial)
 					Expect(err).ToNot(HaveOccurred())
 					values := readBPM(filepath.Join(tmpDir, "loggregator_trafficcontroller", "config/spec.yml"))
 					errstr := fmt.Sprintf("test case %d", i+1)
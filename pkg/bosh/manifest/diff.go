@@ -0,0 +1,236 @@
+package manifest
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// InstanceGroupDiff lists the changes to an instance group between two manifests.
+// Property values are not included, since they might contain secrets.
+type InstanceGroupDiff struct {
+	Name        string           `json:"name"`
+	Added       bool             `json:"added,omitempty"`
+	Removed     bool             `json:"removed,omitempty"`
+	Instances   *InstancesChange `json:"instances,omitempty"`
+	AddedJobs   []string         `json:"addedJobs,omitempty"`
+	RemovedJobs []string         `json:"removedJobs,omitempty"`
+	Images      []ImageChange    `json:"images,omitempty"`
+	Properties  []PropertyChange `json:"properties,omitempty"`
+	// Resources are the changes to the rendered resources of the instance group
+	Resources []ResourceChange `json:"resources,omitempty"`
+}
+
+// InstancesChange is a change of the number of instances
+type InstancesChange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// ImageChange is a change of the release image of a job
+type ImageChange struct {
+	Job  string `json:"job"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PropertyChange is a job property, which was added, removed or changed
+type PropertyChange struct {
+	Job  string `json:"job"`
+	Path string `json:"path"`
+	// Change is one of 'added', 'removed' or 'changed'
+	Change string `json:"change"`
+}
+
+// ResourceChange is a QuarksStatefulSet, Service or QuarksJob of an instance
+// group, which would be created or updated
+type ResourceChange struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Change is one of 'added' or 'changed'
+	Change string `json:"change"`
+	// Paths are the changed fields of an existing resource
+	Paths []string `json:"paths,omitempty"`
+}
+
+// Changed returns true if the instance group has any changes
+func (d InstanceGroupDiff) Changed() bool {
+	return d.Added || d.Removed || d.Instances != nil ||
+		len(d.AddedJobs) > 0 || len(d.RemovedJobs) > 0 ||
+		len(d.Images) > 0 || len(d.Properties) > 0 || len(d.Resources) > 0
+}
+
+// DiffInstanceGroups compares the instance groups of the current manifest
+// with the ones of a new manifest. Only instance groups with changes are returned.
+func DiffInstanceGroups(current *Manifest, desired *Manifest) ([]InstanceGroupDiff, error) {
+	diffs := []InstanceGroupDiff{}
+
+	for _, ig := range desired.InstanceGroups {
+		old, found := current.InstanceGroups.InstanceGroupByName(ig.Name)
+		if !found {
+			diffs = append(diffs, InstanceGroupDiff{Name: ig.Name, Added: true})
+			continue
+		}
+
+		diff, err := diffInstanceGroup(current, old, desired, ig)
+		if err != nil {
+			return diffs, err
+		}
+		if diff.Changed() {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	for _, ig := range current.InstanceGroups {
+		if _, found := desired.InstanceGroups.InstanceGroupByName(ig.Name); !found {
+			diffs = append(diffs, InstanceGroupDiff{Name: ig.Name, Removed: true})
+		}
+	}
+
+	return diffs, nil
+}
+
+func diffInstanceGroup(current *Manifest, old *InstanceGroup, desired *Manifest, ig *InstanceGroup) (InstanceGroupDiff, error) {
+	diff := InstanceGroupDiff{Name: ig.Name}
+
+	if old.Instances != ig.Instances {
+		diff.Instances = &InstancesChange{From: old.Instances, To: ig.Instances}
+	}
+
+	oldJobs := map[string]*Job{}
+	for i := range old.Jobs {
+		oldJobs[old.Jobs[i].Name] = &old.Jobs[i]
+	}
+
+	for i := range ig.Jobs {
+		job := &ig.Jobs[i]
+		oldJob, found := oldJobs[job.Name]
+		if !found {
+			diff.AddedJobs = append(diff.AddedJobs, job.Name)
+			continue
+		}
+		delete(oldJobs, job.Name)
+
+		oldImage, err := current.GetReleaseImage(old.Name, oldJob.Name)
+		if err != nil {
+			return diff, errors.Wrapf(err, "failed to get current release image of job '%s'", job.Name)
+		}
+		image, err := desired.GetReleaseImage(ig.Name, job.Name)
+		if err != nil {
+			return diff, errors.Wrapf(err, "failed to get release image of job '%s'", job.Name)
+		}
+		if oldImage != image {
+			diff.Images = append(diff.Images, ImageChange{Job: job.Name, From: oldImage, To: image})
+		}
+
+		changes, err := diffProperties(oldJob, job)
+		if err != nil {
+			return diff, err
+		}
+		diff.Properties = append(diff.Properties, changes...)
+	}
+
+	for name := range oldJobs {
+		diff.RemovedJobs = append(diff.RemovedJobs, name)
+	}
+	sort.Strings(diff.RemovedJobs)
+
+	return diff, nil
+}
+
+func diffProperties(old *Job, job *Job) ([]PropertyChange, error) {
+	oldProps, err := flattenProperties(old)
+	if err != nil {
+		return nil, err
+	}
+	props, err := flattenProperties(job)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []PropertyChange{}
+	for path, value := range props {
+		oldValue, found := oldProps[path]
+		if !found {
+			changes = append(changes, PropertyChange{Job: job.Name, Path: path, Change: "added"})
+		} else if !reflect.DeepEqual(oldValue, value) {
+			changes = append(changes, PropertyChange{Job: job.Name, Path: path, Change: "changed"})
+		}
+	}
+	for path := range oldProps {
+		if _, found := props[path]; !found {
+			changes = append(changes, PropertyChange{Job: job.Name, Path: path, Change: "removed"})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// flattenProperties returns the job properties indexed by their dotted path
+func flattenProperties(job *Job) (map[string]interface{}, error) {
+	data, err := json.Marshal(job.Properties.ToMap())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal properties of job '%s'", job.Name)
+	}
+
+	properties := map[string]interface{}{}
+	err = json.Unmarshal(data, &properties)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal properties of job '%s'", job.Name)
+	}
+
+	result := map[string]interface{}{}
+	traverse("", properties, func(path string, value interface{}) {
+		result[path] = value
+	})
+	return result, nil
+}
+
+// ChangedPaths returns the dotted paths of the fields, which differ between
+// two objects. Lists are compared as a whole.
+func ChangedPaths(old interface{}, new interface{}) ([]string, error) {
+	oldFields, err := flatten(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flatten(new)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for path, value := range newFields {
+		if oldValue, found := oldFields[path]; !found || !reflect.DeepEqual(oldValue, value) {
+			paths = append(paths, path)
+		}
+	}
+	for path := range oldFields {
+		if _, found := newFields[path]; !found {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func flatten(obj interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal object")
+	}
+
+	fields := map[string]interface{}{}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal object")
+	}
+
+	result := map[string]interface{}{}
+	traverse("", fields, func(path string, value interface{}) {
+		result[path] = value
+	})
+	return result, nil
+}
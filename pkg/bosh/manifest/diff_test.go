@@ -0,0 +1,139 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+)
+
+var _ = Describe("DiffInstanceGroups", func() {
+	const currentManifest = `---
+name: diff
+releases:
+- name: redis
+  url: docker.io/cfcontainerization
+  version: 36.15.0
+  stemcell:
+    os: opensuse-42.3
+    version: 36.g03b4653-30.80-7.0.0_316.gcf9fe4a7
+instance_groups:
+- name: redis-slave
+  instances: 2
+  jobs:
+  - name: redis-server
+    release: redis
+    properties:
+      password: secret
+      persistence: "yes"
+      cluster:
+        enabled: true
+  - name: cleanup
+    release: redis
+- name: unchanged
+  instances: 1
+  jobs:
+  - name: redis-server
+    release: redis
+- name: legacy
+  instances: 1
+  jobs:
+  - name: redis-server
+    release: redis
+`
+
+	var (
+		current *Manifest
+		desired *Manifest
+	)
+
+	BeforeEach(func() {
+		var err error
+		current, err = LoadYAML([]byte(currentManifest))
+		Expect(err).NotTo(HaveOccurred())
+		desired, err = LoadYAML([]byte(currentManifest))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns no changes for identical manifests", func() {
+		diffs, err := DiffInstanceGroups(current, desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).To(BeEmpty())
+	})
+
+	It("lists added and removed instance groups", func() {
+		desired.InstanceGroups[2].Name = "new"
+
+		diffs, err := DiffInstanceGroups(current, desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).To(ConsistOf(
+			InstanceGroupDiff{Name: "new", Added: true},
+			InstanceGroupDiff{Name: "legacy", Removed: true},
+		))
+	})
+
+	It("lists the changes of an instance group", func() {
+		ig := desired.InstanceGroups[0]
+		ig.Instances = 3
+		ig.Jobs[0].Properties.Properties["password"] = "changed"
+		ig.Jobs[0].Properties.Properties["maxclients"] = 100
+		delete(ig.Jobs[0].Properties.Properties, "persistence")
+		ig.Jobs[1].Name = "backup"
+		desired.Releases[0].Version = "36.16.0"
+
+		diffs, err := DiffInstanceGroups(current, desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).To(HaveLen(3))
+
+		diff := diffs[0]
+		Expect(diff.Name).To(Equal("redis-slave"))
+		Expect(diff.Instances).To(Equal(&InstancesChange{From: 2, To: 3}))
+		Expect(diff.AddedJobs).To(Equal([]string{"backup"}))
+		Expect(diff.RemovedJobs).To(Equal([]string{"cleanup"}))
+		Expect(diff.Images).To(ConsistOf(ImageChange{
+			Job:  "redis-server",
+			From: "docker.io/cfcontainerization/redis:opensuse-42.3-36.g03b4653-30.80-7.0.0_316.gcf9fe4a7-36.15.0",
+			To:   "docker.io/cfcontainerization/redis:opensuse-42.3-36.g03b4653-30.80-7.0.0_316.gcf9fe4a7-36.16.0",
+		}))
+		Expect(diff.Properties).To(Equal([]PropertyChange{
+			{Job: "redis-server", Path: "maxclients", Change: "added"},
+			{Job: "redis-server", Path: "password", Change: "changed"},
+			{Job: "redis-server", Path: "persistence", Change: "removed"},
+		}))
+
+		Expect(diffs[1].Name).To(Equal("unchanged"))
+		Expect(diffs[1].Images).To(HaveLen(1))
+	})
+
+	It("uses the dotted path for nested properties", func() {
+		desired.InstanceGroups[0].Jobs[0].Properties.Properties["cluster"] = map[string]interface{}{"enabled": false}
+
+		diffs, err := DiffInstanceGroups(current, desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).To(HaveLen(1))
+		Expect(diffs[0].Properties).To(Equal([]PropertyChange{
+			{Job: "redis-server", Path: "cluster.enabled", Change: "changed"},
+		}))
+	})
+
+	Context("ChangedPaths", func() {
+		It("returns the dotted paths of added, removed and changed fields", func() {
+			old := map[string]interface{}{
+				"spec": map[string]interface{}{"replicas": 1, "ports": []int{80}, "paused": true},
+			}
+			updated := map[string]interface{}{
+				"spec": map[string]interface{}{"replicas": 2, "ports": []int{80, 443}, "name": "nats"},
+			}
+
+			paths, err := ChangedPaths(old, updated)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{"spec.name", "spec.paused", "spec.ports", "spec.replicas"}))
+		})
+
+		It("returns no paths for equal objects", func() {
+			paths, err := ChangedPaths(current.InstanceGroups[0], current.InstanceGroups[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,52 @@
+package manifest
+
+import (
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// ErrandConcurrencyPolicy decides what happens to a scheduled run of an
+// errand, while the errand is still running
+type ErrandConcurrencyPolicy string
+
+const (
+	// ErrandConcurrencyForbid delays the scheduled run until the running errand finished
+	ErrandConcurrencyForbid ErrandConcurrencyPolicy = "Forbid"
+	// ErrandConcurrencyReplace stops the running errand and starts the scheduled run
+	ErrandConcurrencyReplace ErrandConcurrencyPolicy = "Replace"
+)
+
+// ErrandSchedule runs an errand instance group periodically. It is set in
+// the instance group's env.bosh.agent.settings.schedule.
+type ErrandSchedule struct {
+	// Cron is the schedule in cron format, e.g. '0 3 * * *' or '@daily'
+	Cron string `json:"cron"`
+	// ConcurrencyPolicy defaults to 'Forbid'
+	ConcurrencyPolicy ErrandConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+	// StartingDeadlineSeconds is how late a scheduled run may start, before it is skipped
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+	// HistoryLimit is the number of earlier results kept in the BOSHDeployment status
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+}
+
+// Validate returns an error, if the schedule cannot be parsed
+func (s *ErrandSchedule) Validate() error {
+	if _, err := cron.ParseStandard(s.Cron); err != nil {
+		return errors.Wrapf(err, "invalid cron schedule '%s'", s.Cron)
+	}
+
+	switch s.ConcurrencyPolicy {
+	case "", ErrandConcurrencyForbid, ErrandConcurrencyReplace:
+	default:
+		return errors.Errorf("invalid concurrency policy '%s', must be '%s' or '%s'", s.ConcurrencyPolicy, ErrandConcurrencyForbid, ErrandConcurrencyReplace)
+	}
+
+	if s.StartingDeadlineSeconds != nil && *s.StartingDeadlineSeconds < 1 {
+		return errors.Errorf("starting deadline must be positive, got %d", *s.StartingDeadlineSeconds)
+	}
+	if s.HistoryLimit != nil && *s.HistoryLimit < 0 {
+		return errors.Errorf("history limit must not be negative, got %d", *s.HistoryLimit)
+	}
+
+	return nil
+}
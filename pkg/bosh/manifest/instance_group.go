@@ -107,10 +107,19 @@ func (ig *InstanceGroup) IsErrand() bool {
 	return ig.LifeCycle == IGTypeErrand || ig.LifeCycle == IGTypeAutoErrand
 }
 
+// IsSerial returns true if the instance group is updated after the instance
+// groups before it. BOSH defaults to serial, if update.serial is not set.
+func (ig *InstanceGroup) IsSerial() bool {
+	if ig.Update != nil && ig.Update.Serial != nil {
+		return *ig.Update.Serial
+	}
+	return true
+}
+
 // IndexedServiceName constructs an indexed service name. It's used to construct the service
-// names other than the headless service.
-func (ig *InstanceGroup) IndexedServiceName(index int, azIndex int) string {
-	sn := boshnames.TruncatedServiceName(ig.Name, 53)
+// names other than the headless service. The name is prefixed with the deployment name.
+func (ig *InstanceGroup) IndexedServiceName(deploymentName string, index int, azIndex int) string {
+	sn := boshnames.TruncatedServiceName(deploymentName+"-"+ig.Name, 53)
 	if azIndex > -1 {
 		return fmt.Sprintf("%s-z%d-%d", sn, azIndex, index)
 	}
@@ -118,16 +127,18 @@ func (ig *InstanceGroup) IndexedServiceName(index int, azIndex int) string {
 }
 
 func (ig *InstanceGroup) newJobInstances(
+	deploymentName string,
 	jobName string,
 	initialRollout bool,
 ) []JobInstance {
 	if len(ig.AZs) > 0 {
-		return ig.jobInstancesAZ(jobName, initialRollout)
+		return ig.jobInstancesAZ(deploymentName, jobName, initialRollout)
 	}
-	return ig.jobInstances(jobName, initialRollout)
+	return ig.jobInstances(deploymentName, jobName, initialRollout)
 }
 
 func (ig *InstanceGroup) jobInstances(
+	deploymentName string,
 	jobName string,
 	initialRollout bool,
 ) []JobInstance {
@@ -144,7 +155,7 @@ func (ig *InstanceGroup) jobInstances(
 		//specIndex := names.SpecIndex(azIndex+1, i))
 
 		jobsInstances = append(jobsInstances, JobInstance{
-			Address:   ig.IndexedServiceName(i, -1),
+			Address:   ig.IndexedServiceName(deploymentName, i, -1),
 			AZ:        "",
 			Bootstrap: i == bootstrapIndex,
 			Index:     i,
@@ -157,6 +168,7 @@ func (ig *InstanceGroup) jobInstances(
 }
 
 func (ig *InstanceGroup) jobInstancesAZ(
+	deploymentName string,
 	jobName string,
 	initialRollout bool,
 ) []JobInstance {
@@ -174,7 +186,7 @@ func (ig *InstanceGroup) jobInstancesAZ(
 			index := len(jobsInstances)
 
 			jobsInstances = append(jobsInstances, JobInstance{
-				Address:   ig.IndexedServiceName(i, azIndex),
+				Address:   ig.IndexedServiceName(deploymentName, i, azIndex),
 				AZ:        az,
 				Bootstrap: index == bootstrapIndex,
 				Index:     index,
@@ -287,6 +299,7 @@ type AgentSettings struct {
 	InjectReplicasEnv             *bool                         `json:"injectReplicasEnv,omitempty"`
 	TerminationGracePeriodSeconds *int64                        `json:"terminationGracePeriodSeconds,omitempty" yaml:"terminationGracePeriodSeconds,omitempty"`
 	DNS                           string                        `json:"dns,omitempty"`
+	Schedule                      *ErrandSchedule               `json:"schedule,omitempty"`
 }
 
 // Set overrides labels and annotations with operator-owned metadata.
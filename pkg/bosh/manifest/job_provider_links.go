@@ -11,12 +11,14 @@ import (
 type jobProviderLinks struct {
 	links          map[string]map[string]JobLink
 	instanceGroups map[string]map[string]JobLinkProperties
+	provided       map[string][]ProvidedLink
 }
 
 func newJobProviderLinks() jobProviderLinks {
 	return jobProviderLinks{
 		links:          map[string]map[string]JobLink{},
 		instanceGroups: map[string]map[string]JobLinkProperties{},
+		provided:       map[string][]ProvidedLink{},
 	}
 }
 
@@ -26,6 +28,12 @@ func (jpl jobProviderLinks) lookup(provider *JobSpecProvider) (JobLink, bool) {
 	return link, ok
 }
 
+// providedBy returns true, if the instance group provides the link
+func (jpl jobProviderLinks) providedBy(igName string, provider *JobSpecProvider) bool {
+	_, ok := jpl.instanceGroups[igName][names.QuarksLinkSecretKey(provider.Type, provider.Name)]
+	return ok
+}
+
 // add another job to the lookup maps
 func (jpl jobProviderLinks) add(igName string, job Job, spec JobSpec, jobsInstances []JobInstance, linkAddress string) error {
 	var properties map[string]interface{}
@@ -102,6 +110,7 @@ func (jpl jobProviderLinks) add(igName string, job Job, spec JobSpec, jobsInstan
 			jpl.instanceGroups[igName] = map[string]JobLinkProperties{}
 		}
 		jpl.instanceGroups[igName][names.QuarksLinkSecretKey(linkType, linkName)] = properties
+		jpl.provided[igName] = append(jpl.provided[igName], ProvidedLink{Name: linkName, Type: linkType})
 	}
 	return nil
 }
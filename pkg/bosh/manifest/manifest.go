@@ -479,7 +479,7 @@ func (m *Manifest) ImplicitVariables() ([]string, error) {
 }
 
 // ApplyAddons goes through all defined addons and adds jobs to matched instance groups
-func (m *Manifest) ApplyAddons(log *zap.SugaredLogger) error {
+func (m *Manifest) ApplyAddons(log *zap.SugaredLogger, deployment AddOnDeployment) error {
 	if m.AddOnsApplied {
 		return nil
 	}
@@ -488,11 +488,11 @@ func (m *Manifest) ApplyAddons(log *zap.SugaredLogger) error {
 			continue
 		}
 		for _, ig := range m.InstanceGroups {
-			include, err := m.addOnPlacementMatch(log, "inclusion", ig, addon.Include)
+			include, err := m.addOnPlacementMatch(log, "inclusion", deployment, ig, addon.Include)
 			if err != nil {
 				return errors.Wrap(err, "failed to process include placement matches")
 			}
-			exclude, err := m.addOnPlacementMatch(log, "exclusion", ig, addon.Exclude)
+			exclude, err := m.addOnPlacementMatch(log, "exclusion", deployment, ig, addon.Exclude)
 			if err != nil {
 				return errors.Wrap(err, "failed to process exclude placement matches")
 			}
@@ -531,6 +531,12 @@ func (m *Manifest) PropagateGlobalUpdateBlockToIGs() {
 		if ig.Update == nil {
 			ig.Update = m.Update
 		} else {
+			if ig.Update.Canaries == 0 {
+				ig.Update.Canaries = m.Update.Canaries
+			}
+			if ig.Update.MaxInFlight == "" {
+				ig.Update.MaxInFlight = m.Update.MaxInFlight
+			}
 			if ig.Update.CanaryWatchTime == "" {
 				ig.Update.CanaryWatchTime = m.Update.CanaryWatchTime
 			}
@@ -566,6 +572,46 @@ func (m *Manifest) ListMissingProviders() map[string]bool {
 	return consumeFromNames
 }
 
+// ExternalProvider references the deployment of a link provider, when a
+// link is consumed from another BOSHDeployment
+type ExternalProvider struct {
+	Deployment string
+	// Namespace of the providing deployment, empty for the namespace of the consumer
+	Namespace string
+}
+
+// ListExternalProviders returns the providers of links, which are consumed
+// from another deployment, by their provider name. Next to BOSH's
+// 'deployment' key, the 'namespace' key selects a deployment in another
+// namespace.
+func (m *Manifest) ListExternalProviders() map[string]ExternalProvider {
+	providers := map[string]ExternalProvider{}
+
+	for _, ig := range m.InstanceGroups {
+		for _, job := range ig.Jobs {
+			for linkName, property := range job.Consumes {
+				p, ok := property.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				deployment, _ := p["deployment"].(string)
+				if len(deployment) == 0 {
+					continue
+				}
+
+				providerName, _ := p["from"].(string)
+				if len(providerName) == 0 {
+					providerName = linkName
+				}
+				namespace, _ := p["namespace"].(string)
+				providers[providerName] = ExternalProvider{Deployment: deployment, Namespace: namespace}
+			}
+		}
+	}
+
+	return providers
+}
+
 // listProviderNames returns a map containing provider names from job provides and consumes
 func listProviderNames(providerNames map[string]bool, providerProperties map[string]interface{}, providerKey string) map[string]bool {
 	for _, property := range providerProperties {
@@ -573,6 +619,10 @@ func listProviderNames(providerNames map[string]bool, providerProperties map[str
 		if !ok {
 			continue
 		}
+		// links of other deployments are listed by ListExternalProviders
+		if _, ok := p["deployment"]; ok {
+			continue
+		}
 		nameVal, ok := p[providerKey]
 		if !ok {
 			continue
@@ -1315,7 +1315,7 @@ var _ = Describe("Manifest", func() {
 				})
 
 				It("serializes instancegroup quarks", func() {
-					m1.ApplyUpdateBlock()
+					m1.ApplyUpdateBlock("foo-deployment")
 					text, err := m1.Marshal()
 					Expect(err).NotTo(HaveOccurred())
 					By("loading marshalled manifest again")
@@ -1323,9 +1323,9 @@ var _ = Describe("Manifest", func() {
 					Expect(err).NotTo(HaveOccurred())
 					Expect(manifest.InstanceGroups).To(HaveLen(3))
 					Expect(manifest.InstanceGroups[0].Properties.Quarks.RequiredService).To(BeNil())
-					expectedRequireService := "bpm1"
+					expectedRequireService := "foo-deployment-bpm1"
 					Expect(manifest.InstanceGroups[1].Properties.Quarks.RequiredService).To(Equal(&expectedRequireService))
-					expectedRequireService = "bpm2"
+					expectedRequireService = "foo-deployment-bpm2"
 					Expect(manifest.InstanceGroups[2].Properties.Quarks.RequiredService).To(Equal(&expectedRequireService))
 
 				})
@@ -1403,30 +1403,30 @@ var _ = Describe("Manifest", func() {
 			})
 
 			It("calculates first instance group without dependency", func() {
-				manifest.ApplyUpdateBlock()
+				manifest.ApplyUpdateBlock("foo-deployment")
 				Expect(manifest.InstanceGroups).To(HaveLen(4))
 				Expect(manifest.InstanceGroups[0].Properties.Quarks.RequiredService).To(BeNil())
 			})
 
 			It("respects serial=true on instance group as barrier", func() {
-				manifest.ApplyUpdateBlock()
+				manifest.ApplyUpdateBlock("foo-deployment")
 				Expect(manifest.InstanceGroups).To(HaveLen(4))
-				expectedRequireService := "bpm1"
+				expectedRequireService := "foo-deployment-bpm1"
 				Expect(manifest.InstanceGroups[1].Properties.Quarks.RequiredService).To(Equal(&expectedRequireService))
 				Expect(manifest.InstanceGroups[2].Properties.Quarks.RequiredService).To(Equal(&expectedRequireService))
 			})
 
 			It("respects serial=true to wait for the predecessor", func() {
-				manifest.ApplyUpdateBlock()
+				manifest.ApplyUpdateBlock("foo-deployment")
 				Expect(manifest.InstanceGroups).To(HaveLen(4))
-				expectedRequireService := "bpm3"
+				expectedRequireService := "foo-deployment-bpm3"
 				Expect(manifest.InstanceGroups[3].Properties.Quarks.RequiredService).To(Equal(&expectedRequireService))
 			})
 
 			It("respects update serial in manifest", func() {
 				manifestWithUpdate, err := env.BOSHManifestWithUpdateSerialInManifest()
 				Expect(err).NotTo(HaveOccurred())
-				manifestWithUpdate.ApplyUpdateBlock()
+				manifestWithUpdate.ApplyUpdateBlock("foo-deployment")
 				Expect(manifestWithUpdate.InstanceGroups).To(HaveLen(2))
 				Expect(manifestWithUpdate.InstanceGroups[0].Properties.Quarks.RequiredService).To(BeNil())
 				Expect(manifestWithUpdate.InstanceGroups[1].Properties.Quarks.RequiredService).To(BeNil())
@@ -1435,9 +1435,9 @@ var _ = Describe("Manifest", func() {
 			It("doesn't wait for instance groups without ports", func() {
 				manifestWithUpdate, err := env.BOSHManifestWithUpdateSerialAndWithoutPorts()
 				Expect(err).NotTo(HaveOccurred())
-				manifestWithUpdate.ApplyUpdateBlock()
+				manifestWithUpdate.ApplyUpdateBlock("foo-deployment")
 				Expect(manifestWithUpdate.InstanceGroups).To(HaveLen(3))
-				expectedRequireService := "bpm1"
+				expectedRequireService := "foo-deployment-bpm1"
 				Expect(manifestWithUpdate.InstanceGroups[0].Properties.Quarks.RequiredService).To(BeNil())
 				Expect(manifestWithUpdate.InstanceGroups[1].Properties.Quarks.RequiredService).To(Equal(&expectedRequireService))
 				Expect(manifestWithUpdate.InstanceGroups[2].Properties.Quarks.RequiredService).To(Equal(&expectedRequireService))
@@ -1446,21 +1446,27 @@ var _ = Describe("Manifest", func() {
 			It("propagates global update block correctly", func() {
 				manifest, err = env.BOSHManifestWithGlobalUpdateBlock()
 				Expect(err).NotTo(HaveOccurred())
-				manifest.ApplyUpdateBlock()
+				manifest.ApplyUpdateBlock("foo-deployment")
 				By("propagating if ig has no update block")
 				Expect(*manifest.InstanceGroups[0].Update).To(Equal(Update{
+					Canaries:        2,
+					MaxInFlight:     "25%",
 					CanaryWatchTime: "20000-1200000",
 					UpdateWatchTime: "20000-1200000",
 					Serial:          pointer.BoolPtr(false),
 				}))
 				By("retaining ig's serial configuration")
 				Expect(*manifest.InstanceGroups[1].Update).To(Equal(Update{
+					Canaries:        2,
+					MaxInFlight:     "25%",
 					CanaryWatchTime: "20000-1200000",
 					UpdateWatchTime: "20000-1200000",
 					Serial:          pointer.BoolPtr(true),
 				}))
-				By("retaining ig's canaryWatchTime configuration")
+				By("retaining ig's canaryWatchTime and maxInFlight configuration")
 				Expect(*manifest.InstanceGroups[2].Update).To(Equal(Update{
+					Canaries:        2,
+					MaxInFlight:     "3",
 					CanaryWatchTime: "10000-9900000",
 					UpdateWatchTime: "10000-9900000",
 					Serial:          pointer.BoolPtr(false),
@@ -1468,6 +1474,73 @@ var _ = Describe("Manifest", func() {
 			})
 		})
 
+		Describe("RolloutStages", func() {
+			stageNames := func(stages []InstanceGroups) [][]string {
+				result := [][]string{}
+				for _, stage := range stages {
+					igs := []string{}
+					for _, ig := range stage {
+						igs = append(igs, ig.Name)
+					}
+					result = append(result, igs)
+				}
+				return result
+			}
+
+			It("updates serial instance groups one after another and batches non-serial ones", func() {
+				manifest, err = env.BOSHManifestWithUpdateSerial()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stageNames(manifest.RolloutStages())).To(Equal([][]string{
+					{"bpm1"},
+					{"bpm2", "bpm3"},
+					{"bpm4"},
+				}))
+			})
+
+			It("defaults to serial and skips errands", func() {
+				manifest := &Manifest{InstanceGroups: InstanceGroups{
+					{Name: "a"},
+					{Name: "smoke-tests", LifeCycle: IGTypeErrand},
+					{Name: "b", Update: &Update{Serial: pointer.BoolPtr(false)}},
+					{Name: "c"},
+				}}
+				Expect(stageNames(manifest.RolloutStages())).To(Equal([][]string{
+					{"a"},
+					{"b"},
+					{"c"},
+				}))
+			})
+		})
+
+		Describe("ExtractMaxInFlight", func() {
+			It("defaults to one instance", func() {
+				Expect(ExtractMaxInFlight("", 10)).To(Equal(1))
+			})
+
+			It("uses an absolute value", func() {
+				Expect(ExtractMaxInFlight("3", 10)).To(Equal(3))
+				Expect(ExtractMaxInFlight(" 4 ", 10)).To(Equal(4))
+			})
+
+			It("computes a percentage of the instances", func() {
+				Expect(ExtractMaxInFlight("25%", 8)).To(Equal(2))
+				Expect(ExtractMaxInFlight("50%", 5)).To(Equal(2))
+			})
+
+			It("updates at least one and at most all instances", func() {
+				Expect(ExtractMaxInFlight("10%", 3)).To(Equal(1))
+				Expect(ExtractMaxInFlight("0", 3)).To(Equal(1))
+				Expect(ExtractMaxInFlight("12", 3)).To(Equal(3))
+			})
+
+			It("fails for invalid values", func() {
+				_, err := ExtractMaxInFlight("notANumber", 3)
+				Expect(err).To(HaveOccurred())
+				_, err = ExtractMaxInFlight("-1", 3)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
 		Describe("ListMissingProviders", func() {
 			It("finds missing providers if an ig has multiple jobs", func() {
 				manifest, err := LoadYAML([]byte(`---
@@ -1486,6 +1559,34 @@ instance_groups:
 				Expect(manifest.ListMissingProviders()).To(HaveLen(1))
 			})
 		})
+
+		Describe("ListExternalProviders", func() {
+			It("lists the providers of other deployments", func() {
+				manifest, err := LoadYAML([]byte(`---
+instance_groups:
+- name: diego-cell
+  jobs:
+  - name: loggr-udp-forwarder
+    release: loggregator-agent
+    consumes:
+      cloud_controller:
+        from: cloud_controller
+        deployment: cf
+      doppler:
+        deployment: logging
+        namespace: monitoring
+      nats:
+        from: nats`))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(manifest.ListExternalProviders()).To(Equal(map[string]ExternalProvider{
+					"cloud_controller": {Deployment: "cf"},
+					"doppler":          {Deployment: "logging", Namespace: "monitoring"},
+				}))
+
+				By("not listing them as missing providers")
+				Expect(manifest.ListMissingProviders()).To(Equal(map[string]bool{"nats": false}))
+			})
+		})
 		Describe("ImplicitVariables", func() {
 			It("lists only implicit variables", func() {
 				manifest, err := LoadYAML([]byte(boshmanifest.GoraVars))
@@ -0,0 +1,56 @@
+package manifest
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// RuntimeConfig is a BOSH runtime config. Its releases and addons are merged
+// into the manifests of all deployments.
+type RuntimeConfig struct {
+	Releases []*Release `json:"releases,omitempty"`
+	AddOns   []*AddOn   `json:"addons,omitempty"`
+}
+
+// LoadRuntimeConfigYAML returns a new BOSH runtime config from a yaml representation
+func LoadRuntimeConfigYAML(data []byte) (*RuntimeConfig, error) {
+	rc := &RuntimeConfig{}
+	err := yaml.Unmarshal(data, rc, func(opt *json.Decoder) *json.Decoder {
+		opt.UseNumber()
+		return opt
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal BOSH runtime config %s", string(data))
+	}
+
+	return rc, nil
+}
+
+// MergeRuntimeConfig adds the releases and addons of the runtime config to
+// the manifest. Releases already in the manifest need to have the same version.
+// The addons are placed on the instance groups by ApplyAddons.
+func (m *Manifest) MergeRuntimeConfig(rc *RuntimeConfig) error {
+	versions := map[string]string{}
+	for _, release := range m.Releases {
+		versions[release.Name] = release.Version
+	}
+
+	for _, release := range rc.Releases {
+		version, ok := versions[release.Name]
+		if !ok {
+			m.Releases = append(m.Releases, release)
+			versions[release.Name] = release.Version
+			continue
+		}
+
+		if version != release.Version {
+			return errors.Errorf("runtime config release '%s' has version '%s', which conflicts with version '%s' of the manifest", release.Name, release.Version, version)
+		}
+	}
+
+	m.AddOns = append(m.AddOns, rc.AddOns...)
+
+	return nil
+}
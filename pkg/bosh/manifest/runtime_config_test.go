@@ -0,0 +1,70 @@
+package manifest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+)
+
+var _ = Describe("RuntimeConfig", func() {
+	var (
+		manifest      *Manifest
+		runtimeConfig *RuntimeConfig
+	)
+
+	BeforeEach(func() {
+		var err error
+		manifest, err = LoadYAML([]byte(`---
+releases:
+- name: redis
+  version: "36.15.0"
+addons:
+- name: manifest-addon
+  jobs:
+  - name: addon-job
+    release: redis
+instance_groups:
+- name: redis-slave
+  instances: 1
+  jobs:
+  - name: redis-server
+    release: redis
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		runtimeConfig, err = LoadRuntimeConfigYAML([]byte(`---
+releases:
+- name: redis
+  version: "36.15.0"
+- name: syslog
+  version: "11.7.0"
+addons:
+- name: syslog-forwarder
+  jobs:
+  - name: syslog_forwarder
+    release: syslog
+  include:
+    deployments: [foo-deployment]
+`))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("adds the releases and addons to the manifest", func() {
+		err := manifest.MergeRuntimeConfig(runtimeConfig)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(manifest.Releases).To(HaveLen(2))
+		Expect(manifest.Releases[1].Name).To(Equal("syslog"))
+		Expect(manifest.AddOns).To(HaveLen(2))
+		Expect(manifest.AddOns[1].Name).To(Equal("syslog-forwarder"))
+		Expect(manifest.AddOns[1].Include.Deployments).To(Equal([]string{"foo-deployment"}))
+	})
+
+	It("fails if a release version conflicts with the manifest", func() {
+		runtimeConfig.Releases[0].Version = "37.0.0"
+
+		err := manifest.MergeRuntimeConfig(runtimeConfig)
+		Expect(err).To(MatchError(ContainSubstring("runtime config release 'redis' has version '37.0.0', which conflicts with version '36.15.0'")))
+	})
+})
@@ -0,0 +1,38 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ExtractMaxInFlight computes the number of instances, which are updated in parallel,
+// from an absolute value or a percentage of the instance count.
+// This parses the max_in_flight string used in the BOSH manifest's update config:
+// https://bosh.io/docs/manifest-v2/#update
+func ExtractMaxInFlight(rawMaxInFlight string, instances int) (int, error) {
+	if rawMaxInFlight == "" {
+		return 1, nil
+	}
+
+	var maxInFlight int
+	percentRegex := regexp.MustCompile(`^\s*(\d+)\s*%\s*$`) // https://github.com/cloudfoundry/bosh/blob/914edca5278b994df7d91620c4f55f1c6665f81c/src/bosh-director/lib/bosh/director/deployment_plan/numerical_value_calculator.rb
+	absoluteRegex := regexp.MustCompile(`^\s*(\d+)\s*$`)
+	if matches := percentRegex.FindStringSubmatch(rawMaxInFlight); len(matches) > 0 {
+		percent, _ := strconv.Atoi(matches[1])
+		maxInFlight = percent * instances / 100
+	} else if matches := absoluteRegex.FindStringSubmatch(rawMaxInFlight); len(matches) > 0 {
+		maxInFlight, _ = strconv.Atoi(matches[1])
+	} else {
+		return 0, fmt.Errorf("max in flight string did not match regexp: %s", rawMaxInFlight)
+	}
+
+	// BOSH always updates at least one instance at a time
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	if instances > 0 && maxInFlight > instances {
+		maxInFlight = instances
+	}
+	return maxInFlight, nil
+}
@@ -55,7 +55,7 @@ func NewJobFactory() *JobFactory {
 
 // InstanceGroupManifestJob generates the job to create an instance group manifest, this needs to run on the BOSH release image
 func (f *JobFactory) InstanceGroupManifestJob(namespace string, deploymentName string, manifest bdm.Manifest, linkInfos converter.LinkInfos, initialRollout bool) (*qjv1a1.QuarksJob, error) {
-	dmName := desiredManifestName()
+	dmName := desiredManifestName(deploymentName)
 	ct := containerTemplate{
 		deploymentName: deploymentName,
 		manifestName:   dmName,
@@ -70,7 +70,7 @@ func (f *JobFactory) InstanceGroupManifestJob(namespace string, deploymentName s
 		if ig.Instances != 0 {
 			// Additional secret for BOSH links per instance group
 			containerName := names.Sanitize(ig.Name)
-			linkOutputs[containerName] = boshnames.QuarksLinkSecretName()
+			linkOutputs[containerName] = boshnames.QuarksLinkSecretName(deploymentName)
 
 			// One container per instance group
 			containers = append(containers, ct.newUtilContainer(ig.Name, linkInfos.VolumeMounts()))
@@ -99,8 +99,14 @@ func (f *JobFactory) InstanceGroupManifestJob(namespace string, deploymentName s
 
 // desiredManifestName returns the sanitized, versioned name of the manifest.
 // QuarksJob will always pick the latest version for versioned secrets
-func desiredManifestName() string {
-	return versionedsecretstore.VersionedName(desiredmanifest.Name, 1)
+func desiredManifestName(deploymentName string) string {
+	return versionedsecretstore.VersionedName(desiredmanifest.SecretName(deploymentName), 1)
+}
+
+// instanceGroupManifestJobName returns the name of the QuarksJob, which
+// creates the instance group manifests for a deployment
+func instanceGroupManifestJobName(deploymentName string) string {
+	return names.Sanitize(deploymentName + "-ig")
 }
 
 type containerTemplate struct {
@@ -184,12 +190,12 @@ func (f *JobFactory) releaseImageQJob(namespace string, deploymentName string, d
 	}
 
 	outputMap := qjv1a1.OutputMap{}
-	igPrefix := bdv1.DeploymentSecretTypeInstanceGroupResolvedProperties.Prefix()
-	bpmPrefix := bdv1.DeploymentSecretBPMInformation.Prefix()
+	igPrefix := bdv1.DeploymentSecretTypeInstanceGroupResolvedProperties.Prefix(deploymentName)
+	bpmPrefix := bdv1.DeploymentSecretBPMInformation.Prefix(deploymentName)
 	for _, container := range containers {
 		outputMap[container.Name] = qjv1a1.FilesToSecrets{
 			InstanceGroupOutputFilename: qjv1a1.SecretOptions{
-				// the same as names.InstanceGroupSecretName(container.Name, "")
+				// the same as names.InstanceGroupSecretName(deploymentName, container.Name, "")
 				Name: igPrefix + container.Name,
 				AdditionalSecretLabels: map[string]string{
 					bdv1.LabelEntanglementKey:      "true",
@@ -213,7 +219,7 @@ func (f *JobFactory) releaseImageQJob(namespace string, deploymentName string, d
 	// Construct the "BPM configs" or "data gathering" auto-errand qJob
 	qJob := &qjv1a1.QuarksJob{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "ig",
+			Name:      instanceGroupManifestJobName(deploymentName),
 			Namespace: namespace,
 			Labels: map[string]string{
 				bdv1.LabelDeploymentName: deploymentName,
@@ -234,10 +240,10 @@ func (f *JobFactory) releaseImageQJob(namespace string, deploymentName string, d
 				Spec: batchv1.JobSpec{
 					Template: corev1.PodTemplateSpec{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "ig",
+							Name: instanceGroupManifestJobName(deploymentName),
 							Labels: map[string]string{
 								"delete":                 "pod",
-								bdv1.LabelDeploymentName: "deploymentName",
+								bdv1.LabelDeploymentName: deploymentName,
 							},
 						},
 						Spec: corev1.PodSpec{
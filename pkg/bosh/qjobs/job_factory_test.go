@@ -101,7 +101,7 @@ var _ = Describe("JobFactory", func() {
 					qjv1a1.OutputMap{
 						"redis-slave": qjv1a1.FilesToSecrets{
 							"ig.json": qjv1a1.SecretOptions{
-								Name: "ig-resolved.redis-slave",
+								Name: deploymentName + ".ig-resolved.redis-slave",
 								AdditionalSecretLabels: map[string]string{
 									"quarks.cloudfoundry.org/entanglement": "true",
 									"quarks.cloudfoundry.org/secret-type":  "ig-resolved",
@@ -111,7 +111,7 @@ var _ = Describe("JobFactory", func() {
 								PersistenceMethod:           "",
 							},
 							"bpm.json": qjv1a1.SecretOptions{
-								Name: "bpm.redis-slave",
+								Name: deploymentName + ".bpm.redis-slave",
 								AdditionalSecretLabels: map[string]string{
 									"quarks.cloudfoundry.org/entanglement": "true",
 									"quarks.cloudfoundry.org/secret-type":  "bpm",
@@ -121,7 +121,7 @@ var _ = Describe("JobFactory", func() {
 								PersistenceMethod:           "",
 							},
 							"provides.json": qjv1a1.SecretOptions{
-								Name: "link",
+								Name: "link-" + deploymentName,
 								AdditionalSecretLabels: map[string]string{
 									"quarks.cloudfoundry.org/entanglement": "true",
 								},
@@ -132,7 +132,7 @@ var _ = Describe("JobFactory", func() {
 						},
 						"diego-cell": qjv1a1.FilesToSecrets{
 							"ig.json": qjv1a1.SecretOptions{
-								Name: "ig-resolved.diego-cell",
+								Name: deploymentName + ".ig-resolved.diego-cell",
 								AdditionalSecretLabels: map[string]string{
 									"quarks.cloudfoundry.org/entanglement": "true",
 									"quarks.cloudfoundry.org/secret-type":  "ig-resolved",
@@ -142,7 +142,7 @@ var _ = Describe("JobFactory", func() {
 								PersistenceMethod:           "",
 							},
 							"bpm.json": qjv1a1.SecretOptions{
-								Name: "bpm.diego-cell",
+								Name: deploymentName + ".bpm.diego-cell",
 								AdditionalSecretLabels: map[string]string{
 									"quarks.cloudfoundry.org/entanglement": "true",
 									"quarks.cloudfoundry.org/secret-type":  "bpm",
@@ -152,7 +152,7 @@ var _ = Describe("JobFactory", func() {
 								PersistenceMethod:           "",
 							},
 							"provides.json": qjv1a1.SecretOptions{
-								Name: "link",
+								Name: "link-" + deploymentName,
 								AdditionalSecretLabels: map[string]string{
 									"quarks.cloudfoundry.org/entanglement": "true",
 								},
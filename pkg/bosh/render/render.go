@@ -0,0 +1,402 @@
+// Package render runs the BOSHDeployment pipeline of the operator offline.
+// It produces the k8s resources for a BOSH manifest without a cluster.
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/converter"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/qjobs"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/quarksrestart"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/withops"
+	qsv1a1 "code.cloudfoundry.org/quarks-secret/pkg/kube/apis/quarkssecret/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+	boshtpl "github.com/cloudfoundry/bosh-cli/director/template"
+)
+
+// Options are the inputs for rendering a BOSH deployment
+type Options struct {
+	// DeploymentName is the name of the BOSHDeployment
+	DeploymentName string
+	// Namespace the resources are rendered for
+	Namespace string
+	// Manifest is the BOSH deployment manifest
+	Manifest []byte
+	// Ops are the ops files, which are applied in order
+	Ops [][]byte
+	// Vars are YAML files with values for the manifest variables
+	Vars [][]byte
+	// BaseDir contains the unpacked release jobs in 'jobs-src/<release>/<job>'
+	BaseDir string
+	// DNSServiceIP is used as the nameserver of the pods, if the BOSH DNS addon is used
+	DNSServiceIP string
+	// CorednsServiceAccount is the service account of the BOSH DNS deployment
+	CorednsServiceAccount string
+	// RuntimeConfigs are BOSHRuntimeConfig resources as YAML, which are merged into the manifest
+	RuntimeConfigs [][]byte
+	// CloudConfig is a BOSHCloudConfig resource as YAML, which is applied to the instance groups
+	CloudConfig []byte
+}
+
+// Resources are the k8s resources, which the operator creates for a BOSHDeployment
+type Resources struct {
+	QuarksSecrets      []qsv1a1.QuarksSecret
+	Secrets            []corev1.Secret
+	QuarksJobs         []qjv1a1.QuarksJob
+	QuarksStatefulSets []qstsv1a1.QuarksStatefulSet
+	Services           []corev1.Service
+	ConfigMaps         []corev1.ConfigMap
+	Deployments        []appsv1.Deployment
+}
+
+// Render produces all resources for a BOSH manifest, like the operator does
+// in the cluster. Implicit variables have to be set in the vars files, like
+// their secrets have to exist in the cluster. Explicit variables, which are
+// not found in the vars files, are left unresolved, since they would be
+// generated by QuarksSecrets.
+func Render(log *zap.SugaredLogger, opts Options) (*Resources, error) {
+	vars := boshtpl.StaticVariables{}
+	for _, data := range opts.Vars {
+		v := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal vars file")
+		}
+		for name, value := range v {
+			vars[name] = value
+		}
+	}
+
+	withOpsManifest, err := withOps(log, opts, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Resources{}
+	res.QuarksSecrets, err = converter.NewVariablesConverter().Variables(opts.Namespace, opts.DeploymentName, withOpsManifest.Variables)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate quarks secrets from manifest")
+	}
+
+	qJob, err := qjobs.NewJobFactory().InstanceGroupManifestJob(opts.Namespace, opts.DeploymentName, *withOpsManifest, converter.LinkInfos{}, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build instance group manifest qJob")
+	}
+	res.QuarksJobs = append(res.QuarksJobs, *qJob)
+
+	// The desired manifest has the explicit variables interpolated
+	desiredManifest, err := interpolate(withOpsManifest, vars)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to interpolate explicit variables")
+	}
+
+	serviceIP := ""
+	if boshdns.HasBoshDNSAddOn(*desiredManifest) != -1 {
+		err = res.addDNS(opts, desiredManifest)
+		if err != nil {
+			return nil, err
+		}
+		serviceIP = opts.DNSServiceIP
+	}
+
+	desiredManifestBytes, err := desiredManifest.Marshal()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal desired manifest")
+	}
+
+	var cloudConfig *bdv1.BOSHCloudConfigSpec
+	if len(opts.CloudConfig) > 0 {
+		cc := &bdv1.BOSHCloudConfig{}
+		if err := yaml.Unmarshal(opts.CloudConfig, cc); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal cloud config")
+		}
+		cloudConfig = &cc.Spec
+	}
+
+	kc := bpmconverter.NewConverter(bpmconverter.NewVolumeFactory(), bpmconverter.NewContainerFactory)
+	for _, ig := range desiredManifest.InstanceGroups {
+		if ig.Instances == 0 {
+			continue
+		}
+
+		// The resolver and the converter modify the manifest, so they use fresh copies
+		m, err := bdm.LoadYAML(desiredManifestBytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load desired manifest")
+		}
+		igManifest, bpmInfo, err := resolveInstanceGroup(log, opts, *m, ig)
+		if err != nil {
+			return nil, err
+		}
+		res.Secrets = append(res.Secrets, instanceGroupSecret(opts, ig.Name, igManifest))
+
+		m, err = bdm.LoadYAML(desiredManifestBytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load desired manifest")
+		}
+		instanceGroup, _ := m.InstanceGroups.InstanceGroupByName(ig.Name)
+
+		if cloudConfig != nil {
+			err = bpmconverter.ApplyCloudConfigDisks(cloudConfig, instanceGroup)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to apply cloud config disks to instance group '%s'", ig.Name)
+			}
+		}
+
+		r, err := kc.Resources(*m, opts.Namespace, opts.DeploymentName, boshdns.PodDNS{ServiceIP: serviceIP}, "1", instanceGroup, bpmInfo.Configs, "1")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert instance group '%s'", ig.Name)
+		}
+
+		if cloudConfig != nil {
+			err = bpmconverter.ApplyCloudConfig(cloudConfig, instanceGroup, r)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to apply cloud config to instance group '%s'", ig.Name)
+			}
+		}
+
+		res.QuarksJobs = append(res.QuarksJobs, r.Errands...)
+		res.Services = append(res.Services, r.Services...)
+		for _, qSts := range r.InstanceGroups {
+			annotations := qSts.Spec.Template.Spec.Template.Annotations
+			if len(annotations) == 0 {
+				annotations = map[string]string{}
+			}
+			annotations[quarksrestart.AnnotationRestartOnUpdate] = "true"
+			qSts.Spec.Template.Spec.Template.Annotations = annotations
+			res.QuarksStatefulSets = append(res.QuarksStatefulSets, qSts)
+		}
+	}
+
+	return res, nil
+}
+
+// withOps returns the 'with-ops' manifest, with ops files, implicit variables,
+// runtime configs and addons applied. It uses the resolver of the operator,
+// which reads the inputs from an in-memory client, as if they were the
+// resources of a BOSHDeployment.
+func withOps(log *zap.SugaredLogger, opts Options, vars boshtpl.StaticVariables) (*bdm.Manifest, error) {
+	bdpl := &bdv1.BOSHDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: opts.DeploymentName, Namespace: opts.Namespace},
+		Spec: bdv1.BOSHDeploymentSpec{
+			Manifest: bdv1.ResourceReference{Name: opts.DeploymentName + "-manifest", Type: bdv1.ConfigMapReference},
+		},
+	}
+	objects := []client.Object{bdpl, configMap(opts, bdpl.Spec.Manifest.Name, bdv1.ManifestSpecName, opts.Manifest)}
+
+	for i, ops := range opts.Ops {
+		ref := bdv1.ResourceReference{Name: fmt.Sprintf("%s-ops-%d", opts.DeploymentName, i), Type: bdv1.ConfigMapReference}
+		bdpl.Spec.Ops = append(bdpl.Spec.Ops, ref)
+		objects = append(objects, configMap(opts, ref.Name, bdv1.OpsSpecName, ops))
+	}
+
+	for i, data := range opts.RuntimeConfigs {
+		runtimeConfig := &bdv1.BOSHRuntimeConfig{}
+		if err := yaml.Unmarshal(data, runtimeConfig); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal runtime config")
+		}
+		if runtimeConfig.Name == "" {
+			return nil, errors.Errorf("runtime config %d has no name", i)
+		}
+		objects = append(objects, runtimeConfig)
+	}
+
+	secrets, err := variableSecrets(opts, vars)
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, secrets...)
+
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	if err := bdv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(objects...).Build()
+
+	resolver := withops.NewResolver(c, func() withops.Interpolator { return withops.NewInterpolator() })
+	m, _, err := resolver.Manifest(ctxlog.NewParentContext(log), bdpl, opts.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve manifest")
+	}
+	return m, nil
+}
+
+func configMap(opts Options, name string, key string, data []byte) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: opts.Namespace},
+		Data:       map[string]string{key: string(data)},
+	}
+}
+
+// variableSecrets returns the secrets of the implicit variables with the
+// values of the vars files. The values are stored as JSON, the keys of map
+// values are used by variables with a key, e.g. '((name/key))'.
+func variableSecrets(opts Options, vars boshtpl.StaticVariables) ([]client.Object, error) {
+	secrets := []client.Object{}
+	for name, value := range vars {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        names.SecretVariableName(opts.DeploymentName, name),
+				Namespace:   opts.Namespace,
+				Annotations: map[string]string{bdv1.AnnotationJSONValue: "true"},
+			},
+			Data: map[string][]byte{},
+		}
+
+		if values, ok := value.(map[string]interface{}); ok {
+			for key, v := range values {
+				data, err := json.Marshal(v)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to marshal key '%s' of variable '%s'", key, name)
+				}
+				secret.Data[key] = data
+			}
+		}
+		if _, ok := secret.Data[bdv1.ImplicitVariableKeyName]; !ok {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to marshal variable '%s'", name)
+			}
+			secret.Data[bdv1.ImplicitVariableKeyName] = data
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// interpolate replaces the variables, which have a value, in the manifest
+func interpolate(m *bdm.Manifest, vars boshtpl.StaticVariables) (*bdm.Manifest, error) {
+	data, err := m.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = withops.InterpolateExplicitVariables(data, []boshtpl.Variables{vars}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return bdm.LoadYAML(data)
+}
+
+// resolveInstanceGroup renders the instance group manifest and the BPM
+// information of an instance group, like the instance group job does. The
+// pre-render ops of the instance group are applied to both.
+func resolveInstanceGroup(log *zap.SugaredLogger, opts Options, m bdm.Manifest, ig *bdm.InstanceGroup) ([]byte, *bdm.BPMInfo, error) {
+	igr, err := bdm.NewInstanceGroupResolver(log, afero.NewOsFs(), opts.BaseDir, opts.DeploymentName, m, ig.Name)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create resolver for instance group '%s'", ig.Name)
+	}
+
+	err = igr.Resolve(true)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to resolve instance group '%s'", ig.Name)
+	}
+
+	igManifest, err := igr.Manifest()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to render manifest for instance group '%s'", ig.Name)
+	}
+	igBytes, err := igManifest.Marshal()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to marshal manifest for instance group '%s'", ig.Name)
+	}
+
+	bpmInfo, err := igr.BPMInfo()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to render BPM information for instance group '%s'", ig.Name)
+	}
+
+	preRenderOps := ig.Env.AgentEnvBoshConfig.Agent.Settings.PreRenderOps
+	if preRenderOps == nil {
+		return igBytes, &bpmInfo, nil
+	}
+
+	igBytes, err = withops.InterpolateOps(preRenderOps.InstanceGroup, igBytes)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to interpolate pre render ops for instance group '%s'", ig.Name)
+	}
+
+	bpmBytes, err := yaml.Marshal(bpmInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	bpmBytes, err = withops.InterpolateOps(preRenderOps.BPM, bpmBytes)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to interpolate bpm pre render ops for instance group '%s'", ig.Name)
+	}
+
+	result := &bdm.BPMInfo{}
+	err = yaml.Unmarshal(bpmBytes, result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return igBytes, result, nil
+}
+
+// instanceGroupSecret returns the first version of the ig-resolved secret,
+// which the instance group job writes for the pods of the instance group
+func instanceGroupSecret(opts Options, instanceGroupName string, igManifest []byte) corev1.Secret {
+	return corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.InstanceGroupSecretName(opts.DeploymentName, instanceGroupName, "1"),
+			Namespace: opts.Namespace,
+			Labels: map[string]string{
+				bdv1.LabelDeploymentName:             opts.DeploymentName,
+				bdv1.LabelDeploymentSecretType:       bdv1.DeploymentSecretTypeInstanceGroupResolvedProperties.String(),
+				bdv1.LabelEntanglementKey:            "true",
+				qjv1a1.LabelRemoteID:                 instanceGroupName,
+				versionedsecretstore.LabelSecretKind: versionedsecretstore.VersionSecretKind,
+				versionedsecretstore.LabelVersion:    "1",
+			},
+		},
+		Data: map[string][]byte{"properties.yaml": igManifest},
+	}
+}
+
+// addDNS adds the resources of the BOSH DNS server
+func (res *Resources) addDNS(opts Options, m *bdm.Manifest) error {
+	dns, err := boshdns.New(opts.DeploymentName, *m)
+	if err != nil {
+		return err
+	}
+
+	boshDNS, ok := dns.(*boshdns.BoshDomainNameService)
+	if !ok {
+		return nil
+	}
+
+	configMap, err := boshDNS.CorefileConfigMap(opts.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to render the BOSH DNS corefile")
+	}
+	res.ConfigMaps = append(res.ConfigMaps, configMap)
+	res.Deployments = append(res.Deployments, boshDNS.Deployment(opts.Namespace, opts.CorednsServiceAccount))
+	res.Services = append(res.Services, boshDNS.Service(opts.Namespace))
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package render_test
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/render"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/quarksrestart"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Render", func() {
+	var (
+		log  *zap.SugaredLogger
+		opts render.Options
+	)
+
+	BeforeEach(func() {
+		_, log = helper.NewTestLogger()
+
+		opts = render.Options{
+			DeploymentName: "foo",
+			Namespace:      "default",
+			BaseDir:        filepath.Join("..", "..", "..", "testing", "assets"),
+			Manifest: []byte(`---
+name: foo
+releases:
+- name: cflinuxfs3
+  version: 0.62.0
+  url: docker.io/cfcontainerization
+  stemcell:
+    os: opensuse-42.3
+    version: 36.g03b4653-30.80-7.0.0_316.gcf9fe4a7
+instance_groups:
+- name: setup
+  instances: 1
+  jobs:
+  - name: cflinuxfs3-rootfs-setup
+    release: cflinuxfs3
+    properties:
+      cflinuxfs3-rootfs:
+        trusted_certs: ((trusted_certs))
+      quarks:
+        ports:
+        - name: setup
+          protocol: TCP
+          internal: 8080
+        bpm:
+          processes:
+          - name: setup
+            executable: /bin/sh
+            args: ["-c", "sleep 3600"]
+variables:
+- name: password
+  type: password
+`),
+			Ops: [][]byte{[]byte(`
+- type: replace
+  path: /instance_groups/name=setup/instances
+  value: 2
+`)},
+			Vars: [][]byte{[]byte(`trusted_certs: secret-cert`)},
+		}
+	})
+
+	It("renders all resources of the deployment", func() {
+		resources, err := render.Render(log, opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resources.QuarksSecrets).To(HaveLen(1))
+		Expect(resources.QuarksSecrets[0].Name).To(Equal("foo.var-password"))
+
+		Expect(resources.QuarksJobs).To(HaveLen(1))
+		Expect(resources.QuarksJobs[0].Name).To(Equal("foo-ig"))
+
+		Expect(resources.Secrets).To(HaveLen(1))
+		Expect(resources.Secrets[0].Name).To(Equal("foo.ig-resolved.setup-v1"))
+		Expect(string(resources.Secrets[0].Data["properties.yaml"])).To(ContainSubstring("trusted_certs: secret-cert"))
+
+		Expect(resources.QuarksStatefulSets).To(HaveLen(1))
+		qSts := resources.QuarksStatefulSets[0]
+		Expect(qSts.Name).To(Equal("foo-setup"))
+		Expect(*qSts.Spec.Template.Spec.Replicas).To(Equal(int32(2)))
+		Expect(qSts.Spec.Template.Spec.Template.Annotations).To(HaveKeyWithValue(quarksrestart.AnnotationRestartOnUpdate, "true"))
+
+		Expect(resources.Services).ToNot(BeEmpty())
+		Expect(resources.Services[0].Name).To(HavePrefix("foo-setup"))
+
+		Expect(resources.ConfigMaps).To(BeEmpty())
+		Expect(resources.Deployments).To(BeEmpty())
+	})
+
+	It("fails for implicit variables, which are not set in the vars files", func() {
+		opts.Vars = nil
+
+		_, err := render.Render(log, opts)
+		Expect(err).To(MatchError(ContainSubstring("failed to get secret 'default/foo.var-trusted-certs'")))
+	})
+
+	It("merges the runtime configs", func() {
+		opts.RuntimeConfigs = [][]byte{[]byte(`
+metadata:
+  name: releases
+spec:
+  manifest: |
+    releases:
+    - name: cflinuxfs3
+      version: 0.1.0
+`)}
+
+		_, err := render.Render(log, opts)
+		Expect(err).To(MatchError(ContainSubstring("runtime config release 'cflinuxfs3' has version '0.1.0'")))
+	})
+
+	It("applies the cloud config", func() {
+		opts.Ops = append(opts.Ops, []byte(`
+- type: replace
+  path: /instance_groups/name=setup/vm_type?
+  value: small
+`))
+		opts.CloudConfig = []byte(`
+metadata:
+  name: cloud
+spec:
+  vmTypes:
+  - name: small
+    nodeSelector:
+      size: small
+`)
+
+		resources, err := render.Render(log, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resources.QuarksStatefulSets[0].Spec.Template.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue("size", "small"))
+	})
+
+	It("applies the pre-render ops to the instance group secret", func() {
+		opts.Ops = append(opts.Ops, []byte(`
+- type: replace
+  path: /instance_groups/name=setup/env?/bosh/agent/settings/preRenderOps/instanceGroup
+  value:
+  - type: replace
+    path: /instance_groups/name=setup/jobs/name=cflinuxfs3-rootfs-setup/properties/cflinuxfs3-rootfs/trusted_certs
+    value: patched-cert
+`))
+
+		resources, err := render.Render(log, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(resources.Secrets[0].Data["properties.yaml"])).To(ContainSubstring("trusted_certs: patched-cert"))
+	})
+
+	It("fails for invalid ops files", func() {
+		opts.Ops = [][]byte{[]byte(`- type: remove`)}
+
+		_, err := render.Render(log, opts)
+		Expect(err).To(HaveOccurred())
+	})
+})
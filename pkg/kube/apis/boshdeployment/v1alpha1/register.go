@@ -20,6 +20,21 @@ const (
 	BOSHDeploymentResourceKind = "BOSHDeployment"
 	// BOSHDeploymentResourcePlural is the plural name of BOSHDeployment
 	BOSHDeploymentResourcePlural = "boshdeployments"
+
+	// BOSHCloudConfigResourceKind is the kind name of BOSHCloudConfig
+	BOSHCloudConfigResourceKind = "BOSHCloudConfig"
+	// BOSHCloudConfigResourcePlural is the plural name of BOSHCloudConfig
+	BOSHCloudConfigResourcePlural = "boshcloudconfigs"
+
+	// BOSHRuntimeConfigResourceKind is the kind name of BOSHRuntimeConfig
+	BOSHRuntimeConfigResourceKind = "BOSHRuntimeConfig"
+	// BOSHRuntimeConfigResourcePlural is the plural name of BOSHRuntimeConfig
+	BOSHRuntimeConfigResourcePlural = "boshruntimeconfigs"
+
+	// QuarksLinkResourceKind is the kind name of QuarksLink
+	QuarksLinkResourceKind = "QuarksLink"
+	// QuarksLinkResourcePlural is the plural name of QuarksLink
+	QuarksLinkResourcePlural = "quarkslinks"
 )
 
 var (
@@ -59,8 +74,27 @@ var (
 										{
 											Raw: []byte(`"url"`),
 										},
+										{
+											Raw: []byte(`"git"`),
+										},
 									},
 								},
+								"secretRef": {
+									Type: "string",
+								},
+								"caBundle": {
+									Type: "string",
+								},
+								"sha256": {
+									Type:    "string",
+									Pattern: "^[a-fA-F0-9]{64}$",
+								},
+								"ref": {
+									Type: "string",
+								},
+								"path": {
+									Type: "string",
+								},
 							},
 							Required: []string{
 								"type",
@@ -89,8 +123,27 @@ var (
 												{
 													Raw: []byte(`"url"`),
 												},
+												{
+													Raw: []byte(`"git"`),
+												},
 											},
 										},
+										"secretRef": {
+											Type: "string",
+										},
+										"caBundle": {
+											Type: "string",
+										},
+										"sha256": {
+											Type:    "string",
+											Pattern: "^[a-fA-F0-9]{64}$",
+										},
+										"ref": {
+											Type: "string",
+										},
+										"path": {
+											Type: "string",
+										},
 									},
 									Required: []string{
 										"type",
@@ -99,6 +152,42 @@ var (
 								},
 							},
 						},
+						"cloudConfig": {
+							Type: "string",
+						},
+						"networkPolicies": {
+							Type: "boolean",
+						},
+						"dns": {
+							Type: "string",
+							Enum: []extv1.JSON{
+								{
+									Raw: []byte(`"coredns"`),
+								},
+								{
+									Raw: []byte(`"native"`),
+								},
+							},
+						},
+						"linkConsumers": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"namespace": {
+											Type: "string",
+										},
+										"deployment": {
+											Type: "string",
+										},
+									},
+									Required: []string{
+										"deployment",
+									},
+								},
+							},
+						},
 						"vars": {
 							Type: "array",
 							Items: &extv1.JSONSchemaPropsOrArray{
@@ -155,6 +244,155 @@ var (
 							Type:     "string",
 							Nullable: true,
 						},
+						"rollbackVersion": {
+							Type: "integer",
+						},
+						"rollouts": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"name": {
+											Type: "string",
+										},
+										"state": {
+											Type: "string",
+										},
+										"canaries": {
+											Type: "integer",
+										},
+										"canariesReady": {
+											Type: "integer",
+										},
+										"replicas": {
+											Type: "integer",
+										},
+										"updatedReplicas": {
+											Type: "integer",
+										},
+									},
+								},
+							},
+						},
+						"observedGeneration": {
+							Type: "integer",
+						},
+						"conditions": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"type": {
+											Type: "string",
+										},
+										"status": {
+											Type: "string",
+										},
+										"observedGeneration": {
+											Type: "integer",
+										},
+										"lastTransitionTime": {
+											Type:   "string",
+											Format: "date-time",
+										},
+										"reason": {
+											Type: "string",
+										},
+										"message": {
+											Type: "string",
+										},
+									},
+									Required: []string{
+										"type",
+										"status",
+										"lastTransitionTime",
+										"reason",
+										"message",
+									},
+								},
+							},
+						},
+						"instanceGroups": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"name": {
+											Type: "string",
+										},
+										"replicas": {
+											Type: "integer",
+										},
+										"readyReplicas": {
+											Type: "integer",
+										},
+										"igResolvedVersion": {
+											Type: "string",
+										},
+										"bpmVersion": {
+											Type: "string",
+										},
+										"completed": {
+											Type: "boolean",
+										},
+										"lastError": {
+											Type: "string",
+										},
+										"lastErrorTimestamp": {
+											Type:     "string",
+											Nullable: true,
+										},
+									},
+								},
+							},
+						},
+						"gitSources": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"url": {
+											Type: "string",
+										},
+										"ref": {
+											Type: "string",
+										},
+										"commit": {
+											Type: "string",
+										},
+									},
+								},
+							},
+						},
+						"runtimeConfigs": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"name": {
+											Type: "string",
+										},
+										"generation": {
+											Type: "integer",
+										},
+									},
+								},
+							},
+						},
+						"errands": {
+							Type: "array",
+							Items: &extv1.JSONSchemaPropsOrArray{
+								Schema: &extv1.JSONSchemaProps{
+									Type:       "object",
+									Properties: errandStatusProperties(),
+								},
+							},
+						},
 					},
 				},
 			},
@@ -192,6 +430,217 @@ var (
 	// BOSHDeploymentResourceName is the resource name of BOSHDeployment
 	BOSHDeploymentResourceName = fmt.Sprintf("%s.%s", BOSHDeploymentResourcePlural, apis.GroupName)
 
+	// BOSHCloudConfigResourceShortNames is the short names of BOSHCloudConfig
+	BOSHCloudConfigResourceShortNames = []string{"bcc", "bccs"}
+
+	// BOSHCloudConfigValidation is the validation method for BOSHCloudConfig
+	BOSHCloudConfigValidation = extv1.CustomResourceValidation{
+		OpenAPIV3Schema: &extv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]extv1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"vmTypes": namedItems(map[string]extv1.JSONSchemaProps{
+							"resources":    preservedObject(),
+							"nodeSelector": stringMap(),
+						}),
+						"vmExtensions": namedItems(map[string]extv1.JSONSchemaProps{
+							"tolerations": {
+								Type: "array",
+								Items: &extv1.JSONSchemaPropsOrArray{
+									Schema: &extv1.JSONSchemaProps{
+										Type:                   "object",
+										XPreserveUnknownFields: pointers.Bool(true),
+									},
+								},
+							},
+							"labels":      stringMap(),
+							"annotations": stringMap(),
+							"affinity":    preservedObject(),
+						}),
+						"diskTypes": namedItems(map[string]extv1.JSONSchemaProps{
+							"storageClassName": {
+								Type: "string",
+							},
+							"diskSize": {
+								Type: "integer",
+							},
+						}),
+						"azs": namedItems(map[string]extv1.JSONSchemaProps{
+							"zone": {
+								Type: "string",
+							},
+						}),
+						"zoneNodeLabel": {
+							Type: "string",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// BOSHCloudConfigResourceName is the resource name of BOSHCloudConfig
+	BOSHCloudConfigResourceName = fmt.Sprintf("%s.%s", BOSHCloudConfigResourcePlural, apis.GroupName)
+
+	// BOSHRuntimeConfigResourceShortNames is the short names of BOSHRuntimeConfig
+	BOSHRuntimeConfigResourceShortNames = []string{"brc", "brcs"}
+
+	// BOSHRuntimeConfigValidation is the validation method for BOSHRuntimeConfig
+	BOSHRuntimeConfigValidation = extv1.CustomResourceValidation{
+		OpenAPIV3Schema: &extv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]extv1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"manifest": {
+							Type:      "string",
+							MinLength: pointers.Int64(1),
+						},
+					},
+					Required: []string{
+						"manifest",
+					},
+				},
+			},
+		},
+	}
+
+	// BOSHRuntimeConfigResourceName is the resource name of BOSHRuntimeConfig
+	BOSHRuntimeConfigResourceName = fmt.Sprintf("%s.%s", BOSHRuntimeConfigResourcePlural, apis.GroupName)
+
+	// QuarksLinkResourceShortNames is the short names of QuarksLink
+	QuarksLinkResourceShortNames = []string{"qlink", "qlinks"}
+
+	// QuarksLinkValidation is the validation method for QuarksLink
+	QuarksLinkValidation = extv1.CustomResourceValidation{
+		OpenAPIV3Schema: &extv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]extv1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"deployment": {
+							Type:      "string",
+							MinLength: pointers.Int64(1),
+						},
+						"provider": {
+							Type: "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"name": {
+									Type:      "string",
+									MinLength: pointers.Int64(1),
+								},
+								"type": {
+									Type:      "string",
+									MinLength: pointers.Int64(1),
+								},
+								"secret": {
+									Type:      "string",
+									MinLength: pointers.Int64(1),
+								},
+								"selector": labelSelector(),
+							},
+							Required: []string{
+								"name",
+								"type",
+								"secret",
+								"selector",
+							},
+						},
+						"consumer": {
+							Type: "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"selector": labelSelector(),
+								"links": {
+									Type:     "array",
+									MinItems: pointers.Int64(1),
+									Items: &extv1.JSONSchemaPropsOrArray{
+										Schema: &extv1.JSONSchemaProps{
+											Type: "object",
+											Properties: map[string]extv1.JSONSchemaProps{
+												"name": {
+													Type:      "string",
+													MinLength: pointers.Int64(1),
+												},
+												"type": {
+													Type:      "string",
+													MinLength: pointers.Int64(1),
+												},
+											},
+											Required: []string{
+												"name",
+												"type",
+											},
+										},
+									},
+								},
+							},
+							Required: []string{
+								"selector",
+								"links",
+							},
+						},
+					},
+					Required: []string{
+						"deployment",
+					},
+					OneOf: []extv1.JSONSchemaProps{
+						{Required: []string{"provider"}},
+						{Required: []string{"consumer"}},
+					},
+				},
+				"status": {
+					Type: "object",
+					Properties: map[string]extv1.JSONSchemaProps{
+						"resolved": {
+							Type: "boolean",
+						},
+						"message": {
+							Type: "string",
+						},
+						"address": {
+							Type: "string",
+						},
+						"addresses":    stringList(),
+						"instances":    stringList(),
+						"propertyKeys": stringList(),
+						"observedGeneration": {
+							Type: "integer",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// QuarksLinkAdditionalPrinterColumns are used by `kubectl get`
+	QuarksLinkAdditionalPrinterColumns = []extv1.CustomResourceColumnDefinition{
+		{
+			Name:     "deployment",
+			Type:     "string",
+			Priority: 0,
+			JSONPath: ".spec.deployment",
+		},
+		{
+			Name:     "resolved",
+			Type:     "boolean",
+			Priority: 0,
+			JSONPath: ".status.resolved",
+		},
+		{
+			Name:     "address",
+			Type:     "string",
+			Priority: 10,
+			JSONPath: ".status.address",
+		},
+	}
+
+	// QuarksLinkResourceName is the resource name of QuarksLink
+	QuarksLinkResourceName = fmt.Sprintf("%s.%s", QuarksLinkResourcePlural, apis.GroupName)
+
 	// SchemeGroupVersion is group version used to register these objects
 	SchemeGroupVersion = schema.GroupVersion{Group: apis.GroupName, Version: "v1alpha1"}
 )
@@ -211,8 +660,130 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&BOSHDeployment{},
 		&BOSHDeploymentList{},
+		&BOSHCloudConfig{},
+		&BOSHCloudConfigList{},
+		&BOSHRuntimeConfig{},
+		&BOSHRuntimeConfigList{},
+		&QuarksLink{},
+		&QuarksLinkList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
 }
+
+// namedItems is the schema of a list of objects, which are referenced by their name
+func namedItems(properties map[string]extv1.JSONSchemaProps) extv1.JSONSchemaProps {
+	properties["name"] = extv1.JSONSchemaProps{
+		Type:      "string",
+		MinLength: pointers.Int64(1),
+	}
+	return extv1.JSONSchemaProps{
+		Type: "array",
+		Items: &extv1.JSONSchemaPropsOrArray{
+			Schema: &extv1.JSONSchemaProps{
+				Type:       "object",
+				Properties: properties,
+				Required:   []string{"name"},
+			},
+		},
+	}
+}
+
+// labelSelector is the schema of a non-empty map of pod labels
+func labelSelector() extv1.JSONSchemaProps {
+	return extv1.JSONSchemaProps{
+		Type:          "object",
+		MinProperties: pointers.Int64(1),
+		AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+			Allows: true,
+			Schema: &extv1.JSONSchemaProps{
+				Type: "string",
+			},
+		},
+	}
+}
+
+// stringList is the schema of a list of strings
+func stringList() extv1.JSONSchemaProps {
+	return extv1.JSONSchemaProps{
+		Type: "array",
+		Items: &extv1.JSONSchemaPropsOrArray{
+			Schema: &extv1.JSONSchemaProps{
+				Type: "string",
+			},
+		},
+	}
+}
+
+// errandRunProperties is the schema of the result of an errand run
+func errandRunProperties() map[string]extv1.JSONSchemaProps {
+	return map[string]extv1.JSONSchemaProps{
+		"state": {
+			Type: "string",
+		},
+		"message": {
+			Type: "string",
+		},
+		"requestTime": {
+			Type:     "string",
+			Nullable: true,
+		},
+		"pod": {
+			Type: "string",
+		},
+		"exitCode": {
+			Type: "integer",
+		},
+		"startTime": {
+			Type:     "string",
+			Nullable: true,
+		},
+		"endTime": {
+			Type:     "string",
+			Nullable: true,
+		},
+		"logsConfigMap": {
+			Type: "string",
+		},
+	}
+}
+
+// errandStatusProperties is the schema of the last run of an errand and its history
+func errandStatusProperties() map[string]extv1.JSONSchemaProps {
+	properties := errandRunProperties()
+	properties["name"] = extv1.JSONSchemaProps{
+		Type: "string",
+	}
+	properties["lastScheduleTime"] = extv1.JSONSchemaProps{
+		Type:     "string",
+		Nullable: true,
+	}
+	properties["history"] = extv1.JSONSchemaProps{
+		Type: "array",
+		Items: &extv1.JSONSchemaPropsOrArray{
+			Schema: &extv1.JSONSchemaProps{
+				Type:       "object",
+				Properties: errandRunProperties(),
+			},
+		},
+	}
+	return properties
+}
+
+// preservedObject is the schema of an embedded Kubernetes type, which is validated by the API server of the pods
+func preservedObject() extv1.JSONSchemaProps {
+	return extv1.JSONSchemaProps{
+		Type:                   "object",
+		XPreserveUnknownFields: pointers.Bool(true),
+	}
+}
+
+func stringMap() extv1.JSONSchemaProps {
+	return extv1.JSONSchemaProps{
+		Type: "object",
+		AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+			Schema: &extv1.JSONSchemaProps{Type: "string"},
+		},
+	}
+}
@@ -3,6 +3,7 @@ package v1alpha1
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/apis"
@@ -23,6 +24,11 @@ const (
 	SecretReference ReferenceType = "secret"
 	// URLReference represents URL reference
 	URLReference ReferenceType = "url"
+	// GitReference represents a file in a git repository
+	GitReference ReferenceType = "git"
+
+	// CredentialsHeaderPrefix marks the keys of a credentials secret, which are sent as HTTP headers
+	CredentialsHeaderPrefix string = "header-"
 
 	ManifestSpecName        string = "manifest"
 	OpsSpecName             string = "ops"
@@ -56,9 +62,9 @@ func (s DeploymentSecretType) String() string {
 }
 
 // Prefix returns the prefix used for our k8s secrets:
-// `<secretType>.`
-func (s DeploymentSecretType) Prefix() string {
-	return s.String() + "."
+// `<deployment-name>.<secretType>.`
+func (s DeploymentSecretType) Prefix(deploymentName string) string {
+	return deploymentName + "." + s.String() + "."
 }
 
 var (
@@ -76,10 +82,72 @@ var (
 	AnnotationLinkProvidesKey = fmt.Sprintf("%s/provides", apis.GroupName)
 	// AnnotationLinkProviderName is the annotation key used on services to identify the link it provides addresses for
 	AnnotationLinkProviderName = fmt.Sprintf("%s/link-provider-name", apis.GroupName)
+	// AnnotationLinkProviderDeployment is the annotation key used on copied link secrets to identify the '<namespace>/<deployment>' providing the link
+	AnnotationLinkProviderDeployment = fmt.Sprintf("%s/link-provider-deployment", apis.GroupName)
 	// AnnotationJSONValue is the annotation key used to indicate the implicit variable secret has a JSON value
 	AnnotationJSONValue = fmt.Sprintf("%s/json-value", apis.GroupName)
 	// LabelEntanglementKey to identify a quarks link
 	LabelEntanglementKey = fmt.Sprintf("%s/entanglement", apis.GroupName)
+	// LabelLinkConsumerPrefix is the prefix of the labels on entangled pods, which name the links they consume
+	LabelLinkConsumerPrefix = fmt.Sprintf("link.%s", apis.GroupName)
+	// AnnotationCanaries is the number of canary instances for the rollout of an instance group's statefulset
+	AnnotationCanaries = fmt.Sprintf("%s/canaries", apis.GroupName)
+	// AnnotationMaxInFlight is the number of instances of an instance group's statefulset which are updated in parallel
+	AnnotationMaxInFlight = fmt.Sprintf("%s/max-in-flight", apis.GroupName)
+	// AnnotationRolloutState is the state of the canary rollout of an instance group's statefulset, as driven by the rollout controller
+	AnnotationRolloutState = fmt.Sprintf("%s/rollout-state", apis.GroupName)
+	// AnnotationRollbackTo is the annotation key on a BOSHDeployment to request the rollback to an earlier desired manifest version
+	AnnotationRollbackTo = fmt.Sprintf("%s/rollback-to", apis.GroupName)
+	// AnnotationDryRun is the annotation key on a BOSHDeployment to preview changes, instead of applying them
+	AnnotationDryRun = fmt.Sprintf("%s/dry-run", apis.GroupName)
+	// AnnotationIGResolvedVersion is the annotation key on a QuarksStatefulSet for the version of the ig-resolved secret it was created from
+	AnnotationIGResolvedVersion = fmt.Sprintf("%s/ig-resolved-version", apis.GroupName)
+	// AnnotationBPMVersion is the annotation key on a QuarksStatefulSet for the version of the bpm secret it was created from
+	AnnotationBPMVersion = fmt.Sprintf("%s/bpm-version", apis.GroupName)
+	// AnnotationRunErrand is the annotation key on a BOSHDeployment to run the named errand once
+	AnnotationRunErrand = fmt.Sprintf("%s/run-errand", apis.GroupName)
+	// AnnotationErrandSchedule is the annotation key on a QuarksJob for the cron schedule of the errand
+	AnnotationErrandSchedule = fmt.Sprintf("%s/errand-schedule", apis.GroupName)
+	// AnnotationErrandConcurrencyPolicy is the annotation key on a QuarksJob for the behaviour of a scheduled run, while the errand is still running
+	AnnotationErrandConcurrencyPolicy = fmt.Sprintf("%s/errand-concurrency-policy", apis.GroupName)
+	// AnnotationErrandStartingDeadline is the annotation key on a QuarksJob for the seconds a scheduled run may start late, before it is skipped
+	AnnotationErrandStartingDeadline = fmt.Sprintf("%s/errand-starting-deadline-seconds", apis.GroupName)
+	// AnnotationErrandHistoryLimit is the annotation key on a QuarksJob for the number of earlier errand results kept in the status
+	AnnotationErrandHistoryLimit = fmt.Sprintf("%s/errand-history-limit", apis.GroupName)
+	// AnnotationMigratedFrom is the annotation key on resources, which were moved to another instance group by migrated_from, for the name of their original resource
+	AnnotationMigratedFrom = fmt.Sprintf("%s/migrated-from", apis.GroupName)
+	// AnnotationMigratedReclaimPolicy is the annotation key on migrated persistent volume claims for the reclaim policy of their volume, which is retained during the migration
+	AnnotationMigratedReclaimPolicy = fmt.Sprintf("%s/migrated-reclaim-policy", apis.GroupName)
+)
+
+// States of an errand run
+const (
+	// ErrandStateRequested is set, when the errand was triggered
+	ErrandStateRequested = "Requested"
+	// ErrandStateSucceeded is set, when all errand containers exited with zero
+	ErrandStateSucceeded = "Succeeded"
+	// ErrandStateFailed is set, when the errand could not be triggered or a container failed
+	ErrandStateFailed = "Failed"
+	// ErrandStateSkipped is set, when a scheduled run missed its starting deadline
+	ErrandStateSkipped = "Skipped"
+)
+
+// Condition types of a BOSHDeployment
+const (
+	// ConditionManifestResolved is true, if the with-ops and the desired manifest were created
+	ConditionManifestResolved = "ManifestResolved"
+	// ConditionVariablesGenerated is true, if the QuarksSecrets for the explicit variables were created and interpolated
+	ConditionVariablesGenerated = "VariablesGenerated"
+	// ConditionInstanceGroupsRendered is true, if the k8s resources of the instance groups were created
+	ConditionInstanceGroupsRendered = "InstanceGroupsRendered"
+	// ConditionInstanceGroupsReady is true, if all jobs completed and all instance groups are ready
+	ConditionInstanceGroupsReady = "InstanceGroupsReady"
+	// ConditionPersistentDisksResized is true, if the persistent volume claims of an instance group were expanded to a changed persistent_disk size
+	ConditionPersistentDisksResized = "PersistentDisksResized"
+	// ConditionDegraded is true, if a step of the deployment failed
+	ConditionDegraded = "Degraded"
+	// ConditionReady is true, if all other conditions are true and the deployment is not degraded
+	ConditionReady = "Ready"
 )
 
 // BOSHDeploymentSpec defines the desired state of BOSHDeployment
@@ -87,6 +155,37 @@ type BOSHDeploymentSpec struct {
 	Manifest ResourceReference   `json:"manifest"`
 	Ops      []ResourceReference `json:"ops,omitempty"`
 	Vars     []VarReference      `json:"vars,omitempty"`
+	// CloudConfig is the name of a BOSHCloudConfig in the same namespace, which maps the
+	// vm_types, vm_extensions, disk_types and azs of the instance groups to Kubernetes
+	CloudConfig string `json:"cloudConfig,omitempty"`
+	// NetworkPolicies enables a network policy per instance group, which only allows ingress on
+	// the declared quarks ports from instance groups and entangled pods consuming its links
+	NetworkPolicies bool `json:"networkPolicies,omitempty"`
+	// LinkConsumers is the allow-list of BOSHDeployments in other namespaces or of other names,
+	// which may consume the links provided by this deployment
+	LinkConsumers []LinkConsumer `json:"linkConsumers,omitempty"`
+	// DNS selects how the BOSH DNS addon is emulated, defaults to 'coredns'
+	DNS DNSMode `json:"dns,omitempty"`
+}
+
+// DNSMode is the emulation of the BOSH DNS addon for a BOSHDeployment
+type DNSMode = string
+
+// Valid values for the DNS mode
+const (
+	// DNSModeCoreDNS deploys a coredns server per deployment, which resolves
+	// the aliases and BOSH DNS queries
+	DNSModeCoreDNS DNSMode = "coredns"
+	// DNSModeNative resolves the aliases with pod host aliases for the
+	// ClusterIPs of the instance group services, without extra DNS pods
+	DNSModeNative DNSMode = "native"
+)
+
+// LinkConsumer references a BOSHDeployment, which is allowed to consume the links of a deployment
+type LinkConsumer struct {
+	// Namespace of the consuming deployment, defaults to the namespace of the providing deployment
+	Namespace  string `json:"namespace,omitempty"`
+	Deployment string `json:"deployment"`
 }
 
 // VarReference represents a user-defined secret for an explicit variable
@@ -99,6 +198,18 @@ type VarReference struct {
 type ResourceReference struct {
 	Name string        `json:"name"`
 	Type ReferenceType `json:"type"`
+	// SecretRef is the name of a secret with credentials for a URL or git reference.
+	// The 'username' and 'password' keys are used for basic auth. Keys prefixed with 'header-' are sent
+	// as HTTP headers without the prefix, e.g. 'header-Authorization'. All other keys are ignored.
+	SecretRef string `json:"secretRef,omitempty"`
+	// CABundle is a PEM encoded CA bundle, which is used to verify the TLS certificate of a URL reference
+	CABundle string `json:"caBundle,omitempty"`
+	// SHA256 is the expected hex encoded digest of the content of a URL reference
+	SHA256 string `json:"sha256,omitempty"`
+	// Ref is the branch, tag or commit of a git reference, defaults to 'HEAD'
+	Ref string `json:"ref,omitempty"`
+	// Path is the path of the file in the repository of a git reference
+	Path string `json:"path,omitempty"`
 }
 
 // BOSHDeploymentStatus defines the observed state of BOSHDeployment
@@ -112,6 +223,100 @@ type BOSHDeploymentStatus struct {
 	TotalInstanceGroups    int          `json:"totalInstanceGroups"`
 	DeployedInstanceGroups int          `json:"deployedInstanceGroups"`
 	StateTimestamp         *metav1.Time `json:"stateTimestamp"`
+	// RollbackVersion is the desired manifest version of the last rollback
+	RollbackVersion int `json:"rollbackVersion,omitempty"`
+	// Rollouts shows the canary and update progress of the instance group statefulsets
+	Rollouts []RolloutStatus `json:"rollouts,omitempty"`
+	// GitSources are the resolved commits of the git references
+	GitSources []GitSourceStatus `json:"gitSources,omitempty"`
+	// ObservedGeneration is the generation of the BOSHDeployment, which was last processed
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the observations of the deployment's state
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// InstanceGroups shows the state of each instance group
+	InstanceGroups []InstanceGroupStatus `json:"instanceGroups,omitempty"`
+	// RuntimeConfigs are the revisions of the BOSHRuntimeConfigs merged into the with-ops manifest
+	RuntimeConfigs []RuntimeConfigStatus `json:"runtimeConfigs,omitempty"`
+	// Errands are the results of the last run of each errand
+	Errands []ErrandStatus `json:"errands,omitempty"`
+}
+
+// ErrandStatus is the result of the last run of an errand and of the runs before it
+type ErrandStatus struct {
+	// Name of the errand instance group
+	Name            string `json:"name"`
+	ErrandRunStatus `json:",inline"`
+	// LastScheduleTime is the time of the last run, which was due by the errand's schedule
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// History contains the results of earlier runs, newest first
+	History []ErrandRunStatus `json:"history,omitempty"`
+}
+
+// ErrandRunStatus is the result of a single run of an errand
+type ErrandRunStatus struct {
+	// State of the run, 'Requested', 'Succeeded', 'Failed' or 'Skipped'
+	State string `json:"state"`
+	// Message explains why a run could not be triggered
+	Message string `json:"message,omitempty"`
+	// RequestTime is the time the run was requested by the run-errand annotation or the schedule
+	RequestTime *metav1.Time `json:"requestTime,omitempty"`
+	// Pod is the name of the errand pod, which the result was read from
+	Pod string `json:"pod,omitempty"`
+	// ExitCode is the first non-zero exit code of the errand containers, or zero
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	// StartTime is the time the errand pod started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// EndTime is the time the last errand container finished
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+	// LogsConfigMap is the name of the config map with the truncated log of
+	// each errand container. It is only kept for the last run.
+	LogsConfigMap string `json:"logsConfigMap,omitempty"`
+}
+
+// InstanceGroupStatus is the state of a single instance group
+type InstanceGroupStatus struct {
+	// Name of the instance group
+	Name string `json:"name"`
+	// Replicas is the desired number of instances
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas is the number of ready instances of the latest statefulset version
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// IGResolvedVersion is the version of the ig-resolved secret being rolled out
+	IGResolvedVersion string `json:"igResolvedVersion,omitempty"`
+	// BPMVersion is the version of the bpm secret being rolled out
+	BPMVersion string `json:"bpmVersion,omitempty"`
+	// Completed is set for errands and shows if the QuarksJob completed
+	Completed *bool `json:"completed,omitempty"`
+	// LastError is the reason and message of the last warning event of the instance group
+	LastError string `json:"lastError,omitempty"`
+	// LastErrorTimestamp is the time of the last warning event
+	LastErrorTimestamp *metav1.Time `json:"lastErrorTimestamp,omitempty"`
+}
+
+// RuntimeConfigStatus is the revision of an applied BOSHRuntimeConfig
+type RuntimeConfigStatus struct {
+	Name string `json:"name"`
+	// Generation of the BOSHRuntimeConfig, which was merged into the manifest
+	Generation int64 `json:"generation"`
+}
+
+// GitSourceStatus is the commit a ref of a git repository resolved to
+type GitSourceStatus struct {
+	URL    string `json:"url"`
+	Ref    string `json:"ref,omitempty"`
+	Commit string `json:"commit"`
+}
+
+// RolloutStatus is the update progress of a single instance group statefulset
+type RolloutStatus struct {
+	// Name of the statefulset
+	Name string `json:"name"`
+	// State of the canary rollout, e.g. 'CanaryUpscale', 'Canary', 'Rollout', 'Done' or 'Failed'
+	State           string `json:"state"`
+	Canaries        int32  `json:"canaries"`
+	CanariesReady   int32  `json:"canariesReady"`
+	Replicas        int32  `json:"replicas"`
+	UpdatedReplicas int32  `json:"updatedReplicas"`
 }
 
 // +genclient
@@ -146,3 +351,212 @@ func HasDeploymentName(l map[string]string) bool {
 	_, ok := l[LabelDeploymentName]
 	return ok
 }
+
+// BOSHCloudConfigSpec maps the IaaS specific parts of a BOSH manifest to
+// Kubernetes scheduling, like the cloud-config of a BOSH director
+type BOSHCloudConfigSpec struct {
+	// VMTypes map an instance group's vm_type to resources and a node selector
+	VMTypes []VMType `json:"vmTypes,omitempty"`
+	// VMExtensions map an instance group's vm_extensions to pod settings
+	VMExtensions []VMExtension `json:"vmExtensions,omitempty"`
+	// DiskTypes map an instance group's persistent_disk_type to a storage class and size
+	DiskTypes []DiskType `json:"diskTypes,omitempty"`
+	// AZs map an instance group's azs to the values of a node topology label
+	AZs []AZ `json:"azs,omitempty"`
+	// ZoneNodeLabel is the node label of the azs, defaults to 'failure-domain.beta.kubernetes.io/zone'
+	ZoneNodeLabel string `json:"zoneNodeLabel,omitempty"`
+}
+
+// VMType sets the resources of each job container and selects the nodes of the pods
+type VMType struct {
+	Name         string                      `json:"name"`
+	Resources    corev1.ResourceRequirements `json:"resources,omitempty"`
+	NodeSelector map[string]string           `json:"nodeSelector,omitempty"`
+}
+
+// VMExtension adds scheduling settings and metadata to the pods
+type VMExtension struct {
+	Name        string              `json:"name"`
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	Labels      map[string]string   `json:"labels,omitempty"`
+	Annotations map[string]string   `json:"annotations,omitempty"`
+	Affinity    *corev1.Affinity    `json:"affinity,omitempty"`
+}
+
+// DiskType is the storage class and default size of persistent disks
+type DiskType struct {
+	Name             string `json:"name"`
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// DiskSize in MiB is used, if the instance group has no persistent_disk
+	DiskSize int `json:"diskSize,omitempty"`
+}
+
+// AZ maps a BOSH availability zone to the value of the zone node label
+type AZ struct {
+	Name string `json:"name"`
+	// Zone is the value of the zone node label, defaults to the name
+	Zone string `json:"zone,omitempty"`
+}
+
+// VMType returns the vm type with the given name
+func (spec *BOSHCloudConfigSpec) VMType(name string) (*VMType, bool) {
+	for i := range spec.VMTypes {
+		if spec.VMTypes[i].Name == name {
+			return &spec.VMTypes[i], true
+		}
+	}
+	return nil, false
+}
+
+// VMExtension returns the vm extension with the given name
+func (spec *BOSHCloudConfigSpec) VMExtension(name string) (*VMExtension, bool) {
+	for i := range spec.VMExtensions {
+		if spec.VMExtensions[i].Name == name {
+			return &spec.VMExtensions[i], true
+		}
+	}
+	return nil, false
+}
+
+// DiskType returns the disk type with the given name
+func (spec *BOSHCloudConfigSpec) DiskType(name string) (*DiskType, bool) {
+	for i := range spec.DiskTypes {
+		if spec.DiskTypes[i].Name == name {
+			return &spec.DiskTypes[i], true
+		}
+	}
+	return nil, false
+}
+
+// AZ returns the availability zone with the given name
+func (spec *BOSHCloudConfigSpec) AZ(name string) (*AZ, bool) {
+	for i := range spec.AZs {
+		if spec.AZs[i].Name == name {
+			return &spec.AZs[i], true
+		}
+	}
+	return nil, false
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BOSHCloudConfig is the Schema for the boshcloudconfigs API
+// +k8s:openapi-gen=true
+type BOSHCloudConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BOSHCloudConfigSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BOSHCloudConfigList contains a list of BOSHCloudConfig
+type BOSHCloudConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BOSHCloudConfig `json:"items"`
+}
+
+// BOSHRuntimeConfigSpec contains a BOSH runtime config, like the
+// runtime-config of a BOSH director
+type BOSHRuntimeConfigSpec struct {
+	// Manifest is the runtime config YAML. Its releases and addons are merged into the manifests of all BOSHDeployments.
+	Manifest string `json:"manifest"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BOSHRuntimeConfig is the Schema for the boshruntimeconfigs API
+// +k8s:openapi-gen=true
+type BOSHRuntimeConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BOSHRuntimeConfigSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BOSHRuntimeConfigList contains a list of BOSHRuntimeConfig
+type BOSHRuntimeConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BOSHRuntimeConfig `json:"items"`
+}
+
+// QuarksLinkSpec declares either a native provider of a link, which is
+// consumed by a BOSHDeployment, or native pods, which consume links of a
+// BOSHDeployment
+type QuarksLinkSpec struct {
+	// Deployment is the name of the BOSHDeployment in the same namespace, which consumes the provided link or provides the consumed links
+	Deployment string              `json:"deployment"`
+	Provider   *QuarksLinkProvider `json:"provider,omitempty"`
+	Consumer   *QuarksLinkConsumer `json:"consumer,omitempty"`
+}
+
+// QuarksLinkProvider provides a link from a secret and the pods of a service selector
+type QuarksLinkProvider struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Secret is the name of the secret with the link properties, its keys are the property names, e.g. 'nats.password'
+	Secret string `json:"secret"`
+	// Selector selects the pods, which are the instances of the link
+	Selector map[string]string `json:"selector"`
+}
+
+// QuarksLinkConsumer mounts the links of the deployment on the selected pods
+type QuarksLinkConsumer struct {
+	// Selector selects the pods, which consume the links
+	Selector map[string]string `json:"selector"`
+	Links    []LinkReference   `json:"links"`
+}
+
+// LinkReference references a link by its name and type
+type LinkReference struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// QuarksLinkStatus shows the resolved link
+type QuarksLinkStatus struct {
+	// Resolved is true, if the secret of the provider or the links of the consumer exist
+	Resolved bool `json:"resolved"`
+	// Message describes why the link is not resolved
+	Message string `json:"message,omitempty"`
+	// Address is the DNS address of a provided link
+	Address string `json:"address,omitempty"`
+	// Addresses are the pod IPs of the instances of a provided link
+	Addresses []string `json:"addresses,omitempty"`
+	// Instances are the names of the selected pods
+	Instances []string `json:"instances,omitempty"`
+	// PropertyKeys are the names of the provided or consumed link properties
+	PropertyKeys []string `json:"propertyKeys,omitempty"`
+	// ObservedGeneration is the generation of the QuarksLink, which was last processed
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// QuarksLink is the Schema for the quarkslinks API
+// +k8s:openapi-gen=true
+type QuarksLink struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   QuarksLinkSpec   `json:"spec,omitempty"`
+	Status QuarksLinkStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// QuarksLinkList contains a list of QuarksLink
+type QuarksLinkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []QuarksLink `json:"items"`
+}
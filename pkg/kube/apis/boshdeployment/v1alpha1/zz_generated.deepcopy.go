@@ -10,9 +10,127 @@ Don't alter this file, it was generated.
 package v1alpha1
 
 import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AZ) DeepCopyInto(out *AZ) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AZ.
+func (in *AZ) DeepCopy() *AZ {
+	if in == nil {
+		return nil
+	}
+	out := new(AZ)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHCloudConfig) DeepCopyInto(out *BOSHCloudConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHCloudConfig.
+func (in *BOSHCloudConfig) DeepCopy() *BOSHCloudConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHCloudConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BOSHCloudConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHCloudConfigList) DeepCopyInto(out *BOSHCloudConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BOSHCloudConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHCloudConfigList.
+func (in *BOSHCloudConfigList) DeepCopy() *BOSHCloudConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHCloudConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BOSHCloudConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHCloudConfigSpec) DeepCopyInto(out *BOSHCloudConfigSpec) {
+	*out = *in
+	if in.VMTypes != nil {
+		in, out := &in.VMTypes, &out.VMTypes
+		*out = make([]VMType, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VMExtensions != nil {
+		in, out := &in.VMExtensions, &out.VMExtensions
+		*out = make([]VMExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DiskTypes != nil {
+		in, out := &in.DiskTypes, &out.DiskTypes
+		*out = make([]DiskType, len(*in))
+		copy(*out, *in)
+	}
+	if in.AZs != nil {
+		in, out := &in.AZs, &out.AZs
+		*out = make([]AZ, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHCloudConfigSpec.
+func (in *BOSHCloudConfigSpec) DeepCopy() *BOSHCloudConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHCloudConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BOSHDeployment) DeepCopyInto(out *BOSHDeployment) {
 	*out = *in
@@ -88,6 +206,11 @@ func (in *BOSHDeploymentSpec) DeepCopyInto(out *BOSHDeploymentSpec) {
 		*out = make([]VarReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.LinkConsumers != nil {
+		in, out := &in.LinkConsumers, &out.LinkConsumers
+		*out = make([]LinkConsumer, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -112,6 +235,42 @@ func (in *BOSHDeploymentStatus) DeepCopyInto(out *BOSHDeploymentStatus) {
 		in, out := &in.StateTimestamp, &out.StateTimestamp
 		*out = (*in).DeepCopy()
 	}
+	if in.Rollouts != nil {
+		in, out := &in.Rollouts, &out.Rollouts
+		*out = make([]RolloutStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.GitSources != nil {
+		in, out := &in.GitSources, &out.GitSources
+		*out = make([]GitSourceStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InstanceGroups != nil {
+		in, out := &in.InstanceGroups, &out.InstanceGroups
+		*out = make([]InstanceGroupStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RuntimeConfigs != nil {
+		in, out := &in.RuntimeConfigs, &out.RuntimeConfigs
+		*out = make([]RuntimeConfigStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Errands != nil {
+		in, out := &in.Errands, &out.Errands
+		*out = make([]ErrandStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -125,6 +284,376 @@ func (in *BOSHDeploymentStatus) DeepCopy() *BOSHDeploymentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHRuntimeConfig) DeepCopyInto(out *BOSHRuntimeConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHRuntimeConfig.
+func (in *BOSHRuntimeConfig) DeepCopy() *BOSHRuntimeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHRuntimeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BOSHRuntimeConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHRuntimeConfigList) DeepCopyInto(out *BOSHRuntimeConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BOSHRuntimeConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHRuntimeConfigList.
+func (in *BOSHRuntimeConfigList) DeepCopy() *BOSHRuntimeConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHRuntimeConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BOSHRuntimeConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BOSHRuntimeConfigSpec) DeepCopyInto(out *BOSHRuntimeConfigSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BOSHRuntimeConfigSpec.
+func (in *BOSHRuntimeConfigSpec) DeepCopy() *BOSHRuntimeConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BOSHRuntimeConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskType) DeepCopyInto(out *DiskType) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskType.
+func (in *DiskType) DeepCopy() *DiskType {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskType)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ErrandRunStatus) DeepCopyInto(out *ErrandRunStatus) {
+	*out = *in
+	if in.RequestTime != nil {
+		in, out := &in.RequestTime, &out.RequestTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErrandRunStatus.
+func (in *ErrandRunStatus) DeepCopy() *ErrandRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ErrandRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ErrandStatus) DeepCopyInto(out *ErrandStatus) {
+	*out = *in
+	in.ErrandRunStatus.DeepCopyInto(&out.ErrandRunStatus)
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]ErrandRunStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErrandStatus.
+func (in *ErrandStatus) DeepCopy() *ErrandStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ErrandStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitSourceStatus) DeepCopyInto(out *GitSourceStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSourceStatus.
+func (in *GitSourceStatus) DeepCopy() *GitSourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GitSourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LinkConsumer) DeepCopyInto(out *LinkConsumer) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LinkConsumer.
+func (in *LinkConsumer) DeepCopy() *LinkConsumer {
+	if in == nil {
+		return nil
+	}
+	out := new(LinkConsumer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LinkReference) DeepCopyInto(out *LinkReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LinkReference.
+func (in *LinkReference) DeepCopy() *LinkReference {
+	if in == nil {
+		return nil
+	}
+	out := new(LinkReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuarksLink) DeepCopyInto(out *QuarksLink) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuarksLink.
+func (in *QuarksLink) DeepCopy() *QuarksLink {
+	if in == nil {
+		return nil
+	}
+	out := new(QuarksLink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *QuarksLink) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuarksLinkConsumer) DeepCopyInto(out *QuarksLinkConsumer) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Links != nil {
+		in, out := &in.Links, &out.Links
+		*out = make([]LinkReference, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuarksLinkConsumer.
+func (in *QuarksLinkConsumer) DeepCopy() *QuarksLinkConsumer {
+	if in == nil {
+		return nil
+	}
+	out := new(QuarksLinkConsumer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuarksLinkList) DeepCopyInto(out *QuarksLinkList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]QuarksLink, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuarksLinkList.
+func (in *QuarksLinkList) DeepCopy() *QuarksLinkList {
+	if in == nil {
+		return nil
+	}
+	out := new(QuarksLinkList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *QuarksLinkList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuarksLinkProvider) DeepCopyInto(out *QuarksLinkProvider) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuarksLinkProvider.
+func (in *QuarksLinkProvider) DeepCopy() *QuarksLinkProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(QuarksLinkProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuarksLinkSpec) DeepCopyInto(out *QuarksLinkSpec) {
+	*out = *in
+	if in.Provider != nil {
+		in, out := &in.Provider, &out.Provider
+		*out = new(QuarksLinkProvider)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Consumer != nil {
+		in, out := &in.Consumer, &out.Consumer
+		*out = new(QuarksLinkConsumer)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuarksLinkSpec.
+func (in *QuarksLinkSpec) DeepCopy() *QuarksLinkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(QuarksLinkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuarksLinkStatus) DeepCopyInto(out *QuarksLinkStatus) {
+	*out = *in
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PropertyKeys != nil {
+		in, out := &in.PropertyKeys, &out.PropertyKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuarksLinkStatus.
+func (in *QuarksLinkStatus) DeepCopy() *QuarksLinkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(QuarksLinkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceReference) DeepCopyInto(out *ResourceReference) {
 	*out = *in
@@ -141,6 +670,63 @@ func (in *ResourceReference) DeepCopy() *ResourceReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGroupStatus) DeepCopyInto(out *InstanceGroupStatus) {
+	*out = *in
+	if in.Completed != nil {
+		in, out := &in.Completed, &out.Completed
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LastErrorTimestamp != nil {
+		in, out := &in.LastErrorTimestamp, &out.LastErrorTimestamp
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupStatus.
+func (in *InstanceGroupStatus) DeepCopy() *InstanceGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuntimeConfigStatus) DeepCopyInto(out *RuntimeConfigStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeConfigStatus.
+func (in *RuntimeConfigStatus) DeepCopy() *RuntimeConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RuntimeConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VarReference) DeepCopyInto(out *VarReference) {
 	*out = *in
@@ -156,3 +742,69 @@ func (in *VarReference) DeepCopy() *VarReference {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMExtension) DeepCopyInto(out *VMExtension) {
+	*out = *in
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMExtension.
+func (in *VMExtension) DeepCopy() *VMExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(VMExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMType) DeepCopyInto(out *VMType) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMType.
+func (in *VMType) DeepCopy() *VMType {
+	if in == nil {
+		return nil
+	}
+	out := new(VMType)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -15,7 +15,10 @@ import (
 
 type BoshdeploymentV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	BOSHCloudConfigsGetter
 	BOSHDeploymentsGetter
+	BOSHRuntimeConfigsGetter
+	QuarksLinksGetter
 }
 
 // BoshdeploymentV1alpha1Client is used to interact with features provided by the boshdeployment group.
@@ -23,10 +26,22 @@ type BoshdeploymentV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *BoshdeploymentV1alpha1Client) BOSHCloudConfigs(namespace string) BOSHCloudConfigInterface {
+	return newBOSHCloudConfigs(c, namespace)
+}
+
 func (c *BoshdeploymentV1alpha1Client) BOSHDeployments(namespace string) BOSHDeploymentInterface {
 	return newBOSHDeployments(c, namespace)
 }
 
+func (c *BoshdeploymentV1alpha1Client) BOSHRuntimeConfigs() BOSHRuntimeConfigInterface {
+	return newBOSHRuntimeConfigs(c)
+}
+
+func (c *BoshdeploymentV1alpha1Client) QuarksLinks(namespace string) QuarksLinkInterface {
+	return newQuarksLinks(c, namespace)
+}
+
 // NewForConfig creates a new BoshdeploymentV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*BoshdeploymentV1alpha1Client, error) {
 	config := *c
@@ -17,10 +17,22 @@ type FakeBoshdeploymentV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeBoshdeploymentV1alpha1) BOSHCloudConfigs(namespace string) v1alpha1.BOSHCloudConfigInterface {
+	return &FakeBOSHCloudConfigs{c, namespace}
+}
+
 func (c *FakeBoshdeploymentV1alpha1) BOSHDeployments(namespace string) v1alpha1.BOSHDeploymentInterface {
 	return &FakeBOSHDeployments{c, namespace}
 }
 
+func (c *FakeBoshdeploymentV1alpha1) BOSHRuntimeConfigs() v1alpha1.BOSHRuntimeConfigInterface {
+	return &FakeBOSHRuntimeConfigs{c}
+}
+
+func (c *FakeBoshdeploymentV1alpha1) QuarksLinks(namespace string) v1alpha1.QuarksLinkInterface {
+	return &FakeQuarksLinks{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeBoshdeploymentV1alpha1) RESTClient() rest.Interface {
@@ -7,4 +7,10 @@ Don't alter this file, it was generated.
 
 package v1alpha1
 
+type BOSHCloudConfigExpansion interface{}
+
 type BOSHDeploymentExpansion interface{}
+
+type BOSHRuntimeConfigExpansion interface{}
+
+type QuarksLinkExpansion interface{}
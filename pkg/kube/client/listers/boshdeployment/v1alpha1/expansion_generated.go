@@ -7,6 +7,14 @@ Don't alter this file, it was generated.
 
 package v1alpha1
 
+// BOSHCloudConfigListerExpansion allows custom methods to be added to
+// BOSHCloudConfigLister.
+type BOSHCloudConfigListerExpansion interface{}
+
+// BOSHCloudConfigNamespaceListerExpansion allows custom methods to be added to
+// BOSHCloudConfigNamespaceLister.
+type BOSHCloudConfigNamespaceListerExpansion interface{}
+
 // BOSHDeploymentListerExpansion allows custom methods to be added to
 // BOSHDeploymentLister.
 type BOSHDeploymentListerExpansion interface{}
@@ -14,3 +22,15 @@ type BOSHDeploymentListerExpansion interface{}
 // BOSHDeploymentNamespaceListerExpansion allows custom methods to be added to
 // BOSHDeploymentNamespaceLister.
 type BOSHDeploymentNamespaceListerExpansion interface{}
+
+// BOSHRuntimeConfigListerExpansion allows custom methods to be added to
+// BOSHRuntimeConfigLister.
+type BOSHRuntimeConfigListerExpansion interface{}
+
+// QuarksLinkListerExpansion allows custom methods to be added to
+// QuarksLinkLister.
+type QuarksLinkListerExpansion interface{}
+
+// QuarksLinkNamespaceListerExpansion allows custom methods to be added to
+// QuarksLinkNamespaceLister.
+type QuarksLinkNamespaceListerExpansion interface{}
@@ -3,21 +3,28 @@ package boshdeployment
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strconv"
 
 	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/desiredmanifest"
+	"code.cloudfoundry.org/quarks-statefulset/pkg/kube/controllers/statefulset"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 	"code.cloudfoundry.org/quarks-utils/pkg/meltdown"
@@ -59,6 +66,11 @@ func AddBPM(ctx context.Context, config *config.Config, mgr manager.Manager) err
 					return false
 				}
 
+				// Secrets copied from a migrated instance group are rendered, once the instance group manifest job created a new version
+				if metav1.HasAnnotation(o.ObjectMeta, bdv1.AnnotationMigratedFrom) {
+					return false
+				}
+
 				ctxlog.NewPredicateEvent(o).Debug(
 					ctx, e.Object, names.Secret,
 					fmt.Sprintf("Create predicate passed for '%s/%s', existing secret with label %s, value %s",
@@ -82,9 +94,158 @@ func AddBPM(ctx context.Context, config *config.Config, mgr manager.Manager) err
 		return errors.Wrapf(err, "Watching secrets failed in BPM controller.")
 	}
 
+	// Watch BOSHCloudConfigs, so changes are rendered into the instance
+	// groups of the deployments, which reference them.
+	p = predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return true },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*bdv1.BOSHCloudConfig)
+			n := e.ObjectNew.(*bdv1.BOSHCloudConfig)
+			return !reflect.DeepEqual(o.Spec, n.Spec)
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHCloudConfig{}}, handler.EnqueueRequestsFromMapFunc(
+		func(a client.Object) []reconcile.Request {
+			reconciles, err := cloudConfigReconciles(ctx, mgr.GetClient(), a.GetNamespace(), a.GetName())
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate reconciles for cloud config '%s/%s': %v", a.GetNamespace(), a.GetName(), err)
+			}
+
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a).Debug(ctx, reconciliation, "BPMSecret", a.GetName(), bdv1.BOSHCloudConfigResourceKind)
+			}
+
+			return reconciles
+		}), nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching cloud configs failed in BPM controller.")
+	}
+
+	// Watch the rollout of the instance group StatefulSets, so the instance
+	// groups of the next stage are deployed once they are ready.
+	p = predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*appsv1.StatefulSet)
+			n := e.ObjectNew.(*appsv1.StatefulSet)
+			if _, ok := n.GetLabels()[bdv1.LabelDeploymentName]; !ok {
+				return false
+			}
+			return !reflect.DeepEqual(o.Status, n.Status) ||
+				o.GetAnnotations()[statefulset.AnnotationCanaryRollout] != n.GetAnnotations()[statefulset.AnnotationCanaryRollout] ||
+				o.GetAnnotations()[bdv1.AnnotationRolloutState] != n.GetAnnotations()[bdv1.AnnotationRolloutState]
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &appsv1.StatefulSet{}}, handler.EnqueueRequestsFromMapFunc(
+		func(a client.Object) []reconcile.Request {
+			reconciles, err := rolloutReconciles(ctx, mgr.GetClient(), a.GetNamespace(), a.GetLabels()[bdv1.LabelDeploymentName])
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate reconciles for statefulset '%s/%s': %v", a.GetNamespace(), a.GetName(), err)
+			}
+
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a).Debug(ctx, reconciliation, "BPMSecret", a.GetName(), "StatefulSet")
+			}
+
+			return reconciles
+		}), nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching statefulsets failed in BPM controller.")
+	}
+
+	// Watch the DNS mode of BOSHDeployments, so all instance groups are
+	// rendered again with the new pod DNS settings.
+	p = predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*bdv1.BOSHDeployment)
+			n := e.ObjectNew.(*bdv1.BOSHDeployment)
+			return o.Spec.DNS != n.Spec.DNS
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeployment{}}, handler.EnqueueRequestsFromMapFunc(
+		func(a client.Object) []reconcile.Request {
+			reconciles, err := dnsReconciles(ctx, mgr.GetClient(), a.GetNamespace(), a.GetName())
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate reconciles for bosh deployment '%s/%s': %v", a.GetNamespace(), a.GetName(), err)
+			}
+
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a).Debug(ctx, reconciliation, "BPMSecret", a.GetName(), bdv1.BOSHDeploymentResourceKind)
+			}
+
+			return reconciles
+		}), nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching bosh deployments failed in BPM controller.")
+	}
+
 	return nil
 }
 
+// cloudConfigReconciles returns the latest BPM secrets of all deployments, which use the cloud config
+func cloudConfigReconciles(ctx context.Context, c client.Client, namespace string, name string) ([]reconcile.Request, error) {
+	reconciles := []reconcile.Request{}
+
+	bdpls := &bdv1.BOSHDeploymentList{}
+	err := c.List(ctx, bdpls, client.InNamespace(namespace))
+	if err != nil {
+		return reconciles, errors.Wrap(err, "failed to list BOSHDeployments")
+	}
+
+	for _, bdpl := range bdpls.Items {
+		if bdpl.Spec.CloudConfig != name {
+			continue
+		}
+
+		// Only the latest version of each instance group's BPM secret is rendered
+		latest, err := latestBPMSecrets(ctx, c, namespace, bdpl.Name)
+		if err != nil {
+			return reconciles, err
+		}
+
+		for _, secret := range latest {
+			reconciles = append(reconciles, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name},
+			})
+		}
+	}
+
+	return reconciles, nil
+}
+
+// dnsReconciles returns the latest BPM secrets of the deployment
+func dnsReconciles(ctx context.Context, c client.Client, namespace string, name string) ([]reconcile.Request, error) {
+	reconciles := []reconcile.Request{}
+
+	latest, err := latestBPMSecrets(ctx, c, namespace, name)
+	if err != nil {
+		return reconciles, err
+	}
+
+	for _, secret := range latest {
+		reconciles = append(reconciles, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name},
+		})
+	}
+
+	return reconciles, nil
+}
+
+func secretVersion(secret corev1.Secret) int {
+	version, err := strconv.Atoi(secret.Labels[vss.LabelVersion])
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
 func isBPMInfoSecret(secret *corev1.Secret) bool {
 	ok := vss.IsVersionedSecret(*secret)
 	if !ok {
@@ -7,7 +7,9 @@ import (
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,6 +25,7 @@ import (
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/quarksrestart"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/metrics"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/mutate"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
 	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
@@ -35,12 +38,12 @@ import (
 
 // BPMConverter converts k8s resources from single BOSH manifest
 type BPMConverter interface {
-	Resources(manifest bdm.Manifest, namespace string, manifestName string, serviceIP string, qStsVersion string, instanceGroup *bdm.InstanceGroup, bpmConfigs bpm.Configs, igResolvedSecretVersion string) (*bpmconverter.Resources, error)
+	Resources(manifest bdm.Manifest, namespace string, manifestName string, dns boshdns.PodDNS, qStsVersion string, instanceGroup *bdm.InstanceGroup, bpmConfigs bpm.Configs, igResolvedSecretVersion string) (*bpmconverter.Resources, error)
 }
 
 // DesiredManifest unmarshals desired manifest from the manifest secret
 type DesiredManifest interface {
-	DesiredManifest(ctx context.Context, namespace string) (*bdm.Manifest, error)
+	DesiredManifest(ctx context.Context, deploymentName string, namespace string) (*bdm.Manifest, error)
 }
 
 var _ reconcile.Reconciler = &ReconcileBOSHDeployment{}
@@ -97,6 +100,7 @@ func (r *ReconcileBPM) Reconcile(_ context.Context, request reconcile.Request) (
 
 	if meltdown.NewAnnotationWindow(r.config.MeltdownDuration, bpmSecret.ObjectMeta.Annotations).Contains(time.Now()) {
 		log.WithEvent(bpmSecret, "Meltdown").Debugf(ctx, "Resource '%s/%s' is in meltdown, requeue reconcile after %s", bpmSecret.Namespace, bpmSecret.Name, r.config.MeltdownRequeueAfter)
+		metrics.MeltdownHits.WithLabelValues(metrics.ControllerBPM).Inc()
 		return reconcile.Result{RequeueAfter: r.config.MeltdownRequeueAfter}, nil
 	}
 
@@ -113,7 +117,7 @@ func (r *ReconcileBPM) Reconcile(_ context.Context, request reconcile.Request) (
 			log.WithEvent(bpmSecret, "LabelMissingError").Errorf(ctx, "There's no label for a instance group name on the BPM secret '%s'", request.NamespacedName)
 	}
 
-	manifest, err := r.resolver.DesiredManifest(ctx, request.Namespace)
+	manifest, err := r.resolver.DesiredManifest(ctx, deploymentName, request.Namespace)
 	if err != nil {
 		return reconcile.Result{},
 			log.WithEvent(bpmSecret, "DesiredManifestReadError").Errorf(ctx, "Failed to read desired manifest for bpm '%s': %v", request.NamespacedName, err)
@@ -126,22 +130,55 @@ func (r *ReconcileBPM) Reconcile(_ context.Context, request reconcile.Request) (
 			log.WithEvent(bpmSecret, "GetBOSHDeployment").Errorf(ctx, "Failed to get BoshDeployment instance '%s/%s': %v", request.Namespace, deploymentName, err)
 	}
 
-	dnsService := &corev1.Service{}
-	if boshdns.HasBoshDNSAddOn(*manifest) != -1 {
-		err = r.client.Get(ctx, types.NamespacedName{Namespace: request.Namespace, Name: boshdns.AppName}, dnsService)
+	// Serial instance groups are rolled out after the instance groups before them are ready
+	pending, err := r.pendingInstanceGroup(ctx, bdpl, manifest, instanceGroupName)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bpmSecret, "OrderedRolloutError").Errorf(ctx, "Failed to check rollout of instance groups before '%s': %v", instanceGroupName, err)
+	}
+	if pending != "" {
+		log.WithEvent(bpmSecret, "WaitForInstanceGroup").Infof(ctx, "Instance group '%s' waits for the rollout of instance group '%s'", instanceGroupName, pending)
+		return reconcile.Result{RequeueAfter: rolloutRequeueAfter}, nil
+	}
+
+	var native *boshdns.NativeDomainNameService
+	if bdpl.Spec.DNS == bdv1.DNSModeNative {
+		native, err = boshdns.NewNativeDomainNameService(deploymentName, *manifest)
 		if err != nil {
 			return reconcile.Result{},
-				log.WithEvent(bpmSecret, "GetBOSHDeployment").Errorf(ctx, "Failed to get '%s' service '%s/%s': %v", boshdns.AppName, request.Namespace, deploymentName, err)
+				log.WithEvent(bpmSecret, "NativeDNSError").Errorf(ctx, "Failed to load BOSH DNS aliases for bpm '%s': %v", request.NamespacedName, err)
+		}
+		// The deployment might have switched to native DNS after its desired manifest was created
+		err = native.Apply(ctx, request.Namespace, r.client, func(object metav1.Object) error {
+			return r.setReference(bdpl, object, r.scheme)
+		})
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(bpmSecret, "NativeDNSError").Errorf(ctx, "Failed to create alias services for bpm '%s': %v", request.NamespacedName, err)
 		}
 	}
 
+	dns, err := podDNS(ctx, r.client, bdpl, manifest)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bpmSecret, "DNSError").Errorf(ctx, "Failed to get DNS settings for bpm '%s': %v", request.NamespacedName, err)
+	}
+
+	cloudConfig, err := cloudConfigSpec(ctx, r.client, bdpl)
+	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "CloudConfigError", err)
+		return reconcile.Result{},
+			log.WithEvent(bpmSecret, "CloudConfigError").Errorf(ctx, "Failed to get cloud config for bpm '%s': %v", request.NamespacedName, err)
+	}
+
 	// Apply BPM information
-	resources, err := r.applyBPMResources(bdpl.Name, instanceGroupName, bpmSecret, manifest, dnsService.Spec.ClusterIP)
+	resources, err := r.applyBPMResources(bdpl, instanceGroupName, bpmSecret, manifest, dns, cloudConfig)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			log.WithEvent(bpmSecret, "SkipReconcile").Debugf(ctx, "Requeue reconcile: %s", err)
 			return reconcile.Result{RequeueAfter: time.Second * 5}, nil
 		}
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "BPMApplyingError", err)
 		return reconcile.Result{}, log.WithEvent(bpmSecret, "BPMApplyingError").Errorf(ctx, "Failed to apply BPM information: %v", err)
 	}
 
@@ -151,11 +188,33 @@ func (r *ReconcileBPM) Reconcile(_ context.Context, request reconcile.Request) (
 	}
 
 	// Deploy instance groups
-	err = r.deployInstanceGroups(ctx, bdpl, instanceGroupName, resources)
+	resizing, err := r.deployInstanceGroups(ctx, bdpl, instanceGroupName, resources)
 	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "InstanceGroupStartError", err)
 		return reconcile.Result{},
 			log.WithEvent(bpmSecret, "InstanceGroupStartError").Errorf(ctx, "Failed to start: %v", err)
 	}
+	if resizing {
+		log.WithEvent(bpmSecret, "WaitForStatefulSetDeletion").Infof(ctx, "Instance group '%s' waits for its StatefulSets to be deleted for the disk resize", instanceGroupName)
+		return reconcile.Result{RequeueAfter: diskResizeRequeueAfter}, nil
+	}
+	metrics.BPMRenders.WithLabelValues(request.Namespace, deploymentName, instanceGroupName).Inc()
+
+	if native != nil {
+		err = native.RemoveCoreDNS(ctx, request.Namespace, r.client)
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(bpmSecret, "NativeDNSError").Errorf(ctx, "Failed to remove coredns of BOSHDeployment '%s/%s': %v", request.Namespace, deploymentName, err)
+		}
+	}
+
+	err = updateConditions(ctx, r.client, bdpl,
+		metav1.Condition{Type: bdv1.ConditionInstanceGroupsRendered, Status: metav1.ConditionTrue, Reason: "InstanceGroupRendered"},
+	)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bpmSecret, "UpdateError").Errorf(ctx, "Failed to update conditions on BOSHDeployment '%s/%s': %v", request.Namespace, deploymentName, err)
+	}
 
 	meltdown.SetLastReconcile(&bpmSecret.ObjectMeta, time.Now())
 	err = r.client.Update(ctx, bpmSecret)
@@ -167,15 +226,51 @@ func (r *ReconcileBPM) Reconcile(_ context.Context, request reconcile.Request) (
 	return reconcile.Result{}, nil
 }
 
-func (r *ReconcileBPM) applyBPMResources(bdplName string, instanceGroupName string, bpmSecret *corev1.Secret, manifest *bdm.Manifest, serviceIP string) (*bpmconverter.Resources, error) {
-	var bpmInfo bdm.BPMInfo
-	if val, ok := bpmSecret.Data["bpm.yaml"]; ok {
-		err := yaml.Unmarshal(val, &bpmInfo)
+// podDNS returns the DNS settings for the pods of the deployment
+func podDNS(ctx context.Context, c client.Client, bdpl *bdv1.BOSHDeployment, manifest *bdm.Manifest) (boshdns.PodDNS, error) {
+	if bdpl.Spec.DNS == bdv1.DNSModeNative {
+		native, err := boshdns.NewNativeDomainNameService(bdpl.Name, *manifest)
 		if err != nil {
-			return nil, err
+			return boshdns.PodDNS{}, errors.Wrap(err, "failed to load BOSH DNS aliases")
+		}
+		hostAliases, err := native.HostAliases(ctx, bdpl.Namespace, c)
+		if err != nil {
+			return boshdns.PodDNS{}, errors.Wrap(err, "failed to get host aliases")
+		}
+		return boshdns.PodDNS{Native: true, HostAliases: hostAliases}, nil
+	}
+
+	if boshdns.HasBoshDNSAddOn(*manifest) != -1 {
+		dnsService := &corev1.Service{}
+		dnsServiceName := boshdns.ResourceName(bdpl.Name)
+		err := c.Get(ctx, types.NamespacedName{Namespace: bdpl.Namespace, Name: dnsServiceName}, dnsService)
+		if err != nil {
+			return boshdns.PodDNS{}, errors.Wrapf(err, "failed to get '%s' service '%s/%s'", boshdns.AppName, bdpl.Namespace, dnsServiceName)
 		}
-	} else {
-		return nil, errors.New("Couldn't find bpm.yaml key in manifest secret")
+		return boshdns.PodDNS{ServiceIP: dnsService.Spec.ClusterIP}, nil
+	}
+
+	return boshdns.PodDNS{}, nil
+}
+
+// cloudConfigSpec returns the spec of the BOSHCloudConfig referenced by the deployment, or nil
+func cloudConfigSpec(ctx context.Context, c client.Client, bdpl *bdv1.BOSHDeployment) (*bdv1.BOSHCloudConfigSpec, error) {
+	if bdpl.Spec.CloudConfig == "" {
+		return nil, nil
+	}
+
+	cloudConfig := &bdv1.BOSHCloudConfig{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: bdpl.Namespace, Name: bdpl.Spec.CloudConfig}, cloudConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get BOSHCloudConfig '%s/%s'", bdpl.Namespace, bdpl.Spec.CloudConfig)
+	}
+	return &cloudConfig.Spec, nil
+}
+
+func (r *ReconcileBPM) applyBPMResources(bdpl *bdv1.BOSHDeployment, instanceGroupName string, bpmSecret *corev1.Secret, manifest *bdm.Manifest, dns boshdns.PodDNS, cloudConfig *bdv1.BOSHCloudConfigSpec) (*bpmconverter.Resources, error) {
+	bpmInfo, err := bpmInfoFromSecret(bpmSecret)
+	if err != nil {
+		return nil, err
 	}
 
 	instanceGroup, found := manifest.InstanceGroups.InstanceGroupByName(instanceGroupName)
@@ -185,15 +280,15 @@ func (r *ReconcileBPM) applyBPMResources(bdplName string, instanceGroupName stri
 
 	// Fetch qSts version
 	quarksStatefulSet := &qstsv1a1.QuarksStatefulSet{}
-	quarksStatefulSetName := instanceGroup.NameSanitized()
-	err := r.client.Get(r.ctx, types.NamespacedName{Namespace: bpmSecret.Namespace, Name: quarksStatefulSetName}, quarksStatefulSet)
+	quarksStatefulSetName := names.QuarksStatefulSetName(bdpl.Name, instanceGroup.Name)
+	err = r.client.Get(r.ctx, types.NamespacedName{Namespace: bpmSecret.Namespace, Name: quarksStatefulSetName}, quarksStatefulSet)
 	if err != nil {
 		if !apierrors.IsNotFound(err) {
 			return nil, errors.Errorf("Failed to get QuarksStatefulSet instance '%s/%s': %v", bpmSecret.Namespace, quarksStatefulSetName, err)
 		}
 	}
 
-	igResolvedSecretVersion, err := r.fetchIGresolvedVersion(bpmSecret.Namespace, instanceGroupName)
+	igResolvedSecretVersion, err := igResolvedVersion(r.ctx, r.versionedSecretStore, bpmSecret.Namespace, bdpl.Name, instanceGroupName)
 	if err != nil {
 		return nil, err
 	}
@@ -208,17 +303,70 @@ func (r *ReconcileBPM) applyBPMResources(bdplName string, instanceGroupName stri
 		qStsVersionString = strconv.Itoa(qStsVersion)
 	}
 
-	resources, err := r.converter.Resources(*manifest, bpmSecret.Namespace, bdplName, serviceIP, qStsVersionString, instanceGroup, bpmInfo.Configs, igResolvedSecretVersion)
-	if err != nil {
+	return convertInstanceGroup(r.converter, bdpl, instanceGroup, bpmSecret, bpmInfo, manifest, dns, cloudConfig, qStsVersionString, igResolvedSecretVersion)
+}
+
+// bpmInfoFromSecret reads the BPM information of an instance group from its BPM secret
+func bpmInfoFromSecret(bpmSecret *corev1.Secret) (bdm.BPMInfo, error) {
+	var bpmInfo bdm.BPMInfo
+	val, ok := bpmSecret.Data["bpm.yaml"]
+	if !ok {
+		return bpmInfo, errors.New("Couldn't find bpm.yaml key in manifest secret")
+	}
+	err := yaml.Unmarshal(val, &bpmInfo)
+	return bpmInfo, err
+}
+
+// convertInstanceGroup converts the instance group of the manifest with its
+// BPM information into the resources, which are deployed for it
+func convertInstanceGroup(converter BPMConverter, bdpl *bdv1.BOSHDeployment, instanceGroup *bdm.InstanceGroup, bpmSecret *corev1.Secret, bpmInfo bdm.BPMInfo, manifest *bdm.Manifest, dns boshdns.PodDNS, cloudConfig *bdv1.BOSHCloudConfigSpec, qStsVersion string, igResolvedSecretVersion string) (*bpmconverter.Resources, error) {
+	if cloudConfig != nil {
+		err := bpmconverter.ApplyCloudConfigDisks(cloudConfig, instanceGroup)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resources, err := converter.Resources(*manifest, bpmSecret.Namespace, bdpl.Name, dns, qStsVersion, instanceGroup, bpmInfo.Configs, igResolvedSecretVersion)
+	if err != nil || resources == nil {
 		return resources, err
 	}
 
+	if cloudConfig != nil {
+		err = bpmconverter.ApplyCloudConfig(cloudConfig, instanceGroup, resources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only instance groups with pods are isolated, errands don't provide links
+	if bdpl.Spec.NetworkPolicies && len(resources.InstanceGroups) > 0 {
+		resources.NetworkPolicies = append(resources.NetworkPolicies,
+			bpmconverter.NetworkPolicy(bpmSecret.Namespace, bdpl.Name, bpmInfo.InstanceGroup, bpmInfo.Configs, bdpl.Spec.LinkConsumers))
+	}
+
+	// Record the secret versions, so the deployment status can show which
+	// versions are rolled out and the ordered rollout can tell whether the
+	// StatefulSets are up to date
+	for i := range resources.InstanceGroups {
+		for _, obj := range []metav1.Object{&resources.InstanceGroups[i], &resources.InstanceGroups[i].Spec.Template} {
+			annotations := obj.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[bdv1.AnnotationIGResolvedVersion] = igResolvedSecretVersion
+			annotations[bdv1.AnnotationBPMVersion] = bpmSecret.GetLabels()[versionedsecretstore.LabelVersion]
+			obj.SetAnnotations(annotations)
+		}
+	}
+
 	return resources, nil
 }
 
-func (r *ReconcileBPM) fetchIGresolvedVersion(namespace string, instanceGroupName string) (string, error) {
-	igResolvedSecretName := names.InstanceGroupSecretName(instanceGroupName, "")
-	igResolvedSecret, err := r.versionedSecretStore.Latest(r.ctx, namespace, igResolvedSecretName)
+// igResolvedVersion returns the version of the latest ig-resolved secret of the instance group
+func igResolvedVersion(ctx context.Context, store versionedsecretstore.VersionedSecretStore, namespace string, deploymentName string, instanceGroupName string) (string, error) {
+	igResolvedSecretName := names.InstanceGroupSecretName(deploymentName, instanceGroupName, "")
+	igResolvedSecret, err := store.Latest(ctx, namespace, igResolvedSecretName)
 	if err != nil {
 		if igResolvedSecret == nil {
 			return "", apierrors.NewNotFound(corev1.Resource("secret"), igResolvedSecretName)
@@ -228,8 +376,9 @@ func (r *ReconcileBPM) fetchIGresolvedVersion(namespace string, instanceGroupNam
 	return igResolvedSecret.GetLabels()[versionedsecretstore.LabelVersion], nil
 }
 
-// deployInstanceGroups create or update QuarksJobs and QuarksStatefulSets for instance groups
-func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSHDeployment, instanceGroupName string, resources *bpmconverter.Resources) error {
+// deployInstanceGroups create or update QuarksJobs and QuarksStatefulSets for instance groups.
+// It returns true, if a QuarksStatefulSet waits for the deletion of its StatefulSets to resize its disks.
+func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSHDeployment, instanceGroupName string, resources *bpmconverter.Resources) (bool, error) {
 	log.Debugf(ctx, "Creating quarksJobs and quarksStatefulSets for instance group '%s'", instanceGroupName)
 
 	for _, qJob := range resources.Errands {
@@ -239,12 +388,12 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSH
 		}
 
 		if err := r.setReference(bdpl, &qJob, r.scheme); err != nil {
-			return log.WithEvent(bdpl, "QuarksJobForDeploymentError").Errorf(ctx, "Failed to set reference for QuarksJob instance group '%s' : %v", instanceGroupName, err)
+			return false, log.WithEvent(bdpl, "QuarksJobForDeploymentError").Errorf(ctx, "Failed to set reference for QuarksJob instance group '%s' : %v", instanceGroupName, err)
 		}
 
 		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &qJob, mutate.QuarksJobMutateFn(&qJob))
 		if err != nil {
-			return log.WithEvent(bdpl, "ApplyQuarksJobError").Errorf(ctx, "Failed to apply QuarksJob for instance group '%s' : %v", instanceGroupName, err)
+			return false, log.WithEvent(bdpl, "ApplyQuarksJobError").Errorf(ctx, "Failed to apply QuarksJob for instance group '%s' : %v", instanceGroupName, err)
 		}
 
 		log.Debugf(ctx, "QuarksJob '%s/%s' has been %s", bdpl.Namespace, qJob.Name, op)
@@ -257,17 +406,47 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSH
 		}
 
 		if err := r.setReference(bdpl, &svc, r.scheme); err != nil {
-			return log.WithEvent(bdpl, "ServiceForDeploymentError").Errorf(ctx, "Failed to set reference for Service instance group '%s' : %v", instanceGroupName, err)
+			return false, log.WithEvent(bdpl, "ServiceForDeploymentError").Errorf(ctx, "Failed to set reference for Service instance group '%s' : %v", instanceGroupName, err)
 		}
 
 		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &svc, mutate.ServiceMutateFn(&svc))
 		if err != nil {
-			return log.WithEvent(bdpl, "ApplyServiceError").Errorf(ctx, "Failed to apply Service for instance group '%s' : %v", instanceGroupName, err)
+			return false, log.WithEvent(bdpl, "ApplyServiceError").Errorf(ctx, "Failed to apply Service for instance group '%s' : %v", instanceGroupName, err)
 		}
 
 		log.Debugf(ctx, "Service '%s/%s' has been %s", bdpl.Namespace, svc.Name, op)
 	}
 
+	for _, policy := range resources.NetworkPolicies {
+		if policy.Labels[bdv1.LabelInstanceGroupName] != instanceGroupName {
+			log.Debugf(ctx, "Skipping apply NetworkPolicy '%s/%s' for instance group '%s' because of mismatching '%s' label", bdpl.Namespace, policy.Name, bdpl.Name, bdv1.LabelInstanceGroupName)
+			continue
+		}
+
+		if err := r.setReference(bdpl, &policy, r.scheme); err != nil {
+			return false, log.WithEvent(bdpl, "NetworkPolicyForDeploymentError").Errorf(ctx, "Failed to set reference for NetworkPolicy instance group '%s' : %v", instanceGroupName, err)
+		}
+
+		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &policy, mutate.NetworkPolicyMutateFn(&policy))
+		if err != nil {
+			return false, log.WithEvent(bdpl, "ApplyNetworkPolicyError").Errorf(ctx, "Failed to apply NetworkPolicy for instance group '%s' : %v", instanceGroupName, err)
+		}
+
+		log.Debugf(ctx, "NetworkPolicy '%s/%s' has been %s", bdpl.Namespace, policy.Name, op)
+	}
+
+	if !bdpl.Spec.NetworkPolicies {
+		policy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+			Name:      names.NetworkPolicyName(bdpl.Name, instanceGroupName),
+			Namespace: bdpl.Namespace,
+		}}
+		err := r.client.Delete(ctx, policy)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, log.WithEvent(bdpl, "DeleteNetworkPolicyError").Errorf(ctx, "Failed to delete NetworkPolicy for instance group '%s' : %v", instanceGroupName, err)
+		}
+	}
+
+	resizing := false
 	for _, qSts := range resources.InstanceGroups {
 		// Automatically restart instance groups if any of the secret changes
 		annotations := qSts.Spec.Template.Spec.Template.Annotations
@@ -283,16 +462,25 @@ func (r *ReconcileBPM) deployInstanceGroups(ctx context.Context, bdpl *bdv1.BOSH
 		}
 
 		if err := r.setReference(bdpl, &qSts, r.scheme); err != nil {
-			return log.WithEvent(bdpl, "QuarksStatefulSetForDeploymentError").Errorf(ctx, "Failed to set reference for QuarksStatefulSet instance group '%s' : %v", instanceGroupName, err)
+			return false, log.WithEvent(bdpl, "QuarksStatefulSetForDeploymentError").Errorf(ctx, "Failed to set reference for QuarksStatefulSet instance group '%s' : %v", instanceGroupName, err)
+		}
+
+		deleting, err := resizePersistentDisks(ctx, r.client, bdpl, &qSts)
+		if err != nil {
+			return false, log.WithEvent(bdpl, "ResizePersistentDiskError").Errorf(ctx, "Failed to resize persistent disks of instance group '%s' : %v", instanceGroupName, err)
+		}
+		if deleting {
+			resizing = true
+			continue
 		}
 
 		op, err := controllerutil.CreateOrUpdate(ctx, r.client, &qSts, mutate.QuarksStatefulSetMutateFn(&qSts))
 		if err != nil {
-			return log.WithEvent(bdpl, "ApplyQuarksStatefulSetError").Errorf(ctx, "Failed to apply QuarksStatefulSet for instance group '%s' : %v", instanceGroupName, err)
+			return false, log.WithEvent(bdpl, "ApplyQuarksStatefulSetError").Errorf(ctx, "Failed to apply QuarksStatefulSet for instance group '%s' : %v", instanceGroupName, err)
 		}
 
 		log.Debugf(ctx, "QuarksStatefulSet '%s/%s' has been %s", bdpl.Namespace, qSts.Name, op)
 	}
 
-	return nil
+	return resizing, nil
 }
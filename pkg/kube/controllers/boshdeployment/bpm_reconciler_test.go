@@ -11,7 +11,9 @@ import (
 	"go.uber.org/zap/zaptest/observer"
 
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -28,6 +30,7 @@ import (
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
 	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 	"code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
@@ -48,6 +51,7 @@ var _ = Describe("ReconcileBPM", func() {
 		log                       *zap.SugaredLogger
 		config                    *cfcfg.Config
 		client                    *fakes.FakeClient
+		status                    *fakes.FakeStatusWriter
 		manifestWithVars          *corev1.Secret
 		bpmInformation            *corev1.Secret
 		bpmInformationNoProcesses *corev1.Secret
@@ -234,6 +238,9 @@ variables: []
 			return nil
 		})
 
+		status = &fakes.FakeStatusWriter{}
+		client.StatusCalls(func() crc.StatusWriter { return status })
+
 		manager.GetClientReturns(client)
 
 		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo.bpm.fakepod", Namespace: "default"}}
@@ -323,6 +330,12 @@ variables: []
 				_, err := reconciler.Reconcile(context.Background(), request)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("failed to start: failed to apply Service for instance group 'fakepod'"))
+
+				Expect(status.UpdateCallCount()).To(Equal(1))
+				_, object, _ := status.UpdateArgsForCall(0)
+				condition := meta.FindStatusCondition(object.(*bdv1.BOSHDeployment).Status.Conditions, bdv1.ConditionInstanceGroupsRendered)
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal("InstanceGroupStartError"))
 			})
 
 			It("creates instance groups and updates bpm configs created state to deploying state successfully", func() {
@@ -339,10 +352,230 @@ variables: []
 					Requeue: false,
 				}))
 
+				Expect(status.UpdateCallCount()).To(Equal(1))
+				_, object, _ := status.UpdateArgsForCall(0)
+				Expect(meta.IsStatusConditionTrue(object.(*bdv1.BOSHDeployment).Status.Conditions, bdv1.ConditionInstanceGroupsRendered)).To(BeTrue())
+
 				newInstance := &bdv1.BOSHDeployment{}
 				err = client.Get(context.Background(), types.NamespacedName{Name: "foo", Namespace: "default"}, newInstance)
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
+
+		Context("when the deployment enables network policies", func() {
+			var networkPolicies bool
+
+			BeforeEach(func() {
+				networkPolicies = true
+				bpmInformation.Data["bpm.yaml"] = []byte(`instance_group:
+  name: fakepod
+  link_consumers:
+  - consumer
+configs:
+  foo:
+    ports:
+    - name: foo
+      protocol: TCP
+      internal: 8080
+`)
+
+				kubeConverter.ResourcesReturns(&bpmconverter.Resources{
+					InstanceGroups: []qstsv1a1.QuarksStatefulSet{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "fakepod",
+								Namespace: "default",
+								Labels: map[string]string{
+									bdv1.LabelInstanceGroupName: "fakepod",
+								},
+							},
+						},
+					},
+				}, nil)
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object crc.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+						object.Namespace = "default"
+						object.Spec.NetworkPolicies = networkPolicies
+					case *networkingv1.NetworkPolicy:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					}
+
+					return nil
+				})
+			})
+
+			It("creates a network policy for the instance group", func() {
+				var policy *networkingv1.NetworkPolicy
+				client.CreateCalls(func(context context.Context, object crc.Object, _ ...crc.CreateOption) error {
+					if p, ok := object.(*networkingv1.NetworkPolicy); ok {
+						policy = p
+					}
+					return nil
+				})
+
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(policy).NotTo(BeNil())
+				Expect(policy.Name).To(Equal("foo.fakepod"))
+				Expect(policy.Spec.Ingress).To(HaveLen(2))
+				Expect(policy.Spec.Ingress[1].From[0].PodSelector.MatchLabels).To(HaveKeyWithValue(bdv1.LabelInstanceGroupName, "consumer"))
+				Expect(client.DeleteCallCount()).To(Equal(0))
+			})
+
+			It("deletes the network policy, when they are disabled again", func() {
+				networkPolicies = false
+
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(client.DeleteCallCount()).To(Equal(1))
+				_, object, _ := client.DeleteArgsForCall(0)
+				Expect(object).To(BeAssignableToTypeOf(&networkingv1.NetworkPolicy{}))
+				Expect(object.GetName()).To(Equal("foo.fakepod"))
+			})
+		})
+
+		Context("when the deployment uses native DNS", func() {
+			BeforeEach(func() {
+				manifest.AddOns = []*bdm.AddOn{
+					{
+						Name: "bosh-dns-aliases",
+						Jobs: []bdm.AddOnJob{
+							{
+								Name:    "bosh-dns-aliases",
+								Release: bdm.BOSHDNSAliasesAddOnName,
+								Properties: bdm.JobProperties{
+									Properties: map[string]interface{}{
+										"aliases": []interface{}{
+											map[string]interface{}{
+												"domain": "fake.service.cf.internal",
+												"targets": []interface{}{
+													map[string]interface{}{
+														"query":          "*",
+														"instance_group": "fakepod",
+														"deployment":     "foo",
+														"network":        "default",
+														"domain":         "bosh",
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object crc.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+						object.Namespace = "default"
+						object.Spec.DNS = bdv1.DNSModeNative
+					case *corev1.Service:
+						if nn.Name != "foo-fakepod-alias" {
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						}
+						object.Name = nn.Name
+						object.Spec.ClusterIP = "10.0.0.10"
+					}
+
+					return nil
+				})
+			})
+
+			It("renders host aliases instead of the coredns nameserver", func() {
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(kubeConverter.ResourcesCallCount()).To(Equal(1))
+				_, _, _, dns, _, _, _, _ := kubeConverter.ResourcesArgsForCall(0)
+				Expect(dns.Native).To(BeTrue())
+				Expect(dns.ServiceIP).To(BeEmpty())
+				Expect(dns.HostAliases).To(Equal([]corev1.HostAlias{
+					{IP: "10.0.0.10", Hostnames: []string{"fake.service.cf.internal"}},
+				}))
+			})
+		})
+
+		Context("when the deployment references a cloud config", func() {
+			var cloudConfig *bdv1.BOSHCloudConfig
+
+			BeforeEach(func() {
+				cloudConfig = &bdv1.BOSHCloudConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "cloud", Namespace: "default"},
+					Spec: bdv1.BOSHCloudConfigSpec{
+						DiskTypes: []bdv1.DiskType{
+							{Name: "standard", StorageClassName: "fast-ssd"},
+						},
+					},
+				}
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object crc.Object) error {
+					switch object := object.(type) {
+					case *corev1.Secret:
+						if nn.Name == manifestWithVars.Name {
+							manifestWithVars.DeepCopyInto(object)
+						}
+						if nn.Name == bpmInformation.Name {
+							bpmInformation.DeepCopyInto(object)
+						}
+					case *bdv1.BOSHDeployment:
+						object.Name = "foo"
+						object.Namespace = "default"
+						object.Spec.CloudConfig = "cloud"
+					case *bdv1.BOSHCloudConfig:
+						if cloudConfig == nil {
+							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+						}
+						cloudConfig.DeepCopyInto(object)
+					}
+
+					return nil
+				})
+			})
+
+			It("resolves the disk type of the instance group to a storage class", func() {
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(kubeConverter.ResourcesCallCount()).To(Equal(1))
+				_, _, _, _, _, instanceGroup, _, _ := kubeConverter.ResourcesArgsForCall(0)
+				Expect(instanceGroup.PersistentDiskType).To(Equal("fast-ssd"))
+			})
+
+			It("fails, if the cloud config does not exist", func() {
+				cloudConfig = nil
+
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to get cloud config"))
+				Expect(kubeConverter.ResourcesCallCount()).To(Equal(0))
+
+				Expect(status.UpdateCallCount()).To(Equal(1))
+				_, object, _ := status.UpdateArgsForCall(0)
+				condition := meta.FindStatusCondition(object.(*bdv1.BOSHDeployment).Status.Conditions, bdv1.ConditionInstanceGroupsRendered)
+				Expect(condition.Reason).To(Equal("CloudConfigError"))
+			})
+		})
 	})
 })
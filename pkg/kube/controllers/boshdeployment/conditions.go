@@ -0,0 +1,140 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// readyDependencies are the conditions, which all need to be true for a ready deployment
+var readyDependencies = []string{
+	bdv1.ConditionManifestResolved,
+	bdv1.ConditionVariablesGenerated,
+	bdv1.ConditionInstanceGroupsRendered,
+	bdv1.ConditionInstanceGroupsReady,
+}
+
+// setCondition sets the condition on the BOSHDeployment status and recalculates the Ready condition
+func setCondition(bdpl *bdv1.BOSHDeployment, conditionType string, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&bdpl.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: bdpl.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	setReadyCondition(bdpl)
+}
+
+// setFailedCondition marks the step as failed and the deployment as degraded
+func setFailedCondition(bdpl *bdv1.BOSHDeployment, conditionType string, reason string, message string) {
+	setCondition(bdpl, conditionType, metav1.ConditionFalse, reason, message)
+	setCondition(bdpl, bdv1.ConditionDegraded, metav1.ConditionTrue, reason, message)
+}
+
+// resetConditions marks all steps as pending, so the Ready condition doesn't
+// report the result of the previous generation while the new one is deployed
+func resetConditions(bdpl *bdv1.BOSHDeployment) {
+	for _, t := range readyDependencies {
+		meta.SetStatusCondition(&bdpl.Status.Conditions, metav1.Condition{
+			Type:               t,
+			Status:             metav1.ConditionUnknown,
+			ObservedGeneration: bdpl.Generation,
+			Reason:             "Reconciling",
+			Message:            fmt.Sprintf("Deploying generation %d", bdpl.Generation),
+		})
+	}
+	setReadyCondition(bdpl)
+}
+
+// setReadyCondition derives the Ready condition from the other conditions
+func setReadyCondition(bdpl *bdv1.BOSHDeployment) {
+	ready := metav1.Condition{
+		Type:               bdv1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: bdpl.Generation,
+		Reason:             "Deployed",
+		Message:            "All instance groups are ready",
+	}
+
+	if degraded := meta.FindStatusCondition(bdpl.Status.Conditions, bdv1.ConditionDegraded); degraded != nil && degraded.Status == metav1.ConditionTrue {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = degraded.Reason
+		ready.Message = degraded.Message
+	} else {
+		for _, t := range readyDependencies {
+			c := meta.FindStatusCondition(bdpl.Status.Conditions, t)
+			if c == nil {
+				ready.Status = metav1.ConditionFalse
+				ready.Reason = "Pending"
+				ready.Message = "Waiting for " + t
+				break
+			}
+			if c.ObservedGeneration != bdpl.Generation {
+				ready.Status = metav1.ConditionFalse
+				ready.Reason = "Reconciling"
+				ready.Message = fmt.Sprintf("Waiting for %s of generation %d", t, bdpl.Generation)
+				break
+			}
+			if c.Status != metav1.ConditionTrue {
+				ready.Status = metav1.ConditionFalse
+				ready.Reason = c.Reason
+				ready.Message = c.Message
+				break
+			}
+		}
+	}
+
+	meta.SetStatusCondition(&bdpl.Status.Conditions, ready)
+}
+
+// updateFailedCondition sets a failed condition and updates the status. Errors
+// are only logged, so the caller can return the original error.
+func updateFailedCondition(ctx context.Context, c client.Client, bdpl *bdv1.BOSHDeployment, conditionType string, reason string, err error) {
+	setFailedCondition(bdpl, conditionType, reason, err.Error())
+	if updateErr := c.Status().Update(ctx, bdpl); updateErr != nil {
+		log.Errorf(ctx, "failed to update conditions of bdpl '%s': %v", bdpl.GetNamespacedName(), updateErr)
+	}
+}
+
+// updateConditions sets the conditions and updates the status, if the conditions changed
+func updateConditions(ctx context.Context, c client.Client, bdpl *bdv1.BOSHDeployment, conditions ...metav1.Condition) error {
+	before := copyConditions(bdpl.Status.Conditions)
+	for _, condition := range conditions {
+		setCondition(bdpl, condition.Type, condition.Status, condition.Reason, condition.Message)
+	}
+	if conditionsEqual(before, bdpl.Status.Conditions) {
+		return nil
+	}
+	return c.Status().Update(ctx, bdpl)
+}
+
+func copyConditions(conditions []metav1.Condition) []metav1.Condition {
+	result := make([]metav1.Condition, len(conditions))
+	for i := range conditions {
+		conditions[i].DeepCopyInto(&result[i])
+	}
+	return result
+}
+
+// conditionsEqual compares the conditions without the transition timestamps
+func conditionsEqual(a, b []metav1.Condition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		x.LastTransitionTime, y.LastTransitionTime = metav1.Time{}, metav1.Time{}
+		if !reflect.DeepEqual(x, y) {
+			return false
+		}
+	}
+	return true
+}
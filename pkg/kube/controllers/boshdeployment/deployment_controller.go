@@ -20,6 +20,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/converter"
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/qjobs"
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
@@ -44,6 +45,7 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 		),
 		qjobs.NewJobFactory(),
 		converter.NewVariablesConverter(),
+		bpmconverter.NewConverter(bpmconverter.NewVolumeFactory(), bpmconverter.NewContainerFactory),
 		controllerutil.SetControllerReference,
 	)
 
@@ -72,7 +74,10 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			o := e.ObjectOld.(*bdv1.BOSHDeployment)
 			n := e.ObjectNew.(*bdv1.BOSHDeployment)
-			if !reflect.DeepEqual(o.Spec, n.Spec) {
+			// Removing the dry-run annotation applies the changes, new commits of git references are deployed
+			if !reflect.DeepEqual(o.Spec, n.Spec) ||
+				o.Annotations[bdv1.AnnotationDryRun] != n.Annotations[bdv1.AnnotationDryRun] ||
+				!reflect.DeepEqual(o.Status.GitSources, n.Status.GitSources) {
 				ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
 					ctx, e.ObjectNew, "bdv1.BOSHDeployment",
 					fmt.Sprintf("Update predicate passed for '%s/%s'", e.ObjectNew.GetNamespace(), e.ObjectNew.GetName()),
@@ -258,9 +263,94 @@ func AddDeployment(ctx context.Context, config *config.Config, mgr manager.Manag
 
 	}
 
+	// Watch link secrets, consumers in other deployments have to render the changed link properties
+	p = predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return isLinkSecret(e.Object.(*corev1.Secret)) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSecret := e.ObjectOld.(*corev1.Secret)
+			newSecret := e.ObjectNew.(*corev1.Secret)
+
+			return isLinkSecret(newSecret) && !reflect.DeepEqual(oldSecret.Data, newSecret.Data)
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(
+		func(a client.Object) []reconcile.Request {
+			reconciles, err := linkConsumerReconciles(ctx, mgr.GetClient(), a.(*corev1.Secret))
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate link consumers for secret '%s/%s': %v", a.GetNamespace(), a.GetName(), err)
+			}
+
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a).Debug(ctx, reconciliation, "BOSHDeployment", a.GetName(), "LinkSecretOfProvider")
+			}
+
+			return reconciles
+		}), nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "watching link secrets failed in bosh deployment controller.")
+	}
+
+	// Watch BOSHRuntimeConfigs, their addons are merged into the manifests of all deployments.
+	// Runtime configs are cluster-scoped, so the namespace predicate doesn't apply.
+	p = predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return true },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return true },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*bdv1.BOSHRuntimeConfig)
+			n := e.ObjectNew.(*bdv1.BOSHRuntimeConfig)
+			return !reflect.DeepEqual(o.Spec, n.Spec)
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHRuntimeConfig{}}, handler.EnqueueRequestsFromMapFunc(
+		func(a client.Object) []reconcile.Request {
+			reconciles, err := runtimeConfigReconciles(ctx, mgr.GetClient(), config.MonitoredID)
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate reconciles for runtime config '%s': %v", a.GetName(), err)
+			}
+
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a).Debug(ctx, reconciliation, "BOSHDeployment", a.GetName(), bdv1.BOSHRuntimeConfigResourceKind)
+			}
+
+			return reconciles
+		}), p)
+	if err != nil {
+		return errors.Wrapf(err, "watching runtime configs failed in bosh deployment controller.")
+	}
+
 	return nil
 }
 
+// runtimeConfigReconciles returns the BOSHDeployments of all monitored namespaces
+func runtimeConfigReconciles(ctx context.Context, c client.Client, monitoredID string) ([]reconcile.Request, error) {
+	reconciles := []reconcile.Request{}
+
+	namespaces := &corev1.NamespaceList{}
+	err := c.List(ctx, namespaces, client.MatchingLabels{monitorednamespace.LabelNamespace: monitoredID})
+	if err != nil {
+		return reconciles, errors.Wrap(err, "failed to list monitored namespaces")
+	}
+
+	for _, ns := range namespaces.Items {
+		bdpls := &bdv1.BOSHDeploymentList{}
+		err := c.List(ctx, bdpls, client.InNamespace(ns.Name))
+		if err != nil {
+			return reconciles, errors.Wrapf(err, "failed to list BOSHDeployments in namespace '%s'", ns.Name)
+		}
+
+		for _, bdpl := range bdpls.Items {
+			reconciles = append(reconciles, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: bdpl.Namespace, Name: bdpl.Name},
+			})
+		}
+	}
+
+	return reconciles, nil
+}
+
 func getEndpointsService(ctx context.Context, client client.Client, ep corev1.Endpoints) (*corev1.Service, error) {
 	id := types.NamespacedName{Name: ep.Name, Namespace: ep.Namespace}
 	svc := &corev1.Service{}
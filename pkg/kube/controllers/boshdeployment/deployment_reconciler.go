@@ -2,6 +2,8 @@ package boshdeployment
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/pkg/errors"
@@ -14,12 +16,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
 
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/converter"
 	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/metrics"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/mutate"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
 	qsv1a1 "code.cloudfoundry.org/quarks-secret/pkg/kube/apis/quarkssecret/v1alpha1"
 	mutateqs "code.cloudfoundry.org/quarks-secret/pkg/kube/util/mutate"
 	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
@@ -44,7 +49,9 @@ type VariablesConverter interface {
 
 // WithOps interpolates BOSH manifests and operations files to create the WithOps manifest
 type WithOps interface {
-	Manifest(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, error)
+	Manifest(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, []bdv1.RuntimeConfigStatus, error)
+	ManifestDetailed(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, error)
+	InterpolateVariableFromSecrets(ctx context.Context, withOpsManifestData []byte, namespace string, boshdeploymentName string) ([]byte, error)
 }
 
 // Check that ReconcileBOSHDeployment implements the reconcile.Reconciler interface
@@ -56,7 +63,7 @@ const ReconcileSkipDuration = 10 * time.Second
 type setReferenceFunc func(owner, object metav1.Object, scheme *runtime.Scheme) error
 
 // NewDeploymentReconciler returns a new reconcile.Reconciler
-func NewDeploymentReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, withops WithOps, jobFactory JobFactory, converter VariablesConverter, srf setReferenceFunc) reconcile.Reconciler {
+func NewDeploymentReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, withops WithOps, jobFactory JobFactory, converter VariablesConverter, bpmConverter BPMConverter, srf setReferenceFunc) reconcile.Reconciler {
 
 	return &ReconcileBOSHDeployment{
 		ctx:          ctx,
@@ -67,6 +74,7 @@ func NewDeploymentReconciler(ctx context.Context, config *config.Config, mgr man
 		setReference: srf,
 		jobFactory:   jobFactory,
 		converter:    converter,
+		bpmConverter: bpmConverter,
 	}
 }
 
@@ -80,6 +88,7 @@ type ReconcileBOSHDeployment struct {
 	setReference setReferenceFunc
 	jobFactory   JobFactory
 	converter    VariablesConverter
+	bpmConverter BPMConverter
 }
 
 // Reconcile starts the deployment process for a BOSHDeployment and deploys QuarksJobs to generate required properties for instance groups and rendered BPM
@@ -99,6 +108,7 @@ func (r *ReconcileBOSHDeployment) Reconcile(_ context.Context, request reconcile
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
 			log.Debug(ctx, "Skip reconcile: BOSHDeployment not found")
+			metrics.QuarksSecrets.DeleteLabelValues(request.Namespace, request.Name)
 			return reconcile.Result{}, nil
 		}
 
@@ -106,6 +116,10 @@ func (r *ReconcileBOSHDeployment) Reconcile(_ context.Context, request reconcile
 			log.WithEvent(bdpl, "GetBOSHDeploymentError").Errorf(ctx, "failed to get BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
+	if bdpl.Annotations[bdv1.AnnotationDryRun] == "true" {
+		return reconcile.Result{}, r.dryRun(ctx, bdpl)
+	}
+
 	if bdpl.Status.LastReconcile == nil {
 		now := metav1.Now()
 		bdpl.Status.LastReconcile = &now
@@ -121,6 +135,7 @@ func (r *ReconcileBOSHDeployment) Reconcile(_ context.Context, request reconcile
 
 	if meltdown.NewWindow(ReconcileSkipDuration, bdpl.Status.LastReconcile).Contains(time.Now()) {
 		log.Infof(ctx, "Meltdown in progress for '%s'", request.NamespacedName)
+		metrics.MeltdownHits.WithLabelValues(metrics.ControllerDeployment).Inc()
 		return reconcile.Result{}, nil
 	}
 
@@ -131,6 +146,12 @@ func (r *ReconcileBOSHDeployment) Reconcile(_ context.Context, request reconcile
 	now := metav1.Now()
 	bdpl.Status.StateTimestamp = &now
 	bdpl.Status.State = BDPLStateCreating
+	bdpl.Status.Message = ""
+	if bdpl.Status.ObservedGeneration != bdpl.Generation {
+		resetConditions(bdpl)
+	}
+	bdpl.Status.ObservedGeneration = bdpl.Generation
+	setCondition(bdpl, bdv1.ConditionDegraded, metav1.ConditionFalse, "Reconciling", "")
 
 	err = r.client.Status().Update(ctx, bdpl)
 	if err != nil {
@@ -138,27 +159,74 @@ func (r *ReconcileBOSHDeployment) Reconcile(_ context.Context, request reconcile
 			log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update reconcile timestamp on bdpl '%s' (%v): %s", request.NamespacedName, bdpl.ResourceVersion, err)
 	}
 
-	manifest, err := r.resolveManifest(ctx, bdpl)
+	// move the resources of deployments, which were created before the resource names included the deployment name
+	migrating, err := r.migrateLegacyNames(ctx, bdpl)
+	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "MigrationError", err)
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "MigrationError").Errorf(ctx, "failed to migrate legacy resources of BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+	if migrating {
+		// the implicit variables are read and the instance groups are deployed, after the legacy resources are moved
+		return reconcile.Result{RequeueAfter: MigrationRequeueInterval}, nil
+	}
+
+	manifest, runtimeConfigs, err := r.resolveManifest(ctx, bdpl)
 	if err != nil {
+		// e.g. a URL reference can't be downloaded or doesn't match its digest
+		bdpl.Status.Message = fmt.Sprintf("Failed to resolve the manifest: %v", err)
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionManifestResolved, "WithOpsManifestError", err)
 		return reconcile.Result{},
 			log.WithEvent(bdpl, "WithOpsManifestError").Errorf(ctx, "failed to get with-ops manifest for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
+	// record the revisions of the runtime configs, which were merged into the manifest
+	if !reflect.DeepEqual(bdpl.Status.RuntimeConfigs, runtimeConfigs) {
+		bdpl.Status.RuntimeConfigs = runtimeConfigs
+		err = r.client.Status().Update(ctx, bdpl)
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update runtime configs on bdpl '%s' (%v): %s", request.NamespacedName, bdpl.ResourceVersion, err)
+		}
+	}
+
 	// Find the required native-to-bosh links, add the properties to the manifest and error if links are missing
 	l := linkInfoService{
 		log:            logger.TraceFilter(log.ExtractLogger(ctx), "linkinfoservice"),
 		deploymentName: bdpl.Name,
 		namespace:      bdpl.Namespace,
 	}
-	linkInfos, err := l.List(ctx, r.client, manifest)
+	linkInfos, linkSecrets, err := l.List(ctx, r.client, manifest)
 	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "InstanceGroupManifestError", err)
 		return reconcile.Result{},
 			log.WithEvent(bdpl, "InstanceGroupManifestError").Errorf(ctx, "failed to find native quarks-links for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
+	// copy the link secrets of other deployments, which provide links to this deployment
+	err = r.createLinkSecrets(ctx, bdpl, linkSecrets)
+	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "LinkSecretError", err)
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "LinkSecretError").Errorf(ctx, "failed to copy link secrets for BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	// move the volumes, services and secrets of instance groups listed in migrated_from
+	migrating, err = r.migrateInstanceGroups(ctx, bdpl, manifest)
+	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "MigrationError", err)
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "MigrationError").Errorf(ctx, "failed to migrate instance groups for BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+	if migrating {
+		// the new instance groups are only deployed, after their volumes are bound to the new claims
+		return reconcile.Result{RequeueAfter: MigrationRequeueInterval}, nil
+	}
+
 	// delete qsts which are not in the manifest
 	err = r.deleteQuarksStatefulSets(ctx, manifest, bdpl)
 	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "DeleteQuarksStatefulSet", err)
 		return reconcile.Result{},
 			log.WithEvent(bdpl, "DeleteQuarksStatefulSet").Error(ctx, "failed to delete orphan QuarksStatefulSets", err)
 	}
@@ -167,6 +235,7 @@ func (r *ReconcileBOSHDeployment) Reconcile(_ context.Context, request reconcile
 	log.Debug(ctx, "Converting BOSH manifest variables to QuarksSecret resources")
 	secrets, err := r.converter.Variables(request.Namespace, bdpl.Name, manifest.Variables)
 	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionVariablesGenerated, "BadManifestError", err)
 		return reconcile.Result{},
 			log.WithEvent(bdpl, "BadManifestError").Error(ctx, errors.Wrap(err, "failed to generate quarks secrets from manifest"))
 
@@ -176,15 +245,18 @@ func (r *ReconcileBOSHDeployment) Reconcile(_ context.Context, request reconcile
 	if len(secrets) > 0 {
 		err = r.createQuarksSecrets(ctx, bdpl, secrets)
 		if err != nil {
+			updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionVariablesGenerated, "VariableGenerationError", err)
 			return reconcile.Result{},
 				log.WithEvent(bdpl, "VariableGenerationError").Errorf(ctx, "failed to create quarks secrets for BOSH manifest '%s': %v", request.NamespacedName, err)
 		}
 	}
+	metrics.QuarksSecrets.WithLabelValues(bdpl.Namespace, bdpl.Name).Set(float64(len(secrets)))
 
 	// Apply the "Instance group manifest" QuarksJob, which creates instance group manifests (ig-resolved) secrets and BPM config secrets
 	// once the "Variable Interpolation" job created the desired manifest.
 	qJob, err := r.jobFactory.InstanceGroupManifestJob(request.Namespace, bdpl.Name, *manifest, linkInfos, bdpl.ObjectMeta.Generation == 1)
 	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "InstanceGroupManifestError", err)
 		return reconcile.Result{},
 			log.WithEvent(bdpl, "InstanceGroupManifestError").Errorf(ctx, "failed to build instance group manifest qJob: %v", err)
 	}
@@ -192,6 +264,7 @@ func (r *ReconcileBOSHDeployment) Reconcile(_ context.Context, request reconcile
 	log.Debug(ctx, "Creating instance group manifest QuarksJob")
 	err = r.createQuarksJob(ctx, bdpl, qJob)
 	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionInstanceGroupsRendered, "InstanceGroupManifestError", err)
 		return reconcile.Result{},
 			log.WithEvent(bdpl, "InstanceGroupManifestError").Errorf(ctx, "failed to create instance group manifest qJob for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
@@ -200,22 +273,100 @@ func (r *ReconcileBOSHDeployment) Reconcile(_ context.Context, request reconcile
 	log.Debug(ctx, "Creating with-ops manifest secret")
 	err = r.createManifestWithOps(ctx, bdpl, *manifest)
 	if err != nil {
+		updateFailedCondition(ctx, r.client, bdpl, bdv1.ConditionManifestResolved, "WithOpsManifestError", err)
 		return reconcile.Result{},
 			log.WithEvent(bdpl, "WithOpsManifestError").Errorf(ctx, "failed to create with-ops manifest secret for BOSHDeployment '%s': %v", request.NamespacedName, err)
 	}
 
+	err = updateConditions(ctx, r.client, bdpl,
+		metav1.Condition{Type: bdv1.ConditionManifestResolved, Status: metav1.ConditionTrue, Reason: "WithOpsManifestCreated"},
+		metav1.Condition{Type: bdv1.ConditionVariablesGenerated, Status: metav1.ConditionTrue, Reason: "VariablesCreated"},
+	)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update conditions on bdpl '%s' (%v): %s", request.NamespacedName, bdpl.ResourceVersion, err)
+	}
+
 	return reconcile.Result{}, nil
 }
 
 // resolveManifest resolves manifest with ops manifest
-func (r *ReconcileBOSHDeployment) resolveManifest(ctx context.Context, bdpl *bdv1.BOSHDeployment) (*bdm.Manifest, error) {
+func (r *ReconcileBOSHDeployment) resolveManifest(ctx context.Context, bdpl *bdv1.BOSHDeployment) (*bdm.Manifest, []bdv1.RuntimeConfigStatus, error) {
 	log.Debug(ctx, "Resolving manifest")
-	manifest, err := r.withops.Manifest(ctx, bdpl, bdpl.GetNamespace())
+	start := time.Now()
+	manifest, runtimeConfigs, err := r.withops.Manifest(ctx, bdpl, bdpl.GetNamespace())
+	metrics.ManifestResolutionDuration.WithLabelValues(metrics.ManifestWithOps).Observe(time.Since(start).Seconds())
 	if err != nil {
-		return nil, log.WithEvent(bdpl, "WithOpsManifestError").Errorf(ctx, "Error resolving the manifest '%s': %s", bdpl.GetNamespacedName(), err)
+		return nil, nil, log.WithEvent(bdpl, "WithOpsManifestError").Errorf(ctx, "Error resolving the manifest '%s': %s", bdpl.GetNamespacedName(), err)
 	}
 
-	return manifest, nil
+	return manifest, runtimeConfigs, nil
+}
+
+// dryRun compares the with-ops manifest of the BOSHDeployment with the
+// currently deployed one. The changed instance groups are rendered and their
+// resources are compared with the deployed ones. The changes per instance
+// group are stored in a config map. No other resources are created or updated.
+func (r *ReconcileBOSHDeployment) dryRun(ctx context.Context, bdpl *bdv1.BOSHDeployment) error {
+	log.Infof(ctx, "Dry-run for BOSHDeployment '%s'", bdpl.GetNamespacedName())
+
+	manifest, err := r.withops.ManifestDetailed(ctx, bdpl, bdpl.GetNamespace())
+	if err != nil {
+		return log.WithEvent(bdpl, "DryRunError").Errorf(ctx, "failed to get with-ops manifest for BOSHDeployment '%s': %v", bdpl.GetNamespacedName(), err)
+	}
+
+	current, err := r.currentManifest(ctx, bdpl)
+	if err != nil {
+		return log.WithEvent(bdpl, "DryRunError").Errorf(ctx, "failed to get current manifest of BOSHDeployment '%s': %v", bdpl.GetNamespacedName(), err)
+	}
+
+	diffs, err := bdm.DiffInstanceGroups(current, manifest)
+	if err != nil {
+		return log.WithEvent(bdpl, "DryRunError").Errorf(ctx, "failed to compare manifests of BOSHDeployment '%s': %v", bdpl.GetNamespacedName(), err)
+	}
+
+	err = r.resourceChanges(ctx, bdpl, manifest, diffs)
+	if err != nil {
+		return log.WithEvent(bdpl, "DryRunError").Errorf(ctx, "failed to render instance groups of BOSHDeployment '%s': %v", bdpl.GetNamespacedName(), err)
+	}
+
+	diffBytes, err := yaml.Marshal(diffs)
+	if err != nil {
+		return log.WithEvent(bdpl, "DryRunError").Errorf(ctx, "failed to marshal changes of BOSHDeployment '%s': %v", bdpl.GetNamespacedName(), err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.DryRunConfigMapName(bdpl.Name),
+			Namespace: bdpl.Namespace,
+			Labels: map[string]string{
+				bdv1.LabelDeploymentName: bdpl.Name,
+			},
+		},
+	}
+	if err := r.setReference(bdpl, configMap, r.scheme); err != nil {
+		return log.WithEvent(bdpl, "DryRunError").Errorf(ctx, "failed to set ownerReference for ConfigMap '%s/%s': %v", bdpl.Namespace, configMap.Name, err)
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, configMap, func() error {
+		configMap.Data = map[string]string{"diff.yaml": string(diffBytes)}
+		return nil
+	})
+	if err != nil {
+		return log.WithEvent(bdpl, "DryRunError").Errorf(ctx, "failed to apply ConfigMap '%s/%s': %v", bdpl.Namespace, configMap.Name, err)
+	}
+	log.Debugf(ctx, "ConfigMap '%s/%s' has been %s", bdpl.Namespace, configMap.Name, op)
+
+	now := metav1.Now()
+	bdpl.Status.StateTimestamp = &now
+	bdpl.Status.Message = fmt.Sprintf("Dry-run: %d instance groups changed, see config map '%s'", len(diffs), configMap.Name)
+	err = r.client.Status().Update(ctx, bdpl)
+	if err != nil {
+		return log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update status of bdpl '%s' (%v): %s", bdpl.GetNamespacedName(), bdpl.ResourceVersion, err)
+	}
+
+	log.WithEvent(bdpl, "DryRun").Infof(ctx, "Stored %d instance group changes of BOSHDeployment '%s' in config map '%s'", len(diffs), bdpl.GetNamespacedName(), configMap.Name)
+	return nil
 }
 
 // createManifestWithOps creates a secret containing the deployment manifest with ops files applied
@@ -228,7 +379,7 @@ func (r *ReconcileBOSHDeployment) createManifestWithOps(ctx context.Context, bdp
 		return log.WithEvent(bdpl, "ManifestWithOpsMarshalError").Errorf(ctx, "Error marshaling the manifest '%s': %s", bdpl.GetNamespacedName(), err)
 	}
 
-	manifestSecretName := bdv1.DeploymentSecretTypeManifestWithOps.String()
+	manifestSecretName := names.DeploymentSecretName(bdv1.DeploymentSecretTypeManifestWithOps, bdpl.Name)
 
 	// Create a secret object for the manifest
 	manifestSecret := &corev1.Secret{
@@ -277,6 +428,25 @@ func (r *ReconcileBOSHDeployment) createQuarksJob(ctx context.Context, bdpl *bdv
 	return err
 }
 
+// createLinkSecrets applies the copies of the link secrets, which are consumed from other deployments
+func (r *ReconcileBOSHDeployment) createLinkSecrets(ctx context.Context, bdpl *bdv1.BOSHDeployment, secrets []corev1.Secret) error {
+	for i := range secrets {
+		secret := &secrets[i]
+		if err := r.setReference(bdpl, secret, r.scheme); err != nil {
+			return errors.Errorf("failed to set ownerReference for Secret '%s/%s': %v", bdpl.Namespace, secret.Name, err)
+		}
+
+		op, err := controllerutil.CreateOrUpdate(ctx, r.client, secret, mutateqs.SecretMutateFn(secret))
+		if err != nil {
+			return errors.Wrapf(err, "creating or updating Secret '%s/%s'", bdpl.Namespace, secret.Name)
+		}
+
+		log.Debugf(ctx, "Link secret '%s/%s' has been %s", bdpl.Namespace, secret.Name, op)
+	}
+
+	return nil
+}
+
 // createQuarksSecrets create variables quarksSecrets
 func (r *ReconcileBOSHDeployment) createQuarksSecrets(ctx context.Context, bdpl *bdv1.BOSHDeployment, variables []qsv1a1.QuarksSecret) error {
 
@@ -335,7 +505,10 @@ func (r *ReconcileBOSHDeployment) deleteQuarksStatefulSets(ctx context.Context,
 		// delete all associated services
 		services := &corev1.ServiceList{}
 		name := qsts.Labels[bdv1.LabelInstanceGroupName]
-		labels := map[string]string{bdv1.LabelInstanceGroupName: name}
+		labels := map[string]string{
+			bdv1.LabelDeploymentName:    bdpl.Name,
+			bdv1.LabelInstanceGroupName: name,
+		}
 		err := r.client.List(ctx, services, client.InNamespace(bdpl.Namespace), client.MatchingLabels(labels))
 		if err != nil {
 			return errors.Wrapf(err, "failed to list services for instance group %s", name)
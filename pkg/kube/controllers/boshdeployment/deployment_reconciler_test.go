@@ -14,6 +14,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -31,6 +32,7 @@ import (
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
 	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	withopsutil "code.cloudfoundry.org/quarks-operator/pkg/kube/util/withops"
 	qsv1a1 "code.cloudfoundry.org/quarks-secret/pkg/kube/apis/quarkssecret/v1alpha1"
 	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -47,6 +49,7 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 		withops        fakes.FakeWithOps
 		jobFactory     fakes.FakeJobFactory
 		kubeConverter  fakes.FakeVariablesConverter
+		bpmConverter   fakes.FakeBPMConverter
 		manifest       *bdm.Manifest
 		log            *zap.SugaredLogger
 		logs           *observer.ObservedLogs
@@ -67,6 +70,7 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 		withops = fakes.FakeWithOps{}
 		jobFactory = fakes.FakeJobFactory{}
 		kubeConverter = fakes.FakeVariablesConverter{}
+		bpmConverter = fakes.FakeBPMConverter{}
 		kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{}, nil)
 
 		deploymentName = "foo"
@@ -177,10 +181,10 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 	})
 
 	JustBeforeEach(func() {
-		withops.ManifestReturns(manifest, nil)
+		withops.ManifestReturns(manifest, nil, nil)
 		reconciler = cfd.NewDeploymentReconciler(
 			ctx, config, manager,
-			&withops, &jobFactory, &kubeConverter,
+			&withops, &jobFactory, &kubeConverter, &bpmConverter,
 			controllerutil.SetControllerReference,
 		)
 	})
@@ -256,7 +260,7 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 			})
 
 			It("handles an error when resolving the BOSHDeployment", func() {
-				withops.ManifestReturns(nil, fmt.Errorf("resolver error"))
+				withops.ManifestReturns(nil, nil, fmt.Errorf("resolver error"))
 
 				_, err := reconciler.Reconcile(context.Background(), request)
 				Expect(err).To(HaveOccurred())
@@ -265,12 +269,94 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 				// check for events
 				Expect(<-recorder.Events).To(ContainSubstring("WithOpsManifestError"))
 			})
+
+			It("sets the error as status message", func() {
+				statusWriter := &fakes.FakeStatusWriter{}
+				client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+				withops.ManifestReturns(nil, nil, &withopsutil.DigestMismatchError{URL: "https://example.com/manifest.yml", Expected: "abc", Actual: "def"})
+
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).To(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(2))
+				_, object, _ := statusWriter.UpdateArgsForCall(1)
+				Expect(object.(*bdv1.BOSHDeployment).Status.Message).To(ContainSubstring("sha256 digest mismatch for url 'https://example.com/manifest.yml'"))
+			})
+
+			It("sets the failed condition", func() {
+				statusWriter := &fakes.FakeStatusWriter{}
+				client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+				withops.ManifestReturns(nil, nil, fmt.Errorf("resolver error"))
+
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).To(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(2))
+				_, object, _ := statusWriter.UpdateArgsForCall(1)
+				bdpl := object.(*bdv1.BOSHDeployment)
+				condition := meta.FindStatusCondition(bdpl.Status.Conditions, bdv1.ConditionManifestResolved)
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal("WithOpsManifestError"))
+				Expect(meta.IsStatusConditionTrue(bdpl.Status.Conditions, bdv1.ConditionDegraded)).To(BeTrue())
+				Expect(meta.FindStatusCondition(bdpl.Status.Conditions, bdv1.ConditionReady).Reason).To(Equal("WithOpsManifestError"))
+			})
 		})
 
 		Context("when the manifest can be resolved", func() {
+			It("records the revisions of the merged runtime configs in the status", func() {
+				runtimeConfigs := []bdv1.RuntimeConfigStatus{{Name: "syslog", Generation: 3}}
+				withops.ManifestReturns(manifest, runtimeConfigs, nil)
+
+				statusWriter := &fakes.FakeStatusWriter{}
+				client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusWriter.UpdateCallCount()).To(BeNumerically(">=", 2))
+				_, object, _ := statusWriter.UpdateArgsForCall(1)
+				Expect(object.(*bdv1.BOSHDeployment).Status.RuntimeConfigs).To(Equal(runtimeConfigs))
+			})
+
+			It("resets the conditions of the previous generation", func() {
+				instance.Generation = 2
+				instance.Status.ObservedGeneration = 1
+				for _, t := range []string{
+					bdv1.ConditionManifestResolved,
+					bdv1.ConditionVariablesGenerated,
+					bdv1.ConditionInstanceGroupsRendered,
+					bdv1.ConditionInstanceGroupsReady,
+					bdv1.ConditionReady,
+				} {
+					meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+						Type: t, Status: metav1.ConditionTrue, ObservedGeneration: 1, Reason: "Deployed",
+					})
+				}
+
+				statusWriter := &fakes.FakeStatusWriter{}
+				client.StatusCalls(func() crc.StatusWriter { return statusWriter })
+
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, object, _ := statusWriter.UpdateArgsForCall(statusWriter.UpdateCallCount() - 1)
+				bdpl := object.(*bdv1.BOSHDeployment)
+				Expect(bdpl.Status.ObservedGeneration).To(Equal(int64(2)))
+
+				condition := meta.FindStatusCondition(bdpl.Status.Conditions, bdv1.ConditionInstanceGroupsReady)
+				Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+				Expect(condition.Reason).To(Equal("Reconciling"))
+				Expect(condition.ObservedGeneration).To(Equal(int64(2)))
+
+				ready := meta.FindStatusCondition(bdpl.Status.Conditions, bdv1.ConditionReady)
+				Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+				Expect(ready.Reason).To(Equal("Reconciling"))
+				Expect(ready.ObservedGeneration).To(Equal(int64(2)))
+			})
+
 			It("handles an error when resolving manifest", func() {
 				manifest = &bdm.Manifest{}
-				withops.ManifestReturns(manifest, errors.New("fake-error"))
+				withops.ManifestReturns(manifest, nil, errors.New("fake-error"))
 
 				_, err := reconciler.Reconcile(context.Background(), request)
 				Expect(err).To(HaveOccurred())
@@ -278,7 +364,7 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 			})
 
 			It("handles an error when setting the owner reference on the object", func() {
-				reconciler = cfd.NewDeploymentReconciler(ctx, config, manager, &withops, &jobFactory, &kubeConverter,
+				reconciler = cfd.NewDeploymentReconciler(ctx, config, manager, &withops, &jobFactory, &kubeConverter, &bpmConverter,
 					func(owner, object metav1.Object, scheme *runtime.Scheme) error {
 						return fmt.Errorf("some error")
 					},
@@ -297,7 +383,7 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 					case *qjv1a1.QuarksJob:
 						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
 					case *corev1.Secret:
-						if nn.Name == "with-ops" {
+						if nn.Name == deploymentName+".with-ops" {
 							return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
 						}
 					}
@@ -322,7 +408,7 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 				By("From created state to ops applied state")
 				_, err := reconciler.Reconcile(context.Background(), request)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to create with-ops manifest secret for BOSHDeployment 'default/foo': failed to apply Secret 'default/with-ops': fake-error"))
+				Expect(err.Error()).To(ContainSubstring("failed to create with-ops manifest secret for BOSHDeployment 'default/foo': failed to apply Secret 'default/foo.with-ops': fake-error"))
 			})
 
 			It("handles an error generating the new variable secrets", func() {
@@ -552,5 +638,75 @@ var _ = Describe("ReconcileBoshDeployment", func() {
 				})
 			})
 		})
+
+		Context("when the dry-run annotation is set", func() {
+			var (
+				statusWriter fakes.FakeStatusWriter
+				configMap    *corev1.ConfigMap
+			)
+
+			BeforeEach(func() {
+				instance.Annotations = map[string]string{bdv1.AnnotationDryRun: "true"}
+
+				current, err := manifest.Marshal()
+				Expect(err).NotTo(HaveOccurred())
+
+				desired, err := bdm.LoadYAML(current)
+				Expect(err).NotTo(HaveOccurred())
+				desired.InstanceGroups[0].Instances = 2
+				withops.ManifestDetailedReturns(desired, nil)
+
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object crc.Object) error {
+					switch object := object.(type) {
+					case *bdv1.BOSHDeployment:
+						instance.DeepCopyInto(object)
+					case *corev1.Secret:
+						object.Data = map[string][]byte{"manifest.yaml": current}
+					case *corev1.ConfigMap:
+						return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+					}
+					return nil
+				})
+				client.CreateCalls(func(context context.Context, object crc.Object, _ ...crc.CreateOption) error {
+					if cm, ok := object.(*corev1.ConfigMap); ok {
+						configMap = cm
+					}
+					return nil
+				})
+
+				statusWriter = fakes.FakeStatusWriter{}
+				client.StatusCalls(func() crc.StatusWriter { return &statusWriter })
+			})
+
+			It("stores the changes in a config map without deploying", func() {
+				result, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal(reconcile.Result{}))
+
+				Expect(withops.ManifestCallCount()).To(Equal(0))
+				Expect(jobFactory.InstanceGroupManifestJobCallCount()).To(Equal(0))
+				Expect(kubeConverter.VariablesCallCount()).To(Equal(0))
+				Expect(client.CreateCallCount()).To(Equal(1))
+
+				Expect(configMap.Name).To(Equal("foo.dry-run"))
+				Expect(configMap.Data["diff.yaml"]).To(ContainSubstring("name: fakepod"))
+				Expect(configMap.Data["diff.yaml"]).To(ContainSubstring("from: 0"))
+				Expect(configMap.Data["diff.yaml"]).To(ContainSubstring("to: 2"))
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, object, _ := statusWriter.UpdateArgsForCall(0)
+				Expect(object.(*bdv1.BOSHDeployment).Status.Message).To(ContainSubstring("Dry-run: 1 instance groups changed"))
+				Expect(<-recorder.Events).To(ContainSubstring("DryRun"))
+			})
+
+			It("handles an error when resolving the manifest", func() {
+				withops.ManifestDetailedReturns(nil, errors.New("fake-error"))
+
+				_, err := reconciler.Reconcile(context.Background(), request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to get with-ops manifest for BOSHDeployment 'default/foo': fake-error"))
+				Expect(<-recorder.Events).To(ContainSubstring("DryRunError"))
+			})
+		})
 	})
 })
@@ -0,0 +1,228 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	qstscontroller "code.cloudfoundry.org/quarks-statefulset/pkg/kube/controllers/quarksstatefulset"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+const (
+	// annotationDefaultStorageClass marks the default storage class of the cluster
+	annotationDefaultStorageClass = "storageclass.kubernetes.io/is-default-class"
+	// annotationBetaDefaultStorageClass is the deprecated annotation for the default storage class
+	annotationBetaDefaultStorageClass = "storageclass.beta.kubernetes.io/is-default-class"
+	// diskResizeRequeueAfter is used to check on StatefulSets, which are deleted for a disk resize
+	diskResizeRequeueAfter = 5 * time.Second
+)
+
+// diskResize is a volume claim template, whose storage request grew
+type diskResize struct {
+	template corev1.PersistentVolumeClaim
+	from     resource.Quantity
+	to       resource.Quantity
+}
+
+// resizePersistentDisks expands the persistent volume claims of the
+// instance group's StatefulSets, if the storage requested by the volume
+// claim templates of the QuarksStatefulSet grew. StatefulSets don't allow
+// changes to their volume claim templates, so they are deleted without their
+// pods and recreated by the QuarksStatefulSet controller. The steps are
+// recorded in the PersistentDisksResized condition of the BOSHDeployment.
+// It returns true until the deleted StatefulSets are gone, the
+// QuarksStatefulSet must not be updated before.
+func resizePersistentDisks(ctx context.Context, c client.Client, bdpl *bdv1.BOSHDeployment, qSts *qstsv1a1.QuarksStatefulSet) (bool, error) {
+	existing := &qstsv1a1.QuarksStatefulSet{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: qSts.Namespace, Name: qSts.Name}, existing)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to get QuarksStatefulSet '%s/%s'", qSts.Namespace, qSts.Name)
+	}
+
+	statefulSets, _, err := qstscontroller.GetMaxStatefulSetVersion(ctx, c, existing)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get statefulsets of QuarksStatefulSet '%s'", existing.GetNamespacedName())
+	}
+
+	deleting := false
+	steps := []string{}
+	for _, sts := range statefulSets {
+		// the default statefulset, if none exists yet
+		if sts.Name == "" {
+			continue
+		}
+
+		resizes, err := diskResizes(sts, qSts.Spec.Template.Spec.VolumeClaimTemplates)
+		if err != nil {
+			return false, failResize(ctx, c, bdpl, "PersistentDiskShrinkError", err)
+		}
+		if len(resizes) == 0 {
+			continue
+		}
+
+		for _, resize := range resizes {
+			class, err := storageClass(ctx, c, resize.template.Spec.StorageClassName)
+			if err != nil {
+				return false, failResize(ctx, c, bdpl, "PersistentDiskResizeError", err)
+			}
+			if class.AllowVolumeExpansion == nil || !*class.AllowVolumeExpansion {
+				err := errors.Errorf("storage class '%s' of volume claim template '%s' in StatefulSet '%s/%s' does not allow volume expansion", class.Name, resize.template.Name, sts.Namespace, sts.Name)
+				return false, failResize(ctx, c, bdpl, "StorageClassNotExpandable", err)
+			}
+
+			count, err := expandClaims(ctx, c, sts, resize)
+			if err != nil {
+				return false, failResize(ctx, c, bdpl, "PersistentDiskResizeError", err)
+			}
+
+			step := fmt.Sprintf("expanded %d claims of template '%s' in StatefulSet '%s' from %s to %s", count, resize.template.Name, sts.Name, resize.from.String(), resize.to.String())
+			log.WithEvent(bdpl, "ExpandPersistentVolumeClaims").Infof(ctx, "BOSHDeployment '%s' %s", bdpl.GetNamespacedName(), step)
+			steps = append(steps, step)
+		}
+
+		// The pods keep running and are adopted by the recreated StatefulSet
+		if sts.DeletionTimestamp == nil {
+			err = c.Delete(ctx, sts, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+			if err != nil && !apierrors.IsNotFound(err) {
+				err = errors.Wrapf(err, "failed to delete StatefulSet '%s/%s'", sts.Namespace, sts.Name)
+				return false, failResize(ctx, c, bdpl, "PersistentDiskResizeError", err)
+			}
+
+			step := fmt.Sprintf("recreating StatefulSet '%s'", sts.Name)
+			log.WithEvent(bdpl, "RecreateStatefulSet").Infof(ctx, "BOSHDeployment '%s' %s", bdpl.GetNamespacedName(), step)
+			steps = append(steps, step)
+		}
+
+		// The orphan finalizer keeps the StatefulSet, until its pods are released
+		err = c.Get(ctx, types.NamespacedName{Namespace: sts.Namespace, Name: sts.Name}, &appsv1.StatefulSet{})
+		if err == nil {
+			log.Infof(ctx, "Waiting for StatefulSet '%s/%s' to be deleted", sts.Namespace, sts.Name)
+			deleting = true
+		} else if !apierrors.IsNotFound(err) {
+			err = errors.Wrapf(err, "failed to get StatefulSet '%s/%s'", sts.Namespace, sts.Name)
+			return false, failResize(ctx, c, bdpl, "PersistentDiskResizeError", err)
+		}
+	}
+
+	if len(steps) == 0 {
+		return deleting, nil
+	}
+
+	return deleting, updateConditions(ctx, c, bdpl, metav1.Condition{
+		Type:    bdv1.ConditionPersistentDisksResized,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PersistentDisksExpanded",
+		Message: fmt.Sprintf("Instance group '%s': %s", existing.Labels[bdv1.LabelInstanceGroupName], strings.Join(steps, ", ")),
+	})
+}
+
+// failResize records the failed resize on the BOSHDeployment status
+func failResize(ctx context.Context, c client.Client, bdpl *bdv1.BOSHDeployment, reason string, err error) error {
+	updateFailedCondition(ctx, c, bdpl, bdv1.ConditionPersistentDisksResized, reason, err)
+	return err
+}
+
+// diskResizes compares the volume claim templates of the StatefulSet with the
+// desired ones. Shrinking a volume is not supported.
+func diskResizes(sts *appsv1.StatefulSet, desired []corev1.PersistentVolumeClaim) ([]diskResize, error) {
+	resizes := []diskResize{}
+	for _, current := range sts.Spec.VolumeClaimTemplates {
+		for _, template := range desired {
+			if template.Name != current.Name {
+				continue
+			}
+
+			from := current.Spec.Resources.Requests[corev1.ResourceStorage]
+			to := template.Spec.Resources.Requests[corev1.ResourceStorage]
+			switch to.Cmp(from) {
+			case -1:
+				return resizes, errors.Errorf("shrinking volume claim template '%s' in StatefulSet '%s/%s' from %s to %s is not supported", current.Name, sts.Namespace, sts.Name, from.String(), to.String())
+			case 1:
+				resizes = append(resizes, diskResize{template: current, from: from, to: to})
+			}
+		}
+	}
+	return resizes, nil
+}
+
+// storageClass returns the named storage class or the default storage class
+func storageClass(ctx context.Context, c client.Client, name *string) (*storagev1.StorageClass, error) {
+	if name != nil && *name != "" {
+		class := &storagev1.StorageClass{}
+		err := c.Get(ctx, types.NamespacedName{Name: *name}, class)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get storage class '%s'", *name)
+		}
+		return class, nil
+	}
+
+	classes := &storagev1.StorageClassList{}
+	err := c.List(ctx, classes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list storage classes")
+	}
+	for i := range classes.Items {
+		annotations := classes.Items[i].Annotations
+		if annotations[annotationDefaultStorageClass] == "true" || annotations[annotationBetaDefaultStorageClass] == "true" {
+			return &classes.Items[i], nil
+		}
+	}
+	return nil, errors.New("no default storage class found")
+}
+
+// expandClaims sets the new storage request on all claims created from the
+// template for the StatefulSet, i.e. `<template>-<statefulset>-<ordinal>`
+func expandClaims(ctx context.Context, c client.Client, sts *appsv1.StatefulSet, resize diskResize) (int, error) {
+	claims := &corev1.PersistentVolumeClaimList{}
+	err := c.List(ctx, claims, client.InNamespace(sts.Namespace))
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to list persistent volume claims of StatefulSet '%s/%s'", sts.Namespace, sts.Name)
+	}
+
+	count := 0
+	prefix := resize.template.Name + "-" + sts.Name + "-"
+	for i := range claims.Items {
+		pvc := &claims.Items[i]
+		if !strings.HasPrefix(pvc.Name, prefix) {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimPrefix(pvc.Name, prefix)); err != nil {
+			continue
+		}
+
+		request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if request.Cmp(resize.to) >= 0 {
+			continue
+		}
+
+		if pvc.Spec.Resources.Requests == nil {
+			pvc.Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = resize.to
+		err = c.Update(ctx, pvc)
+		if err != nil {
+			return count, errors.Wrapf(err, "failed to expand persistent volume claim '%s/%s'", pvc.Namespace, pvc.Name)
+		}
+		count++
+	}
+
+	return count, nil
+}
@@ -0,0 +1,267 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Persistent disk resize", func() {
+	var (
+		ctx           context.Context
+		recorder      *record.FakeRecorder
+		manager       *fakes.FakeManager
+		resolver      fakes.FakeDesiredManifest
+		converter     fakes.FakeBPMConverter
+		client        crc.Client
+		objects       []crc.Object
+		statefulSet   *appsv1.StatefulSet
+		desiredSize   string
+		storageClass  string
+		reconcileBPM  func() error
+		reconcileRes  func() reconcile.Result
+		getDeployment func() *bdv1.BOSHDeployment
+	)
+
+	claimTemplate := func(size string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-pvc"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &storageClass,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+				},
+			},
+		}
+	}
+
+	claim := func(name string) *corev1.PersistentVolumeClaim {
+		pvc := claimTemplate("1Gi")
+		pvc.Name = name
+		pvc.Namespace = "default"
+		return &pvc
+	}
+
+	BeforeEach(func() {
+		_ = controllers.AddToScheme(scheme.Scheme)
+		recorder = record.NewFakeRecorder(20)
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+		resolver = fakes.FakeDesiredManifest{}
+		resolver.DesiredManifestReturns(&bdm.Manifest{
+			InstanceGroups: []*bdm.InstanceGroup{{Name: "db", Instances: 2}},
+		}, nil)
+		converter = fakes.FakeBPMConverter{}
+
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+
+		desiredSize = "2Gi"
+		storageClass = "expandable"
+
+		qsts := &qstsv1a1.QuarksStatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "db",
+				Namespace: "default",
+				UID:       "qsts-uid",
+				Labels:    map[string]string{bdv1.LabelDeploymentName: "foo", bdv1.LabelInstanceGroupName: "db"},
+			},
+		}
+		statefulSet = &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "db",
+				Namespace:   "default",
+				Annotations: map[string]string{qstsv1a1.AnnotationVersion: "1"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: qstsv1a1.SchemeGroupVersion.String(),
+					Kind:       "QuarksStatefulSet",
+					Name:       "db",
+					UID:        "qsts-uid",
+					Controller: pointers.Bool(true),
+				}},
+			},
+			Spec: appsv1.StatefulSetSpec{
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{claimTemplate("1Gi")},
+			},
+		}
+		versionedLabels := map[string]string{
+			bdv1.LabelDeploymentName: "foo",
+			qjv1a1.LabelRemoteID:     "db",
+			vss.LabelSecretKind:      vss.VersionSecretKind,
+			vss.LabelVersion:         "1",
+		}
+		objects = []crc.Object{
+			&bdv1.BOSHDeployment{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo.bpm.db-v1", Namespace: "default", Labels: versionedLabels},
+				Data:       map[string][]byte{"bpm.yaml": []byte("{}")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo.ig-resolved.db-v1", Namespace: "default", Labels: versionedLabels},
+			},
+			qsts,
+			claim("db-pvc-db-0"),
+			claim("db-pvc-db-1"),
+			claim("db-pvc-db-z0-0"),
+			&storagev1.StorageClass{
+				ObjectMeta:           metav1.ObjectMeta{Name: "expandable"},
+				AllowVolumeExpansion: pointers.Bool(true),
+			},
+			&storagev1.StorageClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "fixed"},
+			},
+		}
+
+		reconcileBPM = func() error {
+			reconciler := cfd.NewBPMReconciler(ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager, &resolver, controllerutil.SetControllerReference, &converter)
+			_, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo.bpm.db-v1"},
+			})
+			return err
+		}
+
+		reconcileRes = func() reconcile.Result {
+			reconciler := cfd.NewBPMReconciler(ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager, &resolver, controllerutil.SetControllerReference, &converter)
+			result, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo.bpm.db-v1"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			return result
+		}
+
+		getDeployment = func() *bdv1.BOSHDeployment {
+			bdpl := &bdv1.BOSHDeployment{}
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo"}, bdpl)).To(Succeed())
+			return bdpl
+		}
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(append(objects, statefulSet)...).Build()
+		manager.GetClientReturns(client)
+
+		qsts := qstsv1a1.QuarksStatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "db",
+				Namespace: "default",
+				Labels:    map[string]string{bdv1.LabelDeploymentName: "foo", bdv1.LabelInstanceGroupName: "db"},
+			},
+		}
+		qsts.Spec.Template.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{claimTemplate(desiredSize)}
+		converter.ResourcesReturns(&bpmconverter.Resources{InstanceGroups: []qstsv1a1.QuarksStatefulSet{qsts}}, nil)
+	})
+
+	It("expands the claims of the statefulset and deletes it", func() {
+		Expect(reconcileRes().RequeueAfter).To(BeZero())
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		for _, name := range []string{"db-pvc-db-0", "db-pvc-db-1"} {
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, pvc)).To(Succeed())
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("2Gi"))
+		}
+		Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "db-pvc-db-z0-0"}, pvc)).To(Succeed())
+		Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("1Gi"))
+
+		err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "db"}, &appsv1.StatefulSet{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		condition := meta.FindStatusCondition(getDeployment().Status.Conditions, bdv1.ConditionPersistentDisksResized)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring("expanded 2 claims of template 'db-pvc' in StatefulSet 'db' from 1Gi to 2Gi"))
+		Expect(condition.Message).To(ContainSubstring("recreating StatefulSet 'db'"))
+	})
+
+	Context("when the statefulset is still being deleted", func() {
+		BeforeEach(func() {
+			now := metav1.Now()
+			statefulSet.DeletionTimestamp = &now
+			statefulSet.Finalizers = []string{metav1.FinalizerOrphanDependents}
+		})
+
+		It("requeues before updating the QuarksStatefulSet", func() {
+			Expect(reconcileRes().RequeueAfter).To(BeNumerically(">", 0))
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "db-pvc-db-0"}, pvc)).To(Succeed())
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("2Gi"))
+		})
+	})
+
+	Context("when the size didn't change", func() {
+		BeforeEach(func() {
+			desiredSize = "1Gi"
+		})
+
+		It("doesn't touch the statefulset", func() {
+			Expect(reconcileBPM()).To(Succeed())
+
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "db"}, &appsv1.StatefulSet{})).To(Succeed())
+			Expect(meta.FindStatusCondition(getDeployment().Status.Conditions, bdv1.ConditionPersistentDisksResized)).To(BeNil())
+		})
+	})
+
+	Context("when the size shrinks", func() {
+		BeforeEach(func() {
+			desiredSize = "512Mi"
+		})
+
+		It("reports the failure on the deployment", func() {
+			err := reconcileBPM()
+			Expect(err).To(MatchError(ContainSubstring("shrinking volume claim template 'db-pvc' in StatefulSet 'default/db' from 1Gi to 512Mi is not supported")))
+
+			condition := meta.FindStatusCondition(getDeployment().Status.Conditions, bdv1.ConditionPersistentDisksResized)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("PersistentDiskShrinkError"))
+		})
+	})
+
+	Context("when the storage class doesn't allow volume expansion", func() {
+		BeforeEach(func() {
+			storageClass = "fixed"
+		})
+
+		It("reports the failure on the deployment", func() {
+			err := reconcileBPM()
+			Expect(err).To(MatchError(ContainSubstring("storage class 'fixed' of volume claim template 'db-pvc' in StatefulSet 'default/db' does not allow volume expansion")))
+
+			condition := meta.FindStatusCondition(getDeployment().Status.Conditions, bdv1.ConditionPersistentDisksResized)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("StorageClassNotExpandable"))
+
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "db"}, &appsv1.StatefulSet{})).To(Succeed())
+		})
+	})
+})
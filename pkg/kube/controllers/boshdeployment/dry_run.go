@@ -0,0 +1,184 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/mutate"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+// resourceChanges renders the changed instance groups of the with-ops
+// manifest with their latest BPM information and adds the changes of the
+// resulting QuarksStatefulSets, Services and QuarksJobs to their diffs.
+// Instance groups, which were not rendered before, are skipped.
+func (r *ReconcileBOSHDeployment) resourceChanges(ctx context.Context, bdpl *bdv1.BOSHDeployment, manifest *bdm.Manifest, diffs []bdm.InstanceGroupDiff) error {
+	changed := false
+	for _, diff := range diffs {
+		changed = changed || (!diff.Added && !diff.Removed)
+	}
+	if !changed {
+		return nil
+	}
+
+	manifestBytes, err := manifest.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal with-ops manifest")
+	}
+	desiredBytes, err := r.withops.InterpolateVariableFromSecrets(ctx, manifestBytes, bdpl.Namespace, bdpl.Name)
+	if err != nil {
+		return errors.Wrap(err, "failed to interpolate variables")
+	}
+	desired, err := bdm.LoadYAML(desiredBytes)
+	if err != nil {
+		return errors.Wrap(err, "failed to load desired manifest")
+	}
+
+	dns, err := podDNS(ctx, r.client, bdpl, desired)
+	if err != nil {
+		return err
+	}
+	cloudConfig, err := cloudConfigSpec(ctx, r.client, bdpl)
+	if err != nil {
+		return err
+	}
+	bpmSecrets, err := latestBPMSecrets(ctx, r.client, bdpl.Namespace, bdpl.Name)
+	if err != nil {
+		return err
+	}
+	store := versionedsecretstore.NewVersionedSecretStore(r.client)
+
+	for i := range diffs {
+		diff := &diffs[i]
+		if diff.Added || diff.Removed {
+			continue
+		}
+		bpmSecret, ok := bpmSecrets[diff.Name]
+		if !ok {
+			continue
+		}
+		bpmInfo, err := bpmInfoFromSecret(&bpmSecret)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read BPM information of instance group '%s'", diff.Name)
+		}
+		instanceGroup, found := desired.InstanceGroups.InstanceGroupByName(diff.Name)
+		if !found {
+			continue
+		}
+
+		igResolvedSecretVersion, err := igResolvedVersion(ctx, store, bdpl.Namespace, bdpl.Name, diff.Name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		qStsVersion, err := r.deployedVersion(ctx, bdpl, diff.Name)
+		if err != nil {
+			return err
+		}
+
+		resources, err := convertInstanceGroup(r.bpmConverter, bdpl, instanceGroup, &bpmSecret, bpmInfo, desired, dns, cloudConfig, qStsVersion, igResolvedSecretVersion)
+		if err != nil {
+			return errors.Wrapf(err, "failed to convert instance group '%s'", diff.Name)
+		}
+		if resources == nil {
+			continue
+		}
+
+		diff.Resources, err = r.compareResources(ctx, resources)
+		if err != nil {
+			return errors.Wrapf(err, "failed to compare resources of instance group '%s'", diff.Name)
+		}
+	}
+
+	return nil
+}
+
+// deployedVersion returns the deployment version of the instance group's
+// QuarksStatefulSet, so unchanged resources are rendered identically
+func (r *ReconcileBOSHDeployment) deployedVersion(ctx context.Context, bdpl *bdv1.BOSHDeployment, instanceGroupName string) (string, error) {
+	qSts := &qstsv1a1.QuarksStatefulSet{}
+	name := names.QuarksStatefulSetName(bdpl.Name, instanceGroupName)
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: bdpl.Namespace, Name: name}, qSts)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "0", nil
+		}
+		return "", errors.Wrapf(err, "failed to get QuarksStatefulSet '%s/%s'", bdpl.Namespace, name)
+	}
+	return qSts.Labels[bdv1.LabelDeploymentVersion], nil
+}
+
+// compareResources returns the resources, which would be created or updated
+// by the BPM controller
+func (r *ReconcileBOSHDeployment) compareResources(ctx context.Context, resources *bpmconverter.Resources) ([]bdm.ResourceChange, error) {
+	changes := []bdm.ResourceChange{}
+	add := func(kind string, obj client.Object, mutateFn controllerutil.MutateFn) error {
+		change, err := r.resourceChange(ctx, kind, obj, mutateFn)
+		if change != nil {
+			changes = append(changes, *change)
+		}
+		return err
+	}
+
+	for i := range resources.InstanceGroups {
+		qSts := resources.InstanceGroups[i].DeepCopy()
+		if err := add(qstsv1a1.QuarksStatefulSetResourceKind, qSts, mutate.QuarksStatefulSetMutateFn(qSts)); err != nil {
+			return changes, err
+		}
+	}
+	for i := range resources.Services {
+		svc := resources.Services[i].DeepCopy()
+		if err := add("Service", svc, mutate.ServiceMutateFn(svc)); err != nil {
+			return changes, err
+		}
+	}
+	for i := range resources.Errands {
+		qJob := resources.Errands[i].DeepCopy()
+		if err := add(qjv1a1.QuarksJobResourceKind, qJob, mutate.QuarksJobMutateFn(qJob)); err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+}
+
+// resourceChange reads the deployed object into obj and applies the mutate
+// function like controllerutil.CreateOrUpdate, without updating the object
+func (r *ReconcileBOSHDeployment) resourceChange(ctx context.Context, kind string, obj client.Object, mutateFn controllerutil.MutateFn) (*bdm.ResourceChange, error) {
+	key := client.ObjectKeyFromObject(obj)
+	err := r.client.Get(ctx, key, obj)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return &bdm.ResourceChange{Kind: kind, Name: key.Name, Change: "added"}, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get %s '%s'", kind, key)
+	}
+
+	deployed := obj.DeepCopyObject()
+	if err := mutateFn(); err != nil {
+		return nil, errors.Wrapf(err, "failed to mutate %s '%s'", kind, key)
+	}
+
+	paths, err := bdm.ChangedPaths(deployed, obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	return &bdm.ResourceChange{Kind: kind, Name: key.Name, Change: "changed", Paths: paths}, nil
+}
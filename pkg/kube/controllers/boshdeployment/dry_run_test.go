@@ -0,0 +1,222 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Dry-run", func() {
+	var (
+		ctx          context.Context
+		recorder     *record.FakeRecorder
+		manager      *fakes.FakeManager
+		withops      fakes.FakeWithOps
+		bpmConverter fakes.FakeBPMConverter
+		client       crc.Client
+		objects      []crc.Object
+		request      reconcile.Request
+	)
+
+	qSts := func(replicas int32) qstsv1a1.QuarksStatefulSet {
+		q := qstsv1a1.QuarksStatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-nats",
+				Namespace: "default",
+				Labels:    map[string]string{bdv1.LabelDeploymentVersion: "3"},
+			},
+		}
+		q.Spec.Template.Spec.Replicas = pointer.Int32Ptr(replicas)
+		return q
+	}
+
+	service := func(name string) corev1.Service {
+		return corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.ServiceSpec{
+				Ports:    []corev1.ServicePort{{Name: "nats", Port: 4222}},
+				Selector: map[string]string{bdv1.LabelInstanceGroupName: "nats"},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		_ = controllers.AddToScheme(scheme.Scheme)
+		recorder = record.NewFakeRecorder(20)
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}
+
+		current := &bdm.Manifest{InstanceGroups: []*bdm.InstanceGroup{{Name: "nats", Instances: 1}}}
+		currentBytes, err := current.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+		desired := &bdm.Manifest{InstanceGroups: []*bdm.InstanceGroup{{Name: "nats", Instances: 2}}}
+		desiredBytes, err := desired.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+
+		withops = fakes.FakeWithOps{}
+		withops.ManifestDetailedReturns(desired, nil)
+		withops.InterpolateVariableFromSecretsReturns(desiredBytes, nil)
+
+		existing := qSts(1)
+		existingService := service("foo-nats-0")
+		objects = []crc.Object{
+			&bdv1.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "default",
+					Annotations: map[string]string{bdv1.AnnotationDryRun: "true"},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      names.DeploymentSecretName(bdv1.DeploymentSecretTypeManifestWithOps, "foo"),
+					Namespace: "default",
+				},
+				Data: map[string][]byte{"manifest.yaml": currentBytes},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      bdv1.DeploymentSecretBPMInformation.Prefix("foo") + "nats-v1",
+					Namespace: "default",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName:       "foo",
+						bdv1.LabelDeploymentSecretType: bdv1.DeploymentSecretBPMInformation.String(),
+						qjv1a1.LabelRemoteID:           "nats",
+						vss.LabelSecretKind:            vss.VersionSecretKind,
+						vss.LabelVersion:               "1",
+					},
+				},
+				Data: map[string][]byte{"bpm.yaml": []byte("instance_group:\n  name: nats\n")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      names.InstanceGroupSecretName("foo", "nats", "1"),
+					Namespace: "default",
+					Labels: map[string]string{
+						vss.LabelSecretKind: vss.VersionSecretKind,
+						vss.LabelVersion:    "1",
+					},
+				},
+			},
+			&existing,
+			&existingService,
+		}
+
+		bpmConverter = fakes.FakeBPMConverter{}
+		bpmConverter.ResourcesReturns(&bpmconverter.Resources{
+			InstanceGroups: []qstsv1a1.QuarksStatefulSet{qSts(2)},
+			Services:       []corev1.Service{service("foo-nats-0"), service("foo-nats-1")},
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+		manager.GetClientReturns(client)
+	})
+
+	reconcileDeployment := func() error {
+		reconciler := cfd.NewDeploymentReconciler(
+			ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager,
+			&withops, &fakes.FakeJobFactory{}, &fakes.FakeVariablesConverter{}, &bpmConverter,
+			controllerutil.SetControllerReference,
+		)
+		_, err := reconciler.Reconcile(ctx, request)
+		return err
+	}
+
+	diffs := func() []bdm.InstanceGroupDiff {
+		configMap := &corev1.ConfigMap{}
+		Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo.dry-run"}, configMap)).To(Succeed())
+
+		result := []bdm.InstanceGroupDiff{}
+		Expect(yaml.Unmarshal([]byte(configMap.Data["diff.yaml"]), &result)).To(Succeed())
+		return result
+	}
+
+	It("renders the changed instance group with the interpolated manifest", func() {
+		Expect(reconcileDeployment()).To(Succeed())
+
+		Expect(withops.InterpolateVariableFromSecretsCallCount()).To(Equal(1))
+		Expect(bpmConverter.ResourcesCallCount()).To(Equal(1))
+		m, namespace, deploymentName, _, qStsVersion, ig, _, igResolvedVersion := bpmConverter.ResourcesArgsForCall(0)
+		Expect(m.InstanceGroups[0].Instances).To(Equal(2))
+		Expect(namespace).To(Equal("default"))
+		Expect(deploymentName).To(Equal("foo"))
+		Expect(qStsVersion).To(Equal("3"))
+		Expect(ig.Name).To(Equal("nats"))
+		Expect(igResolvedVersion).To(Equal("1"))
+	})
+
+	It("records the changes of the rendered resources", func() {
+		Expect(reconcileDeployment()).To(Succeed())
+
+		result := diffs()
+		Expect(result).To(HaveLen(1))
+		Expect(result[0].Instances).To(Equal(&bdm.InstancesChange{From: 1, To: 2}))
+		Expect(result[0].Resources).To(Equal([]bdm.ResourceChange{
+			{Kind: "QuarksStatefulSet", Name: "foo-nats", Change: "changed", Paths: []string{"spec.template.spec.replicas"}},
+			{Kind: "Service", Name: "foo-nats-1", Change: "added"},
+		}))
+	})
+
+	It("doesn't touch the deployed resources", func() {
+		Expect(reconcileDeployment()).To(Succeed())
+
+		deployed := &qstsv1a1.QuarksStatefulSet{}
+		Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo-nats"}, deployed)).To(Succeed())
+		Expect(*deployed.Spec.Template.Spec.Replicas).To(Equal(int32(1)))
+
+		svc := &corev1.Service{}
+		err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo-nats-1"}, svc)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	Context("when the instance group was not rendered yet", func() {
+		BeforeEach(func() {
+			objects = objects[:2]
+		})
+
+		It("only records the manifest changes", func() {
+			Expect(reconcileDeployment()).To(Succeed())
+
+			Expect(bpmConverter.ResourcesCallCount()).To(Equal(0))
+			result := diffs()
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Resources).To(BeEmpty())
+		})
+	})
+})
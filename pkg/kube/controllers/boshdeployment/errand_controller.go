@@ -0,0 +1,126 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/monitorednamespace"
+)
+
+// AddErrand creates a new errand controller to watch for the run-errand
+// annotation on BOSHDeployments, which triggers the errand's QuarksJob once.
+// A second controller watches the errand pods and records the result of the
+// run in the BOSHDeployment status.
+func AddErrand(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "errand-reconciler", mgr.GetEventRecorderFor("errand-recorder"))
+	r := NewErrandReconciler(ctx, config, mgr)
+
+	c, err := controller.New("errand-controller", mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding errand controller to manager failed.")
+	}
+
+	nsPred := monitorednamespace.NewNSPredicate(ctx, mgr.GetClient(), config.MonitoredID)
+
+	// Trigger when the run-errand annotation is set on a BOSHDeployment
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return metav1.HasAnnotation(e.Object.(*bdv1.BOSHDeployment).ObjectMeta, bdv1.AnnotationRunErrand)
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			n := e.ObjectNew.(*bdv1.BOSHDeployment)
+			if !metav1.HasAnnotation(n.ObjectMeta, bdv1.AnnotationRunErrand) {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+				ctx, e.ObjectNew, "bdv1.BOSHDeployment",
+				fmt.Sprintf("Update predicate passed for '%s/%s' for errand run", e.ObjectNew.GetNamespace(), e.ObjectNew.GetName()),
+			)
+			return true
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeployment{}}, &handler.EnqueueRequestForObject{}, nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching bosh deployment failed in errand controller.")
+	}
+
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return errors.Wrap(err, "Creating clientset for errand result controller failed.")
+	}
+
+	ctx = ctxlog.NewContextWithRecorder(ctx, "errand-result-reconciler", mgr.GetEventRecorderFor("errand-result-recorder"))
+	rr := NewErrandResultReconciler(ctx, config, mgr, controllerutil.SetControllerReference, clientset)
+
+	c, err = controller.New("errand-result-controller", mgr, controller.Options{
+		Reconciler:              rr,
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding errand result controller to manager failed.")
+	}
+
+	// Trigger when an errand pod of a BOSHDeployment has terminated
+	podPred := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isTerminatedErrandPod(e.Object.(*corev1.Pod))
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*corev1.Pod)
+			n := e.ObjectNew.(*corev1.Pod)
+			if o.Status.Phase == n.Status.Phase || !isTerminatedErrandPod(n) {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+				ctx, e.ObjectNew, "corev1.Pod",
+				fmt.Sprintf("Update predicate passed for errand pod '%s/%s'", e.ObjectNew.GetNamespace(), e.ObjectNew.GetName()),
+			)
+			return true
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestForObject{}, nsPred, podPred)
+	if err != nil {
+		return errors.Wrapf(err, "Watching pods failed in errand result controller.")
+	}
+
+	return nil
+}
+
+// isTerminatedErrandPod returns true for pods of a BOSHDeployment's
+// QuarksJob, which succeeded or failed
+func isTerminatedErrandPod(pod *corev1.Pod) bool {
+	labels := pod.GetLabels()
+	for _, label := range []string{qjv1a1.LabelQJobName, bdv1.LabelDeploymentName, bdv1.LabelInstanceGroupName} {
+		if _, ok := labels[label]; !ok {
+			return false
+		}
+	}
+
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
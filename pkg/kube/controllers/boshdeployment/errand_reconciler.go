@@ -0,0 +1,401 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+const (
+	// errandLogLines is the number of log lines kept per errand container
+	errandLogLines = int64(500)
+	// errandLogBytes is the maximum size of the log kept per errand container
+	errandLogBytes = 32 * 1024
+	// defaultErrandHistoryLimit is the number of earlier runs kept in the
+	// status of an errand, unless its schedule sets a history limit
+	defaultErrandHistoryLimit = 3
+)
+
+var _ reconcile.Reconciler = &ReconcileErrand{}
+
+// NewErrandReconciler returns a new reconcile.Reconciler
+func NewErrandReconciler(ctx context.Context, config *config.Config, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileErrand{
+		ctx:    ctx,
+		config: config,
+		client: mgr.GetClient(),
+	}
+}
+
+// ReconcileErrand reconciles the run-errand annotation of a BOSHDeployment
+type ReconcileErrand struct {
+	ctx    context.Context
+	config *config.Config
+	client client.Client
+}
+
+// Reconcile triggers the QuarksJob of the errand, which is requested by the
+// run-errand annotation on the BOSHDeployment. The result of the run is
+// recorded by the errand result reconciler, once the errand pod terminates.
+func (r *ReconcileErrand) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
+	// Set the ctx to be Background, as the top-level context for incoming requests.
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	log.Infof(ctx, "Reconciling errand run of BOSHDeployment '%s'", request.NamespacedName)
+	bdpl := &bdv1.BOSHDeployment{}
+	err := r.client.Get(ctx, request.NamespacedName, bdpl)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Return and don't requeue
+			log.Debug(ctx, "Skip reconcile: BOSHDeployment not found")
+			return reconcile.Result{}, nil
+		}
+
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "GetBOSHDeploymentError").Errorf(ctx, "failed to get BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	errand, ok := bdpl.GetAnnotations()[bdv1.AnnotationRunErrand]
+	if !ok {
+		log.Debugf(ctx, "Skip reconcile: BOSHDeployment '%s' has no run-errand annotation", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	// The annotation is removed in any case, so a broken value does not block future runs
+	annotations := bdpl.GetAnnotations()
+	delete(annotations, bdv1.AnnotationRunErrand)
+	bdpl.SetAnnotations(annotations)
+	err = r.client.Update(ctx, bdpl)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to remove run-errand annotation from BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	now := metav1.Now()
+	run := bdv1.ErrandRunStatus{
+		State:       bdv1.ErrandStateRequested,
+		RequestTime: &now,
+	}
+
+	qJob, err := triggerErrand(ctx, r.client, bdpl, errand)
+	if err != nil {
+		run.State = bdv1.ErrandStateFailed
+		run.Message = err.Error()
+		_ = log.WithEvent(bdpl, "RunErrandError").Errorf(ctx, "failed to run errand '%s' of BOSHDeployment '%s': %v", errand, request.NamespacedName, err)
+	} else {
+		log.WithEvent(bdpl, "RunErrand").Infof(ctx, "Running errand '%s' of BOSHDeployment '%s'", errand, request.NamespacedName)
+	}
+
+	startErrandRun(bdpl, errand, run, errandHistoryLimit(qJob))
+	err = r.client.Status().Update(ctx, bdpl)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update errand status on BOSHDeployment '%s' (%v): %s", request.NamespacedName, bdpl.ResourceVersion, err)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// triggerErrand sets the trigger strategy of the errand's QuarksJob to 'now'.
+// It returns the QuarksJob, if it belongs to the deployment.
+func triggerErrand(ctx context.Context, c client.Client, bdpl *bdv1.BOSHDeployment, errand string) (*qjv1a1.QuarksJob, error) {
+	qJob := &qjv1a1.QuarksJob{}
+	qJobName := names.ErrandName(bdpl.Name, errand)
+	err := c.Get(ctx, types.NamespacedName{Namespace: bdpl.Namespace, Name: qJobName}, qJob)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("errand '%s' does not exist", errand)
+		}
+		return nil, fmt.Errorf("failed to get QuarksJob '%s/%s': %v", bdpl.Namespace, qJobName, err)
+	}
+
+	if qJob.GetLabels()[bdv1.LabelDeploymentName] != bdpl.Name {
+		return nil, fmt.Errorf("errand '%s' does not belong to the deployment", errand)
+	}
+	if qJob.Spec.Trigger.Strategy != qjv1a1.TriggerManual && qJob.Spec.Trigger.Strategy != qjv1a1.TriggerNow {
+		return qJob, fmt.Errorf("instance group '%s' is not an errand", errand)
+	}
+
+	qJob.Spec.Trigger.Strategy = qjv1a1.TriggerNow
+	err = c.Update(ctx, qJob)
+	if err != nil {
+		return qJob, fmt.Errorf("failed to trigger QuarksJob '%s/%s': %v", bdpl.Namespace, errand, err)
+	}
+
+	return qJob, nil
+}
+
+var _ reconcile.Reconciler = &ReconcileErrandResult{}
+
+// NewErrandResultReconciler returns a new reconcile.Reconciler
+func NewErrandResultReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, srf setReferenceFunc, clientset kubernetes.Interface) reconcile.Reconciler {
+	return &ReconcileErrandResult{
+		ctx:          ctx,
+		config:       config,
+		client:       mgr.GetClient(),
+		scheme:       mgr.GetScheme(),
+		setReference: srf,
+		clientset:    clientset,
+	}
+}
+
+// ReconcileErrandResult reconciles terminated errand pods
+type ReconcileErrandResult struct {
+	ctx          context.Context
+	config       *config.Config
+	client       client.Client
+	scheme       *runtime.Scheme
+	setReference setReferenceFunc
+	clientset    kubernetes.Interface
+}
+
+// Reconcile records the exit code, start and end time of a terminated
+// errand pod in the BOSHDeployment status. The truncated logs of its
+// containers are stored in a config map, which is referenced by the status.
+func (r *ReconcileErrandResult) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
+	// Set the ctx to be Background, as the top-level context for incoming requests.
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	log.Infof(ctx, "Reconciling errand pod '%s'", request.NamespacedName)
+	pod := &corev1.Pod{}
+	err := r.client.Get(ctx, request.NamespacedName, pod)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Return and don't requeue
+			log.Debug(ctx, "Skip reconcile: pod not found")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get pod '%s'", request.NamespacedName)
+	}
+
+	if !isTerminatedErrandPod(pod) {
+		log.Debugf(ctx, "Skip reconcile: pod '%s' is not a terminated errand pod", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	bdpl := &bdv1.BOSHDeployment{}
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Labels[bdv1.LabelDeploymentName]}, bdpl)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Debugf(ctx, "Skip reconcile: BOSHDeployment of pod '%s' not found", request.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get BOSHDeployment of pod '%s'", request.NamespacedName)
+	}
+
+	errand := pod.Labels[bdv1.LabelInstanceGroupName]
+	status := findErrandStatus(bdpl, errand)
+	if status == nil {
+		log.Debugf(ctx, "Skip reconcile: no run of errand '%s' requested", errand)
+		return reconcile.Result{}, nil
+	}
+	run := errandRunOfPod(status, pod)
+	if run == nil {
+		log.Debugf(ctx, "Skip reconcile: pod '%s' does not belong to a pending run of errand '%s'", request.NamespacedName, errand)
+		return reconcile.Result{}, nil
+	}
+
+	exitCode, endTime := errandExitCode(pod)
+	run.Pod = pod.Name
+	run.ExitCode = &exitCode
+	run.StartTime = pod.Status.StartTime
+	run.EndTime = endTime
+	run.State = bdv1.ErrandStateSucceeded
+	if exitCode != 0 || pod.Status.Phase == corev1.PodFailed {
+		run.State = bdv1.ErrandStateFailed
+	}
+
+	// Only the last run keeps its logs, they are overwritten by each run
+	if run == &status.ErrandRunStatus {
+		run.LogsConfigMap, err = r.storeLogs(ctx, bdpl, errand, pod)
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(bdpl, "ErrandResultError").Errorf(ctx, "failed to store logs of errand '%s' of BOSHDeployment '%s': %v", errand, bdpl.GetNamespacedName(), err)
+		}
+	}
+
+	err = r.client.Status().Update(ctx, bdpl)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update errand status on BOSHDeployment '%s' (%v): %s", bdpl.GetNamespacedName(), bdpl.ResourceVersion, err)
+	}
+
+	log.WithEvent(bdpl, "ErrandResult").Infof(ctx, "Errand '%s' of BOSHDeployment '%s' finished with exit code %d", errand, bdpl.GetNamespacedName(), exitCode)
+	return reconcile.Result{}, nil
+}
+
+// storeLogs writes the logs of the errand pod to a config map, which is
+// owned by the BOSHDeployment, and returns its name
+func (r *ReconcileErrandResult) storeLogs(ctx context.Context, bdpl *bdv1.BOSHDeployment, errand string, pod *corev1.Pod) (string, error) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.ErrandLogsConfigMapName(bdpl.Name, errand),
+			Namespace: bdpl.Namespace,
+			Labels: map[string]string{
+				bdv1.LabelDeploymentName:    bdpl.Name,
+				bdv1.LabelInstanceGroupName: errand,
+			},
+		},
+	}
+	if err := r.setReference(bdpl, configMap, r.scheme); err != nil {
+		return "", errors.Wrapf(err, "failed to set ownerReference for ConfigMap '%s/%s'", bdpl.Namespace, configMap.Name)
+	}
+
+	logs := r.containerLogs(ctx, pod)
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, configMap, func() error {
+		configMap.Data = logs
+		return nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to apply ConfigMap '%s/%s'", bdpl.Namespace, configMap.Name)
+	}
+	log.Debugf(ctx, "ConfigMap '%s/%s' has been %s", bdpl.Namespace, configMap.Name, op)
+
+	return configMap.Name, nil
+}
+
+// containerLogs returns the tail of the logs of all containers of the pod,
+// by '<container-name>.log'
+func (r *ReconcileErrandResult) containerLogs(ctx context.Context, pod *corev1.Pod) map[string]string {
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+
+	logs := make(map[string]string, len(containers))
+	for _, container := range containers {
+		tailLines := errandLogLines
+		raw, err := r.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container: container.Name,
+			TailLines: &tailLines,
+		}).DoRaw(ctx)
+		if err != nil {
+			log.Debugf(ctx, "Failed to get logs of container '%s' in pod '%s/%s': %v", container.Name, pod.Namespace, pod.Name, err)
+			raw = []byte(fmt.Sprintf("failed to get logs: %v", err))
+		}
+		if len(raw) > errandLogBytes {
+			raw = raw[len(raw)-errandLogBytes:]
+		}
+		logs[container.Name+".log"] = string(raw)
+	}
+
+	return logs
+}
+
+// errandExitCode returns the first non-zero exit code of the pod's
+// containers, or zero, and the time the last container finished
+func errandExitCode(pod *corev1.Pod) (int32, *metav1.Time) {
+	exitCode := int32(0)
+	var endTime *metav1.Time
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		terminated := s.State.Terminated
+		if terminated == nil {
+			continue
+		}
+		if exitCode == 0 {
+			exitCode = terminated.ExitCode
+		}
+		if endTime == nil || endTime.Before(&terminated.FinishedAt) {
+			finishedAt := terminated.FinishedAt
+			endTime = &finishedAt
+		}
+	}
+
+	return exitCode, endTime
+}
+
+// errandRunOfPod returns the run of the errand, which started the pod. That
+// is the latest run requested before the pod was created. It returns nil, if
+// the pod's result is recorded already, or if a later retry of the run was
+// recorded.
+func errandRunOfPod(status *bdv1.ErrandStatus, pod *corev1.Pod) *bdv1.ErrandRunStatus {
+	var run *bdv1.ErrandRunStatus
+	runs := append([]*bdv1.ErrandRunStatus{&status.ErrandRunStatus}, historyRuns(status)...)
+	for _, r := range runs {
+		if r.RequestTime == nil || r.State == bdv1.ErrandStateSkipped || pod.CreationTimestamp.Before(r.RequestTime) {
+			continue
+		}
+		if run == nil || run.RequestTime.Before(r.RequestTime) {
+			run = r
+		}
+	}
+
+	if run == nil || run.Pod == pod.Name {
+		return nil
+	}
+	if run.StartTime != nil && pod.Status.StartTime != nil && pod.Status.StartTime.Before(run.StartTime) {
+		return nil
+	}
+	return run
+}
+
+func historyRuns(status *bdv1.ErrandStatus) []*bdv1.ErrandRunStatus {
+	runs := make([]*bdv1.ErrandRunStatus, len(status.History))
+	for i := range status.History {
+		runs[i] = &status.History[i]
+	}
+	return runs
+}
+
+// errandHistoryLimit returns the number of earlier runs, which are kept in
+// the status of the errand
+func errandHistoryLimit(qJob *qjv1a1.QuarksJob) int {
+	if qJob == nil {
+		return defaultErrandHistoryLimit
+	}
+	limit, err := strconv.Atoi(qJob.GetAnnotations()[bdv1.AnnotationErrandHistoryLimit])
+	if err != nil || limit < 0 {
+		return defaultErrandHistoryLimit
+	}
+	return limit
+}
+
+// findErrandStatus returns the status of the errand's last run
+func findErrandStatus(bdpl *bdv1.BOSHDeployment, errand string) *bdv1.ErrandStatus {
+	for i := range bdpl.Status.Errands {
+		if bdpl.Status.Errands[i].Name == errand {
+			return &bdpl.Status.Errands[i]
+		}
+	}
+	return nil
+}
+
+// startErrandRun replaces the last run of the errand and moves the last run
+// into the bounded history
+func startErrandRun(bdpl *bdv1.BOSHDeployment, errand string, run bdv1.ErrandRunStatus, historyLimit int) *bdv1.ErrandStatus {
+	status := findErrandStatus(bdpl, errand)
+	if status == nil {
+		bdpl.Status.Errands = append(bdpl.Status.Errands, bdv1.ErrandStatus{Name: errand})
+		status = &bdpl.Status.Errands[len(bdpl.Status.Errands)-1]
+	} else if status.State != "" {
+		last := status.ErrandRunStatus
+		last.LogsConfigMap = ""
+		status.History = append([]bdv1.ErrandRunStatus{last}, status.History...)
+	}
+
+	if len(status.History) > historyLimit {
+		status.History = status.History[:historyLimit]
+	}
+	status.ErrandRunStatus = run
+	return status
+}
@@ -0,0 +1,313 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Errands", func() {
+	var (
+		ctx           context.Context
+		recorder      *record.FakeRecorder
+		manager       *fakes.FakeManager
+		client        crc.Client
+		bdpl          *bdv1.BOSHDeployment
+		qJob          *qjv1a1.QuarksJob
+		getDeployment func() *bdv1.BOSHDeployment
+	)
+
+	BeforeEach(func() {
+		_ = controllers.AddToScheme(scheme.Scheme)
+		recorder = record.NewFakeRecorder(20)
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+
+		bdpl = &bdv1.BOSHDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "foo",
+				Namespace:   "default",
+				Annotations: map[string]string{bdv1.AnnotationRunErrand: "smoke-tests"},
+			},
+		}
+		qJob = &qjv1a1.QuarksJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-smoke-tests",
+				Namespace: "default",
+				Labels: map[string]string{
+					bdv1.LabelDeploymentName:    "foo",
+					bdv1.LabelInstanceGroupName: "smoke-tests",
+				},
+			},
+			Spec: qjv1a1.QuarksJobSpec{Trigger: qjv1a1.Trigger{Strategy: qjv1a1.TriggerManual}},
+		}
+
+		getDeployment = func() *bdv1.BOSHDeployment {
+			bdpl := &bdv1.BOSHDeployment{}
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo"}, bdpl)).To(Succeed())
+			return bdpl
+		}
+	})
+
+	Describe("ReconcileErrand", func() {
+		var reconcileErrand func()
+
+		JustBeforeEach(func() {
+			client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(bdpl, qJob).Build()
+			manager.GetClientReturns(client)
+
+			reconcileErrand = func() {
+				reconciler := cfd.NewErrandReconciler(ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager)
+				_, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("triggers the errand's quarks job and removes the annotation", func() {
+			reconcileErrand()
+
+			job := &qjv1a1.QuarksJob{}
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo-smoke-tests"}, job)).To(Succeed())
+			Expect(job.Spec.Trigger.Strategy).To(Equal(qjv1a1.TriggerNow))
+
+			bdpl := getDeployment()
+			Expect(bdpl.GetAnnotations()).NotTo(HaveKey(bdv1.AnnotationRunErrand))
+			Expect(bdpl.Status.Errands).To(HaveLen(1))
+			Expect(bdpl.Status.Errands[0].Name).To(Equal("smoke-tests"))
+			Expect(bdpl.Status.Errands[0].State).To(Equal(bdv1.ErrandStateRequested))
+			Expect(bdpl.Status.Errands[0].RequestTime).NotTo(BeNil())
+			Expect(recorder.Events).To(Receive(ContainSubstring("Running errand 'smoke-tests'")))
+		})
+
+		Context("when the errand ran before", func() {
+			BeforeEach(func() {
+				bdpl.Status.Errands = []bdv1.ErrandStatus{{
+					Name: "smoke-tests",
+					ErrandRunStatus: bdv1.ErrandRunStatus{
+						State:         bdv1.ErrandStateSucceeded,
+						Pod:           "smoke-tests-old",
+						LogsConfigMap: "foo.errand-smoke-tests",
+					},
+				}}
+			})
+
+			It("moves the last result into the history", func() {
+				reconcileErrand()
+
+				status := getDeployment().Status.Errands[0]
+				Expect(status.State).To(Equal(bdv1.ErrandStateRequested))
+				Expect(status.Pod).To(BeEmpty())
+				Expect(status.History).To(HaveLen(1))
+				Expect(status.History[0].Pod).To(Equal("smoke-tests-old"))
+				Expect(status.History[0].LogsConfigMap).To(BeEmpty())
+			})
+		})
+
+		Context("when the errand does not exist", func() {
+			BeforeEach(func() {
+				bdpl.Annotations[bdv1.AnnotationRunErrand] = "unknown"
+			})
+
+			It("records the failure in the status", func() {
+				reconcileErrand()
+
+				bdpl := getDeployment()
+				Expect(bdpl.GetAnnotations()).NotTo(HaveKey(bdv1.AnnotationRunErrand))
+				Expect(bdpl.Status.Errands).To(HaveLen(1))
+				Expect(bdpl.Status.Errands[0].State).To(Equal(bdv1.ErrandStateFailed))
+				Expect(bdpl.Status.Errands[0].Message).To(Equal("errand 'unknown' does not exist"))
+			})
+		})
+
+		Context("when the quarks job is an auto-errand", func() {
+			BeforeEach(func() {
+				qJob.Spec.Trigger.Strategy = qjv1a1.TriggerDone
+			})
+
+			It("does not trigger it", func() {
+				reconcileErrand()
+
+				job := &qjv1a1.QuarksJob{}
+				Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo-smoke-tests"}, job)).To(Succeed())
+				Expect(job.Spec.Trigger.Strategy).To(Equal(qjv1a1.TriggerDone))
+				Expect(getDeployment().Status.Errands[0].State).To(Equal(bdv1.ErrandStateFailed))
+			})
+		})
+	})
+
+	Describe("ReconcileErrandResult", func() {
+		var (
+			pod                   *corev1.Pod
+			exitCode              int32
+			createdAfterRequest   time.Duration
+			requestTime           metav1.Time
+			reconcileErrandResult func()
+		)
+
+		BeforeEach(func() {
+			exitCode = 0
+			createdAfterRequest = 5 * time.Second
+			requestTime = metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+		})
+
+		JustBeforeEach(func() {
+			bdpl.Annotations = nil
+			bdpl.Status.Errands = []bdv1.ErrandStatus{{
+				Name: "smoke-tests",
+				ErrandRunStatus: bdv1.ErrandRunStatus{
+					State:       bdv1.ErrandStateRequested,
+					RequestTime: &requestTime,
+				},
+			}}
+			startTime := metav1.NewTime(requestTime.Add(10 * time.Second))
+			pod = &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "smoke-tests-abcde",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(requestTime.Add(createdAfterRequest)),
+					Labels: map[string]string{
+						qjv1a1.LabelQJobName:        "foo-smoke-tests",
+						bdv1.LabelDeploymentName:    "foo",
+						bdv1.LabelInstanceGroupName: "smoke-tests",
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "bpm-pre-start-smoke-tests"}},
+					Containers:     []corev1.Container{{Name: "smoke-tests"}},
+				},
+				Status: corev1.PodStatus{
+					Phase:     corev1.PodSucceeded,
+					StartTime: &startTime,
+					InitContainerStatuses: []corev1.ContainerStatus{{
+						Name: "bpm-pre-start-smoke-tests",
+						State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+							FinishedAt: metav1.NewTime(requestTime.Add(20 * time.Second)),
+						}},
+					}},
+					ContainerStatuses: []corev1.ContainerStatus{{
+						Name: "smoke-tests",
+						State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+							ExitCode:   exitCode,
+							FinishedAt: metav1.NewTime(requestTime.Add(30 * time.Second)),
+						}},
+					}},
+				},
+			}
+			if exitCode != 0 {
+				pod.Status.Phase = corev1.PodFailed
+			}
+
+			client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(bdpl, pod).Build()
+			manager.GetClientReturns(client)
+
+			reconcileErrandResult = func() {
+				reconciler := cfd.NewErrandResultReconciler(ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager, controllerutil.SetControllerReference, kfake.NewSimpleClientset())
+				_, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: "default", Name: "smoke-tests-abcde"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("records the result in the status and the logs in a config map", func() {
+			reconcileErrandResult()
+
+			status := getDeployment().Status.Errands[0]
+			Expect(status.State).To(Equal(bdv1.ErrandStateSucceeded))
+			Expect(status.Pod).To(Equal("smoke-tests-abcde"))
+			Expect(*status.ExitCode).To(BeZero())
+			Expect(status.StartTime.Time).To(Equal(requestTime.Add(10 * time.Second)))
+			Expect(status.EndTime.Time).To(Equal(requestTime.Add(30 * time.Second)))
+			Expect(status.LogsConfigMap).To(Equal("foo.errand-smoke-tests"))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo.errand-smoke-tests"}, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("smoke-tests.log", "fake logs"))
+			Expect(configMap.Data).To(HaveKeyWithValue("bpm-pre-start-smoke-tests.log", "fake logs"))
+			Expect(configMap.GetOwnerReferences()).To(HaveLen(1))
+		})
+
+		Context("when the errand fails", func() {
+			BeforeEach(func() {
+				exitCode = 3
+			})
+
+			It("records the exit code", func() {
+				reconcileErrandResult()
+
+				status := getDeployment().Status.Errands[0]
+				Expect(status.State).To(Equal(bdv1.ErrandStateFailed))
+				Expect(*status.ExitCode).To(Equal(int32(3)))
+			})
+		})
+
+		Context("when a later run was requested, while the pod was running", func() {
+			JustBeforeEach(func() {
+				bdpl := getDeployment()
+				laterRequest := metav1.NewTime(requestTime.Add(time.Minute))
+				bdpl.Status.Errands[0].History = []bdv1.ErrandRunStatus{bdpl.Status.Errands[0].ErrandRunStatus}
+				bdpl.Status.Errands[0].ErrandRunStatus = bdv1.ErrandRunStatus{
+					State:       bdv1.ErrandStateRequested,
+					RequestTime: &laterRequest,
+				}
+				Expect(client.Status().Update(context.Background(), bdpl)).To(Succeed())
+			})
+
+			It("records the result in the history", func() {
+				reconcileErrandResult()
+
+				status := getDeployment().Status.Errands[0]
+				Expect(status.State).To(Equal(bdv1.ErrandStateRequested))
+				Expect(status.History[0].State).To(Equal(bdv1.ErrandStateSucceeded))
+				Expect(status.History[0].Pod).To(Equal("smoke-tests-abcde"))
+				Expect(status.History[0].LogsConfigMap).To(BeEmpty())
+
+				err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo.errand-smoke-tests"}, &corev1.ConfigMap{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			})
+		})
+
+		Context("when the pod belongs to an earlier run", func() {
+			BeforeEach(func() {
+				createdAfterRequest = -time.Hour
+			})
+
+			It("ignores the pod", func() {
+				reconcileErrandResult()
+
+				status := getDeployment().Status.Errands[0]
+				Expect(status.State).To(Equal(bdv1.ErrandStateRequested))
+				Expect(status.ExitCode).To(BeNil())
+			})
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/monitorednamespace"
+)
+
+// AddErrandSchedule creates a new errand schedule controller to watch for
+// QuarksJobs of errands with a schedule. It triggers the errand, whenever a
+// run is due, and requeues the QuarksJob for the next run.
+func AddErrandSchedule(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "errand-schedule-reconciler", mgr.GetEventRecorderFor("errand-schedule-recorder"))
+	r := NewErrandScheduleReconciler(ctx, config, mgr)
+
+	c, err := controller.New("errand-schedule-controller", mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding errand schedule controller to manager failed.")
+	}
+
+	nsPred := monitorednamespace.NewNSPredicate(ctx, mgr.GetClient(), config.MonitoredID)
+
+	// Trigger when a QuarksJob with a schedule is created or its schedule changes
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return metav1.HasAnnotation(e.Object.(*qjv1a1.QuarksJob).ObjectMeta, bdv1.AnnotationErrandSchedule)
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*qjv1a1.QuarksJob)
+			n := e.ObjectNew.(*qjv1a1.QuarksJob)
+			if !metav1.HasAnnotation(n.ObjectMeta, bdv1.AnnotationErrandSchedule) ||
+				reflect.DeepEqual(o.GetAnnotations(), n.GetAnnotations()) {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+				ctx, e.ObjectNew, "qjv1a1.QuarksJob",
+				fmt.Sprintf("Update predicate passed for '%s/%s' for errand schedule", e.ObjectNew.GetNamespace(), e.ObjectNew.GetName()),
+			)
+			return true
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &qjv1a1.QuarksJob{}}, &handler.EnqueueRequestForObject{}, nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching quarks jobs failed in errand schedule controller.")
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+const (
+	// errandScheduleRequeueAfter is the interval to check, if a scheduled run,
+	// which is forbidden by a running errand, can start
+	errandScheduleRequeueAfter = 30 * time.Second
+	// maxMissedErrandRuns limits the missed runs, which are iterated to find
+	// the latest run, which is due. More missed runs are skipped altogether.
+	maxMissedErrandRuns = 100
+)
+
+var _ reconcile.Reconciler = &ReconcileErrandSchedule{}
+
+// NewErrandScheduleReconciler returns a new reconcile.Reconciler
+func NewErrandScheduleReconciler(ctx context.Context, config *config.Config, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileErrandSchedule{
+		ctx:    ctx,
+		config: config,
+		client: mgr.GetClient(),
+	}
+}
+
+// ReconcileErrandSchedule reconciles the schedule of an errand's QuarksJob
+type ReconcileErrandSchedule struct {
+	ctx    context.Context
+	config *config.Config
+	client client.Client
+}
+
+// Reconcile triggers the errand, if a scheduled run is due, and requeues the
+// QuarksJob for the next run. Runs which missed their starting deadline are
+// skipped. The concurrency policy decides, if a run waits for the running
+// errand or replaces it.
+func (r *ReconcileErrandSchedule) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
+	// Set the ctx to be Background, as the top-level context for incoming requests.
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	log.Infof(ctx, "Reconciling errand schedule of QuarksJob '%s'", request.NamespacedName)
+	qJob := &qjv1a1.QuarksJob{}
+	err := r.client.Get(ctx, request.NamespacedName, qJob)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Return and don't requeue
+			log.Debug(ctx, "Skip reconcile: QuarksJob not found")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get QuarksJob '%s'", request.NamespacedName)
+	}
+
+	value, ok := qJob.GetAnnotations()[bdv1.AnnotationErrandSchedule]
+	if !ok {
+		log.Debugf(ctx, "Skip reconcile: QuarksJob '%s' has no schedule", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+	schedule, err := cron.ParseStandard(value)
+	if err != nil {
+		_ = log.WithEvent(qJob, "ErrandScheduleError").Errorf(ctx, "invalid schedule '%s' of QuarksJob '%s': %v", value, request.NamespacedName, err)
+		return reconcile.Result{}, nil
+	}
+
+	bdpl := &bdv1.BOSHDeployment{}
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: qJob.Namespace, Name: qJob.GetLabels()[bdv1.LabelDeploymentName]}, bdpl)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Debugf(ctx, "Skip reconcile: BOSHDeployment of QuarksJob '%s' not found", request.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get BOSHDeployment of QuarksJob '%s'", request.NamespacedName)
+	}
+
+	errand := instanceGroupName(qJob.ObjectMeta)
+	now := time.Now()
+	status := findErrandStatus(bdpl, errand)
+	last := qJob.CreationTimestamp.Time
+	if status != nil && status.LastScheduleTime != nil {
+		last = status.LastScheduleTime.Time
+	}
+
+	// The latest run, which is due, replaces all runs missed before it
+	var scheduled time.Time
+	missed := 0
+	for t := schedule.Next(last); !t.After(now) && missed <= maxMissedErrandRuns; t = schedule.Next(t) {
+		scheduled = t
+		missed++
+	}
+	next := reconcile.Result{RequeueAfter: schedule.Next(now).Sub(now)}
+	if scheduled.IsZero() {
+		log.Debugf(ctx, "Next run of errand '%s' of BOSHDeployment '%s' is due in %s", errand, bdpl.GetNamespacedName(), next.RequeueAfter)
+		return next, nil
+	}
+
+	historyLimit := errandHistoryLimit(qJob)
+	deadline, err := strconv.Atoi(qJob.GetAnnotations()[bdv1.AnnotationErrandStartingDeadline])
+	if err != nil {
+		deadline = 0
+	}
+
+	var message string
+	switch {
+	case missed > maxMissedErrandRuns:
+		message = fmt.Sprintf("More than %d runs of errand '%s' were missed since %s", maxMissedErrandRuns, errand, last.Format(time.RFC3339))
+		scheduled = now
+	case deadline > 0 && now.Sub(scheduled) > time.Duration(deadline)*time.Second:
+		message = fmt.Sprintf("Run of errand '%s' scheduled at %s missed its starting deadline of %ds", errand, scheduled.Format(time.RFC3339), deadline)
+	}
+
+	scheduleTime := metav1.NewTime(scheduled)
+	if message != "" {
+		status := startErrandRun(bdpl, errand, bdv1.ErrandRunStatus{
+			State:       bdv1.ErrandStateSkipped,
+			Message:     message,
+			RequestTime: &scheduleTime,
+		}, historyLimit)
+		status.LastScheduleTime = &scheduleTime
+
+		err = r.client.Status().Update(ctx, bdpl)
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update errand status on BOSHDeployment '%s' (%v): %s", bdpl.GetNamespacedName(), bdpl.ResourceVersion, err)
+		}
+		log.WithEvent(bdpl, "ErrandScheduleMissed").Infof(ctx, "%s", message)
+		return next, nil
+	}
+
+	if status != nil && status.State == bdv1.ErrandStateRequested {
+		if bdm.ErrandConcurrencyPolicy(qJob.GetAnnotations()[bdv1.AnnotationErrandConcurrencyPolicy]) == bdm.ErrandConcurrencyReplace {
+			err := r.stopErrand(ctx, qJob)
+			if err != nil {
+				return reconcile.Result{},
+					log.WithEvent(bdpl, "ErrandScheduleError").Errorf(ctx, "failed to stop running errand '%s' of BOSHDeployment '%s': %v", errand, bdpl.GetNamespacedName(), err)
+			}
+			status.State = bdv1.ErrandStateFailed
+			status.Message = fmt.Sprintf("Replaced by the run scheduled at %s", scheduled.Format(time.RFC3339))
+		} else {
+			lost, err := r.errandRunLost(ctx, qJob, status.ErrandRunStatus, now, deadline)
+			if err != nil {
+				return reconcile.Result{},
+					log.WithEvent(bdpl, "ErrandScheduleError").Errorf(ctx, "failed to get running errand '%s' of BOSHDeployment '%s': %v", errand, bdpl.GetNamespacedName(), err)
+			}
+			if !lost {
+				log.Debugf(ctx, "Run of errand '%s' scheduled at %s waits for the running errand", errand, scheduled.Format(time.RFC3339))
+				if next.RequeueAfter > errandScheduleRequeueAfter {
+					next.RequeueAfter = errandScheduleRequeueAfter
+				}
+				return next, nil
+			}
+			status.State = bdv1.ErrandStateFailed
+			status.Message = fmt.Sprintf("Not running anymore, when the run scheduled at %s started", scheduled.Format(time.RFC3339))
+			log.WithEvent(bdpl, "ErrandRunLost").Infof(ctx, "Run of errand '%s' of BOSHDeployment '%s' is not running anymore", errand, bdpl.GetNamespacedName())
+		}
+	}
+
+	requestTime := metav1.NewTime(now)
+	run := bdv1.ErrandRunStatus{
+		State:       bdv1.ErrandStateRequested,
+		RequestTime: &requestTime,
+	}
+	_, err = triggerErrand(ctx, r.client, bdpl, errand)
+	if err != nil {
+		run.State = bdv1.ErrandStateFailed
+		run.Message = err.Error()
+		_ = log.WithEvent(bdpl, "RunErrandError").Errorf(ctx, "failed to run errand '%s' of BOSHDeployment '%s': %v", errand, bdpl.GetNamespacedName(), err)
+	} else {
+		log.WithEvent(bdpl, "RunErrand").Infof(ctx, "Running errand '%s' of BOSHDeployment '%s' scheduled at %s", errand, bdpl.GetNamespacedName(), scheduled.Format(time.RFC3339))
+	}
+
+	status = startErrandRun(bdpl, errand, run, historyLimit)
+	status.LastScheduleTime = &scheduleTime
+	err = r.client.Status().Update(ctx, bdpl)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update errand status on BOSHDeployment '%s' (%v): %s", bdpl.GetNamespacedName(), bdpl.ResourceVersion, err)
+	}
+
+	return next, nil
+}
+
+// stopErrand deletes the unfinished jobs of the errand's QuarksJob, together
+// with their pods
+func (r *ReconcileErrandSchedule) stopErrand(ctx context.Context, qJob *qjv1a1.QuarksJob) error {
+	jobs := &batchv1.JobList{}
+	err := r.client.List(ctx, jobs, client.InNamespace(qJob.Namespace), client.MatchingLabels{qjv1a1.LabelQJobName: qJob.Name})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list jobs of QuarksJob '%s'", qJob.GetNamespacedName())
+	}
+
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if jobFinished(job) {
+			continue
+		}
+		err := r.client.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete job '%s/%s'", job.Namespace, job.Name)
+		}
+		log.Debugf(ctx, "Deleted running job '%s/%s' of errand '%s'", job.Namespace, job.Name, qJob.Name)
+	}
+
+	return nil
+}
+
+// errandRunLost returns true, if the requested run of the errand is not
+// running. This happens, if the QuarksJob was recreated since the request, or
+// if it has no unfinished job after the starting deadline of the errand.
+func (r *ReconcileErrandSchedule) errandRunLost(ctx context.Context, qJob *qjv1a1.QuarksJob, run bdv1.ErrandRunStatus, now time.Time, deadline int) (bool, error) {
+	if run.RequestTime == nil || qJob.CreationTimestamp.After(run.RequestTime.Time) {
+		return true, nil
+	}
+
+	jobs := &batchv1.JobList{}
+	err := r.client.List(ctx, jobs, client.InNamespace(qJob.Namespace), client.MatchingLabels{qjv1a1.LabelQJobName: qJob.Name})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list jobs of QuarksJob '%s'", qJob.GetNamespacedName())
+	}
+	for i := range jobs.Items {
+		if !jobFinished(&jobs.Items[i]) {
+			return false, nil
+		}
+	}
+
+	// Give the QuarksJob time to create the job of the run
+	timeout := errandScheduleRequeueAfter
+	if deadline > 0 {
+		timeout = time.Duration(deadline) * time.Second
+	}
+	return now.Sub(run.RequestTime.Time) > timeout, nil
+}
+
+// jobFinished returns true, if the job completed or failed
+func jobFinished(job *batchv1.Job) bool {
+	if job.Status.CompletionTime != nil {
+		return true
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,302 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileErrandSchedule", func() {
+	var (
+		ctx              context.Context
+		recorder         *record.FakeRecorder
+		manager          *fakes.FakeManager
+		client           crc.Client
+		bdpl             *bdv1.BOSHDeployment
+		qJob             *qjv1a1.QuarksJob
+		objects          []crc.Object
+		lastScheduleTime metav1.Time
+		reconcileErrand  func() reconcile.Result
+		getDeployment    func() *bdv1.BOSHDeployment
+		getStrategy      func() qjv1a1.Strategy
+	)
+
+	BeforeEach(func() {
+		_ = controllers.AddToScheme(scheme.Scheme)
+		recorder = record.NewFakeRecorder(20)
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+
+		// a yearly run, which is due once since the last run two years ago
+		lastScheduleTime = metav1.NewTime(time.Now().AddDate(-2, 0, 0).Truncate(time.Second))
+		bdpl = &bdv1.BOSHDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Status: bdv1.BOSHDeploymentStatus{
+				Errands: []bdv1.ErrandStatus{{
+					Name: "backup",
+					ErrandRunStatus: bdv1.ErrandRunStatus{
+						State:       bdv1.ErrandStateSucceeded,
+						RequestTime: &lastScheduleTime,
+						Pod:         "backup-old",
+					},
+					LastScheduleTime: &lastScheduleTime,
+					History: []bdv1.ErrandRunStatus{
+						{State: bdv1.ErrandStateSucceeded, Pod: "backup-older"},
+						{State: bdv1.ErrandStateSucceeded, Pod: "backup-oldest"},
+					},
+				}},
+			},
+		}
+		qJob = &qjv1a1.QuarksJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "foo-backup",
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(time.Now().AddDate(-3, 0, 0)),
+				Labels: map[string]string{
+					bdv1.LabelDeploymentName:    "foo",
+					bdv1.LabelInstanceGroupName: "backup",
+				},
+				Annotations: map[string]string{bdv1.AnnotationErrandSchedule: "@yearly"},
+			},
+			Spec: qjv1a1.QuarksJobSpec{Trigger: qjv1a1.Trigger{Strategy: qjv1a1.TriggerManual}},
+		}
+		objects = []crc.Object{}
+
+		reconcileErrand = func() reconcile.Result {
+			reconciler := cfd.NewErrandScheduleReconciler(ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager)
+			result, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo-backup"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			return result
+		}
+
+		getDeployment = func() *bdv1.BOSHDeployment {
+			bdpl := &bdv1.BOSHDeployment{}
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo"}, bdpl)).To(Succeed())
+			return bdpl
+		}
+
+		getStrategy = func() qjv1a1.Strategy {
+			job := &qjv1a1.QuarksJob{}
+			Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo-backup"}, job)).To(Succeed())
+			return job.Spec.Trigger.Strategy
+		}
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(append(objects, bdpl, qJob)...).Build()
+		manager.GetClientReturns(client)
+	})
+
+	It("runs the errand when it is due and requeues for the next run", func() {
+		result := reconcileErrand()
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(result.RequeueAfter).To(BeNumerically("<=", 366*24*time.Hour))
+
+		Expect(getStrategy()).To(Equal(qjv1a1.TriggerNow))
+
+		status := getDeployment().Status.Errands[0]
+		Expect(status.State).To(Equal(bdv1.ErrandStateRequested))
+		Expect(status.LastScheduleTime.After(lastScheduleTime.Time)).To(BeTrue())
+		Expect(status.History).To(HaveLen(3))
+		Expect(status.History[0].Pod).To(Equal("backup-old"))
+		Expect(recorder.Events).To(Receive(ContainSubstring("Running errand 'backup' of BOSHDeployment 'default/foo' scheduled at")))
+	})
+
+	Context("when the history limit is reached", func() {
+		BeforeEach(func() {
+			qJob.Annotations[bdv1.AnnotationErrandHistoryLimit] = "1"
+		})
+
+		It("drops the oldest results", func() {
+			reconcileErrand()
+
+			status := getDeployment().Status.Errands[0]
+			Expect(status.History).To(HaveLen(1))
+			Expect(status.History[0].Pod).To(Equal("backup-old"))
+		})
+	})
+
+	Context("when no run is due", func() {
+		BeforeEach(func() {
+			lastScheduleTime = metav1.NewTime(time.Now().Truncate(time.Second))
+		})
+
+		It("requeues for the next run", func() {
+			result := reconcileErrand()
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			Expect(getStrategy()).To(Equal(qjv1a1.TriggerManual))
+			Expect(getDeployment().Status.Errands[0].Pod).To(Equal("backup-old"))
+		})
+	})
+
+	Context("when the run missed its starting deadline", func() {
+		BeforeEach(func() {
+			qJob.Annotations[bdv1.AnnotationErrandStartingDeadline] = "60"
+		})
+
+		It("skips the run", func() {
+			result := reconcileErrand()
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			Expect(getStrategy()).To(Equal(qjv1a1.TriggerManual))
+			status := getDeployment().Status.Errands[0]
+			Expect(status.State).To(Equal(bdv1.ErrandStateSkipped))
+			Expect(status.Message).To(ContainSubstring("missed its starting deadline of 60s"))
+			Expect(status.LastScheduleTime.After(lastScheduleTime.Time)).To(BeTrue())
+		})
+	})
+
+	Context("when more than 100 runs were missed", func() {
+		BeforeEach(func() {
+			qJob.Annotations[bdv1.AnnotationErrandSchedule] = "* * * * *"
+		})
+
+		It("skips them without iterating all of them", func() {
+			result := reconcileErrand()
+			Expect(result.RequeueAfter).To(BeNumerically("<=", time.Minute))
+
+			Expect(getStrategy()).To(Equal(qjv1a1.TriggerManual))
+			status := getDeployment().Status.Errands[0]
+			Expect(status.State).To(Equal(bdv1.ErrandStateSkipped))
+			Expect(status.Message).To(ContainSubstring("More than 100 runs of errand 'backup' were missed"))
+			Expect(status.LastScheduleTime.After(time.Now().Add(-time.Minute))).To(BeTrue())
+			Expect(recorder.Events).To(Receive(ContainSubstring("More than 100 runs")))
+		})
+	})
+
+	Context("when the requested run is not running", func() {
+		BeforeEach(func() {
+			bdpl.Status.Errands[0].State = bdv1.ErrandStateRequested
+			objects = append(objects, &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "backup-failed",
+					Namespace: "default",
+					Labels:    map[string]string{qjv1a1.LabelQJobName: "foo-backup"},
+				},
+				Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+					{Type: batchv1.JobFailed, Status: corev1.ConditionTrue},
+				}},
+			})
+		})
+
+		It("fails the lost run and runs the errand again", func() {
+			reconcileErrand()
+
+			Expect(getStrategy()).To(Equal(qjv1a1.TriggerNow))
+			status := getDeployment().Status.Errands[0]
+			Expect(status.State).To(Equal(bdv1.ErrandStateRequested))
+			Expect(status.History[0].State).To(Equal(bdv1.ErrandStateFailed))
+			Expect(status.History[0].Message).To(ContainSubstring("Not running anymore"))
+			Expect(recorder.Events).To(Receive(ContainSubstring("is not running anymore")))
+		})
+
+		Context("when the run was just requested", func() {
+			BeforeEach(func() {
+				requestTime := metav1.NewTime(time.Now())
+				bdpl.Status.Errands[0].RequestTime = &requestTime
+			})
+
+			It("waits for the job of the run", func() {
+				result := reconcileErrand()
+				Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+
+				Expect(getStrategy()).To(Equal(qjv1a1.TriggerManual))
+				Expect(getDeployment().Status.Errands[0].State).To(Equal(bdv1.ErrandStateRequested))
+			})
+		})
+
+		Context("when the QuarksJob was recreated since the request", func() {
+			BeforeEach(func() {
+				requestTime := metav1.NewTime(time.Now().Add(-time.Second))
+				bdpl.Status.Errands[0].RequestTime = &requestTime
+				qJob.CreationTimestamp = metav1.NewTime(time.Now())
+			})
+
+			It("runs the errand again", func() {
+				reconcileErrand()
+
+				Expect(getDeployment().Status.Errands[0].History[0].State).To(Equal(bdv1.ErrandStateFailed))
+			})
+		})
+	})
+
+	Context("when the errand is still running", func() {
+		BeforeEach(func() {
+			bdpl.Status.Errands[0].State = bdv1.ErrandStateRequested
+			objects = append(objects,
+				&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+					Name:      "backup-running",
+					Namespace: "default",
+					Labels:    map[string]string{qjv1a1.LabelQJobName: "foo-backup"},
+				}},
+				&batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "backup-completed",
+						Namespace: "default",
+						Labels:    map[string]string{qjv1a1.LabelQJobName: "foo-backup"},
+					},
+					Status: batchv1.JobStatus{CompletionTime: &lastScheduleTime},
+				},
+			)
+		})
+
+		It("waits for it by default", func() {
+			result := reconcileErrand()
+			Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+
+			Expect(getStrategy()).To(Equal(qjv1a1.TriggerManual))
+			status := getDeployment().Status.Errands[0]
+			Expect(status.State).To(Equal(bdv1.ErrandStateRequested))
+			Expect(status.LastScheduleTime.Time).To(Equal(lastScheduleTime.Time))
+		})
+
+		Context("when the concurrency policy is 'Replace'", func() {
+			BeforeEach(func() {
+				qJob.Annotations[bdv1.AnnotationErrandConcurrencyPolicy] = "Replace"
+			})
+
+			It("stops the running errand and runs it again", func() {
+				reconcileErrand()
+
+				err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "backup-running"}, &batchv1.Job{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "backup-completed"}, &batchv1.Job{})).To(Succeed())
+
+				Expect(getStrategy()).To(Equal(qjv1a1.TriggerNow))
+				status := getDeployment().Status.Errands[0]
+				Expect(status.State).To(Equal(bdv1.ErrandStateRequested))
+				Expect(status.History[0].State).To(Equal(bdv1.ErrandStateFailed))
+				Expect(status.History[0].Message).To(ContainSubstring("Replaced by the run scheduled at"))
+			})
+		})
+	})
+})
@@ -0,0 +1,247 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/converter"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+// providedLink is a link provided by an instance group of a deployment
+type providedLink struct {
+	instanceGroup string
+	linkType      string
+	// key is the '<type>-<name>' key of the link secret
+	key string
+}
+
+// externalQuarksLinks resolves the links, which are consumed from other
+// BOSHDeployments. The link secret of each provider is copied into the
+// namespace of the consumer, if the providing deployment allows the consumer
+// to use its links. The copies are returned and have to be applied by the
+// caller.
+func (l *linkInfoService) externalQuarksLinks(ctx context.Context, client crc.Client, providers map[string]bdm.ExternalProvider) (map[string]bdm.QuarksLink, converter.LinkInfos, []corev1.Secret, error) {
+	quarksLinks := map[string]bdm.QuarksLink{}
+	linkInfos := converter.LinkInfos{}
+	linkSecrets := []corev1.Secret{}
+
+	providerNames := make([]string, 0, len(providers))
+	for providerName := range providers {
+		providerNames = append(providerNames, providerName)
+	}
+	sort.Strings(providerNames)
+
+	for _, providerName := range providerNames {
+		provider := providers[providerName]
+		namespace := provider.Namespace
+		if namespace == "" {
+			namespace = l.namespace
+		}
+
+		bdpl := &bdv1.BOSHDeployment{}
+		err := client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: provider.Deployment}, bdpl)
+		if err != nil {
+			return quarksLinks, linkInfos, linkSecrets, errors.Wrapf(err, "failed to get deployment '%s/%s' of link provider '%s'", namespace, provider.Deployment, providerName)
+		}
+		if !allowsLinkConsumer(bdpl, l.namespace, l.deploymentName) {
+			return quarksLinks, linkInfos, linkSecrets, errors.Errorf("deployment '%s/%s' does not allow '%s/%s' to consume link '%s'", namespace, provider.Deployment, l.namespace, l.deploymentName, providerName)
+		}
+
+		link, err := findProvidedLink(ctx, client, namespace, provider.Deployment, providerName)
+		if err != nil {
+			return quarksLinks, linkInfos, linkSecrets, err
+		}
+
+		providerSecret := &corev1.Secret{}
+		providerSecretName := names.QuarksLinkSecretName(provider.Deployment, link.key)
+		err = client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: providerSecretName}, providerSecret)
+		if err != nil {
+			return quarksLinks, linkInfos, linkSecrets, errors.Wrapf(err, "failed to get link secret '%s/%s'", namespace, providerSecretName)
+		}
+
+		properties := map[string]string{}
+		for key, value := range providerSecret.Data {
+			properties[key] = string(value)
+		}
+		linkBytes, err := yaml.Marshal(properties)
+		if err != nil {
+			return quarksLinks, linkInfos, linkSecrets, errors.Wrapf(err, "failed to marshal properties of link '%s'", providerName)
+		}
+
+		svcRecord := serviceRecord{
+			selector: map[string]string{
+				bdv1.LabelDeploymentName:    provider.Deployment,
+				bdv1.LabelInstanceGroupName: link.instanceGroup,
+			},
+			dnsRecord: fmt.Sprintf("%s.%s.svc.%s", names.ServiceName(provider.Deployment, link.instanceGroup), namespace, boshdns.GetClusterDomain()),
+		}
+		instances, err := svcRecord.jobInstances(ctx, client, namespace, providerName)
+		if err != nil {
+			return quarksLinks, linkInfos, linkSecrets, errors.Wrapf(err, "failed to get job instances of link provider '%s'", providerName)
+		}
+
+		secretName := names.QuarksLinkSecretName(l.deploymentName, "consumes", providerName)
+		linkSecrets = append(linkSecrets, corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: l.namespace,
+				Labels: map[string]string{
+					bdv1.LabelDeploymentName: l.deploymentName,
+				},
+				Annotations: map[string]string{
+					bdv1.AnnotationLinkProviderDeployment: fmt.Sprintf("%s/%s", namespace, provider.Deployment),
+				},
+			},
+			StringData: map[string]string{bdm.LinkFile: string(linkBytes)},
+		})
+		linkInfos = append(linkInfos, converter.LinkInfo{
+			SecretName:   secretName,
+			ProviderName: providerName,
+			ProviderType: link.linkType,
+		})
+		quarksLinks[providerName] = bdm.QuarksLink{
+			Type:      link.linkType,
+			Address:   svcRecord.dnsRecord,
+			Instances: instances,
+		}
+		l.log.Debugf("consuming link '%s' of type '%s' from deployment '%s/%s'", providerName, link.linkType, namespace, provider.Deployment)
+	}
+
+	return quarksLinks, linkInfos, linkSecrets, nil
+}
+
+// allowsLinkConsumer returns true, if the deployment lists the consumer in its link consumers
+func allowsLinkConsumer(bdpl *bdv1.BOSHDeployment, namespace string, deploymentName string) bool {
+	for _, consumer := range bdpl.Spec.LinkConsumers {
+		consumerNamespace := consumer.Namespace
+		if consumerNamespace == "" {
+			consumerNamespace = bdpl.Namespace
+		}
+		if consumerNamespace == namespace && consumer.Deployment == deploymentName {
+			return true
+		}
+	}
+	return false
+}
+
+// findProvidedLink looks up the instance group and type of a link in the
+// latest BPM information of the providing deployment's instance groups
+func findProvidedLink(ctx context.Context, client crc.Client, namespace string, deploymentName string, linkName string) (providedLink, error) {
+	secrets := &corev1.SecretList{}
+	err := client.List(ctx, secrets,
+		crc.InNamespace(namespace),
+		crc.MatchingLabels{
+			bdv1.LabelDeploymentName:       deploymentName,
+			bdv1.LabelDeploymentSecretType: bdv1.DeploymentSecretBPMInformation.String(),
+		},
+	)
+	if err != nil {
+		return providedLink{}, errors.Wrapf(err, "listing BPM secrets of deployment '%s/%s'", namespace, deploymentName)
+	}
+
+	latest := map[string]corev1.Secret{}
+	for _, secret := range secrets.Items {
+		container := secret.Labels[qjv1a1.LabelPersistentSecretContainer]
+		version, err := vss.Version(secret)
+		if err != nil {
+			continue
+		}
+		if current, ok := latest[container]; ok {
+			if currentVersion, _ := vss.Version(current); currentVersion >= version {
+				continue
+			}
+		}
+		latest[container] = secret
+	}
+
+	links := []providedLink{}
+	for _, secret := range latest {
+		var bpmInfo bdm.BPMInfo
+		if err := yaml.Unmarshal(secret.Data["bpm.yaml"], &bpmInfo); err != nil {
+			return providedLink{}, errors.Wrapf(err, "failed to unmarshal BPM information '%s/%s'", namespace, secret.Name)
+		}
+
+		for _, link := range bpmInfo.InstanceGroup.ProvidedLinks {
+			if link.Name == linkName {
+				links = append(links, providedLink{
+					instanceGroup: bpmInfo.InstanceGroup.Name,
+					linkType:      link.Type,
+					key:           link.Key(),
+				})
+			}
+		}
+	}
+
+	switch len(links) {
+	case 0:
+		return providedLink{}, errors.Errorf("deployment '%s/%s' does not provide link '%s'", namespace, deploymentName, linkName)
+	case 1:
+		return links[0], nil
+	}
+
+	providers := make([]string, 0, len(links))
+	for _, link := range links {
+		providers = append(providers, fmt.Sprintf("%s (type %s)", link.instanceGroup, link.linkType))
+	}
+	sort.Strings(providers)
+	return providedLink{}, errors.Errorf("link '%s' of deployment '%s/%s' is ambiguous, it is provided by %s", linkName, namespace, deploymentName, strings.Join(providers, ", "))
+}
+
+// isLinkSecret returns true for the secrets, which contain the properties
+// of a link provided by an instance group
+func isLinkSecret(secret *corev1.Secret) bool {
+	labels := secret.GetLabels()
+	if _, ok := labels[bdv1.LabelDeploymentName]; !ok {
+		return false
+	}
+	_, ok := labels[bdv1.LabelDeploymentSecretType]
+	return labels[bdv1.LabelEntanglementKey] == "true" && !ok
+}
+
+// linkConsumerReconciles returns the deployments, which are allowed to
+// consume the links of the deployment owning the link secret
+func linkConsumerReconciles(ctx context.Context, client crc.Client, secret *corev1.Secret) ([]reconcile.Request, error) {
+	reconciles := []reconcile.Request{}
+
+	bdpl := &bdv1.BOSHDeployment{}
+	err := client.Get(ctx, types.NamespacedName{Namespace: secret.Namespace, Name: secret.Labels[bdv1.LabelDeploymentName]}, bdpl)
+	if err != nil {
+		return reconciles, crc.IgnoreNotFound(err)
+	}
+
+	seen := sets.NewString()
+	for _, consumer := range bdpl.Spec.LinkConsumers {
+		namespace := consumer.Namespace
+		if namespace == "" {
+			namespace = bdpl.Namespace
+		}
+		if seen.Has(namespace + "/" + consumer.Deployment) {
+			continue
+		}
+		seen.Insert(namespace + "/" + consumer.Deployment)
+
+		reconciles = append(reconciles, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: namespace, Name: consumer.Deployment},
+		})
+	}
+
+	return reconciles, nil
+}
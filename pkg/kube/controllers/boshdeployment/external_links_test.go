@@ -0,0 +1,252 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/converter"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	qsv1a1 "code.cloudfoundry.org/quarks-secret/pkg/kube/apis/quarkssecret/v1alpha1"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Links of other deployments", func() {
+	var (
+		ctx           context.Context
+		recorder      *record.FakeRecorder
+		manager       *fakes.FakeManager
+		withops       fakes.FakeWithOps
+		jobFactory    fakes.FakeJobFactory
+		kubeConverter fakes.FakeVariablesConverter
+		client        crc.Client
+		manifest      *bdm.Manifest
+		provider      *bdv1.BOSHDeployment
+		objects       []crc.Object
+		request       reconcile.Request
+	)
+
+	BeforeEach(func() {
+		_ = controllers.AddToScheme(scheme.Scheme)
+		recorder = record.NewFakeRecorder(20)
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+		withops = fakes.FakeWithOps{}
+		jobFactory = fakes.FakeJobFactory{}
+		jobFactory.InstanceGroupManifestJobReturns(&qjv1a1.QuarksJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "ig-consumer", Namespace: "staging"},
+		}, nil)
+		kubeConverter = fakes.FakeVariablesConverter{}
+		kubeConverter.VariablesReturns([]qsv1a1.QuarksSecret{}, nil)
+
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "consumer", Namespace: "staging"}}
+
+		manifest = &bdm.Manifest{
+			InstanceGroups: []*bdm.InstanceGroup{
+				{
+					Name:      "app",
+					Instances: 1,
+					Jobs: []bdm.Job{
+						{
+							Name: "app",
+							Consumes: map[string]interface{}{
+								"nats": map[string]interface{}{
+									"from":       "nats",
+									"deployment": "provider",
+									"namespace":  "default",
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		lastReconcile := metav1.NewTime(time.Now().Add(-2 * cfd.ReconcileSkipDuration))
+		provider = &bdv1.BOSHDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "provider", Namespace: "default"},
+			Spec: bdv1.BOSHDeploymentSpec{
+				LinkConsumers: []bdv1.LinkConsumer{{Namespace: "staging", Deployment: "consumer"}},
+			},
+		}
+		bpmLabels := map[string]string{
+			bdv1.LabelDeploymentName:              "provider",
+			bdv1.LabelDeploymentSecretType:        bdv1.DeploymentSecretBPMInformation.String(),
+			qjv1a1.LabelPersistentSecretContainer: "nats",
+			vss.LabelSecretKind:                   vss.VersionSecretKind,
+		}
+		objects = []crc.Object{
+			&bdv1.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "consumer", Namespace: "staging"},
+				Status:     bdv1.BOSHDeploymentStatus{LastReconcile: &lastReconcile},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      bdv1.DeploymentSecretBPMInformation.Prefix("provider") + "nats-v1",
+					Namespace: "default",
+					Labels:    labelsWithVersion(bpmLabels, "1"),
+				},
+				Data: map[string][]byte{"bpm.yaml": []byte("instance_group:\n  name: nats\n")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      bdv1.DeploymentSecretBPMInformation.Prefix("provider") + "nats-v2",
+					Namespace: "default",
+					Labels:    labelsWithVersion(bpmLabels, "2"),
+				},
+				Data: map[string][]byte{"bpm.yaml": []byte("instance_group:\n  name: nats\n  provided_links:\n  - name: nats\n    type: nats_server\n")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "link-provider-nats-server-nats",
+					Namespace: "default",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName:  "provider",
+						bdv1.LabelEntanglementKey: "true",
+					},
+				},
+				Data: map[string][]byte{"nats.user": []byte("admin")},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "nats-0",
+					Namespace: "default",
+					UID:       "1234",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName:    "provider",
+						bdv1.LabelInstanceGroupName: "nats",
+					},
+				},
+				Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(append(objects, provider)...).Build()
+		manager.GetClientReturns(client)
+		withops.ManifestReturns(manifest, nil, nil)
+	})
+
+	reconcileDeployment := func() error {
+		reconciler := cfd.NewDeploymentReconciler(
+			ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager,
+			&withops, &jobFactory, &kubeConverter, &fakes.FakeBPMConverter{},
+			controllerutil.SetControllerReference,
+		)
+		_, err := reconciler.Reconcile(context.Background(), request)
+		return err
+	}
+
+	It("copies the link secret of the provider into the consumer's namespace", func() {
+		Expect(reconcileDeployment()).To(Succeed())
+
+		secret := &corev1.Secret{}
+		Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "staging", Name: "link-consumer-consumes-nats"}, secret)).To(Succeed())
+		Expect(secret.StringData).To(HaveKeyWithValue("link", "nats.user: admin\n"))
+		Expect(secret.Annotations).To(HaveKeyWithValue(bdv1.AnnotationLinkProviderDeployment, "default/provider"))
+		Expect(secret.OwnerReferences).To(HaveLen(1))
+	})
+
+	It("adds the link with the provider's type, address and instances", func() {
+		Expect(reconcileDeployment()).To(Succeed())
+
+		Expect(jobFactory.InstanceGroupManifestJobCallCount()).To(Equal(1))
+		_, _, m, linkInfos, _ := jobFactory.InstanceGroupManifestJobArgsForCall(0)
+		Expect(linkInfos).To(Equal(converter.LinkInfos{{
+			SecretName:   "link-consumer-consumes-nats",
+			ProviderName: "nats",
+			ProviderType: "nats_server",
+		}}))
+
+		quarksLinks := m.Properties[bdm.QuarksLinksProperty].(map[string]bdm.QuarksLink)
+		Expect(quarksLinks["nats"].Type).To(Equal("nats_server"))
+		Expect(quarksLinks["nats"].Address).To(HavePrefix("provider-nats.default.svc."))
+		Expect(quarksLinks["nats"].Instances).To(HaveLen(1))
+		Expect(quarksLinks["nats"].Instances[0].Address).To(Equal("10.0.0.1"))
+	})
+
+	Context("when the provider does not allow the consumer", func() {
+		BeforeEach(func() {
+			provider.Spec.LinkConsumers = []bdv1.LinkConsumer{{Deployment: "consumer"}}
+		})
+
+		It("fails", func() {
+			err := reconcileDeployment()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("deployment 'default/provider' does not allow 'staging/consumer' to consume link 'nats'"))
+			Expect(jobFactory.InstanceGroupManifestJobCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the provider does not provide the link", func() {
+		BeforeEach(func() {
+			manifest.InstanceGroups[0].Jobs[0].Consumes["nats"] = map[string]interface{}{
+				"from":       "nats-tls",
+				"deployment": "provider",
+				"namespace":  "default",
+			}
+		})
+
+		It("fails", func() {
+			err := reconcileDeployment()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("deployment 'default/provider' does not provide link 'nats-tls'"))
+		})
+	})
+
+	Context("when the provider only provides a link, whose name ends with the link name", func() {
+		BeforeEach(func() {
+			objects[2].(*corev1.Secret).Data["bpm.yaml"] = []byte("instance_group:\n  name: nats\n  provided_links:\n  - name: tls-nats\n    type: nats_server\n")
+		})
+
+		It("fails", func() {
+			err := reconcileDeployment()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("deployment 'default/provider' does not provide link 'nats'"))
+		})
+	})
+
+	Context("when the provider provides the link name with several types", func() {
+		BeforeEach(func() {
+			objects[2].(*corev1.Secret).Data["bpm.yaml"] = []byte("instance_group:\n  name: nats\n  provided_links:\n  - name: nats\n    type: nats_server\n  - name: nats\n    type: nats_tls\n")
+		})
+
+		It("fails", func() {
+			err := reconcileDeployment()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("link 'nats' of deployment 'default/provider' is ambiguous, it is provided by nats (type nats_server), nats (type nats_tls)"))
+		})
+	})
+})
+
+func labelsWithVersion(labels map[string]string, version string) map[string]string {
+	result := map[string]string{vss.LabelVersion: version}
+	for k, v := range labels {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/withops"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/monitorednamespace"
+)
+
+// AddGit creates a new git controller, which polls the git references of
+// BOSHDeployments and records the commits in their status
+func AddGit(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "git-reconciler", mgr.GetEventRecorderFor("git-recorder"))
+	r := NewGitReconciler(ctx, config, mgr, withops.GitRepositories)
+
+	c, err := controller.New("git-controller", mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding git controller to manager failed.")
+	}
+
+	nsPred := monitorednamespace.NewNSPredicate(ctx, mgr.GetClient(), config.MonitoredID)
+
+	// Trigger for BOSHDeployments with git references, afterwards the reconciler requeues itself
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return len(gitReferences(e.Object.(*bdv1.BOSHDeployment))) > 0
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*bdv1.BOSHDeployment)
+			n := e.ObjectNew.(*bdv1.BOSHDeployment)
+			if len(gitReferences(n)) == 0 || reflect.DeepEqual(o.Spec, n.Spec) {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+				ctx, e.ObjectNew, "bdv1.BOSHDeployment",
+				fmt.Sprintf("Update predicate passed for '%s/%s' for git references", e.ObjectNew.GetNamespace(), e.ObjectNew.GetName()),
+			)
+			return true
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeployment{}}, &handler.EnqueueRequestForObject{}, nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching bosh deployment failed in git controller.")
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package boshdeployment
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/gitrepo"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// GitPollInterval is the interval in which the refs of git references are resolved
+const GitPollInterval = 1 * time.Minute
+
+// GitCommitResolver resolves a ref of a git repository to a commit
+type GitCommitResolver interface {
+	ResolveCommit(ctx context.Context, url string, ref string, creds gitrepo.Credentials) (string, error)
+}
+
+var _ reconcile.Reconciler = &ReconcileGit{}
+
+// NewGitReconciler returns a new reconcile.Reconciler
+func NewGitReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, resolver GitCommitResolver) reconcile.Reconciler {
+	return &ReconcileGit{
+		ctx:      ctx,
+		config:   config,
+		client:   mgr.GetClient(),
+		resolver: resolver,
+	}
+}
+
+// ReconcileGit polls the git references of a BOSHDeployment
+type ReconcileGit struct {
+	ctx      context.Context
+	config   *config.Config
+	client   client.Client
+	resolver GitCommitResolver
+}
+
+// Reconcile resolves the refs of all git references of the BOSHDeployment and
+// records the commits in its status. A changed commit triggers the deployment
+// reconciler, which renders the with-ops manifest from the new commit.
+func (r *ReconcileGit) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
+	// Set the ctx to be Background, as the top-level context for incoming requests.
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	log.Debugf(ctx, "Reconciling git references of BOSHDeployment '%s'", request.NamespacedName)
+	bdpl := &bdv1.BOSHDeployment{}
+	err := r.client.Get(ctx, request.NamespacedName, bdpl)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Return and don't requeue
+			log.Debug(ctx, "Skip reconcile: BOSHDeployment not found")
+			return reconcile.Result{}, nil
+		}
+
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "GetBOSHDeploymentError").Errorf(ctx, "failed to get BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	refs := gitReferences(bdpl)
+	if len(refs) == 0 {
+		log.Debugf(ctx, "Skip reconcile: BOSHDeployment '%s' has no git references", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	sources := []bdv1.GitSourceStatus{}
+	seen := map[bdv1.GitSourceStatus]bool{}
+	for _, ref := range refs {
+		source := bdv1.GitSourceStatus{URL: ref.Name, Ref: ref.Ref}
+		if seen[source] {
+			continue
+		}
+		seen[source] = true
+
+		creds := gitrepo.Credentials{}
+		if ref.SecretRef != "" {
+			secret := &corev1.Secret{}
+			err := r.client.Get(ctx, types.NamespacedName{Namespace: bdpl.Namespace, Name: ref.SecretRef}, secret)
+			if err != nil {
+				_ = log.WithEvent(bdpl, "GitError").Errorf(ctx, "failed to get credentials secret '%s/%s' for git repository '%s': %v", bdpl.Namespace, ref.SecretRef, ref.Name, err)
+				return reconcile.Result{RequeueAfter: GitPollInterval}, nil
+			}
+			creds = secret.Data
+		}
+
+		source.Commit, err = r.resolver.ResolveCommit(ctx, ref.Name, ref.Ref, creds)
+		if err != nil {
+			_ = log.WithEvent(bdpl, "GitError").Errorf(ctx, "failed to resolve git reference of BOSHDeployment '%s': %v", request.NamespacedName, err)
+			return reconcile.Result{RequeueAfter: GitPollInterval}, nil
+		}
+		sources = append(sources, source)
+	}
+
+	if !reflect.DeepEqual(sources, bdpl.Status.GitSources) {
+		for _, source := range sources {
+			log.WithEvent(bdpl, "GitCommit").Infof(ctx, "Git repository '%s' ref '%s' of BOSHDeployment '%s' is at commit '%s'", source.URL, source.Ref, request.NamespacedName, source.Commit)
+		}
+
+		bdpl.Status.GitSources = sources
+		err = r.client.Status().Update(ctx, bdpl)
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update git status on BOSHDeployment '%s' (%v): %s", request.NamespacedName, bdpl.ResourceVersion, err)
+		}
+	}
+
+	return reconcile.Result{RequeueAfter: GitPollInterval}, nil
+}
+
+// gitReferences returns the manifest and ops references of type git
+func gitReferences(bdpl *bdv1.BOSHDeployment) []bdv1.ResourceReference {
+	refs := []bdv1.ResourceReference{}
+	for _, ref := range append([]bdv1.ResourceReference{bdpl.Spec.Manifest}, bdpl.Spec.Ops...) {
+		if ref.Type == bdv1.GitReference {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
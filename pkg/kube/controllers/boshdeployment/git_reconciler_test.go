@@ -0,0 +1,147 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	bdplcontroller "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	cfakes "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileGit", func() {
+	var (
+		manager    *cfakes.FakeManager
+		reconciler reconcile.Reconciler
+		request    reconcile.Request
+		ctx        context.Context
+		log        *zap.SugaredLogger
+		config     *cfcfg.Config
+		client     *cfakes.FakeClient
+		status     *cfakes.FakeStatusWriter
+		resolver   *cfakes.FakeGitCommitResolver
+		bdpl       *bdv1.BOSHDeployment
+	)
+
+	BeforeEach(func() {
+		err := controllers.AddToScheme(scheme.Scheme)
+		Expect(err).ToNot(HaveOccurred())
+
+		manager = &cfakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}
+		config = &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		_, log = helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+
+		bdpl = &bdv1.BOSHDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: bdv1.BOSHDeploymentSpec{
+				Manifest: bdv1.ResourceReference{
+					Type:      bdv1.GitReference,
+					Name:      "https://example.com/manifests.git",
+					Ref:       "main",
+					Path:      "manifest.yml",
+					SecretRef: "git-credentials",
+				},
+				Ops: []bdv1.ResourceReference{
+					{Type: bdv1.GitReference, Name: "https://example.com/manifests.git", Ref: "main", Path: "ops.yml", SecretRef: "git-credentials"},
+					{Type: bdv1.ConfigMapReference, Name: "ops"},
+				},
+			},
+		}
+
+		status = &cfakes.FakeStatusWriter{}
+		status.UpdateCalls(func(_ context.Context, object crc.Object, _ ...crc.UpdateOption) error {
+			object.(*bdv1.BOSHDeployment).DeepCopyInto(bdpl)
+			return nil
+		})
+
+		client = &cfakes.FakeClient{}
+		client.GetCalls(func(_ context.Context, nn types.NamespacedName, object crc.Object) error {
+			switch object := object.(type) {
+			case *bdv1.BOSHDeployment:
+				bdpl.DeepCopyInto(object)
+				return nil
+			case *corev1.Secret:
+				if nn.Name == "git-credentials" {
+					object.Data = map[string][]byte{"username": []byte("user")}
+					return nil
+				}
+			}
+			return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+		})
+		client.StatusCalls(func() crc.StatusWriter { return status })
+		manager.GetClientReturns(client)
+
+		resolver = &cfakes.FakeGitCommitResolver{}
+		resolver.ResolveCommitReturns("0123456789abcdef0123456789abcdef01234567", nil)
+	})
+
+	JustBeforeEach(func() {
+		reconciler = bdplcontroller.NewGitReconciler(ctx, config, manager, resolver)
+	})
+
+	It("records the commit of each repository ref in the status", func() {
+		result, err := reconciler.Reconcile(context.Background(), request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(bdplcontroller.GitPollInterval))
+
+		Expect(resolver.ResolveCommitCallCount()).To(Equal(1))
+		_, url, ref, creds := resolver.ResolveCommitArgsForCall(0)
+		Expect(url).To(Equal("https://example.com/manifests.git"))
+		Expect(ref).To(Equal("main"))
+		Expect(string(creds["username"])).To(Equal("user"))
+
+		Expect(bdpl.Status.GitSources).To(Equal([]bdv1.GitSourceStatus{
+			{URL: "https://example.com/manifests.git", Ref: "main", Commit: "0123456789abcdef0123456789abcdef01234567"},
+		}))
+	})
+
+	It("does not update the status if the commit is unchanged", func() {
+		bdpl.Status.GitSources = []bdv1.GitSourceStatus{
+			{URL: "https://example.com/manifests.git", Ref: "main", Commit: "0123456789abcdef0123456789abcdef01234567"},
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(status.UpdateCallCount()).To(Equal(0))
+	})
+
+	It("keeps polling if the ref can't be resolved", func() {
+		resolver.ResolveCommitReturns("", errors.New("fake-error"))
+
+		result, err := reconciler.Reconcile(context.Background(), request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(bdplcontroller.GitPollInterval))
+		Expect(status.UpdateCallCount()).To(Equal(0))
+	})
+
+	It("stops polling if there are no git references", func() {
+		bdpl.Spec = bdv1.BOSHDeploymentSpec{Manifest: bdv1.ResourceReference{Type: bdv1.ConfigMapReference, Name: "manifest"}}
+
+		result, err := reconciler.Reconcile(context.Background(), request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(reconcile.Result{}))
+		Expect(resolver.ResolveCommitCallCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,210 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	qstscontroller "code.cloudfoundry.org/quarks-statefulset/pkg/kube/controllers/quarksstatefulset"
+)
+
+// instanceGroupStatuses collects the state of each instance group from its
+// QuarksStatefulSet or errand QuarksJob and the warning events of its resources.
+// Events are read with the API reader, as they are not cached.
+func instanceGroupStatuses(ctx context.Context, c client.Client, reader client.Reader, bdpl *bdv1.BOSHDeployment) ([]bdv1.InstanceGroupStatus, error) {
+	result := []bdv1.InstanceGroupStatus{}
+	// the k8s resources, which belong to an instance group
+	involved := map[string][]corev1.ObjectReference{}
+
+	qstsList := &qstsv1a1.QuarksStatefulSetList{}
+	err := c.List(ctx, qstsList, client.InNamespace(bdpl.Namespace))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list QuarksStatefulSets")
+	}
+
+	for i := range qstsList.Items {
+		qsts := &qstsList.Items[i]
+		if qsts.GetLabels()[bdv1.LabelDeploymentName] != bdpl.Name {
+			continue
+		}
+		status := bdv1.InstanceGroupStatus{
+			Name:              instanceGroupName(qsts.ObjectMeta),
+			Replicas:          desiredReplicas(qsts),
+			IGResolvedVersion: qsts.GetAnnotations()[bdv1.AnnotationIGResolvedVersion],
+			BPMVersion:        qsts.GetAnnotations()[bdv1.AnnotationBPMVersion],
+		}
+
+		statefulSets, _, err := qstscontroller.GetMaxStatefulSetVersion(ctx, c, qsts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get statefulsets of QuarksStatefulSet '%s'", qsts.GetNamespacedName())
+		}
+		refs := []corev1.ObjectReference{{Kind: qstsv1a1.QuarksStatefulSetResourceKind, Name: qsts.Name}}
+		for _, sts := range statefulSets {
+			status.ReadyReplicas += sts.Status.ReadyReplicas
+			refs = append(refs, statefulSetObjects(*sts)...)
+		}
+
+		involved[status.Name] = refs
+		result = append(result, status)
+	}
+
+	qJobList := &qjv1a1.QuarksJobList{}
+	err = c.List(ctx, qJobList, client.InNamespace(bdpl.Namespace))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list QuarksJobs")
+	}
+
+	for _, qJob := range qJobList.Items {
+		// Only errands belong to an instance group, not the deployment's manifest jobs
+		if qJob.GetLabels()[bdv1.LabelDeploymentName] != bdpl.Name {
+			continue
+		}
+		if _, ok := qJob.GetLabels()[bdv1.LabelInstanceGroupName]; !ok {
+			continue
+		}
+		completed := qJob.Status.Completed
+		status := bdv1.InstanceGroupStatus{
+			Name:      instanceGroupName(qJob.ObjectMeta),
+			Completed: &completed,
+		}
+
+		refs, err := quarksJobObjects(ctx, reader, qJob)
+		if err != nil {
+			return nil, err
+		}
+		involved[status.Name] = refs
+		result = append(result, status)
+	}
+
+	events, err := latestWarningEvents(ctx, reader, bdpl.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		event := lastWarningEvent(events, involved[result[i].Name])
+		if event == nil {
+			continue
+		}
+		timestamp := eventTimestamp(*event)
+		result[i].LastError = fmt.Sprintf("%s: %s", event.Reason, event.Message)
+		result[i].LastErrorTimestamp = &timestamp
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+// statefulSetObjects returns the statefulset and its pods, whose names are
+// derived from the ordinals
+func statefulSetObjects(sts appsv1.StatefulSet) []corev1.ObjectReference {
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	refs := []corev1.ObjectReference{{Kind: "StatefulSet", Name: sts.Name}}
+	for i := int32(0); i < replicas; i++ {
+		refs = append(refs, corev1.ObjectReference{Kind: "Pod", Name: fmt.Sprintf("%s-%d", sts.Name, i)})
+	}
+	return refs
+}
+
+// quarksJobObjects returns the QuarksJob and the jobs and pods it created
+func quarksJobObjects(ctx context.Context, reader client.Reader, qJob qjv1a1.QuarksJob) ([]corev1.ObjectReference, error) {
+	refs := []corev1.ObjectReference{{Kind: qjv1a1.QuarksJobResourceKind, Name: qJob.Name}}
+	selector := client.MatchingLabels{qjv1a1.LabelQJobName: qJob.Name}
+
+	jobs := &batchv1.JobList{}
+	if err := reader.List(ctx, jobs, client.InNamespace(qJob.Namespace), selector); err != nil {
+		return nil, errors.Wrapf(err, "failed to list jobs of QuarksJob '%s'", qJob.GetNamespacedName())
+	}
+	for _, job := range jobs.Items {
+		refs = append(refs, corev1.ObjectReference{Kind: "Job", Name: job.Name})
+	}
+
+	pods := &corev1.PodList{}
+	if err := reader.List(ctx, pods, client.InNamespace(qJob.Namespace), selector); err != nil {
+		return nil, errors.Wrapf(err, "failed to list pods of QuarksJob '%s'", qJob.GetNamespacedName())
+	}
+	for _, pod := range pods.Items {
+		refs = append(refs, corev1.ObjectReference{Kind: "Pod", Name: pod.Name})
+	}
+	return refs, nil
+}
+
+// instanceGroupName returns the instance group label, which contains the unsanitized name
+func instanceGroupName(meta metav1.ObjectMeta) string {
+	if name, ok := meta.Labels[bdv1.LabelInstanceGroupName]; ok {
+		return name
+	}
+	return meta.Name
+}
+
+// desiredReplicas returns the number of instances over all zones
+func desiredReplicas(qsts *qstsv1a1.QuarksStatefulSet) int32 {
+	replicas := int32(1)
+	if qsts.Spec.Template.Spec.Replicas != nil {
+		replicas = *qsts.Spec.Template.Spec.Replicas
+	}
+	if len(qsts.Spec.Zones) > 0 {
+		replicas = replicas * int32(len(qsts.Spec.Zones))
+	}
+	return replicas
+}
+
+// latestWarningEvents lists the warning events of the namespace once and
+// returns the latest event of each involved object, keyed by its kind and name
+func latestWarningEvents(ctx context.Context, reader client.Reader, namespace string) (map[corev1.ObjectReference]*corev1.Event, error) {
+	events := &corev1.EventList{}
+	err := reader.List(ctx, events, client.InNamespace(namespace), client.MatchingFields{"type": corev1.EventTypeWarning})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list warning events in namespace '%s'", namespace)
+	}
+
+	latest := map[corev1.ObjectReference]*corev1.Event{}
+	for i := range events.Items {
+		event := &events.Items[i]
+		key := corev1.ObjectReference{Kind: event.InvolvedObject.Kind, Name: event.InvolvedObject.Name}
+		if last, ok := latest[key]; !ok || eventTimestamp(*event).After(eventTimestamp(*last).Time) {
+			latest[key] = event
+		}
+	}
+	return latest, nil
+}
+
+// lastWarningEvent returns the latest warning event of the involved objects.
+// The events are matched by the kind and name of the objects, so only
+// events of these exact objects are considered.
+func lastWarningEvent(events map[corev1.ObjectReference]*corev1.Event, refs []corev1.ObjectReference) *corev1.Event {
+	var last *corev1.Event
+	for _, ref := range refs {
+		event, ok := events[corev1.ObjectReference{Kind: ref.Kind, Name: ref.Name}]
+		if !ok {
+			continue
+		}
+		if last == nil || eventTimestamp(*event).After(eventTimestamp(*last).Time) {
+			last = event
+		}
+	}
+	return last
+}
+
+func eventTimestamp(event corev1.Event) metav1.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp
+	}
+	if !event.EventTime.IsZero() {
+		return metav1.NewTime(event.EventTime.Time)
+	}
+	return event.CreationTimestamp
+}
@@ -0,0 +1,336 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	qsv1a1 "code.cloudfoundry.org/quarks-secret/pkg/kube/apis/quarkssecret/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	utilnames "code.cloudfoundry.org/quarks-utils/pkg/names"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+const (
+	// legacyWithOpsSecretName is the name of the with-ops manifest secret
+	// of deployments, which were created while only one deployment was
+	// allowed per namespace. Their resource names don't include the
+	// deployment name.
+	legacyWithOpsSecretName = "with-ops"
+	// legacyVariablePrefix is the prefix of the variable secrets of these deployments
+	legacyVariablePrefix = "var-"
+	// legacyInstanceGroupManifestJobName is the name of their instance group manifest QuarksJob
+	legacyInstanceGroupManifestJobName = "ig"
+)
+
+// migrateLegacyNames moves the resources of a BOSHDeployment, which was
+// created before the resource names included the deployment name, to their
+// new names. The old QuarksStatefulSets are deleted and their persistent
+// volumes are bound to the claims of the new StatefulSets. The variable
+// secrets are copied to their new names. The other legacy resources are
+// deleted, they are created again with the new names.
+// It returns true while the migration waits for old pods and claims to be
+// deleted. The legacy with-ops secret is deleted last, so an interrupted
+// migration is continued by the next reconcile.
+func (r *ReconcileBOSHDeployment) migrateLegacyNames(ctx context.Context, bdpl *bdv1.BOSHDeployment) (bool, error) {
+	secret := &corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: bdpl.Namespace, Name: legacyWithOpsSecretName}, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to get legacy with-ops manifest secret '%s/%s'", bdpl.Namespace, legacyWithOpsSecretName)
+	}
+	if secret.Labels[bdv1.LabelDeploymentName] != bdpl.Name {
+		return false, nil
+	}
+
+	legacy, err := bdm.LoadYAML(secret.Data["manifest.yaml"])
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to load legacy with-ops manifest from secret '%s/%s'", bdpl.Namespace, legacyWithOpsSecretName)
+	}
+
+	log.WithEvent(bdpl, "MigrateLegacyNames").Infof(ctx, "Migrating resources of BOSHDeployment '%s' to names including the deployment name", bdpl.GetNamespacedName())
+
+	// The legacy versioned secrets must not be updated anymore
+	err = r.deleteLegacyObject(ctx, bdpl, &qjv1a1.QuarksJob{}, legacyInstanceGroupManifestJobName, nil)
+	if err != nil {
+		return false, err
+	}
+
+	pending := false
+	for _, ig := range legacy.InstanceGroups {
+		if ig.IsErrand() {
+			err = r.deleteLegacyObject(ctx, bdpl, &qjv1a1.QuarksJob{}, ig.Name, map[string]string{bdv1.LabelInstanceGroupName: ig.Name})
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		waiting, err := r.migrateLegacyInstanceGroup(ctx, bdpl, ig)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to migrate instance group '%s'", ig.Name)
+		}
+		pending = pending || waiting
+	}
+	if pending {
+		return true, nil
+	}
+
+	err = r.migrateLegacyVariables(ctx, bdpl)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.deleteLegacyVersionedSecrets(ctx, bdpl)
+	if err != nil {
+		return false, err
+	}
+
+	// Only one deployment per namespace used the coredns resources
+	meta := metav1.ObjectMeta{Name: boshdns.AppName, Namespace: bdpl.Namespace}
+	for _, obj := range []client.Object{
+		&appsv1.Deployment{ObjectMeta: meta},
+		&corev1.Service{ObjectMeta: meta},
+		&corev1.ConfigMap{ObjectMeta: meta},
+	} {
+		if err := r.client.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return false, errors.Wrapf(err, "failed to delete legacy coredns resource '%s/%s'", bdpl.Namespace, meta.Name)
+		}
+	}
+
+	err = r.client.Delete(ctx, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "failed to delete legacy with-ops manifest secret '%s/%s'", bdpl.Namespace, legacyWithOpsSecretName)
+	}
+
+	log.WithEvent(bdpl, "MigrateLegacyNames").Infof(ctx, "Migrated resources of BOSHDeployment '%s'", bdpl.GetNamespacedName())
+	return false, nil
+}
+
+// migrateLegacyInstanceGroup deletes the legacy QuarksStatefulSet and
+// services of an instance group and moves its claims to the names used by
+// the new StatefulSets
+func (r *ReconcileBOSHDeployment) migrateLegacyInstanceGroup(ctx context.Context, bdpl *bdv1.BOSHDeployment, ig *bdm.InstanceGroup) (bool, error) {
+	legacyName := ig.NameSanitized()
+	deleted, err := r.deleteMigratedQuarksStatefulSet(ctx, bdpl, legacyName)
+	if err != nil {
+		return false, err
+	}
+	if !deleted {
+		log.Infof(ctx, "Waiting for QuarksStatefulSet '%s/%s' to be deleted", bdpl.Namespace, legacyName)
+		return true, nil
+	}
+
+	pods := &corev1.PodList{}
+	err = r.client.List(ctx, pods, client.InNamespace(bdpl.Namespace), client.MatchingLabels{
+		bdv1.LabelDeploymentName:    bdpl.Name,
+		bdv1.LabelInstanceGroupName: ig.Name,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list pods of instance group '%s'", ig.Name)
+	}
+	if len(pods.Items) > 0 {
+		log.Infof(ctx, "Waiting for %d pods of instance group '%s' to be deleted", len(pods.Items), ig.Name)
+		return true, nil
+	}
+
+	pending := false
+	claim := names.PersistentVolumeClaimName(ig.Name)
+	qstsName := names.QuarksStatefulSetName(bdpl.Name, ig.Name)
+	for _, address := range instanceAddresses(ig) {
+		from := fmt.Sprintf("%s-%s-%d", claim, address.statefulSetName(legacyName), address.ordinal)
+		to := fmt.Sprintf("%s-%s-%d", claim, address.statefulSetName(qstsName), address.ordinal)
+		waiting, err := r.movePersistentVolumeClaim(ctx, bdpl.Namespace, from, to, ig.Name)
+		if err != nil {
+			return false, err
+		}
+		pending = pending || waiting
+	}
+	if pending {
+		return true, nil
+	}
+
+	labels := map[string]string{bdv1.LabelInstanceGroupName: ig.Name}
+	serviceNames := []string{utilnames.Sanitize(ig.Name)}
+	for _, address := range instanceAddresses(ig) {
+		serviceNames = append(serviceNames, legacyIndexedServiceName(ig.Name, address))
+	}
+	for _, name := range serviceNames {
+		err = r.deleteLegacyObject(ctx, bdpl, &corev1.Service{}, name, labels)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// instanceAddresses returns the addresses of all instances of an instance group
+func instanceAddresses(ig *bdm.InstanceGroup) []instanceAddress {
+	addresses := []instanceAddress{}
+	azIndexes := []int{-1}
+	if len(ig.AZs) > 0 {
+		azIndexes = []int{}
+		for i := range ig.AZs {
+			azIndexes = append(azIndexes, i)
+		}
+	}
+
+	for _, azIndex := range azIndexes {
+		for ordinal := 0; ordinal < ig.Instances; ordinal++ {
+			addresses = append(addresses, instanceAddress{azIndex: azIndex, ordinal: ordinal})
+		}
+	}
+	return addresses
+}
+
+// legacyIndexedServiceName returns the name of the service of an instance,
+// before it included the deployment name
+func legacyIndexedServiceName(igName string, address instanceAddress) string {
+	sn := names.TruncatedServiceName(igName, 53)
+	if address.azIndex > -1 {
+		return fmt.Sprintf("%s-z%d-%d", sn, address.azIndex, address.ordinal)
+	}
+	return fmt.Sprintf("%s-%d", sn, address.ordinal)
+}
+
+// migrateLegacyVariables copies the `var-*` secrets to the names including
+// the deployment name. This includes the implicit variables, which were
+// provided by the user. Generated secrets lose the generated label, so the
+// new QuarksSecrets keep their values instead of generating new ones.
+// The legacy QuarksSecrets and the secrets they generated are deleted, user
+// provided secrets are kept.
+func (r *ReconcileBOSHDeployment) migrateLegacyVariables(ctx context.Context, bdpl *bdv1.BOSHDeployment) error {
+	secrets := &corev1.SecretList{}
+	err := r.client.List(ctx, secrets, client.InNamespace(bdpl.Namespace))
+	if err != nil {
+		return errors.Wrapf(err, "failed to list secrets in namespace '%s'", bdpl.Namespace)
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !isLegacyVariableName(secret.Name) {
+			continue
+		}
+
+		labels := map[string]string{}
+		for k, v := range secret.Labels {
+			if k == qsv1a1.LabelKind {
+				continue
+			}
+			labels[k] = v
+		}
+		annotations := map[string]string{}
+		for k, v := range secret.Annotations {
+			annotations[k] = v
+		}
+		annotations[bdv1.AnnotationMigratedFrom] = secret.Name
+
+		migrated := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        utilnames.SanitizeSubdomain(bdpl.Name + "." + secret.Name),
+				Namespace:   secret.Namespace,
+				Labels:      labels,
+				Annotations: annotations,
+			},
+			Type: secret.Type,
+			Data: secret.Data,
+		}
+		err = r.client.Create(ctx, migrated)
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create secret '%s/%s'", migrated.Namespace, migrated.Name)
+		}
+	}
+
+	qsecs := &qsv1a1.QuarksSecretList{}
+	err = r.client.List(ctx, qsecs, client.InNamespace(bdpl.Namespace), client.MatchingLabels{bdv1.LabelDeploymentName: bdpl.Name})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list QuarksSecrets of BOSHDeployment '%s'", bdpl.GetNamespacedName())
+	}
+	for i := range qsecs.Items {
+		qsec := &qsecs.Items[i]
+		if !isLegacyVariableName(qsec.Name) {
+			continue
+		}
+		err = r.client.Delete(ctx, qsec)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete QuarksSecret '%s/%s'", qsec.Namespace, qsec.Name)
+		}
+	}
+
+	return nil
+}
+
+// isLegacyVariableName returns true for `var-<name>`. The names of variable
+// secrets of a deployment `<deployment-name>.var-<name>` contain a dot.
+func isLegacyVariableName(name string) bool {
+	return strings.HasPrefix(name, legacyVariablePrefix) && !strings.Contains(name, ".")
+}
+
+// deleteLegacyVersionedSecrets deletes the desired manifest, ig-resolved and
+// bpm secrets, whose names don't start with the deployment name
+func (r *ReconcileBOSHDeployment) deleteLegacyVersionedSecrets(ctx context.Context, bdpl *bdv1.BOSHDeployment) error {
+	secrets := &corev1.SecretList{}
+	err := r.client.List(ctx, secrets, client.InNamespace(bdpl.Namespace), client.MatchingLabels{
+		bdv1.LabelDeploymentName: bdpl.Name,
+		vss.LabelSecretKind:      vss.VersionSecretKind,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list versioned secrets of BOSHDeployment '%s'", bdpl.GetNamespacedName())
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if strings.HasPrefix(secret.Name, bdpl.Name+".") {
+			continue
+		}
+		err = r.client.Delete(ctx, secret)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete secret '%s/%s'", secret.Namespace, secret.Name)
+		}
+	}
+	return nil
+}
+
+// deleteLegacyObject deletes an object of the deployment, if it has the
+// deployment's label and the given labels
+func (r *ReconcileBOSHDeployment) deleteLegacyObject(ctx context.Context, bdpl *bdv1.BOSHDeployment, obj client.Object, name string, labels map[string]string) error {
+	key := types.NamespacedName{Namespace: bdpl.Namespace, Name: name}
+	err := r.client.Get(ctx, key, obj)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to get legacy resource '%s'", key)
+	}
+
+	if obj.GetLabels()[bdv1.LabelDeploymentName] != bdpl.Name {
+		return nil
+	}
+	for k, v := range labels {
+		if obj.GetLabels()[k] != v {
+			return nil
+		}
+	}
+
+	err = r.client.Delete(ctx, obj)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete legacy resource '%s'", key)
+	}
+	return nil
+}
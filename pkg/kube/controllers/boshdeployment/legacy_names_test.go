@@ -0,0 +1,269 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	qsv1a1 "code.cloudfoundry.org/quarks-secret/pkg/kube/apis/quarkssecret/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Legacy resource names migration", func() {
+	var (
+		ctx        context.Context
+		manager    *fakes.FakeManager
+		withops    fakes.FakeWithOps
+		jobFactory fakes.FakeJobFactory
+		converter  fakes.FakeVariablesConverter
+		client     crc.Client
+		legacy     *bdm.Manifest
+		objects    []crc.Object
+		request    reconcile.Request
+	)
+
+	get := func(name string, object crc.Object) error {
+		return client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, object)
+	}
+
+	isDeleted := func(name string, object crc.Object) bool {
+		return apierrors.IsNotFound(get(name, object))
+	}
+
+	BeforeEach(func() {
+		_ = controllers.AddToScheme(scheme.Scheme)
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+		withops = fakes.FakeWithOps{}
+		jobFactory = fakes.FakeJobFactory{}
+		jobFactory.InstanceGroupManifestJobReturns(&qjv1a1.QuarksJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-ig", Namespace: "default"},
+		}, nil)
+		converter = fakes.FakeVariablesConverter{}
+		converter.VariablesReturns([]qsv1a1.QuarksSecret{}, nil)
+
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", record.NewFakeRecorder(20))
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}
+
+		legacy = &bdm.Manifest{
+			InstanceGroups: []*bdm.InstanceGroup{
+				{Name: "db", Instances: 1},
+				{Name: "smoke", Instances: 1, LifeCycle: bdm.IGTypeErrand},
+			},
+		}
+
+		lastReconcile := metav1.NewTime(time.Now().Add(-2 * cfd.ReconcileSkipDuration))
+		deploymentLabels := map[string]string{bdv1.LabelDeploymentName: "foo"}
+		igLabels := func(ig string) map[string]string {
+			return map[string]string{
+				bdv1.LabelDeploymentName:    "foo",
+				bdv1.LabelInstanceGroupName: ig,
+			}
+		}
+		objects = []crc.Object{
+			&bdv1.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+				Status:     bdv1.BOSHDeploymentStatus{LastReconcile: &lastReconcile},
+			},
+			&qstsv1a1.QuarksStatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: igLabels("db")},
+			},
+			&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "db-pvc-db-0", Namespace: "default"},
+				Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+			},
+			&corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+				Spec: corev1.PersistentVolumeSpec{
+					PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+					ClaimRef:                      &corev1.ObjectReference{Namespace: "default", Name: "db-pvc-db-0", UID: "1234"},
+				},
+			},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: igLabels("db")}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "default", Labels: igLabels("db")}},
+			&qjv1a1.QuarksJob{ObjectMeta: metav1.ObjectMeta{Name: "ig", Namespace: "default", Labels: deploymentLabels}},
+			&qjv1a1.QuarksJob{ObjectMeta: metav1.ObjectMeta{Name: "smoke", Namespace: "default", Labels: igLabels("smoke")}},
+			&qsv1a1.QuarksSecret{ObjectMeta: metav1.ObjectMeta{Name: "var-password", Namespace: "default", Labels: deploymentLabels}},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "var-password",
+					Namespace: "default",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName: "foo",
+						qsv1a1.LabelKind:         qsv1a1.GeneratedSecretKind,
+					},
+				},
+				Data: map[string][]byte{"password": []byte("secret")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "var-system-domain", Namespace: "default"},
+				Data:       map[string][]byte{"value": []byte("example.com")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "bpm.db-v1",
+					Namespace: "default",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName: "foo",
+						vss.LabelSecretKind:      vss.VersionSecretKind,
+						vss.LabelVersion:         "1",
+					},
+				},
+			},
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: boshdns.AppName, Namespace: "default"}},
+		}
+	})
+
+	JustBeforeEach(func() {
+		legacyBytes, err := legacy.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+		objects = append(objects, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "with-ops",
+				Namespace: "default",
+				Labels:    map[string]string{bdv1.LabelDeploymentName: "foo"},
+			},
+			Data: map[string][]byte{"manifest.yaml": legacyBytes},
+		})
+
+		client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+		manager.GetClientReturns(client)
+		withops.ManifestReturns(&bdm.Manifest{InstanceGroups: []*bdm.InstanceGroup{{Name: "db", Instances: 1}}}, nil, nil)
+	})
+
+	reconcileResult := func() (reconcile.Result, error) {
+		reconciler := cfd.NewDeploymentReconciler(
+			ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager,
+			&withops, &jobFactory, &converter, &fakes.FakeBPMConverter{},
+			controllerutil.SetControllerReference,
+		)
+		return reconciler.Reconcile(context.Background(), request)
+	}
+
+	It("binds the persistent volume to the claim of the new StatefulSet", func() {
+		_, err := reconcileResult()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isDeleted("db", &qstsv1a1.QuarksStatefulSet{})).To(BeTrue())
+		Expect(isDeleted("db-pvc-db-0", &corev1.PersistentVolumeClaim{})).To(BeTrue())
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(get("db-pvc-foo-db-0", pvc)).To(Succeed())
+		Expect(pvc.Spec.VolumeName).To(Equal("pv-1"))
+
+		pv := &corev1.PersistentVolume{}
+		Expect(client.Get(context.Background(), types.NamespacedName{Name: "pv-1"}, pv)).To(Succeed())
+		Expect(pv.Spec.ClaimRef.Name).To(Equal("db-pvc-foo-db-0"))
+		Expect(pv.Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimDelete))
+	})
+
+	It("copies the variable secrets to their new names", func() {
+		_, err := reconcileResult()
+		Expect(err).NotTo(HaveOccurred())
+
+		secret := &corev1.Secret{}
+		Expect(get("foo.var-password", secret)).To(Succeed())
+		Expect(secret.Data).To(HaveKeyWithValue("password", []byte("secret")))
+		Expect(secret.Labels).NotTo(HaveKey(qsv1a1.LabelKind))
+		Expect(secret.Annotations).To(HaveKeyWithValue(bdv1.AnnotationMigratedFrom, "var-password"))
+		Expect(isDeleted("var-password", &qsv1a1.QuarksSecret{})).To(BeTrue())
+
+		Expect(get("foo.var-system-domain", secret)).To(Succeed())
+		Expect(secret.Data).To(HaveKeyWithValue("value", []byte("example.com")))
+		// user provided secrets are kept
+		Expect(get("var-system-domain", secret)).To(Succeed())
+	})
+
+	It("deletes the other legacy resources", func() {
+		_, err := reconcileResult()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isDeleted("db", &corev1.Service{})).To(BeTrue())
+		Expect(isDeleted("db-0", &corev1.Service{})).To(BeTrue())
+		Expect(isDeleted("ig", &qjv1a1.QuarksJob{})).To(BeTrue())
+		Expect(isDeleted("smoke", &qjv1a1.QuarksJob{})).To(BeTrue())
+		Expect(isDeleted("bpm.db-v1", &corev1.Secret{})).To(BeTrue())
+		Expect(isDeleted(boshdns.AppName, &appsv1.Deployment{})).To(BeTrue())
+		Expect(isDeleted("with-ops", &corev1.Secret{})).To(BeTrue())
+	})
+
+	It("deploys the instance groups with their new names afterwards", func() {
+		_, err := reconcileResult()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(withops.ManifestCallCount()).To(Equal(1))
+		Expect(get("foo.with-ops", &corev1.Secret{})).To(Succeed())
+	})
+
+	Context("when the pods of the legacy instance group are still running", func() {
+		BeforeEach(func() {
+			objects = append(objects, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "db-0",
+					Namespace: "default",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName:    "foo",
+						bdv1.LabelInstanceGroupName: "db",
+					},
+				},
+			})
+		})
+
+		It("requeues before resolving the manifest", func() {
+			result, err := reconcileResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(cfd.MigrationRequeueInterval))
+			Expect(withops.ManifestCallCount()).To(Equal(0))
+
+			Expect(get("db-pvc-db-0", &corev1.PersistentVolumeClaim{})).To(Succeed())
+			Expect(get("with-ops", &corev1.Secret{})).To(Succeed())
+			Expect(isDeleted("foo.var-password", &corev1.Secret{})).To(BeTrue())
+		})
+	})
+
+	Context("when the legacy resources belong to another deployment", func() {
+		BeforeEach(func() {
+			request.Name = "bar"
+			objects = append(objects, &bdv1.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"},
+				Status:     bdv1.BOSHDeploymentStatus{LastReconcile: objects[0].(*bdv1.BOSHDeployment).Status.LastReconcile},
+			})
+		})
+
+		It("doesn't touch them", func() {
+			_, err := reconcileResult()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(get("db", &qstsv1a1.QuarksStatefulSet{})).To(Succeed())
+			Expect(get("with-ops", &corev1.Secret{})).To(Succeed())
+			Expect(isDeleted("bar.var-password", &corev1.Secret{})).To(BeTrue())
+		})
+	})
+})
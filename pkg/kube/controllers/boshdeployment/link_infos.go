@@ -29,18 +29,41 @@ type linkInfoService struct {
 }
 
 // List returns a LinkInfos struct containing link providers if needed
-// and updates `quarks_links` properties
-func (l *linkInfoService) List(ctx context.Context, client crc.Client, manifest *bdm.Manifest) (converter.LinkInfos, error) {
+// and updates `quarks_links` properties. It also returns the copies of the
+// link secrets of other deployments, which need to be applied.
+func (l *linkInfoService) List(ctx context.Context, client crc.Client, manifest *bdm.Manifest) (converter.LinkInfos, []corev1.Secret, error) {
 	// find all missing providers in the manifest, so we can look for secrets
 	missingProviders := manifest.ListMissingProviders()
-	if len(missingProviders) == 0 {
+	externalProviders := manifest.ListExternalProviders()
+	if len(missingProviders) == 0 && len(externalProviders) == 0 {
 		l.log.Debug("manifest is not missing any link providers")
-		return converter.LinkInfos{}, nil
+		return converter.LinkInfos{}, nil, nil
 	}
 
-	quarksLinks, linkInfos, err := l.nativeQuarksLinks(ctx, client, missingProviders)
-	if err != nil {
-		return linkInfos, err
+	quarksLinks := map[string]bdm.QuarksLink{}
+	linkInfos := converter.LinkInfos{}
+	if len(missingProviders) != 0 {
+		nativeLinks, nativeInfos, err := l.nativeQuarksLinks(ctx, client, missingProviders)
+		if err != nil {
+			return nativeInfos, nil, err
+		}
+		for name, link := range nativeLinks {
+			quarksLinks[name] = link
+		}
+		linkInfos = append(linkInfos, nativeInfos...)
+	}
+
+	var linkSecrets []corev1.Secret
+	if len(externalProviders) != 0 {
+		externalLinks, externalInfos, secrets, err := l.externalQuarksLinks(ctx, client, externalProviders)
+		if err != nil {
+			return linkInfos, nil, err
+		}
+		for name, link := range externalLinks {
+			quarksLinks[name] = link
+		}
+		linkInfos = append(linkInfos, externalInfos...)
+		linkSecrets = secrets
 	}
 
 	if len(quarksLinks) != 0 {
@@ -49,7 +72,7 @@ func (l *linkInfoService) List(ctx context.Context, client crc.Client, manifest
 		}
 		manifest.Properties[bdm.QuarksLinksProperty] = quarksLinks
 	}
-	return linkInfos, err
+	return linkInfos, linkSecrets, nil
 }
 
 // nativeQuarksLinks finds secrets for all missing links. It creates the link
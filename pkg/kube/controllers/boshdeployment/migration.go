@@ -0,0 +1,572 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	utilnames "code.cloudfoundry.org/quarks-utils/pkg/names"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+// MigrationRequeueInterval is the interval in which a migration checks, if the
+// pods and claims of the old instance group are deleted
+const MigrationRequeueInterval = 5 * time.Second
+
+// instanceAddress identifies an instance of an instance group by the index
+// of its az and its pod ordinal. The az index is -1 for instance groups
+// without azs.
+type instanceAddress struct {
+	azIndex int
+	ordinal int
+}
+
+// label returns the az index used by the labels of pods and services
+func (a instanceAddress) label() string {
+	if a.azIndex < 0 {
+		return "0"
+	}
+	return strconv.Itoa(a.azIndex)
+}
+
+// statefulSetName returns the name of the StatefulSet, which runs the instance
+func (a instanceAddress) statefulSetName(qstsName string) string {
+	if a.azIndex < 0 {
+		return qstsName
+	}
+	return fmt.Sprintf("%s-z%d", qstsName, a.azIndex)
+}
+
+// instanceMove maps an instance of a migrated instance group to an instance
+// of the instance group it migrated to
+type instanceMove struct {
+	from instanceAddress
+	to   instanceAddress
+}
+
+// instanceGroupMigration moves the instances of an instance group listed in
+// migrated_from to the instance group, which lists it
+type instanceGroupMigration struct {
+	from  *bdm.InstanceGroup
+	to    *bdm.InstanceGroup
+	moves []instanceMove
+}
+
+// nextOrdinals returns the first free pod ordinal per az index of the
+// instance group. Instances of an existing instance group are kept, migrated
+// instances are added.
+func nextOrdinals(ig *bdm.InstanceGroup, current *bdm.Manifest) map[int]int {
+	next := map[int]int{}
+	existing, found := current.InstanceGroups.InstanceGroupByName(ig.Name)
+	if !found {
+		return next
+	}
+
+	if len(ig.AZs) == 0 {
+		next[-1] = existing.Instances
+	}
+	for i := range ig.AZs {
+		next[i] = existing.Instances
+	}
+	return next
+}
+
+// instanceMoves returns the new addresses of all instances of the migrated
+// instance group and advances the next free ordinals. Instances of instance
+// groups without azs are placed in the az of the migrated_from entry.
+func instanceMoves(old *bdm.InstanceGroup, from *bdm.MigratedFrom, ig *bdm.InstanceGroup, next map[int]int) ([]instanceMove, error) {
+	zones := old.AZs
+	if len(zones) == 0 {
+		zones = []string{from.Az}
+	}
+
+	moves := []instanceMove{}
+	for i, zone := range zones {
+		fromAZ := i
+		if len(old.AZs) == 0 {
+			fromAZ = -1
+		}
+
+		toAZ := -1
+		if len(ig.AZs) > 0 {
+			toAZ = indexOf(ig.AZs, zone)
+			if toAZ < 0 {
+				return moves, errors.Errorf("az '%s' of instance group '%s' is not an az of instance group '%s'", zone, old.Name, ig.Name)
+			}
+		}
+
+		for ordinal := 0; ordinal < old.Instances; ordinal++ {
+			moves = append(moves, instanceMove{
+				from: instanceAddress{azIndex: fromAZ, ordinal: ordinal},
+				to:   instanceAddress{azIndex: toAZ, ordinal: next[toAZ]},
+			})
+			next[toAZ]++
+		}
+	}
+
+	return moves, nil
+}
+
+func indexOf(list []string, s string) int {
+	for i, e := range list {
+		if e == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// migrateInstanceGroups implements BOSH's migrated_from. The persistent
+// volume claims, services and secrets of instance groups, which were renamed
+// or merged into another instance group, are moved to the new instance group,
+// after the QuarksStatefulSets of the old instance groups are deleted.
+// It returns true while the migration waits for old pods and claims to be
+// deleted, the BOSHDeployment needs to be reconciled again.
+func (r *ReconcileBOSHDeployment) migrateInstanceGroups(ctx context.Context, bdpl *bdv1.BOSHDeployment, manifest *bdm.Manifest) (bool, error) {
+	migrated := false
+	for _, ig := range manifest.InstanceGroups {
+		if len(ig.MigratedFrom) > 0 {
+			migrated = true
+			break
+		}
+	}
+	if !migrated {
+		return false, nil
+	}
+
+	current, err := r.currentManifest(ctx, bdpl)
+	if err != nil {
+		return false, err
+	}
+
+	// All migrations are validated, before any resources are changed
+	migrations := []instanceGroupMigration{}
+	for _, ig := range manifest.InstanceGroups {
+		if len(ig.MigratedFrom) == 0 {
+			continue
+		}
+
+		next := nextOrdinals(ig, current)
+		for _, from := range ig.MigratedFrom {
+			if _, found := manifest.InstanceGroups.InstanceGroupByName(from.Name); found {
+				return false, errors.Errorf("instance group '%s' can't migrate from instance group '%s', which is still part of the manifest", ig.Name, from.Name)
+			}
+
+			// Nothing to do, if the instance group was migrated before
+			old, found := current.InstanceGroups.InstanceGroupByName(from.Name)
+			if !found {
+				continue
+			}
+
+			moves, err := instanceMoves(old, from, ig, next)
+			if err != nil {
+				return false, err
+			}
+			migrations = append(migrations, instanceGroupMigration{from: old, to: ig, moves: moves})
+		}
+	}
+
+	pending := false
+	for _, m := range migrations {
+		log.WithEvent(bdpl, "MigrateInstanceGroup").Infof(ctx, "Migrating instance group '%s' to '%s' for BOSHDeployment '%s'", m.from.Name, m.to.Name, bdpl.GetNamespacedName())
+		waiting, err := r.migrateInstanceGroup(ctx, bdpl, m.from, m.to, m.moves)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to migrate instance group '%s' to '%s'", m.from.Name, m.to.Name)
+		}
+		pending = pending || waiting
+	}
+
+	return pending, nil
+}
+
+func (r *ReconcileBOSHDeployment) migrateInstanceGroup(ctx context.Context, bdpl *bdv1.BOSHDeployment, old *bdm.InstanceGroup, ig *bdm.InstanceGroup, moves []instanceMove) (bool, error) {
+	// The old pods need to be gone, before their volumes can be bound to new claims
+	oldQstsName := names.QuarksStatefulSetName(bdpl.Name, old.Name)
+	deleted, err := r.deleteMigratedQuarksStatefulSet(ctx, bdpl, oldQstsName)
+	if err != nil {
+		return false, err
+	}
+	if !deleted {
+		log.Infof(ctx, "Waiting for QuarksStatefulSet '%s/%s' to be deleted", bdpl.Namespace, oldQstsName)
+		return true, nil
+	}
+
+	pods := &corev1.PodList{}
+	err = r.client.List(ctx, pods, client.InNamespace(bdpl.Namespace), client.MatchingLabels{
+		bdv1.LabelDeploymentName:    bdpl.Name,
+		bdv1.LabelInstanceGroupName: old.Name,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list pods of instance group '%s'", old.Name)
+	}
+	if len(pods.Items) > 0 {
+		log.Infof(ctx, "Waiting for %d pods of instance group '%s' to be deleted", len(pods.Items), old.Name)
+		return true, nil
+	}
+
+	pending := false
+	oldClaim := names.PersistentVolumeClaimName(old.Name)
+	newClaim := names.PersistentVolumeClaimName(ig.Name)
+	for _, move := range moves {
+		from := fmt.Sprintf("%s-%s-%d", oldClaim, move.from.statefulSetName(oldQstsName), move.from.ordinal)
+		to := fmt.Sprintf("%s-%s-%d", newClaim, move.to.statefulSetName(names.QuarksStatefulSetName(bdpl.Name, ig.Name)), move.to.ordinal)
+		waiting, err := r.movePersistentVolumeClaim(ctx, bdpl.Namespace, from, to, ig.Name)
+		if err != nil {
+			return false, err
+		}
+		pending = pending || waiting
+	}
+	if pending {
+		return true, nil
+	}
+
+	err = r.moveServices(ctx, bdpl, old.Name, ig.Name, moves)
+	if err != nil {
+		return false, err
+	}
+
+	return false, r.moveSecrets(ctx, bdpl, old.Name, ig.Name)
+}
+
+// deleteMigratedQuarksStatefulSet deletes the QuarksStatefulSet of a migrated
+// instance group and its pods. It returns true once it is gone.
+func (r *ReconcileBOSHDeployment) deleteMigratedQuarksStatefulSet(ctx context.Context, bdpl *bdv1.BOSHDeployment, name string) (bool, error) {
+	key := types.NamespacedName{Namespace: bdpl.Namespace, Name: name}
+	qsts := &qstsv1a1.QuarksStatefulSet{}
+	err := r.client.Get(ctx, key, qsts)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, errors.Wrapf(err, "failed to get QuarksStatefulSet '%s'", key)
+	}
+	if qsts.Labels[bdv1.LabelDeploymentName] != bdpl.Name {
+		return true, nil
+	}
+
+	if qsts.DeletionTimestamp == nil {
+		err = r.client.Delete(ctx, qsts, client.PropagationPolicy(metav1.DeletePropagationForeground))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, errors.Wrapf(err, "failed to delete QuarksStatefulSet '%s'", key)
+		}
+	}
+
+	// Foreground deletion keeps the QuarksStatefulSet until its StatefulSets and pods are deleted
+	err = r.client.Get(ctx, key, qsts)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get QuarksStatefulSet '%s'", key)
+	}
+	return false, nil
+}
+
+// movePersistentVolumeClaim replaces the claim of a persistent volume with a
+// claim for the StatefulSet of the new instance group. The volume is retained
+// while it has no claim. It is bound to the new claim after the old claim is
+// deleted, until then it returns true.
+func (r *ReconcileBOSHDeployment) movePersistentVolumeClaim(ctx context.Context, namespace string, from string, to string, igName string) (bool, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: from}, pvc)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, r.bindPersistentVolume(ctx, namespace, from, to)
+		}
+		return false, errors.Wrapf(err, "failed to get persistent volume claim '%s/%s'", namespace, from)
+	}
+
+	if pvc.DeletionTimestamp != nil {
+		log.Infof(ctx, "Waiting for persistent volume claim '%s/%s' to be deleted", namespace, from)
+		return true, nil
+	}
+
+	// An unbound claim has no data, which needs to be kept
+	if pvc.Spec.VolumeName == "" {
+		err = r.client.Delete(ctx, pvc)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, errors.Wrapf(err, "failed to delete persistent volume claim '%s/%s'", namespace, from)
+		}
+		return false, nil
+	}
+
+	pv := &corev1.PersistentVolume{}
+	err = r.client.Get(ctx, types.NamespacedName{Name: pvc.Spec.VolumeName}, pv)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get persistent volume '%s'", pvc.Spec.VolumeName)
+	}
+
+	reclaimPolicy := pv.Spec.PersistentVolumeReclaimPolicy
+	if reclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+		pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+		err = r.client.Update(ctx, pv)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to retain persistent volume '%s'", pv.Name)
+		}
+	}
+
+	labels := map[string]string{}
+	for k, v := range pvc.Labels {
+		labels[k] = v
+	}
+	if _, ok := labels[bdv1.LabelInstanceGroupName]; ok {
+		labels[bdv1.LabelInstanceGroupName] = igName
+	}
+
+	// The original reclaim policy is restored, when the volume is bound to the new claim
+	newPVC := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      to,
+			Namespace: namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				bdv1.AnnotationMigratedFrom:          from,
+				bdv1.AnnotationMigratedReclaimPolicy: string(reclaimPolicy),
+			},
+		},
+		Spec: *pvc.Spec.DeepCopy(),
+	}
+	err = r.client.Create(ctx, newPVC)
+	if err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return false, errors.Wrapf(err, "failed to create persistent volume claim '%s/%s'", namespace, to)
+		}
+
+		existing := &corev1.PersistentVolumeClaim{}
+		err = r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: to}, existing)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to get persistent volume claim '%s/%s'", namespace, to)
+		}
+		if existing.Spec.VolumeName != pvc.Spec.VolumeName {
+			return false, errors.Errorf("persistent volume claim '%s/%s' already exists for another volume", namespace, to)
+		}
+	}
+
+	err = r.client.Delete(ctx, pvc)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "failed to delete persistent volume claim '%s/%s'", namespace, from)
+	}
+
+	// The pvc-protection finalizer can keep the old claim a while
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: from}, pvc)
+	if apierrors.IsNotFound(err) {
+		return false, r.bindPersistentVolume(ctx, namespace, from, to)
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get persistent volume claim '%s/%s'", namespace, from)
+	}
+	log.Infof(ctx, "Waiting for persistent volume claim '%s/%s' to be deleted", namespace, from)
+	return true, nil
+}
+
+// bindPersistentVolume binds the volume of a migrated claim to the new claim
+// and restores its reclaim policy
+func (r *ReconcileBOSHDeployment) bindPersistentVolume(ctx context.Context, namespace string, from string, to string) error {
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: to}, pvc)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to get persistent volume claim '%s/%s'", namespace, to)
+	}
+	if pvc.Annotations[bdv1.AnnotationMigratedFrom] != from || pvc.Spec.VolumeName == "" {
+		return nil
+	}
+
+	pv := &corev1.PersistentVolume{}
+	err = r.client.Get(ctx, types.NamespacedName{Name: pvc.Spec.VolumeName}, pv)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get persistent volume '%s'", pvc.Spec.VolumeName)
+	}
+	if ref := pv.Spec.ClaimRef; ref != nil && ref.Namespace == namespace && ref.Name == to {
+		return nil
+	}
+
+	pv.Spec.ClaimRef = &corev1.ObjectReference{
+		Kind:       "PersistentVolumeClaim",
+		APIVersion: "v1",
+		Namespace:  namespace,
+		Name:       to,
+	}
+	if policy, ok := pvc.Annotations[bdv1.AnnotationMigratedReclaimPolicy]; ok {
+		pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimPolicy(policy)
+	}
+	err = r.client.Update(ctx, pv)
+	if err != nil {
+		return errors.Wrapf(err, "failed to bind persistent volume '%s' to claim '%s/%s'", pv.Name, namespace, to)
+	}
+
+	log.Infof(ctx, "Moved persistent volume '%s' from claim '%s' to '%s'", pv.Name, from, to)
+	return nil
+}
+
+// moveServices changes the services of the old instance group to select the
+// pods of the new instance group. Their names and DNS entries are kept.
+func (r *ReconcileBOSHDeployment) moveServices(ctx context.Context, bdpl *bdv1.BOSHDeployment, from string, to string, moves []instanceMove) error {
+	services := &corev1.ServiceList{}
+	err := r.client.List(ctx, services, client.InNamespace(bdpl.Namespace), client.MatchingLabels{
+		bdv1.LabelDeploymentName:    bdpl.Name,
+		bdv1.LabelInstanceGroupName: from,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list services for instance group '%s'", from)
+	}
+
+	for i := range services.Items {
+		svc := &services.Items[i]
+		svc.Labels[bdv1.LabelInstanceGroupName] = to
+		if svc.Spec.Selector == nil {
+			svc.Spec.Selector = map[string]string{}
+		}
+		svc.Spec.Selector[bdv1.LabelInstanceGroupName] = to
+
+		// Services of single instances select the pod by az index and ordinal
+		if ordinal, ok := svc.Labels[qstsv1a1.LabelPodOrdinal]; ok {
+			move, found := findMove(moves, svc.Labels[qstsv1a1.LabelAZIndex], ordinal)
+			if !found {
+				continue
+			}
+			for _, m := range []map[string]string{svc.Labels, svc.Spec.Selector} {
+				m[qstsv1a1.LabelAZIndex] = move.to.label()
+				m[qstsv1a1.LabelPodOrdinal] = strconv.Itoa(move.to.ordinal)
+			}
+		}
+
+		err = r.client.Update(ctx, svc)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update service '%s/%s'", svc.Namespace, svc.Name)
+		}
+	}
+
+	return nil
+}
+
+func findMove(moves []instanceMove, azIndex string, ordinal string) (instanceMove, bool) {
+	for _, move := range moves {
+		if move.from.label() == azIndex && strconv.Itoa(move.from.ordinal) == ordinal {
+			return move, true
+		}
+	}
+	return instanceMove{}, false
+}
+
+// moveSecrets moves the quarks-link and versioned secrets of the old instance
+// group to the new one. Versioned secrets are immutable, so they are copied
+// to the names of the new instance group, keeping their version.
+// The names of link secrets only contain the deployment name and the link
+// type and name, so they are kept and only relabelled for the container of
+// the new instance group, which updates them.
+func (r *ReconcileBOSHDeployment) moveSecrets(ctx context.Context, bdpl *bdv1.BOSHDeployment, from string, to string) error {
+	secrets := &corev1.SecretList{}
+	err := r.client.List(ctx, secrets, client.InNamespace(bdpl.Namespace), client.MatchingLabels{
+		bdv1.LabelDeploymentName: bdpl.Name,
+		qjv1a1.LabelRemoteID:     from,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list secrets for instance group '%s'", from)
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		secret.Labels[qjv1a1.LabelRemoteID] = to
+		secret.Labels[qjv1a1.LabelPersistentSecretContainer] = utilnames.Sanitize(to)
+
+		if !vss.IsVersionedSecret(*secret) {
+			err = r.client.Update(ctx, secret)
+			if err != nil {
+				return errors.Wrapf(err, "failed to update secret '%s/%s'", secret.Namespace, secret.Name)
+			}
+			continue
+		}
+
+		name, err := migratedSecretName(bdpl.Name, secret, to)
+		if err != nil {
+			return err
+		}
+
+		annotations := map[string]string{}
+		for k, v := range secret.Annotations {
+			annotations[k] = v
+		}
+		annotations[bdv1.AnnotationMigratedFrom] = secret.Name
+
+		migrated := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       secret.Namespace,
+				Labels:          secret.Labels,
+				Annotations:     annotations,
+				OwnerReferences: secret.OwnerReferences,
+			},
+			Type: secret.Type,
+			Data: secret.Data,
+		}
+		err = r.client.Create(ctx, migrated)
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create secret '%s/%s'", migrated.Namespace, migrated.Name)
+		}
+
+		err = r.client.Delete(ctx, secret)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete secret '%s/%s'", secret.Namespace, secret.Name)
+		}
+	}
+
+	return nil
+}
+
+// migratedSecretName returns the name of the versioned secret for the new instance group
+func migratedSecretName(deploymentName string, secret *corev1.Secret, igName string) (string, error) {
+	version, err := vss.Version(*secret)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get version of secret '%s/%s'", secret.Namespace, secret.Name)
+	}
+
+	var prefix string
+	switch secret.Labels[bdv1.LabelDeploymentSecretType] {
+	case bdv1.DeploymentSecretTypeInstanceGroupResolvedProperties.String():
+		prefix = bdv1.DeploymentSecretTypeInstanceGroupResolvedProperties.Prefix(deploymentName)
+	case bdv1.DeploymentSecretBPMInformation.String():
+		prefix = bdv1.DeploymentSecretBPMInformation.Prefix(deploymentName)
+	default:
+		return "", errors.Errorf("secret '%s/%s' has an unknown secret type", secret.Namespace, secret.Name)
+	}
+
+	return vss.VersionedName(prefix+utilnames.Sanitize(igName), version), nil
+}
+
+// currentManifest returns the deployed with-ops manifest, or an empty
+// manifest before the first deployment
+func (r *ReconcileBOSHDeployment) currentManifest(ctx context.Context, bdpl *bdv1.BOSHDeployment) (*bdm.Manifest, error) {
+	secretName := names.DeploymentSecretName(bdv1.DeploymentSecretTypeManifestWithOps, bdpl.Name)
+	secret := &corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: bdpl.Namespace, Name: secretName}, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return &bdm.Manifest{}, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get with-ops manifest secret '%s/%s'", bdpl.Namespace, secretName)
+	}
+
+	current, err := bdm.LoadYAML(secret.Data["manifest.yaml"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load with-ops manifest from secret '%s/%s'", bdpl.Namespace, secretName)
+	}
+
+	return current, nil
+}
@@ -0,0 +1,324 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	qsv1a1 "code.cloudfoundry.org/quarks-secret/pkg/kube/apis/quarkssecret/v1alpha1"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Instance group migration", func() {
+	var (
+		ctx        context.Context
+		recorder   *record.FakeRecorder
+		manager    *fakes.FakeManager
+		withops    fakes.FakeWithOps
+		jobFactory fakes.FakeJobFactory
+		converter  fakes.FakeVariablesConverter
+		client     crc.Client
+		manifest   *bdm.Manifest
+		current    *bdm.Manifest
+		objects    []crc.Object
+		request    reconcile.Request
+	)
+
+	get := func(name string, object crc.Object) error {
+		return client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, object)
+	}
+
+	BeforeEach(func() {
+		_ = controllers.AddToScheme(scheme.Scheme)
+		recorder = record.NewFakeRecorder(20)
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+		withops = fakes.FakeWithOps{}
+		jobFactory = fakes.FakeJobFactory{}
+		jobFactory.InstanceGroupManifestJobReturns(&qjv1a1.QuarksJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "ig-foo", Namespace: "default"},
+		}, nil)
+		converter = fakes.FakeVariablesConverter{}
+		converter.VariablesReturns([]qsv1a1.QuarksSecret{}, nil)
+
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}
+
+		current = &bdm.Manifest{
+			InstanceGroups: []*bdm.InstanceGroup{{Name: "db", Instances: 1}},
+		}
+		manifest = &bdm.Manifest{
+			InstanceGroups: []*bdm.InstanceGroup{
+				{
+					Name:         "database",
+					Instances:    1,
+					MigratedFrom: []*bdm.MigratedFrom{{Name: "db"}},
+				},
+			},
+		}
+
+		lastReconcile := metav1.NewTime(time.Now().Add(-2 * cfd.ReconcileSkipDuration))
+		deploymentLabels := map[string]string{
+			bdv1.LabelDeploymentName:    "foo",
+			bdv1.LabelInstanceGroupName: "db",
+		}
+		objects = []crc.Object{
+			&bdv1.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+				Status:     bdv1.BOSHDeploymentStatus{LastReconcile: &lastReconcile},
+			},
+			&qstsv1a1.QuarksStatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-db", Namespace: "default", Labels: deploymentLabels},
+			},
+			&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "db-pvc-foo-db-0", Namespace: "default"},
+				Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+			},
+			&corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+				Spec: corev1.PersistentVolumeSpec{
+					PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+					ClaimRef:                      &corev1.ObjectReference{Namespace: "default", Name: "db-pvc-foo-db-0", UID: "1234"},
+				},
+			},
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-db-0",
+					Namespace: "default",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName:    "foo",
+						bdv1.LabelInstanceGroupName: "db",
+						qstsv1a1.LabelAZIndex:       "0",
+						qstsv1a1.LabelPodOrdinal:    "0",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Selector: map[string]string{
+						bdv1.LabelDeploymentName:    "foo",
+						bdv1.LabelInstanceGroupName: "db",
+						qstsv1a1.LabelAZIndex:       "0",
+						qstsv1a1.LabelPodOrdinal:    "0",
+					},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      bdv1.DeploymentSecretBPMInformation.Prefix("foo") + "db-v1",
+					Namespace: "default",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName:              "foo",
+						bdv1.LabelDeploymentSecretType:        bdv1.DeploymentSecretBPMInformation.String(),
+						qjv1a1.LabelPersistentSecretContainer: "db",
+						qjv1a1.LabelRemoteID:                  "db",
+						vss.LabelVersion:                      "1",
+						vss.LabelSecretKind:                   vss.VersionSecretKind,
+					},
+				},
+				Data: map[string][]byte{"bpm.yaml": []byte("processes: []")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "link-foo-database-db",
+					Namespace: "default",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName:              "foo",
+						qjv1a1.LabelPersistentSecretContainer: "db",
+						qjv1a1.LabelRemoteID:                  "db",
+					},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		currentBytes, err := current.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+		objects = append(objects, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo.with-ops", Namespace: "default"},
+			Data:       map[string][]byte{"manifest.yaml": currentBytes},
+		})
+
+		client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+		manager.GetClientReturns(client)
+		withops.ManifestReturns(manifest, nil, nil)
+	})
+
+	reconcileResult := func() (reconcile.Result, error) {
+		reconciler := cfd.NewDeploymentReconciler(
+			ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager,
+			&withops, &jobFactory, &converter, &fakes.FakeBPMConverter{},
+			controllerutil.SetControllerReference,
+		)
+		return reconciler.Reconcile(context.Background(), request)
+	}
+
+	reconcileDeployment := func() error {
+		_, err := reconcileResult()
+		return err
+	}
+
+	It("deletes the QuarksStatefulSet of the old instance group", func() {
+		Expect(reconcileDeployment()).To(Succeed())
+
+		err := get("foo-db", &qstsv1a1.QuarksStatefulSet{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("binds the persistent volume to a claim of the new instance group", func() {
+		Expect(reconcileDeployment()).To(Succeed())
+
+		err := get("db-pvc-foo-db-0", &corev1.PersistentVolumeClaim{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(get("database-pvc-foo-database-0", pvc)).To(Succeed())
+		Expect(pvc.Spec.VolumeName).To(Equal("pv-1"))
+		Expect(pvc.Annotations).To(HaveKeyWithValue(bdv1.AnnotationMigratedFrom, "db-pvc-foo-db-0"))
+
+		pv := &corev1.PersistentVolume{}
+		Expect(client.Get(context.Background(), types.NamespacedName{Name: "pv-1"}, pv)).To(Succeed())
+		Expect(pv.Spec.ClaimRef.Name).To(Equal("database-pvc-foo-database-0"))
+		Expect(pv.Spec.ClaimRef.UID).To(BeEmpty())
+		Expect(pv.Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimDelete))
+	})
+
+	Context("when the pods of the old instance group are still running", func() {
+		BeforeEach(func() {
+			objects = append(objects, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-db-0",
+					Namespace: "default",
+					Labels: map[string]string{
+						bdv1.LabelDeploymentName:    "foo",
+						bdv1.LabelInstanceGroupName: "db",
+					},
+				},
+			})
+		})
+
+		It("requeues without moving the volumes", func() {
+			result, err := reconcileResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(cfd.MigrationRequeueInterval))
+			Expect(withops.ManifestCallCount()).To(Equal(1))
+			Expect(jobFactory.InstanceGroupManifestJobCallCount()).To(Equal(0))
+
+			Expect(get("db-pvc-foo-db-0", &corev1.PersistentVolumeClaim{})).To(Succeed())
+			err = get("database-pvc-foo-database-0", &corev1.PersistentVolumeClaim{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("when the old claim is being deleted", func() {
+		BeforeEach(func() {
+			now := metav1.Now()
+			objects[2].SetDeletionTimestamp(&now)
+			objects[2].SetFinalizers([]string{"kubernetes.io/pvc-protection"})
+		})
+
+		It("requeues before binding the volume to the new claim", func() {
+			result, err := reconcileResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(cfd.MigrationRequeueInterval))
+
+			pv := &corev1.PersistentVolume{}
+			Expect(client.Get(context.Background(), types.NamespacedName{Name: "pv-1"}, pv)).To(Succeed())
+			Expect(pv.Spec.ClaimRef.Name).To(Equal("db-pvc-foo-db-0"))
+		})
+	})
+
+	It("changes the services to select the pods of the new instance group", func() {
+		Expect(reconcileDeployment()).To(Succeed())
+
+		svc := &corev1.Service{}
+		Expect(get("foo-db-0", svc)).To(Succeed())
+		Expect(svc.Labels).To(HaveKeyWithValue(bdv1.LabelInstanceGroupName, "database"))
+		Expect(svc.Spec.Selector).To(HaveKeyWithValue(bdv1.LabelInstanceGroupName, "database"))
+		Expect(svc.Spec.Selector).To(HaveKeyWithValue(qstsv1a1.LabelPodOrdinal, "0"))
+	})
+
+	It("moves the versioned and the link secrets to the new instance group", func() {
+		Expect(reconcileDeployment()).To(Succeed())
+
+		err := get("foo.bpm.db-v1", &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		secret := &corev1.Secret{}
+		Expect(get("foo.bpm.database-v1", secret)).To(Succeed())
+		Expect(secret.Labels).To(HaveKeyWithValue(qjv1a1.LabelRemoteID, "database"))
+		Expect(secret.Labels).To(HaveKeyWithValue(vss.LabelVersion, "1"))
+		Expect(secret.Annotations).To(HaveKeyWithValue(bdv1.AnnotationMigratedFrom, "foo.bpm.db-v1"))
+		Expect(secret.Data).To(HaveKeyWithValue("bpm.yaml", []byte("processes: []")))
+
+		Expect(get("link-foo-database-db", secret)).To(Succeed())
+		Expect(secret.Labels).To(HaveKeyWithValue(qjv1a1.LabelRemoteID, "database"))
+		Expect(secret.Labels).To(HaveKeyWithValue(qjv1a1.LabelPersistentSecretContainer, "database"))
+	})
+
+	Context("when merging instance groups with azs", func() {
+		BeforeEach(func() {
+			current.InstanceGroups = append(current.InstanceGroups, &bdm.InstanceGroup{Name: "db-2", Instances: 1})
+			manifest.InstanceGroups[0].AZs = []string{"z1", "z2"}
+			manifest.InstanceGroups[0].MigratedFrom = []*bdm.MigratedFrom{
+				{Name: "db", Az: "z1"},
+				{Name: "db-2", Az: "z2"},
+			}
+			objects = append(objects, &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "db-2-pvc-foo-db-2-0", Namespace: "default"},
+				Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-2"},
+			}, &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-2"},
+			})
+		})
+
+		It("binds the volumes to the claims of the azs", func() {
+			Expect(reconcileDeployment()).To(Succeed())
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(get("database-pvc-foo-database-z0-0", pvc)).To(Succeed())
+			Expect(pvc.Spec.VolumeName).To(Equal("pv-1"))
+			Expect(get("database-pvc-foo-database-z1-0", pvc)).To(Succeed())
+			Expect(pvc.Spec.VolumeName).To(Equal("pv-2"))
+		})
+
+		It("fails for an az, which isn't an az of the new instance group", func() {
+			manifest.InstanceGroups[0].MigratedFrom[1].Az = "z3"
+
+			err := reconcileDeployment()
+			Expect(err).To(MatchError(ContainSubstring("az 'z3' of instance group 'db-2' is not an az of instance group 'database'")))
+			Expect(<-recorder.Events).To(ContainSubstring("MigrationError"))
+		})
+	})
+
+	It("fails if the old instance group is still part of the manifest", func() {
+		manifest.InstanceGroups = append(manifest.InstanceGroups, &bdm.InstanceGroup{Name: "db", Instances: 1})
+
+		err := reconcileDeployment()
+		Expect(err).To(MatchError(ContainSubstring("can't migrate from instance group 'db', which is still part of the manifest")))
+	})
+})
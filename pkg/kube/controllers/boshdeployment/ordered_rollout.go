@@ -0,0 +1,177 @@
+package boshdeployment
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	qstscontroller "code.cloudfoundry.org/quarks-statefulset/pkg/kube/controllers/quarksstatefulset"
+	"code.cloudfoundry.org/quarks-statefulset/pkg/kube/controllers/statefulset"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+)
+
+// pendingInstanceGroup returns the name of the first instance group, which
+// is rolled out in an earlier stage than the given instance group and is not
+// yet ready on its latest ig-resolved and bpm versions. It returns an empty
+// string, if the instance group can be deployed.
+func (r *ReconcileBPM) pendingInstanceGroup(ctx context.Context, bdpl *bdv1.BOSHDeployment, manifest *bdm.Manifest, instanceGroupName string) (string, error) {
+	for _, stage := range manifest.RolloutStages() {
+		if _, found := stage.InstanceGroupByName(instanceGroupName); found {
+			return "", nil
+		}
+
+		for _, ig := range stage {
+			ready, err := r.instanceGroupRolledOut(ctx, bdpl, ig)
+			if err != nil {
+				return "", err
+			}
+			if !ready {
+				return ig.Name, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// instanceGroupRolledOut returns true if the QuarksStatefulSet of the
+// instance group was deployed from the latest versioned secrets and all its
+// StatefulSets are updated and ready
+func (r *ReconcileBPM) instanceGroupRolledOut(ctx context.Context, bdpl *bdv1.BOSHDeployment, ig *bdm.InstanceGroup) (bool, error) {
+	versions := map[string]string{}
+	for annotation, prefix := range map[string]string{
+		bdv1.AnnotationIGResolvedVersion: bdv1.DeploymentSecretTypeInstanceGroupResolvedProperties.Prefix(bdpl.Name),
+		bdv1.AnnotationBPMVersion:        bdv1.DeploymentSecretBPMInformation.Prefix(bdpl.Name),
+	} {
+		secret, err := r.versionedSecretStore.Latest(ctx, bdpl.Namespace, prefix+ig.NameSanitized())
+		if err != nil {
+			log.Debugf(ctx, "Instance group '%s' has no rendered secret '%s': %v", ig.Name, prefix+ig.NameSanitized(), err)
+			return false, nil
+		}
+		versions[annotation] = secret.GetLabels()[vss.LabelVersion]
+	}
+
+	qsts := &qstsv1a1.QuarksStatefulSet{}
+	qstsName := names.QuarksStatefulSetName(bdpl.Name, ig.Name)
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: bdpl.Namespace, Name: qstsName}, qsts)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to get QuarksStatefulSet '%s/%s'", bdpl.Namespace, qstsName)
+	}
+
+	statefulSets, _, err := qstscontroller.GetMaxStatefulSetVersion(ctx, r.client, qsts)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get statefulsets of QuarksStatefulSet '%s'", qsts.GetNamespacedName())
+	}
+
+	for annotation, version := range versions {
+		if qsts.GetAnnotations()[annotation] != version {
+			return false, nil
+		}
+		for _, sts := range statefulSets {
+			if sts.GetAnnotations()[annotation] != version {
+				return false, nil
+			}
+		}
+	}
+
+	for _, sts := range statefulSets {
+		if !statefulSetRolledOut(sts) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// statefulSetRolledOut returns true if all replicas of the StatefulSet are
+// updated and ready and the canary rollout is done
+func statefulSetRolledOut(sts *appsv1.StatefulSet) bool {
+	// the default statefulset, if none exists yet
+	if sts.Name == "" {
+		return false
+	}
+
+	if state, ok := sts.GetAnnotations()[statefulset.AnnotationCanaryRollout]; ok && state != RolloutStateDone {
+		return false
+	}
+	if state, ok := sts.GetAnnotations()[bdv1.AnnotationRolloutState]; ok && state != RolloutStateDone {
+		return false
+	}
+
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	return sts.Status.ObservedGeneration >= sts.Generation &&
+		sts.Status.UpdatedReplicas >= replicas &&
+		sts.Status.ReadyReplicas >= replicas
+}
+
+// rolloutReconciles returns the latest BPM secrets of the deployment, which
+// are not yet deployed to their QuarksStatefulSet. Their rollout might wait
+// for the instance groups of earlier stages.
+func rolloutReconciles(ctx context.Context, c client.Client, namespace string, deploymentName string) ([]reconcile.Request, error) {
+	reconciles := []reconcile.Request{}
+
+	latest, err := latestBPMSecrets(ctx, c, namespace, deploymentName)
+	if err != nil {
+		return reconciles, err
+	}
+
+	for ig, secret := range latest {
+		qsts := &qstsv1a1.QuarksStatefulSet{}
+		qstsName := names.QuarksStatefulSetName(deploymentName, ig)
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: qstsName}, qsts)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return reconciles, errors.Wrapf(err, "failed to get QuarksStatefulSet '%s/%s'", namespace, qstsName)
+		}
+		if err == nil && qsts.GetAnnotations()[bdv1.AnnotationBPMVersion] == secret.Labels[vss.LabelVersion] {
+			continue
+		}
+
+		reconciles = append(reconciles, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name},
+		})
+	}
+
+	return reconciles, nil
+}
+
+// latestBPMSecrets returns the latest version of each instance group's BPM
+// secret, by the sanitized instance group name
+func latestBPMSecrets(ctx context.Context, c client.Client, namespace string, deploymentName string) (map[string]corev1.Secret, error) {
+	secrets := &corev1.SecretList{}
+	err := c.List(ctx, secrets, client.InNamespace(namespace), client.MatchingLabels{
+		bdv1.LabelDeploymentName:       deploymentName,
+		bdv1.LabelDeploymentSecretType: bdv1.DeploymentSecretBPMInformation.String(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list BPM secrets of BOSHDeployment '%s/%s'", namespace, deploymentName)
+	}
+
+	latest := map[string]corev1.Secret{}
+	for _, secret := range secrets.Items {
+		ig := secret.Labels[qjv1a1.LabelRemoteID]
+		if l, ok := latest[ig]; !ok || secretVersion(secret) > secretVersion(l) {
+			latest[ig] = secret
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,220 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	cfd "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+	vss "code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Ordered rollout", func() {
+	var (
+		ctx          context.Context
+		recorder     *record.FakeRecorder
+		manager      *fakes.FakeManager
+		resolver     fakes.FakeDesiredManifest
+		converter    fakes.FakeBPMConverter
+		client       crc.Client
+		objects      []crc.Object
+		serial       bool
+		dbVersion    string
+		dbReady      int32
+		reconcileWeb func() reconcile.Result
+	)
+
+	versionedSecret := func(name string, ig string, version string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name + "-v" + version,
+				Namespace: "default",
+				Labels: map[string]string{
+					bdv1.LabelDeploymentName: "foo",
+					qjv1a1.LabelRemoteID:     ig,
+					vss.LabelSecretKind:      vss.VersionSecretKind,
+					vss.LabelVersion:         version,
+				},
+			},
+			Data: map[string][]byte{"bpm.yaml": []byte("{}")},
+		}
+	}
+
+	BeforeEach(func() {
+		_ = controllers.AddToScheme(scheme.Scheme)
+		recorder = record.NewFakeRecorder(20)
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+		resolver = fakes.FakeDesiredManifest{}
+		converter = fakes.FakeBPMConverter{}
+
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", recorder)
+
+		serial = true
+		dbVersion = "2"
+		dbReady = 2
+
+		reconcileWeb = func() reconcile.Result {
+			reconciler := cfd.NewBPMReconciler(ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager, &resolver, controllerutil.SetControllerReference, &converter)
+			result, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo.bpm.web-v1"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			return result
+		}
+	})
+
+	JustBeforeEach(func() {
+		resolver.DesiredManifestReturns(&bdm.Manifest{
+			InstanceGroups: []*bdm.InstanceGroup{
+				{Name: "db", Instances: 2, Update: &bdm.Update{Serial: pointer.BoolPtr(serial)}},
+				{Name: "smoke-tests", LifeCycle: bdm.IGTypeErrand},
+				{Name: "web", Instances: 1, Update: &bdm.Update{Serial: pointer.BoolPtr(serial)}},
+			},
+		}, nil)
+
+		versions := map[string]string{
+			bdv1.AnnotationIGResolvedVersion: dbVersion,
+			bdv1.AnnotationBPMVersion:        dbVersion,
+		}
+		objects = []crc.Object{
+			&bdv1.BOSHDeployment{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}},
+			versionedSecret("foo.bpm.web", "web", "1"),
+			versionedSecret("foo.ig-resolved.web", "web", "1"),
+			versionedSecret("foo.bpm.db", "db", "1"),
+			versionedSecret("foo.bpm.db", "db", "2"),
+			versionedSecret("foo.ig-resolved.db", "db", "2"),
+			&qstsv1a1.QuarksStatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo-db",
+					Namespace:   "default",
+					UID:         "qsts-uid",
+					Labels:      map[string]string{bdv1.LabelDeploymentName: "foo", bdv1.LabelInstanceGroupName: "db"},
+					Annotations: versions,
+				},
+			},
+			&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-db",
+					Namespace: "default",
+					Labels:    map[string]string{bdv1.LabelDeploymentName: "foo", bdv1.LabelInstanceGroupName: "db"},
+					Annotations: map[string]string{
+						qstsv1a1.AnnotationVersion:       "2",
+						bdv1.AnnotationIGResolvedVersion: dbVersion,
+						bdv1.AnnotationBPMVersion:        dbVersion,
+					},
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: qstsv1a1.SchemeGroupVersion.String(),
+						Kind:       "QuarksStatefulSet",
+						Name:       "foo-db",
+						UID:        "qsts-uid",
+						Controller: pointers.Bool(true),
+					}},
+				},
+				Spec: appsv1.StatefulSetSpec{Replicas: pointers.Int32(2)},
+				Status: appsv1.StatefulSetStatus{
+					Replicas:        2,
+					ReadyReplicas:   dbReady,
+					UpdatedReplicas: 2,
+				},
+			},
+		}
+
+		client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+		manager.GetClientReturns(client)
+
+		qsts := qstsv1a1.QuarksStatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-web",
+				Namespace: "default",
+				Labels:    map[string]string{bdv1.LabelDeploymentName: "foo", bdv1.LabelInstanceGroupName: "web"},
+			},
+		}
+		converter.ResourcesReturns(&bpmconverter.Resources{InstanceGroups: []qstsv1a1.QuarksStatefulSet{qsts}}, nil)
+	})
+
+	webDeployed := func() bool {
+		err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo-web"}, &qstsv1a1.QuarksStatefulSet{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	It("deploys the instance group once the serial instance groups before it are rolled out", func() {
+		result := reconcileWeb()
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(webDeployed()).To(BeTrue())
+
+		qsts := &qstsv1a1.QuarksStatefulSet{}
+		Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "foo-web"}, qsts)).To(Succeed())
+		Expect(qsts.Spec.Template.GetAnnotations()).To(HaveKeyWithValue(bdv1.AnnotationBPMVersion, "1"))
+		Expect(qsts.Spec.Template.GetAnnotations()).To(HaveKeyWithValue(bdv1.AnnotationIGResolvedVersion, "1"))
+	})
+
+	Context("when the instance group before it is not on the latest version", func() {
+		BeforeEach(func() {
+			dbVersion = "1"
+		})
+
+		It("waits for the instance group", func() {
+			result := reconcileWeb()
+			Expect(result.RequeueAfter).NotTo(BeZero())
+			Expect(webDeployed()).To(BeFalse())
+			Expect(recorder.Events).To(Receive(ContainSubstring("Instance group 'web' waits for the rollout of instance group 'db'")))
+		})
+	})
+
+	Context("when the instance group before it is not ready", func() {
+		BeforeEach(func() {
+			dbReady = 1
+		})
+
+		It("waits for the instance group", func() {
+			result := reconcileWeb()
+			Expect(result.RequeueAfter).NotTo(BeZero())
+			Expect(webDeployed()).To(BeFalse())
+		})
+
+		Context("when the instance groups are not serial", func() {
+			BeforeEach(func() {
+				serial = false
+			})
+
+			It("deploys them in parallel", func() {
+				result := reconcileWeb()
+				Expect(result.RequeueAfter).To(BeZero())
+				Expect(webDeployed()).To(BeTrue())
+			})
+		})
+	})
+})
@@ -0,0 +1,67 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/desiredmanifest"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/monitorednamespace"
+)
+
+// AddRollback creates a new rollback controller to watch for the rollback
+// annotation on BOSHDeployments. It restores an earlier desired manifest
+// version, which is then rendered into k8s resources again.
+func AddRollback(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "rollback-reconciler", mgr.GetEventRecorderFor("rollback-recorder"))
+	r := NewRollbackReconciler(ctx, config, mgr, desiredmanifest.NewDesiredManifest(mgr.GetClient()))
+
+	c, err := controller.New("rollback-controller", mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding rollback controller to manager failed.")
+	}
+
+	nsPred := monitorednamespace.NewNSPredicate(ctx, mgr.GetClient(), config.MonitoredID)
+
+	// Trigger when the rollback annotation is set on a BOSHDeployment
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return metav1.HasAnnotation(e.Object.(*bdv1.BOSHDeployment).ObjectMeta, bdv1.AnnotationRollbackTo)
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			n := e.ObjectNew.(*bdv1.BOSHDeployment)
+			if !metav1.HasAnnotation(n.ObjectMeta, bdv1.AnnotationRollbackTo) {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+				ctx, e.ObjectNew, "bdv1.BOSHDeployment",
+				fmt.Sprintf("Update predicate passed for '%s/%s' for rollback", e.ObjectNew.GetNamespace(), e.ObjectNew.GetName()),
+			)
+			return true
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeployment{}}, &handler.EnqueueRequestForObject{}, nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching bosh deployment failed in rollback controller.")
+	}
+
+	return nil
+}
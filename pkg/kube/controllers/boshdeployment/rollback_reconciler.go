@@ -0,0 +1,122 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+// ManifestRollback restores an earlier version of the desired manifest
+type ManifestRollback interface {
+	Rollback(ctx context.Context, bdpl *bdv1.BOSHDeployment, version int) (bool, error)
+}
+
+var _ reconcile.Reconciler = &ReconcileRollback{}
+
+// NewRollbackReconciler returns a new reconcile.Reconciler
+func NewRollbackReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, rollback ManifestRollback) reconcile.Reconciler {
+	return &ReconcileRollback{
+		ctx:      ctx,
+		config:   config,
+		client:   mgr.GetClient(),
+		rollback: rollback,
+	}
+}
+
+// ReconcileRollback reconciles the rollback annotation of a BOSHDeployment
+type ReconcileRollback struct {
+	ctx      context.Context
+	config   *config.Config
+	client   client.Client
+	rollback ManifestRollback
+}
+
+// Reconcile restores the desired manifest version, which is requested by the
+// rollback annotation on the BOSHDeployment. The QuarksStatefulSets,
+// QuarksJobs and Services are then rendered from that manifest by the
+// instance group job and the BPM reconciler, like for any other update.
+func (r *ReconcileRollback) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
+	// Set the ctx to be Background, as the top-level context for incoming requests.
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	log.Infof(ctx, "Reconciling rollback of BOSHDeployment '%s'", request.NamespacedName)
+	bdpl := &bdv1.BOSHDeployment{}
+	err := r.client.Get(ctx, request.NamespacedName, bdpl)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Return and don't requeue
+			log.Debug(ctx, "Skip reconcile: BOSHDeployment not found")
+			return reconcile.Result{}, nil
+		}
+
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "GetBOSHDeploymentError").Errorf(ctx, "failed to get BOSHDeployment '%s': %v", request.NamespacedName, err)
+	}
+
+	value, ok := bdpl.GetAnnotations()[bdv1.AnnotationRollbackTo]
+	if !ok {
+		log.Debugf(ctx, "Skip reconcile: BOSHDeployment '%s' has no rollback annotation", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		_ = log.WithEvent(bdpl, "RollbackError").Errorf(ctx, "invalid desired manifest version '%s' in rollback annotation of BOSHDeployment '%s'", value, request.NamespacedName)
+		// A broken value does not block future rollbacks
+		return reconcile.Result{}, r.removeAnnotation(ctx, bdpl)
+	}
+
+	created, err := r.rollback.Rollback(ctx, bdpl, version)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			_ = log.WithEvent(bdpl, "RollbackError").Errorf(ctx, "desired manifest version %d of BOSHDeployment '%s' does not exist: %v", version, request.NamespacedName, err)
+			return reconcile.Result{}, r.removeAnnotation(ctx, bdpl)
+		}
+		// The annotation is kept, so the rollback is retried
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "RollbackError").Errorf(ctx, "failed to roll back BOSHDeployment '%s' to desired manifest version %d: %v", request.NamespacedName, version, err)
+	}
+	if !created {
+		log.WithEvent(bdpl, "Rollback").Infof(ctx, "BOSHDeployment '%s' already uses the desired manifest of version %d", request.NamespacedName, version)
+	} else {
+		log.WithEvent(bdpl, "Rollback").Infof(ctx, "Rolling back BOSHDeployment '%s' to desired manifest version %d", request.NamespacedName, version)
+	}
+
+	// The rolled back version is written, a retry after a failed update finds it as the latest version
+	err = r.removeAnnotation(ctx, bdpl)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	bdpl.Status.RollbackVersion = version
+	bdpl.Status.Message = fmt.Sprintf("Rolled back to desired manifest version %d", version)
+	err = r.client.Status().Update(ctx, bdpl)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to update rollback status on BOSHDeployment '%s' (%v): %s", request.NamespacedName, bdpl.ResourceVersion, err)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// removeAnnotation removes the rollback annotation from the BOSHDeployment
+func (r *ReconcileRollback) removeAnnotation(ctx context.Context, bdpl *bdv1.BOSHDeployment) error {
+	annotations := bdpl.GetAnnotations()
+	delete(annotations, bdv1.AnnotationRollbackTo)
+	bdpl.SetAnnotations(annotations)
+	err := r.client.Update(ctx, bdpl)
+	if err != nil {
+		return log.WithEvent(bdpl, "UpdateError").Errorf(ctx, "failed to remove rollback annotation from BOSHDeployment '%s': %v", bdpl.GetNamespacedName(), err)
+	}
+	return nil
+}
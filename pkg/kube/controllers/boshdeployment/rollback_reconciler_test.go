@@ -0,0 +1,163 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	bdplcontroller "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	cfakes "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileRollback", func() {
+	var (
+		manager    *cfakes.FakeManager
+		reconciler reconcile.Reconciler
+		request    reconcile.Request
+		ctx        context.Context
+		log        *zap.SugaredLogger
+		config     *cfcfg.Config
+		client     *cfakes.FakeClient
+		status     *cfakes.FakeStatusWriter
+		rollback   *cfakes.FakeManifestRollback
+		bdpl       *bdv1.BOSHDeployment
+	)
+
+	BeforeEach(func() {
+		err := controllers.AddToScheme(scheme.Scheme)
+		Expect(err).ToNot(HaveOccurred())
+
+		manager = &cfakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}
+		config = &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		_, log = helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+
+		bdpl = &bdv1.BOSHDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "foo",
+				Namespace:   "default",
+				Annotations: map[string]string{bdv1.AnnotationRollbackTo: "2"},
+			},
+		}
+
+		status = &cfakes.FakeStatusWriter{}
+		status.UpdateCalls(func(_ context.Context, object crc.Object, _ ...crc.UpdateOption) error {
+			object.(*bdv1.BOSHDeployment).DeepCopyInto(bdpl)
+			return nil
+		})
+
+		client = &cfakes.FakeClient{}
+		client.GetCalls(func(_ context.Context, nn types.NamespacedName, object crc.Object) error {
+			switch object := object.(type) {
+			case *bdv1.BOSHDeployment:
+				bdpl.DeepCopyInto(object)
+				return nil
+			}
+			return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+		})
+		client.UpdateCalls(func(_ context.Context, object crc.Object, _ ...crc.UpdateOption) error {
+			object.(*bdv1.BOSHDeployment).DeepCopyInto(bdpl)
+			return nil
+		})
+		client.StatusCalls(func() crc.StatusWriter { return status })
+		manager.GetClientReturns(client)
+
+		rollback = &cfakes.FakeManifestRollback{}
+		rollback.RollbackReturns(true, nil)
+	})
+
+	JustBeforeEach(func() {
+		reconciler = bdplcontroller.NewRollbackReconciler(ctx, config, manager, rollback)
+	})
+
+	It("keeps the annotation until the desired manifest version is written", func() {
+		rollback.RollbackCalls(func(_ context.Context, _ *bdv1.BOSHDeployment, _ int) (bool, error) {
+			Expect(bdpl.Annotations).To(HaveKey(bdv1.AnnotationRollbackTo))
+			return true, nil
+		})
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bdpl.Annotations).ToNot(HaveKey(bdv1.AnnotationRollbackTo))
+	})
+
+	It("restores the requested desired manifest version", func() {
+		result, err := reconciler.Reconcile(context.Background(), request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(reconcile.Result{}))
+
+		Expect(rollback.RollbackCallCount()).To(Equal(1))
+		_, deployment, version := rollback.RollbackArgsForCall(0)
+		Expect(deployment.Name).To(Equal("foo"))
+		Expect(version).To(Equal(2))
+
+		Expect(bdpl.Annotations).ToNot(HaveKey(bdv1.AnnotationRollbackTo))
+		Expect(bdpl.Status.RollbackVersion).To(Equal(2))
+		Expect(client.UpdateCallCount()).To(Equal(1))
+		Expect(bdpl.Status.Message).To(Equal("Rolled back to desired manifest version 2"))
+	})
+
+	Context("when the annotation is not a version", func() {
+		BeforeEach(func() {
+			bdpl.Annotations[bdv1.AnnotationRollbackTo] = "latest"
+		})
+
+		It("removes the annotation without rolling back", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rollback.RollbackCallCount()).To(Equal(0))
+			Expect(bdpl.Annotations).ToNot(HaveKey(bdv1.AnnotationRollbackTo))
+			Expect(status.UpdateCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the version does not exist", func() {
+		BeforeEach(func() {
+			rollback.RollbackReturns(false, errors.Wrap(apierrors.NewNotFound(schema.GroupResource{}, "foo.desired-manifest-v2"), "failed to read version 2"))
+		})
+
+		It("removes the annotation without updating the status", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(bdpl.Annotations).ToNot(HaveKey(bdv1.AnnotationRollbackTo))
+			Expect(status.UpdateCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when writing the desired manifest version fails", func() {
+		BeforeEach(func() {
+			rollback.RollbackReturns(false, errors.New("connection refused"))
+		})
+
+		It("keeps the annotation to retry the rollback", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).To(MatchError(ContainSubstring("connection refused")))
+
+			Expect(client.UpdateCallCount()).To(Equal(0))
+			Expect(bdpl.Annotations).To(HaveKeyWithValue(bdv1.AnnotationRollbackTo, "2"))
+			Expect(status.UpdateCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,75 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/monitorednamespace"
+)
+
+// AddRollout creates a new rollout controller and adds it to the manager.
+// The purpose of this controller is to apply the canaries and max_in_flight
+// settings of the BOSH update block to the canary rollout of the instance
+// group statefulsets.
+func AddRollout(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "rollout-reconciler", mgr.GetEventRecorderFor("rollout-recorder"))
+	r := NewRolloutReconciler(ctx, config, mgr)
+
+	c, err := controller.New("rollout-controller", mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: config.MaxQuarksStatefulSetWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding rollout controller to manager failed.")
+	}
+
+	nsPred := monitorednamespace.NewNSPredicate(ctx, mgr.GetClient(), config.MonitoredID)
+
+	// Trigger when the rollout state or the pods of an instance group statefulset change
+	p := predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			newSts := e.ObjectNew.(*appsv1.StatefulSet)
+			oldSts := e.ObjectOld.(*appsv1.StatefulSet)
+			if !bdv1.HasDeploymentName(newSts.GetLabels()) {
+				return false
+			}
+			if _, ok := newSts.Annotations[bdv1.AnnotationRolloutState]; !ok {
+				return false
+			}
+
+			if newSts.Annotations[bdv1.AnnotationRolloutState] != oldSts.Annotations[bdv1.AnnotationRolloutState] ||
+				newSts.Status.ReadyReplicas != oldSts.Status.ReadyReplicas ||
+				newSts.Status.UpdatedReplicas != oldSts.Status.UpdatedReplicas ||
+				newSts.Status.Replicas != oldSts.Status.Replicas {
+				ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+					ctx, e.ObjectNew, "appsv1.StatefulSet",
+					fmt.Sprintf("Update predicate passed for '%s/%s' for instance group rollout", e.ObjectNew.GetNamespace(), e.ObjectNew.GetName()),
+				)
+				return true
+			}
+			return false
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &appsv1.StatefulSet{}}, &handler.EnqueueRequestForObject{}, nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching statefulsets failed in rollout controller.")
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package boshdeployment
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-statefulset/pkg/kube/controllers/statefulset"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	podutil "code.cloudfoundry.org/quarks-utils/pkg/pod"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+	"code.cloudfoundry.org/quarks-utils/pkg/util"
+)
+
+// These are the states of the canary rollout. The rollout webhook starts a
+// rollout in 'CanaryUpscale' or 'Canary' state, the rollout controller tracks
+// it in AnnotationRolloutState.
+const (
+	RolloutStateCanaryUpscale = "CanaryUpscale"
+	RolloutStateCanary        = "Canary"
+	RolloutStateRollout       = "Rollout"
+	RolloutStateDone          = "Done"
+	RolloutStateFailed        = "Failed"
+)
+
+// rolloutRequeueAfter is used to check on the rollout, while pods are updated
+const rolloutRequeueAfter = 30 * time.Second
+
+// NewRolloutReconciler returns a new reconciler for the rollout of instance group statefulsets
+func NewRolloutReconciler(ctx context.Context, config *config.Config, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileRollout{
+		ctx:    ctx,
+		config: config,
+		client: mgr.GetClient(),
+	}
+}
+
+// ReconcileRollout reconciles the partition of an instance group statefulset
+type ReconcileRollout struct {
+	ctx    context.Context
+	client client.Client
+	config *config.Config
+}
+
+// podRolloutState is the update state of a single statefulset pod
+type podRolloutState struct {
+	pod     *corev1.Pod
+	ready   bool
+	updated bool
+}
+
+// Reconcile drives the canary rollout of an instance group statefulset.
+// The rollout webhook opts these statefulsets out of the quarks-statefulset
+// rollout controller, so only this reconciler moves the partition. Newly
+// added replicas are rolled out first, then the number of canaries and
+// afterwards max_in_flight instances are updated at the same time. The
+// rollout fails if the canaries or the update do not finish within their
+// watch times. The progress is shown on the BOSHDeployment status.
+func (r *ReconcileRollout) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
+	sts := &appsv1.StatefulSet{}
+
+	// Set the ctx to be Background, as the top-level context for incoming requests.
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	log.Info(ctx, "Reconciling rollout of statefulset ", request.NamespacedName)
+	err := r.client.Get(ctx, request.NamespacedName, sts)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Return and don't requeue
+			log.Debug(ctx, "Skip rollout reconcile: statefulset not found")
+			return reconcile.Result{}, nil
+		}
+
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	deploymentName, ok := sts.GetLabels()[bdv1.LabelDeploymentName]
+	if !ok {
+		log.Debugf(ctx, "Skip rollout reconcile: statefulset '%s' does not belong to a BOSHDeployment", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	if sts.Spec.UpdateStrategy.RollingUpdate == nil || sts.Spec.UpdateStrategy.RollingUpdate.Partition == nil {
+		log.Debugf(ctx, "Skip rollout reconcile: statefulset '%s' has no partition", request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	canaries := annotationInt32(sts, bdv1.AnnotationCanaries, 1)
+	maxInFlight := annotationInt32(sts, bdv1.AnnotationMaxInFlight, 1)
+
+	pods, err := r.podRolloutStates(ctx, sts, replicas)
+	if err != nil {
+		return reconcile.Result{}, log.WithEvent(sts, "GetPodsError").Errorf(ctx, "Failed to get pods of statefulset '%s': %v", request.NamespacedName, err)
+	}
+
+	// all pods from this ordinal on are updated and ready
+	done := replicas
+	for done > 0 && pods[done-1].ready && pods[done-1].updated {
+		done--
+	}
+
+	if sts.Annotations == nil {
+		sts.Annotations = map[string]string{}
+	}
+	partition := *sts.Spec.UpdateStrategy.RollingUpdate.Partition
+	state, target := nextRolloutState(sts, sts.Annotations[bdv1.AnnotationRolloutState], partition, done, replicas, canaries, maxInFlight)
+	if target < 0 {
+		target = 0
+	}
+
+	if target < partition || state != sts.Annotations[bdv1.AnnotationRolloutState] {
+		log.Infof(ctx, "Moving partition of statefulset '%s' from %d to %d in state '%s'", request.NamespacedName, partition, target, state)
+		sts.Annotations[bdv1.AnnotationRolloutState] = state
+		sts.Spec.UpdateStrategy.RollingUpdate.Partition = pointers.Int32(target)
+		err = r.client.Update(ctx, sts)
+		if err != nil {
+			return reconcile.Result{}, log.WithEvent(sts, "UpdateError").Errorf(ctx, "Failed to update rollout of statefulset '%s': %v", request.NamespacedName, err)
+		}
+	}
+
+	if rolloutInProgress(state) {
+		// The statefulset controller updates one pod at a time, delete the
+		// outdated pods so they are recreated in parallel.
+		for i := target; i < done; i++ {
+			p := pods[i]
+			if p.pod == nil || p.updated || p.pod.DeletionTimestamp != nil {
+				continue
+			}
+			log.Debugf(ctx, "Deleting outdated pod '%s/%s' for rollout", p.pod.Namespace, p.pod.Name)
+			if err := r.client.Delete(ctx, p.pod); err != nil && !apierrors.IsNotFound(err) {
+				return reconcile.Result{}, log.WithEvent(sts, "DeletePodError").Errorf(ctx, "Failed to delete outdated pod '%s/%s': %v", p.pod.Namespace, p.pod.Name, err)
+			}
+		}
+	}
+
+	canariesReady := int32(0)
+	for i := replicas - canaries; i < replicas; i++ {
+		if i >= 0 && pods[i].ready && pods[i].updated {
+			canariesReady++
+		}
+	}
+
+	err = r.updateRolloutStatus(ctx, sts.Namespace, deploymentName, bdv1.RolloutStatus{
+		Name:            sts.Name,
+		State:           state,
+		Canaries:        canaries,
+		CanariesReady:   canariesReady,
+		Replicas:        replicas,
+		UpdatedReplicas: sts.Status.UpdatedReplicas,
+	})
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if rolloutInProgress(state) {
+		return reconcile.Result{RequeueAfter: rolloutRequeueAfter}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// nextRolloutState returns the next state of a rollout and the partition it
+// needs. All pods from the ordinal 'done' on are updated and ready.
+func nextRolloutState(sts *appsv1.StatefulSet, state string, partition, done, replicas, canaries, maxInFlight int32) (string, int32) {
+	switch state {
+	case RolloutStateCanaryUpscale:
+		// the added replicas are created from the new revision
+		if sts.Status.Replicas < replicas || done > partition {
+			break
+		}
+		if partition == 0 {
+			return RolloutStateDone, partition
+		}
+		return RolloutStateCanary, partition - canaries
+	case RolloutStateCanary:
+		if done <= partition {
+			if partition == 0 {
+				return RolloutStateDone, partition
+			}
+			return RolloutStateRollout, done - maxInFlight
+		}
+		if watchTimeExceeded(sts, statefulset.AnnotationCanaryWatchTime) {
+			return RolloutStateFailed, partition
+		}
+	case RolloutStateRollout:
+		if done == 0 {
+			return RolloutStateDone, partition
+		}
+		if watchTimeExceeded(sts, statefulset.AnnotationUpdateWatchTime) {
+			return RolloutStateFailed, partition
+		}
+		return state, util.MinInt32(partition, done-maxInFlight)
+	}
+	return state, partition
+}
+
+// rolloutInProgress returns true if the rollout controller is still moving the partition
+func rolloutInProgress(state string) bool {
+	return state == RolloutStateCanaryUpscale || state == RolloutStateCanary || state == RolloutStateRollout
+}
+
+// podRolloutStates returns the update state of the statefulset pods by ordinal
+func (r *ReconcileRollout) podRolloutStates(ctx context.Context, sts *appsv1.StatefulSet, replicas int32) ([]podRolloutState, error) {
+	states := make([]podRolloutState, replicas)
+	for i := range states {
+		pod := &corev1.Pod{}
+		err := r.client.Get(ctx, types.NamespacedName{Namespace: sts.Namespace, Name: fmt.Sprintf("%s-%d", sts.Name, i)}, pod)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		states[i] = podRolloutState{
+			pod:     pod,
+			ready:   podutil.IsPodReady(pod),
+			updated: pod.Labels[appsv1.StatefulSetRevisionLabel] == sts.Status.UpdateRevision,
+		}
+	}
+	return states, nil
+}
+
+// updateRolloutStatus stores the rollout progress of a statefulset on the BOSHDeployment
+func (r *ReconcileRollout) updateRolloutStatus(ctx context.Context, namespace string, deploymentName string, status bdv1.RolloutStatus) error {
+	bdpl := &bdv1.BOSHDeployment{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: deploymentName}, bdpl)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Debugf(ctx, "Skip rollout status: BOSHDeployment '%s/%s' not found", namespace, deploymentName)
+			return nil
+		}
+		return log.WithEvent(bdpl, "GetBOSHDeployment").Errorf(ctx, "Failed to get BOSHDeployment '%s/%s': %v", namespace, deploymentName, err)
+	}
+
+	rollouts := make([]bdv1.RolloutStatus, 0, len(bdpl.Status.Rollouts)+1)
+	found := false
+	failed := false
+	for _, rollout := range bdpl.Status.Rollouts {
+		if rollout.Name == status.Name {
+			failed = rollout.State != RolloutStateFailed && status.State == RolloutStateFailed
+			rollout = status
+			found = true
+		}
+		rollouts = append(rollouts, rollout)
+	}
+	if !found {
+		failed = status.State == RolloutStateFailed
+		rollouts = append(rollouts, status)
+	}
+
+	if reflect.DeepEqual(rollouts, bdpl.Status.Rollouts) {
+		return nil
+	}
+
+	bdpl.Status.Rollouts = rollouts
+	if failed {
+		bdpl.Status.Message = fmt.Sprintf("Rollout of '%s' stopped, %d of %d canaries and %d of %d replicas were updated within the watch time", status.Name, status.CanariesReady, status.Canaries, status.UpdatedReplicas, status.Replicas)
+		setCondition(bdpl, bdv1.ConditionDegraded, metav1.ConditionTrue, "RolloutFailed", bdpl.Status.Message)
+		_ = log.WithEvent(bdpl, "RolloutFailed").Errorf(ctx, "Rollout of statefulset '%s/%s' failed: %s", namespace, status.Name, bdpl.Status.Message)
+	}
+
+	err = r.client.Status().Update(ctx, bdpl)
+	if err != nil {
+		return log.WithEvent(bdpl, "UpdateStatusError").Errorf(ctx, "Failed to update rollout status on BOSHDeployment '%s/%s' (%v): %s", namespace, deploymentName, bdpl.ResourceVersion, err)
+	}
+	return nil
+}
+
+// annotationInt32 reads an integer annotation, which has to be at least one
+func annotationInt32(sts *appsv1.StatefulSet, key string, defaultValue int32) int32 {
+	value, err := strconv.Atoi(sts.Annotations[key])
+	if err != nil || value < 1 {
+		return defaultValue
+	}
+	return int32(value)
+}
+
+// watchTimeExceeded returns true if more time than the watch time in
+// milliseconds passed since the rollout webhook started the update
+func watchTimeExceeded(sts *appsv1.StatefulSet, key string) bool {
+	watchTime, err := strconv.Atoi(sts.Annotations[key])
+	if err != nil {
+		return false
+	}
+	start, err := strconv.ParseInt(sts.Annotations[statefulset.AnnotationUpdateStartTime], 10, 64)
+	if err != nil {
+		return false
+	}
+	return time.Since(time.Unix(start, 0)) > time.Duration(watchTime)*time.Millisecond
+}
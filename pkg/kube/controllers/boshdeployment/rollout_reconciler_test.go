@@ -0,0 +1,325 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers"
+	bdplcontroller "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	cfakes "code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/quarks-statefulset/pkg/kube/controllers/statefulset"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileRollout", func() {
+	var (
+		manager    *cfakes.FakeManager
+		reconciler reconcile.Reconciler
+		request    reconcile.Request
+		ctx        context.Context
+		log        *zap.SugaredLogger
+		config     *cfcfg.Config
+		client     *cfakes.FakeClient
+		status     *cfakes.FakeStatusWriter
+		bdpl       *bdv1.BOSHDeployment
+		sts        *appsv1.StatefulSet
+		pods       map[string]*corev1.Pod
+	)
+
+	newPod := func(i int, revision string, ready bool) *corev1.Pod {
+		condition := corev1.ConditionFalse
+		if ready {
+			condition = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("foo-%d", i),
+				Namespace: "default",
+				Labels:    map[string]string{appsv1.StatefulSetRevisionLabel: revision},
+			},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: condition}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		err := controllers.AddToScheme(scheme.Scheme)
+		Expect(err).ToNot(HaveOccurred())
+
+		manager = &cfakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}
+		config = &cfcfg.Config{CtxTimeOut: 10 * time.Second}
+		_, log = helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+
+		bdpl = &bdv1.BOSHDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployment-name", Namespace: "default"},
+		}
+		sts = &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+				Labels:    map[string]string{bdv1.LabelDeploymentName: "deployment-name"},
+				Annotations: map[string]string{
+					bdv1.AnnotationCanaries:     "2",
+					bdv1.AnnotationMaxInFlight:  "2",
+					bdv1.AnnotationRolloutState: bdplcontroller.RolloutStateCanary,
+				},
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: pointers.Int32(5),
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+					Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+					RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: pointers.Int32(3)},
+				},
+			},
+			Status: appsv1.StatefulSetStatus{Replicas: 5, UpdateRevision: "new"},
+		}
+		pods = map[string]*corev1.Pod{}
+		for i := 0; i < 5; i++ {
+			pod := newPod(i, "old", true)
+			pods[pod.Name] = pod
+		}
+
+		status = &cfakes.FakeStatusWriter{}
+		status.UpdateCalls(func(_ context.Context, object crc.Object, _ ...crc.UpdateOption) error {
+			if update, ok := object.(*bdv1.BOSHDeployment); ok {
+				bdpl = update
+			}
+			return nil
+		})
+
+		client = &cfakes.FakeClient{}
+		client.GetCalls(func(_ context.Context, nn types.NamespacedName, object crc.Object) error {
+			switch object := object.(type) {
+			case *appsv1.StatefulSet:
+				sts.DeepCopyInto(object)
+				return nil
+			case *bdv1.BOSHDeployment:
+				bdpl.DeepCopyInto(object)
+				return nil
+			case *corev1.Pod:
+				if pod, ok := pods[nn.Name]; ok {
+					pod.DeepCopyInto(object)
+					return nil
+				}
+			}
+			return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+		})
+		client.StatusCalls(func() crc.StatusWriter { return status })
+		manager.GetClientReturns(client)
+	})
+
+	JustBeforeEach(func() {
+		reconciler = bdplcontroller.NewRolloutReconciler(ctx, config, manager)
+	})
+
+	updatedStatefulSet := func() *appsv1.StatefulSet {
+		Expect(client.UpdateCallCount()).To(Equal(1))
+		_, object, _ := client.UpdateArgsForCall(0)
+		return object.(*appsv1.StatefulSet)
+	}
+
+	deletedPods := func() []string {
+		names := []string{}
+		for i := 0; i < client.DeleteCallCount(); i++ {
+			_, object, _ := client.DeleteArgsForCall(i)
+			names = append(names, object.GetName())
+		}
+		return names
+	}
+
+	// startRollout sets the rollout state and partition like the rollout webhook
+	startRollout := func(state string, partition int32) {
+		sts.Annotations[bdv1.AnnotationRolloutState] = state
+		sts.Spec.UpdateStrategy.RollingUpdate.Partition = pointers.Int32(partition)
+	}
+
+	Context("when the rollout webhook started a rollout", func() {
+		It("updates all canaries at once", func() {
+			result, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).ToNot(BeZero())
+
+			Expect(client.UpdateCallCount()).To(Equal(0))
+			Expect(deletedPods()).To(Equal([]string{"foo-3", "foo-4"}))
+		})
+
+		It("does not delete pods, which are already updated or terminating", func() {
+			pods["foo-3"] = newPod(3, "new", false)
+			pods["foo-4"].DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.DeleteCallCount()).To(Equal(0))
+		})
+
+		It("ignores statefulsets without a rollout state", func() {
+			delete(sts.Annotations, bdv1.AnnotationRolloutState)
+
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.UpdateCallCount()).To(Equal(0))
+			Expect(client.DeleteCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when new replicas are added", func() {
+		BeforeEach(func() {
+			startRollout(bdplcontroller.RolloutStateCanaryUpscale, 3)
+			pods["foo-3"] = newPod(3, "new", true)
+			pods["foo-4"] = newPod(4, "new", true)
+		})
+
+		It("updates the canaries once the new replicas are ready", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+
+			updated := updatedStatefulSet()
+			Expect(*updated.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(1)))
+			Expect(updated.Annotations[bdv1.AnnotationRolloutState]).To(Equal(bdplcontroller.RolloutStateCanary))
+			Expect(deletedPods()).To(Equal([]string{"foo-1", "foo-2"}))
+		})
+
+		It("waits for the new replicas to become ready", func() {
+			pods["foo-4"] = newPod(4, "new", false)
+
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.UpdateCallCount()).To(Equal(0))
+			Expect(client.DeleteCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the statefulset is in canary state", func() {
+		BeforeEach(func() {
+			startRollout(bdplcontroller.RolloutStateCanary, 3)
+			pods["foo-4"] = newPod(4, "new", true)
+			sts.Status.UpdatedReplicas = 1
+		})
+
+		It("shows the canary progress on the BOSHDeployment status", func() {
+			result, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).ToNot(BeZero())
+			Expect(client.UpdateCallCount()).To(Equal(0))
+
+			Expect(status.UpdateCallCount()).To(Equal(1))
+			Expect(bdpl.Status.Rollouts).To(Equal([]bdv1.RolloutStatus{{
+				Name:            "foo",
+				State:           bdplcontroller.RolloutStateCanary,
+				Canaries:        2,
+				CanariesReady:   1,
+				Replicas:        5,
+				UpdatedReplicas: 1,
+			}}))
+		})
+
+		It("continues with max_in_flight instances once the canaries are ready", func() {
+			pods["foo-3"] = newPod(3, "new", true)
+
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+
+			updated := updatedStatefulSet()
+			Expect(*updated.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(1)))
+			Expect(updated.Annotations[bdv1.AnnotationRolloutState]).To(Equal(bdplcontroller.RolloutStateRollout))
+			Expect(deletedPods()).To(Equal([]string{"foo-1", "foo-2"}))
+		})
+
+		It("fails when the canaries are not ready within the canary watch time", func() {
+			sts.Annotations[statefulset.AnnotationCanaryWatchTime] = "1000"
+			sts.Annotations[statefulset.AnnotationUpdateStartTime] = strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+			result, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{}))
+
+			updated := updatedStatefulSet()
+			Expect(*updated.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(3)))
+			Expect(updated.Annotations[bdv1.AnnotationRolloutState]).To(Equal(bdplcontroller.RolloutStateFailed))
+			Expect(bdpl.Status.Message).To(ContainSubstring("Rollout of 'foo' stopped, 1 of 2 canaries"))
+		})
+	})
+
+	Context("when the statefulset is in rollout state", func() {
+		BeforeEach(func() {
+			startRollout(bdplcontroller.RolloutStateRollout, 3)
+			pods["foo-3"] = newPod(3, "new", true)
+			pods["foo-4"] = newPod(4, "new", true)
+		})
+
+		It("updates max_in_flight instances at once", func() {
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(*updatedStatefulSet().Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(1)))
+			Expect(deletedPods()).To(Equal([]string{"foo-1", "foo-2"}))
+		})
+
+		It("waits for instances in flight to become ready", func() {
+			sts.Spec.UpdateStrategy.RollingUpdate.Partition = pointers.Int32(1)
+			pods["foo-1"] = newPod(1, "new", true)
+			pods["foo-2"] = newPod(2, "new", false)
+
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.UpdateCallCount()).To(Equal(0))
+			Expect(client.DeleteCallCount()).To(Equal(0))
+		})
+
+		It("finishes the rollout once all instances are updated", func() {
+			sts.Spec.UpdateStrategy.RollingUpdate.Partition = pointers.Int32(0)
+			for i := 0; i < 5; i++ {
+				pod := newPod(i, "new", true)
+				pods[pod.Name] = pod
+			}
+
+			result, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{}))
+			Expect(updatedStatefulSet().Annotations[bdv1.AnnotationRolloutState]).To(Equal(bdplcontroller.RolloutStateDone))
+		})
+	})
+
+	Context("when the canaries failed", func() {
+		BeforeEach(func() {
+			startRollout(bdplcontroller.RolloutStateFailed, 3)
+			bdpl.Status.Rollouts = []bdv1.RolloutStatus{{Name: "foo", State: bdplcontroller.RolloutStateCanary}}
+		})
+
+		It("halts the rollout and reports the failure", func() {
+			result, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{}))
+
+			Expect(client.UpdateCallCount()).To(Equal(0))
+			Expect(client.DeleteCallCount()).To(Equal(0))
+			Expect(bdpl.Status.Rollouts[0].State).To(Equal(bdplcontroller.RolloutStateFailed))
+			Expect(bdpl.Status.Message).To(ContainSubstring("Rollout of 'foo' stopped"))
+		})
+	})
+})
@@ -0,0 +1,162 @@
+package boshdeployment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistration "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-statefulset/pkg/kube/controllers/statefulset"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/logger"
+	"code.cloudfoundry.org/quarks-utils/pkg/monitorednamespace"
+	utilnames "code.cloudfoundry.org/quarks-utils/pkg/names"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+	wh "code.cloudfoundry.org/quarks-utils/pkg/webhook"
+)
+
+// NewStatefulSetRolloutMutator returns a new webhook, which starts the
+// rollout of instance group statefulsets
+func NewStatefulSetRolloutMutator(log *zap.SugaredLogger, config *config.Config) *wh.OperatorWebhook {
+	log = logger.Unskip(log, "bosh-rollout-mutator")
+	log.Info("Setting up mutator for the rollout of instance group statefulsets")
+
+	mutator := NewRolloutMutator(log, config)
+
+	scope := admissionregistration.NamespacedScope
+	return &wh.OperatorWebhook{
+		FailurePolicy: admissionregistration.Fail,
+		Rules: []admissionregistration.RuleWithOperations{
+			{
+				Rule: admissionregistration.Rule{
+					APIGroups:   []string{"apps"},
+					APIVersions: []string{"v1"},
+					Resources:   []string{"statefulsets"},
+					Scope:       &scope,
+				},
+				Operations: []admissionregistration.OperationType{
+					"CREATE",
+					"UPDATE",
+				},
+			},
+		},
+		Path: "/mutate-bosh-statefulsets",
+		Name: "mutate-bosh-statefulsets." + utilnames.GroupName,
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				monitorednamespace.LabelNamespace: config.MonitoredID,
+			},
+		},
+		Webhook: &admission.Webhook{Handler: mutator},
+	}
+}
+
+// RolloutMutator starts the rollout of instance group statefulsets
+type RolloutMutator struct {
+	log     *zap.SugaredLogger
+	config  *config.Config
+	decoder *admission.Decoder
+}
+
+// Check that RolloutMutator implements the admission.Handler interface
+var _ admission.Handler = &RolloutMutator{}
+
+// NewRolloutMutator returns a new mutator for instance group statefulsets
+func NewRolloutMutator(log *zap.SugaredLogger, config *config.Config) admission.Handler {
+	return &RolloutMutator{
+		log:    log,
+		config: config,
+	}
+}
+
+// Handle opts instance group statefulsets out of the quarks-statefulset
+// rollout controller, which would move the partition by one instance at a
+// time. The quarks-statefulset reconciler enables it on every update, so the
+// annotation is reset on each request. When the pod template changes, the
+// partition is set for the first canaries and the rollout controller of the
+// operator takes over. Other statefulsets are not modified.
+func (m *RolloutMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	sts := &appsv1.StatefulSet{}
+	err := m.decoder.Decode(req, sts)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if !bdv1.HasDeploymentName(sts.GetLabels()) {
+		return admission.Allowed("not an instance group statefulset")
+	}
+
+	updatedSts := sts.DeepCopy()
+	if updatedSts.Annotations == nil {
+		updatedSts.Annotations = map[string]string{}
+	}
+	updatedSts.Annotations[statefulset.AnnotationCanaryRolloutEnabled] = "false"
+
+	if req.Operation == admissionv1.Create {
+		startRollout(updatedSts)
+	} else {
+		oldSts := &appsv1.StatefulSet{}
+		err = m.decoder.DecodeRaw(req.OldObject, oldSts)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		if !reflect.DeepEqual(sts.Spec.Template, oldSts.Spec.Template) {
+			m.log.Debugf("Starting rollout of statefulset '%s/%s'", req.Namespace, sts.Name)
+			startRollout(updatedSts)
+		}
+	}
+
+	marshaledSts, err := json.Marshal(updatedSts)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledSts)
+}
+
+// startRollout sets the partition for the first step of a new rollout.
+// Added replicas are rolled out first, otherwise the canaries are updated.
+// The quarks-statefulset webhook might have started its own rollout before,
+// it is marked as done.
+func startRollout(sts *appsv1.StatefulSet) {
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	state := RolloutStateCanary
+	partition := replicas - annotationInt32(sts, bdv1.AnnotationCanaries, 1)
+	if sts.Status.Replicas < replicas {
+		state = RolloutStateCanaryUpscale
+		partition = sts.Status.Replicas
+	}
+	if partition < 0 {
+		partition = 0
+	}
+
+	sts.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+	sts.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{
+		Partition: pointers.Int32(partition),
+	}
+	sts.Annotations[bdv1.AnnotationRolloutState] = state
+	sts.Annotations[statefulset.AnnotationCanaryRollout] = RolloutStateDone
+	sts.Annotations[statefulset.AnnotationUpdateStartTime] = strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+// InjectDecoder injects the decoder.
+func (m *RolloutMutator) InjectDecoder(d *admission.Decoder) error {
+	m.decoder = d
+	return nil
+}
@@ -0,0 +1,152 @@
+package boshdeployment_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-statefulset/pkg/kube/controllers/statefulset"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("When the rollout webhook handles a statefulset", func() {
+	var (
+		log     *zap.SugaredLogger
+		ctx     context.Context
+		mutator admission.Handler
+		sts     *appsv1.StatefulSet
+		oldSts  *appsv1.StatefulSet
+	)
+
+	annotation := func(key string) string {
+		return "/metadata/annotations/" + strings.ReplaceAll(key, "/", "~1")
+	}
+	partition := "/spec/updateStrategy/rollingUpdate/partition"
+
+	// handle returns the values of the JSON patches by path
+	handle := func(operation admissionv1.Operation) map[string]interface{} {
+		raw, _ := json.Marshal(sts)
+		oldRaw, _ := json.Marshal(oldSts)
+		response := mutator.Handle(ctx, admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: operation,
+				Object:    runtime.RawExtension{Raw: raw},
+				OldObject: runtime.RawExtension{Raw: oldRaw},
+			},
+		})
+		Expect(response.Allowed).To(BeTrue())
+
+		patches := map[string]interface{}{}
+		for _, patch := range response.Patches {
+			patches[patch.Path] = patch.Value
+		}
+		return patches
+	}
+
+	BeforeEach(func() {
+		_, log = helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+
+		scheme := runtime.NewScheme()
+		Expect(appsv1.AddToScheme(scheme)).To(Succeed())
+		decoder, _ := admission.NewDecoder(scheme)
+		mutator = boshdeployment.NewRolloutMutator(log, &cfcfg.Config{CtxTimeOut: 10 * time.Second})
+		_ = mutator.(admission.DecoderInjector).InjectDecoder(decoder)
+
+		oldSts = &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+				Labels:    map[string]string{bdv1.LabelDeploymentName: "deployment-name"},
+				Annotations: map[string]string{
+					bdv1.AnnotationCanaries:                    "2",
+					bdv1.AnnotationRolloutState:                boshdeployment.RolloutStateDone,
+					statefulset.AnnotationCanaryRolloutEnabled: "false",
+				},
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: pointers.Int32(5),
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+					Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+					RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: pointers.Int32(0)},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"version": "1"}},
+				},
+			},
+			Status: appsv1.StatefulSetStatus{Replicas: 5},
+		}
+		sts = oldSts.DeepCopy()
+		// the quarks-statefulset reconciler enables its rollout controller on every update
+		sts.Annotations[statefulset.AnnotationCanaryRolloutEnabled] = "true"
+	})
+
+	It("opts the statefulset out of the quarks-statefulset rollout controller", func() {
+		Expect(handle(admissionv1.Update)).To(Equal(map[string]interface{}{
+			annotation(statefulset.AnnotationCanaryRolloutEnabled): "false",
+		}))
+	})
+
+	It("starts the rollout with the canaries when the pod template changes", func() {
+		sts.Spec.Template.Labels["version"] = "2"
+
+		patches := handle(admissionv1.Update)
+		Expect(patches).To(HaveKeyWithValue(annotation(statefulset.AnnotationCanaryRolloutEnabled), "false"))
+		Expect(patches).To(HaveKeyWithValue(annotation(bdv1.AnnotationRolloutState), boshdeployment.RolloutStateCanary))
+		Expect(patches).To(HaveKey(annotation(statefulset.AnnotationUpdateStartTime)))
+		Expect(patches).To(HaveKeyWithValue(partition, float64(3)))
+	})
+
+	It("marks a rollout started by the quarks-statefulset webhook as done", func() {
+		sts.Spec.Template.Labels["version"] = "2"
+		sts.Annotations[statefulset.AnnotationCanaryRollout] = "Pending"
+		sts.Spec.UpdateStrategy.RollingUpdate.Partition = pointers.Int32(5)
+
+		patches := handle(admissionv1.Update)
+		Expect(patches).To(HaveKeyWithValue(annotation(statefulset.AnnotationCanaryRollout), boshdeployment.RolloutStateDone))
+		Expect(patches).To(HaveKeyWithValue(partition, float64(3)))
+	})
+
+	It("rolls out added replicas first", func() {
+		sts.Spec.Template.Labels["version"] = "2"
+		sts.Spec.Replicas = pointers.Int32(7)
+
+		patches := handle(admissionv1.Update)
+		Expect(patches).To(HaveKeyWithValue(annotation(bdv1.AnnotationRolloutState), boshdeployment.RolloutStateCanaryUpscale))
+		Expect(patches).To(HaveKeyWithValue(partition, float64(5)))
+	})
+
+	It("creates all replicas from the new revision", func() {
+		sts.Status = appsv1.StatefulSetStatus{}
+		sts.Annotations[bdv1.AnnotationRolloutState] = ""
+		sts.Spec.UpdateStrategy.RollingUpdate.Partition = pointers.Int32(5)
+
+		patches := handle(admissionv1.Create)
+		Expect(patches).To(HaveKeyWithValue(annotation(bdv1.AnnotationRolloutState), boshdeployment.RolloutStateCanaryUpscale))
+		Expect(patches).To(HaveKeyWithValue(partition, float64(0)))
+	})
+
+	It("does not modify other statefulsets", func() {
+		delete(sts.Labels, bdv1.LabelDeploymentName)
+		sts.Spec.Template.Labels["version"] = "2"
+
+		Expect(handle(admissionv1.Update)).To(BeEmpty())
+	})
+})
@@ -2,6 +2,8 @@ package boshdeployment
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
 
@@ -12,6 +14,7 @@ import (
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -32,37 +35,41 @@ const (
 // NewStatusQSTSReconciler returns a new reconcile.Reconciler for QuarksStatefulSets Status
 func NewStatusQSTSReconciler(ctx context.Context, config *config.Config, mgr manager.Manager) reconcile.Reconciler {
 	return &ReconcileBoshDeploymentQSTSStatus{
-		ctx:    ctx,
-		config: config,
-		client: mgr.GetClient(),
-		scheme: mgr.GetScheme(),
+		ctx:       ctx,
+		config:    config,
+		client:    mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
+		scheme:    mgr.GetScheme(),
 	}
 }
 
 // NewQJobStatusReconciler returns a new reconcile.Reconciler for QuarksStatefulSets Status
 func NewQJobStatusReconciler(ctx context.Context, config *config.Config, mgr manager.Manager) reconcile.Reconciler {
 	return &ReconcileBoshDeploymentQJobStatus{
-		ctx:    ctx,
-		config: config,
-		client: mgr.GetClient(),
-		scheme: mgr.GetScheme(),
+		ctx:       ctx,
+		config:    config,
+		client:    mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
+		scheme:    mgr.GetScheme(),
 	}
 }
 
 // ReconcileBoshDeploymentQSTSStatus reconciles an QuarksStatefulSet object for its status
 type ReconcileBoshDeploymentQSTSStatus struct {
-	ctx    context.Context
-	client client.Client
-	scheme *runtime.Scheme
-	config *config.Config
+	ctx       context.Context
+	client    client.Client
+	apiReader client.Reader
+	scheme    *runtime.Scheme
+	config    *config.Config
 }
 
 // ReconcileBoshDeploymentQJobStatus reconciles an QuarksStatefulSet object for its status
 type ReconcileBoshDeploymentQJobStatus struct {
-	ctx    context.Context
-	client client.Client
-	scheme *runtime.Scheme
-	config *config.Config
+	ctx       context.Context
+	client    client.Client
+	apiReader client.Reader
+	scheme    *runtime.Scheme
+	config    *config.Config
 }
 
 // Reconcile reads that state of QuarksJobs and QuarksStatefulSets and updates the bosh deployment status accordingly.
@@ -103,7 +110,7 @@ func (r *ReconcileBoshDeploymentQJobStatus) Reconcile(_ context.Context, request
 			ctxlog.WithEvent(qJob, "GetBOSHDeployment").Errorf(ctx, "Failed to get BoshDeployment instance '%s/%s': %v", request.Namespace, deploymentName, err)
 	}
 
-	toUpdate, err := resolveDeploymentState(r.ctx, r.client, bdpl)
+	toUpdate, err := resolveDeploymentState(r.ctx, r.client, r.apiReader, bdpl)
 	if err != nil {
 		return reconcile.Result{Requeue: false}, err
 	}
@@ -163,7 +170,7 @@ func (r *ReconcileBoshDeploymentQSTSStatus) Reconcile(_ context.Context, request
 			ctxlog.WithEvent(qStatefulSet, "GetBOSHDeployment").Errorf(ctx, "Failed to get BoshDeployment instance '%s/%s': %v", request.Namespace, deploymentName, err)
 	}
 
-	toUpdate, err := resolveDeploymentState(r.ctx, r.client, bdpl)
+	toUpdate, err := resolveDeploymentState(r.ctx, r.client, r.apiReader, bdpl)
 	if err != nil {
 		return reconcile.Result{Requeue: false}, err
 	}
@@ -180,7 +187,7 @@ func (r *ReconcileBoshDeploymentQSTSStatus) Reconcile(_ context.Context, request
 	return reconcile.Result{}, nil
 }
 
-func resolveDeploymentState(ctx context.Context, client client.Client, bdpl *bdv1.BOSHDeployment) (bool, error) {
+func resolveDeploymentState(ctx context.Context, client client.Client, apiReader client.Reader, bdpl *bdv1.BOSHDeployment) (bool, error) {
 	toUpdate := false
 
 	// Get all QJobs from the bdpl
@@ -262,5 +269,33 @@ func resolveDeploymentState(ctx context.Context, client client.Client, bdpl *bdv
 		bdpl.Status.State = BDPLStateDeployed
 		toUpdate = true
 	}
+
+	instanceGroups, err := instanceGroupStatuses(ctx, client, apiReader, bdpl)
+	if err != nil {
+		return toUpdate, ctxlog.WithEvent(bdpl, "UpdateStatusError").Errorf(ctx, "Failed to get instance group status of BDPL (%v): %s", bdpl.Name, err)
+	}
+	if !reflect.DeepEqual(instanceGroups, bdpl.Status.InstanceGroups) {
+		bdpl.Status.InstanceGroups = instanceGroups
+		toUpdate = true
+	}
+
+	conditions := copyConditions(bdpl.Status.Conditions)
+	if deployedState {
+		setCondition(bdpl, bdv1.ConditionInstanceGroupsReady, metav1.ConditionTrue, "Deployed", "")
+		// A deployment recovers from a failed step, once all steps succeeded
+		if meta.IsStatusConditionTrue(bdpl.Status.Conditions, bdv1.ConditionManifestResolved) &&
+			meta.IsStatusConditionTrue(bdpl.Status.Conditions, bdv1.ConditionVariablesGenerated) &&
+			meta.IsStatusConditionTrue(bdpl.Status.Conditions, bdv1.ConditionInstanceGroupsRendered) {
+			setCondition(bdpl, bdv1.ConditionDegraded, metav1.ConditionFalse, "Deployed", "")
+		}
+	} else {
+		setCondition(bdpl, bdv1.ConditionInstanceGroupsReady, metav1.ConditionFalse, "InstanceGroupsNotReady",
+			fmt.Sprintf("%d/%d jobs completed, %d/%d instance groups deployed",
+				bdpl.Status.CompletedJobCount, bdpl.Status.TotalJobCount, bdpl.Status.DeployedInstanceGroups, bdpl.Status.TotalInstanceGroups))
+	}
+	if !conditionsEqual(conditions, bdpl.Status.Conditions) {
+		toUpdate = true
+	}
+
 	return toUpdate, nil
 }
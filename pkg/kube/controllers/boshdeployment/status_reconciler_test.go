@@ -8,8 +8,13 @@ import (
 	. "github.com/onsi/gomega"
 	"go.uber.org/zap"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -25,6 +30,7 @@ import (
 	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
 	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/pointers"
 	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
 )
 
@@ -42,6 +48,9 @@ var _ = Describe("ReconcileBDPL", func() {
 		bdpl                *bdv1.BOSHDeployment
 		desiredQStatefulSet *qstsv1a1.QuarksStatefulSet
 		desiredQJob         *qjv1a1.QuarksJob
+		statefulSets        []appsv1.StatefulSet
+		events              []corev1.Event
+		errandPods          []corev1.Pod
 		reconcileRequest    func()
 		status              *cfakes.FakeStatusWriter
 	)
@@ -68,6 +77,9 @@ var _ = Describe("ReconcileBDPL", func() {
 		ctx = ctxlog.NewParentContext(log)
 
 		status = &cfakes.FakeStatusWriter{}
+		statefulSets = []appsv1.StatefulSet{}
+		events = []corev1.Event{}
+		errandPods = []corev1.Pod{}
 
 		client = &cfakes.FakeClient{}
 		client.GetCalls(func(context context.Context, nn types.NamespacedName, object crc.Object) error {
@@ -96,12 +108,39 @@ var _ = Describe("ReconcileBDPL", func() {
 				list := &qstsv1a1.QuarksStatefulSetList{Items: []qstsv1a1.QuarksStatefulSet{*desiredQStatefulSet}}
 				list.DeepCopyInto(object)
 				return nil
+			case *appsv1.StatefulSetList:
+				list := &appsv1.StatefulSetList{Items: statefulSets}
+				list.DeepCopyInto(object)
+				return nil
+			case *corev1.EventList:
+				// the API server only returns the events matching the field selector
+				listOpts := &crc.ListOptions{}
+				listOpts.ApplyOptions(opts)
+				list := &corev1.EventList{}
+				for _, event := range events {
+					if listOpts.FieldSelector.Matches(fields.Set{
+						"involvedObject.kind": event.InvolvedObject.Kind,
+						"involvedObject.name": event.InvolvedObject.Name,
+						"type":                event.Type,
+					}) {
+						list.Items = append(list.Items, event)
+					}
+				}
+				list.DeepCopyInto(object)
+				return nil
+			case *corev1.PodList:
+				list := &corev1.PodList{Items: errandPods}
+				list.DeepCopyInto(object)
+				return nil
+			case *batchv1.JobList:
+				return nil
 			}
 
 			return apierrors.NewNotFound(schema.GroupResource{}, "test")
 		})
 
 		manager.GetClientReturns(client)
+		manager.GetAPIReaderReturns(client)
 
 		client.StatusCalls(func() crc.StatusWriter { return status })
 	})
@@ -189,6 +228,22 @@ var _ = Describe("ReconcileBDPL", func() {
 			Expect(bdpl.Status.DeployedInstanceGroups).To(Equal(1))
 			Expect(bdpl.Status.State).To(Equal(bdplcontroller.BDPLStateDeployed))
 			Expect(bdpl.Name).To(Equal("deployment-name"))
+			Expect(meta.IsStatusConditionTrue(bdpl.Status.Conditions, bdv1.ConditionInstanceGroupsReady)).To(BeTrue())
+		})
+
+		It("sets the ready condition, once all steps succeeded", func() {
+			desiredQStatefulSet.Status = qstsv1a1.QuarksStatefulSetStatus{Ready: true}
+			for _, t := range []string{bdv1.ConditionManifestResolved, bdv1.ConditionVariablesGenerated, bdv1.ConditionInstanceGroupsRendered} {
+				meta.SetStatusCondition(&bdpl.Status.Conditions, metav1.Condition{Type: t, Status: metav1.ConditionTrue, Reason: "Test"})
+			}
+			meta.SetStatusCondition(&bdpl.Status.Conditions, metav1.Condition{Type: bdv1.ConditionDegraded, Status: metav1.ConditionTrue, Reason: "RolloutFailed"})
+
+			result, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{}))
+
+			Expect(meta.IsStatusConditionFalse(bdpl.Status.Conditions, bdv1.ConditionDegraded)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(bdpl.Status.Conditions, bdv1.ConditionReady)).To(BeTrue())
 		})
 	})
 
@@ -278,6 +333,148 @@ var _ = Describe("ReconcileBDPL", func() {
 			Expect(bdpl.Status.DeployedInstanceGroups).To(Equal(0))
 			Expect(bdpl.Status.State).To(Equal(bdplcontroller.BDPLStateResolving))
 			Expect(bdpl.Name).To(Equal("deployment-name"))
+
+			condition := meta.FindStatusCondition(bdpl.Status.Conditions, bdv1.ConditionInstanceGroupsReady)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(Equal("0/1 jobs completed, 0/1 instance groups deployed"))
+			Expect(meta.IsStatusConditionFalse(bdpl.Status.Conditions, bdv1.ConditionReady)).To(BeTrue())
+		})
+	})
+
+	Context("BDPL status shows the state of each instance group", func() {
+		BeforeEach(func() {
+			statefulSets = []appsv1.StatefulSet{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "foo-z0",
+						Namespace:   "default",
+						Annotations: map[string]string{qstsv1a1.AnnotationVersion: "2"},
+						OwnerReferences: []metav1.OwnerReference{
+							{Kind: "QuarksStatefulSet", Name: "foo", Controller: pointers.Bool(true)},
+						},
+					},
+					Spec:   appsv1.StatefulSetSpec{Replicas: pointers.Int32(2)},
+					Status: appsv1.StatefulSetStatus{ReadyReplicas: 1},
+				},
+			}
+			events = []corev1.Event{
+				{
+					ObjectMeta:     metav1.ObjectMeta{Name: "old", Namespace: "default"},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "foo-z0-1"},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "FailedMount",
+					Message:        "old error",
+					LastTimestamp:  metav1.NewTime(time.Now().Add(-time.Hour)),
+				},
+				{
+					ObjectMeta:     metav1.ObjectMeta{Name: "new", Namespace: "default"},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "foo-z0-1"},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "BackOff",
+					Message:        "Back-off restarting failed container",
+					LastTimestamp:  metav1.Now(),
+				},
+				{
+					ObjectMeta:     metav1.ObjectMeta{Name: "normal", Namespace: "default"},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "foo-z0-1"},
+					Type:           corev1.EventTypeNormal,
+					Reason:         "Started",
+					LastTimestamp:  metav1.NewTime(time.Now().Add(time.Hour)),
+				},
+				{
+					ObjectMeta:     metav1.ObjectMeta{Name: "other-instance-group", Namespace: "default"},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "foo-z0-1-other"},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "Failed",
+					LastTimestamp:  metav1.NewTime(time.Now().Add(time.Hour)),
+				},
+				{
+					ObjectMeta:     metav1.ObjectMeta{Name: "other-kind", Namespace: "default"},
+					InvolvedObject: corev1.ObjectReference{Kind: "PersistentVolumeClaim", Name: "foo-z0-1"},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "ProvisioningFailed",
+					LastTimestamp:  metav1.NewTime(time.Now().Add(time.Hour)),
+				},
+			}
+		})
+
+		It("adds an entry per instance group", func() {
+			desiredQStatefulSet.Labels[bdv1.LabelInstanceGroupName] = "foo"
+			desiredQStatefulSet.Annotations = map[string]string{
+				bdv1.AnnotationIGResolvedVersion: "3",
+				bdv1.AnnotationBPMVersion:        "2",
+			}
+			desiredQStatefulSet.Spec.Template.Spec.Replicas = pointers.Int32(2)
+			desiredQJob.Name = "errand"
+			desiredQJob.Labels = map[string]string{
+				bdv1.LabelDeploymentName:    "deployment-name",
+				bdv1.LabelInstanceGroupName: "errand",
+			}
+			desiredQJob.Status.Completed = true
+
+			reconcileRequest()
+
+			Expect(bdpl.Status.InstanceGroups).To(HaveLen(2))
+
+			errand := bdpl.Status.InstanceGroups[0]
+			Expect(errand.Name).To(Equal("errand"))
+			Expect(*errand.Completed).To(BeTrue())
+			Expect(errand.LastError).To(BeEmpty())
+
+			ig := bdpl.Status.InstanceGroups[1]
+			Expect(ig.Name).To(Equal("foo"))
+			Expect(ig.Replicas).To(Equal(int32(2)))
+			Expect(ig.ReadyReplicas).To(Equal(int32(1)))
+			Expect(ig.IGResolvedVersion).To(Equal("3"))
+			Expect(ig.BPMVersion).To(Equal("2"))
+			Expect(ig.Completed).To(BeNil())
+			Expect(ig.LastError).To(Equal("BackOff: Back-off restarting failed container"))
+			Expect(ig.LastErrorTimestamp).NotTo(BeNil())
+		})
+
+		It("adds the warning events of the errand pods", func() {
+			desiredQJob.Name = "errand"
+			desiredQJob.Labels = map[string]string{
+				bdv1.LabelDeploymentName:    "deployment-name",
+				bdv1.LabelInstanceGroupName: "errand",
+			}
+			errandPods = []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "errand-abc-xyz", Namespace: "default"}}}
+			events = append(events, corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Name: "errand", Namespace: "default"},
+				InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "errand-abc-xyz"},
+				Type:           corev1.EventTypeWarning,
+				Reason:         "FailedScheduling",
+				Message:        "0/1 nodes are available",
+				LastTimestamp:  metav1.Now(),
+			})
+
+			reconcileRequest()
+
+			Expect(bdpl.Status.InstanceGroups).To(HaveLen(2))
+			errand := bdpl.Status.InstanceGroups[0]
+			Expect(errand.Name).To(Equal("errand"))
+			Expect(errand.LastError).To(Equal("FailedScheduling: 0/1 nodes are available"))
+		})
+
+		It("lists the warning events only once", func() {
+			desiredQJob.Name = "errand"
+			desiredQJob.Labels = map[string]string{
+				bdv1.LabelDeploymentName:    "deployment-name",
+				bdv1.LabelInstanceGroupName: "errand",
+			}
+			errandPods = []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "errand-abc-xyz", Namespace: "default"}}}
+
+			_, err := reconciler.Reconcile(context.Background(), request)
+			Expect(err).ToNot(HaveOccurred())
+
+			eventLists := 0
+			for i := 0; i < client.ListCallCount(); i++ {
+				_, object, _ := client.ListArgsForCall(i)
+				if _, ok := object.(*corev1.EventList); ok {
+					eventLists++
+				}
+			}
+			Expect(eventLists).To(Equal(1))
 		})
 	})
 
@@ -303,6 +500,8 @@ var _ = Describe("ReconcileBDPL", func() {
 					}}
 					list.DeepCopyInto(object)
 					return nil
+				case *appsv1.StatefulSetList, *corev1.EventList:
+					return nil
 				}
 
 				return apierrors.NewNotFound(schema.GroupResource{}, "test")
@@ -12,18 +12,22 @@ import (
 	v1 "k8s.io/api/admission/v1"
 	admissionregistration "k8s.io/api/admissionregistration/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/metrics"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/withops"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/logger"
 	"code.cloudfoundry.org/quarks-utils/pkg/monitorednamespace"
-	"code.cloudfoundry.org/quarks-utils/pkg/names"
+	utilnames "code.cloudfoundry.org/quarks-utils/pkg/names"
 	wh "code.cloudfoundry.org/quarks-utils/pkg/webhook"
 )
 
@@ -39,7 +43,7 @@ func NewBOSHDeploymentValidator(log *zap.SugaredLogger, config *config.Config) *
 		Rules: []admissionregistration.RuleWithOperations{
 			{
 				Rule: admissionregistration.Rule{
-					APIGroups:   []string{names.GroupName},
+					APIGroups:   []string{utilnames.GroupName},
 					APIVersions: []string{"v1alpha1"},
 					Resources:   []string{"boshdeployments"},
 					Scope:       &globalScopeType,
@@ -51,7 +55,7 @@ func NewBOSHDeploymentValidator(log *zap.SugaredLogger, config *config.Config) *
 			},
 		},
 		Path: "/validate-boshdeployment",
-		Name: "validate-boshdeployment." + names.GroupName,
+		Name: "validate-boshdeployment." + utilnames.GroupName,
 		NamespaceSelector: &metav1.LabelSelector{
 			MatchLabels: map[string]string{
 				monitorednamespace.LabelNamespace: config.MonitoredID,
@@ -92,28 +96,14 @@ func (v *Validator) Handle(ctx context.Context, req admission.Request) admission
 
 	err := v.decoder.Decode(req, boshDeployment)
 	if err != nil {
-		denied(fmt.Sprintf("Failed to decode BOSHDeployment: %s", err.Error()))
-	}
-
-	// verify only one bdpl exists in this namespace
-	bdpls := &bdv1.BOSHDeploymentList{}
-	err = v.client.List(ctx, bdpls, client.InNamespace(boshDeployment.GetNamespace()))
-	if err != nil {
-		return denied(fmt.Sprintf("Failed to list bdpl: %s", err.Error()))
-	}
-	if len(bdpls.Items) > 0 {
-		for _, bdpl := range bdpls.Items {
-			if bdpl.Name != boshDeployment.Name {
-				return denied(fmt.Sprintf("Only one deployment allowed per namespace. Namespace is used for '%s'", bdpl.Name))
-			}
-		}
+		return denied("DecodeError", fmt.Sprintf("Failed to decode BOSHDeployment: %s", err.Error()))
 	}
 
 	// verify dependencies exist
 	v.log.Debugf("Verifying dependencies for deployment '%s'", boshDeployment.Name)
 	resourceExist, msg := v.opsResourcesExist(ctx, boshDeployment.Spec.Ops, boshDeployment.Namespace)
 	if !resourceExist {
-		return denied(msg)
+		return denied("MissingResources", msg)
 	}
 
 	// verify with-ops manifest
@@ -124,12 +114,17 @@ func (v *Validator) Handle(ctx context.Context, req admission.Request) admission
 	)
 	manifest, err := resolver.ManifestDetailed(ctx, boshDeployment, boshDeployment.GetNamespace())
 	if err != nil {
-		return denied(fmt.Sprintf("Failed to resolve manifest: %s", err.Error()))
+		return denied("ManifestError", fmt.Sprintf("Failed to resolve manifest: %s", err.Error()))
 	}
 
 	err = validateUpdateBlock(manifest.Update)
 	if err != nil {
-		return denied(fmt.Sprintf("Failed to validate update block: %s", err.Error()))
+		return denied("InvalidUpdateBlock", fmt.Sprintf("Failed to validate update block: %s", err.Error()))
+	}
+
+	err = v.validateResourceNames(ctx, boshDeployment, manifest)
+	if err != nil {
+		return denied("ResourceNameCollision", fmt.Sprintf("Failed to validate resource names: %s", err.Error()))
 	}
 
 	return admission.Response{
@@ -139,7 +134,9 @@ func (v *Validator) Handle(ctx context.Context, req admission.Request) admission
 	}
 }
 
-func denied(msg string) admission.Response {
+// denied rejects the request and counts the denial by reason
+func denied(reason string, msg string) admission.Response {
+	metrics.ValidationDenials.WithLabelValues(reason).Inc()
 	return admission.Response{
 		AdmissionResponse: v1.AdmissionResponse{
 			Allowed: false,
@@ -223,6 +220,54 @@ func (v *Validator) opsResourcesExist(ctx context.Context, specOpsResource []bdv
 	}
 }
 
+// validateResourceNames rejects instance groups, whose resources would have
+// the same names as the resources of an instance group of another deployment
+// in the namespace. The names join the deployment and instance group names
+// with a dash, e.g. deployment 'a-b' with instance group 'c' collides with
+// deployment 'a' with instance group 'b-c'.
+func (v *Validator) validateResourceNames(ctx context.Context, bdpl *bdv1.BOSHDeployment, m *manifest.Manifest) error {
+	bdpls := &bdv1.BOSHDeploymentList{}
+	err := v.client.List(ctx, bdpls, client.InNamespace(bdpl.Namespace))
+	if err != nil {
+		return errors.Wrapf(err, "failed to list BOSHDeployments in namespace '%s'", bdpl.Namespace)
+	}
+
+	instanceGroups := map[string]string{}
+	for _, ig := range m.InstanceGroups {
+		instanceGroups[names.QuarksStatefulSetName(bdpl.Name, ig.Name)] = ig.Name
+	}
+
+	for _, other := range bdpls.Items {
+		if other.Name == bdpl.Name {
+			continue
+		}
+
+		// Deployments without a with-ops manifest have no resources yet
+		secretName := names.DeploymentSecretName(bdv1.DeploymentSecretTypeManifestWithOps, other.Name)
+		secret := &corev1.Secret{}
+		err := v.client.Get(ctx, types.NamespacedName{Namespace: bdpl.Namespace, Name: secretName}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "failed to get with-ops manifest secret '%s/%s'", bdpl.Namespace, secretName)
+		}
+		otherManifest, err := manifest.LoadYAML(secret.Data["manifest.yaml"])
+		if err != nil {
+			return errors.Wrapf(err, "failed to load with-ops manifest of BOSHDeployment '%s'", other.Name)
+		}
+
+		for _, ig := range otherManifest.InstanceGroups {
+			name := names.QuarksStatefulSetName(other.Name, ig.Name)
+			if igName, ok := instanceGroups[name]; ok {
+				return errors.Errorf("instance group '%s' has the resource name '%s' of instance group '%s' of BOSHDeployment '%s'", igName, name, ig.Name, other.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
 func validateUpdateBlock(update *manifest.Update) error {
 	if update == nil {
 		return nil
@@ -233,6 +278,9 @@ func validateUpdateBlock(update *manifest.Update) error {
 	if _, err := manifest.ExtractWatchTime(update.UpdateWatchTime); err != nil {
 		return errors.Wrap(err, "update block has invalid update_watch_time")
 	}
+	if _, err := manifest.ExtractMaxInFlight(update.MaxInFlight, 1); err != nil {
+		return errors.Wrap(err, "update block has invalid max_in_flight")
+	}
 	return nil
 }
 
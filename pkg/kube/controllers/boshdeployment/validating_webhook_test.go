@@ -6,6 +6,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/zap"
 
 	v1 "k8s.io/api/admission/v1"
@@ -19,8 +20,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/metrics"
 	"code.cloudfoundry.org/quarks-operator/testing"
 	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -37,6 +40,7 @@ var _ = Describe("When the validating webhook handles a manifest", func() {
 		manifest               *manifest.Manifest
 		validator              admission.Handler
 		boshDeploymentBytes    []byte
+		otherManifest          *bdm.Manifest
 		validateBoshDeployment func() admission.Response
 	)
 
@@ -58,10 +62,12 @@ var _ = Describe("When the validating webhook handles a manifest", func() {
 		}
 		boshDeploymentBytes, _ = json.Marshal(boshDeployment)
 		manifest, _ = env.BOSHManifestWithZeroInstances()
+		otherManifest = &bdm.Manifest{InstanceGroups: []*bdm.InstanceGroup{{Name: "nats"}}}
 	})
 
 	JustBeforeEach(func() {
 		manifestBytes, _ := manifest.Marshal()
+		otherBytes, _ := otherManifest.Marshal()
 		scheme := runtime.NewScheme()
 		Expect(corev1.AddToScheme(scheme)).To(Succeed())
 		Expect(bdv1.AddToScheme(scheme)).To(Succeed())
@@ -74,6 +80,11 @@ var _ = Describe("When the validating webhook handles a manifest", func() {
 				Data: map[string]string{
 					bdv1.ManifestSpecName: string(manifestBytes),
 				},
+			}, &bdv1.BOSHDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "deployment-x", Namespace: "default"},
+			}, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "deployment-x.with-ops", Namespace: "default"},
+				Data:       map[string][]byte{"manifest.yaml": otherBytes},
 			}).
 			WithScheme(scheme).
 			Build()
@@ -139,4 +150,50 @@ var _ = Describe("When the validating webhook handles a manifest", func() {
 			Expect(response.AdmissionResponse.Allowed).To(BeFalse())
 		})
 	})
+
+	Context("with a percentage max_in_flight", func() {
+		BeforeEach(func() {
+			manifest.Update.MaxInFlight = "30%"
+		})
+
+		It("the manifest is accepted", func() {
+			response := validateBoshDeployment()
+			Expect(response.AdmissionResponse.Allowed).To(BeTrue(), response.Result.String)
+		})
+	})
+
+	Context("with an invalid max_in_flight", func() {
+		BeforeEach(func() {
+			manifest.Update.MaxInFlight = "many"
+		})
+
+		It("the manifest is rejected", func() {
+			response := validateBoshDeployment()
+			Expect(response.AdmissionResponse.Allowed).To(BeFalse())
+			Expect(response.AdmissionResponse.Result.Message).To(ContainSubstring("invalid max_in_flight"))
+		})
+
+		It("counts the denial by reason", func() {
+			before := testutil.ToFloat64(metrics.ValidationDenials.WithLabelValues("InvalidUpdateBlock"))
+			validateBoshDeployment()
+			Expect(testutil.ToFloat64(metrics.ValidationDenials.WithLabelValues("InvalidUpdateBlock"))).To(Equal(before + 1))
+		})
+	})
+
+	Context("when another deployment uses the same resource names", func() {
+		BeforeEach(func() {
+			manifest.InstanceGroups[0].Name = "x-nats"
+		})
+
+		It("the manifest is rejected", func() {
+			response := validateBoshDeployment()
+			Expect(response.AdmissionResponse.Allowed).To(BeFalse())
+			Expect(response.AdmissionResponse.Result.Message).To(ContainSubstring("instance group 'x-nats' has the resource name 'deployment-x-nats' of instance group 'nats' of BOSHDeployment 'deployment-x'"))
+		})
+	})
+
+	It("accepts instance groups with the same name in another deployment", func() {
+		response := validateBoshDeployment()
+		Expect(response.AdmissionResponse.Allowed).To(BeTrue(), response.Result.String)
+	})
 })
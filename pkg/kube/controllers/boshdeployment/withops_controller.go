@@ -22,6 +22,7 @@ import (
 	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	boshnames "code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/withops"
 	qsv1a1 "code.cloudfoundry.org/quarks-secret/pkg/kube/apis/quarkssecret/v1alpha1"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
@@ -43,7 +44,9 @@ func AddWithOps(ctx context.Context, config *config.Config, mgr manager.Manager)
 			func() withops.Interpolator { return withops.NewInterpolator() },
 		),
 		controllerutil.SetControllerReference,
-		func(m bdm.Manifest) (boshdns.DomainNameService, error) { return boshdns.New(m) },
+		func(deploymentName string, mode bdv1.DNSMode, m bdm.Manifest) (boshdns.DomainNameService, error) {
+			return boshdns.NewForMode(deploymentName, mode, m)
+		},
 	)
 
 	// Create a new controller
@@ -119,7 +122,7 @@ func AddWithOps(ctx context.Context, config *config.Config, mgr manager.Manager)
 			result := []reconcile.Request{
 				{
 					NamespacedName: types.NamespacedName{
-						Name:      bdv1.DeploymentSecretTypeManifestWithOps.String(),
+						Name:      boshnames.DeploymentSecretName(bdv1.DeploymentSecretTypeManifestWithOps, s.Labels[bdv1.LabelDeploymentName]),
 						Namespace: s.Namespace,
 					},
 				},
@@ -135,6 +138,35 @@ func AddWithOps(ctx context.Context, config *config.Config, mgr manager.Manager)
 		return errors.Wrapf(err, "Watching secrets failed in withops controller.")
 	}
 
+	// Watch the DNS mode of BOSHDeployments, so the DNS is applied again
+	p = predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*bdv1.BOSHDeployment)
+			n := e.ObjectNew.(*bdv1.BOSHDeployment)
+			return o.Spec.DNS != n.Spec.DNS
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.BOSHDeployment{}}, handler.EnqueueRequestsFromMapFunc(
+		func(a client.Object) []reconcile.Request {
+			result := []reconcile.Request{
+				{
+					NamespacedName: types.NamespacedName{
+						Name:      boshnames.DeploymentSecretName(bdv1.DeploymentSecretTypeManifestWithOps, a.GetName()),
+						Namespace: a.GetNamespace(),
+					},
+				},
+			}
+			ctxlog.NewMappingEvent(a).Debug(ctx, result[0], "WithOpsSecret", a.GetName(), bdv1.BOSHDeploymentResourceKind)
+
+			return result
+		}), nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching bosh deployments failed in withops controller.")
+	}
+
 	return nil
 }
 
@@ -18,6 +18,8 @@ import (
 	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/desiredmanifest"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/metrics"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 	"code.cloudfoundry.org/quarks-utils/pkg/meltdown"
@@ -30,7 +32,7 @@ type InterpolateSecrets interface {
 }
 
 // NewDNSFunc returns a dns client for the manifest
-type NewDNSFunc func(m bdm.Manifest) (boshdns.DomainNameService, error)
+type NewDNSFunc func(deploymentName string, mode bdv1.DNSMode, m bdm.Manifest) (boshdns.DomainNameService, error)
 
 // NewWithOpsReconciler returns a new reconcile.Reconciler
 func NewWithOpsReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, resolver InterpolateSecrets, srf setReferenceFunc, dns NewDNSFunc) reconcile.Reconciler {
@@ -108,6 +110,7 @@ func (r *ReconcileWithOps) Reconcile(_ context.Context, request reconcile.Reques
 
 	if meltdown.NewAnnotationWindow(ReconcileSkipDuration, annotations).Contains(time.Now()) {
 		log.Infof(ctx, "Meltdown in progress for '%s'", request.NamespacedName)
+		metrics.MeltdownHits.WithLabelValues(metrics.ControllerWithOps).Inc()
 		return reconcile.Result{}, nil
 	}
 	log.Infof(ctx, "Meltdown ended for '%s'", request.NamespacedName)
@@ -129,30 +132,29 @@ func (r *ReconcileWithOps) Reconcile(_ context.Context, request reconcile.Reques
 
 	withOpsManifestData := withOpsSecret.Data["manifest.yaml"]
 
+	start := time.Now()
 	desiredManifestBytes, err := r.resolver.InterpolateVariableFromSecrets(ctx, withOpsManifestData, request.Namespace, boshdeploymentName)
+	metrics.ManifestResolutionDuration.WithLabelValues(metrics.ManifestDesired).Observe(time.Since(start).Seconds())
 	if err != nil {
 		if strings.HasSuffix(err.Error(), "has generated status false") {
 			log.WithEvent(withOpsSecret, "SkipReconcile").Debugf(ctx, "Requeue reconcile: %s", err)
 			return reconcile.Result{RequeueAfter: time.Second * 5}, nil
 		}
+		updateFailedCondition(ctx, r.client, boshdeployment, bdv1.ConditionVariablesGenerated, "VariableInterpolationError", err)
 		return reconcile.Result{},
 			log.WithEvent(withOpsSecret, "WithOpsManifestError").Errorf(ctx, "failed to interpolated variables for BOSHDeployment '%s': %v", boshdeploymentName, err)
 	}
 
-	err = r.createDesiredManifest(ctx, desiredManifestBytes, *boshdeployment, request.Namespace)
-	if err != nil {
-		return reconcile.Result{},
-			log.WithEvent(withOpsSecret, "WithOpsManifestError").Errorf(ctx, "failed to create desired manifest secret for BOSHDeployment '%s': %v", boshdeploymentName, err)
-	}
-
 	manifest, err := bdm.LoadYAML(desiredManifestBytes)
 	if err != nil {
+		updateFailedCondition(ctx, r.client, boshdeployment, bdv1.ConditionManifestResolved, "DesiredManifestError", err)
 		return reconcile.Result{},
 			log.WithEvent(withOpsSecret, "WithOpsManifestError").Errorf(ctx, "failed to unmarshal manifest bytes for boshdeployment '%s': %v", boshdeploymentName, err)
 	}
 
-	dns, err := r.newDNSFunc(*manifest)
+	dns, err := r.newDNSFunc(boshdeploymentName, boshdeployment.Spec.DNS, *manifest)
 	if err != nil {
+		updateFailedCondition(ctx, r.client, boshdeployment, bdv1.ConditionInstanceGroupsRendered, "DNSError", err)
 		return reconcile.Result{},
 			log.WithEvent(withOpsSecret, "WithOpsManifestError").Errorf(ctx, "failed to create desired manifest secret for BOSHDeployment '%s': %v", boshdeploymentName, err)
 	}
@@ -161,10 +163,29 @@ func (r *ReconcileWithOps) Reconcile(_ context.Context, request reconcile.Reques
 		return r.setReference(boshdeployment, object, r.scheme)
 	})
 	if err != nil {
+		updateFailedCondition(ctx, r.client, boshdeployment, bdv1.ConditionInstanceGroupsRendered, "DNSError", err)
 		return reconcile.Result{},
 			log.WithEvent(withOpsSecret, "WithOpsManifestError").Errorf(ctx, "Failed to reconcile dns: %v", err)
 	}
 
+	// The DNS is applied first, so the services of the native DNS aliases
+	// exist when the instance groups of the desired manifest are rendered
+	err = r.createDesiredManifest(ctx, desiredManifestBytes, *boshdeployment, request.Namespace)
+	if err != nil {
+		updateFailedCondition(ctx, r.client, boshdeployment, bdv1.ConditionManifestResolved, "DesiredManifestError", err)
+		return reconcile.Result{},
+			log.WithEvent(withOpsSecret, "WithOpsManifestError").Errorf(ctx, "failed to create desired manifest secret for BOSHDeployment '%s': %v", boshdeploymentName, err)
+	}
+
+	err = updateConditions(ctx, r.client, boshdeployment,
+		metav1.Condition{Type: bdv1.ConditionVariablesGenerated, Status: metav1.ConditionTrue, Reason: "VariablesInterpolated"},
+		metav1.Condition{Type: bdv1.ConditionManifestResolved, Status: metav1.ConditionTrue, Reason: "DesiredManifestCreated"},
+	)
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(withOpsSecret, "UpdateError").Errorf(ctx, "failed to update conditions on bdpl '%s': %v", boshdeploymentName, err)
+	}
+
 	return reconcile.Result{}, nil
 }
 
@@ -183,7 +204,7 @@ func (r *ReconcileWithOps) createDesiredManifest(ctx context.Context, desiredMan
 		return err
 	}
 
-	desiredManifestSecretName := "desired-manifest"
+	desiredManifestSecretName := desiredmanifest.SecretName(boshdeployment.Name)
 	secretLabels := map[string]string{
 		bdv1.LabelDeploymentName:       boshdeployment.Name,
 		bdv1.LabelDeploymentSecretType: bdv1.DeploymentSecretTypeDesiredManifest.String(),
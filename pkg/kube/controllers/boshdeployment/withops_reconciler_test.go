@@ -19,6 +19,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -38,6 +39,7 @@ var _ = Describe("ReconcileWithOps", func() {
 		resolver       fakes.FakeInterpolateSecrets
 		config         *cfcfg.Config
 		client         *fakes.FakeClient
+		status         *fakes.FakeStatusWriter
 		withOpsSecret  *corev1.Secret
 		passwordSecret *corev1.Secret
 		boshDeployment *bdv1.BOSHDeployment
@@ -117,6 +119,9 @@ variables:
 			return nil
 		})
 
+		status = &fakes.FakeStatusWriter{}
+		client.StatusCalls(func() crc.StatusWriter { return status })
+
 		manager.GetClientReturns(client)
 
 		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "fakeWithOpsSecret", Namespace: "default"}}
@@ -127,7 +132,7 @@ variables:
 			ctx, config, manager,
 			&resolver,
 			controllerutil.SetControllerReference,
-			func(m bdm.Manifest) (boshdns.DomainNameService, error) {
+			func(deploymentName string, mode bdv1.DNSMode, m bdm.Manifest) (boshdns.DomainNameService, error) {
 				return boshdns.NewSimpleDomainNameService(), nil
 			},
 		)
@@ -139,7 +144,7 @@ variables:
 				switch object := object.(type) {
 				case *corev1.Secret:
 					secret := object
-					Expect(secret.Name).To(Equal("desired-manifest-v1"))
+					Expect(secret.Name).To(Equal("gora.desired-manifest-v1"))
 					Expect(secret.Labels).To(Equal(map[string]string{
 						"quarks.cloudfoundry.org/deployment-name": "gora",
 						"quarks.cloudfoundry.org/secret-kind":     "versionedSecret",
@@ -155,6 +160,12 @@ variables:
 			Expect(result).To(Equal(reconcile.Result{
 				Requeue: false,
 			}))
+
+			Expect(status.UpdateCallCount()).To(Equal(1))
+			_, object, _ := status.UpdateArgsForCall(0)
+			conditions := object.(*bdv1.BOSHDeployment).Status.Conditions
+			Expect(meta.IsStatusConditionTrue(conditions, bdv1.ConditionManifestResolved)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(conditions, bdv1.ConditionVariablesGenerated)).To(BeTrue())
 		})
 
 		It("should requeue after if quarks secret is not found", func() {
@@ -163,6 +174,15 @@ variables:
 			_, err := reconciler.Reconcile(context.Background(), request)
 			Expect(err).To(HaveOccurred())
 			Expect(logs.FilterMessageSnippet("Expected to find variables: password").Len()).To(Equal(1))
+
+			Expect(status.UpdateCallCount()).To(Equal(1))
+			_, object, _ := status.UpdateArgsForCall(0)
+			conditions := object.(*bdv1.BOSHDeployment).Status.Conditions
+			condition := meta.FindStatusCondition(conditions, bdv1.ConditionVariablesGenerated)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("VariableInterpolationError"))
+			Expect(meta.IsStatusConditionTrue(conditions, bdv1.ConditionDegraded)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(conditions, bdv1.ConditionReady)).To(BeTrue())
 		})
 	})
 })
@@ -7,8 +7,11 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
+	admissionregistration "k8s.io/api/admissionregistration/v1beta1"
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
@@ -41,6 +44,12 @@ var addToManagerFuncs = []func(context.Context, *config.Config, manager.Manager)
 	boshdeployment.AddBPM,
 	boshdeployment.AddWithOps,
 	boshdeployment.AddBDPLStatusReconcilers,
+	boshdeployment.AddRollout,
+	boshdeployment.AddRollback,
+	boshdeployment.AddErrand,
+	boshdeployment.AddErrandSchedule,
+	boshdeployment.AddGit,
+	quarkslink.AddQuarksLink,
 	quarksrestart.AddRestart,
 }
 
@@ -60,6 +69,7 @@ var validatingHookFuncs = []func(*zap.SugaredLogger, *config.Config) *webhook.Op
 var mutatingHookFuncs = []func(*zap.SugaredLogger, *config.Config) *webhook.OperatorWebhook{
 	quarkslink.NewBOSHLinkPodMutator,
 	waitservice.NewWaitServicePodMutator,
+	boshdeployment.NewStatefulSetRolloutMutator,
 }
 
 // AddToManager adds all Controllers to the Manager
@@ -81,7 +91,7 @@ func AddToScheme(s *runtime.Scheme) error {
 func AddHooks(ctx context.Context, config *config.Config, m manager.Manager, generator credsgen.Generator) error {
 	ctxlog.Infof(ctx, "Setting up webhook server on %s:%d", config.WebhookServerHost, config.WebhookServerPort)
 
-	webhookConfig := webhook.NewConfig(m.GetClient(), config, generator, WebhookConfigPrefix+config.OperatorNamespace)
+	webhookConfig := webhook.NewConfig(webhookConfigClient{Client: m.GetClient()}, config, generator, WebhookConfigPrefix+config.OperatorNamespace)
 
 	hookServer := m.GetWebhookServer()
 	hookServer.CertDir = webhookConfig.CertDir
@@ -105,6 +115,10 @@ func AddHooks(ctx context.Context, config *config.Config, m manager.Manager, gen
 
 	ctxlog.Info(ctx, "Generating webhook certificates")
 	err := webhookConfig.SetupCertificate(ctx, "cf-operator-webhook")
+	if apierrors.IsAlreadyExists(err) {
+		// Another operator replica created the certificate secret at the same time, use its certificate
+		err = webhookConfig.SetupCertificate(ctx, "cf-operator-webhook")
+	}
 	if err != nil {
 		return errors.Wrap(err, "setting up the webhook server certificate")
 	}
@@ -124,6 +138,50 @@ func AddHooks(ctx context.Context, config *config.Config, m manager.Manager, gen
 	return nil
 }
 
+// webhookConfigClient updates existing webhook configurations in place. The
+// webhook config deletes and recreates them, which would disable the webhooks
+// for a moment, whenever an operator replica starts.
+type webhookConfigClient struct {
+	client.Client
+}
+
+// Delete skips the webhook configurations, they are updated by Create instead
+func (c webhookConfigClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if isWebhookConfiguration(obj) {
+		return nil
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+// Create updates a webhook configuration, if it exists already
+func (c webhookConfigClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	err := c.Client.Create(ctx, obj, opts...)
+	if !apierrors.IsAlreadyExists(err) || !isWebhookConfiguration(obj) {
+		return err
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	err = c.Client.Update(ctx, obj)
+	if apierrors.IsConflict(err) {
+		// All replicas share the certificate, so a configuration written by
+		// another replica at the same time is equal to ours
+		return nil
+	}
+	return err
+}
+
+func isWebhookConfiguration(obj client.Object) bool {
+	switch obj.(type) {
+	case *admissionregistration.ValidatingWebhookConfiguration, *admissionregistration.MutatingWebhookConfiguration:
+		return true
+	}
+	return false
+}
+
 func ordinaryHTTPHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -125,6 +125,42 @@ var _ = Describe("Controllers", func() {
 			})
 		})
 
+		Context("if another replica persists the cert secret at the same time", func() {
+			It("uses the certificate of the other replica", func() {
+				secret := &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"data": map[string]interface{}{
+							"certificate":    base64.StdEncoding.EncodeToString([]byte("the-cert")),
+							"private_key":    base64.StdEncoding.EncodeToString([]byte("the-key")),
+							"ca_certificate": base64.StdEncoding.EncodeToString([]byte("the-ca-cert")),
+							"ca_private_key": base64.StdEncoding.EncodeToString([]byte("the-ca-key")),
+						},
+					},
+				}
+				created := false
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object crc.Object) error {
+					if object, ok := object.(*unstructured.Unstructured); ok && created {
+						secret.DeepCopyInto(object)
+						return nil
+					}
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+				client.CreateCalls(func(context context.Context, object crc.Object, _ ...crc.CreateOption) error {
+					switch object.(type) {
+					case *admissionregistration.ValidatingWebhookConfiguration, *admissionregistration.MutatingWebhookConfiguration:
+						return nil
+					}
+					created = true
+					return apierrors.NewAlreadyExists(schema.GroupResource{}, object.GetName())
+				})
+
+				err := controllers.AddHooks(ctx, config, manager, generator)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(client.GetCallCount()).To(Equal(2))
+				Expect(client.CreateCallCount()).To(Equal(3)) // the secret of the other replica and the 2 webhook configs
+			})
+		})
+
 		Context("if there is a persisted cert secret already", func() {
 			BeforeEach(func() {
 				secret := &unstructured.Unstructured{
@@ -157,6 +193,30 @@ var _ = Describe("Controllers", func() {
 				Expect(client.CreateCallCount()).To(Equal(2)) // webhook config for Mutation and Validation
 			})
 
+			It("updates existing webhook configurations in place", func() {
+				client.CreateCalls(func(context context.Context, object crc.Object, _ ...crc.CreateOption) error {
+					return apierrors.NewAlreadyExists(schema.GroupResource{}, object.GetName())
+				})
+				getSecret := client.GetStub
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object crc.Object) error {
+					if _, ok := object.(*unstructured.Unstructured); ok {
+						return getSecret(context, nn, object)
+					}
+					object.SetResourceVersion("42")
+					return nil
+				})
+
+				err := controllers.AddHooks(ctx, config, manager, generator)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(client.DeleteCallCount()).To(Equal(0))
+				Expect(client.UpdateCallCount()).To(Equal(2))
+				for i := 0; i < client.UpdateCallCount(); i++ {
+					_, object, _ := client.UpdateArgsForCall(i)
+					Expect(object.GetName()).To(Equal("cf-operator-hook-default"))
+					Expect(object.GetResourceVersion()).To(Equal("42"))
+				}
+			})
+
 			It("generates the webhook configuration", func() {
 				client.CreateCalls(func(context context.Context, object crc.Object, _ ...crc.CreateOption) error {
 					// We should be getting 2 Create calls - one for the
@@ -165,7 +225,8 @@ var _ = Describe("Controllers", func() {
 					switch config := object.(type) {
 					case *admissionregistration.MutatingWebhookConfiguration:
 						Expect(config.Name).To(Equal("cf-operator-hook-default"))
-						Expect(len(config.Webhooks)).To(Equal(2))
+						Expect(len(config.Webhooks)).To(Equal(3))
+						Expect(config.Webhooks[2].Name).To(Equal("mutate-bosh-statefulsets.quarks.cloudfoundry.org"))
 
 						wh := config.Webhooks[0]
 						Expect(wh.Name).To(Equal("mutate-tangled-pods.quarks.cloudfoundry.org"))
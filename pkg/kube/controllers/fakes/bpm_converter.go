@@ -8,16 +8,17 @@ import (
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/bpmconverter"
 	"code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/boshdeployment"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
 )
 
 type FakeBPMConverter struct {
-	ResourcesStub        func(manifest.Manifest, string, string, string, string, *manifest.InstanceGroup, bpm.Configs, string) (*bpmconverter.Resources, error)
+	ResourcesStub        func(manifest.Manifest, string, string, boshdns.PodDNS, string, *manifest.InstanceGroup, bpm.Configs, string) (*bpmconverter.Resources, error)
 	resourcesMutex       sync.RWMutex
 	resourcesArgsForCall []struct {
 		arg1 manifest.Manifest
 		arg2 string
 		arg3 string
-		arg4 string
+		arg4 boshdns.PodDNS
 		arg5 string
 		arg6 *manifest.InstanceGroup
 		arg7 bpm.Configs
@@ -35,14 +36,14 @@ type FakeBPMConverter struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeBPMConverter) Resources(arg1 manifest.Manifest, arg2 string, arg3 string, arg4 string, arg5 string, arg6 *manifest.InstanceGroup, arg7 bpm.Configs, arg8 string) (*bpmconverter.Resources, error) {
+func (fake *FakeBPMConverter) Resources(arg1 manifest.Manifest, arg2 string, arg3 string, arg4 boshdns.PodDNS, arg5 string, arg6 *manifest.InstanceGroup, arg7 bpm.Configs, arg8 string) (*bpmconverter.Resources, error) {
 	fake.resourcesMutex.Lock()
 	ret, specificReturn := fake.resourcesReturnsOnCall[len(fake.resourcesArgsForCall)]
 	fake.resourcesArgsForCall = append(fake.resourcesArgsForCall, struct {
 		arg1 manifest.Manifest
 		arg2 string
 		arg3 string
-		arg4 string
+		arg4 boshdns.PodDNS
 		arg5 string
 		arg6 *manifest.InstanceGroup
 		arg7 bpm.Configs
@@ -66,13 +67,13 @@ func (fake *FakeBPMConverter) ResourcesCallCount() int {
 	return len(fake.resourcesArgsForCall)
 }
 
-func (fake *FakeBPMConverter) ResourcesCalls(stub func(manifest.Manifest, string, string, string, string, *manifest.InstanceGroup, bpm.Configs, string) (*bpmconverter.Resources, error)) {
+func (fake *FakeBPMConverter) ResourcesCalls(stub func(manifest.Manifest, string, string, boshdns.PodDNS, string, *manifest.InstanceGroup, bpm.Configs, string) (*bpmconverter.Resources, error)) {
 	fake.resourcesMutex.Lock()
 	defer fake.resourcesMutex.Unlock()
 	fake.ResourcesStub = stub
 }
 
-func (fake *FakeBPMConverter) ResourcesArgsForCall(i int) (manifest.Manifest, string, string, string, string, *manifest.InstanceGroup, bpm.Configs, string) {
+func (fake *FakeBPMConverter) ResourcesArgsForCall(i int) (manifest.Manifest, string, string, boshdns.PodDNS, string, *manifest.InstanceGroup, bpm.Configs, string) {
 	fake.resourcesMutex.RLock()
 	defer fake.resourcesMutex.RUnlock()
 	argsForCall := fake.resourcesArgsForCall[i]
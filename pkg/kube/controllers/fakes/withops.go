@@ -11,7 +11,23 @@ import (
 )
 
 type FakeWithOps struct {
-	ManifestStub        func(context.Context, *v1alpha1.BOSHDeployment, string) (*manifest.Manifest, error)
+	InterpolateVariableFromSecretsStub        func(context.Context, []byte, string, string) ([]byte, error)
+	interpolateVariableFromSecretsMutex       sync.RWMutex
+	interpolateVariableFromSecretsArgsForCall []struct {
+		arg1 context.Context
+		arg2 []byte
+		arg3 string
+		arg4 string
+	}
+	interpolateVariableFromSecretsReturns struct {
+		result1 []byte
+		result2 error
+	}
+	interpolateVariableFromSecretsReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	ManifestStub        func(context.Context, *v1alpha1.BOSHDeployment, string) (*manifest.Manifest, []v1alpha1.RuntimeConfigStatus, error)
 	manifestMutex       sync.RWMutex
 	manifestArgsForCall []struct {
 		arg1 context.Context
@@ -20,9 +36,26 @@ type FakeWithOps struct {
 	}
 	manifestReturns struct {
 		result1 *manifest.Manifest
-		result2 error
+		result2 []v1alpha1.RuntimeConfigStatus
+		result3 error
 	}
 	manifestReturnsOnCall map[int]struct {
+		result1 *manifest.Manifest
+		result2 []v1alpha1.RuntimeConfigStatus
+		result3 error
+	}
+	ManifestDetailedStub        func(context.Context, *v1alpha1.BOSHDeployment, string) (*manifest.Manifest, error)
+	manifestDetailedMutex       sync.RWMutex
+	manifestDetailedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *v1alpha1.BOSHDeployment
+		arg3 string
+	}
+	manifestDetailedReturns struct {
+		result1 *manifest.Manifest
+		result2 error
+	}
+	manifestDetailedReturnsOnCall map[int]struct {
 		result1 *manifest.Manifest
 		result2 error
 	}
@@ -30,7 +63,79 @@ type FakeWithOps struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeWithOps) Manifest(arg1 context.Context, arg2 *v1alpha1.BOSHDeployment, arg3 string) (*manifest.Manifest, error) {
+func (fake *FakeWithOps) InterpolateVariableFromSecrets(arg1 context.Context, arg2 []byte, arg3 string, arg4 string) ([]byte, error) {
+	var arg2Copy []byte
+	if arg2 != nil {
+		arg2Copy = make([]byte, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.interpolateVariableFromSecretsMutex.Lock()
+	ret, specificReturn := fake.interpolateVariableFromSecretsReturnsOnCall[len(fake.interpolateVariableFromSecretsArgsForCall)]
+	fake.interpolateVariableFromSecretsArgsForCall = append(fake.interpolateVariableFromSecretsArgsForCall, struct {
+		arg1 context.Context
+		arg2 []byte
+		arg3 string
+		arg4 string
+	}{arg1, arg2Copy, arg3, arg4})
+	stub := fake.InterpolateVariableFromSecretsStub
+	fakeReturns := fake.interpolateVariableFromSecretsReturns
+	fake.recordInvocation("InterpolateVariableFromSecrets", []interface{}{arg1, arg2Copy, arg3, arg4})
+	fake.interpolateVariableFromSecretsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeWithOps) InterpolateVariableFromSecretsCallCount() int {
+	fake.interpolateVariableFromSecretsMutex.RLock()
+	defer fake.interpolateVariableFromSecretsMutex.RUnlock()
+	return len(fake.interpolateVariableFromSecretsArgsForCall)
+}
+
+func (fake *FakeWithOps) InterpolateVariableFromSecretsCalls(stub func(context.Context, []byte, string, string) ([]byte, error)) {
+	fake.interpolateVariableFromSecretsMutex.Lock()
+	defer fake.interpolateVariableFromSecretsMutex.Unlock()
+	fake.InterpolateVariableFromSecretsStub = stub
+}
+
+func (fake *FakeWithOps) InterpolateVariableFromSecretsArgsForCall(i int) (context.Context, []byte, string, string) {
+	fake.interpolateVariableFromSecretsMutex.RLock()
+	defer fake.interpolateVariableFromSecretsMutex.RUnlock()
+	argsForCall := fake.interpolateVariableFromSecretsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeWithOps) InterpolateVariableFromSecretsReturns(result1 []byte, result2 error) {
+	fake.interpolateVariableFromSecretsMutex.Lock()
+	defer fake.interpolateVariableFromSecretsMutex.Unlock()
+	fake.InterpolateVariableFromSecretsStub = nil
+	fake.interpolateVariableFromSecretsReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeWithOps) InterpolateVariableFromSecretsReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.interpolateVariableFromSecretsMutex.Lock()
+	defer fake.interpolateVariableFromSecretsMutex.Unlock()
+	fake.InterpolateVariableFromSecretsStub = nil
+	if fake.interpolateVariableFromSecretsReturnsOnCall == nil {
+		fake.interpolateVariableFromSecretsReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.interpolateVariableFromSecretsReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeWithOps) Manifest(arg1 context.Context, arg2 *v1alpha1.BOSHDeployment, arg3 string) (*manifest.Manifest, []v1alpha1.RuntimeConfigStatus, error) {
 	fake.manifestMutex.Lock()
 	ret, specificReturn := fake.manifestReturnsOnCall[len(fake.manifestArgsForCall)]
 	fake.manifestArgsForCall = append(fake.manifestArgsForCall, struct {
@@ -38,16 +143,17 @@ func (fake *FakeWithOps) Manifest(arg1 context.Context, arg2 *v1alpha1.BOSHDeplo
 		arg2 *v1alpha1.BOSHDeployment
 		arg3 string
 	}{arg1, arg2, arg3})
+	stub := fake.ManifestStub
+	fakeReturns := fake.manifestReturns
 	fake.recordInvocation("Manifest", []interface{}{arg1, arg2, arg3})
 	fake.manifestMutex.Unlock()
-	if fake.ManifestStub != nil {
-		return fake.ManifestStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
-		return ret.result1, ret.result2
+		return ret.result1, ret.result2, ret.result3
 	}
-	fakeReturns := fake.manifestReturns
-	return fakeReturns.result1, fakeReturns.result2
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
 }
 
 func (fake *FakeWithOps) ManifestCallCount() int {
@@ -56,7 +162,7 @@ func (fake *FakeWithOps) ManifestCallCount() int {
 	return len(fake.manifestArgsForCall)
 }
 
-func (fake *FakeWithOps) ManifestCalls(stub func(context.Context, *v1alpha1.BOSHDeployment, string) (*manifest.Manifest, error)) {
+func (fake *FakeWithOps) ManifestCalls(stub func(context.Context, *v1alpha1.BOSHDeployment, string) (*manifest.Manifest, []v1alpha1.RuntimeConfigStatus, error)) {
 	fake.manifestMutex.Lock()
 	defer fake.manifestMutex.Unlock()
 	fake.ManifestStub = stub
@@ -69,27 +175,96 @@ func (fake *FakeWithOps) ManifestArgsForCall(i int) (context.Context, *v1alpha1.
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *FakeWithOps) ManifestReturns(result1 *manifest.Manifest, result2 error) {
+func (fake *FakeWithOps) ManifestReturns(result1 *manifest.Manifest, result2 []v1alpha1.RuntimeConfigStatus, result3 error) {
 	fake.manifestMutex.Lock()
 	defer fake.manifestMutex.Unlock()
 	fake.ManifestStub = nil
 	fake.manifestReturns = struct {
 		result1 *manifest.Manifest
-		result2 error
-	}{result1, result2}
+		result2 []v1alpha1.RuntimeConfigStatus
+		result3 error
+	}{result1, result2, result3}
 }
 
-func (fake *FakeWithOps) ManifestReturnsOnCall(i int, result1 *manifest.Manifest, result2 error) {
+func (fake *FakeWithOps) ManifestReturnsOnCall(i int, result1 *manifest.Manifest, result2 []v1alpha1.RuntimeConfigStatus, result3 error) {
 	fake.manifestMutex.Lock()
 	defer fake.manifestMutex.Unlock()
 	fake.ManifestStub = nil
 	if fake.manifestReturnsOnCall == nil {
 		fake.manifestReturnsOnCall = make(map[int]struct {
 			result1 *manifest.Manifest
-			result2 error
+			result2 []v1alpha1.RuntimeConfigStatus
+			result3 error
 		})
 	}
 	fake.manifestReturnsOnCall[i] = struct {
+		result1 *manifest.Manifest
+		result2 []v1alpha1.RuntimeConfigStatus
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeWithOps) ManifestDetailed(arg1 context.Context, arg2 *v1alpha1.BOSHDeployment, arg3 string) (*manifest.Manifest, error) {
+	fake.manifestDetailedMutex.Lock()
+	ret, specificReturn := fake.manifestDetailedReturnsOnCall[len(fake.manifestDetailedArgsForCall)]
+	fake.manifestDetailedArgsForCall = append(fake.manifestDetailedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *v1alpha1.BOSHDeployment
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.ManifestDetailedStub
+	fakeReturns := fake.manifestDetailedReturns
+	fake.recordInvocation("ManifestDetailed", []interface{}{arg1, arg2, arg3})
+	fake.manifestDetailedMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeWithOps) ManifestDetailedCallCount() int {
+	fake.manifestDetailedMutex.RLock()
+	defer fake.manifestDetailedMutex.RUnlock()
+	return len(fake.manifestDetailedArgsForCall)
+}
+
+func (fake *FakeWithOps) ManifestDetailedCalls(stub func(context.Context, *v1alpha1.BOSHDeployment, string) (*manifest.Manifest, error)) {
+	fake.manifestDetailedMutex.Lock()
+	defer fake.manifestDetailedMutex.Unlock()
+	fake.ManifestDetailedStub = stub
+}
+
+func (fake *FakeWithOps) ManifestDetailedArgsForCall(i int) (context.Context, *v1alpha1.BOSHDeployment, string) {
+	fake.manifestDetailedMutex.RLock()
+	defer fake.manifestDetailedMutex.RUnlock()
+	argsForCall := fake.manifestDetailedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeWithOps) ManifestDetailedReturns(result1 *manifest.Manifest, result2 error) {
+	fake.manifestDetailedMutex.Lock()
+	defer fake.manifestDetailedMutex.Unlock()
+	fake.ManifestDetailedStub = nil
+	fake.manifestDetailedReturns = struct {
+		result1 *manifest.Manifest
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeWithOps) ManifestDetailedReturnsOnCall(i int, result1 *manifest.Manifest, result2 error) {
+	fake.manifestDetailedMutex.Lock()
+	defer fake.manifestDetailedMutex.Unlock()
+	fake.ManifestDetailedStub = nil
+	if fake.manifestDetailedReturnsOnCall == nil {
+		fake.manifestDetailedReturnsOnCall = make(map[int]struct {
+			result1 *manifest.Manifest
+			result2 error
+		})
+	}
+	fake.manifestDetailedReturnsOnCall[i] = struct {
 		result1 *manifest.Manifest
 		result2 error
 	}{result1, result2}
@@ -98,8 +273,12 @@ func (fake *FakeWithOps) ManifestReturnsOnCall(i int, result1 *manifest.Manifest
 func (fake *FakeWithOps) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.interpolateVariableFromSecretsMutex.RLock()
+	defer fake.interpolateVariableFromSecretsMutex.RUnlock()
 	fake.manifestMutex.RLock()
 	defer fake.manifestMutex.RUnlock()
+	fake.manifestDetailedMutex.RLock()
+	defer fake.manifestDetailedMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -19,6 +19,10 @@ var (
 	// ConsumesKey is the key for identifying the provider to be consumed, in
 	// the format of: '[{"name":"<name>","type":"<type>"}]' (JSON string)
 	ConsumesKey = fmt.Sprintf("%s/consumes", apis.GroupName)
+	// LinkTemplateKey is the key for the name of a config map, whose entries
+	// are Go templates, which are rendered with the consumed links into
+	// files below '/quarks/link-rendered'
+	LinkTemplateKey = fmt.Sprintf("%s/link-template", apis.GroupName)
 )
 
 func validEntanglement(annotations map[string]string) bool {
@@ -67,6 +71,9 @@ type entanglement struct {
 	deployment string
 	consumes   string
 	links      links
+	// quarksLink is the name of the QuarksLink consumer, if the entanglement
+	// was not requested by pod annotations
+	quarksLink string
 }
 
 func newEntanglement(obj map[string]string) entanglement {
@@ -79,6 +86,17 @@ func newEntanglement(obj map[string]string) entanglement {
 	return e
 }
 
+// newConsumerEntanglement returns the entanglement of a QuarksLink consumer
+func newConsumerEntanglement(qlink bdv1.QuarksLink) entanglement {
+	e := entanglement{deployment: qlink.Spec.Deployment, quarksLink: qlink.Name}
+	for _, l := range qlink.Spec.Consumer.Links {
+		e.links = append(e.links, link{Name: l.Name, LinkType: l.Type})
+	}
+	consumes, _ := json.Marshal(e.links)
+	e.consumes = string(consumes)
+	return e
+}
+
 func (e entanglement) find(secret corev1.Secret) (link, bool) {
 	// secret has a deployment label
 	entanglementDeployment, found := secret.Labels[bdv1.LabelDeploymentName]
@@ -92,7 +110,7 @@ func (e entanglement) find(secret corev1.Secret) (link, bool) {
 	}
 
 	for _, link := range e.links {
-		name := names.QuarksLinkSecretName(link.LinkType, link.Name)
+		name := names.QuarksLinkSecretName(e.deployment, link.LinkType, link.Name)
 		if _, ok := secret.Labels[bdv1.LabelEntanglementKey]; ok && secret.Name == name {
 			return link, true
 		}
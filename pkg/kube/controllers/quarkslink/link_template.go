@@ -0,0 +1,129 @@
+package quarkslink
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/linktemplate"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/operatorimage"
+)
+
+const (
+	// linkTemplatesVolumeName is the name of the volume with the templates config map
+	linkTemplatesVolumeName = "link-templates"
+	// linkRenderedVolumeName is the name of the volume, which is shared by
+	// the renderer and the containers of the pod
+	linkRenderedVolumeName = "link-rendered"
+	// linkRenderContainerName is the name of the init container and, with a
+	// '-watch' suffix, the side car, which render the templates
+	linkRenderContainerName = "link-render"
+)
+
+// linkRenderResources are the resources of the renderer, which only reads a few small files
+var linkRenderResources = corev1.ResourceRequirements{
+	Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("10m"),
+		corev1.ResourceMemory: resource.MustParse("16Mi"),
+	},
+	Limits: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("64Mi"),
+	},
+}
+
+// addLinkTemplate injects an init container, which renders the templates of
+// the config map with the mounted links before the pod starts. A side car
+// renders them again in place, whenever the kubelet updates the link secrets.
+// Pods, which run to completion, don't get the side car, as it would keep them
+// running. The rendered files are mounted on all containers of the pod.
+func addLinkTemplate(pod *corev1.Pod, configMapName string) {
+	// the renderer needs the same link mounts as the pod's containers
+	mounts := []corev1.VolumeMount{}
+	if len(pod.Spec.Containers) > 0 {
+		for _, mount := range pod.Spec.Containers[0].VolumeMounts {
+			if strings.HasPrefix(mount.MountPath, linktemplate.LinksDir+"/") {
+				mounts = append(mounts, mount)
+			}
+		}
+	}
+	mounts = append(mounts,
+		corev1.VolumeMount{
+			Name:      linkTemplatesVolumeName,
+			ReadOnly:  true,
+			MountPath: linktemplate.TemplatesDir,
+		},
+		corev1.VolumeMount{
+			Name:      linkRenderedVolumeName,
+			MountPath: linktemplate.RenderedDir,
+		},
+	)
+
+	if !hasVolume(pod.Spec.Volumes, linkTemplatesVolumeName) {
+		pod.Spec.Volumes = append(pod.Spec.Volumes,
+			corev1.Volume{
+				Name: linkTemplatesVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: configMapName},
+					},
+				},
+			},
+			corev1.Volume{
+				Name:         linkRenderedVolumeName,
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			},
+		)
+	}
+
+	for i, container := range pod.Spec.Containers {
+		if findVolumeMount(container.VolumeMounts, linkRenderedVolumeName) > -1 {
+			continue
+		}
+		pod.Spec.Containers[i].VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      linkRenderedVolumeName,
+			ReadOnly:  true,
+			MountPath: linktemplate.RenderedDir,
+		})
+	}
+
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, linkRenderContainer(linkRenderContainerName, mounts))
+	if isLongRunning(pod) {
+		pod.Spec.Containers = append(pod.Spec.Containers, linkRenderContainer(linkRenderContainerName+"-watch", mounts, "--watch"))
+	}
+}
+
+// isLongRunning returns false for pods of jobs and other pods, which terminate
+func isLongRunning(pod *corev1.Pod) bool {
+	if pod.Spec.RestartPolicy != "" && pod.Spec.RestartPolicy != corev1.RestartPolicyAlways {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "Job" {
+			return false
+		}
+	}
+	return true
+}
+
+func linkRenderContainer(name string, mounts []corev1.VolumeMount, args ...string) corev1.Container {
+	return corev1.Container{
+		Name:            name,
+		Image:           operatorimage.GetOperatorDockerImage(),
+		ImagePullPolicy: operatorimage.GetOperatorImagePullPolicy(),
+		Command:         []string{"/usr/bin/dumb-init", "--"},
+		Args:            append([]string{"quarks-operator", "util", "link-render"}, args...),
+		VolumeMounts:    mounts,
+		Resources:       linkRenderResources,
+	}
+}
+
+func hasVolume(volumes []corev1.Volume, name string) bool {
+	for _, v := range volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
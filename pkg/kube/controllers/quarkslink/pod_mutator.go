@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -19,6 +20,8 @@ import (
 
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/quarksrestart"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/linktemplate"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
 )
 
@@ -42,7 +45,8 @@ func NewPodMutator(log *zap.SugaredLogger, config *config.Config) admission.Hand
 }
 
 // Handle checks if the pod is an entangled pod and mounts the quarkslink secret, returns
-// the unmodified pod otherwise
+// the unmodified pod otherwise. Pods selected by QuarksLink consumers are
+// admitted with a warning, if their links can't be found.
 func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
 	pod := &corev1.Pod{}
 	err := m.decoder.Decode(req, pod)
@@ -51,20 +55,49 @@ func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 	}
 
 	updatedPod := pod.DeepCopy()
+	entanglements := []entanglement{}
+	warnings := []string{}
 	if validEntanglement(pod.GetAnnotations()) {
-		m.log.Debugf("Mutating pod '%s/%s', adding restart-on-update annotation and entanglement secrets", req.Namespace, pod.Name)
-
-		// Apply quarksrestart annotation so the link gets restarted when mounted secrets are changed
-		annotations := updatedPod.Annotations
-		if len(annotations) == 0 {
-			annotations = map[string]string{}
+		entanglements = append(entanglements, newEntanglement(pod.GetAnnotations()))
+	} else {
+		entanglements, err = m.consumerEntanglements(ctx, req.Namespace, pod)
+		if err != nil {
+			// Don't block pods on the operator, the QuarksLink status shows missing links
+			m.log.Warnf("Admitting pod '%s/%s' without quarks link consumers: %v", req.Namespace, pod.Name, err)
+			warnings = append(warnings, fmt.Sprintf("quarks links were not added: %v", err))
 		}
-		annotations[quarksrestart.AnnotationRestartOnUpdate] = "true"
-		updatedPod.Annotations = annotations
+	}
 
-		err = m.addSecrets(ctx, req.Namespace, updatedPod)
+	mounted := 0
+	for _, e := range entanglements {
+		err = m.addSecrets(ctx, req.Namespace, updatedPod, e)
 		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
+			if e.quarksLink == "" {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			m.log.Warnf("Admitting pod '%s/%s' without the links of quarks link '%s': %v", req.Namespace, pod.Name, e.quarksLink, err)
+			warnings = append(warnings, fmt.Sprintf("links of quarks link '%s' were not added: %v", e.quarksLink, err))
+			continue
+		}
+		mounted++
+	}
+
+	if mounted > 0 {
+		templates := pod.GetAnnotations()[LinkTemplateKey]
+		if templates == "" {
+			m.log.Debugf("Mutating pod '%s/%s', adding restart-on-update annotation and entanglement secrets", req.Namespace, pod.Name)
+
+			// Apply quarksrestart annotation so the link gets restarted when mounted secrets are changed
+			annotations := updatedPod.Annotations
+			if len(annotations) == 0 {
+				annotations = map[string]string{}
+			}
+			annotations[quarksrestart.AnnotationRestartOnUpdate] = "true"
+			updatedPod.Annotations = annotations
+		} else {
+			// Rendered templates are updated in place, so the pod is not restarted
+			m.log.Debugf("Mutating pod '%s/%s', adding entanglement secrets and renderer for link templates '%s'", req.Namespace, pod.Name, templates)
+			addLinkTemplate(updatedPod, templates)
 		}
 	}
 
@@ -73,11 +106,31 @@ func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod)
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod).WithWarnings(warnings...)
 }
 
-func (m *PodMutator) addSecrets(ctx context.Context, namespace string, pod *corev1.Pod) error {
-	e := newEntanglement(pod.GetAnnotations())
+// consumerEntanglements returns an entanglement for each QuarksLink consumer,
+// which selects the pod
+func (m *PodMutator) consumerEntanglements(ctx context.Context, namespace string, pod *corev1.Pod) ([]entanglement, error) {
+	entanglements := []entanglement{}
+
+	list := &bdv1.QuarksLinkList{}
+	err := m.client.List(ctx, list, client.InNamespace(namespace))
+	if err != nil {
+		return entanglements, errors.Wrapf(err, "listing quarks links in %s", namespace)
+	}
+
+	for _, qlink := range list.Items {
+		if qlink.Spec.Consumer == nil || !selectsPod(qlink, pod) {
+			continue
+		}
+		m.log.Debugf("Pod '%s/%s' is selected by quarks link consumer '%s'", namespace, pod.Name, qlink.Name)
+		entanglements = append(entanglements, newConsumerEntanglement(qlink))
+	}
+	return entanglements, nil
+}
+
+func (m *PodMutator) addSecrets(ctx context.Context, namespace string, pod *corev1.Pod, e entanglement) error {
 	links, err := m.findLinks(ctx, namespace, e)
 	if err != nil {
 		m.log.Errorf("Couldn't list entanglement secrets for '%s/%s' in %s", e.deployment, e.consumes, namespace)
@@ -90,6 +143,13 @@ func (m *PodMutator) addSecrets(ctx context.Context, namespace string, pod *core
 
 	// add missing volume sources to pod
 	for _, link := range links {
+		// label the pod as a consumer of the link, so network policies of the
+		// providing instance group allow its traffic
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[names.QuarksLinkConsumerLabel(link.String())] = e.deployment
+
 		if !hasSecretVolumeSource(pod.Spec.Volumes, link.secret.Name) {
 			volume := corev1.Volume{
 				Name: link.secret.Name,
@@ -106,7 +166,7 @@ func (m *PodMutator) addSecrets(ctx context.Context, namespace string, pod *core
 		mount := corev1.VolumeMount{
 			Name:      link.secret.Name,
 			ReadOnly:  true,
-			MountPath: filepath.Join("/quarks/link", e.deployment, link.String()),
+			MountPath: filepath.Join(linktemplate.LinksDir, e.deployment, link.String()),
 		}
 		for i, container := range pod.Spec.Containers {
 			idx := findVolumeMount(container.VolumeMounts, link.secret.Name)
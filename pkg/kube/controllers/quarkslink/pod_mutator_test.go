@@ -11,13 +11,16 @@ import (
 
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/json"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/quarkslink"
 	"code.cloudfoundry.org/quarks-operator/testing"
 	"code.cloudfoundry.org/quarks-utils/pkg/config"
@@ -45,10 +48,11 @@ var _ = Describe("Mount quarks link secret on entangled pods", func() {
 		response           admission.Response
 	)
 	annotationPatch := `{"op":"add","path":"/metadata/annotations/quarks.cloudfoundry.org~1restart-on-update","value":"true"}`
+	labelPatch := `{"op":"add","path":"/metadata/labels","value":{"link.quarks.cloudfoundry.org/nats-nats":"nats-deployment"}}`
 
-	podPatch := `{"op":"add","path":"/spec/volumes","value":[{"name":"link-nats-nats","secret":{"secretName":"link-nats-nats"}}]}`
-	containerPatch := `{"op":"add","path":"/spec/containers/0/volumeMounts","value":[{"mountPath":"/quarks/link/nats-deployment/nats-nats","name":"link-nats-nats","readOnly":true}]}`
-	secondContainerPatch := `{"op":"add","path":"/spec/containers/1/volumeMounts","value":[{"mountPath":"/quarks/link/nats-deployment/nats-nats","name":"link-nats-nats","readOnly":true}]}`
+	podPatch := `{"op":"add","path":"/spec/volumes","value":[{"name":"link-nats-deployment-nats-nats","secret":{"secretName":"link-nats-deployment-nats-nats"}}]}`
+	containerPatch := `{"op":"add","path":"/spec/containers/0/volumeMounts","value":[{"mountPath":"/quarks/link/nats-deployment/nats-nats","name":"link-nats-deployment-nats-nats","readOnly":true}]}`
+	secondContainerPatch := `{"op":"add","path":"/spec/containers/1/volumeMounts","value":[{"mountPath":"/quarks/link/nats-deployment/nats-nats","name":"link-nats-deployment-nats-nats","readOnly":true}]}`
 
 	jsonPatches := func(operations []jsonpatch.Operation) []string {
 		patches := make([]string, len(operations))
@@ -76,6 +80,8 @@ var _ = Describe("Mount quarks link secret on entangled pods", func() {
 		scheme := runtime.NewScheme()
 		Expect(corev1.AddToScheme(scheme)).To(Succeed())
 
+		Expect(bdv1.AddToScheme(kubescheme.Scheme)).To(Succeed())
+
 		decoder, _ = admission.NewDecoder(scheme)
 		_ = mutator.(admission.DecoderInjector).InjectDecoder(decoder)
 
@@ -102,6 +108,89 @@ var _ = Describe("Mount quarks link secret on entangled pods", func() {
 		})
 	})
 
+	Context("when a quarks link consumer selects the pod", func() {
+		var qlink *bdv1.QuarksLink
+
+		BeforeEach(func() {
+			pod = env.DefaultPod("test-pod")
+			pod.Labels = map[string]string{"app": "nats-client"}
+			request = newAdmissionRequest(pod)
+			qlink = &bdv1.QuarksLink{
+				ObjectMeta: metav1.ObjectMeta{Name: "nats-client"},
+				Spec: bdv1.QuarksLinkSpec{
+					Deployment: deploymentName,
+					Consumer: &bdv1.QuarksLinkConsumer{
+						Selector: map[string]string{"app": "nats-client"},
+						Links:    []bdv1.LinkReference{{Name: "nats", Type: "nats"}},
+					},
+				},
+			}
+		})
+
+		Context("when the link secret exists", func() {
+			BeforeEach(func() {
+				client = fake.NewClientBuilder().
+					WithObjects(&entanglementSecret, qlink).
+					Build()
+			})
+
+			It("mounts the link secret like for an entanglement", func() {
+				Expect(response.Allowed).To(BeTrue(), response.Result)
+
+				patches := jsonPatches(response.Patches)
+				Expect(patches).To(ContainElement(`{"op":"add","path":"/metadata/annotations","value":{"quarks.cloudfoundry.org/restart-on-update":"true"}}`))
+				Expect(patches).To(ContainElement(podPatch))
+				Expect(patches).To(ContainElement(containerPatch))
+				Expect(patches).To(ContainElement(`{"op":"add","path":"/metadata/labels/link.quarks.cloudfoundry.org~1nats-nats","value":"nats-deployment"}`))
+			})
+		})
+
+		Context("when the quarks link does not select the pod", func() {
+			BeforeEach(func() {
+				qlink.Spec.Consumer.Selector = map[string]string{"app": "other"}
+				client = fake.NewClientBuilder().
+					WithObjects(&entanglementSecret, qlink).
+					Build()
+			})
+
+			It("does not apply changes", func() {
+				Expect(response.AdmissionResponse.Allowed).To(BeTrue())
+				Expect(response.Patches).To(BeEmpty())
+			})
+		})
+
+		Context("when the link secret doesn't exist", func() {
+			BeforeEach(func() {
+				client = fake.NewClientBuilder().
+					WithObjects(qlink).
+					Build()
+			})
+
+			It("admits the pod without the links and warns", func() {
+				Expect(response.AdmissionResponse.Allowed).To(BeTrue())
+				Expect(response.Patches).To(BeEmpty())
+				Expect(response.Warnings).To(ConsistOf(ContainSubstring("links of quarks link 'nats-client' were not added")))
+			})
+		})
+
+		Context("when the quarks links can't be listed", func() {
+			BeforeEach(func() {
+				scheme := runtime.NewScheme()
+				Expect(corev1.AddToScheme(scheme)).To(Succeed())
+				client = fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(&entanglementSecret).
+					Build()
+			})
+
+			It("admits the pod without the links and warns", func() {
+				Expect(response.AdmissionResponse.Allowed).To(BeTrue())
+				Expect(response.Patches).To(BeEmpty())
+				Expect(response.Warnings).To(ConsistOf(ContainSubstring("quarks links were not added")))
+			})
+		})
+	})
+
 	Context("when valid bosh entanglement exists on pod", func() {
 		BeforeEach(func() {
 			pod = env.AnnotatedPod("entangled-pod", map[string]string{
@@ -125,7 +214,7 @@ var _ = Describe("Mount quarks link secret on entangled pods", func() {
 			It("secret is mounted on all containers", func() {
 				Expect(response.Allowed).To(BeTrue(), response.Result)
 
-				Expect(response.Patches).To(HaveLen(6))
+				Expect(response.Patches).To(HaveLen(7))
 				patches := jsonPatches(response.Patches)
 				Expect(patches).To(ContainElement(podPatch))
 				Expect(patches).To(ContainElement(containerPatch))
@@ -145,12 +234,19 @@ var _ = Describe("Mount quarks link secret on entangled pods", func() {
 			It("adds a quarks restart annotation", func() {
 				Expect(response.Allowed).To(BeTrue(), response.Result)
 
-				Expect(response.Patches).To(HaveLen(6))
+				Expect(response.Patches).To(HaveLen(7))
 				patches := jsonPatches(response.Patches)
 				Expect(patches).To(ContainElement(annotationPatch))
 
 				Expect(response.AdmissionResponse.Allowed).To(BeTrue())
 			})
+
+			It("labels the pod as a consumer of the link", func() {
+				Expect(response.Allowed).To(BeTrue(), response.Result)
+
+				patches := jsonPatches(response.Patches)
+				Expect(patches).To(ContainElement(labelPatch))
+			})
 		})
 
 		Context("when quarks link secret doesn't exist", func() {
@@ -166,6 +262,77 @@ var _ = Describe("Mount quarks link secret on entangled pods", func() {
 		})
 	})
 
+	Context("when the pod has a link template annotation", func() {
+		patchPaths := func(operations []jsonpatch.Operation) []string {
+			paths := make([]string, len(operations))
+			for i, patch := range operations {
+				paths[i] = patch.Path
+			}
+			return paths
+		}
+
+		BeforeEach(func() {
+			pod = env.AnnotatedPod("entangled-pod", map[string]string{
+				quarkslink.DeploymentKey:   deploymentName,
+				quarkslink.ConsumesKey:     consumesNats,
+				quarkslink.LinkTemplateKey: "nats-config",
+			})
+			request = newAdmissionRequest(pod)
+			client = fake.NewClientBuilder().
+				WithObjects(&entanglementSecret).
+				Build()
+		})
+
+		It("adds an init container and a side car rendering the templates", func() {
+			Expect(response.Allowed).To(BeTrue(), response.Result)
+
+			Expect(patchPaths(response.Patches)).To(ContainElements("/spec/initContainers", "/spec/containers/1"))
+			for _, patch := range response.Patches {
+				switch patch.Path {
+				case "/spec/initContainers":
+					initContainer := patch.Value.([]interface{})[0].(map[string]interface{})
+					Expect(initContainer["name"]).To(Equal("link-render"))
+					Expect(initContainer["args"]).To(Equal([]interface{}{"quarks-operator", "util", "link-render"}))
+					Expect(initContainer["volumeMounts"]).To(ContainElement(HaveKeyWithValue("mountPath", "/quarks/link/nats-deployment/nats-nats")))
+				case "/spec/containers/1":
+					sidecar := patch.Value.(map[string]interface{})
+					Expect(sidecar["name"]).To(Equal("link-render-watch"))
+					Expect(sidecar["args"]).To(Equal([]interface{}{"quarks-operator", "util", "link-render", "--watch"}))
+					Expect(sidecar["resources"]).To(HaveKeyWithValue("limits", HaveKeyWithValue("memory", "64Mi")))
+				}
+			}
+		})
+
+		It("mounts the templates and the rendered files", func() {
+			Expect(response.Allowed).To(BeTrue(), response.Result)
+
+			patches := jsonPatches(response.Patches)
+			Expect(patches).To(ContainElement(`{"op":"add","path":"/spec/volumes","value":[{"name":"link-nats-deployment-nats-nats","secret":{"secretName":"link-nats-deployment-nats-nats"}},{"configMap":{"name":"nats-config"},"name":"link-templates"},{"emptyDir":{},"name":"link-rendered"}]}`))
+			Expect(patches).To(ContainElement(`{"op":"add","path":"/spec/containers/0/volumeMounts","value":[{"mountPath":"/quarks/link/nats-deployment/nats-nats","name":"link-nats-deployment-nats-nats","readOnly":true},{"mountPath":"/quarks/link-rendered","name":"link-rendered","readOnly":true}]}`))
+		})
+
+		It("does not restart the pod, when the link changes", func() {
+			Expect(response.Allowed).To(BeTrue(), response.Result)
+			Expect(jsonPatches(response.Patches)).ToNot(ContainElement(annotationPatch))
+		})
+
+		Context("when the pod runs to completion", func() {
+			BeforeEach(func() {
+				pod.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
+				pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "job", UID: "1234"}}
+				request = newAdmissionRequest(pod)
+			})
+
+			It("only adds the init container", func() {
+				Expect(response.Allowed).To(BeTrue(), response.Result)
+
+				paths := patchPaths(response.Patches)
+				Expect(paths).To(ContainElement("/spec/initContainers"))
+				Expect(paths).ToNot(ContainElement("/spec/containers/1"))
+			})
+		})
+	})
+
 	Context("when invalid bosh entanglement exists on pod", func() {
 		BeforeEach(func() {
 			pod = env.AnnotatedPod("entangled-pod", map[string]string{
@@ -185,9 +352,10 @@ var _ = Describe("Mount quarks link secret on entangled pods", func() {
 	})
 
 	Context("when pod has existing volumes", func() {
-		podPatch := `{"op":"add","path":"/spec/volumes/1","value":{"name":"link-nats-nats","secret":{"secretName":"link-nats-nats"}}}`
-		containerPatch := `{"op":"add","path":"/spec/containers/0/volumeMounts/1","value":{"mountPath":"/quarks/link/nats-deployment/nats-nats","name":"link-nats-nats","readOnly":true}}`
-		envVarsPatch := `{"op":"add","path":"/spec/containers/0/env","value":[{"name":"LINK_NATS_PASSWORD","valueFrom":{"secretKeyRef":{"key":"nats.password","name":"link-nats-nats"}}},{"name":"LINK_NATS_PORT","valueFrom":{"secretKeyRef":{"key":"nats.port","name":"link-nats-nats"}}},{"name":"LINK_NATS_USER","valueFrom":{"secretKeyRef":{"key":"nats.user","name":"link-nats-nats"}}}]}`
+		podPatch := `{"op":"add","path":"/spec/volumes/1","value":{"name":"link-nats-deployment-nats-nats","secret":{"secretName":"link-nats-deployment-nats-nats"}}}`
+		containerPatch := `{"op":"add","path":"/spec/containers/0/volumeMounts/1","value":{"mountPath":"/quarks/link/nats-deployment/nats-nats","name":"link-nats-deployment-nats-nats","readOnly":true}}`
+		labelPatch := `{"op":"add","path":"/metadata/labels/link.quarks.cloudfoundry.org~1nats-nats","value":"nats-deployment"}`
+		envVarsPatch := `{"op":"add","path":"/spec/containers/0/env","value":[{"name":"LINK_NATS_PASSWORD","valueFrom":{"secretKeyRef":{"key":"nats.password","name":"link-nats-deployment-nats-nats"}}},{"name":"LINK_NATS_PORT","valueFrom":{"secretKeyRef":{"key":"nats.port","name":"link-nats-deployment-nats-nats"}}},{"name":"LINK_NATS_USER","valueFrom":{"secretKeyRef":{"key":"nats.user","name":"link-nats-deployment-nats-nats"}}}]}`
 
 		BeforeEach(func() {
 			pod = env.NatsPod("entangled-pod")
@@ -207,8 +375,9 @@ var _ = Describe("Mount quarks link secret on entangled pods", func() {
 
 			It("does add the link volume and mounts it on all containers", func() {
 				Expect(response.Allowed).To(BeTrue(), response.Result)
-				Expect(response.Patches).To(HaveLen(4))
+				Expect(response.Patches).To(HaveLen(5))
 				patches := jsonPatches(response.Patches)
+				Expect(patches).To(ContainElement(labelPatch))
 				Expect(patches).To(ContainElement(podPatch))
 				Expect(patches).To(ContainElement(containerPatch))
 				Expect(patches).To(ContainElement(envVarsPatch))
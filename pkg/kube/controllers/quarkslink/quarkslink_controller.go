@@ -0,0 +1,196 @@
+package quarkslink
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	"code.cloudfoundry.org/quarks-utils/pkg/monitorednamespace"
+)
+
+// AddQuarksLink creates a new QuarksLink controller, which resolves the links
+// of native providers and consumers declared by QuarksLinks
+func AddQuarksLink(ctx context.Context, config *config.Config, mgr manager.Manager) error {
+	ctx = ctxlog.NewContextWithRecorder(ctx, "quarkslink-reconciler", mgr.GetEventRecorderFor("quarkslink-recorder"))
+	r := NewQuarksLinkReconciler(ctx, config, mgr, controllerutil.SetControllerReference)
+
+	c, err := controller.New("quarkslink-controller", mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: config.MaxBoshDeploymentWorkers,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Adding quarks link controller to manager failed.")
+	}
+
+	nsPred := monitorednamespace.NewNSPredicate(ctx, mgr.GetClient(), config.MonitoredID)
+
+	// Watch for changes to primary resource QuarksLink
+	p := predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return true },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			o := e.ObjectOld.(*bdv1.QuarksLink)
+			n := e.ObjectNew.(*bdv1.QuarksLink)
+			if reflect.DeepEqual(o.Spec, n.Spec) {
+				return false
+			}
+
+			ctxlog.NewPredicateEvent(e.ObjectNew).Debug(
+				ctx, e.ObjectNew, "bdv1.QuarksLink",
+				fmt.Sprintf("Update predicate passed for '%s/%s'", e.ObjectNew.GetNamespace(), e.ObjectNew.GetName()),
+			)
+			return true
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &bdv1.QuarksLink{}}, &handler.EnqueueRequestForObject{}, nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching quarks links failed in quarks link controller.")
+	}
+
+	// Watch the secrets of providers and the link secrets wanted by consumers
+	p = predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return true },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return true },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSecret := e.ObjectOld.(*corev1.Secret)
+			newSecret := e.ObjectNew.(*corev1.Secret)
+
+			return !reflect.DeepEqual(oldSecret.Data, newSecret.Data)
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(
+		func(a client.Object) []reconcile.Request {
+			reconciles, err := reconcilesForSecret(ctx, mgr.GetClient(), a.(*corev1.Secret))
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate quarks links for secret '%s/%s': %v", a.GetNamespace(), a.GetName(), err)
+			}
+
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a).Debug(ctx, reconciliation, "QuarksLink", a.GetName(), "secret")
+			}
+
+			return reconciles
+		}), nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching secrets failed in quarks link controller.")
+	}
+
+	// Watch pods, the instances of providers and consumers are listed in the status
+	p = predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return true },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return true },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod := e.ObjectOld.(*corev1.Pod)
+			newPod := e.ObjectNew.(*corev1.Pod)
+
+			return oldPod.Status.PodIP != newPod.Status.PodIP || !reflect.DeepEqual(oldPod.Labels, newPod.Labels)
+		},
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(
+		func(a client.Object) []reconcile.Request {
+			reconciles, err := reconcilesForPod(ctx, mgr.GetClient(), a.(*corev1.Pod))
+			if err != nil {
+				ctxlog.Errorf(ctx, "Failed to calculate quarks links for pod '%s/%s': %v", a.GetNamespace(), a.GetName(), err)
+			}
+
+			for _, reconciliation := range reconciles {
+				ctxlog.NewMappingEvent(a).Debug(ctx, reconciliation, "QuarksLink", a.GetName(), "pod")
+			}
+
+			return reconciles
+		}), nsPred, p)
+	if err != nil {
+		return errors.Wrapf(err, "Watching pods failed in quarks link controller.")
+	}
+
+	return nil
+}
+
+// reconcilesForSecret returns the QuarksLinks, which provide the secret's
+// properties or consume the link of the secret
+func reconcilesForSecret(ctx context.Context, c client.Client, secret *corev1.Secret) ([]reconcile.Request, error) {
+	reconciles := []reconcile.Request{}
+
+	list := &bdv1.QuarksLinkList{}
+	if err := c.List(ctx, list, client.InNamespace(secret.Namespace)); err != nil {
+		return reconciles, err
+	}
+
+	for _, qlink := range list.Items {
+		if referencesSecret(qlink, secret.Name) {
+			reconciles = append(reconciles, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: qlink.Namespace, Name: qlink.Name},
+			})
+		}
+	}
+	return reconciles, nil
+}
+
+// reconcilesForPod returns the QuarksLinks, which select the pod
+func reconcilesForPod(ctx context.Context, c client.Client, pod *corev1.Pod) ([]reconcile.Request, error) {
+	reconciles := []reconcile.Request{}
+
+	list := &bdv1.QuarksLinkList{}
+	if err := c.List(ctx, list, client.InNamespace(pod.Namespace)); err != nil {
+		return reconciles, err
+	}
+
+	for _, qlink := range list.Items {
+		if selectsPod(qlink, pod) {
+			reconciles = append(reconciles, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: qlink.Namespace, Name: qlink.Name},
+			})
+		}
+	}
+	return reconciles, nil
+}
+
+func referencesSecret(qlink bdv1.QuarksLink, name string) bool {
+	if qlink.Spec.Provider != nil {
+		return qlink.Spec.Provider.Secret == name
+	}
+	if qlink.Spec.Consumer != nil {
+		for _, l := range qlink.Spec.Consumer.Links {
+			if names.QuarksLinkSecretName(qlink.Spec.Deployment, l.Type, l.Name) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func selectsPod(qlink bdv1.QuarksLink, pod *corev1.Pod) bool {
+	var selector map[string]string
+	switch {
+	case qlink.Spec.Provider != nil:
+		selector = qlink.Spec.Provider.Selector
+	case qlink.Spec.Consumer != nil:
+		selector = qlink.Spec.Consumer.Selector
+	}
+	if len(selector) == 0 {
+		return false
+	}
+	return labels.SelectorFromSet(selector).Matches(labels.Set(pod.Labels))
+}
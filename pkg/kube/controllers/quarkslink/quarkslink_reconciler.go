@@ -0,0 +1,285 @@
+package quarkslink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/mutate"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	mutateqs "code.cloudfoundry.org/quarks-secret/pkg/kube/util/mutate"
+	"code.cloudfoundry.org/quarks-utils/pkg/config"
+	log "code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+)
+
+var _ reconcile.Reconciler = &ReconcileQuarksLink{}
+
+type setReferenceFunc func(owner, object metav1.Object, scheme *runtime.Scheme) error
+
+// NewQuarksLinkReconciler returns a new reconcile.Reconciler for QuarksLinks
+func NewQuarksLinkReconciler(ctx context.Context, config *config.Config, mgr manager.Manager, srf setReferenceFunc) reconcile.Reconciler {
+	return &ReconcileQuarksLink{
+		ctx:          ctx,
+		config:       config,
+		client:       mgr.GetClient(),
+		scheme:       mgr.GetScheme(),
+		setReference: srf,
+	}
+}
+
+// ReconcileQuarksLink resolves the link of a QuarksLink
+type ReconcileQuarksLink struct {
+	ctx          context.Context
+	config       *config.Config
+	client       client.Client
+	scheme       *runtime.Scheme
+	setReference setReferenceFunc
+}
+
+// Reconcile creates the link secret and service for a native link provider,
+// so the BOSHDeployment can consume the link. For native consumers it checks
+// the link secrets of the BOSHDeployment exist, the pod mutator mounts them.
+// Both record the resolved link in the status of the QuarksLink.
+func (r *ReconcileQuarksLink) Reconcile(_ context.Context, request reconcile.Request) (reconcile.Result, error) {
+	// Set the ctx to be Background, as the top-level context for incoming requests.
+	ctx, cancel := context.WithTimeout(r.ctx, r.config.CtxTimeOut)
+	defer cancel()
+
+	log.Infof(ctx, "Reconciling QuarksLink '%s'", request.NamespacedName)
+	qlink := &bdv1.QuarksLink{}
+	err := r.client.Get(ctx, request.NamespacedName, qlink)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Return and don't requeue
+			log.Debug(ctx, "Skip reconcile: QuarksLink not found")
+			return reconcile.Result{}, nil
+		}
+
+		return reconcile.Result{},
+			log.WithEvent(qlink, "GetQuarksLinkError").Errorf(ctx, "failed to get QuarksLink '%s': %v", request.NamespacedName, err)
+	}
+
+	var status bdv1.QuarksLinkStatus
+	switch {
+	case qlink.Spec.Provider != nil:
+		status, err = r.reconcileProvider(ctx, qlink)
+	case qlink.Spec.Consumer != nil:
+		status, err = r.reconcileConsumer(ctx, qlink)
+	default:
+		status = bdv1.QuarksLinkStatus{Message: "neither provider nor consumer is set"}
+	}
+	if err != nil {
+		return reconcile.Result{},
+			log.WithEvent(qlink, "QuarksLinkError").Errorf(ctx, "failed to resolve QuarksLink '%s': %v", request.NamespacedName, err)
+	}
+
+	status.ObservedGeneration = qlink.Generation
+	if !reflect.DeepEqual(status, qlink.Status) {
+		if !status.Resolved {
+			log.WithEvent(qlink, "Unresolved").Infof(ctx, "QuarksLink '%s' is not resolved: %s", request.NamespacedName, status.Message)
+		}
+
+		qlink.Status = status
+		err = r.client.Status().Update(ctx, qlink)
+		if err != nil {
+			return reconcile.Result{},
+				log.WithEvent(qlink, "UpdateError").Errorf(ctx, "failed to update status of QuarksLink '%s' (%v): %s", request.NamespacedName, qlink.ResourceVersion, err)
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// reconcileProvider copies the properties into a link secret of the
+// deployment and creates a service for the selected pods, the annotations of
+// both are used by the BOSHDeployment to look up the native link
+func (r *ReconcileQuarksLink) reconcileProvider(ctx context.Context, qlink *bdv1.QuarksLink) (bdv1.QuarksLinkStatus, error) {
+	provider := qlink.Spec.Provider
+	status := bdv1.QuarksLinkStatus{}
+
+	source := &corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: qlink.Namespace, Name: provider.Secret}, source)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			status.Message = fmt.Sprintf("secret '%s' not found", provider.Secret)
+			return status, nil
+		}
+		return status, errors.Wrapf(err, "failed to get secret '%s/%s'", qlink.Namespace, provider.Secret)
+	}
+
+	properties := map[string]interface{}{}
+	if err := yaml.Unmarshal(source.Data[bdm.LinkFile], &properties); err != nil {
+		status.Message = fmt.Sprintf("secret '%s' has invalid link properties: %v", provider.Secret, err)
+		return status, nil
+	}
+	if len(properties) == 0 {
+		status.Message = fmt.Sprintf("secret '%s' has no link properties in key '%s'", provider.Secret, bdm.LinkFile)
+		return status, nil
+	}
+	status.PropertyKeys = sortedKeys(properties)
+
+	provides, err := json.Marshal(link{Name: provider.Name, LinkType: provider.Type})
+	if err != nil {
+		return status, errors.Wrapf(err, "failed to marshal link '%s'", provider.Name)
+	}
+
+	stringData := map[string]string{}
+	for key, value := range source.Data {
+		stringData[key] = string(value)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ProviderSecretName(qlink),
+			Namespace: qlink.Namespace,
+			Labels: map[string]string{
+				bdv1.LabelDeploymentName: qlink.Spec.Deployment,
+			},
+			Annotations: map[string]string{
+				bdv1.AnnotationLinkProvidesKey: string(provides),
+			},
+		},
+		StringData: stringData,
+	}
+	if err := r.setReference(qlink, secret, r.scheme); err != nil {
+		return status, errors.Wrapf(err, "could not set reference for secret '%s/%s'", secret.Namespace, secret.Name)
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, secret, mutateqs.SecretMutateFn(secret))
+	if err != nil {
+		return status, errors.Wrapf(err, "creating or updating secret '%s/%s'", secret.Namespace, secret.Name)
+	}
+	log.Debugf(ctx, "Link secret '%s/%s' has been %s", secret.Namespace, secret.Name, op)
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ProviderServiceName(qlink),
+			Namespace: qlink.Namespace,
+			Labels: map[string]string{
+				bdv1.LabelDeploymentName: qlink.Spec.Deployment,
+			},
+			Annotations: map[string]string{
+				bdv1.AnnotationLinkProviderName: provider.Name,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  provider.Selector,
+		},
+	}
+	if err := r.setReference(qlink, svc, r.scheme); err != nil {
+		return status, errors.Wrapf(err, "could not set reference for service '%s/%s'", svc.Namespace, svc.Name)
+	}
+	op, err = controllerutil.CreateOrUpdate(ctx, r.client, svc, mutate.ServiceMutateFn(svc))
+	if err != nil {
+		return status, errors.Wrapf(err, "creating or updating service '%s/%s'", svc.Namespace, svc.Name)
+	}
+	log.Debugf(ctx, "Link service '%s/%s' has been %s", svc.Namespace, svc.Name, op)
+	status.Address = fmt.Sprintf("%s.%s.svc.%s", svc.Name, qlink.Namespace, boshdns.GetClusterDomain())
+
+	pods, err := r.listPods(ctx, qlink.Namespace, provider.Selector)
+	if err != nil {
+		return status, err
+	}
+	for _, pod := range pods {
+		status.Instances = append(status.Instances, pod.Name)
+		if pod.Status.PodIP != "" {
+			status.Addresses = append(status.Addresses, pod.Status.PodIP)
+		}
+	}
+
+	status.Resolved = true
+	return status, nil
+}
+
+// reconcileConsumer looks up the link secrets of the deployment, which are
+// mounted on the selected pods
+func (r *ReconcileQuarksLink) reconcileConsumer(ctx context.Context, qlink *bdv1.QuarksLink) (bdv1.QuarksLinkStatus, error) {
+	consumer := qlink.Spec.Consumer
+	status := bdv1.QuarksLinkStatus{}
+
+	pods, err := r.listPods(ctx, qlink.Namespace, consumer.Selector)
+	if err != nil {
+		return status, err
+	}
+	for _, pod := range pods {
+		status.Instances = append(status.Instances, pod.Name)
+	}
+
+	missing := []string{}
+	keys := map[string]interface{}{}
+	for _, l := range consumer.Links {
+		name := names.QuarksLinkSecretName(qlink.Spec.Deployment, l.Type, l.Name)
+		secret := &corev1.Secret{}
+		err := r.client.Get(ctx, types.NamespacedName{Namespace: qlink.Namespace, Name: name}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				missing = append(missing, names.QuarksLinkSecretKey(l.Type, l.Name))
+				continue
+			}
+			return status, errors.Wrapf(err, "failed to get link secret '%s/%s'", qlink.Namespace, name)
+		}
+
+		for key := range secret.Data {
+			keys[key] = nil
+		}
+	}
+	status.PropertyKeys = sortedKeys(keys)
+
+	if len(missing) > 0 {
+		status.Message = fmt.Sprintf("deployment '%s' does not provide links: %s", qlink.Spec.Deployment, strings.Join(missing, ", "))
+		return status, nil
+	}
+
+	status.Resolved = true
+	return status, nil
+}
+
+func (r *ReconcileQuarksLink) listPods(ctx context.Context, namespace string, selector map[string]string) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	err := r.client.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels(selector))
+	if err != nil {
+		return pods.Items, errors.Wrapf(err, "listing pods from selector '%+v'", selector)
+	}
+
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	return pods.Items, nil
+}
+
+// ProviderSecretName returns the name of the link secret created for a QuarksLink provider
+// `link-<deployment-name>-provides-<link-name>`
+func ProviderSecretName(qlink *bdv1.QuarksLink) string {
+	return names.QuarksLinkSecretName(qlink.Spec.Deployment, "provides", qlink.Spec.Provider.Name)
+}
+
+// ProviderServiceName returns the name of the service created for a QuarksLink provider
+// `<deployment-name>-<link-name>-link`
+func ProviderServiceName(qlink *bdv1.QuarksLink) string {
+	return names.ServiceName(qlink.Spec.Deployment, qlink.Spec.Provider.Name+"-link")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
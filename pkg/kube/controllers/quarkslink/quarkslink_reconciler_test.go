@@ -0,0 +1,179 @@
+package quarkslink_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/quarkslink"
+	"code.cloudfoundry.org/quarks-operator/testing"
+	cfcfg "code.cloudfoundry.org/quarks-utils/pkg/config"
+	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("ReconcileQuarksLink", func() {
+	var (
+		ctx     context.Context
+		env     testing.Catalog
+		manager *fakes.FakeManager
+		client  crc.Client
+		qlink   *bdv1.QuarksLink
+		objects []crc.Object
+		request reconcile.Request
+	)
+
+	BeforeEach(func() {
+		Expect(bdv1.AddToScheme(scheme.Scheme)).To(Succeed())
+		manager = &fakes.FakeManager{}
+		manager.GetSchemeReturns(scheme.Scheme)
+
+		_, log := helper.NewTestLogger()
+		ctx = ctxlog.NewParentContext(log)
+		ctx = ctxlog.NewContextWithRecorder(ctx, "TestRecorder", record.NewFakeRecorder(20))
+		request = reconcile.Request{NamespacedName: types.NamespacedName{Name: "gora", Namespace: "default"}}
+
+		objects = []crc.Object{
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "gora-0", Namespace: "default", Labels: map[string]string{"app": "gora"}},
+				Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-0", Namespace: "default", Labels: map[string]string{"app": "other"}},
+				Status:     corev1.PodStatus{PodIP: "10.0.0.2"},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(append(objects, qlink)...).Build()
+		manager.GetClientReturns(client)
+	})
+
+	reconcileQuarksLink := func() *bdv1.QuarksLink {
+		reconciler := quarkslink.NewQuarksLinkReconciler(ctx, &cfcfg.Config{CtxTimeOut: 10 * time.Second}, manager, controllerutil.SetControllerReference)
+		_, err := reconciler.Reconcile(context.Background(), request)
+		Expect(err).ToNot(HaveOccurred())
+
+		result := &bdv1.QuarksLink{}
+		Expect(client.Get(context.Background(), request.NamespacedName, result)).To(Succeed())
+		return result
+	}
+
+	Context("when the quarks link is a provider", func() {
+		BeforeEach(func() {
+			qlink = &bdv1.QuarksLink{
+				ObjectMeta: metav1.ObjectMeta{Name: "gora", Namespace: "default", Generation: 2},
+				Spec: bdv1.QuarksLinkSpec{
+					Deployment: "cf",
+					Provider: &bdv1.QuarksLinkProvider{
+						Name:     "gora",
+						Type:     "quarks-gora",
+						Secret:   "gora-properties",
+						Selector: map[string]string{"app": "gora"},
+					},
+				},
+			}
+		})
+
+		Context("when the secret exists", func() {
+			BeforeEach(func() {
+				objects = append(objects, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "gora-properties", Namespace: "default"},
+					Data:       map[string][]byte{"link": []byte("quarks-gora.port: \"1234\"\nquarks-gora.ssl: false\n")},
+				})
+			})
+
+			It("creates the link secret for the deployment", func() {
+				reconcileQuarksLink()
+
+				secret := &corev1.Secret{}
+				Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "link-cf-provides-gora"}, secret)).To(Succeed())
+				Expect(secret.Labels).To(HaveKeyWithValue(bdv1.LabelDeploymentName, "cf"))
+				Expect(secret.Annotations).To(HaveKeyWithValue(bdv1.AnnotationLinkProvidesKey, `{"name":"gora","type":"quarks-gora"}`))
+				Expect(secret.StringData).To(HaveKeyWithValue("link", "quarks-gora.port: \"1234\"\nquarks-gora.ssl: false\n"))
+				Expect(secret.OwnerReferences).To(HaveLen(1))
+			})
+
+			It("creates a service selecting the instances", func() {
+				reconcileQuarksLink()
+
+				svc := &corev1.Service{}
+				Expect(client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "cf-gora-link"}, svc)).To(Succeed())
+				Expect(svc.Annotations).To(HaveKeyWithValue(bdv1.AnnotationLinkProviderName, "gora"))
+				Expect(svc.Spec.Selector).To(Equal(map[string]string{"app": "gora"}))
+				Expect(svc.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+			})
+
+			It("resolves the link in the status", func() {
+				status := reconcileQuarksLink().Status
+				Expect(status.Resolved).To(BeTrue())
+				Expect(status.Address).To(HavePrefix("cf-gora-link.default.svc."))
+				Expect(status.Instances).To(Equal([]string{"gora-0"}))
+				Expect(status.Addresses).To(Equal([]string{"10.0.0.1"}))
+				Expect(status.PropertyKeys).To(Equal([]string{"quarks-gora.port", "quarks-gora.ssl"}))
+				Expect(status.ObservedGeneration).To(Equal(int64(2)))
+			})
+		})
+
+		Context("when the secret is missing", func() {
+			It("does not resolve the link", func() {
+				status := reconcileQuarksLink().Status
+				Expect(status.Resolved).To(BeFalse())
+				Expect(status.Message).To(Equal("secret 'gora-properties' not found"))
+			})
+		})
+	})
+
+	Context("when the quarks link is a consumer", func() {
+		BeforeEach(func() {
+			qlink = &bdv1.QuarksLink{
+				ObjectMeta: metav1.ObjectMeta{Name: "gora", Namespace: "default"},
+				Spec: bdv1.QuarksLinkSpec{
+					Deployment: "nats-deployment",
+					Consumer: &bdv1.QuarksLinkConsumer{
+						Selector: map[string]string{"app": "gora"},
+						Links:    []bdv1.LinkReference{{Name: "nats", Type: "nats"}},
+					},
+				},
+			}
+		})
+
+		Context("when the deployment provides the link", func() {
+			BeforeEach(func() {
+				secret := env.DefaultQuarksLinkSecret("nats-deployment", "nats")
+				secret.Namespace = "default"
+				objects = append(objects, &secret)
+			})
+
+			It("resolves the link in the status", func() {
+				status := reconcileQuarksLink().Status
+				Expect(status.Resolved).To(BeTrue())
+				Expect(status.Instances).To(Equal([]string{"gora-0"}))
+				Expect(status.PropertyKeys).To(Equal([]string{"nats.password", "nats.port", "nats.user"}))
+			})
+		})
+
+		Context("when the deployment does not provide the link", func() {
+			It("does not resolve the link", func() {
+				status := reconcileQuarksLink().Status
+				Expect(status.Resolved).To(BeFalse())
+				Expect(status.Message).To(Equal("deployment 'nats-deployment' does not provide links: nats-nats"))
+			})
+		})
+	})
+})
@@ -51,7 +51,7 @@ func NewManager(ctx context.Context, config *config.Config, cfg *rest.Config, op
 	return mgr, nil
 }
 
-// ApplyCRDs applies a bdpl CRD into the cluster
+// ApplyCRDs applies the bdpl and bosh cloud config CRDs into the cluster
 func ApplyCRDs(ctx context.Context, config *rest.Config) error {
 	client, err := extv1client.NewForConfig(config)
 	if err != nil {
@@ -80,5 +80,72 @@ func ApplyCRDs(ctx context.Context, config *rest.Config) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to wait for CRD '%s' ready", bdv1.BOSHDeploymentResourceName)
 	}
+
+	// Add bosh cloud config crd
+	b = crd.New(
+		bdv1.BOSHCloudConfigResourceName,
+		extv1.CustomResourceDefinitionNames{
+			Kind:       bdv1.BOSHCloudConfigResourceKind,
+			Plural:     bdv1.BOSHCloudConfigResourcePlural,
+			ShortNames: bdv1.BOSHCloudConfigResourceShortNames,
+		},
+		bdv1.SchemeGroupVersion,
+	)
+
+	err = b.WithValidation(&bdv1.BOSHCloudConfigValidation).
+		Build().
+		Apply(ctx, client)
+	if err != nil {
+		return errors.Wrapf(err, "failed to apply CRD '%s'", bdv1.BOSHCloudConfigResourceName)
+	}
+	err = crd.WaitForCRDReady(ctx, client, bdv1.BOSHCloudConfigResourceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to wait for CRD '%s' ready", bdv1.BOSHCloudConfigResourceName)
+	}
+
+	// Add bosh runtime config crd, runtime configs apply to the deployments of all namespaces
+	b = crd.New(
+		bdv1.BOSHRuntimeConfigResourceName,
+		extv1.CustomResourceDefinitionNames{
+			Kind:       bdv1.BOSHRuntimeConfigResourceKind,
+			Plural:     bdv1.BOSHRuntimeConfigResourcePlural,
+			ShortNames: bdv1.BOSHRuntimeConfigResourceShortNames,
+		},
+		bdv1.SchemeGroupVersion,
+	)
+
+	b = b.WithValidation(&bdv1.BOSHRuntimeConfigValidation).Build()
+	b.CRD.Spec.Scope = extv1.ClusterScoped
+	err = b.Apply(ctx, client)
+	if err != nil {
+		return errors.Wrapf(err, "failed to apply CRD '%s'", bdv1.BOSHRuntimeConfigResourceName)
+	}
+	err = crd.WaitForCRDReady(ctx, client, bdv1.BOSHRuntimeConfigResourceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to wait for CRD '%s' ready", bdv1.BOSHRuntimeConfigResourceName)
+	}
+
+	// Add quarks link crd
+	b = crd.New(
+		bdv1.QuarksLinkResourceName,
+		extv1.CustomResourceDefinitionNames{
+			Kind:       bdv1.QuarksLinkResourceKind,
+			Plural:     bdv1.QuarksLinkResourcePlural,
+			ShortNames: bdv1.QuarksLinkResourceShortNames,
+		},
+		bdv1.SchemeGroupVersion,
+	)
+
+	err = b.WithValidation(&bdv1.QuarksLinkValidation).
+		WithAdditionalPrinterColumns(bdv1.QuarksLinkAdditionalPrinterColumns).
+		Build().
+		Apply(ctx, client)
+	if err != nil {
+		return errors.Wrapf(err, "failed to apply CRD '%s'", bdv1.QuarksLinkResourceName)
+	}
+	err = crd.WaitForCRDReady(ctx, client, bdv1.QuarksLinkResourceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to wait for CRD '%s' ready", bdv1.QuarksLinkResourceName)
+	}
 	return nil
 }
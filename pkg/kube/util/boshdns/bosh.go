@@ -2,6 +2,7 @@ package boshdns
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/pkg/errors"
@@ -15,13 +16,18 @@ import (
 
 	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/apis"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/mutate"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/operatorimage"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
 )
 
 const (
-	// AppName is the name os the DNS deployed by quarks.
+	// AppName is the name os the DNS deployed by quarks. It's used as the
+	// 'app' label and as a suffix for the per deployment resource names.
 	AppName        = "coredns-quarks"
 	coreConfigFile = "Corefile"
+	configDir      = "/etc/coredns"
 	// CorednsServiceAccountLabel is the label of coredns service account on ns.
 	CorednsServiceAccountLabel = "quarks.cloudfoundry.org/coredns-quarks-service-account"
 )
@@ -59,21 +65,37 @@ type Target struct {
 	Domain        string `json:"domain"`
 }
 
+// ResourceName returns the name of the coredns ConfigMap, Deployment and
+// Service for a BOSH deployment.
+func ResourceName(deploymentName string) string {
+	return names.Sanitize(deploymentName + "-" + AppName)
+}
+
 // BoshDomainNameService is used to emulate Bosh DNS.
 type BoshDomainNameService struct {
 	Corefile       *Corefile
 	LocalDNSIP     string
+	DeploymentName string
 	InstanceGroups bdm.InstanceGroups
 }
 
 // NewBoshDomainNameService create a new DomainNameService to setup BOSH DNS.
-func NewBoshDomainNameService(instanceGroups bdm.InstanceGroups) *BoshDomainNameService {
+func NewBoshDomainNameService(deploymentName string, instanceGroups bdm.InstanceGroups) *BoshDomainNameService {
 	return &BoshDomainNameService{
 		Corefile:       &Corefile{},
+		DeploymentName: deploymentName,
 		InstanceGroups: instanceGroups,
 	}
 }
 
+// labels returns the labels for the DNS resources of the deployment
+func (dns *BoshDomainNameService) labels() map[string]string {
+	return map[string]string{
+		"app":                    AppName,
+		bdv1.LabelDeploymentName: dns.DeploymentName,
+	}
+}
+
 // Add create a new DomainNameService to setup BOSH DNS.
 func (dns *BoshDomainNameService) Add(addOn *bdm.AddOn) error {
 	for _, job := range addOn.Jobs {
@@ -88,40 +110,63 @@ func (dns *BoshDomainNameService) Add(addOn *bdm.AddOn) error {
 func (dns *BoshDomainNameService) CorefileConfigMap(namespace string) (corev1.ConfigMap, error) {
 	cm := corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      AppName,
+			Name:      ResourceName(dns.DeploymentName),
 			Namespace: namespace,
-			Labels:    map[string]string{"app": AppName},
+			Labels:    dns.labels(),
 		},
 	}
 
-	corefile, err := dns.Corefile.Create(namespace, dns.InstanceGroups)
+	corefile, err := dns.Corefile.Create(namespace, dns.DeploymentName, dns.InstanceGroups)
 	if err != nil {
 		return cm, err
 	}
-	cm.Data = map[string]string{coreConfigFile: corefile}
+
+	queries, err := json.Marshal(dns.QueryConfig())
+	if err != nil {
+		return cm, errors.Wrapf(err, "failed to marshal BOSH DNS query config")
+	}
+	cm.Data = map[string]string{
+		coreConfigFile:  corefile,
+		queryConfigFile: string(queries),
+	}
 
 	return cm, nil
 }
 
-// Deployment returns the k8s Deployment for coredns
+// QueryConfig returns the instance groups, which are needed to answer BOSH
+// DNS queries
+func (dns *BoshDomainNameService) QueryConfig() QueryConfig {
+	config := QueryConfig{Deployment: dns.DeploymentName}
+	for _, ig := range dns.InstanceGroups {
+		config.InstanceGroups = append(config.InstanceGroups, QueryInstanceGroup{
+			Name:      ig.Name,
+			Instances: ig.Instances,
+			AZs:       ig.AZs,
+		})
+	}
+	return config
+}
+
+// Deployment returns the k8s Deployment for coredns. A side car answers the
+// BOSH DNS queries, which coredns forwards to it.
 func (dns *BoshDomainNameService) Deployment(namespace string, corednsServiceAccountName string) appsv1.Deployment {
 	var corefileMode int32 = 0644
 	var replicas int32 = 2
 	const volumeName = "bosh-dns-volume"
 	return appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      AppName,
+			Name:      ResourceName(dns.DeploymentName),
 			Namespace: namespace,
-			Labels:    map[string]string{"app": AppName},
+			Labels:    dns.labels(),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{"app": AppName},
+				MatchLabels: dns.labels(),
 			},
 			Replicas: &replicas,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels:      map[string]string{"app": AppName},
+					Labels:      dns.labels(),
 					Annotations: map[string]string{annotationRestartOnUpdate: "true"},
 				},
 				Spec: corev1.PodSpec{
@@ -133,7 +178,7 @@ func (dns *BoshDomainNameService) Deployment(namespace string, corednsServiceAcc
 							Image: boshDNSDockerImage,
 							Ports: []corev1.ContainerPort{dnsUDPPort, dnsTCPPort, metricsPort},
 							VolumeMounts: []corev1.VolumeMount{
-								{MountPath: "/etc/coredns", Name: volumeName, ReadOnly: true},
+								{MountPath: configDir, Name: volumeName, ReadOnly: true},
 							},
 							ReadinessProbe: &corev1.Probe{
 								Handler: corev1.Handler{
@@ -163,6 +208,21 @@ func (dns *BoshDomainNameService) Deployment(namespace string, corednsServiceAcc
 								InitialDelaySeconds: 60,
 							},
 						},
+						{
+							Name:            "bosh-dns-queries",
+							Image:           operatorimage.GetOperatorDockerImage(),
+							ImagePullPolicy: operatorimage.GetOperatorImagePullPolicy(),
+							Command:         []string{"/usr/bin/dumb-init", "--"},
+							Args: []string{
+								"quarks-operator", "util", "bosh-dns-server",
+								"--config", configDir + "/" + queryConfigFile,
+								"--namespace", namespace,
+								"--address", QueryServerAddress,
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{MountPath: configDir, Name: volumeName, ReadOnly: true},
+							},
+						},
 					},
 					Volumes: []corev1.Volume{
 						{
@@ -171,10 +231,11 @@ func (dns *BoshDomainNameService) Deployment(namespace string, corednsServiceAcc
 								ConfigMap: &corev1.ConfigMapVolumeSource{
 									DefaultMode: &corefileMode,
 									LocalObjectReference: corev1.LocalObjectReference{
-										Name: AppName,
+										Name: ResourceName(dns.DeploymentName),
 									},
 									Items: []corev1.KeyToPath{
 										{Key: coreConfigFile, Path: coreConfigFile},
+										{Key: queryConfigFile, Path: queryConfigFile},
 									},
 								},
 							},
@@ -190,9 +251,9 @@ func (dns *BoshDomainNameService) Deployment(namespace string, corednsServiceAcc
 func (dns *BoshDomainNameService) Service(namespace string) corev1.Service {
 	return corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      AppName,
+			Name:      ResourceName(dns.DeploymentName),
 			Namespace: namespace,
-			Labels:    map[string]string{"app": AppName},
+			Labels:    dns.labels(),
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
@@ -200,7 +261,7 @@ func (dns *BoshDomainNameService) Service(namespace string) corev1.Service {
 				{Name: dnsTCPPort.Name, Port: 53, Protocol: dnsTCPPort.Protocol, TargetPort: intstr.FromString(dnsTCPPort.Name)},
 				{Name: metricsPort.Name, Port: 9153, Protocol: metricsPort.Protocol, TargetPort: intstr.FromString(metricsPort.Name)},
 			},
-			Selector: map[string]string{"app": AppName},
+			Selector: dns.labels(),
 			Type:     "ClusterIP",
 		},
 	}
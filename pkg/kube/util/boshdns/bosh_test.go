@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -208,7 +209,7 @@ var _ = Describe("BOSHDomainNameService", func() {
 					&manifest.InstanceGroup{Name: "bits", AZs: []string{"az1", "az2"}},
 					&manifest.InstanceGroup{Name: "diego-cell", AZs: []string{"az1", "az2"}, Instances: 1},
 				}
-				dns = boshdns.NewBoshDomainNameService(igs)
+				dns = boshdns.NewBoshDomainNameService("scf", igs)
 				err := dns.Add(loadAddOn(aliasAddon))
 				Expect(err).NotTo(HaveOccurred())
 
@@ -242,17 +243,17 @@ var _ = Describe("BOSHDomainNameService", func() {
 				Expect(corefile).To(ContainSubstring(`
 	template IN A bits.service.cf.internal {
 		match ^bits\.service\.cf\.internal\.$
-		answer "{{ .Name }} 60 IN CNAME bits.default.svc."
+		answer "{{ .Name }} 60 IN CNAME scf-bits.default.svc."
 		fallthrough`))
 				Expect(corefile).To(ContainSubstring(`
 	template IN AAAA bits.service.cf.internal {
 		match ^bits\.service\.cf\.internal\.$
-		answer "{{ .Name }} 60 IN CNAME bits.default.svc."
+		answer "{{ .Name }} 60 IN CNAME scf-bits.default.svc."
 		fallthrough`))
 				Expect(corefile).To(ContainSubstring(`
 	template IN CNAME bbs1.service.cf.internal {
 		match ^bbs1\.service\.cf\.internal\.$
-		answer "{{ .Name }} 60 IN CNAME diego-api.default.svc."
+		answer "{{ .Name }} 60 IN CNAME scf-diego-api.default.svc."
 		fallthrough
 	}`))
 
@@ -263,18 +264,29 @@ var _ = Describe("BOSHDomainNameService", func() {
 		answer "{{ .Name }} 60 IN CNAME uaa.default.svc."
 		fallthrough`))
 
+				By("adding the instance groups for BOSH DNS queries")
+				_, obj, _ := client.CreateArgsForCall(0)
+				cm := obj.(*corev1.ConfigMap)
+				Expect(cm.Data).To(HaveKeyWithValue("queries.json", ContainSubstring(`{"name":"diego-cell","instances":1,"azs":["az1","az2"]}`)))
+
+				By("adding the BOSH DNS query server")
+				_, obj, _ = client.CreateArgsForCall(1)
+				deployment := obj.(*appsv1.Deployment)
+				Expect(deployment.Spec.Template.Spec.Containers).To(HaveLen(2))
+				Expect(deployment.Spec.Template.Spec.Containers[1].Args).To(ContainElement("bosh-dns-server"))
+
 				By("checking for entries for diego-cells in mutli-zone")
 				Expect(corefile).To(ContainSubstring(`
 	template IN A diego-cell-z0-0.cell.service.cf.internal {
 		match ^diego-cell-z0-0\.cell\.service\.cf\.internal\.$
-		answer "{{ .Name }} 60 IN CNAME diego-cell-z0-0.default.svc."
+		answer "{{ .Name }} 60 IN CNAME scf-diego-cell-z0-0.default.svc."
 		fallthrough`))
 			})
 		})
 
 		When("BOSHDNS Addon has handlers", func() {
 			BeforeEach(func() {
-				dns = boshdns.NewBoshDomainNameService(manifest.InstanceGroups{})
+				dns = boshdns.NewBoshDomainNameService("scf", manifest.InstanceGroups{})
 				err := dns.Add(loadAddOn(handlerAddon))
 				Expect(err).NotTo(HaveOccurred())
 
@@ -303,7 +315,7 @@ var _ = Describe("BOSHDomainNameService", func() {
 
 		When("adding multiple dns addons", func() {
 			BeforeEach(func() {
-				dns = boshdns.NewBoshDomainNameService(manifest.InstanceGroups{})
+				dns = boshdns.NewBoshDomainNameService("scf", manifest.InstanceGroups{})
 				err := dns.Add(loadAddOn(handlerAddon))
 				Expect(err).NotTo(HaveOccurred())
 				err = dns.Add(loadAddOn(aliasAddon))
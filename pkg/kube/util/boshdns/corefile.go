@@ -71,7 +71,7 @@ func (c *Corefile) Add(props map[string]interface{}) error {
 }
 
 // Create the coredns corefile
-func (c *Corefile) Create(namespace string, instanceGroups bdm.InstanceGroups) (string, error) {
+func (c *Corefile) Create(namespace string, deploymentName string, instanceGroups bdm.InstanceGroups) (string, error) {
 	rewrites := make([]string, 0)
 	for _, alias := range c.Aliases {
 		for _, target := range alias.Targets {
@@ -89,6 +89,7 @@ func (c *Corefile) Create(namespace string, instanceGroups bdm.InstanceGroups) (
 					*instanceGroup,
 					target,
 					namespace,
+					deploymentName,
 					alias)
 			}
 		}
@@ -97,9 +98,11 @@ func (c *Corefile) Create(namespace string, instanceGroups bdm.InstanceGroups) (
 	tmpl := template.Must(template.New("Corefile").Parse(corefileTemplate))
 	var config strings.Builder
 	data := struct {
-		Rewrites []string
-		Handlers []Handler
-	}{rewrites, c.Handlers}
+		Rewrites    []string
+		Handlers    []Handler
+		BoshDomain  string
+		QueryServer string
+	}{rewrites, c.Handlers, BoshDomain, QueryServerAddress}
 	if err := tmpl.Execute(&config, data); err != nil {
 		return "", errors.Wrapf(err, "failed to generate Corefile")
 	}
@@ -128,29 +131,17 @@ func gatherAllRewrites(rewrites []string,
 	instanceGroup bdm.InstanceGroup,
 	target Target,
 	namespace string,
+	deploymentName string,
 	alias Alias) []string {
 	if target.Query == "_" {
-		if len(instanceGroup.AZs) > 0 {
-			for azIndex := range instanceGroup.AZs {
-				rewrites = gatherRewritesForInstances(rewrites,
-					instanceGroup,
-					target,
-					namespace,
-					azIndex,
-					alias)
-			}
-		} else {
-			rewrites = gatherRewritesForInstances(rewrites,
-				instanceGroup,
-				target,
-				namespace,
-				-1,
-				alias)
+		for _, instance := range instanceAliases(instanceGroup, target, deploymentName, alias) {
+			to := fmt.Sprintf("%s.%s.svc.%s", instance.serviceName, namespace, clusterDomain)
+			rewrites = append(rewrites, newTemplate(instance.domain, to))
 		}
 	} else {
 		from := alias.Domain
 		to := fmt.Sprintf("%s.%s.svc.%s",
-			names.ServiceName(target.InstanceGroup),
+			names.ServiceName(deploymentName, target.InstanceGroup),
 			namespace,
 			clusterDomain)
 		rewrites = append(rewrites, newTemplate(from, to))
@@ -159,35 +150,54 @@ func gatherAllRewrites(rewrites []string,
 	return rewrites
 }
 
-func gatherRewritesForInstances(rewrites []string,
-	instanceGroup bdm.InstanceGroup,
-	target Target,
-	namespace string,
-	azIndex int,
-	alias Alias) []string {
-	id := ""
-	for i := 0; i < instanceGroup.Instances; i++ {
-		if azIndex > -1 {
-			id = fmt.Sprintf("%s-z%d-%d", target.InstanceGroup, azIndex, i)
-		} else {
-			id = fmt.Sprintf("%s-%d", target.InstanceGroup, i)
+type instanceAlias struct {
+	domain      string
+	serviceName string
+	azIndex     int
+	index       int
+}
+
+// instanceAliases expands the BOSH DNS placeholder alias for each instance
+// of the instance group: https://bosh.io/docs/dns/#placeholder-alias
+func instanceAliases(instanceGroup bdm.InstanceGroup, target Target, deploymentName string, alias Alias) []instanceAlias {
+	aliases := []instanceAlias{}
+	azIndexes := []int{-1}
+	if len(instanceGroup.AZs) > 0 {
+		azIndexes = []int{}
+		for azIndex := range instanceGroup.AZs {
+			azIndexes = append(azIndexes, azIndex)
 		}
-		from := strings.Replace(alias.Domain, "_", id, 1)
-		serviceName := instanceGroup.IndexedServiceName(i, azIndex)
-		to := fmt.Sprintf("%s.%s.svc.%s", serviceName, namespace, clusterDomain)
-		rewrites = append(rewrites, newTemplate(from, to))
 	}
 
-	return rewrites
+	for _, azIndex := range azIndexes {
+		for i := 0; i < instanceGroup.Instances; i++ {
+			id := fmt.Sprintf("%s-%d", target.InstanceGroup, i)
+			if azIndex > -1 {
+				id = fmt.Sprintf("%s-z%d-%d", target.InstanceGroup, azIndex, i)
+			}
+			aliases = append(aliases, instanceAlias{
+				domain:      strings.Replace(alias.Domain, "_", id, 1),
+				serviceName: instanceGroup.IndexedServiceName(deploymentName, i, azIndex),
+				azIndex:     azIndex,
+				index:       i,
+			})
+		}
+	}
+	return aliases
 }
 
 // The Corefile values other than the rewrites were based on the default cluster CoreDNS Corefile.
+// BOSH DNS queries, like 'q-s0.web.default.cf.bosh', are answered by the query server side car.
 const corefileTemplate = `
 {{- range $h := .Handlers }}
 {{ .Zone }}:8053 {
 	forward . {{ range .Source.Recursors }}{{ $h.Source.Protocol }}{{ . }} {{ end }}
 }
 {{- end }}
+{{ .BoshDomain }}:8053 {
+	errors
+	forward . {{ .QueryServer }}
+}
 .:8053 {
 	errors
 	health
@@ -91,13 +91,18 @@ var _ = Describe("Corefile", func() {
 				err = corefile.Add(load(handlerAddon))
 				Expect(err).NotTo(HaveOccurred())
 
-				corefile, err := corefile.Create("default", igs)
+				corefile, err := corefile.Create("default", "scf", igs)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(corefile).To(ContainSubstring(`corp.intranet.local:8053 {`))
 				Expect(corefile).To(ContainSubstring(`forward . dns://10.0.0.2 dns://127.0.0.1`))
 				Expect(corefile).To(ContainSubstring(`forward . /etc/resolv.conf`))
 				Expect(corefile).To(ContainSubstring(`
+bosh:8053 {
+	errors
+	forward . 127.0.0.1:8054
+}`))
+				Expect(corefile).To(ContainSubstring(`
 	template IN A bits.service.cf.internal {
 		match ^bits\.service\.cf\.internal\.$
 		answer "{{ .Name }} 60 IN CNAME bits.default.svc."
@@ -122,7 +127,7 @@ var _ = Describe("Corefile", func() {
 					err := corefile.Add(load(strings.Replace(handlerAddon, "dns", t.Type, 1)))
 					Expect(err).NotTo(HaveOccurred())
 
-					corefile, err := corefile.Create("default", igs)
+					corefile, err := corefile.Create("default", "scf", igs)
 					Expect(err).NotTo(HaveOccurred())
 
 					Expect(corefile).To(ContainSubstring(fmt.Sprintf(`forward . %[1]s://10.0.0.2 %[1]s://127.0.0.1`, t.Protocol)))
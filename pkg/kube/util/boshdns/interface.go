@@ -12,6 +12,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 )
 
 // DomainNameService abstraction.
@@ -20,9 +21,9 @@ type DomainNameService interface {
 	Apply(ctx context.Context, namespace string, c client.Client, setOwner func(object metav1.Object) error) error
 }
 
-// New returns the DNS service management struct
-func New(m bdm.Manifest) (DomainNameService, error) {
-	dns := NewBoshDomainNameService(m.InstanceGroups)
+// New returns the DNS service management struct for the given deployment
+func New(deploymentName string, m bdm.Manifest) (DomainNameService, error) {
+	dns := NewBoshDomainNameService(deploymentName, m.InstanceGroups)
 	found := false
 	for index, addon := range m.AddOns {
 		for _, job := range addon.Jobs {
@@ -41,9 +42,18 @@ func New(m bdm.Manifest) (DomainNameService, error) {
 	return NewSimpleDomainNameService(), nil
 }
 
+// NewForMode returns the DNS service management struct for the DNS mode of the deployment
+func NewForMode(deploymentName string, mode bdv1.DNSMode, m bdm.Manifest) (DomainNameService, error) {
+	if mode == bdv1.DNSModeNative {
+		return NewNativeDomainNameService(deploymentName, m)
+	}
+	return New(deploymentName, m)
+}
+
 // Validate that all job properties of the addon section can be decoded
 func Validate(m bdm.Manifest) error {
-	_, err := New(m)
+	// the deployment name is only needed to name resources
+	_, err := New("", m)
 	return err
 }
 
@@ -75,6 +85,29 @@ func DNSSetting(m bdm.Manifest, serviceIP, namespace string) (corev1.DNSPolicy,
 	return corev1.DNSClusterFirst, nil, nil
 }
 
+// PodDNS is the DNS setting for the pods of a deployment
+type PodDNS struct {
+	// ServiceIP of the coredns service, which is the nameserver if the manifest has the BOSH DNS addon
+	ServiceIP string
+	// Native pods use the cluster DNS and resolve the aliases with host aliases
+	Native      bool
+	HostAliases []corev1.HostAlias
+}
+
+// SetPodSpec sets the DNS policy, config and host aliases of the pod spec
+func (d PodDNS) SetPodSpec(m bdm.Manifest, namespace string, spec *corev1.PodSpec) error {
+	if d.Native {
+		spec.DNSPolicy = corev1.DNSClusterFirst
+		spec.DNSConfig = nil
+		spec.HostAliases = d.HostAliases
+		return nil
+	}
+
+	var err error
+	spec.DNSPolicy, spec.DNSConfig, err = DNSSetting(m, d.ServiceIP, namespace)
+	return err
+}
+
 // HasBoshDNSAddOn checks if the manifest has bosh dns addon
 func HasBoshDNSAddOn(m bdm.Manifest) int {
 	index := -1
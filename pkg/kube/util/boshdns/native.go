@@ -0,0 +1,252 @@
+package boshdns
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+)
+
+// NativeDomainNameService emulates BOSH DNS aliases without a DNS server.
+// The pods use the cluster DNS and resolve the aliases with host aliases for
+// the ClusterIPs of the instance group services.
+type NativeDomainNameService struct {
+	Aliases        []Alias
+	DeploymentName string
+	InstanceGroups bdm.InstanceGroups
+}
+
+// NewNativeDomainNameService returns the native DNS service for the aliases of the BOSH DNS addons.
+// Host aliases can't express wildcard domains, so these are rejected.
+func NewNativeDomainNameService(deploymentName string, m bdm.Manifest) (*NativeDomainNameService, error) {
+	corefile := &Corefile{}
+	for _, addon := range m.AddOns {
+		for _, job := range addon.Jobs {
+			if job.Release == bdm.BoshDNSAddOnName || job.Release == bdm.BOSHDNSAliasesAddOnName {
+				if err := corefile.Add(job.Properties.Properties); err != nil {
+					return nil, errors.Wrapf(err, "error loading BOSH DNS configuration")
+				}
+			}
+		}
+	}
+
+	for _, alias := range corefile.Aliases {
+		if strings.Contains(alias.Domain, "*") {
+			return nil, errors.Errorf("wildcard alias domain '%s' is not supported with native DNS", alias.Domain)
+		}
+	}
+
+	return &NativeDomainNameService{
+		Aliases:        corefile.Aliases,
+		DeploymentName: deploymentName,
+		InstanceGroups: m.InstanceGroups,
+	}, nil
+}
+
+// AliasServiceName returns the name of the ClusterIP service of an instance
+// group, which is the target of aliases in the native DNS mode
+// `<deployment-name>-<instance-group>-alias`
+func AliasServiceName(deploymentName string, instanceGroupName string) string {
+	return names.ServiceName(deploymentName, instanceGroupName+"-alias")
+}
+
+// Apply creates the missing services, which are the targets of the aliases.
+// Their ClusterIPs have to be known, before the instance groups are rendered
+// with the host aliases. The services are updated with the instance groups.
+func (dns *NativeDomainNameService) Apply(ctx context.Context, namespace string, c client.Client, setOwner func(object metav1.Object) error) error {
+	for _, svc := range dns.services(namespace) {
+		svc := svc
+		err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: svc.Name}, &corev1.Service{})
+		if err == nil {
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get service '%s/%s'", namespace, svc.Name)
+		}
+
+		if err := setOwner(&svc); err != nil {
+			return errors.Wrapf(err, "failed to set reference for service '%s/%s'", namespace, svc.Name)
+		}
+		if err := c.Create(ctx, &svc); err != nil && !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create service '%s/%s'", namespace, svc.Name)
+		}
+	}
+	return nil
+}
+
+// RemoveCoreDNS removes the coredns resources, in case the deployment used
+// the coredns mode before. They are kept until the QuarksStatefulSets of all
+// instance groups stopped using coredns as their nameserver.
+func (dns *NativeDomainNameService) RemoveCoreDNS(ctx context.Context, namespace string, c client.Client) error {
+	for _, ig := range dns.InstanceGroups {
+		if ig.IsErrand() {
+			continue
+		}
+
+		qsts := &qstsv1a1.QuarksStatefulSet{}
+		name := names.QuarksStatefulSetName(dns.DeploymentName, ig.Name)
+		err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, qsts)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "failed to get quarks statefulset '%s/%s'", namespace, name)
+		}
+		if qsts.Spec.Template.Spec.Template.Spec.DNSPolicy == corev1.DNSNone {
+			return nil
+		}
+	}
+
+	meta := metav1.ObjectMeta{Name: ResourceName(dns.DeploymentName), Namespace: namespace}
+	for _, obj := range []client.Object{
+		&appsv1.Deployment{ObjectMeta: meta},
+		&corev1.Service{ObjectMeta: meta},
+		&corev1.ConfigMap{ObjectMeta: meta},
+	} {
+		if err := c.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete '%s/%s'", namespace, meta.Name)
+		}
+	}
+	return nil
+}
+
+// services returns the alias services and the per-instance services, which
+// are referenced by the aliases. Their selectors match the services of the
+// instance groups.
+func (dns *NativeDomainNameService) services(namespace string) []corev1.Service {
+	services := []corev1.Service{}
+	seen := map[string]bool{}
+	add := func(name string, labels map[string]string, ports []corev1.ServicePort, active bool) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+
+		selector := map[string]string{}
+		for k, v := range labels {
+			selector[k] = v
+		}
+		if active {
+			selector[qstsv1a1.LabelActivePod] = "active"
+		}
+		services = append(services, corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+			Spec:       corev1.ServiceSpec{Ports: ports, Selector: selector},
+		})
+	}
+
+	for _, alias := range dns.Aliases {
+		for _, target := range alias.Targets {
+			instanceGroup, found := dns.InstanceGroups.InstanceGroupByName(target.InstanceGroup)
+			if !found {
+				continue
+			}
+			ports := instanceGroup.ServicePorts()
+			if len(ports) == 0 {
+				continue
+			}
+			active := isActivePassive(*instanceGroup)
+
+			if target.Query != "_" {
+				add(AliasServiceName(dns.DeploymentName, instanceGroup.Name), map[string]string{
+					bdv1.LabelDeploymentName:    dns.DeploymentName,
+					bdv1.LabelInstanceGroupName: instanceGroup.Name,
+				}, ports, active)
+				continue
+			}
+
+			for _, instance := range instanceAliases(*instanceGroup, target, dns.DeploymentName, alias) {
+				azIndex := instance.azIndex
+				if azIndex == -1 {
+					azIndex = 0
+				}
+				add(instance.serviceName, map[string]string{
+					bdv1.LabelDeploymentName:    dns.DeploymentName,
+					bdv1.LabelInstanceGroupName: instanceGroup.Name,
+					qstsv1a1.LabelAZIndex:       strconv.Itoa(azIndex),
+					qstsv1a1.LabelPodOrdinal:    strconv.Itoa(instance.index),
+				}, ports, active)
+			}
+		}
+	}
+	return services
+}
+
+func isActivePassive(instanceGroup bdm.InstanceGroup) bool {
+	for _, job := range instanceGroup.Jobs {
+		if len(job.Properties.Quarks.ActivePassiveProbes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HostAliases maps the alias domains to the ClusterIPs of their target
+// services. Services, which don't exist yet, are skipped. The result is
+// sorted, so the pod templates only change if the IPs change.
+func (dns *NativeDomainNameService) HostAliases(ctx context.Context, namespace string, c client.Reader) ([]corev1.HostAlias, error) {
+	hostnames := map[string][]string{}
+	add := func(serviceName string, hostname string) error {
+		svc := &corev1.Service{}
+		err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: serviceName}, svc)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to get service '%s/%s'", namespace, serviceName)
+		}
+		ip := svc.Spec.ClusterIP
+		if ip == "" || ip == corev1.ClusterIPNone {
+			return nil
+		}
+		hostnames[ip] = append(hostnames[ip], hostname)
+		return nil
+	}
+
+	for _, alias := range dns.Aliases {
+		for _, target := range alias.Targets {
+			instanceGroup, found := dns.InstanceGroups.InstanceGroupByName(target.InstanceGroup)
+			switch {
+			case !found && target.Query == "_":
+				// We can't resolve indexes of unknown instance groups
+				continue
+			case !found:
+				// Even if the instance group doesn't exist, the user may want to setup aliases to other kube service names
+				if err := add(target.InstanceGroup, alias.Domain); err != nil {
+					return nil, err
+				}
+			case target.Query == "_":
+				for _, instance := range instanceAliases(*instanceGroup, target, dns.DeploymentName, alias) {
+					if err := add(instance.serviceName, instance.domain); err != nil {
+						return nil, err
+					}
+				}
+			default:
+				if err := add(AliasServiceName(dns.DeploymentName, instanceGroup.Name), alias.Domain); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	hostAliases := []corev1.HostAlias{}
+	for ip, domains := range hostnames {
+		sort.Strings(domains)
+		hostAliases = append(hostAliases, corev1.HostAlias{IP: ip, Hostnames: domains})
+	}
+	sort.Slice(hostAliases, func(i, j int) bool { return hostAliases[i].IP < hostAliases[j].IP })
+	return hostAliases, nil
+}
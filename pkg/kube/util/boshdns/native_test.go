@@ -0,0 +1,291 @@
+package boshdns_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	crc "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+)
+
+const nativeManifest = `---
+addons:
+- name: bosh-dns-aliases
+  jobs:
+  - name: bosh-dns-aliases
+    release: bosh-dns-aliases
+    properties:
+      aliases:
+      - domain: uaa.service.cf.internal
+        targets:
+        - query: '*'
+          instance_group: uaa
+          deployment: cf
+          network: default
+          domain: bosh
+      - domain: login.service.cf.internal
+        targets:
+        - query: '*'
+          instance_group: uaa
+          deployment: cf
+          network: default
+          domain: bosh
+      - domain: _.cell.service.cf.internal
+        targets:
+        - query: '_'
+          instance_group: diego-cell
+          deployment: cf
+          network: default
+          domain: bosh
+      - domain: db.service.cf.internal
+        targets:
+        - query: '*'
+          instance_group: external-db
+          deployment: cf
+          network: default
+          domain: bosh
+      - domain: credhub.service.cf.internal
+        targets:
+        - query: '*'
+          instance_group: credhub
+          deployment: cf
+          network: default
+          domain: bosh
+instance_groups:
+- name: uaa
+  instances: 1
+  jobs:
+  - name: uaa
+    release: uaa
+    properties:
+      quarks:
+        ports:
+        - name: uaa
+          protocol: TCP
+          internal: 8080
+- name: diego-cell
+  instances: 2
+  azs: [z1]
+  jobs:
+  - name: rep
+    release: diego
+    properties:
+      quarks:
+        ports:
+        - name: rep
+          protocol: TCP
+          internal: 1801
+- name: credhub
+  instances: 1
+  jobs: []
+`
+
+var _ = Describe("NativeDomainNameService", func() {
+	var (
+		ctx context.Context
+		m   *manifest.Manifest
+		dns *boshdns.NativeDomainNameService
+	)
+
+	service := func(name, clusterIP string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.ServiceSpec{ClusterIP: clusterIP},
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		m, err = manifest.LoadYAML([]byte(nativeManifest))
+		Expect(err).NotTo(HaveOccurred())
+		dns, err = boshdns.NewNativeDomainNameService("cf", *m)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("NewForMode", func() {
+		It("returns the native service for the native mode", func() {
+			d, err := boshdns.NewForMode("cf", bdv1.DNSModeNative, *m)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(d).To(BeAssignableToTypeOf(&boshdns.NativeDomainNameService{}))
+
+			d, err = boshdns.NewForMode("cf", bdv1.DNSModeCoreDNS, *m)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(d).To(BeAssignableToTypeOf(&boshdns.BoshDomainNameService{}))
+		})
+	})
+
+	Context("NewNativeDomainNameService", func() {
+		It("rejects wildcard alias domains", func() {
+			m.AddOns[0].Jobs[0].Properties.Properties["aliases"] = []interface{}{
+				map[string]interface{}{
+					"domain":  "*.uaa.service.cf.internal",
+					"targets": []interface{}{map[string]interface{}{"query": "*", "instance_group": "uaa"}},
+				},
+			}
+
+			_, err := boshdns.NewNativeDomainNameService("cf", *m)
+			Expect(err).To(MatchError(ContainSubstring("wildcard alias domain '*.uaa.service.cf.internal' is not supported")))
+		})
+	})
+
+	Context("Apply", func() {
+		It("creates the services of the aliases", func() {
+			client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				service("cf-diego-cell-z0-1", "10.0.1.2"),
+			).Build()
+
+			err := dns.Apply(ctx, "default", client, func(object metav1.Object) error { return nil })
+			Expect(err).NotTo(HaveOccurred())
+
+			svc := &corev1.Service{}
+			Expect(client.Get(ctx, crc.ObjectKey{Namespace: "default", Name: "cf-uaa-alias"}, svc)).To(Succeed())
+			Expect(svc.Spec.Selector).To(Equal(map[string]string{
+				bdv1.LabelDeploymentName:    "cf",
+				bdv1.LabelInstanceGroupName: "uaa",
+			}))
+			Expect(svc.Spec.Ports[0].Port).To(Equal(int32(8080)))
+
+			Expect(client.Get(ctx, crc.ObjectKey{Namespace: "default", Name: "cf-diego-cell-z0-0"}, svc)).To(Succeed())
+			Expect(svc.Spec.Selector).To(HaveKeyWithValue(qstsv1a1.LabelPodOrdinal, "0"))
+
+			Expect(client.Get(ctx, crc.ObjectKey{Namespace: "default", Name: "cf-diego-cell-z0-1"}, svc)).To(Succeed())
+			Expect(svc.Spec.ClusterIP).To(Equal("10.0.1.2"))
+
+			// credhub has no ports, so it has no services
+			Expect(apierrors.IsNotFound(client.Get(ctx, crc.ObjectKey{Namespace: "default", Name: "cf-credhub-alias"}, svc))).To(BeTrue())
+		})
+
+		It("keeps the coredns resources", func() {
+			meta := metav1.ObjectMeta{Name: boshdns.ResourceName("cf"), Namespace: "default"}
+			client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				&appsv1.Deployment{ObjectMeta: meta},
+			).Build()
+
+			err := dns.Apply(ctx, "default", client, func(object metav1.Object) error { return nil })
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Get(ctx, crc.ObjectKey{Namespace: "default", Name: meta.Name}, &appsv1.Deployment{})).To(Succeed())
+		})
+	})
+
+	Context("RemoveCoreDNS", func() {
+		var (
+			meta metav1.ObjectMeta
+			key  crc.ObjectKey
+		)
+
+		qsts := func(name string, policy corev1.DNSPolicy) *qstsv1a1.QuarksStatefulSet {
+			q := &qstsv1a1.QuarksStatefulSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+			q.Spec.Template.Spec.Template.Spec.DNSPolicy = policy
+			return q
+		}
+
+		newClient := func(objects ...crc.Object) crc.Client {
+			s := runtime.NewScheme()
+			Expect(scheme.AddToScheme(s)).To(Succeed())
+			Expect(qstsv1a1.AddToScheme(s)).To(Succeed())
+			objects = append(objects,
+				&appsv1.Deployment{ObjectMeta: meta},
+				&corev1.Service{ObjectMeta: meta},
+				&corev1.ConfigMap{ObjectMeta: meta},
+			)
+			return fake.NewClientBuilder().WithScheme(s).WithObjects(objects...).Build()
+		}
+
+		BeforeEach(func() {
+			meta = metav1.ObjectMeta{Name: boshdns.ResourceName("cf"), Namespace: "default"}
+			key = crc.ObjectKey{Namespace: "default", Name: meta.Name}
+		})
+
+		It("removes the coredns resources, when no instance group uses them", func() {
+			client := newClient(
+				qsts("cf-uaa", corev1.DNSClusterFirst),
+				qsts("cf-diego-cell", corev1.DNSClusterFirst),
+			)
+
+			Expect(dns.RemoveCoreDNS(ctx, "default", client)).To(Succeed())
+			Expect(apierrors.IsNotFound(client.Get(ctx, key, &appsv1.Deployment{}))).To(BeTrue())
+			Expect(apierrors.IsNotFound(client.Get(ctx, key, &corev1.Service{}))).To(BeTrue())
+			Expect(apierrors.IsNotFound(client.Get(ctx, key, &corev1.ConfigMap{}))).To(BeTrue())
+		})
+
+		It("keeps the coredns resources, while an instance group still uses them", func() {
+			client := newClient(
+				qsts("cf-uaa", corev1.DNSClusterFirst),
+				qsts("cf-diego-cell", corev1.DNSNone),
+			)
+
+			Expect(dns.RemoveCoreDNS(ctx, "default", client)).To(Succeed())
+			Expect(client.Get(ctx, key, &appsv1.Deployment{})).To(Succeed())
+			Expect(client.Get(ctx, key, &corev1.Service{})).To(Succeed())
+			Expect(client.Get(ctx, key, &corev1.ConfigMap{})).To(Succeed())
+		})
+	})
+
+	Context("HostAliases", func() {
+		It("maps the aliases to the ClusterIPs of their services", func() {
+			client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				service("cf-uaa", corev1.ClusterIPNone),
+				service("cf-uaa-alias", "10.0.0.10"),
+				service("cf-diego-cell-z0-0", "10.0.1.1"),
+				service("cf-diego-cell-z0-1", "10.0.1.2"),
+				service("external-db", "10.0.2.1"),
+			).Build()
+
+			hostAliases, err := dns.HostAliases(ctx, "default", client)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hostAliases).To(Equal([]corev1.HostAlias{
+				{IP: "10.0.0.10", Hostnames: []string{"login.service.cf.internal", "uaa.service.cf.internal"}},
+				{IP: "10.0.1.1", Hostnames: []string{"diego-cell-z0-0.cell.service.cf.internal"}},
+				{IP: "10.0.1.2", Hostnames: []string{"diego-cell-z0-1.cell.service.cf.internal"}},
+				{IP: "10.0.2.1", Hostnames: []string{"db.service.cf.internal"}},
+			}))
+		})
+
+		It("skips headless services", func() {
+			client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				service("cf-uaa-alias", corev1.ClusterIPNone),
+			).Build()
+
+			hostAliases, err := dns.HostAliases(ctx, "default", client)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hostAliases).To(BeEmpty())
+		})
+	})
+
+	Context("PodDNS", func() {
+		It("sets the host aliases and the cluster DNS for native pods", func() {
+			hostAliases := []corev1.HostAlias{{IP: "10.0.0.10", Hostnames: []string{"uaa.service.cf.internal"}}}
+			spec := &corev1.PodSpec{DNSConfig: &corev1.PodDNSConfig{}}
+
+			err := boshdns.PodDNS{Native: true, HostAliases: hostAliases}.SetPodSpec(*m, "default", spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.DNSPolicy).To(Equal(corev1.DNSClusterFirst))
+			Expect(spec.DNSConfig).To(BeNil())
+			Expect(spec.HostAliases).To(Equal(hostAliases))
+		})
+
+		It("uses the coredns service otherwise", func() {
+			spec := &corev1.PodSpec{}
+
+			err := boshdns.PodDNS{ServiceIP: "1.2.3.5"}.SetPodSpec(*m, "default", spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.DNSPolicy).To(Equal(corev1.DNSNone))
+			Expect(spec.DNSConfig.Nameservers).To(Equal([]string{"1.2.3.5"}))
+			Expect(spec.HostAliases).To(BeEmpty())
+		})
+	})
+})
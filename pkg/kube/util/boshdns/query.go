@@ -0,0 +1,193 @@
+package boshdns
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// BoshDomain is the top level domain of BOSH DNS queries
+	BoshDomain  = "bosh"
+	queryPrefix = "q-"
+)
+
+// Status selects the instances of a query by their health, it's the 's'
+// filter of a BOSH DNS query
+type Status int
+
+const (
+	// StatusSmart returns the healthy instances, or all of them if none is healthy
+	StatusSmart Status = 0
+	// StatusUnhealthy returns the instances which are not ready
+	StatusUnhealthy Status = 1
+	// StatusHealthy returns the ready instances
+	StatusHealthy Status = 3
+	// StatusAll returns all instances
+	StatusAll Status = 4
+)
+
+// Query is a decoded BOSH DNS query, either the long form
+// `q-<filters>.<instance-group>.<network>.<deployment>.bosh`, the short form
+// `q-<filters>.q-g<group>.bosh` or an instance specific query
+// `<instance-id>.<instance-group>.<network>.<deployment>.bosh`.
+// https://bosh.io/docs/dns/#constructing-dns-queries
+type Query struct {
+	InstanceGroup string
+	Network       string
+	Deployment    string
+	// Group is the 1-based index of the instance group in the manifest, used by short queries
+	Group int
+	// InstanceID is set for instance specific queries
+	InstanceID string
+	// AZs are the 1-based indexes of the AZs of the instance group
+	AZs []int
+	// Indexes are the BOSH spec indexes of the instances
+	Indexes []int
+	Status  Status
+}
+
+// ParseQuery decodes a BOSH DNS query
+func ParseQuery(name string) (Query, error) {
+	q := Query{}
+
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(name), "."), ".")
+	if labels[len(labels)-1] != BoshDomain {
+		return q, errors.Errorf("query '%s' is not in the '%s' domain", name, BoshDomain)
+	}
+	labels = labels[:len(labels)-1]
+
+	switch len(labels) {
+	case 2:
+		if !strings.HasPrefix(labels[1], queryPrefix+"g") {
+			return q, errors.Errorf("query '%s' has no group", name)
+		}
+		group, err := strconv.Atoi(strings.TrimPrefix(labels[1], queryPrefix+"g"))
+		if err != nil || group < 1 {
+			return q, errors.Errorf("query '%s' has an invalid group", name)
+		}
+		q.Group = group
+	case 4:
+		q.InstanceGroup = labels[1]
+		q.Network = labels[2]
+		q.Deployment = labels[3]
+	default:
+		return q, errors.Errorf("query '%s' has %d labels, expected 3 or 5", name, len(labels)+1)
+	}
+
+	if !strings.HasPrefix(labels[0], queryPrefix) {
+		if q.Group > 0 {
+			return q, errors.Errorf("query '%s' has no filters", name)
+		}
+		q.InstanceID = labels[0]
+		q.Status = StatusAll
+		return q, nil
+	}
+
+	if err := q.parseFilters(strings.TrimPrefix(labels[0], queryPrefix)); err != nil {
+		return q, errors.Wrapf(err, "query '%s' has invalid filters", name)
+	}
+	return q, nil
+}
+
+// parseFilters decodes the filters of a query, each is a letter followed by
+// a number, e.g. `a1i3s0`
+func (q *Query) parseFilters(filters string) error {
+	for len(filters) > 0 {
+		key := filters[0]
+		end := 1
+		for end < len(filters) && filters[end] >= '0' && filters[end] <= '9' {
+			end++
+		}
+		if end == 1 {
+			return errors.Errorf("filter '%c' has no value", key)
+		}
+		value, err := strconv.Atoi(filters[1:end])
+		if err != nil {
+			return errors.Wrapf(err, "filter '%c' has an invalid value", key)
+		}
+		filters = filters[end:]
+
+		switch key {
+		case 'a':
+			q.AZs = append(q.AZs, value)
+		case 'i':
+			q.Indexes = append(q.Indexes, value)
+		case 's':
+			switch Status(value) {
+			case StatusSmart, StatusUnhealthy, StatusHealthy, StatusAll:
+				q.Status = Status(value)
+			default:
+				return errors.Errorf("unknown status '%d'", value)
+			}
+		case 'g':
+			q.Group = value
+		case 'm', 'n', 'y':
+			// Network ids, numeric ids and synchronous health checks have no
+			// meaning in kubernetes, every instance has one network
+		default:
+			return errors.Errorf("unknown filter '%c'", key)
+		}
+	}
+	return nil
+}
+
+// Instance is an instance of an instance group, which can be returned by a query
+type Instance struct {
+	ID string
+	IP string
+	// AZIndex is the 0-based index of the AZ, like the az-index label of the pod
+	AZIndex int
+	// Index is the BOSH spec index of the instance
+	Index int
+	Ready bool
+}
+
+// Select returns the instances matching the filters of the query
+func (q Query) Select(instances []Instance) []Instance {
+	matching := []Instance{}
+	for _, instance := range instances {
+		if q.InstanceID != "" && q.InstanceID != instance.ID {
+			continue
+		}
+		if len(q.AZs) > 0 && !containsInt(q.AZs, instance.AZIndex+1) {
+			continue
+		}
+		if len(q.Indexes) > 0 && !containsInt(q.Indexes, instance.Index) {
+			continue
+		}
+		matching = append(matching, instance)
+	}
+
+	switch q.Status {
+	case StatusHealthy:
+		return selectReady(matching, true)
+	case StatusUnhealthy:
+		return selectReady(matching, false)
+	case StatusSmart:
+		if healthy := selectReady(matching, true); len(healthy) > 0 {
+			return healthy
+		}
+	}
+	return matching
+}
+
+func selectReady(instances []Instance, ready bool) []Instance {
+	selected := []Instance{}
+	for _, instance := range instances {
+		if instance.Ready == ready {
+			selected = append(selected, instance)
+		}
+	}
+	return selected
+}
+
+func containsInt(list []int, i int) bool {
+	for _, e := range list {
+		if e == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package boshdns_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+)
+
+var _ = Describe("Query", func() {
+	Context("ParseQuery", func() {
+		It("decodes the long form", func() {
+			q, err := boshdns.ParseQuery("q-a1a2i3s3.diego-cell.default.cf.bosh.")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(q.InstanceGroup).To(Equal("diego-cell"))
+			Expect(q.Network).To(Equal("default"))
+			Expect(q.Deployment).To(Equal("cf"))
+			Expect(q.AZs).To(Equal([]int{1, 2}))
+			Expect(q.Indexes).To(Equal([]int{3}))
+			Expect(q.Status).To(Equal(boshdns.StatusHealthy))
+		})
+
+		It("decodes the short form with a group", func() {
+			q, err := boshdns.ParseQuery("q-m1n2s4.q-g7.bosh")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(q.Group).To(Equal(7))
+			Expect(q.InstanceGroup).To(BeEmpty())
+			Expect(q.Status).To(Equal(boshdns.StatusAll))
+		})
+
+		It("decodes instance specific queries", func() {
+			q, err := boshdns.ParseQuery("nats-z0-1.nats.default.cf.bosh")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(q.InstanceID).To(Equal("nats-z0-1"))
+			Expect(q.Status).To(Equal(boshdns.StatusAll))
+		})
+
+		It("defaults to the smart status", func() {
+			q, err := boshdns.ParseQuery("q-i0.nats.default.cf.bosh")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(q.Status).To(Equal(boshdns.StatusSmart))
+		})
+
+		It("rejects invalid queries", func() {
+			for _, name := range []string{
+				"q-s0.nats.default.cf.internal",
+				"q-s0.nats.cf.bosh",
+				"q-s2.nats.default.cf.bosh",
+				"q-x1.nats.default.cf.bosh",
+				"q-a.nats.default.cf.bosh",
+				"q-s0.q-gx.bosh",
+				"nats.q-g1.bosh",
+			} {
+				_, err := boshdns.ParseQuery(name)
+				Expect(err).To(HaveOccurred(), name)
+			}
+		})
+	})
+
+	Context("Select", func() {
+		instances := []boshdns.Instance{
+			{ID: "nats-z0-0", IP: "10.0.0.1", AZIndex: 0, Index: 0, Ready: true},
+			{ID: "nats-z0-1", IP: "10.0.0.2", AZIndex: 0, Index: 1, Ready: false},
+			{ID: "nats-z1-0", IP: "10.0.1.1", AZIndex: 1, Index: 2, Ready: true},
+			{ID: "nats-z1-1", IP: "10.0.1.2", AZIndex: 1, Index: 3, Ready: false},
+		}
+
+		ips := func(query string) []string {
+			q, err := boshdns.ParseQuery(query)
+			Expect(err).NotTo(HaveOccurred())
+			result := []string{}
+			for _, i := range q.Select(instances) {
+				result = append(result, i.IP)
+			}
+			return result
+		}
+
+		It("filters by status", func() {
+			Expect(ips("q-s0.nats.default.cf.bosh")).To(Equal([]string{"10.0.0.1", "10.0.1.1"}))
+			Expect(ips("q-s1.nats.default.cf.bosh")).To(Equal([]string{"10.0.0.2", "10.0.1.2"}))
+			Expect(ips("q-s3.nats.default.cf.bosh")).To(Equal([]string{"10.0.0.1", "10.0.1.1"}))
+			Expect(ips("q-s4.nats.default.cf.bosh")).To(HaveLen(4))
+		})
+
+		It("falls back to all instances, if none is healthy", func() {
+			Expect(ips("q-i1s0.nats.default.cf.bosh")).To(Equal([]string{"10.0.0.2"}))
+			Expect(ips("q-i1s3.nats.default.cf.bosh")).To(BeEmpty())
+		})
+
+		It("filters by AZ and index", func() {
+			Expect(ips("q-a2s4.nats.default.cf.bosh")).To(Equal([]string{"10.0.1.1", "10.0.1.2"}))
+			Expect(ips("q-a1i1i2s4.nats.default.cf.bosh")).To(Equal([]string{"10.0.0.2"}))
+		})
+
+		It("selects an instance by id", func() {
+			Expect(ips("nats-z1-1.nats.default.cf.bosh")).To(Equal([]string{"10.0.1.2"}))
+		})
+	})
+})
@@ -0,0 +1,188 @@
+package boshdns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	utilnames "code.cloudfoundry.org/quarks-utils/pkg/names"
+)
+
+const queryConfigFile = "queries.json"
+
+// ErrUnknownQuery is returned for queries, which don't match an instance group
+var ErrUnknownQuery = errors.New("query does not match an instance group")
+
+// QueryConfig is the part of the deployment manifest, which is needed to
+// answer BOSH DNS queries
+type QueryConfig struct {
+	Deployment     string               `json:"deployment"`
+	InstanceGroups []QueryInstanceGroup `json:"instance_groups"`
+}
+
+// QueryInstanceGroup is an instance group, in the order of the manifest
+type QueryInstanceGroup struct {
+	Name      string   `json:"name"`
+	Instances int      `json:"instances"`
+	AZs       []string `json:"azs,omitempty"`
+}
+
+// instanceID returns the BOSH instance id, like in the job instances of the links
+func (ig QueryInstanceGroup) instanceID(azIndex int, ordinal int) string {
+	if len(ig.AZs) > 0 {
+		return fmt.Sprintf("%s-z%d-%d", utilnames.Sanitize(ig.Name), azIndex, ordinal)
+	}
+	return fmt.Sprintf("%s-%d", utilnames.Sanitize(ig.Name), ordinal)
+}
+
+// Resolver answers BOSH DNS queries from the endpoints of the instance group
+// services. Ready addresses are healthy instances, not ready addresses are
+// unhealthy instances.
+type Resolver struct {
+	config    QueryConfig
+	namespace string
+	client    client.Reader
+}
+
+// NewResolver returns a resolver for the instance groups of a deployment
+func NewResolver(config QueryConfig, namespace string, c client.Reader) *Resolver {
+	return &Resolver{
+		config:    config,
+		namespace: namespace,
+		client:    c,
+	}
+}
+
+// Resolve returns the IPs of the instances matching the query
+func (r *Resolver) Resolve(ctx context.Context, name string) ([]string, error) {
+	q, err := ParseQuery(name)
+	if err != nil {
+		return nil, errors.Wrap(ErrUnknownQuery, err.Error())
+	}
+
+	ig, ok := r.instanceGroup(q)
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownQuery, "no instance group for '%s'", name)
+	}
+
+	instances, err := r.instances(ctx, ig)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := []string{}
+	for _, instance := range q.Select(instances) {
+		ips = append(ips, instance.IP)
+	}
+	return ips, nil
+}
+
+func (r *Resolver) instanceGroup(q Query) (QueryInstanceGroup, bool) {
+	if q.Group > 0 && q.InstanceGroup == "" {
+		if q.Group > len(r.config.InstanceGroups) {
+			return QueryInstanceGroup{}, false
+		}
+		return r.config.InstanceGroups[q.Group-1], true
+	}
+
+	if q.Deployment != utilnames.Sanitize(r.config.Deployment) {
+		return QueryInstanceGroup{}, false
+	}
+	for _, ig := range r.config.InstanceGroups {
+		if q.InstanceGroup == utilnames.Sanitize(ig.Name) {
+			return ig, true
+		}
+	}
+	return QueryInstanceGroup{}, false
+}
+
+// instances returns the instances of the instance group, from the endpoints
+// of its headless service
+func (r *Resolver) instances(ctx context.Context, ig QueryInstanceGroup) ([]Instance, error) {
+	instances := []Instance{}
+
+	endpoints := &corev1.Endpoints{}
+	serviceName := names.ServiceName(r.config.Deployment, ig.Name)
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: r.namespace, Name: serviceName}, endpoints)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return instances, nil
+		}
+		return instances, errors.Wrapf(err, "failed to get endpoints '%s/%s'", r.namespace, serviceName)
+	}
+
+	pods := &corev1.PodList{}
+	err = r.client.List(ctx, pods,
+		client.InNamespace(r.namespace),
+		client.MatchingLabels{
+			bdv1.LabelDeploymentName:    r.config.Deployment,
+			bdv1.LabelInstanceGroupName: ig.Name,
+		},
+	)
+	if err != nil {
+		return instances, errors.Wrapf(err, "failed to list pods of instance group '%s'", ig.Name)
+	}
+
+	return InstancesFromEndpoints(ig, endpoints, pods.Items), nil
+}
+
+// InstancesFromEndpoints returns the instances for the addresses of the
+// endpoints. The AZ and BOSH spec index of an instance are taken from the
+// labels of its pod.
+func InstancesFromEndpoints(ig QueryInstanceGroup, endpoints *corev1.Endpoints, pods []corev1.Pod) []Instance {
+	podsByName := map[string]corev1.Pod{}
+	for _, pod := range pods {
+		podsByName[pod.Name] = pod
+	}
+
+	instances := []Instance{}
+	seen := map[string]bool{}
+	add := func(address corev1.EndpointAddress, ready bool) {
+		if address.TargetRef == nil || address.TargetRef.Kind != "Pod" {
+			return
+		}
+		pod, ok := podsByName[address.TargetRef.Name]
+		if !ok || seen[pod.Name] {
+			return
+		}
+		seen[pod.Name] = true
+		azIndex, err := strconv.Atoi(pod.Labels[qstsv1a1.LabelAZIndex])
+		if err != nil {
+			azIndex = 0
+		}
+		ordinal, err := strconv.Atoi(pod.Labels[qstsv1a1.LabelPodOrdinal])
+		if err != nil {
+			return
+		}
+
+		instances = append(instances, Instance{
+			ID:      ig.instanceID(azIndex, ordinal),
+			IP:      address.IP,
+			AZIndex: azIndex,
+			Index:   azIndex*ig.Instances + ordinal,
+			Ready:   ready,
+		})
+	}
+
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			add(address, true)
+		}
+		for _, address := range subset.NotReadyAddresses {
+			add(address, false)
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Index < instances[j].Index })
+	return instances
+}
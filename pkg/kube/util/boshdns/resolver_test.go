@@ -0,0 +1,162 @@
+package boshdns_test
+
+import (
+	"context"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/dns/dnsmessage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	qstsv1a1 "code.cloudfoundry.org/quarks-statefulset/pkg/kube/apis/quarksstatefulset/v1alpha1"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Resolver", func() {
+	var (
+		ctx      context.Context
+		resolver *boshdns.Resolver
+	)
+
+	pod := func(name, azIndex, ordinal string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels: map[string]string{
+					bdv1.LabelDeploymentName:    "cf",
+					bdv1.LabelInstanceGroupName: "nats",
+					qstsv1a1.LabelAZIndex:       azIndex,
+					qstsv1a1.LabelPodOrdinal:    ordinal,
+				},
+			},
+		}
+	}
+
+	address := func(ip, podName string) corev1.EndpointAddress {
+		return corev1.EndpointAddress{IP: ip, TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: podName}}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		config := boshdns.QueryConfig{
+			Deployment: "cf",
+			InstanceGroups: []boshdns.QueryInstanceGroup{
+				{Name: "api", Instances: 1},
+				{Name: "nats", Instances: 2, AZs: []string{"z1", "z2"}},
+			},
+		}
+		client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			pod("nats-z0-0", "0", "0"),
+			pod("nats-z0-1", "0", "1"),
+			pod("nats-z1-0", "1", "0"),
+			&corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Name: "cf-nats", Namespace: "default"},
+				Subsets: []corev1.EndpointSubset{{
+					Addresses:         []corev1.EndpointAddress{address("10.0.0.1", "nats-z0-0"), address("10.0.1.1", "nats-z1-0")},
+					NotReadyAddresses: []corev1.EndpointAddress{address("10.0.0.2", "nats-z0-1")},
+				}},
+			},
+		).Build()
+		resolver = boshdns.NewResolver(config, "default", client)
+	})
+
+	Context("Resolve", func() {
+		It("answers from the endpoints of the instance group", func() {
+			ips, err := resolver.Resolve(ctx, "q-s0.nats.default.cf.bosh.")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]string{"10.0.0.1", "10.0.1.1"}))
+
+			ips, err = resolver.Resolve(ctx, "q-s1.nats.default.cf.bosh.")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]string{"10.0.0.2"}))
+		})
+
+		It("uses the BOSH spec index across AZs", func() {
+			ips, err := resolver.Resolve(ctx, "q-i2s4.nats.default.cf.bosh.")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]string{"10.0.1.1"}))
+		})
+
+		It("looks up groups by their position in the manifest", func() {
+			ips, err := resolver.Resolve(ctx, "q-a1s4.q-g2.bosh.")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+		})
+
+		It("returns no addresses for instance groups without endpoints", func() {
+			ips, err := resolver.Resolve(ctx, "q-s0.api.default.cf.bosh.")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(BeEmpty())
+		})
+
+		It("fails for unknown instance groups", func() {
+			_, err := resolver.Resolve(ctx, "q-s0.router.default.cf.bosh.")
+			Expect(err).To(MatchError(ContainSubstring("query does not match an instance group")))
+
+			_, err = resolver.Resolve(ctx, "q-s0.nats.default.other.bosh.")
+			Expect(err).To(HaveOccurred())
+
+			_, err = resolver.Resolve(ctx, "q-s0.q-g3.bosh.")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Server", func() {
+		var server *boshdns.Server
+
+		BeforeEach(func() {
+			_, log := helper.NewTestLogger()
+			server = boshdns.NewServer(log, resolver, boshdns.QueryServerAddress)
+		})
+
+		request := func(name string, t dnsmessage.Type) []byte {
+			msg := dnsmessage.Message{
+				Header: dnsmessage.Header{ID: 42, RecursionDesired: true},
+				Questions: []dnsmessage.Question{
+					{Name: dnsmessage.MustNewName(name), Type: t, Class: dnsmessage.ClassINET},
+				},
+			}
+			packed, err := msg.Pack()
+			Expect(err).NotTo(HaveOccurred())
+			return packed
+		}
+
+		answer := func(req []byte) dnsmessage.Message {
+			resp, err := server.Answer(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			msg := dnsmessage.Message{}
+			Expect(msg.Unpack(resp)).To(Succeed())
+			return msg
+		}
+
+		It("answers A queries", func() {
+			msg := answer(request("q-s3.nats.default.cf.bosh.", dnsmessage.TypeA))
+			Expect(msg.ID).To(Equal(uint16(42)))
+			Expect(msg.RCode).To(Equal(dnsmessage.RCodeSuccess))
+			Expect(msg.Answers).To(HaveLen(2))
+
+			a, ok := msg.Answers[0].Body.(*dnsmessage.AResource)
+			Expect(ok).To(BeTrue())
+			Expect(net.IP(a.A[:]).String()).To(Equal("10.0.0.1"))
+		})
+
+		It("returns no answers for AAAA queries of IPv4 instances", func() {
+			msg := answer(request("q-s3.nats.default.cf.bosh.", dnsmessage.TypeAAAA))
+			Expect(msg.RCode).To(Equal(dnsmessage.RCodeSuccess))
+			Expect(msg.Answers).To(BeEmpty())
+		})
+
+		It("returns NXDOMAIN for unknown queries", func() {
+			msg := answer(request("q-s3.router.default.cf.bosh.", dnsmessage.TypeA))
+			Expect(msg.RCode).To(Equal(dnsmessage.RCodeNameError))
+		})
+	})
+})
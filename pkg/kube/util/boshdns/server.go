@@ -0,0 +1,233 @@
+package boshdns
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// QueryServerAddress is where the BOSH DNS query server listens, next to coredns
+	QueryServerAddress = "127.0.0.1:8054"
+	// answerTTL is short, so clients notice instances becoming unhealthy
+	answerTTL     = 5
+	maxUDPSize    = 512
+	tcpTimeout    = 10 * time.Second
+	maxPacketSize = 65535
+)
+
+// Server answers BOSH DNS queries for the 'bosh' domain over UDP and TCP.
+// Coredns forwards the domain to it.
+type Server struct {
+	log      *zap.SugaredLogger
+	resolver *Resolver
+	address  string
+}
+
+// NewServer returns a DNS server for the queries of the resolver
+func NewServer(log *zap.SugaredLogger, resolver *Resolver, address string) *Server {
+	return &Server{
+		log:      log,
+		resolver: resolver,
+		address:  address,
+	}
+}
+
+// ListenAndServe serves DNS requests until the context is done
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	udp, err := net.ListenPacket("udp", s.address)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on udp '%s'", s.address)
+	}
+	tcp, err := net.Listen("tcp", s.address)
+	if err != nil {
+		udp.Close()
+		return errors.Wrapf(err, "failed to listen on tcp '%s'", s.address)
+	}
+
+	go func() {
+		<-ctx.Done()
+		udp.Close()
+		tcp.Close()
+	}()
+
+	errs := make(chan error, 2)
+	go func() { errs <- s.serveUDP(ctx, udp) }()
+	go func() { errs <- s.serveTCP(ctx, tcp) }()
+
+	s.log.Infof("Serving BOSH DNS queries on '%s'", s.address)
+	err = <-errs
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) serveUDP(ctx context.Context, conn net.PacketConn) error {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return errors.Wrap(err, "failed to read udp request")
+		}
+
+		response, err := s.answer(ctx, buf[:n], maxUDPSize)
+		if err != nil {
+			s.log.Debugf("Dropping invalid request from '%s': %v", addr, err)
+			continue
+		}
+		if _, err := conn.WriteTo(response, addr); err != nil {
+			s.log.Errorf("Failed to write response to '%s': %v", addr, err)
+		}
+	}
+}
+
+func (s *Server) serveTCP(ctx context.Context, listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return errors.Wrap(err, "failed to accept tcp connection")
+		}
+		go s.handleTCP(ctx, conn)
+	}
+}
+
+// handleTCP answers the length prefixed requests of a connection
+func (s *Server) handleTCP(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		if err := conn.SetDeadline(time.Now().Add(tcpTimeout)); err != nil {
+			return
+		}
+
+		var length uint16
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return
+		}
+		request := make([]byte, length)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+
+		response, err := s.answer(ctx, request, maxPacketSize)
+		if err != nil {
+			s.log.Debugf("Dropping invalid request from '%s': %v", conn.RemoteAddr(), err)
+			return
+		}
+		if err := binary.Write(conn, binary.BigEndian, uint16(len(response))); err != nil {
+			return
+		}
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+// Answer returns the packed response to a packed DNS request
+func (s *Server) Answer(ctx context.Context, request []byte) ([]byte, error) {
+	return s.answer(ctx, request, maxPacketSize)
+}
+
+// answer truncates responses, which don't fit the size, so the client retries over TCP
+func (s *Server) answer(ctx context.Context, request []byte, size int) ([]byte, error) {
+	msg, err := s.handle(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := msg.Pack()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to pack response")
+	}
+	if len(response) > size {
+		msg.Truncated = true
+		msg.Answers = nil
+		return msg.Pack()
+	}
+	return response, nil
+}
+
+func (s *Server) handle(ctx context.Context, request []byte) (dnsmessage.Message, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(request)
+	if err != nil {
+		return dnsmessage.Message{}, errors.Wrap(err, "failed to parse header")
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return dnsmessage.Message{}, errors.Wrap(err, "failed to parse questions")
+	}
+
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               header.ID,
+			Response:         true,
+			OpCode:           header.OpCode,
+			Authoritative:    true,
+			RecursionDesired: header.RecursionDesired,
+		},
+		Questions: questions,
+	}
+	if header.Response || header.OpCode != 0 || len(questions) != 1 {
+		msg.RCode = dnsmessage.RCodeFormatError
+		return msg, nil
+	}
+
+	question := questions[0]
+	ips, err := s.resolver.Resolve(ctx, question.Name.String())
+	if err != nil {
+		if errors.Cause(err) == ErrUnknownQuery {
+			msg.RCode = dnsmessage.RCodeNameError
+			return msg, nil
+		}
+		s.log.Errorf("Failed to resolve '%s': %v", question.Name, err)
+		msg.RCode = dnsmessage.RCodeServerFailure
+		return msg, nil
+	}
+
+	for _, ip := range ips {
+		resource, ok := newResource(question, net.ParseIP(ip))
+		if ok {
+			msg.Answers = append(msg.Answers, resource)
+		}
+	}
+	return msg, nil
+}
+
+// newResource returns an A or AAAA record for the IP, if it matches the type of the question
+func newResource(question dnsmessage.Question, ip net.IP) (dnsmessage.Resource, bool) {
+	header := dnsmessage.ResourceHeader{
+		Name:  question.Name,
+		Class: dnsmessage.ClassINET,
+		TTL:   answerTTL,
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		if question.Type != dnsmessage.TypeA && question.Type != dnsmessage.TypeALL {
+			return dnsmessage.Resource{}, false
+		}
+		header.Type = dnsmessage.TypeA
+		r := &dnsmessage.AResource{}
+		copy(r.A[:], ip4)
+		return dnsmessage.Resource{Header: header, Body: r}, true
+	}
+
+	if ip16 := ip.To16(); ip16 != nil {
+		if question.Type != dnsmessage.TypeAAAA && question.Type != dnsmessage.TypeALL {
+			return dnsmessage.Resource{}, false
+		}
+		header.Type = dnsmessage.TypeAAAA
+		r := &dnsmessage.AAAAResource{}
+		copy(r.AAAA[:], ip16)
+		return dnsmessage.Resource{Header: header, Body: r}, true
+	}
+
+	return dnsmessage.Resource{}, false
+}
@@ -1,14 +1,19 @@
-// Package desiredmanifest retrieves the latest desired manifest
+// Package desiredmanifest retrieves the latest desired manifest and rolls
+// back to earlier versions
 package desiredmanifest
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 
 	"github.com/pkg/errors"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-utils/pkg/names"
 	"code.cloudfoundry.org/quarks-utils/pkg/versionedsecretstore"
 )
 
@@ -19,6 +24,13 @@ var (
 	Name = "desired-manifest"
 )
 
+// SecretName returns the name of the versioned desired manifest secret,
+// without the version suffix:
+// `<deployment-name>.desired-manifest`
+func SecretName(deploymentName string) string {
+	return names.SanitizeSubdomain(deploymentName + "." + Name)
+}
+
 // DesiredManifest resolves references from bdpl CRD to a BOSH manifest
 type DesiredManifest struct {
 	client               client.Client
@@ -35,18 +47,56 @@ func NewDesiredManifest(client client.Client) *DesiredManifest {
 
 // DesiredManifest reads the versioned secret created by the variable interpolation job
 // and unmarshals it into a Manifest object
-func (r *DesiredManifest) DesiredManifest(ctx context.Context, namespace string) (*bdm.Manifest, error) {
-	secret, err := r.versionedSecretStore.Latest(ctx, namespace, Name)
+func (r *DesiredManifest) DesiredManifest(ctx context.Context, deploymentName string, namespace string) (*bdm.Manifest, error) {
+	secretName := SecretName(deploymentName)
+	secret, err := r.versionedSecretStore.Latest(ctx, namespace, secretName)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to read latest versioned secret %s for bosh deployment in %s", Name, namespace)
+		return nil, errors.Wrapf(err, "failed to read latest versioned secret %s for bosh deployment '%s' in %s", secretName, deploymentName, namespace)
 	}
 
 	manifestData := secret.Data["manifest.yaml"]
 
 	manifest, err := bdm.LoadYAML(manifestData)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to unmarshal manifest from secret %s for boshdeployment in %s", Name, namespace)
+		return nil, errors.Wrapf(err, "failed to unmarshal manifest from secret %s for boshdeployment '%s' in %s", secretName, deploymentName, namespace)
 	}
 
 	return manifest, nil
 }
+
+// Rollback creates a new version of the desired manifest secret from the
+// content of an earlier version. The new version is picked up by the instance
+// group job, which renders the instance groups of the earlier manifest again.
+// It returns false if the latest version already has the same content.
+func (r *DesiredManifest) Rollback(ctx context.Context, bdpl *bdv1.BOSHDeployment, version int) (bool, error) {
+	secretName := SecretName(bdpl.Name)
+	secret, err := r.versionedSecretStore.Get(ctx, bdpl.Namespace, secretName, version)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read version %d of desired manifest secret %s for bosh deployment '%s' in %s", version, secretName, bdpl.Name, bdpl.Namespace)
+	}
+
+	latest, err := r.versionedSecretStore.Latest(ctx, bdpl.Namespace, secretName)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read latest versioned secret %s for bosh deployment '%s' in %s", secretName, bdpl.Name, bdpl.Namespace)
+	}
+	if reflect.DeepEqual(secret.Data, latest.Data) {
+		return false, nil
+	}
+
+	data := map[string]string{}
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	labels := map[string]string{
+		bdv1.LabelDeploymentName:       bdpl.Name,
+		bdv1.LabelDeploymentSecretType: bdv1.DeploymentSecretTypeDesiredManifest.String(),
+	}
+	sourceDescription := fmt.Sprintf("rollback to version %d by quarksOperator", version)
+
+	err = r.versionedSecretStore.Create(ctx, bdpl.Namespace, bdpl.Name, bdpl.GetUID(), bdpl.Kind, secretName, data, map[string]string{}, labels, sourceDescription)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to create desired manifest secret %s from version %d for bosh deployment '%s' in %s", secretName, version, bdpl.Name, bdpl.Namespace)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,287 @@
+// Package gitrepo reads files from git repositories for BOSHDeployment git references.
+// It uses the git binary, which has to be available in the PATH of the operator.
+// Credentials require git 2.31 or newer, which reads config from the environment.
+package gitrepo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+// DefaultRef is used if a git reference doesn't specify a ref
+const DefaultRef = "HEAD"
+
+// CacheMaxAge is the time after which an unused local repository is removed from the cache
+const CacheMaxAge = 24 * time.Hour
+
+var commitRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Credentials for a git repository. The 'username' and 'password' keys are
+// used for basic auth. Keys prefixed with 'header-' are sent as HTTP headers
+// without the prefix, all other keys are ignored.
+type Credentials map[string][]byte
+
+// Repositories fetches commits into local bare repositories, which are kept
+// as a cache below the base dir
+type Repositories struct {
+	// AllowFileProtocol allows local paths as repository URLs. It's only meant for tests.
+	AllowFileProtocol bool
+
+	baseDir   string
+	mu        sync.Mutex
+	locks     map[string]*sync.Mutex
+	lastPrune time.Time
+
+	versionOnce sync.Once
+	versionErr  error
+}
+
+// NewRepositories returns a new Repositories, which stores the local repositories in baseDir
+func NewRepositories(baseDir string) *Repositories {
+	return &Repositories{baseDir: baseDir, locks: map[string]*sync.Mutex{}}
+}
+
+// ValidateURL checks that the URL of a git repository uses https. Other
+// transports are rejected, since the credentials only support HTTP auth.
+func ValidateURL(url string) error {
+	if strings.HasPrefix(url, "https://") {
+		return nil
+	}
+	return errors.Errorf("git repository URL '%s' has to use https://", url)
+}
+
+func (r *Repositories) validateURL(url string) error {
+	if r.AllowFileProtocol && !strings.HasPrefix(url, "-") {
+		return nil
+	}
+	return ValidateURL(url)
+}
+
+// ResolveCommit returns the commit SHA the ref points to in the remote repository
+func (r *Repositories) ResolveCommit(ctx context.Context, url string, ref string, creds Credentials) (string, error) {
+	if err := r.validateURL(url); err != nil {
+		return "", err
+	}
+	if ref == "" {
+		ref = DefaultRef
+	}
+	if strings.HasPrefix(ref, "-") {
+		return "", errors.Errorf("invalid ref '%s' for git repository '%s'", ref, url)
+	}
+	if commitRegexp.MatchString(ref) {
+		return ref, nil
+	}
+
+	// The peeled commit of annotated tags is only listed with its own pattern
+	out, err := r.git(ctx, "", creds, "ls-remote", "--", url, ref, ref+"^{}")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list ref '%s' of git repository '%s'", ref, url)
+	}
+
+	commit := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		// Prefer the peeled commit of annotated tags
+		if fields[1] == ref || fields[1] == "refs/heads/"+ref || fields[1] == "refs/tags/"+ref+"^{}" {
+			commit = fields[0]
+		}
+		if commit == "" && fields[1] == "refs/tags/"+ref {
+			commit = fields[0]
+		}
+	}
+	if commit == "" {
+		return "", errors.Errorf("ref '%s' not found in git repository '%s'", ref, url)
+	}
+
+	return commit, nil
+}
+
+// ReadFile returns the content of the file at path in the given commit
+func (r *Repositories) ReadFile(ctx context.Context, url string, commit string, path string, creds Credentials) ([]byte, error) {
+	if err := r.validateURL(url); err != nil {
+		return nil, err
+	}
+	if !commitRegexp.MatchString(commit) {
+		return nil, errors.Errorf("invalid commit '%s' for git repository '%s'", commit, url)
+	}
+
+	r.prune()
+
+	dir := filepath.Join(r.baseDir, repoDirName(url))
+
+	lock := r.lock(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if _, err := r.git(ctx, "", nil, "init", "--bare", "--quiet", "--", dir); err != nil {
+			return nil, errors.Wrapf(err, "failed to create local repository for '%s'", url)
+		}
+	}
+
+	object := fmt.Sprintf("%s:%s", commit, strings.TrimPrefix(path, "/"))
+	if _, err := r.git(ctx, dir, nil, "cat-file", "-e", commit); err != nil {
+		if _, err := r.git(ctx, dir, creds, "fetch", "--quiet", "--depth", "1", "--", url, commit); err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch commit '%s' of git repository '%s'", commit, url)
+		}
+	}
+
+	out, err := r.git(ctx, dir, nil, "show", object)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read '%s' from commit '%s' of git repository '%s'", path, commit, url)
+	}
+
+	// The modification time of the local repository tracks its last use
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+
+	return out, nil
+}
+
+// prune removes local repositories, which weren't used for CacheMaxAge.
+// It runs at most once per hour.
+func (r *Repositories) prune() {
+	r.mu.Lock()
+	if time.Since(r.lastPrune) < time.Hour {
+		r.mu.Unlock()
+		return
+	}
+	r.lastPrune = time.Now()
+	r.mu.Unlock()
+
+	entries, err := ioutil.ReadDir(r.baseDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || time.Since(entry.ModTime()) < CacheMaxAge {
+			continue
+		}
+
+		dir := filepath.Join(r.baseDir, entry.Name())
+		lock := r.lock(dir)
+		lock.Lock()
+		// The repository could have been used while waiting for the lock
+		if info, err := os.Stat(dir); err == nil && time.Since(info.ModTime()) >= CacheMaxAge {
+			_ = os.RemoveAll(dir)
+		}
+		lock.Unlock()
+	}
+}
+
+func (r *Repositories) lock(dir string) *sync.Mutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.locks[dir]; !ok {
+		r.locks[dir] = &sync.Mutex{}
+	}
+	return r.locks[dir]
+}
+
+func repoDirName(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// git runs a git command. Credentials are passed as extra HTTP headers via
+// the environment, so they are neither stored in the local repository nor
+// visible in the arguments of the process.
+func (r *Repositories) git(ctx context.Context, dir string, creds Credentials, args ...string) ([]byte, error) {
+	config := []string{}
+	if !r.AllowFileProtocol {
+		config = append(config, "-c", "protocol.file.allow=never")
+	}
+
+	cmd := exec.CommandContext(ctx, "git", append(config, args...)...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	headers := httpHeaders(creds)
+	if len(headers) > 0 {
+		if err := r.checkVersion(ctx); err != nil {
+			return nil, err
+		}
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", len(headers)))
+		for i, header := range headers {
+			cmd.Env = append(cmd.Env,
+				fmt.Sprintf("GIT_CONFIG_KEY_%d=http.extraHeader", i),
+				fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, header),
+			)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// checkVersion fails if git is too old to read config from the environment,
+// which would silently drop the credentials
+func (r *Repositories) checkVersion(ctx context.Context) error {
+	r.versionOnce.Do(func() {
+		out, err := exec.CommandContext(ctx, "git", "version").Output()
+		if err != nil {
+			r.versionErr = errors.Wrap(err, "failed to get git version")
+			return
+		}
+
+		var major, minor int
+		if _, err := fmt.Sscanf(string(out), "git version %d.%d", &major, &minor); err != nil {
+			r.versionErr = errors.Wrapf(err, "failed to parse git version '%s'", strings.TrimSpace(string(out)))
+			return
+		}
+		if major < 2 || (major == 2 && minor < 31) {
+			r.versionErr = errors.Errorf("git credentials require git 2.31 or newer, found '%s'", strings.TrimSpace(string(out)))
+		}
+	})
+	return r.versionErr
+}
+
+// httpHeaders returns the HTTP headers for the credentials, sorted by key
+func httpHeaders(creds Credentials) []string {
+	keys := make([]string, 0, len(creds))
+	for k := range creds {
+		if strings.HasPrefix(k, bdv1.CredentialsHeaderPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	headers := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		headers = append(headers, fmt.Sprintf("%s: %s", strings.TrimPrefix(k, bdv1.CredentialsHeaderPrefix), creds[k]))
+	}
+	if username, ok := creds["username"]; ok {
+		auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, creds["password"])))
+		headers = append(headers, fmt.Sprintf("Authorization: Basic %s", auth))
+	}
+	return headers
+}
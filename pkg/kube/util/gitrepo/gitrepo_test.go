@@ -0,0 +1,214 @@
+package gitrepo_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/gitrepo"
+)
+
+var _ = Describe("Repositories", func() {
+	var (
+		ctx      context.Context
+		tmpDir   string
+		workDir  string
+		remote   string
+		repos    *gitrepo.Repositories
+		gitInDir func(dir string, args ...string) string
+		commit   func(content string) string
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		tmpDir, err = ioutil.TempDir("", "gitrepo")
+		Expect(err).ToNot(HaveOccurred())
+
+		gitInDir = func(dir string, args ...string) string {
+			args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			Expect(err).ToNot(HaveOccurred(), string(out))
+			return strings.TrimSpace(string(out))
+		}
+		commit = func(content string) string {
+			err := ioutil.WriteFile(filepath.Join(workDir, "manifest.yml"), []byte(content), 0644)
+			Expect(err).ToNot(HaveOccurred())
+			gitInDir(workDir, "add", "manifest.yml")
+			gitInDir(workDir, "commit", "--quiet", "-m", content)
+			gitInDir(workDir, "push", "--quiet", "origin", "main")
+			return gitInDir(workDir, "rev-parse", "HEAD")
+		}
+
+		remote = filepath.Join(tmpDir, "remote.git")
+		workDir = filepath.Join(tmpDir, "work")
+		gitInDir(tmpDir, "init", "--quiet", "--bare", remote)
+		gitInDir(tmpDir, "clone", "--quiet", remote, workDir)
+
+		repos = gitrepo.NewRepositories(filepath.Join(tmpDir, "cache"))
+		repos.AllowFileProtocol = true
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Describe("ResolveCommit", func() {
+		var first string
+
+		BeforeEach(func() {
+			first = commit("first")
+		})
+
+		It("resolves HEAD by default", func() {
+			sha, err := repos.ResolveCommit(ctx, remote, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sha).To(Equal(first))
+		})
+
+		It("resolves branches", func() {
+			second := commit("second")
+
+			sha, err := repos.ResolveCommit(ctx, remote, "main", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sha).To(Equal(second))
+		})
+
+		It("resolves annotated tags to the commit", func() {
+			gitInDir(workDir, "tag", "-a", "v1", "-m", "v1")
+			gitInDir(workDir, "push", "--quiet", "origin", "v1")
+			commit("second")
+
+			sha, err := repos.ResolveCommit(ctx, remote, "v1", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sha).To(Equal(first))
+		})
+
+		It("returns commit SHAs unchanged", func() {
+			sha, err := repos.ResolveCommit(ctx, remote, first, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sha).To(Equal(first))
+		})
+
+		It("fails for unknown refs", func() {
+			_, err := repos.ResolveCommit(ctx, remote, "unknown", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("ref 'unknown' not found"))
+		})
+
+		It("rejects refs, which look like git options", func() {
+			_, err := repos.ResolveCommit(ctx, remote, "--upload-pack=touch /tmp/pwned", nil)
+			Expect(err).To(MatchError(ContainSubstring("invalid ref")))
+		})
+
+		It("sends the credentials as HTTP headers", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				user, pass, ok := req.BasicAuth()
+				if !ok || user != "user" || pass != "pass" || req.Header.Get("X-Token") != "token" || req.Header.Get("Ignored") != "" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				// a dumb HTTP server only lists the refs
+				_, _ = w.Write([]byte(first + "\trefs/heads/main\n"))
+			}))
+			defer server.Close()
+
+			creds := gitrepo.Credentials{
+				"username":       []byte("user"),
+				"password":       []byte("pass"),
+				"header-X-Token": []byte("token"),
+				"ignored":        []byte("value"),
+			}
+			sha, err := repos.ResolveCommit(ctx, server.URL+"/repo.git", "main", creds)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sha).To(Equal(first))
+
+			_, err = repos.ResolveCommit(ctx, server.URL+"/repo.git", "main", nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects local repositories by default", func() {
+			repos = gitrepo.NewRepositories(filepath.Join(tmpDir, "cache"))
+
+			_, err := repos.ResolveCommit(ctx, remote, "main", nil)
+			Expect(err).To(MatchError(ContainSubstring("has to use https://")))
+
+			_, err = repos.ResolveCommit(ctx, "file://"+remote, "main", nil)
+			Expect(err).To(MatchError(ContainSubstring("has to use https://")))
+		})
+	})
+
+	Describe("ReadFile", func() {
+		It("reads the file of the given commit", func() {
+			first := commit("first")
+			second := commit("second")
+
+			data, err := repos.ReadFile(ctx, remote, second, "manifest.yml", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("second"))
+
+			data, err = repos.ReadFile(ctx, remote, first, "/manifest.yml", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("first"))
+		})
+
+		It("removes unused local repositories from the cache", func() {
+			stale := filepath.Join(tmpDir, "cache", "stale")
+			Expect(os.MkdirAll(stale, 0755)).To(Succeed())
+			old := time.Now().Add(-gitrepo.CacheMaxAge - time.Minute)
+			Expect(os.Chtimes(stale, old, old)).To(Succeed())
+
+			_, err := repos.ReadFile(ctx, remote, commit("first"), "manifest.yml", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = os.Stat(stale)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("rejects invalid commits", func() {
+			_, err := repos.ReadFile(ctx, remote, "--output=/tmp/pwned", "manifest.yml", nil)
+			Expect(err).To(MatchError(ContainSubstring("invalid commit")))
+		})
+
+		It("fails for missing files", func() {
+			sha := commit("first")
+
+			_, err := repos.ReadFile(ctx, remote, sha, "missing.yml", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to read 'missing.yml'"))
+		})
+	})
+
+	Describe("ValidateURL", func() {
+		It("accepts https URLs", func() {
+			Expect(gitrepo.ValidateURL("https://github.com/cloudfoundry-incubator/quarks-operator.git")).To(Succeed())
+		})
+
+		It("rejects other URLs and git options", func() {
+			for _, url := range []string{
+				"--upload-pack=touch /tmp/pwned",
+				"-c",
+				"http://github.com/cloudfoundry-incubator/quarks-operator.git",
+				"ssh://git@github.com/cloudfoundry-incubator/quarks-operator.git",
+				"git@github.com:cloudfoundry-incubator/quarks-operator.git",
+				"file:///etc",
+				"ext::sh -c touch% /tmp/pwned",
+				"/tmp/repo.git",
+				"C:/repo.git",
+			} {
+				Expect(gitrepo.ValidateURL(url)).NotTo(Succeed(), "url %s", url)
+			}
+		})
+	})
+})
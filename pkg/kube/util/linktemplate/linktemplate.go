@@ -0,0 +1,261 @@
+// Package linktemplate renders Go templates with the properties of the quarks
+// links mounted on an entangled pod
+package linktemplate
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// LinksDir is where the link secrets are mounted on entangled pods
+	LinksDir = "/quarks/link"
+	// TemplatesDir is where the templates config map is mounted on entangled pods
+	TemplatesDir = "/quarks/link-templates"
+	// RenderedDir is where the rendered templates are written to, on a volume shared with all containers
+	RenderedDir = "/quarks/link-rendered"
+)
+
+// Data is passed to the templates
+type Data struct {
+	// Links maps the '<type>-<name>' key of each link to its properties
+	Links map[string]map[string]string
+	// Deployments maps the deployment name to the links it provides
+	Deployments map[string]map[string]map[string]string
+}
+
+// funcs returns the template functions, 'link' returns a property of a link
+// and fails, unlike 'index', if the link or property doesn't exist:
+// `{{ link "nats-nats" "nats.user" }}`
+func (d Data) funcs() template.FuncMap {
+	return template.FuncMap{
+		"link": func(key string, property string) (string, error) {
+			properties, ok := d.Links[key]
+			if !ok {
+				return "", errors.Errorf("link '%s' is not mounted", key)
+			}
+			value, ok := properties[property]
+			if !ok {
+				return "", errors.Errorf("link '%s' has no property '%s'", key, property)
+			}
+			return value, nil
+		},
+	}
+}
+
+// Renderer renders the templates of a directory into the output directory
+type Renderer struct {
+	log          *zap.SugaredLogger
+	linksDir     string
+	templatesDir string
+	outputDir    string
+}
+
+// NewRenderer returns a renderer for the links mounted below linksDir, in
+// the '<deployment>/<type>-<name>/<property>' layout of the pod mutator
+func NewRenderer(log *zap.SugaredLogger, linksDir, templatesDir, outputDir string) *Renderer {
+	return &Renderer{
+		log:          log,
+		linksDir:     linksDir,
+		templatesDir: templatesDir,
+		outputDir:    outputDir,
+	}
+}
+
+// Render renders each file of the templates directory into a file of the
+// same name in the output directory
+func (r *Renderer) Render() error {
+	data, err := r.readLinks()
+	if err != nil {
+		return err
+	}
+
+	files, err := visibleEntries(r.templatesDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list templates in '%s'", r.templatesDir)
+	}
+
+	for _, name := range files {
+		path := filepath.Join(r.templatesDir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to stat template '%s'", path)
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read template '%s'", path)
+		}
+		tmpl, err := template.New(name).Option("missingkey=error").Funcs(data.funcs()).Parse(string(text))
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse template '%s'", path)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return errors.Wrapf(err, "failed to render template '%s'", path)
+		}
+
+		if err := writeFile(filepath.Join(r.outputDir, name), buf.Bytes()); err != nil {
+			return err
+		}
+		r.log.Infof("Rendered '%s'", filepath.Join(r.outputDir, name))
+	}
+
+	return nil
+}
+
+// Watch renders the templates whenever the kubelet updates the mounted link
+// secrets or templates, until the context is done
+func (r *Renderer) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "failed to create file watcher")
+	}
+	defer watcher.Close()
+
+	dirs, err := r.linkDirs()
+	if err != nil {
+		return err
+	}
+	for _, dir := range append(dirs, r.templatesDir) {
+		if err := watcher.Add(dir); err != nil {
+			return errors.Wrapf(err, "failed to watch '%s'", dir)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// Mounted volumes are updated by atomically replacing the '..data' symlink
+			if filepath.Base(event.Name) != "..data" {
+				continue
+			}
+			r.log.Debugf("Files of '%s' changed", filepath.Dir(event.Name))
+			if err := r.Render(); err != nil {
+				r.log.Errorf("Failed to render link templates: %v", err)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			r.log.Errorf("Failed to watch link files: %v", err)
+		}
+	}
+}
+
+func (r *Renderer) readLinks() (Data, error) {
+	data := Data{
+		Links:       map[string]map[string]string{},
+		Deployments: map[string]map[string]map[string]string{},
+	}
+
+	dirs, err := r.linkDirs()
+	if err != nil {
+		return data, err
+	}
+
+	for _, dir := range dirs {
+		key := filepath.Base(dir)
+		deployment := filepath.Base(filepath.Dir(dir))
+
+		names, err := visibleEntries(dir)
+		if err != nil {
+			return data, errors.Wrapf(err, "failed to list link properties in '%s'", dir)
+		}
+
+		properties := map[string]string{}
+		for _, name := range names {
+			value, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return data, errors.Wrapf(err, "failed to read link property '%s'", filepath.Join(dir, name))
+			}
+			properties[name] = string(value)
+		}
+
+		data.Links[key] = properties
+		if _, ok := data.Deployments[deployment]; !ok {
+			data.Deployments[deployment] = map[string]map[string]string{}
+		}
+		data.Deployments[deployment][key] = properties
+	}
+
+	return data, nil
+}
+
+// linkDirs returns the '<deployment>/<type>-<name>' directories of the mounted link secrets
+func (r *Renderer) linkDirs() ([]string, error) {
+	dirs := []string{}
+
+	deployments, err := visibleEntries(r.linksDir)
+	if err != nil {
+		return dirs, errors.Wrapf(err, "failed to list links in '%s'", r.linksDir)
+	}
+	for _, deployment := range deployments {
+		links, err := visibleEntries(filepath.Join(r.linksDir, deployment))
+		if err != nil {
+			return dirs, errors.Wrapf(err, "failed to list links of deployment '%s'", deployment)
+		}
+		for _, link := range links {
+			dirs = append(dirs, filepath.Join(r.linksDir, deployment, link))
+		}
+	}
+	return dirs, nil
+}
+
+// visibleEntries lists a directory without the hidden '..' entries, which
+// the kubelet uses for atomic updates of mounted volumes
+func visibleEntries(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), "..") {
+			continue
+		}
+		names = append(names, info.Name())
+	}
+	return names, nil
+}
+
+// writeFile replaces the file atomically, so the application never reads a
+// partially rendered file
+func writeFile(path string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temporary file for '%s'", path)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to write '%s'", tmp.Name())
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close '%s'", tmp.Name())
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return errors.Wrapf(err, "failed to change mode of '%s'", tmp.Name())
+	}
+	return errors.Wrapf(os.Rename(tmp.Name(), path), "failed to replace '%s'", path)
+}
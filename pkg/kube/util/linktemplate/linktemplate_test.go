@@ -0,0 +1,122 @@
+package linktemplate_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/linktemplate"
+	helper "code.cloudfoundry.org/quarks-utils/testing/testhelper"
+)
+
+var _ = Describe("Renderer", func() {
+	var (
+		baseDir      string
+		linksDir     string
+		templatesDir string
+		outputDir    string
+		renderer     *linktemplate.Renderer
+	)
+
+	writeFile := func(path string, content string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	readFile := func(path string) string {
+		content, err := ioutil.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		return string(content)
+	}
+
+	BeforeEach(func() {
+		var err error
+		baseDir, err = ioutil.TempDir("", "linktemplate")
+		Expect(err).ToNot(HaveOccurred())
+
+		linksDir = filepath.Join(baseDir, "link")
+		templatesDir = filepath.Join(baseDir, "templates")
+		outputDir = filepath.Join(baseDir, "rendered")
+		Expect(os.MkdirAll(templatesDir, 0755)).To(Succeed())
+		Expect(os.MkdirAll(outputDir, 0755)).To(Succeed())
+
+		writeFile(filepath.Join(linksDir, "nats-deployment", "nats-nats", "nats.user"), "admin")
+		writeFile(filepath.Join(linksDir, "nats-deployment", "nats-nats", "nats.password"), "secret")
+		writeFile(filepath.Join(linksDir, "nats-deployment", "nats-nats", "..data", "nats.user"), "hidden")
+		writeFile(filepath.Join(templatesDir, "config.json"), `{"user":"{{ link "nats-nats" "nats.user" }}","password":"{{ index .Deployments "nats-deployment" "nats-nats" "nats.password" }}"}`)
+
+		_, log := helper.NewTestLogger()
+		renderer = linktemplate.NewRenderer(log, linksDir, templatesDir, outputDir)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(baseDir)).To(Succeed())
+	})
+
+	It("renders the templates with the link properties", func() {
+		Expect(renderer.Render()).To(Succeed())
+		Expect(readFile(filepath.Join(outputDir, "config.json"))).To(Equal(`{"user":"admin","password":"secret"}`))
+	})
+
+	It("skips the hidden files of mounted volumes", func() {
+		writeFile(filepath.Join(templatesDir, "..data", "ignored"), "{{ .Missing }}")
+		Expect(renderer.Render()).To(Succeed())
+
+		entries, err := ioutil.ReadDir(outputDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	Context("when a template uses a missing link", func() {
+		BeforeEach(func() {
+			writeFile(filepath.Join(templatesDir, "config.json"), `{{ link "nats-nuts" "nats.user" }}`)
+		})
+
+		It("fails", func() {
+			err := renderer.Render()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("link 'nats-nuts' is not mounted"))
+		})
+	})
+
+	Context("when a template uses a missing property", func() {
+		BeforeEach(func() {
+			writeFile(filepath.Join(templatesDir, "config.json"), `{{ link "nats-nats" "nats.port" }}`)
+		})
+
+		It("fails", func() {
+			err := renderer.Render()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("link 'nats-nats' has no property 'nats.port'"))
+		})
+	})
+
+	It("re-renders when the mounted link secret is updated", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		Expect(renderer.Render()).To(Succeed())
+		done := make(chan error)
+		go func() { done <- renderer.Watch(ctx) }()
+		// give the watcher time to register the directories
+		time.Sleep(100 * time.Millisecond)
+
+		// simulate the kubelet's atomic update of the secret volume
+		writeFile(filepath.Join(linksDir, "nats-deployment", "nats-nats", "nats.user"), "operator")
+		dataDir := filepath.Join(linksDir, "nats-deployment", "nats-nats", "..data")
+		Expect(os.Rename(dataDir, dataDir+"_old")).To(Succeed())
+		Expect(os.Rename(dataDir+"_old", dataDir)).To(Succeed())
+
+		Eventually(func() string {
+			return readFile(filepath.Join(outputDir, "config.json"))
+		}).Should(Equal(`{"user":"operator","password":"secret"}`))
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
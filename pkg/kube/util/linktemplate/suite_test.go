@@ -0,0 +1,13 @@
+package linktemplate_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLinkTemplate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Link Template Suite")
+}
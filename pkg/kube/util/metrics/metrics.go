@@ -0,0 +1,105 @@
+// Package metrics contains the prometheus metrics of the operator. They are
+// registered with the controller-runtime registry, which is served by the
+// manager's metrics endpoint together with the default controller metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	namespace = "quarks"
+	subsystem = "boshdeployment"
+
+	// ManifestWithOps is the manifest label value for the with-ops manifest
+	ManifestWithOps = "with-ops"
+	// ManifestDesired is the manifest label value for the desired manifest
+	ManifestDesired = "desired"
+
+	// ControllerDeployment is the controller label value for the BOSHDeployment controller
+	ControllerDeployment = "boshdeployment"
+	// ControllerWithOps is the controller label value for the with-ops secret controller
+	ControllerWithOps = "with-ops"
+	// ControllerBPM is the controller label value for the BPM secret controller
+	ControllerBPM = "bpm"
+)
+
+var (
+	// ManifestResolutionDuration is the latency of resolving the with-ops and the desired manifest
+	ManifestResolutionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "manifest_resolution_duration_seconds",
+			Help:      "Time it takes to resolve the with-ops and the desired manifest of a BOSHDeployment.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"manifest"},
+	)
+
+	// OpsErrors counts the ops files, which could not be applied to a manifest
+	OpsErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "ops_errors_total",
+			Help:      "Number of errors while applying ops files to the manifest of a BOSHDeployment.",
+		},
+		[]string{"namespace", "deployment"},
+	)
+
+	// QuarksSecrets is the number of QuarksSecrets created for the explicit variables of a deployment
+	QuarksSecrets = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "quarks_secrets",
+			Help:      "Number of QuarksSecrets for the explicit variables of a BOSHDeployment.",
+		},
+		[]string{"namespace", "deployment"},
+	)
+
+	// BPMRenders counts how often the resources of an instance group were rendered from its BPM secret
+	BPMRenders = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "bpm_renders_total",
+			Help:      "Number of times the resources of an instance group were rendered from its BPM configuration.",
+		},
+		[]string{"namespace", "deployment", "instance_group"},
+	)
+
+	// MeltdownHits counts the reconciles, which were skipped because of an active meltdown window
+	MeltdownHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "meltdown_hits_total",
+			Help:      "Number of reconciles skipped during a meltdown window.",
+		},
+		[]string{"controller"},
+	)
+
+	// ValidationDenials counts the BOSHDeployments rejected by the validating webhook
+	ValidationDenials = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "validation_denials_total",
+			Help:      "Number of BOSHDeployments denied by the validating webhook.",
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(
+		ManifestResolutionDuration,
+		OpsErrors,
+		QuarksSecrets,
+		BPMRenders,
+		MeltdownHits,
+		ValidationDenials,
+	)
+}
@@ -0,0 +1,63 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/metrics"
+)
+
+var _ = Describe("Metrics", func() {
+	var server *httptest.Server
+
+	scrape := func() string {
+		resp, err := http.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		server = httptest.NewServer(promhttp.HandlerFor(crmetrics.Registry, promhttp.HandlerOpts{}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("serves the operator metrics from the controller-runtime registry", func() {
+		metrics.ManifestResolutionDuration.WithLabelValues(metrics.ManifestWithOps).Observe(0.2)
+		metrics.OpsErrors.WithLabelValues("default", "nats").Inc()
+		metrics.QuarksSecrets.WithLabelValues("default", "nats").Set(3)
+		metrics.BPMRenders.WithLabelValues("default", "nats", "nats").Inc()
+		metrics.MeltdownHits.WithLabelValues(metrics.ControllerBPM).Inc()
+		metrics.ValidationDenials.WithLabelValues("ManifestError").Inc()
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`quarks_boshdeployment_manifest_resolution_duration_seconds_count{manifest="with-ops"} 1`))
+		Expect(body).To(ContainSubstring(`quarks_boshdeployment_ops_errors_total{deployment="nats",namespace="default"} 1`))
+		Expect(body).To(ContainSubstring(`quarks_boshdeployment_quarks_secrets{deployment="nats",namespace="default"} 3`))
+		Expect(body).To(ContainSubstring(`quarks_boshdeployment_bpm_renders_total{deployment="nats",instance_group="nats",namespace="default"} 1`))
+		Expect(body).To(ContainSubstring(`quarks_meltdown_hits_total{controller="bpm"} 1`))
+		Expect(body).To(ContainSubstring(`quarks_boshdeployment_validation_denials_total{reason="ManifestError"} 1`))
+	})
+
+	It("removes the QuarksSecrets gauge of deleted deployments", func() {
+		metrics.QuarksSecrets.WithLabelValues("default", "deleted").Set(1)
+		Expect(scrape()).To(ContainSubstring(`quarks_boshdeployment_quarks_secrets{deployment="deleted",namespace="default"} 1`))
+
+		metrics.QuarksSecrets.DeleteLabelValues("default", "deleted")
+		Expect(scrape()).ToNot(ContainSubstring(`deployment="deleted"`))
+	})
+})
@@ -3,6 +3,7 @@ package mutate
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	qjv1a1 "code.cloudfoundry.org/quarks-job/pkg/kube/apis/quarksjob/v1alpha1"
@@ -73,3 +74,16 @@ func ServiceMutateFn(svc *corev1.Service) controllerutil.MutateFn {
 		return nil
 	}
 }
+
+// NetworkPolicyMutateFn returns MutateFn which mutates NetworkPolicy including:
+// - labels, annotations
+// - spec
+func NetworkPolicyMutateFn(policy *networkingv1.NetworkPolicy) controllerutil.MutateFn {
+	updated := policy.DeepCopy()
+	return func() error {
+		policy.Labels = updated.Labels
+		policy.Annotations = updated.Annotations
+		policy.Spec = updated.Spec
+		return nil
+	}
+}
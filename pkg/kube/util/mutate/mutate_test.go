@@ -8,6 +8,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -400,4 +401,70 @@ var _ = Describe("Mutate", func() {
 			})
 		})
 	})
+
+	Describe("NetworkPolicyMutateFn", func() {
+		var (
+			policy *networkingv1.NetworkPolicy
+		)
+
+		BeforeEach(func() {
+			policy = &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "default",
+				},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"foo": "bar",
+						},
+					},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				},
+			}
+		})
+
+		Context("when the network policy is not found", func() {
+			It("creates the network policy", func() {
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object crc.Object) error {
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+
+				ops, err := controllerutil.CreateOrUpdate(ctx, client, policy, mutate.NetworkPolicyMutateFn(policy))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ops).To(Equal(controllerutil.OperationResultCreated))
+			})
+		})
+
+		Context("when the network policy is found", func() {
+			It("updates the network policy when spec is changed", func() {
+				client.GetCalls(func(context context.Context, nn types.NamespacedName, object crc.Object) error {
+					switch object := object.(type) {
+					case *networkingv1.NetworkPolicy:
+						existing := &networkingv1.NetworkPolicy{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "foo",
+								Namespace: "default",
+							},
+							Spec: networkingv1.NetworkPolicySpec{
+								PodSelector: metav1.LabelSelector{
+									MatchLabels: map[string]string{
+										"foo": "baz",
+									},
+								},
+							},
+						}
+						existing.DeepCopyInto(object)
+
+						return nil
+					}
+
+					return apierrors.NewNotFound(schema.GroupResource{}, nn.Name)
+				})
+				ops, err := controllerutil.CreateOrUpdate(ctx, client, policy, mutate.NetworkPolicyMutateFn(policy))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ops).To(Equal(controllerutil.OperationResultUpdated))
+			})
+		})
+	})
 })
@@ -8,25 +8,33 @@ import (
 	"code.cloudfoundry.org/quarks-utils/pkg/names"
 )
 
-// SecretVariableName generates a valid secret name for a given name
-// `var-<name>`
-func SecretVariableName(name string) string {
+// SecretVariableName generates a valid secret name for a variable of a
+// deployment:
+// `<deployment-name>.var-<name>`
+func SecretVariableName(deploymentName string, name string) string {
 	secretType := bdv1.DeploymentSecretTypeVariable
 	if name == "" {
 		name = secretType.String()
 	} else {
 		name = fmt.Sprintf("%s-%s", secretType, name)
 	}
-	return names.SanitizeSubdomain(name)
+	return names.SanitizeSubdomain(deploymentName + "." + name)
+}
+
+// DeploymentSecretName returns the name of a k8s secret, which is unique for
+// each BOSH deployment in a namespace:
+// `<deployment-name>.<secretType>`
+func DeploymentSecretName(secretType bdv1.DeploymentSecretType, deploymentName string) string {
+	return names.SanitizeSubdomain(deploymentName + "." + secretType.String())
 }
 
 // InstanceGroupSecretName returns the name of a k8s secret:
-// `<secretType>.<instance-group>-v<version>` secret.
+// `<deployment-name>.<secretType>.<instance-group>-v<version>` secret.
 //
 // These secrets are created by QuarksJob and mounted on containers, e.g.
 // for the template rendering.
-func InstanceGroupSecretName(igName string, version string) string {
-	prefix := bdv1.DeploymentSecretTypeInstanceGroupResolvedProperties.Prefix()
+func InstanceGroupSecretName(deploymentName string, igName string, version string) string {
+	prefix := bdv1.DeploymentSecretTypeInstanceGroupResolvedProperties.Prefix(deploymentName)
 	finalName := names.SanitizeSubdomain(prefix + igName)
 
 	if version != "" {
@@ -43,6 +51,49 @@ func TruncatedServiceName(igName string, maxLength int) string {
 }
 
 // ServiceName constructs the headless service name for the instance group.
-func ServiceName(instanceGroupName string) string {
-	return names.Sanitize(instanceGroupName)
+// It includes the deployment name, so instance groups of different
+// deployments in the same namespace don't collide.
+func ServiceName(deploymentName string, instanceGroupName string) string {
+	return names.Sanitize(deploymentName + "-" + instanceGroupName)
+}
+
+// QuarksStatefulSetName returns the name of the QuarksStatefulSet of an
+// instance group. Its StatefulSets, pods and volume claims are named after it:
+// `<deployment-name>-<instance-group>`
+func QuarksStatefulSetName(deploymentName string, instanceGroupName string) string {
+	return names.Sanitize(deploymentName + "-" + instanceGroupName)
+}
+
+// ErrandName returns the name of the QuarksJob of an errand instance group:
+// `<deployment-name>-<instance-group>`
+func ErrandName(deploymentName string, instanceGroupName string) string {
+	return names.Sanitize(deploymentName + "-" + instanceGroupName)
+}
+
+// PersistentVolumeClaimName returns the name of the persistent volume claim
+// template of an instance group's StatefulSet:
+// `<instance-group>-pvc`
+func PersistentVolumeClaimName(instanceGroupName string) string {
+	return names.Sanitize(instanceGroupName + "-pvc")
+}
+
+// DryRunConfigMapName returns the name of the config map, which contains the
+// changes of a BOSHDeployment in dry-run mode:
+// `<deployment-name>.dry-run`
+func DryRunConfigMapName(deploymentName string) string {
+	return names.SanitizeSubdomain(deploymentName + ".dry-run")
+}
+
+// ErrandLogsConfigMapName returns the name of the config map, which contains
+// the logs of the last run of an errand:
+// `<deployment-name>.errand-<errand-name>`
+func ErrandLogsConfigMapName(deploymentName string, errandName string) string {
+	return names.SanitizeSubdomain(deploymentName + ".errand-" + errandName)
+}
+
+// NetworkPolicyName returns the name of the network policy, which isolates
+// the pods of an instance group:
+// `<deployment-name>.<instance-group>`
+func NetworkPolicyName(deploymentName string, instanceGroupName string) string {
+	return names.SanitizeSubdomain(deploymentName + "." + instanceGroupName)
 }
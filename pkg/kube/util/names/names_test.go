@@ -6,46 +6,103 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
 )
 
 var _ = Describe("Names", func() {
 	Context("InstanceGroupSecretName", func() {
 		type test struct {
+			arg1 string
 			arg3 string
 			arg4 string
 			name string
 		}
 		tests := []test{
 			{
+				arg1: "foo",
 				arg3: "ig-Name",
 				arg4: "", // "0.1",
-				name: "ig-resolved.ig-name",
+				name: "foo.ig-resolved.ig-name",
 			},
 			{
+				arg1: "foo",
 				arg3: "ig_Name",
 				arg4: "",
-				name: "ig-resolved.ig-name",
+				name: "foo.ig-resolved.ig-name",
 			},
 			{
+				arg1: "foo",
+				arg3: "ig-name",
+				arg4: "1",
+				name: "foo.ig-resolved.ig-name-v1",
+			},
+			{
+				arg1: "foo",
 				arg3: "igname12345678901234567890ABC" + text225,
 				arg4: "",
-				name: "ig-resolved.igname12345678901234567890abcthis-is-w" + text171[:170] + "-d1a31c527eb0ad85b571d5028e28573c",
+				name: "foo.ig-resolved.igname12345678901234567890abcthis-is-w" + text171[:166] + "-8df9bdec2f50cac088e062464fb3f5d4",
 			},
 		}
 
 		It("produces valid k8s secret names", func() {
 			for _, t := range tests {
-				r := names.InstanceGroupSecretName(t.arg3, t.arg4)
+				r := names.InstanceGroupSecretName(t.arg1, t.arg3, t.arg4)
 				Expect(r).To(Equal(t.name), fmt.Sprintf("%#v", t))
 			}
 		})
 	})
 
+	Context("DeploymentSecretName", func() {
+		It("prefixes the secret type with the deployment name", func() {
+			Expect(names.DeploymentSecretName(bdv1.DeploymentSecretTypeManifestWithOps, "foo")).To(Equal("foo.with-ops"))
+		})
+	})
+
 	Context("ServiceName", func() {
+		It("prefixes the instance group with the deployment name", func() {
+			Expect(names.ServiceName("foo", "nats")).To(Equal("foo-nats"))
+		})
+
 		It("shortens long service names", func() {
-			Expect(len(names.ServiceName("scheduler-scheduler-scheduler-scheduler-scheduler-scheduler-scheduler-scheduler"))).
+			Expect(len(names.ServiceName("foo", "scheduler-scheduler-scheduler-scheduler-scheduler-scheduler-scheduler-scheduler"))).
 				To(Equal(63))
 		})
 	})
+
+	Context("SecretVariableName", func() {
+		It("prefixes the variable with the deployment name", func() {
+			Expect(names.SecretVariableName("foo", "nats_password")).To(Equal("foo.var-nats-password"))
+		})
+	})
+
+	Context("QuarksStatefulSetName", func() {
+		It("prefixes the instance group with the deployment name", func() {
+			Expect(names.QuarksStatefulSetName("foo", "Diego_Cell")).To(Equal("foo-diego-cell"))
+		})
+	})
+
+	Context("ErrandName", func() {
+		It("prefixes the errand with the deployment name", func() {
+			Expect(names.ErrandName("foo", "smoke_tests")).To(Equal("foo-smoke-tests"))
+		})
+	})
+
+	Context("PersistentVolumeClaimName", func() {
+		It("appends the suffix to the sanitized instance group name", func() {
+			Expect(names.PersistentVolumeClaimName("Diego_Cell")).To(Equal("diego-cell-pvc"))
+		})
+	})
+
+	Context("ErrandLogsConfigMapName", func() {
+		It("prefixes the errand with the deployment name", func() {
+			Expect(names.ErrandLogsConfigMapName("foo", "smoke_tests")).To(Equal("foo.errand-smoke-tests"))
+		})
+	})
+
+	Context("NetworkPolicyName", func() {
+		It("prefixes the instance group with the deployment name", func() {
+			Expect(names.NetworkPolicyName("foo", "log_api")).To(Equal("foo.log-api"))
+		})
+	})
 })
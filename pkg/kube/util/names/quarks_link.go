@@ -4,15 +4,16 @@ import (
 	"fmt"
 	"strings"
 
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	sharednames "code.cloudfoundry.org/quarks-utils/pkg/names"
 )
 
 // QuarksLinkSecretName returns the name of a secret used for Quarks links
 // to be mounted or used by environment variables
-// `link-<suffix>-<suffix>...`
-func QuarksLinkSecretName(suffixes ...string) string {
+// `link-<deployment-name>-<suffix>-<suffix>...`
+func QuarksLinkSecretName(deploymentName string, suffixes ...string) string {
 	return sharednames.SanitizeSubdomain(strings.Join(
-		append([]string{"link"}, suffixes...),
+		append([]string{"link", deploymentName}, suffixes...),
 		"-",
 	))
 }
@@ -22,3 +23,10 @@ func QuarksLinkSecretName(suffixes ...string) string {
 func QuarksLinkSecretKey(linkType, linkName string) string {
 	return fmt.Sprintf("%s-%s", linkType, linkName)
 }
+
+// QuarksLinkConsumerLabel returns the label key, which marks entangled pods
+// as consumers of a link, for the key returned by QuarksLinkSecretKey
+// `link.quarks.cloudfoundry.org/<type>-<name>`
+func QuarksLinkConsumerLabel(linkKey string) string {
+	return fmt.Sprintf("%s/%s", bdv1.LabelLinkConsumerPrefix, sharednames.Sanitize(linkKey))
+}
@@ -27,4 +27,14 @@ var _ = Describe("QuarksLink related names", func() {
 			Expect(names.QuarksLinkSecretName("deploymentname", "one", "two")).To(Equal("link-deploymentname-one-two"))
 		})
 	})
+
+	Context("link consumer labels", func() {
+		It("should return a label key for the link", func() {
+			Expect(names.QuarksLinkConsumerLabel("type-name")).To(Equal("link.quarks.cloudfoundry.org/type-name"))
+		})
+
+		It("should sanitize the link key", func() {
+			Expect(names.QuarksLinkConsumerLabel("Type-link_name")).To(Equal("link.quarks.cloudfoundry.org/type-link-name"))
+		})
+	})
 })
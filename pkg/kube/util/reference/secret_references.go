@@ -29,13 +29,13 @@ func getSecretsReferencedBy(ctx context.Context, client crc.Client, object inter
 func getSecretRefFromBdpl(ctx context.Context, client crc.Client, object bdv1.BOSHDeployment) (map[string]bool, error) {
 	result := map[string]bool{}
 
-	if object.Spec.Manifest.Type == bdv1.SecretReference {
-		result[object.Spec.Manifest.Name] = true
-	}
-
-	for _, ops := range object.Spec.Ops {
-		if ops.Type == bdv1.SecretReference {
-			result[ops.Name] = true
+	for _, ref := range append([]bdv1.ResourceReference{object.Spec.Manifest}, object.Spec.Ops...) {
+		if ref.Type == bdv1.SecretReference {
+			result[ref.Name] = true
+		}
+		// Credentials for URL references
+		if ref.SecretRef != "" {
+			result[ref.SecretRef] = true
 		}
 	}
 
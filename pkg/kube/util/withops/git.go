@@ -0,0 +1,47 @@
+package withops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/gitrepo"
+)
+
+// GitRepositories caches the fetched commits of git references for all resolvers
+var GitRepositories = gitrepo.NewRepositories(filepath.Join(os.TempDir(), "quarks-git"))
+
+// GitCommit returns the commit of a git reference, which is recorded in the status of the BOSHDeployment
+func GitCommit(status bdv1.BOSHDeploymentStatus, ref bdv1.ResourceReference) string {
+	for _, source := range status.GitSources {
+		if source.URL == ref.Name && source.Ref == ref.Ref {
+			return source.Commit
+		}
+	}
+	return ""
+}
+
+// gitData reads the file of a git reference. It uses the commit from the
+// BOSHDeployment status, so all references of a repository use the same commit.
+func (r *Resolver) gitData(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string, ref bdv1.ResourceReference, key string) (string, error) {
+	credentials, err := r.credentials(ctx, namespace, ref, key)
+	if err != nil {
+		return "", err
+	}
+
+	commit := GitCommit(bdpl.Status, ref)
+	if commit == "" {
+		commit, err = GitRepositories.ResolveCommit(ctx, ref.Name, ref.Ref, credentials)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	data, err := GitRepositories.ReadFile(ctx, ref.Name, commit, ref.Path, credentials)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
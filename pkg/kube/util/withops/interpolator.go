@@ -5,6 +5,8 @@ import (
 	boshtpl "github.com/cloudfoundry/bosh-cli/director/template"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
+
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 )
 
 // Interpolator renders BOSH manifests by operations files
@@ -55,3 +57,24 @@ func (i *InterpolatorImpl) Interpolate(manifestBytes []byte) ([]byte, error) {
 	}
 	return bytes, nil
 }
+
+// InterpolateOps applies a list of ops patches, e.g. the pre-render ops of an
+// instance group, to a YAML document
+func InterpolateOps(ops bdm.OpsPatches, data []byte) ([]byte, error) {
+	if len(ops) == 0 {
+		return data, nil
+	}
+
+	opsData, err := ops.Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get bytes for ops")
+	}
+
+	interpolator := NewInterpolator()
+	err = interpolator.AddOps(opsData)
+	if err != nil {
+		return nil, err
+	}
+
+	return interpolator.Interpolate(data)
+}
@@ -4,6 +4,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	ipl "code.cloudfoundry.org/quarks-operator/pkg/kube/util/withops"
 )
 
@@ -588,4 +589,24 @@ This is a wrong ops format file
 			Expect(err.Error()).To(ContainSubstring("found character that cannot start any token"))
 		})
 	})
+
+	Describe("InterpolateOps", func() {
+		It("applies ops patches to a document", func() {
+			ops := bdm.OpsPatches{{Type: "replace", Path: "/processes/name=foo/executable", Value: "/bin/bar"}}
+
+			bytes, err := ipl.InterpolateOps(ops, []byte(`
+processes:
+- name: foo
+  executable: /bin/foo
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(bytes)).To(ContainSubstring("executable: /bin/bar"))
+		})
+
+		It("returns the document without ops patches", func() {
+			bytes, err := ipl.InterpolateOps(nil, []byte("name: foo"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(bytes)).To(Equal("name: foo"))
+		})
+	})
 })
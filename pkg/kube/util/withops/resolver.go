@@ -5,8 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/SUSE/go-patch/patch"
@@ -18,6 +17,7 @@ import (
 	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/boshdns"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/metrics"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/names"
 	qsv1a1 "code.cloudfoundry.org/quarks-secret/pkg/kube/apis/quarkssecret/v1alpha1"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
@@ -45,7 +45,7 @@ func NewResolver(client client.Client, f NewInterpolatorFunc) *Resolver {
 	}
 }
 
-func (r *Resolver) load(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, error) {
+func (r *Resolver) load(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, []bdv1.RuntimeConfigStatus, error) {
 	var (
 		m            string
 		err          error
@@ -53,22 +53,23 @@ func (r *Resolver) load(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespac
 		spec         = bdpl.Spec
 	)
 
-	m, err = r.resourceData(ctx, namespace, spec.Manifest.Type, spec.Manifest.Name, bdv1.ManifestSpecName)
+	m, err = r.resourceData(ctx, bdpl, namespace, spec.Manifest, bdv1.ManifestSpecName)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Interpolation failed for bosh deployment '%s' in '%s'", bdpl.Name, namespace)
+		return nil, nil, errors.Wrapf(err, "Interpolation failed for bosh deployment '%s' in '%s'", bdpl.Name, namespace)
 	}
 
 	// Interpolate manifest with ops
 	ops := spec.Ops
 
 	for _, op := range ops {
-		opsData, err := r.resourceData(ctx, namespace, op.Type, op.Name, bdv1.OpsSpecName)
+		opsData, err := r.resourceData(ctx, bdpl, namespace, op, bdv1.OpsSpecName)
 		if err != nil {
-			return nil, errors.Wrapf(err, "Interpolation failed for bosh deployment '%s' in '%s'", bdpl.Name, namespace)
+			return nil, nil, errors.Wrapf(err, "Interpolation failed for bosh deployment '%s' in '%s'", bdpl.Name, namespace)
 		}
 		err = interpolator.AddOps([]byte(opsData))
 		if err != nil {
-			return nil, errors.Wrapf(err, "Interpolation failed for bosh deployment '%s' in '%s'", bdpl.Name, namespace)
+			metrics.OpsErrors.WithLabelValues(namespace, bdpl.Name).Inc()
+			return nil, nil, errors.Wrapf(err, "Interpolation failed for bosh deployment '%s' in '%s'", bdpl.Name, namespace)
 		}
 	}
 
@@ -76,36 +77,80 @@ func (r *Resolver) load(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespac
 	if len(ops) != 0 {
 		bytes, err = interpolator.Interpolate([]byte(m))
 		if err != nil {
-			return nil, errors.Wrapf(err, "Failed to interpolate %#v in interpolation task", m)
+			metrics.OpsErrors.WithLabelValues(namespace, bdpl.Name).Inc()
+			return nil, nil, errors.Wrapf(err, "Failed to interpolate %#v in interpolation task", m)
 		}
 	}
 
 	manifest, err := bdm.LoadYAML(bytes)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Loading yaml failed in interpolation task after applying ops %#v", m)
+		return nil, nil, errors.Wrapf(err, "Loading yaml failed in interpolation task after applying ops %#v", m)
 	}
-	return manifest, nil
+
+	runtimeConfigs, err := r.mergeRuntimeConfigs(ctx, bdpl, manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, runtimeConfigs, nil
+}
+
+// mergeRuntimeConfigs adds the releases and addons of all BOSHRuntimeConfigs
+// to the manifest, ordered by name. It returns the revisions of the merged
+// runtime configs.
+func (r *Resolver) mergeRuntimeConfigs(ctx context.Context, bdpl *bdv1.BOSHDeployment, manifest *bdm.Manifest) ([]bdv1.RuntimeConfigStatus, error) {
+	list := &bdv1.BOSHRuntimeConfigList{}
+	err := r.client.List(ctx, list)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list runtime configs for bosh deployment '%s'", bdpl.Name)
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+
+	var applied []bdv1.RuntimeConfigStatus
+	for _, runtimeConfig := range list.Items {
+		rc, err := bdm.LoadRuntimeConfigYAML([]byte(runtimeConfig.Spec.Manifest))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load runtime config '%s'", runtimeConfig.Name)
+		}
+
+		err = manifest.MergeRuntimeConfig(rc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to merge runtime config '%s' into bosh deployment '%s'", runtimeConfig.Name, bdpl.Name)
+		}
+
+		applied = append(applied, bdv1.RuntimeConfigStatus{
+			Name:       runtimeConfig.Name,
+			Generation: runtimeConfig.Generation,
+		})
+	}
+
+	return applied, nil
 }
 
 // Manifest returns manifest and a list of implicit variables referenced by our bdpl CRD
-// The resulting manifest has variables interpolated and ops files applied.
-// It is the 'with-ops' manifest.
-func (r *Resolver) Manifest(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, error) {
-	manifest, err := r.load(ctx, bdpl, namespace)
+// The resulting manifest has variables interpolated, ops files and runtime configs applied.
+// It is the 'with-ops' manifest. The revisions of the applied runtime configs are returned, too.
+func (r *Resolver) Manifest(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string) (*bdm.Manifest, []bdv1.RuntimeConfigStatus, error) {
+	manifest, runtimeConfigs, err := r.load(ctx, bdpl, namespace)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	manifest, err = r.applyVariables(ctx, bdpl, namespace, manifest, "manifest-addons")
+	if err != nil {
+		return nil, nil, err
 	}
-	return r.applyVariables(ctx, bdpl, namespace, manifest, "manifest-addons")
+	return manifest, runtimeConfigs, nil
 }
 
 // ImplicitVariables returns the implicit variables found in the manifest
 func (r *Resolver) ImplicitVariables(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string) ([]string, error) {
-	manifest, err := r.load(ctx, bdpl, namespace)
+	manifest, _, err := r.load(ctx, bdpl, namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	refs, err := buildSecretRefs(manifest)
+	refs, err := buildSecretRefs(bdpl.Name, manifest)
 	if err != nil {
 		return []string{}, errors.Wrapf(err, "failed to parse all implicit variable names")
 	}
@@ -135,7 +180,7 @@ func (r *Resolver) ManifestDetailed(ctx context.Context, bdpl *bdv1.BOSHDeployme
 		spec = bdpl.Spec
 	)
 
-	m, err = r.resourceData(ctx, namespace, spec.Manifest.Type, spec.Manifest.Name, bdv1.ManifestSpecName)
+	m, err = r.resourceData(ctx, bdpl, namespace, spec.Manifest, bdv1.ManifestSpecName)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Interpolation failed for bosh deployment %s", namespace)
 	}
@@ -147,17 +192,19 @@ func (r *Resolver) ManifestDetailed(ctx context.Context, bdpl *bdv1.BOSHDeployme
 	for _, op := range ops {
 		interpolator := r.newInterpolatorFunc()
 
-		opsData, err := r.resourceData(ctx, namespace, op.Type, op.Name, bdv1.OpsSpecName)
+		opsData, err := r.resourceData(ctx, bdpl, namespace, op, bdv1.OpsSpecName)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed to get resource data for interpolation of bosh deployment '%s' and ops '%s' in '%s'", bdpl.Name, op.Name, namespace)
 		}
 		err = interpolator.AddOps([]byte(opsData))
 		if err != nil {
+			metrics.OpsErrors.WithLabelValues(namespace, bdpl.Name).Inc()
 			return nil, errors.Wrapf(err, "Interpolation failed for bosh deployment '%s' and ops '%s' in '%s'", bdpl.Name, op.Name, namespace)
 		}
 
 		bytes, err = interpolator.Interpolate(bytes)
 		if err != nil {
+			metrics.OpsErrors.WithLabelValues(namespace, bdpl.Name).Inc()
 			return nil, errors.Wrapf(err, "Failed to interpolate ops '%s' for manifest '%s' in '%s'", op.Name, bdpl.Name, namespace)
 		}
 	}
@@ -167,6 +214,11 @@ func (r *Resolver) ManifestDetailed(ctx context.Context, bdpl *bdv1.BOSHDeployme
 		return nil, errors.Wrapf(err, "Loading yaml failed in interpolation task after applying ops %#v", m)
 	}
 
+	_, err = r.mergeRuntimeConfigs(ctx, bdpl, manifest)
+	if err != nil {
+		return nil, err
+	}
+
 	manifest, err = r.applyVariables(ctx, bdpl, namespace, manifest, "detailed-manifest-addons")
 	if err != nil {
 		return nil, errors.Wrapf(err, "Loading yaml failed after applying variable: %#v", m)
@@ -190,7 +242,7 @@ func (s secretRefs) add(variable string, secName string, key string) {
 }
 
 // Find implicit variable references and index by secret name
-func buildSecretRefs(manifest *bdm.Manifest) (secretRefs, error) {
+func buildSecretRefs(deploymentName string, manifest *bdm.Manifest) (secretRefs, error) {
 	vars, err := manifest.ImplicitVariables()
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to list implicit variables")
@@ -207,10 +259,10 @@ func buildSecretRefs(manifest *bdm.Manifest) (secretRefs, error) {
 				return refs, fmt.Errorf("expected one / separator for implicit variable/key name, have %d", len(parts))
 			}
 
-			secName = names.SecretVariableName(parts[0])
+			secName = names.SecretVariableName(deploymentName, parts[0])
 			key = parts[1]
 		} else {
-			secName = names.SecretVariableName(v)
+			secName = names.SecretVariableName(deploymentName, v)
 			key = bdv1.ImplicitVariableKeyName
 		}
 
@@ -221,7 +273,7 @@ func buildSecretRefs(manifest *bdm.Manifest) (secretRefs, error) {
 
 // Apply all variables and interpolate
 func (r *Resolver) applyVariables(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string, manifest *bdm.Manifest, logName string) (*bdm.Manifest, error) {
-	refs, err := buildSecretRefs(manifest)
+	refs, err := buildSecretRefs(bdpl.Name, manifest)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to parse all implicit variable names")
 	}
@@ -274,7 +326,7 @@ func (r *Resolver) applyVariables(ctx context.Context, bdpl *bdv1.BOSHDeployment
 
 	// Apply addons
 	log := ctxlog.ExtractLogger(ctx)
-	err = manifest.ApplyAddons(logger.TraceFilter(log, logName))
+	err = manifest.ApplyAddons(logger.TraceFilter(log, logName), bdm.AddOnDeployment{Name: bdpl.Name, Teams: []string{namespace}})
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to apply addons")
 	}
@@ -320,19 +372,20 @@ func (r *Resolver) applyVariables(ctx context.Context, bdpl *bdv1.BOSHDeployment
 	if err != nil {
 		return nil, err
 	}
-	manifest.ApplyUpdateBlock()
+	manifest.ApplyUpdateBlock(bdpl.Name)
 
 	return manifest, err
 }
 
 // resourceData resolves different manifest reference types and returns the resource's data
-func (r *Resolver) resourceData(ctx context.Context, namespace string, resType bdv1.ReferenceType, name string, key string) (string, error) {
+func (r *Resolver) resourceData(ctx context.Context, bdpl *bdv1.BOSHDeployment, namespace string, ref bdv1.ResourceReference, key string) (string, error) {
 	var (
 		data string
 		ok   bool
+		name = ref.Name
 	)
 
-	switch resType {
+	switch ref.Type {
 	case bdv1.ConfigMapReference:
 		opsConfig := &corev1.ConfigMap{}
 		err := r.client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, opsConfig)
@@ -355,15 +408,9 @@ func (r *Resolver) resourceData(ctx context.Context, namespace string, resType b
 		}
 		data = string(encodedData)
 	case bdv1.URLReference:
-		httpResponse, err := http.Get(name)
-		if err != nil {
-			return data, errors.Wrapf(err, "failed to resolve %s from url '%s' via http.Get", key, name)
-		}
-		body, err := ioutil.ReadAll(httpResponse.Body)
-		if err != nil {
-			return data, errors.Wrapf(err, "failed to read %s response body '%s' via ioutil", key, name)
-		}
-		data = string(body)
+		return r.urlData(ctx, namespace, ref, key)
+	case bdv1.GitReference:
+		return r.gitData(ctx, bdpl, namespace, ref, key)
 	default:
 		return data, fmt.Errorf("unrecognized %s ref type %s", key, name)
 	}
@@ -384,7 +431,7 @@ func (r *Resolver) InterpolateVariableFromSecrets(ctx context.Context, withOpsMa
 		staticVars := boshtpl.StaticVariables{}
 
 		varName := variable.Name
-		varSecretName := names.SecretVariableName(varName)
+		varSecretName := names.SecretVariableName(boshdeploymentName, varName)
 
 		varQuarksSecret := &qsv1a1.QuarksSecret{}
 		err = r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: varSecretName}, varQuarksSecret)
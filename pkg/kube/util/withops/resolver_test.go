@@ -2,8 +2,16 @@ package withops_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	boshtpl "github.com/cloudfoundry/bosh-cli/director/template"
 	"github.com/go-test/deep"
@@ -14,12 +22,15 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	bdm "code.cloudfoundry.org/quarks-operator/pkg/bosh/manifest"
 	bdc "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/controllers/fakes"
+	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/gitrepo"
 	"code.cloudfoundry.org/quarks-operator/pkg/kube/util/withops"
 	"code.cloudfoundry.org/quarks-utils/pkg/ctxlog"
 	"code.cloudfoundry.org/quarks-utils/testing/testhelper"
@@ -46,6 +57,8 @@ var _ = Describe("WithOps", func() {
 	)
 
 	BeforeEach(func() {
+		Expect(bdc.AddToScheme(scheme.Scheme)).To(Succeed())
+
 		_, log := testhelper.NewTestLogger()
 		ctx = ctxlog.NewParentContext(log)
 		validManifestPath = "/valid-manifest.yml"
@@ -236,21 +249,21 @@ instance_groups:
 				},
 				&corev1.Secret{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "var-system-domain",
+						Name:      "foo-deployment.var-system-domain",
 						Namespace: "default",
 					},
 					Data: map[string][]byte{"value": []byte("example.com")},
 				},
 				&corev1.Secret{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "var-implicit-ca",
+						Name:      "foo-deployment.var-implicit-ca",
 						Namespace: "default",
 					},
 					Data: map[string][]byte{"value": []byte("complicated\n'multiline'\nstring")},
 				},
 				&corev1.Secret{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "var-ssl",
+						Name:      "foo-deployment.var-ssl",
 						Namespace: "default",
 					},
 					Data: map[string][]byte{
@@ -261,7 +274,7 @@ instance_groups:
 				},
 				&corev1.Secret{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "var-implicit-struct",
+						Name:      "foo-deployment.var-implicit-struct",
 						Namespace: "default",
 						Annotations: map[string]string{
 							bdc.AnnotationJSONValue: "true",
@@ -402,7 +415,7 @@ instance_groups:
 				AddOnsApplied: true,
 			}
 
-			manifest, err := resolver.Manifest(ctx, deployment, "default")
+			manifest, _, err := resolver.Manifest(ctx, deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -439,7 +452,7 @@ instance_groups:
 				AddOnsApplied: true,
 			}
 
-			manifest, err := resolver.Manifest(ctx, deployment, "default")
+			manifest, _, err := resolver.Manifest(ctx, deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -469,7 +482,7 @@ instance_groups:
 				AddOnsApplied: true,
 			}
 
-			manifest, err := resolver.Manifest(ctx, deployment, "default")
+			manifest, _, err := resolver.Manifest(ctx, deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -520,7 +533,7 @@ instance_groups:
 				AddOnsApplied: true,
 			}
 
-			manifest, err := resolver.Manifest(ctx, deployment, "default")
+			manifest, _, err := resolver.Manifest(ctx, deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -578,7 +591,7 @@ instance_groups:
 				AddOnsApplied: true,
 			}
 
-			manifest, err := resolver.Manifest(ctx, deployment, "default")
+			manifest, _, err := resolver.Manifest(ctx, deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -609,7 +622,7 @@ instance_groups:
 				},
 			}
 
-			manifest, err := resolver.Manifest(ctx, deployment, "default")
+			manifest, _, err := resolver.Manifest(ctx, deployment, "default")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(manifest).ToNot(Equal(nil))
@@ -628,7 +641,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve manifest"))
 		})
@@ -642,7 +655,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("doesn't contain key 'manifest'"))
 		})
@@ -656,7 +669,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("cannot unmarshal string into Go value of type manifest.Manifest"))
 		})
@@ -670,7 +683,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("unrecognized manifest ref type"))
 		})
@@ -690,7 +703,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve ops from configmap"))
 		})
@@ -710,7 +723,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("doesn't contain key 'ops'"))
 		})
@@ -732,7 +745,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Interpolation failed for bosh deployment"))
 		})
@@ -753,7 +766,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Failed to interpolate"))
 		})
@@ -773,7 +786,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("unrecognized ops ref type"))
 		})
@@ -797,7 +810,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve ops from configmap"))
 		})
@@ -821,7 +834,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve ops from secret"))
 		})
@@ -849,7 +862,7 @@ instance_groups:
 					},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to retrieve ops from secret"))
@@ -872,7 +885,7 @@ instance_groups:
 			})
 
 			It("returns correct value", func() {
-				m, err := resolver.Manifest(ctx, deployment, "default")
+				m, _, err := resolver.Manifest(ctx, deployment, "default")
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(m.Variables[1].Options.CommonName).To(Equal("example.com"))
@@ -883,7 +896,7 @@ instance_groups:
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(len(implicitVars)).To(Equal(1))
-				Expect(implicitVars[0]).To(Equal("var-system-domain"))
+				Expect(implicitVars[0]).To(Equal("foo-deployment.var-system-domain"))
 			})
 		})
 
@@ -906,7 +919,7 @@ instance_groups:
 					Ops: []bdc.ResourceReference{},
 				},
 			}
-			_, err := resolver.Manifest(ctx, deployment, "default")
+			_, _, err := resolver.Manifest(ctx, deployment, "default")
 			Expect(err).ToNot(HaveOccurred())
 		})
 
@@ -927,7 +940,7 @@ instance_groups:
 			})
 
 			It("uses values of implicit vars", func() {
-				m, err := resolver.Manifest(ctx, deployment, "default")
+				m, _, err := resolver.Manifest(ctx, deployment, "default")
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(m.InstanceGroups[0].Properties.Properties["ca"]).To(Equal("complicated\n'multiline'\nstring"))
@@ -938,7 +951,7 @@ instance_groups:
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(len(implicitVars)).To(Equal(1))
-				Expect(implicitVars[0]).To(Equal("var-implicit-ca"))
+				Expect(implicitVars[0]).To(Equal("foo-deployment.var-implicit-ca"))
 			})
 		})
 
@@ -959,7 +972,7 @@ instance_groups:
 			})
 
 			It("uses values of implicit vars", func() {
-				m, err := resolver.Manifest(ctx, deployment, "default")
+				m, _, err := resolver.Manifest(ctx, deployment, "default")
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(m.InstanceGroups[0].Properties.Properties["host"]).To(Equal("foo.example.com"))
@@ -970,7 +983,7 @@ instance_groups:
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(len(implicitVars)).To(Equal(1))
-				Expect(implicitVars[0]).To(Equal("var-system-domain"))
+				Expect(implicitVars[0]).To(Equal("foo-deployment.var-system-domain"))
 			})
 		})
 
@@ -991,7 +1004,7 @@ instance_groups:
 			})
 
 			It("uses values of implicit vars", func() {
-				m, err := resolver.Manifest(ctx, deployment, "default")
+				m, _, err := resolver.Manifest(ctx, deployment, "default")
 
 				sslProps := m.InstanceGroups[0].Properties.Properties["ssl"].(map[string]interface{})
 				Expect(err).ToNot(HaveOccurred())
@@ -1024,7 +1037,7 @@ instance_groups:
 			})
 
 			It("uses json content of implicit vars", func() {
-				m, err := resolver.Manifest(ctx, deployment, "default")
+				m, _, err := resolver.Manifest(ctx, deployment, "default")
 				Expect(err).ToNot(HaveOccurred())
 
 				props, ok := m.InstanceGroups[1].Properties.Properties["nested"]
@@ -1040,7 +1053,295 @@ instance_groups:
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(implicitVars).To(HaveLen(1))
-				Expect(implicitVars).To(ContainElement("var-implicit-struct"))
+				Expect(implicitVars).To(ContainElement("foo-deployment.var-implicit-struct"))
+			})
+		})
+
+		Context("when the URL reference has options", func() {
+			const remoteManifest = `---
+instance_groups:
+  - name: component6
+    instances: 1`
+
+			var (
+				server *ghttp.Server
+				ref    bdc.ResourceReference
+			)
+
+			manifestFor := func(ref bdc.ResourceReference) (*bdm.Manifest, error) {
+				deployment := &bdc.BOSHDeployment{
+					Spec: bdc.BOSHDeploymentSpec{Manifest: ref},
+				}
+				m, _, err := resolver.Manifest(ctx, deployment, "default")
+				return m, err
+			}
+
+			BeforeEach(func() {
+				server = ghttp.NewTLSServer()
+				server.AllowUnhandledRequests = true
+				server.RouteToHandler("GET", validManifestPath, func(w http.ResponseWriter, req *http.Request) {
+					user, pass, ok := req.BasicAuth()
+					if !ok || user != "user" || pass != "pass" || req.Header.Get("X-Token") != "token" || req.Header.Get("Ca.crt") != "" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					_, _ = w.Write([]byte(remoteManifest))
+				})
+
+				caBundle := pem.EncodeToMemory(&pem.Block{
+					Type:  "CERTIFICATE",
+					Bytes: server.HTTPTestServer.Certificate().Raw,
+				})
+
+				err := client.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "artifact-credentials", Namespace: "default"},
+					Data: map[string][]byte{
+						"username":       []byte("user"),
+						"password":       []byte("pass"),
+						"header-X-Token": []byte("token"),
+						"ca.crt":         []byte("not a header"),
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				sum := sha256.Sum256([]byte(remoteManifest))
+				ref = bdc.ResourceReference{
+					Type:      bdc.URLReference,
+					Name:      server.URL() + validManifestPath,
+					SecretRef: "artifact-credentials",
+					CABundle:  string(caBundle),
+					SHA256:    hex.EncodeToString(sum[:]),
+				}
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			It("downloads the manifest with credentials and the custom CA", func() {
+				manifest, err := manifestFor(ref)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups).To(HaveLen(1))
+				Expect(manifest.InstanceGroups[0].Name).To(Equal("component6"))
+			})
+
+			It("caches the response", func() {
+				_, err := manifestFor(ref)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = manifestFor(ref)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(server.ReceivedRequests()).To(HaveLen(1))
+			})
+
+			It("fails if the CA is not trusted", func() {
+				ref.CABundle = ""
+				_, err := manifestFor(ref)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("certificate"))
+			})
+
+			It("fails if the credentials secret is missing", func() {
+				ref.SecretRef = "missing"
+				_, err := manifestFor(ref)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to retrieve credentials for manifest"))
+			})
+
+			It("fails if the server rejects the request", func() {
+				ref.SecretRef = ""
+
+				_, err := manifestFor(ref)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unexpected status"))
+			})
+
+			It("fails if the response exceeds the size limit", func() {
+				maxSize := withops.URLMaxSize
+				withops.URLMaxSize = int64(len(remoteManifest)) - 1
+				defer func() { withops.URLMaxSize = maxSize }()
+				// A different cache key, so the response is not read from the cache
+				ref.SHA256 = ""
+
+				_, err := manifestFor(ref)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("response body exceeds"))
+			})
+
+			It("fails on a digest mismatch", func() {
+				ref.SHA256 = strings.Repeat("0", 64)
+				_, err := manifestFor(ref)
+				Expect(err).To(HaveOccurred())
+
+				var digestErr *withops.DigestMismatchError
+				Expect(errors.As(err, &digestErr)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("sha256 digest mismatch for url"))
+			})
+		})
+
+		Context("when runtime configs exist", func() {
+			BeforeEach(func() {
+				deployment = &bdc.BOSHDeployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-deployment",
+						Namespace: "default",
+					},
+					Spec: bdc.BOSHDeploymentSpec{
+						Manifest: bdc.ResourceReference{
+							Type: bdc.ConfigMapReference,
+							Name: "base-manifest",
+						},
+					},
+				}
+
+				for _, rc := range []*bdc.BOSHRuntimeConfig{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "syslog", Generation: 3},
+						Spec: bdc.BOSHRuntimeConfigSpec{Manifest: `---
+releases:
+- name: syslog
+  version: "11.7.0"
+addons:
+- name: syslog-forwarder
+  jobs:
+  - name: syslog_forwarder
+    release: syslog
+  include:
+    deployments: [foo-deployment]
+`},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "monitoring", Generation: 1},
+						Spec: bdc.BOSHRuntimeConfigSpec{Manifest: `---
+addons:
+- name: node-exporter
+  jobs:
+  - name: node_exporter
+    release: node-exporter
+  include:
+    instance_groups: [component2]
+  exclude:
+    teams: [default]
+`},
+					},
+				} {
+					Expect(client.Create(ctx, rc)).To(Succeed())
+				}
+			})
+
+			It("merges the addons matching the deployment", func() {
+				manifest, _, err := resolver.Manifest(ctx, deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(manifest.Releases).To(HaveLen(1))
+				Expect(manifest.Releases[0].Name).To(Equal("syslog"))
+				for _, ig := range manifest.InstanceGroups {
+					Expect(ig.Jobs).To(HaveLen(1))
+					Expect(ig.Jobs[0].Name).To(Equal("syslog_forwarder"))
+					Expect(ig.Jobs[0].Properties.Quarks.IsAddon).To(BeTrue())
+				}
+			})
+
+			It("returns the revisions of the applied runtime configs", func() {
+				_, runtimeConfigs, err := resolver.Manifest(ctx, deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(runtimeConfigs).To(Equal([]bdc.RuntimeConfigStatus{
+					{Name: "monitoring", Generation: 1},
+					{Name: "syslog", Generation: 3},
+				}))
+			})
+
+			It("doesn't change the status of the deployment", func() {
+				_, err := resolver.ManifestDetailed(ctx, deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(deployment.Status.RuntimeConfigs).To(BeEmpty())
+			})
+
+			It("fails if the runtime config is invalid", func() {
+				rc := &bdc.BOSHRuntimeConfig{}
+				Expect(client.Get(ctx, types.NamespacedName{Name: "monitoring"}, rc)).To(Succeed())
+				rc.Spec.Manifest = "addons: {}"
+				Expect(client.Update(ctx, rc)).To(Succeed())
+
+				_, _, err := resolver.Manifest(ctx, deployment, "default")
+				Expect(err).To(MatchError(ContainSubstring("failed to load runtime config 'monitoring'")))
+			})
+		})
+
+		Context("when using a git reference", func() {
+			var (
+				tmpDir   string
+				remote   string
+				commits  []string
+				gitRepos *gitrepo.Repositories
+			)
+
+			gitInDir := func(dir string, args ...string) string {
+				args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+				cmd := exec.Command("git", args...)
+				cmd.Dir = dir
+				out, err := cmd.CombinedOutput()
+				Expect(err).ToNot(HaveOccurred(), string(out))
+				return strings.TrimSpace(string(out))
+			}
+
+			BeforeEach(func() {
+				var err error
+				tmpDir, err = ioutil.TempDir("", "withops-git")
+				Expect(err).ToNot(HaveOccurred())
+
+				gitRepos = withops.GitRepositories
+				withops.GitRepositories = gitrepo.NewRepositories(filepath.Join(tmpDir, "cache"))
+				withops.GitRepositories.AllowFileProtocol = true
+
+				remote = filepath.Join(tmpDir, "remote.git")
+				work := filepath.Join(tmpDir, "work")
+				gitInDir(tmpDir, "init", "--quiet", "--bare", remote)
+				gitInDir(tmpDir, "clone", "--quiet", remote, work)
+				gitInDir(work, "checkout", "--quiet", "-b", "main")
+
+				commits = []string{}
+				for _, instances := range []string{"1", "2"} {
+					err = ioutil.WriteFile(filepath.Join(work, "manifest.yml"), []byte("instance_groups:\n- name: component7\n  instances: "+instances+"\n"), 0644)
+					Expect(err).ToNot(HaveOccurred())
+					gitInDir(work, "add", "manifest.yml")
+					gitInDir(work, "commit", "--quiet", "-m", instances)
+					gitInDir(work, "push", "--quiet", "origin", "main")
+					commits = append(commits, gitInDir(work, "rev-parse", "HEAD"))
+				}
+			})
+
+			AfterEach(func() {
+				withops.GitRepositories = gitRepos
+				Expect(os.RemoveAll(tmpDir)).To(Succeed())
+			})
+
+			It("reads the manifest from the latest commit of the ref", func() {
+				deployment := &bdc.BOSHDeployment{
+					Spec: bdc.BOSHDeploymentSpec{
+						Manifest: bdc.ResourceReference{Type: bdc.GitReference, Name: remote, Ref: "main", Path: "manifest.yml"},
+					},
+				}
+
+				manifest, _, err := resolver.Manifest(ctx, deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups[0].Instances).To(Equal(2))
+			})
+
+			It("uses the commit from the status", func() {
+				deployment := &bdc.BOSHDeployment{
+					Spec: bdc.BOSHDeploymentSpec{
+						Manifest: bdc.ResourceReference{Type: bdc.GitReference, Name: remote, Ref: "main", Path: "manifest.yml"},
+					},
+					Status: bdc.BOSHDeploymentStatus{
+						GitSources: []bdc.GitSourceStatus{{URL: remote, Ref: "main", Commit: commits[0]}},
+					},
+				}
+
+				manifest, _, err := resolver.Manifest(ctx, deployment, "default")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(manifest.InstanceGroups[0].Instances).To(Equal(1))
 			})
 		})
 	})
@@ -0,0 +1,204 @@
+package withops
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	bdv1 "code.cloudfoundry.org/quarks-operator/pkg/kube/apis/boshdeployment/v1alpha1"
+)
+
+const (
+	// URLTimeout is the timeout for downloading a URL reference
+	URLTimeout = 30 * time.Second
+	// URLCacheDuration is the duration for which a downloaded URL reference is cached
+	URLCacheDuration = 1 * time.Minute
+)
+
+// URLMaxSize is the maximum size in bytes of a downloaded URL reference
+var URLMaxSize int64 = 10 * 1024 * 1024
+
+// urlCache is shared by all resolvers, so reconciles of several controllers use the same downloads
+var urlCache = newURLResponseCache(URLCacheDuration)
+
+// DigestMismatchError is returned if the content of a URL reference doesn't match the expected SHA256 digest
+type DigestMismatchError struct {
+	URL      string
+	Expected string
+	Actual   string
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("sha256 digest mismatch for url '%s': expected '%s', got '%s'", e.URL, e.Expected, e.Actual)
+}
+
+// urlResponseCache holds the content of URL references for a limited time
+type urlResponseCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]urlCacheEntry
+}
+
+type urlCacheEntry struct {
+	data    string
+	expires time.Time
+}
+
+func newURLResponseCache(ttl time.Duration) *urlResponseCache {
+	return &urlResponseCache{ttl: ttl, entries: map[string]urlCacheEntry{}}
+}
+
+func (c *urlResponseCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.data, true
+}
+
+func (c *urlResponseCache) set(key string, data string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = urlCacheEntry{data: data, expires: now.Add(c.ttl)}
+}
+
+// credentials returns the data of the secret, which is referenced by a URL or git reference
+func (r *Resolver) credentials(ctx context.Context, namespace string, ref bdv1.ResourceReference, key string) (map[string][]byte, error) {
+	if ref.SecretRef == "" {
+		return map[string][]byte{}, nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: ref.SecretRef, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve credentials for %s %s '%s' from secret '%s/%s'", key, ref.Type, ref.Name, namespace, ref.SecretRef)
+	}
+	return secret.Data, nil
+}
+
+// urlData downloads the content of a URL reference. It uses the credentials
+// from the referenced secret and verifies the content against the digest.
+func (r *Resolver) urlData(ctx context.Context, namespace string, ref bdv1.ResourceReference, key string) (string, error) {
+	credentials, err := r.credentials(ctx, namespace, ref, key)
+	if err != nil {
+		return "", err
+	}
+
+	cacheKey := urlCacheKey(ref, credentials)
+	if data, ok := urlCache.get(cacheKey); ok {
+		return data, nil
+	}
+
+	client, err := httpClient(ref.CABundle)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to configure http client for %s url '%s'", key, ref.Name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref.Name, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to build request for %s url '%s'", key, ref.Name)
+	}
+	for k, v := range credentials {
+		if strings.HasPrefix(k, bdv1.CredentialsHeaderPrefix) {
+			req.Header.Set(strings.TrimPrefix(k, bdv1.CredentialsHeaderPrefix), string(v))
+		}
+	}
+	if username, ok := credentials["username"]; ok {
+		req.SetBasicAuth(string(username), string(credentials["password"]))
+	}
+
+	httpResponse, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve %s from url '%s'", key, ref.Name)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s from url '%s': unexpected status '%s'", key, ref.Name, httpResponse.Status)
+	}
+
+	// Read one byte more than allowed, to detect bodies exceeding the limit
+	body, err := ioutil.ReadAll(io.LimitReader(httpResponse.Body, URLMaxSize+1))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read %s response body '%s' via ioutil", key, ref.Name)
+	}
+	if int64(len(body)) > URLMaxSize {
+		return "", fmt.Errorf("failed to resolve %s from url '%s': response body exceeds %d bytes", key, ref.Name, URLMaxSize)
+	}
+
+	if ref.SHA256 != "" {
+		sum := sha256.Sum256(body)
+		actual := hex.EncodeToString(sum[:])
+		if !strings.EqualFold(actual, ref.SHA256) {
+			return "", &DigestMismatchError{URL: ref.Name, Expected: ref.SHA256, Actual: actual}
+		}
+	}
+
+	data := string(body)
+	urlCache.set(cacheKey, data)
+
+	return data, nil
+}
+
+// httpClient returns a client with a timeout, which trusts the CA bundle in addition to the system CAs
+func httpClient(caBundle string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if caBundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caBundle)) {
+			return nil, errors.New("no valid PEM certificate found in CA bundle")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &http.Client{Timeout: URLTimeout, Transport: transport}, nil
+}
+
+// urlCacheKey identifies a download by all inputs, so changed credentials or digests are not served from the cache
+func urlCacheKey(ref bdv1.ResourceReference, credentials map[string][]byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", ref.Name, ref.CABundle, ref.SHA256)
+
+	keys := make([]string, 0, len(credentials))
+	for k := range credentials {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, credentials[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -2429,6 +2429,8 @@ releases:
     version: 36.g03b4653-30.80-7.0.0_316.gcf9fe4a7
 update:
   serial: false
+  canaries: 2
+  max_in_flight: 25%
   canary_watch_time: 20000-1200000
   update_watch_time: 20000-1200000
 instance_groups:
@@ -2459,6 +2461,7 @@ instance_groups:
           internal: 1338
 - name: bpm3
   update:
+    max_in_flight: "3"
     canary_watch_time: 10000-9900000
     update_watch_time: 10000-9900000
   jobs:
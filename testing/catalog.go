@@ -250,7 +250,7 @@ func (c *Catalog) VersionedSecret(name string) corev1.Secret {
 
 // QuarksLinkSecret returns a link secret, as generated for consumption by an external (non BOSH) consumer
 func (c *Catalog) QuarksLinkSecret(deploymentName, linkType, linkName string, value map[string][]byte) corev1.Secret {
-	name := names.QuarksLinkSecretName(linkType, linkName)
+	name := names.QuarksLinkSecretName(deploymentName, linkType, linkName)
 	return corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        name,